@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// gcPolicy selects what `shelley-fuse gc` removes from the state file.
+type gcPolicy struct {
+	cloneTimeout time.Duration            // unconversed clones older than this (0 = keep)
+	cloneByModel map[string]time.Duration // per-model overrides of cloneTimeout
+	pruneStale   bool                     // adopted conversations gone from the server
+	dryRun       bool                     // report only
+}
+
+// gcRemoval is one state entry removed (or, in a dry run, to be removed).
+type gcRemoval struct {
+	Backend string
+	LocalID string
+	Reason  string
+}
+
+// cloneTimeoutFor returns the timeout for an unconversed clone, applying the
+// same per-model overrides as the mount.
+func (p gcPolicy) cloneTimeoutFor(cs state.ConversationState) time.Duration {
+	for _, model := range []string{cs.Model, cs.ModelID} {
+		if d, ok := p.cloneByModel[model]; ok && model != "" {
+			return d
+		}
+	}
+	return p.cloneTimeout
+}
+
+// serverConversationIDs returns the IDs of all active and archived
+// conversations on a backend.
+func serverConversationIDs(client shelley.ShelleyClient) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	for _, list := range []func() ([]byte, error){client.ListConversations, client.ListArchivedConversations} {
+		data, err := list()
+		if err != nil {
+			return nil, err
+		}
+		var convs []shelley.Conversation
+		if err := json.Unmarshal(data, &convs); err != nil {
+			return nil, err
+		}
+		for _, c := range convs {
+			ids[c.ConversationID] = true
+		}
+	}
+	return ids, nil
+}
+
+// collectGarbage removes expired clones and, if asked, stale adoptions from
+// every backend in store. Backends whose server can't be reached keep their
+// adoptions: a failed listing says nothing about what still exists.
+// newClient is used to reach each backend's URL.
+func collectGarbage(store *state.Store, policy gcPolicy, now time.Time, newClient func(url string) shelley.ShelleyClient, warn io.Writer) []gcRemoval {
+	var removed []gcRemoval
+	for _, backend := range store.ListBackends() {
+		var serverIDs map[string]bool
+		if policy.pruneStale {
+			if b := store.GetBackend(backend); b == nil || b.URL == "" {
+				fmt.Fprintf(warn, "skipping stale check for backend %s: no URL recorded\n", backend)
+			} else if ids, err := serverConversationIDs(newClient(b.URL)); err != nil {
+				fmt.Fprintf(warn, "skipping stale check for backend %s: %v\n", backend, err)
+			} else {
+				serverIDs = ids
+			}
+		}
+
+		mappings := store.ListMappingsForBackend(backend)
+		sort.Slice(mappings, func(i, j int) bool { return mappings[i].LocalID < mappings[j].LocalID })
+		for _, cs := range mappings {
+			var reason string
+			switch {
+			case !cs.Created:
+				timeout := policy.cloneTimeoutFor(cs)
+				if timeout <= 0 || cs.CreatedAt.IsZero() || now.Sub(cs.CreatedAt) <= timeout {
+					continue
+				}
+				reason = fmt.Sprintf("unconversed clone, idle %s (limit %s)", now.Sub(cs.CreatedAt).Truncate(time.Second), timeout)
+			case serverIDs != nil && cs.ShelleyConversationID != "" && !serverIDs[cs.ShelleyConversationID]:
+				reason = fmt.Sprintf("conversation %s no longer on server", cs.ShelleyConversationID)
+			default:
+				continue
+			}
+			if !policy.dryRun {
+				if err := store.ForceDeleteForBackend(backend, cs.LocalID); err != nil {
+					fmt.Fprintf(warn, "failed to remove %s: %v\n", cs.LocalID, err)
+					continue
+				}
+			}
+			removed = append(removed, gcRemoval{Backend: backend, LocalID: cs.LocalID, Reason: reason})
+		}
+	}
+	return removed
+}
+
+// runGC implements `shelley-fuse gc`: prune the state file without mounting.
+// It must not run against the state file of a live mount, which would write
+// its in-memory copy back over the result.
+func runGC(args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("gc", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	statePath := flags.String("state", "", "path to state.json (default: ~/.shelley-fuse/state.json)")
+	profile := flags.String("profile", "", "use the state of the named `profile` instead of the default")
+	cloneTimeout := flags.Duration("clone-timeout", time.Hour, "remove unconversed clones older than this (0 to keep them)")
+	cloneByModel := modelDurations{}
+	flags.Var(cloneByModel, "model-clone-timeout", "per-model `model=duration` override of -clone-timeout (repeatable)")
+	pruneStale := flags.Bool("stale", true, "remove adopted conversations that no longer exist on their backend")
+	dryRun := flags.Bool("dry-run", false, "report what would be removed without changing anything")
+	tlsOpts := addTLSFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(errOut, "Usage: shelley-fuse gc [options]\n\nPrune the state file of an unmounted filesystem.\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	path, err := resolveStatePath(*statePath, *profile)
+	if err != nil {
+		fmt.Fprintf(errOut, "Invalid -profile: %v\n", err)
+		return 2
+	}
+	store, err := state.NewStore(path)
+	if err != nil {
+		fmt.Fprintf(errOut, "Failed to load state: %v\n", err)
+		return 1
+	}
+
+	policy := gcPolicy{
+		cloneTimeout: *cloneTimeout,
+		cloneByModel: cloneByModel,
+		pruneStale:   *pruneStale,
+		dryRun:       *dryRun,
+	}
+	total := 0
+	for _, backend := range store.ListBackends() {
+		total += len(store.ListMappingsForBackend(backend))
+	}
+	tlsConfig, err := tlsOpts.config()
+	if err != nil {
+		fmt.Fprintf(errOut, "Invalid TLS options: %v\n", err)
+		return 2
+	}
+	newClient := func(url string) shelley.ShelleyClient { return shelley.NewClientWithTLS(url, tlsConfig) }
+	removed := collectGarbage(store, policy, time.Now(), newClient, errOut)
+
+	verb := "removed"
+	if *dryRun {
+		verb = "would remove"
+	}
+	for _, r := range removed {
+		fmt.Fprintf(out, "%s %s/%s: %s\n", verb, r.Backend, r.LocalID, r.Reason)
+	}
+	fmt.Fprintf(out, "%s %d of %d entries from %s\n", verb, len(removed), total, store.Path)
+	return 0
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	server := mockserver.New(mockserver.WithConversation("conv-live", nil))
+	defer server.Close()
+
+	store, err := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnsureBackendURL(state.DefaultBackendName, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	live, _ := store.Adopt("conv-live")
+	gone, _ := store.Adopt("conv-gone")
+	quick, _ := store.Clone()
+	store.SetCtl(quick, "model", "claude-haiku")
+	fresh, _ := store.Clone()
+
+	policy := gcPolicy{
+		cloneTimeout: time.Hour,
+		cloneByModel: map[string]time.Duration{"claude-haiku": 5 * time.Minute},
+		pruneStale:   true,
+	}
+	newClient := func(url string) shelley.ShelleyClient { return shelley.NewClient(url) }
+	var warn bytes.Buffer
+	removed := collectGarbage(store, policy, time.Now().Add(10*time.Minute), newClient, &warn)
+
+	got := make(map[string]bool)
+	for _, r := range removed {
+		got[r.LocalID] = true
+	}
+	if len(removed) != 2 || !got[gone] || !got[quick] {
+		t.Errorf("removed = %+v, want %s and %s", removed, gone, quick)
+	}
+	for _, id := range []string{live, fresh} {
+		if store.Get(id) == nil {
+			t.Errorf("%s should have been kept", id)
+		}
+	}
+	if store.Get(gone) != nil || store.Get(quick) != nil {
+		t.Error("removed entries are still in the store")
+	}
+	if warn.Len() != 0 {
+		t.Errorf("unexpected warnings: %s", warn.String())
+	}
+}
+
+func TestCollectGarbageKeepsAdoptionsWhenServerFails(t *testing.T) {
+	server := mockserver.New(mockserver.WithErrorMode(http.StatusInternalServerError))
+	defer server.Close()
+
+	store, err := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.EnsureBackendURL(state.DefaultBackendName, server.URL)
+	id, _ := store.Adopt("conv-1")
+
+	newClient := func(url string) shelley.ShelleyClient { return shelley.NewClient(url) }
+	var warn bytes.Buffer
+	removed := collectGarbage(store, gcPolicy{pruneStale: true}, time.Now(), newClient, &warn)
+	if len(removed) != 0 || store.Get(id) == nil {
+		t.Errorf("adoption removed although the server listing failed: %+v", removed)
+	}
+	if !strings.Contains(warn.String(), "skipping stale check") {
+		t.Errorf("expected a warning, got %q", warn.String())
+	}
+}
+
+func TestRunGCDryRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := store.Clone()
+
+	var out, errOut bytes.Buffer
+	if code := runGC([]string{"-state", path, "-clone-timeout", "1ns", "-stale=false", "-dry-run"}, &out, &errOut); code != 0 {
+		t.Fatalf("runGC exited %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "would remove "+state.DefaultBackendName+"/"+id) || !strings.Contains(out.String(), "would remove 1 of 1 entries") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+
+	reloaded, err := state.NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Get(id) == nil {
+		t.Error("dry run removed the clone")
+	}
+}
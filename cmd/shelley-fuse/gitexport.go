@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// gitExportTrailer marks each exported commit with the message it holds, so
+// a second export of the same conversation only adds the new messages.
+const gitExportTrailer = "Shelley-Message"
+
+// resolveExportConversation returns the server conversation ID for what was
+// given on the command line: a local ID, a slug, or a server ID.
+func resolveExportConversation(store *state.Store, backend, arg string) string {
+	if cs := store.GetForBackend(backend, arg); cs != nil {
+		return cs.ShelleyConversationID
+	}
+	if localID := store.GetBySlugForBackend(backend, arg); localID != "" {
+		if cs := store.GetForBackend(backend, localID); cs != nil {
+			return cs.ShelleyConversationID
+		}
+	}
+	return arg
+}
+
+// gitRunner runs git in a repository. Commits are made with the author and
+// dates in env, and without reading the user's hooks or signing config.
+type gitRunner struct {
+	dir string
+}
+
+func (g gitRunner) run(env []string, stdin io.Reader, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.dir, "-c", "commit.gpgsign=false", "-c", "core.hooksPath=/dev/null"}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// exportedMessages returns the IDs of messages already committed to the
+// repository, read back from the commit trailers. A repository without
+// commits has none.
+func (g gitRunner) exportedMessages() (map[string]bool, error) {
+	ids := make(map[string]bool)
+	if _, err := g.run(nil, nil, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return ids, nil
+	}
+	log, err := g.run(nil, nil, "log", "--format=%B")
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(strings.NewReader(log))
+	for scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), gitExportTrailer+": "); ok {
+			ids[strings.TrimSpace(id)] = true
+		}
+	}
+	return ids, nil
+}
+
+// gitCommitMessage is the commit message for one exported message: the
+// role and the first line of its text, then the trailer naming it.
+func gitCommitMessage(m shelley.Message, section string) string {
+	subject := m.Type
+	_, body, _ := strings.Cut(section, "\n\n")
+	if line, _, _ := strings.Cut(strings.TrimSpace(body), "\n"); line != "" {
+		// Cut by runes, so the subject stays valid UTF-8.
+		if utf8.RuneCountInString(line) > 60 {
+			line = string([]rune(line)[:57]) + "..."
+		}
+		subject += ": " + line
+	}
+	return fmt.Sprintf("%s\n\n%s: %s\n", subject, gitExportTrailer, m.MessageID)
+}
+
+// gitExport commits each message of msgs that is not yet in the repository
+// at dir, appending its markdown to file. Commits are authored by the
+// message's role and dated with its created_at, so the history reads like
+// the session did. It returns the number of commits made.
+func gitExport(dir, file string, msgs []shelley.Message) (int, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+	g := gitRunner{dir: dir}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if _, err := g.run(nil, nil, "init", "-q"); err != nil {
+			return 0, err
+		}
+	}
+	done, err := g.exportedMessages()
+	if err != nil {
+		return 0, err
+	}
+
+	// One chunk per message; together they are exactly all.md.
+	sections := shelley.FormatMarkdownChunks(msgs, 1)
+	path := filepath.Join(dir, file)
+	commits := 0
+	for i, m := range msgs {
+		if done[m.MessageID] || i >= len(sections) {
+			continue
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return commits, err
+		}
+		_, err = f.Write(sections[i])
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return commits, err
+		}
+		if _, err := g.run(nil, nil, "add", "--", file); err != nil {
+			return commits, err
+		}
+
+		env := []string{
+			"GIT_AUTHOR_NAME=" + m.Type,
+			"GIT_AUTHOR_EMAIL=" + m.Type + "@shelley.invalid",
+			"GIT_COMMITTER_NAME=shelley-fuse",
+			"GIT_COMMITTER_EMAIL=shelley-fuse@shelley.invalid",
+		}
+		if t, err := time.Parse(time.RFC3339Nano, m.CreatedAt); err == nil {
+			date := t.Format(time.RFC3339)
+			env = append(env, "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+		}
+		msg := gitCommitMessage(m, string(sections[i]))
+		if _, err := g.run(env, strings.NewReader(msg), "commit", "-q", "--allow-empty", "-F", "-"); err != nil {
+			return commits, err
+		}
+		commits++
+	}
+	return commits, nil
+}
+
+// runGitExport implements `shelley-fuse git-export CONVERSATION REPO`.
+func runGitExport(args []string, out, errOut io.Writer) int {
+	flags := flag.NewFlagSet("git-export", flag.ContinueOnError)
+	flags.SetOutput(errOut)
+	statePath := flags.String("state", "", "path to state.json (default: ~/.shelley-fuse/state.json)")
+	profile := flags.String("profile", "", "use the state of the named `profile` instead of the default")
+	backend := flags.String("backend", "", "backend the conversation is on (default: the default backend)")
+	serverURL := flags.String("url", "", "Shelley server URL (default: the backend's recorded URL)")
+	file := flags.String("file", "conversation.md", "file in the repository the messages are appended to")
+	tlsOpts := addTLSFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(errOut, "Usage: shelley-fuse git-export [options] CONVERSATION REPO\n\n"+
+			"Commit each message of a conversation (local ID, slug or server ID) to the\n"+
+			"git repository REPO, creating it if needed. Rerunning adds new messages only.\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	conversation, repo := flags.Arg(0), flags.Arg(1)
+
+	path, err := resolveStatePath(*statePath, *profile)
+	if err != nil {
+		fmt.Fprintf(errOut, "Invalid -profile: %v\n", err)
+		return 2
+	}
+	store, err := state.NewStore(path)
+	if err != nil {
+		fmt.Fprintf(errOut, "Failed to load state: %v\n", err)
+		return 1
+	}
+	if *backend == "" {
+		*backend = store.GetDefaultBackend()
+	}
+	url := *serverURL
+	if url == "" {
+		if b := store.GetBackend(*backend); b != nil {
+			url = b.URL
+		}
+	}
+	if url == "" {
+		fmt.Fprintf(errOut, "No URL recorded for backend %s; pass -url\n", *backend)
+		return 1
+	}
+
+	serverID := resolveExportConversation(store, *backend, conversation)
+	if serverID == "" {
+		fmt.Fprintf(errOut, "Conversation %s has not been started on the backend yet\n", conversation)
+		return 1
+	}
+	tlsConfig, err := tlsOpts.config()
+	if err != nil {
+		fmt.Fprintf(errOut, "Invalid TLS options: %v\n", err)
+		return 2
+	}
+	data, err := shelley.NewClientWithTLS(url, tlsConfig).GetConversation(serverID)
+	if err != nil {
+		fmt.Fprintf(errOut, "Failed to fetch conversation %s: %v\n", serverID, err)
+		return 1
+	}
+	msgs, err := shelley.ParseMessages(data)
+	if err != nil {
+		fmt.Fprintf(errOut, "Failed to parse conversation %s: %v\n", serverID, err)
+		return 1
+	}
+
+	commits, err := gitExport(repo, *file, msgs)
+	if err != nil {
+		fmt.Fprintf(errOut, "Export failed after %d commit(s): %v\n", commits, err)
+		return 1
+	}
+	fmt.Fprintf(out, "exported %d new of %d messages from %s to %s\n", commits, len(msgs), serverID, repo)
+	return 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestRunGitExport(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	text := func(s string) *string { return &s }
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-1", SequenceID: 1, Type: "user", UserData: text("Fix the flaky test"), CreatedAt: "2024-03-01T10:00:00Z"},
+		{MessageID: "m2", ConversationID: "conv-1", SequenceID: 2, Type: "agent", LLMData: text("Done, it was a race."), CreatedAt: "2024-03-01T10:05:00Z"},
+	}
+	server := mockserver.New(mockserver.WithConversation("conv-1", msgs))
+	defer server.Close()
+
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	store, err := state.NewStore(statePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.EnsureBackendURL(state.DefaultBackendName, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	localID, _ := store.Adopt("conv-1")
+
+	repo := filepath.Join(t.TempDir(), "repo")
+	var out, errOut bytes.Buffer
+	if code := runGitExport([]string{"-state", statePath, localID, repo}, &out, &errOut); code != 0 {
+		t.Fatalf("git-export exited %d: %s", code, errOut.String())
+	}
+
+	git := func(args ...string) string {
+		t.Helper()
+		b, err := exec.Command("git", append([]string{"-C", repo}, args...)...).Output()
+		if err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+		return string(b)
+	}
+	log := git("log", "--reverse", "--format=%an %aI %s")
+	want := "user 2024-03-01T10:00:00+00:00 user: Fix the flaky test\n" +
+		"agent 2024-03-01T10:05:00+00:00 agent: Done, it was a race.\n"
+	if log != want {
+		t.Errorf("git log =\n%s\nwant\n%s", log, want)
+	}
+	content, err := os.ReadFile(filepath.Join(repo, "conversation.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != string(shelley.FormatMarkdown(msgs)) {
+		t.Errorf("conversation.md = %q, want all.md", content)
+	}
+
+	// A second run finds both messages already exported.
+	out.Reset()
+	if code := runGitExport([]string{"-state", statePath, "conv-1", repo}, &out, &errOut); code != 0 {
+		t.Fatalf("second git-export exited %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "exported 0 new of 2") {
+		t.Errorf("second run output = %q", out.String())
+	}
+	if n := strings.Count(git("log", "--format=%H"), "\n"); n != 2 {
+		t.Errorf("repository has %d commits after rerun, want 2", n)
+	}
+}
+
+func TestGitCommitMessageCutsOnRunes(t *testing.T) {
+	text := strings.Repeat("é", 70)
+	msg := gitCommitMessage(shelley.Message{MessageID: "m1", Type: "user"}, "## user\n\n"+text)
+	subject, _, _ := strings.Cut(msg, "\n")
+	if !utf8.ValidString(subject) {
+		t.Errorf("subject %q is not valid UTF-8", subject)
+	}
+	if want := "user: " + strings.Repeat("é", 57) + "..."; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+}
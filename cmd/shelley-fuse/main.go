@@ -10,12 +10,18 @@ import (
 	"os"
 	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fs"
 	shelleyfuse "shelley-fuse/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/journal"
+	"shelley-fuse/sdnotify"
 	"shelley-fuse/shelley"
 	"shelley-fuse/state"
 )
@@ -30,7 +36,8 @@ type SocketInfo struct {
 }
 
 // parseListenAddress parses the JSON output from `systemctl list-sockets shelley.socket --output=json`
-// and returns an HTTP URL for the first TCP listen address found.
+// and returns an HTTP URL for the first TCP listen address found, or a
+// unix:// URL for shelley.socket's unix socket if it has no TCP address.
 func parseListenAddress(jsonOutput string) (string, error) {
 	var sockets []SocketInfo
 	if err := json.Unmarshal([]byte(jsonOutput), &sockets); err != nil {
@@ -38,9 +45,13 @@ func parseListenAddress(jsonOutput string) (string, error) {
 	}
 
 	// The output should contain shelley.socket entries
+	unixURL := ""
 	for _, s := range sockets {
-		// Skip unix sockets (absolute paths)
+		// Unix sockets (absolute paths) are used only without a TCP address
 		if strings.HasPrefix(s.Listen, "/") {
+			if unixURL == "" && s.Unit == "shelley.socket" {
+				unixURL = "unix://" + s.Listen
+			}
 			continue
 		}
 
@@ -58,7 +69,10 @@ func parseListenAddress(jsonOutput string) (string, error) {
 		return fmt.Sprintf("http://%s", net.JoinHostPort(host, port)), nil
 	}
 
-	return "", fmt.Errorf("no TCP listen address found for shelley.socket")
+	if unixURL != "" {
+		return unixURL, nil
+	}
+	return "", fmt.Errorf("no listen address found for shelley.socket")
 }
 
 // discoverBackendURL attempts to discover the backend URL from the
@@ -80,22 +94,248 @@ func discoverBackendURL() string {
 	return url
 }
 
+// modelDurations is a repeatable flag of model=duration pairs.
+type modelDurations map[string]time.Duration
+
+func (m modelDurations) String() string {
+	pairs := make([]string, 0, len(m))
+	for model, d := range m {
+		pairs = append(pairs, model+"="+d.String())
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set accepts "model=duration", or several separated by commas.
+func (m modelDurations) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		model, dur, ok := strings.Cut(pair, "=")
+		if !ok || model == "" {
+			return fmt.Errorf("expected model=duration, got %q", pair)
+		}
+		d, err := time.ParseDuration(dur)
+		if err != nil {
+			return fmt.Errorf("invalid duration for model %s: %w", model, err)
+		}
+		m[model] = d
+	}
+	return nil
+}
+
+// modelAliases is a repeatable flag of alias=model pairs.
+type modelAliases map[string]string
+
+func (m modelAliases) String() string {
+	pairs := make([]string, 0, len(m))
+	for alias, model := range m {
+		pairs = append(pairs, alias+"="+model)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set accepts "alias=model", or several separated by commas. Aliases are
+// single path components.
+func (m modelAliases) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		alias, model, ok := strings.Cut(pair, "=")
+		if !ok || alias == "" || model == "" {
+			return fmt.Errorf("expected alias=model, got %q", pair)
+		}
+		if strings.Contains(alias, "/") || alias == "." || alias == ".." {
+			return fmt.Errorf("invalid alias name %q", alias)
+		}
+		m[alias] = model
+	}
+	return nil
+}
+
+// kernelTTLs is a repeatable flag of tier=duration pairs overriding the
+// kernel cache timeouts of the filesystem.
+type kernelTTLs struct {
+	timeouts shelleyfuse.CacheTimeouts
+}
+
+func (k *kernelTTLs) String() string {
+	if k == nil {
+		return ""
+	}
+	t := k.timeouts
+	return fmt.Sprintf("immutable=%v,static=%v,models=%v,conversation=%v", t.Immutable, t.Static, t.Models, t.Conversation)
+}
+
+// Set accepts "tier=duration", or several separated by commas.
+func (k *kernelTTLs) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		tier, dur, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected tier=duration, got %q", pair)
+		}
+		d, err := time.ParseDuration(dur)
+		if err != nil {
+			return fmt.Errorf("invalid duration for tier %s: %w", tier, err)
+		}
+		if err := k.timeouts.Set(tier, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveStatePath returns the state file to use given the -state and
+// -profile flags, which can't both be set.
+func resolveStatePath(statePath, profile string) (string, error) {
+	if profile == "" {
+		return statePath, nil
+	}
+	if statePath != "" {
+		return "", fmt.Errorf("-state and -profile can't be used together")
+	}
+	return state.ProfilePath(profile)
+}
+
+// stringList is a repeatable flag of values.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+// Set accepts a value, or several separated by commas.
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v == "" {
+			return fmt.Errorf("empty value in %q", value)
+		}
+		*l = append(*l, v)
+	}
+	return nil
+}
+
+// statusErrnos is a repeatable flag of status=ERRNO pairs, e.g. 429=EBUSY.
+// Values are kept as validated, upper-case errno names.
+type statusErrnos map[int]string
+
+func (m statusErrnos) String() string {
+	pairs := make([]string, 0, len(m))
+	for status, name := range m {
+		pairs = append(pairs, fmt.Sprintf("%d=%s", status, name))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Set accepts "status=ERRNO", or several separated by commas.
+func (m statusErrnos) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		code, name, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected status=ERRNO, got %q", pair)
+		}
+		status, err := strconv.Atoi(code)
+		if err != nil || status < 100 || status > 599 {
+			return fmt.Errorf("invalid HTTP status %q", code)
+		}
+		if _, err := shelleyfuse.ParseErrno(name); err != nil {
+			return err
+		}
+		m[status] = strings.ToUpper(name)
+	}
+	return nil
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		os.Exit(runGC(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "git-export" {
+		os.Exit(runGitExport(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	debug := flag.Bool("debug", false, "enable debug output")
+	debugOps := flag.String("debug-ops", "", "with -debug, only log these comma-separated FUSE `opcodes`, e.g. LOOKUP,READ (default: all)")
+	debugSample := flag.Int("debug-sample", 1, "with -debug, log only one in every `N` FUSE requests")
+	debugRate := flag.Int("debug-rate", 200, "with -debug, log at most this many FUSE requests per second, noting how many were dropped (0 for no limit)")
 	cloneTimeout := flag.Duration("clone-timeout", time.Hour, "duration after which unconversed clone IDs are cleaned up")
+	modelCloneTimeouts := modelDurations{}
+	flag.Var(modelCloneTimeouts, "model-clone-timeout", "per-model `model=duration` override of -clone-timeout for clones with that ctl model (repeatable)")
+	aliases := modelAliases{}
+	flag.Var(aliases, "model-alias", "`alias=model` symlink under model/ pointing at a model by display name or ID, e.g. fast=claude-haiku (repeatable)")
 	cacheTTL := flag.Duration("cache-ttl", 3*time.Second, "cache TTL for backend responses (0 to disable caching)")
+	kernelTTL := &kernelTTLs{timeouts: shelleyfuse.DefaultCacheTimeouts()}
+	flag.Var(kernelTTL, "kernel-ttl", "`tier=duration` the kernel caches names and attributes of that tier for: immutable (message files), static (README, fixed symlinks), models or conversation (conversation directories and lists); send, working and other live files are never cached (repeatable)")
+	liveTTL := flag.Duration("live-ttl", 0, "how stale working, cancel and messages/count may be; they are answered from the conversation list, fetched at most this often, and a conversation is only refetched for them once the list shows it changed (0: working asks the backend every time, count follows -cache-ttl)")
+	retries := flag.Int("retries", 0, "try a backend read that fails to connect, times out or gets a 5xx up to this many more times, with exponential backoff (0 for no retries)")
+	requestTimeout := flag.Duration("request-timeout", 0, "give up on a backend request that hasn't been answered within this long, and retry it if it is a read (0: only the overall 2m limit)")
+	breakerThreshold := flag.Int("circuit-breaker", 0, "after this many failed backend requests in a row, fail requests at once for 10s instead of waiting on the backend (0 to disable)")
+	serveStale := flag.Bool("serve-stale", false, "while the backend is unreachable, answer reads from cached responses however old, instead of failing with EIO (needs -cache-ttl)")
+	cacheMaxBytes := flag.Int64("cache-max-bytes", 0, "total size limit for cached conversations; least recently used ones are evicted beyond it (0 for no limit)")
 	statePath := flag.String("state", "", "path to state.json (default: ~/.shelley-fuse/state.json)")
+	profile := flag.String("profile", "", "use the state of the named `profile`, ~/.shelley-fuse/profiles/NAME/state.json, instead of the default")
 	readyFD := flag.Int("ready-fd", 0, "fd number; when >0, write READY\\n to this fd after mount+diag are ready, then close it")
 	diagAddr := flag.String("diag-addr", "", "address for diag HTTP server (default: disabled)")
+	layoutName := flag.String("layout", "ids", "how conversations are named under /conversation: ids (local-ID directories) or slugs (slug directories, IDs as symlinks)")
+	passthrough := flag.Bool("passthrough", false, "list server conversations under their server IDs without recording them in the state file")
+	mdChunkSize := flag.Int("md-chunk-size", 64<<20, "split all.md into messages/all.md.d/part-NNN.md files of at most this many bytes once it grows larger (0 to disable)")
+	absSymlinks := flag.Bool("absolute-symlinks", false, "make symlinks that point inside the mount read as absolute paths under the mountpoint instead of relative ones, for tools that resolve relative targets wrongly")
+	onMessage := flag.String("on-message", "", "shell command to run when a conversation gets a new message (see README for its environment)")
+	onCreate := flag.String("on-create", "", "shell command to run when a conversation is created on the backend")
+	onError := flag.String("on-error", "", "shell command to run when a request about a conversation fails")
+	titleLinks := flag.Bool("title-links", false, "add conversation/by-title/ with a symlink per conversation named after its title (listing it fetches every conversation)")
+	exportCompat := flag.Bool("export-compat", false, "prepare the mount for re-export over NFS or SMB: stable inode numbers, generation numbers, and real sizes for rendered files")
+	sparseMessages := flag.Bool("sparse-messages", false, "leave per-message directories out of messages/ listings (they can still be opened by name), so tools that walk the mount stay fast")
+	budgetTokens := flag.Int64("budget-tokens", 0, "refuse sends (EDQUOT) to any conversation that has used this many tokens (0 for no cap); ctl budget_tokens= sets a cap per conversation")
+	budgetUSD := flag.Float64("budget-usd", 0, "refuse sends (EDQUOT) to any conversation that has cost this many dollars (0 for no cap); ctl budget_usd= sets a cap per conversation")
+	maxSendSize := flag.Int("max-send-size", 1<<20, "largest message accepted by send, in bytes; bigger writes fail with EFBIG (0 for no limit)")
+	syncInterval := flag.Duration("sync-mappings", 0, "store the local ID mapping on the backend, pushing changes at this interval (0 to disable)")
+	pollActive := flag.Duration("poll-active", 0, "refresh conversations with recent activity in the background at this interval, so reads of messages/ find them cached (0 to disable)")
+	prefetch := flag.Int("prefetch", 0, "fetch and cache the `N` most recently updated conversations right after mounting, so the first reads of them don't wait for the backend")
+	pollIdle := flag.Duration("poll-idle", 5*time.Minute, "with -poll-active, refresh the other conversations at this interval (0 to leave them alone)")
+	pollInterval := flag.Duration("poll-interval", 0, "list conversations at this interval and drop the kernel's cached names and contents of those that changed on the backend (0 to disable)")
+	errnoOverrides := statusErrnos{}
+	flag.Var(errnoOverrides, "status-errno", "map a backend HTTP `status=ERRNO` to a different errno, e.g. 429=EBUSY (repeatable)")
+	modelReadyTimeout := flag.Duration("model-ready-timeout", shelleyfuse.DefaultModelReadyTimeout, "how long reading model/{id}/wait_ready blocks before failing with ETIMEDOUT")
+	errorLogSize := flag.Int("error-log-size", diag.DefaultErrorLogSize, "number of backend errors kept in each conversation/{id}/errors.log (0 to disable)")
+	traceSize := flag.Int("trace", 0, "keep the last N FUSE operations and backend requests of each conversation in conversation/{id}/.trace (0 to disable)")
+	maxClones := flag.Int("max-clones", 1000, "most cloned conversations without a first message at a time; cloning beyond it fails with EDQUOT (0 for no limit)")
+	accessLog := flag.String("access-log", "", "append a JSON line to this `file` for every open of a conversation's files for reading, with the user and process that opened it (default: disabled)")
+	captureDir := flag.String("capture-dir", "", "write scrubbed copies of backend requests and responses to this directory, for bug reports (default: disabled)")
+	var includeSlugs, excludeSlugs, onlyModels stringList
+	flag.Var(&includeSlugs, "include-slug", "only adopt and list server conversations whose slug matches this `glob` (repeatable)")
+	flag.Var(&excludeSlugs, "exclude-slug", "don't adopt or list server conversations whose slug matches this `glob` (repeatable)")
+	flag.Var(&onlyModels, "only-model", "only adopt and list server conversations using this `model` (repeatable)")
+	maxAge := flag.Duration("max-age", 0, "don't adopt or list server conversations last updated longer ago than this (0 for no limit)")
+	tlsOpts := addTLSFlags(flag.CommandLine)
 	flag.Parse()
 
 	if flag.NArg() < 1 {
 		fmt.Printf("Usage: %s [options] MOUNTPOINT [URL]\n", os.Args[0])
+		fmt.Printf("       %s gc [options]\n", os.Args[0])
+		fmt.Printf("       %s git-export [options] CONVERSATION REPO\n", os.Args[0])
 		fmt.Printf("Options:\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
 
+	// Under systemd, log straight to the journal with structured fields
+	// instead of interleaving plain text on stderr.
+	var journalWriter *journal.Writer
+	if journal.Enabled() {
+		if w, err := journal.Dial(journal.DefaultSocket, "shelley-fuse"); err != nil {
+			log.Printf("Journal logging unavailable, using stderr: %v", err)
+		} else {
+			journalWriter = w
+			log.SetOutput(w)
+			log.SetFlags(0) // the journal timestamps entries itself
+		}
+	}
+	// Whatever is logged can also be followed live at /diag/logstream.
+	logStream := diag.NewLogStream(log.Writer())
+	log.SetOutput(logStream)
+
+	layout, err := shelleyfuse.ParseLayout(*layoutName)
+	if err != nil {
+		log.Fatalf("Invalid -layout: %v", err)
+	}
+
 	mountpoint := flag.Arg(0)
 
 	var url string
@@ -107,10 +347,26 @@ func main() {
 	log.Printf("Using backend URL: %s", url)
 
 	// Create state store
-	store, err := state.NewStore(*statePath)
+	if *statePath, err = resolveStatePath(*statePath, *profile); err != nil {
+		log.Fatalf("Invalid -profile: %v", err)
+	}
+	if *profile != "" {
+		log.Printf("Using profile %s (%s)", *profile, *statePath)
+	}
+	store, repair, err := state.NewStoreRepairing(*statePath)
 	if err != nil {
 		log.Fatalf("Failed to initialize state: %v", err)
 	}
+	if repair != nil {
+		log.Printf("State repair: %s", repair)
+	}
+
+	store.SetPassthrough(*passthrough)
+	store.SetMaxPendingClones(*maxClones)
+
+	if journalWriter != nil {
+		journalWriter.KnownConversation = store.Known
+	}
 
 	// Set the URL for the default backend (creating it if needed)
 	if err := store.EnsureBackendURL(state.DefaultBackendName, url); err != nil {
@@ -119,18 +375,137 @@ func main() {
 
 	// Create ClientManager for multi-backend support
 	clientMgr := shelley.NewClientManager(*cacheTTL)
+	tlsConfig, err := tlsOpts.config()
+	if err != nil {
+		log.Fatalf("Invalid TLS options: %v", err)
+	}
+	clientMgr.SetTLSConfig(tlsConfig)
+	cacheBudget := shelley.NewCacheBudget(*cacheMaxBytes)
+	clientMgr.SetCacheBudget(cacheBudget)
+	clientMgr.SetLiveTTL(*liveTTL)
+	if *retries > 0 || *requestTimeout > 0 || *breakerThreshold > 0 {
+		clientMgr.SetResilience(shelley.ResilienceOptions{Retries: *retries, RequestTimeout: *requestTimeout, BreakerThreshold: *breakerThreshold})
+	}
+	clientMgr.SetServeStale(*serveStale)
+	tracker := diag.NewTracker()
+	tracker.SetErrorLogSize(*errorLogSize)
+	if *traceSize > 0 {
+		tracker.EnableTrace(*traceSize, knownLocalID(store))
+	}
+	tracker.AddWarning(func() string {
+		if status := store.PersistStatus(); status.MemoryOnly {
+			return "state file " + status.String()
+		}
+		return ""
+	})
+	clientMgr.SetRequestObserver(observeRequests(store, tracker))
+	schemaWatch := shelley.NewSchemaWatch()
+	clientMgr.SetSchemaWatch(schemaWatch)
+	clientMgr.SetNegotiation(true)
+	clientMgr.SetNegotiationHook(func(backend string, caps shelley.Capabilities) {
+		err := store.SetBackendCapabilities(backend, state.BackendCapabilities{
+			Version:    caps.Version,
+			Features:   caps.Features,
+			Negotiated: caps.Negotiated,
+			CheckedAt:  time.Now().UTC(),
+		})
+		if err != nil {
+			log.Printf("Failed to record capabilities of backend %s: %v", backend, err)
+		}
+	})
+	if *captureDir != "" {
+		capture, err := shelley.NewCapture(*captureDir, shelley.DefaultCaptureMaxBody, shelley.DefaultCaptureMaxFiles)
+		if err != nil {
+			log.Fatalf("Invalid -capture-dir: %v", err)
+		}
+		clientMgr.SetCapture(capture)
+		log.Printf("Capturing backend requests to %s", *captureDir)
+	}
 
 	// Ensure the client for the default backend exists
-	if _, err := clientMgr.EnsureURL(state.DefaultBackendName, url); err != nil {
+	client, err := clientMgr.EnsureURL(state.DefaultBackendName, url)
+	if err != nil {
 		log.Fatalf("Failed to create client for default backend: %v", err)
 	}
 
+	// Restore the mapping table from the backend before anything is adopted,
+	// so conversations get the same local IDs as on other mounts.
+	var mappingStore shelley.MappingStore
+	if *syncInterval > 0 {
+		if ms, ok := client.(shelley.MappingStore); ok {
+			if kept, err := restoreMappings(store, ms); err != nil {
+				log.Printf("Mapping sync disabled: %v", err)
+			} else {
+				log.Printf("Restored %d conversation mappings from backend", kept)
+				mappingStore = ms
+			}
+		}
+	}
+
+	// After a repair, adopt what the damaged state file lost, once mapping
+	// sync has had the chance to bring back the local IDs it recorded.
+	if repair != nil {
+		repairFromServers(store, clientMgr)
+	}
+
 	// Create FUSE filesystem with backend support
 	shelleyFS := shelleyfuse.NewFSWithBackends(clientMgr, store, *cloneTimeout)
+	shelleyFS.SetLayout(layout)
+	if len(includeSlugs) > 0 || len(excludeSlugs) > 0 || len(onlyModels) > 0 || *maxAge > 0 {
+		filter := &shelleyfuse.ConversationFilter{IncludeSlugs: includeSlugs, ExcludeSlugs: excludeSlugs, Models: onlyModels, MaxAge: *maxAge}
+		if err := filter.Validate(); err != nil {
+			log.Fatalf("Invalid -include-slug/-exclude-slug: %v", err)
+		}
+		shelleyFS.SetConversationFilter(filter)
+	}
+	shelleyFS.SetModelCloneTimeouts(modelCloneTimeouts)
+	shelleyFS.SetModelAliases(aliases)
+	shelleyFS.SetMarkdownChunkSize(*mdChunkSize)
+	shelleyFS.SetSparseMessages(*sparseMessages)
+	if *absSymlinks {
+		abs, err := filepath.Abs(mountpoint)
+		if err != nil {
+			log.Fatalf("Failed to resolve mountpoint: %v", err)
+		}
+		shelleyFS.SetAbsoluteSymlinks(abs)
+	}
+	shelleyFS.SetExportCompat(*exportCompat)
+	shelleyFS.SetTitleLinks(*titleLinks)
+	shelleyFS.SetMaxSendSize(*maxSendSize)
+	shelleyFS.SetBudget(shelleyfuse.Budget{Tokens: *budgetTokens, CostUSD: *budgetUSD})
+	shelleyFS.SetCacheBudget(cacheBudget)
+	shelleyFS.SetModelReadyTimeout(*modelReadyTimeout)
+	shelleyFS.SetCacheTimeouts(kernelTTL.timeouts)
+	shelleyFS.Diag = tracker
+	shelleyFS.SetHooks(shelleyfuse.Hooks{OnMessage: *onMessage, OnCreate: *onCreate, OnError: *onError})
+	if *accessLog != "" {
+		f, err := os.OpenFile(*accessLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			log.Fatalf("Invalid -access-log: %v", err)
+		}
+		shelleyFS.SetAccessLog(f)
+		log.Printf("Logging conversation reads to %s", *accessLog)
+	}
+	for status, name := range errnoOverrides {
+		errno, _ := shelleyfuse.ParseErrno(name)
+		shelleyfuse.SetStatusErrno(status, errno)
+	}
 
 	// Set up FUSE server options
 	opts := &fs.Options{}
 	opts.Debug = *debug
+	fuseLog, fuseLogFlags := log.Writer(), log.Flags()
+	if journalWriter != nil {
+		fuseLog, fuseLogFlags = journalWriter.WithPriority(journal.PriDebug, map[string]string{"SUBSYSTEM": "go-fuse"}), 0
+	}
+	if *debug {
+		var ops []string
+		if *debugOps != "" {
+			ops = strings.Split(*debugOps, ",")
+		}
+		fuseLog = diag.NewDebugFilter(fuseLog, ops, *debugSample, *debugRate)
+	}
+	opts.Logger = log.New(fuseLog, "", fuseLogFlags)
 	entryTimeout := time.Duration(0)
 	attrTimeout := time.Duration(0)
 	negativeTimeout := time.Duration(0)
@@ -139,7 +514,7 @@ func main() {
 	opts.NegativeTimeout = &negativeTimeout
 
 	// Mount the filesystem
-	fssrv, err := fs.Mount(mountpoint, shelleyFS, opts)
+	fssrv, err := shelleyfuse.Mount(mountpoint, shelleyFS, opts)
 	if err != nil {
 		log.Fatalf("Mount failed: %v", err)
 	}
@@ -152,6 +527,11 @@ func main() {
 		}
 		diagMux := http.NewServeMux()
 		diagMux.Handle("/diag", shelleyFS.Diag.Handler())
+		diagMux.Handle("/diag/cache", cacheBudget.Handler())
+		diagMux.Handle("/diag/handles", shelleyFS.Handles.Handler())
+		diagMux.Handle("/diag/clones", shelleyfuse.PendingClonesHandler(store))
+		diagMux.Handle("/diag/logstream", logStream.Handler())
+		diagMux.Handle("/diag/schema", schemaWatch.Handler())
 		diagSrv := &http.Server{Handler: diagMux}
 		go diagSrv.Serve(diagListener)
 		fmt.Fprintf(os.Stderr, "DIAG=http://%s/diag\n", diagListener.Addr().String())
@@ -168,12 +548,63 @@ func main() {
 		}
 		f.Close()
 	}
+	// Under systemd with Type=notify, report readiness too, and with
+	// WatchdogSec= prove every so often that the mount still answers.
+	if _, err := sdnotify.Notify(sdnotify.Ready); err != nil {
+		log.Printf("Failed to notify systemd: %v", err)
+	}
+	stopWatchdog := make(chan struct{})
+	if interval := sdnotify.WatchdogInterval(); interval > 0 {
+		go runWatchdog(mountpoint, interval/2, func() error {
+			_, err := sdnotify.Notify(sdnotify.Watchdog)
+			return err
+		}, stopWatchdog)
+	}
+
+	stopSync := make(chan struct{})
+	syncDone := make(chan struct{})
+	if mappingStore != nil {
+		go func() {
+			syncMappings(store, mappingStore, *syncInterval, stopSync)
+			close(syncDone)
+		}()
+	} else {
+		close(syncDone)
+	}
+
+	stopPoll := make(chan struct{})
+	if *pollActive > 0 {
+		if *cacheTTL == 0 {
+			log.Printf("-poll-active has no effect with -cache-ttl=0")
+		} else {
+			go shelleyfuse.NewPoller(shelleyFS, *pollActive, *pollIdle).Run(stopPoll)
+		}
+	}
+
+	if *pollInterval > 0 {
+		go shelleyfuse.NewInvalidator(shelleyFS, *pollInterval).Run(stopPoll)
+	}
+
+	if *prefetch > 0 {
+		if *cacheTTL == 0 {
+			log.Printf("-prefetch has no effect with -cache-ttl=0")
+		} else {
+			go func() {
+				log.Printf("Prefetched %d conversation(s)", shelleyFS.Prefetch(*prefetch))
+			}()
+		}
+	}
 
 	// Set up signal handling for clean unmount
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-signals
+		sdnotify.Notify(sdnotify.Stopping)
+		close(stopWatchdog)
+		close(stopPoll)
+		close(stopSync)
+		<-syncDone
 		fssrv.Unmount()
 		os.Exit(0)
 	}()
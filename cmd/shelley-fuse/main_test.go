@@ -2,6 +2,9 @@ package main
 
 import (
 	"testing"
+	"time"
+
+	shelleyfuse "shelley-fuse/fuse"
 )
 
 func TestParseListenAddress(t *testing.T) {
@@ -32,9 +35,9 @@ func TestParseListenAddress(t *testing.T) {
 			want:  "http://localhost:8080",
 		},
 		{
-			name:    "unix socket only should error",
-			input:   `[{"listen":"/run/shelley.sock","unit":"shelley.socket","activates":"shelley.service"}]`,
-			wantErr: true,
+			name:  "unix socket only becomes a unix URL",
+			input: `[{"listen":"/run/shelley.sock","unit":"shelley.socket","activates":"shelley.service"}]`,
+			want:  "unix:///run/shelley.sock",
 		},
 		{
 			name:  "multiple entries with unix socket first finds TCP",
@@ -108,3 +111,92 @@ func TestDiscoverBackendURL(t *testing.T) {
 	}
 	t.Logf("discovered URL: %s", url)
 }
+
+func TestModelDurations(t *testing.T) {
+	m := modelDurations{}
+	if err := m.Set("claude-haiku=5m"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("claude-opus=24h,gpt-4o=0s"); err != nil {
+		t.Fatal(err)
+	}
+	if m["claude-haiku"] != 5*time.Minute || m["claude-opus"] != 24*time.Hour || m["gpt-4o"] != 0 {
+		t.Errorf("unexpected durations: %v", m)
+	}
+	if got := m.String(); got != "claude-haiku=5m0s,claude-opus=24h0m0s,gpt-4o=0s" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, bad := range []string{"claude-haiku", "=5m", "claude-haiku=soon"} {
+		if err := (modelDurations{}).Set(bad); err == nil {
+			t.Errorf("Set(%q) should fail", bad)
+		}
+	}
+}
+
+func TestModelAliases(t *testing.T) {
+	m := modelAliases{}
+	if err := m.Set("fast=claude-haiku"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("smart=custom-f999b9b0,cheap=gpt-4o-mini"); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.String(); got != "cheap=gpt-4o-mini,fast=claude-haiku,smart=custom-f999b9b0" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, bad := range []string{"fast", "=claude-haiku", "fast=", "a/b=claude-haiku", "..=claude-haiku"} {
+		if err := (modelAliases{}).Set(bad); err == nil {
+			t.Errorf("Set(%q) should fail", bad)
+		}
+	}
+}
+
+func TestStatusErrnos(t *testing.T) {
+	m := statusErrnos{}
+	if err := m.Set("429=ebusy,503=EAGAIN"); err != nil {
+		t.Fatal(err)
+	}
+	if m[429] != "EBUSY" || m[503] != "EAGAIN" {
+		t.Errorf("unexpected mapping: %v", m)
+	}
+	if got := m.String(); got != "429=EBUSY,503=EAGAIN" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, bad := range []string{"429", "abc=EIO", "42=EIO", "429=ENOPE"} {
+		if err := (statusErrnos{}).Set(bad); err == nil {
+			t.Errorf("Set(%q) should fail", bad)
+		}
+	}
+}
+
+func TestKernelTTLs(t *testing.T) {
+	k := &kernelTTLs{timeouts: shelleyfuse.DefaultCacheTimeouts()}
+	if err := k.Set("immutable=24h,conversation=0s"); err != nil {
+		t.Fatal(err)
+	}
+	if got := k.String(); got != "immutable=24h0m0s,static=1h0m0s,models=5m0s,conversation=0s" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, bad := range []string{"immutable", "immutable=soon", "messages=1h", "static=-1s"} {
+		if err := (&kernelTTLs{}).Set(bad); err == nil {
+			t.Errorf("Set(%q) should fail", bad)
+		}
+	}
+}
+
+func TestResolveStatePath(t *testing.T) {
+	t.Setenv("HOME", "/home/test")
+	if got, err := resolveStatePath("/tmp/s.json", ""); err != nil || got != "/tmp/s.json" {
+		t.Errorf("-state only = %q, %v", got, err)
+	}
+	if got, err := resolveStatePath("", "work"); err != nil || got != "/home/test/.shelley-fuse/profiles/work/state.json" {
+		t.Errorf("-profile only = %q, %v", got, err)
+	}
+	if _, err := resolveStatePath("/tmp/s.json", "work"); err == nil {
+		t.Error("-state with -profile should fail")
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// mappingRecords converts the local mapping table into backend records.
+// Only conversations that exist on the server are included; unconversed
+// clones are local scratch state and would be meaningless on another mount,
+// and passthrough entries have no local ID worth sharing.
+func mappingRecords(store *state.Store) []shelley.MappingRecord {
+	var records []shelley.MappingRecord
+	for _, cs := range store.ListMappings() {
+		if !cs.Created || cs.ShelleyConversationID == "" || cs.Transient() {
+			continue
+		}
+		records = append(records, shelley.MappingRecord{
+			LocalID:        cs.LocalID,
+			ConversationID: cs.ShelleyConversationID,
+			Slug:           cs.Slug,
+			Meta:           cs.Meta,
+		})
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].LocalID < records[j].LocalID })
+	return records
+}
+
+// restoreMappings imports the mapping table stored on the backend into the
+// local store, so a fresh mount reuses the local IDs another mount chose.
+// Returns the number of records that kept their recorded local ID.
+func restoreMappings(store *state.Store, ms shelley.MappingStore) (int, error) {
+	records, err := ms.GetMappings()
+	if err != nil {
+		return 0, err
+	}
+	kept := 0
+	for _, r := range records {
+		id, err := store.ImportMapping(r.LocalID, r.ConversationID, r.Slug, r.Meta)
+		if err != nil {
+			log.Printf("Skipping mapping %s -> %s: %v", r.LocalID, r.ConversationID, err)
+			continue
+		}
+		if id == r.LocalID {
+			kept++
+		}
+	}
+	return kept, nil
+}
+
+// syncMappings pushes the mapping table to the backend whenever the state
+// store changes, checking every interval until stop is closed. A final push
+// is made on stop so the last changes are not lost on unmount.
+func syncMappings(store *state.Store, ms shelley.MappingStore, interval time.Duration, stop <-chan struct{}) {
+	var pushed uint64
+	push := func() {
+		rev := store.Revision()
+		if rev == pushed {
+			return
+		}
+		if err := ms.PutMappings(mappingRecords(store)); err != nil {
+			log.Printf("Failed to push mappings to backend: %v", err)
+			return
+		}
+		pushed = rev
+	}
+
+	push()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			push()
+		case <-stop:
+			push()
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestRestoreMappings(t *testing.T) {
+	server := mockserver.New(mockserver.WithMappings([]shelley.MappingRecord{
+		{LocalID: "aaaa0001", ConversationID: "conv-1", Slug: "first", Meta: map[string]string{"owner": "ci"}},
+		{LocalID: "aaaa0002", ConversationID: "conv-2"},
+	}))
+	defer server.Close()
+
+	store, err := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// conv-2 is already known locally under another ID; that ID must win.
+	existing, err := store.Adopt("conv-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kept, err := restoreMappings(store, shelley.NewClient(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kept != 1 {
+		t.Errorf("kept = %d, want 1", kept)
+	}
+	if got := store.GetByShelleyID("conv-1"); got != "aaaa0001" {
+		t.Errorf("conv-1 local ID = %q, want aaaa0001", got)
+	}
+	if got := store.GetByShelleyID("conv-2"); got != existing {
+		t.Errorf("conv-2 local ID = %q, want %q", got, existing)
+	}
+	if v, _ := store.GetMeta("aaaa0001", "owner"); v != "ci" {
+		t.Errorf("meta owner = %q, want ci", v)
+	}
+}
+
+func TestSyncMappingsPushesChanges(t *testing.T) {
+	server := mockserver.New(mockserver.WithMappings(nil))
+	defer server.Close()
+
+	store, err := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := store.AdoptWithSlug("conv-1", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Clone(); err != nil { // unconversed clones are not pushed
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	syncMappings(store, shelley.NewClient(server.URL), time.Hour, stop)
+
+	got := server.Mappings()
+	if len(got) != 1 || got[0].LocalID != id || got[0].ConversationID != "conv-1" || got[0].Slug != "hello" {
+		t.Errorf("pushed mappings = %+v", got)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// readoptConversations adopts every active conversation on backend that
+// store doesn't track, after a corrupt state file lost part of the mapping
+// table. It returns how many were adopted.
+func readoptConversations(store *state.Store, backend string, client shelley.ShelleyClient) (int, error) {
+	data, err := client.ListConversations()
+	if err != nil {
+		return 0, err
+	}
+	var convs []shelley.Conversation
+	if err := json.Unmarshal(data, &convs); err != nil {
+		return 0, err
+	}
+	adopted := 0
+	for _, c := range convs {
+		if store.GetByShelleyIDForBackend(backend, c.ConversationID) != "" {
+			continue
+		}
+		if _, err := store.AdoptWithMetadataForBackend(backend, c.ConversationID, derefStr(c.Slug), c.CreatedAt, c.UpdatedAt, derefStr(c.Model), derefStr(c.Cwd)); err != nil {
+			return adopted, err
+		}
+		adopted++
+	}
+	return adopted, nil
+}
+
+// repairFromServers re-adopts the conversations a repaired state file lost
+// on each backend it knows the URL of, and logs what it did.
+func repairFromServers(store *state.Store, clientMgr *shelley.ClientManager) {
+	for _, backend := range store.ListBackends() {
+		b := store.GetBackend(backend)
+		if b == nil || b.URL == "" {
+			continue
+		}
+		client, err := clientMgr.EnsureURL(backend, b.URL)
+		if err != nil {
+			log.Printf("State repair: backend %s: %v", backend, err)
+			continue
+		}
+		n, err := readoptConversations(store, backend, client)
+		if err != nil {
+			log.Printf("State repair: re-adopting from backend %s: %v", backend, err)
+			continue
+		}
+		log.Printf("State repair: re-adopted %d conversation(s) from backend %s", n, backend)
+	}
+}
+
+func derefStr(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
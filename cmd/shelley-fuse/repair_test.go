@@ -0,0 +1,36 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestReadoptConversations(t *testing.T) {
+	slug := "second"
+	server := mockserver.New(
+		mockserver.WithConversation("conv-1", nil),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-2", Slug: &slug}, nil),
+	)
+	defer server.Close()
+
+	store, err := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovered, _ := store.Adopt("conv-1")
+
+	n, err := readoptConversations(store, state.DefaultBackendName, shelley.NewClient(server.URL))
+	if err != nil || n != 1 {
+		t.Fatalf("readoptConversations = %d, %v; want 1", n, err)
+	}
+	if got := store.GetByShelleyID("conv-1"); got != recovered {
+		t.Errorf("conv-1 moved from %s to %s", recovered, got)
+	}
+	if id := store.GetByShelleyID("conv-2"); id == "" || store.Get(id).Slug != "second" {
+		t.Errorf("conv-2 not adopted with its slug")
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+
+	"shelley-fuse/shelley"
+)
+
+// tlsFlags adds the TLS options for https backends to flags. Call config
+// after parsing.
+type tlsFlags struct {
+	opts shelley.TLSOptions
+}
+
+func addTLSFlags(flags *flag.FlagSet) *tlsFlags {
+	t := &tlsFlags{}
+	flags.StringVar(&t.opts.CAFile, "tls-ca", "", "PEM `file` of CA certificates to trust for https backends, in addition to the system's")
+	flags.StringVar(&t.opts.CertFile, "tls-cert", "", "PEM client certificate `file` to present to https backends (with -tls-key)")
+	flags.StringVar(&t.opts.KeyFile, "tls-key", "", "PEM private key `file` of -tls-cert")
+	return t
+}
+
+// config loads the files, returning nil when no TLS flag was given.
+func (t *tlsFlags) config() (*tls.Config, error) {
+	return t.opts.Config()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// knownLocalID reports whether id is a conversation on any backend, so
+// traces are only kept for conversations that exist.
+func knownLocalID(store *state.Store) func(id string) bool {
+	return func(id string) bool {
+		for _, backend := range store.ListBackends() {
+			if store.GetForBackend(backend, id) != nil {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// localIDForServerID returns the local ID of a server conversation on any
+// backend, or "" if it has none.
+func localIDForServerID(store *state.Store, serverID string) string {
+	for _, backend := range store.ListBackends() {
+		if localID := store.GetByShelleyIDForBackend(backend, serverID); localID != "" {
+			return localID
+		}
+	}
+	return ""
+}
+
+// observeRequests returns a request observer that adds failed backend
+// requests about a conversation to its errors.log and, if tracing is
+// enabled, every such request to its .trace.
+func observeRequests(store *state.Store, tracker *diag.Tracker) func(shelley.RequestInfo) {
+	return func(info shelley.RequestInfo) {
+		if info.ConversationID == "" {
+			return
+		}
+		localID := localIDForServerID(store, info.ConversationID)
+		if localID == "" {
+			return
+		}
+		elapsed := info.Duration.Round(time.Microsecond)
+		switch {
+		case info.Err != nil:
+			tracker.Record(localID, "http", "%s %s failed after %s: %v", info.Method, info.Path, elapsed, info.Err)
+			tracker.RecordError(localID, "%s %s: %v", info.Method, info.Path, info.Err)
+		case info.Status >= 400:
+			tracker.Record(localID, "http", "%s %s %d (%s)", info.Method, info.Path, info.Status, elapsed)
+			tracker.RecordError(localID, "%s %s: %d %s: %s", info.Method, info.Path, info.Status, http.StatusText(info.Status), info.ErrorBody)
+		default:
+			tracker.Record(localID, "http", "%s %s %d (%s)", info.Method, info.Path, info.Status, elapsed)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestObserveRequests(t *testing.T) {
+	store, err := state.NewStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	localID, _ := store.Adopt("server-1")
+
+	tracker := diag.NewTracker()
+	tracker.EnableTrace(10, knownLocalID(store))
+	observe := observeRequests(store, tracker)
+
+	observe(shelley.RequestInfo{Method: "GET", Path: "/api/conversation/server-1", ConversationID: "server-1", Status: 200, Duration: time.Millisecond})
+	observe(shelley.RequestInfo{Method: "POST", Path: "/api/conversation/server-1/chat", ConversationID: "server-1", Status: 503, ErrorBody: "overloaded"})
+	observe(shelley.RequestInfo{Method: "GET", Path: "/api/conversation/server-1", ConversationID: "server-1", Err: errors.New("connection refused")})
+	observe(shelley.RequestInfo{Method: "GET", Path: "/api/conversation/unknown", ConversationID: "unknown", Status: 500})
+
+	trace := tracker.Trace(localID)
+	if n := strings.Count(trace, "\n"); n != 3 {
+		t.Errorf("expected 3 traced requests, got %d:\n%s", n, trace)
+	}
+	errs := tracker.ErrorLog(localID)
+	for _, want := range []string{
+		"POST /api/conversation/server-1/chat: 503 " + http.StatusText(503) + ": overloaded",
+		"GET /api/conversation/server-1: connection refused",
+	} {
+		if !strings.Contains(errs, want) {
+			t.Errorf("errors.log missing %q:\n%s", want, errs)
+		}
+	}
+	if strings.Contains(errs, " 200") {
+		t.Errorf("successful request logged as an error:\n%s", errs)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// watchdogProbe is looked up on the mount to check that it still answers.
+// It doesn't exist, and negative lookups aren't cached, so every probe is a
+// round trip through the kernel to our request loop.
+const watchdogProbe = ".watchdog-probe"
+
+// probeMount reports whether a lookup on mountpoint returns within timeout.
+// A probe that hangs is left running; until it returns, later calls fail
+// straight away instead of piling up more stuck lookups.
+func probeMount(mountpoint string, timeout time.Duration, inFlight chan struct{}) bool {
+	select {
+	case inFlight <- struct{}{}:
+	default:
+		return false
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := os.Lstat(filepath.Join(mountpoint, watchdogProbe))
+		<-inFlight
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		return err == nil || os.IsNotExist(err)
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// runWatchdog calls notify every interval for as long as the mount answers
+// probes, until stop is closed. systemd restarts the service once the
+// keep-alives stop coming for its WatchdogSec.
+func runWatchdog(mountpoint string, interval time.Duration, notify func() error, stop <-chan struct{}) {
+	inFlight := make(chan struct{}, 1)
+	healthy := true
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+		if !probeMount(mountpoint, interval, inFlight) {
+			if healthy {
+				log.Printf("Watchdog probe of %s failed; withholding keep-alives", mountpoint)
+			}
+			healthy = false
+			continue
+		}
+		if !healthy {
+			log.Printf("Watchdog probe of %s answered again", mountpoint)
+		}
+		healthy = true
+		if err := notify(); err != nil {
+			log.Printf("Watchdog keep-alive failed: %v", err)
+		}
+	}
+}
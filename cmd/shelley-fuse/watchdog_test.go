@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func TestRunWatchdog(t *testing.T) {
+	var beats atomic.Int32
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		runWatchdog(t.TempDir(), 10*time.Millisecond, func() error {
+			beats.Add(1)
+			return nil
+		}, stop)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	<-done
+	if beats.Load() < 2 {
+		t.Errorf("expected keep-alives while the directory answers, got %d", beats.Load())
+	}
+}
+
+// hangingRoot never answers lookups until release is closed.
+type hangingRoot struct {
+	fs.Inode
+	release chan struct{}
+}
+
+func (r *hangingRoot) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	<-r.release
+	return nil, syscall.ENOENT
+}
+
+func TestProbeMountHung(t *testing.T) {
+	dir := t.TempDir()
+	root := &hangingRoot{release: make(chan struct{})}
+	server, err := fs.Mount(dir, root, &fs.Options{})
+	if err != nil {
+		t.Fatalf("mount: %v", err)
+	}
+	defer server.Unmount()
+
+	inFlight := make(chan struct{}, 1)
+	if probeMount(dir, 50*time.Millisecond, inFlight) {
+		t.Fatal("probe of a hung mount succeeded")
+	}
+	// The first probe is still stuck, so the next fails without another lookup.
+	start := time.Now()
+	if probeMount(dir, time.Second, inFlight) || time.Since(start) > 500*time.Millisecond {
+		t.Error("second probe should fail at once while the first is stuck")
+	}
+
+	close(root.release)
+	deadline := time.Now().Add(5 * time.Second)
+	for !probeMount(dir, time.Second, inFlight) {
+		if time.Now().After(deadline) {
+			t.Fatal("probe still failing after the mount recovered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,105 @@
+package fuse
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/state"
+)
+
+// --- Access log: who read which conversation ---
+// With SetAccessLog, every open for reading of a file inside a
+// conversation's directory is logged as one JSON line, with the user,
+// group and process the kernel says opened it. The log is best-effort: the
+// kernel reports the caller of the open, not of later reads through the
+// same handle (or a dup or fork of it), and a file the kernel only learned
+// about from a readdirplus listing can't be traced back to its
+// conversation, so its opens are not logged.
+
+// AccessEntry is one line of the access log.
+type AccessEntry struct {
+	Time    time.Time `json:"time"`
+	Backend string    `json:"backend,omitempty"` // only on mounts with backends
+	LocalID string    `json:"local_id"`
+	Path    string    `json:"path"` // relative to the mount point
+	UID     uint32    `json:"uid"`
+	GID     uint32    `json:"gid"`
+	PID     uint32    `json:"pid"`               // 0 if the kernel didn't say
+	Command string    `json:"command,omitempty"` // from /proc/PID/comm, if readable
+}
+
+// accessLogger writes access log entries to w.
+type accessLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	state  *state.Store
+	failed bool // a write failed; reported once
+}
+
+// opened logs an open of nodeID by caller, if the node is inside a
+// conversation.
+func (l *accessLogger) opened(t *HandleTracker, nodeID uint64, caller fuse.Caller) {
+	p, ok := t.path(nodeID)
+	if !ok {
+		return
+	}
+	backend, localID, ok := l.conversationOf(p)
+	if !ok {
+		return
+	}
+	l.write(AccessEntry{
+		Time:    time.Now().UTC(),
+		Backend: backend,
+		LocalID: localID,
+		Path:    p,
+		UID:     caller.Uid,
+		GID:     caller.Gid,
+		PID:     caller.Pid,
+		Command: processCommand(caller.Pid),
+	})
+}
+
+// conversationOf finds the conversation a path relative to the mount point
+// lies inside: conversation/{id}/... on a mount without backends, or
+// backend/{name}/conversation/{id}/... on one with. {id} may be a local ID
+// or, with the slugs layout, a slug.
+func (l *accessLogger) conversationOf(p string) (backend, localID string, ok bool) {
+	parts := strings.Split(strings.TrimPrefix(p, "./"), "/")
+	if len(parts) >= 3 && parts[0] == "backend" {
+		backend, parts = parts[1], parts[2:]
+	}
+	if len(parts) < 3 || parts[0] != "conversation" {
+		return "", "", false
+	}
+	name := parts[1]
+	lookupBackend := backend
+	if lookupBackend == "" {
+		lookupBackend = l.state.GetDefaultBackend()
+	}
+	if l.state.GetForBackend(lookupBackend, name) != nil {
+		return backend, name, true
+	}
+	if id := l.state.GetBySlugForBackend(lookupBackend, name); id != "" {
+		return backend, id, true
+	}
+	return "", "", false
+}
+
+func (l *accessLogger) write(e AccessEntry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(line); err != nil && !l.failed {
+		l.failed = true
+		log.Printf("access log: %v (further errors not reported)", err)
+	}
+}
@@ -0,0 +1,100 @@
+package fuse
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+// syncBuffer is a bytes.Buffer safe to write from the FUSE server while a
+// test reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestAccessLogConversationOf(t *testing.T) {
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-access", "my-slug")
+	l := &accessLogger{state: store}
+	backend := store.GetDefaultBackend()
+
+	tests := []struct {
+		path        string
+		wantBackend string
+		wantID      string
+		wantOK      bool
+	}{
+		{"./conversation/" + localID + "/messages/all.md", "", localID, true},
+		{"./conversation/my-slug/ctl", "", localID, true},
+		{"./backend/" + backend + "/conversation/" + localID + "/send", backend, localID, true},
+		{"./conversation/" + localID, "", "", false},
+		{"./conversation/.events", "", "", false},
+		{"./conversation/unknown1/ctl", "", "", false},
+		{"./backend/nosuch/conversation/" + localID + "/ctl", "", "", false},
+		{"./README.md", "", "", false},
+	}
+	for _, tt := range tests {
+		gotBackend, gotID, gotOK := l.conversationOf(tt.path)
+		if gotBackend != tt.wantBackend || gotID != tt.wantID || gotOK != tt.wantOK {
+			t.Errorf("conversationOf(%q) = %q, %q, %v; want %q, %q, %v", tt.path, gotBackend, gotID, gotOK, tt.wantBackend, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	server := mockConversationsServer(t, []shelley.Conversation{{ConversationID: "conv-access"}})
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-access")
+	var logged syncBuffer
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetAccessLog(&logged)
+	mountPoint := mountWithMount(t, shelleyFS)
+
+	dir := filepath.Join(mountPoint, "conversation", localID)
+	if _, err := os.ReadFile(filepath.Join(dir, "messages", "all.json")); err != nil {
+		t.Fatal(err)
+	}
+	// Not logged: outside any conversation, and opened for writing.
+	if _, err := os.ReadFile(filepath.Join(mountPoint, "README.md")); err != nil {
+		t.Fatal(err)
+	}
+	if f, err := os.OpenFile(filepath.Join(dir, "ctl"), os.O_WRONLY, 0); err == nil {
+		f.Close()
+	}
+
+	lines := strings.Split(strings.TrimSuffix(logged.String(), "\n"), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("access log has %d lines, want 1:\n%s", len(lines), logged.String())
+	}
+	var e AccessEntry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.LocalID != localID || e.Path != "conversation/"+localID+"/messages/all.json" {
+		t.Errorf("entry = %+v, want local_id %s and path of messages/all.json", e, localID)
+	}
+	if e.UID != uint32(os.Getuid()) || e.PID == 0 {
+		t.Errorf("entry = %+v, want uid %d and a pid", e, os.Getuid())
+	}
+}
@@ -0,0 +1,167 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/state"
+)
+
+// --- ActivityBoard: what the poller last saw, for /conversation/.activity.json ---
+// The background poller (see Poller) records each conversation it refreshes
+// here: when it was last active, how many messages it has, and whether the
+// agent is waiting for the user. Status bars and prompts read the summary
+// instead of walking every conversation themselves. Without a poller the
+// board stays empty.
+
+// ConversationActivity is one conversation as of its last poll.
+type ConversationActivity struct {
+	LocalID        string `json:"local_id"`
+	ConversationID string `json:"conversation_id"`
+	Slug           string `json:"slug,omitempty"`
+	LastActivity   string `json:"last_activity,omitempty"` // RFC3339
+	Messages       int    `json:"messages"`
+	LastMessage    string `json:"last_message,omitempty"` // slug of the last message, e.g. "user" or "agent"
+	Working        bool   `json:"working"`
+	// AwaitingReply is set when the agent has answered and stopped: the
+	// last message is the agent's and the conversation isn't working.
+	AwaitingReply bool   `json:"awaiting_reply"`
+	PolledAt      string `json:"polled_at"` // RFC3339
+}
+
+// activitySummary is the content of .activity.json.
+type activitySummary struct {
+	AwaitingReply int                    `json:"awaiting_reply"`
+	Working       int                    `json:"working"`
+	Conversations []ConversationActivity `json:"conversations"`
+}
+
+// ActivityBoard holds the latest ConversationActivity of each polled
+// conversation, by server conversation ID. A nil *ActivityBoard records
+// nothing.
+type ActivityBoard struct {
+	mu      sync.Mutex
+	entries map[string]ConversationActivity
+	updated time.Time
+}
+
+// NewActivityBoard creates an empty board.
+func NewActivityBoard() *ActivityBoard {
+	return &ActivityBoard{entries: make(map[string]ConversationActivity)}
+}
+
+// Record replaces what is known about a conversation. The board counts as
+// updated only if something other than the poll time changed.
+func (b *ActivityBoard) Record(a ConversationActivity) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	old, ok := b.entries[a.ConversationID]
+	old.PolledAt = a.PolledAt
+	if !ok || old != a {
+		b.updated = time.Now()
+	}
+	b.entries[a.ConversationID] = a
+}
+
+// Forget drops a conversation that is no longer tracked.
+func (b *ActivityBoard) Forget(conversationID string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.entries[conversationID]; ok {
+		delete(b.entries, conversationID)
+		b.updated = time.Now()
+	}
+}
+
+// lastUpdate returns when the board last changed.
+func (b *ActivityBoard) lastUpdate() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.updated
+}
+
+// summary renders .activity.json for the conversations store tracks, most
+// recently active first.
+func (b *ActivityBoard) summary(store *state.Store) []byte {
+	b.mu.Lock()
+	sum := activitySummary{Conversations: []ConversationActivity{}}
+	for id, a := range b.entries {
+		if store.GetByShelleyID(id) == "" {
+			continue
+		}
+		sum.Conversations = append(sum.Conversations, a)
+	}
+	b.mu.Unlock()
+
+	sort.Slice(sum.Conversations, func(i, j int) bool {
+		ci, cj := sum.Conversations[i], sum.Conversations[j]
+		if ci.LastActivity != cj.LastActivity {
+			return ci.LastActivity > cj.LastActivity
+		}
+		return ci.LocalID < cj.LocalID
+	})
+	for _, a := range sum.Conversations {
+		if a.AwaitingReply {
+			sum.AwaitingReply++
+		}
+		if a.Working {
+			sum.Working++
+		}
+	}
+	data, _ := json.MarshalIndent(sum, "", "  ")
+	return append(data, '\n')
+}
+
+// --- ActivityNode: /conversation/.activity.json ---
+
+type ActivityNode struct {
+	fs.Inode
+	board     *ActivityBoard
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*ActivityNode)(nil))
+var _ = (fs.NodeGetattrer)((*ActivityNode)(nil))
+
+// Open renders the summary once; the handle reads and sizes that snapshot.
+func (n *ActivityNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(n.diag, "ActivityNode", "Open", "").Done()
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &ConvContentFileHandle{content: n.board.summary(n.state), messageTime: n.mtime()}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *ActivityNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fg, ok := f.(fs.FileGetattrer); ok {
+		return fg.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(len(n.board.summary(n.state)))
+	setTimestamps(&out.Attr, n.mtime())
+	return 0
+}
+
+// mtime is when the poller last recorded something, so `find -newer` and
+// editors notice changes.
+func (n *ActivityNode) mtime() time.Time {
+	if t := n.board.lastUpdate(); !t.IsZero() {
+		return t
+	}
+	return n.startTime
+}
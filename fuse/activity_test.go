@@ -0,0 +1,70 @@
+package fuse
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestActivityFile(t *testing.T) {
+	question, answer := "Done yet?", `{"Content":[{"Type":2,"Text":"Yes."}]}`
+	server := mockserver.New(
+		mockserver.WithConversation("conv-answered", []shelley.Message{
+			{MessageID: "m1", ConversationID: "conv-answered", SequenceID: 1, Type: "user", UserData: &question},
+			{MessageID: "m2", ConversationID: "conv-answered", SequenceID: 2, Type: "agent", LLMData: &answer, CreatedAt: "2026-01-02T03:04:05Z"},
+		}),
+		mockserver.WithConversation("conv-busy", []shelley.Message{
+			{MessageID: "m1", ConversationID: "conv-busy", SequenceID: 1, Type: "user", UserData: &question},
+		}),
+		mockserver.WithConversationWorking("conv-busy", true),
+	)
+	defer server.Close()
+	store := testStore(t)
+	now := time.Now()
+	answered, _ := store.AdoptWithMetadata("conv-answered", "answered", "", now.Format(time.RFC3339), "", "")
+	busy, _ := store.AdoptWithMetadata("conv-busy", "", "", now.Format(time.RFC3339), "", "")
+
+	shelleyFS := NewFS(shelley.NewCachingClient(shelley.NewClient(server.URL), time.Hour), store, time.Hour)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	path := filepath.Join(mountPoint, "conversation", ".activity.json")
+
+	read := func() activitySummary {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum activitySummary
+		if err := json.Unmarshal(data, &sum); err != nil {
+			t.Fatalf("%v: %s", err, data)
+		}
+		return sum
+	}
+
+	// Nothing is known before the first poll.
+	if sum := read(); len(sum.Conversations) != 0 {
+		t.Fatalf("before polling: %+v", sum)
+	}
+
+	NewPoller(shelleyFS, time.Second, time.Hour).poll(now)
+	sum := read()
+	if sum.AwaitingReply != 1 || sum.Working != 1 || len(sum.Conversations) != 2 {
+		t.Fatalf("after polling: %+v", sum)
+	}
+	byID := make(map[string]ConversationActivity)
+	for _, a := range sum.Conversations {
+		byID[a.LocalID] = a
+	}
+	if a := byID[answered]; !a.AwaitingReply || a.Messages != 2 || a.LastMessage != "agent" || a.Slug != "answered" {
+		t.Errorf("answered conversation: %+v", a)
+	}
+	if a := byID[busy]; a.AwaitingReply || !a.Working || a.Messages != 1 || a.LastMessage != "user" {
+		t.Errorf("busy conversation: %+v", a)
+	}
+}
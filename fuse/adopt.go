@@ -0,0 +1,47 @@
+package fuse
+
+import (
+	"fmt"
+	"log"
+
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- Adopting server conversations in batches ---
+// Listing /conversation adopts every server conversation that isn't tracked
+// yet. A server with thousands of them would otherwise mean thousands of
+// state file writes, each holding the store's lock, inside a single Readdir.
+// Instead they are adopted adoptBatchSize at a time, one write per batch,
+// releasing the lock in between so other operations on the mount go on, and
+// the listing's progress shows as its phase in /diag. Batches with nothing
+// new in them aren't written at all.
+
+// adoptBatchSize is how many conversations are adopted per state write.
+const adoptBatchSize = 200
+
+// adoptRequest describes conv for adoption with its API metadata.
+func adoptRequest(conv shelley.Conversation) state.AdoptRequest {
+	return state.AdoptRequest{
+		ShelleyConversationID: conv.ConversationID,
+		Slug:                  derefStr(conv.Slug),
+		APICreatedAt:          conv.CreatedAt,
+		APIUpdatedAt:          conv.UpdatedAt,
+		Model:                 derefStr(conv.Model),
+		Cwd:                   derefStr(conv.Cwd),
+	}
+}
+
+// adoptBatched adopts reqs in batches of adoptBatchSize, reporting progress
+// as op's phase. A batch that fails is logged and skipped; its conversations
+// are adopted by a later listing or lookup.
+func (c *ConversationListNode) adoptBatched(op *diag.OpHandle, reqs []state.AdoptRequest) {
+	for start := 0; start < len(reqs); start += adoptBatchSize {
+		end := min(start+adoptBatchSize, len(reqs))
+		op.SetPhase(fmt.Sprintf("adopting %d-%d of %d", start+1, end, len(reqs)))
+		if err := c.state.AdoptBatch(reqs[start:end]); err != nil {
+			log.Printf("Adopting conversations %d-%d of %d failed: %v", start+1, end, len(reqs), err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestReaddirAdoptsInBatches(t *testing.T) {
+	const total = 2*adoptBatchSize + 10
+	var opts []mockserver.Option
+	for i := 0; i < total; i++ {
+		opts = append(opts, mockserver.WithConversation(fmt.Sprintf("server-%04d", i), nil))
+	}
+	server := mockserver.New(opts...)
+	defer server.Close()
+	store := testStore(t)
+	node := &ConversationListNode{client: shelley.NewClient(server.URL), state: store, startTime: time.Now()}
+
+	rev := store.Revision()
+	stream, errno := node.Readdir(context.Background())
+	if errno != 0 {
+		t.Fatalf("Readdir: %v", errno)
+	}
+	dirs := 0
+	for stream.HasNext() {
+		if e, _ := stream.Next(); store.Get(e.Name) != nil {
+			dirs++
+		}
+	}
+	if dirs != total {
+		t.Errorf("listed %d conversation directories, want %d", dirs, total)
+	}
+	if got := store.Revision() - rev; got != 3 {
+		t.Errorf("state written %d times, want 3 (one per batch)", got)
+	}
+
+	// Listing again adopts nothing and writes nothing.
+	rev = store.Revision()
+	if _, errno := node.Readdir(context.Background()); errno != 0 {
+		t.Fatalf("second Readdir: %v", errno)
+	}
+	if store.Revision() != rev {
+		t.Errorf("second listing wrote the state %d times", store.Revision()-rev)
+	}
+}
@@ -0,0 +1,176 @@
+package fuse
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- ConvArchiveNode: /conversation/{id}/export.tar, export.zip ---
+// The whole conversation as one archive, so `cp export.tar ~/backup/` takes
+// a complete snapshot. Everything is under a directory named by local ID:
+//
+//	{id}/messages/all.md
+//	{id}/messages/all.json
+//	{id}/messages/{NNN-slug}/content.md
+//	{id}/messages/{NNN-slug}/content.txt
+//	{id}/messages/{NNN-slug}/result.{ext}   (tool results)
+//
+// with the same contents as the files of the same name in the mount, and
+// each message's creation time as its modification time. The archive is
+// built in memory on open; the handle reads and sizes that snapshot, so cp
+// sees the real size through fstat even though stat on the path says 0.
+
+type archiveFormat int
+
+const (
+	archiveTar archiveFormat = iota
+	archiveZip
+)
+
+// archiveFile is one file of an export archive.
+type archiveFile struct {
+	name    string
+	data    []byte
+	modTime time.Time
+}
+
+// conversationArchiveFiles lists the files of the export archive of a
+// conversation with the given local ID, in archive order.
+func conversationArchiveFiles(localID string, result *ParseResult, modTime time.Time) ([]archiveFile, error) {
+	allJSON, err := shelley.FormatJSON(result.Messages)
+	if err != nil {
+		return nil, err
+	}
+	dir := localID + "/messages/"
+	files := []archiveFile{
+		{name: dir + "all.md", data: shelley.FormatMarkdown(result.Messages), modTime: modTime},
+		{name: dir + "all.json", data: append(allJSON, '\n'), modTime: modTime},
+	}
+	for i := range result.Messages {
+		msg := &result.Messages[i]
+		t := modTime
+		if mt, err := time.Parse(time.RFC3339, msg.CreatedAt); err == nil {
+			t = mt
+		}
+		msgDir := dir + messageFileBase(msg.SequenceID, shelley.MessageSlug(msg, result.ToolMap), result.MaxSeqID) + "/"
+		files = append(files,
+			archiveFile{name: msgDir + "content.md", data: shelley.FormatMarkdown([]shelley.Message{*msg}), modTime: t},
+			archiveFile{name: msgDir + "content.txt", data: shelley.FormatPlainText(msg), modTime: t})
+		if data, ext, ok := shelley.ToolResultPayload(msg); ok {
+			files = append(files, archiveFile{name: msgDir + "result." + ext, data: data, modTime: t})
+		}
+	}
+	return files, nil
+}
+
+// writeArchive writes files to w as a tar or zip archive.
+func writeArchive(w io.Writer, format archiveFormat, files []archiveFile) error {
+	if format == archiveZip {
+		zw := zip.NewWriter(w)
+		for _, f := range files {
+			fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: f.modTime})
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(f.data); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	}
+	tw := tar.NewWriter(w)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.name, Mode: 0444, Size: int64(len(f.data)), ModTime: f.modTime, Format: tar.FormatPAX}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+type ConvArchiveNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	format      archiveFormat
+	startTime   time.Time
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*ConvArchiveNode)(nil))
+var _ = (fs.NodeGetattrer)((*ConvArchiveNode)(nil))
+
+// modTime is the time given to the archive's all.* files: when the
+// conversation was last updated, as far as the state knows.
+func (n *ConvArchiveNode) modTime() time.Time {
+	cs := n.state.Get(n.localID)
+	if cs == nil {
+		return n.startTime
+	}
+	if t, err := time.Parse(time.RFC3339, cs.APIUpdatedAt); err == nil {
+		return t
+	}
+	if !cs.CreatedAt.IsZero() {
+		return cs.CreatedAt
+	}
+	return n.startTime
+}
+
+func (n *ConvArchiveNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	op := diag.Track(n.diag, "ConvArchiveNode", "Open", n.localID)
+	defer op.Done()
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	cs := n.state.Get(n.localID)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return nil, 0, syscall.ENOENT
+	}
+	convData, err := n.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return nil, 0, backendErrno(err)
+	}
+	result, err := n.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return nil, 0, syscall.EIO
+	}
+	op.SetPhase("archiving")
+	modTime := n.modTime()
+	files, err := conversationArchiveFiles(n.localID, result, modTime)
+	if err != nil {
+		return nil, 0, syscall.EIO
+	}
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, n.format, files); err != nil {
+		return nil, 0, syscall.EIO
+	}
+	return &ConvContentFileHandle{content: buf.Bytes(), messageTime: modTime}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *ConvArchiveNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fga, ok := f.(fs.FileGetattrer); ok {
+		return fga.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	if exportCompat(&n.Inode) {
+		openedSize(ctx, n, out)
+	}
+	setTimestamps(&out.Attr, n.modTime())
+	return 0
+}
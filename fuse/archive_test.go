@@ -0,0 +1,111 @@
+package fuse
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestConversationArchive(t *testing.T) {
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-export", SequenceID: 1, Type: "user", UserData: strPtr("back me up"), CreatedAt: "2024-01-15T10:30:00Z"},
+		{MessageID: "m2", ConversationID: "conv-export", SequenceID: 2, Type: "shelley", LLMData: strPtr("done"), CreatedAt: "2024-01-15T10:31:00Z"},
+	}
+	server := mockserver.New(mockserver.WithConversation("conv-export", msgs))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, "conv-export", "")
+	unsent, _ := store.Clone()
+	client := shelley.NewClient(server.URL)
+	ctx := context.Background()
+
+	read := func(id, name string) ([]byte, syscall.Errno) {
+		conv := &ConversationNode{localID: id, client: client, state: store, startTime: time.Now()}
+		fs.NewNodeFS(conv, &fs.Options{})
+		var out fuse.EntryOut
+		in, errno := conv.Lookup(ctx, name, &out)
+		if errno != 0 {
+			return nil, errno
+		}
+		fh, _, errno := in.Operations().(*ConvArchiveNode).Open(ctx, syscall.O_RDONLY)
+		if errno != 0 {
+			return nil, errno
+		}
+		return fh.(*ConvContentFileHandle).content, 0
+	}
+
+	want := map[string]string{
+		localID + "/messages/all.md":              "back me up",
+		localID + "/messages/all.json":            `"message_id": "m2"`,
+		localID + "/messages/0-user/content.md":   "back me up",
+		localID + "/messages/1-agent/content.txt": "done",
+		localID + "/messages/1-agent/content.md":  "done",
+		localID + "/messages/0-user/content.txt":  "back me up",
+	}
+
+	data, errno := read(localID, "export.tar")
+	if errno != 0 {
+		t.Fatalf("export.tar: %v", errno)
+	}
+	got := make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading export.tar: %v", err)
+		}
+		body, _ := io.ReadAll(tr)
+		got[hdr.Name] = string(body)
+		if hdr.Name == localID+"/messages/0-user/content.md" && !hdr.ModTime.Equal(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)) {
+			t.Errorf("%s modified %v, want the message's creation time", hdr.Name, hdr.ModTime)
+		}
+	}
+	for name, substr := range want {
+		if !strings.Contains(got[name], substr) {
+			t.Errorf("export.tar %s = %q, want it to contain %q", name, got[name], substr)
+		}
+	}
+
+	data, errno = read(localID, "export.zip")
+	if errno != 0 {
+		t.Fatalf("export.zip: %v", errno)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("reading export.zip: %v", err)
+	}
+	if len(zr.File) != len(got) {
+		t.Errorf("export.zip has %d files, export.tar %d", len(zr.File), len(got))
+	}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(body) != got[f.Name] {
+			t.Errorf("export.zip %s differs from export.tar", f.Name)
+		}
+	}
+
+	// Nothing to export before the conversation exists.
+	if _, errno := read(unsent, "export.tar"); errno != syscall.ENOENT {
+		t.Errorf("export.tar of an unsent conversation = %v, want ENOENT", errno)
+	}
+}
@@ -0,0 +1,147 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+)
+
+// --- AttachNode: /conversation/attach ---
+// Writing a Shelley conversation ID or slug adopts that one conversation
+// right away, without listing (and so adopting) everything else, and the
+// local ID can be read back from the same descriptor:
+//
+//	exec 3<>conversation/attach
+//	echo fix-the-build >&3
+//	read -r id <&3
+//
+// A server ID is fetched directly; a slug is looked up in the active and
+// archived listings. A name that is already tracked just resolves to its
+// local ID. Unknown names fail the write with ENOENT.
+
+type AttachNode struct {
+	fs.Inode
+	list *ConversationListNode
+}
+
+var _ = (fs.NodeOpener)((*AttachNode)(nil))
+var _ = (fs.NodeGetattrer)((*AttachNode)(nil))
+var _ = (fs.NodeSetattrer)((*AttachNode)(nil))
+
+func (n *AttachNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return &attachHandle{node: n}, fuse.FOPEN_DIRECT_IO | fuse.FOPEN_NONSEEKABLE, 0
+}
+
+func (n *AttachNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0644
+	setTimestamps(&out.Attr, n.list.startTime)
+	return 0
+}
+
+func (n *AttachNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	// Accept truncate (from shell > redirect) silently
+	return n.Getattr(ctx, f, out)
+}
+
+// attachHandle attaches each name written to it and queues the local IDs,
+// one per line, for reading back.
+type attachHandle struct {
+	node    *AttachNode
+	mu      sync.Mutex
+	pending []byte // attached local IDs not read yet
+}
+
+var _ = (fs.FileWriter)((*attachHandle)(nil))
+var _ = (fs.FileReader)((*attachHandle)(nil))
+
+func (h *attachHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	op := diag.Track(h.node.list.diag, "attachHandle", "Write", "")
+	defer op.Done()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, name := range strings.Fields(string(data)) {
+		localID, errno := h.node.list.attach(op, name)
+		if errno != 0 {
+			return 0, errno
+		}
+		h.pending = append(h.pending, localID+"\n"...)
+	}
+	return uint32(len(data)), 0
+}
+
+// Read returns the local IDs attached through this descriptor that haven't
+// been read yet. Like a pipe it ignores the offset, which after a write
+// points past anything there is to read.
+func (h *attachHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := copy(dest, h.pending)
+	h.pending = h.pending[n:]
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+// attach resolves name, a local ID, server ID or slug, to a local ID,
+// adopting the conversation if it isn't tracked yet.
+func (c *ConversationListNode) attach(op *diag.OpHandle, name string) (string, syscall.Errno) {
+	if !isValidFilename(name) {
+		return "", syscall.EINVAL
+	}
+	if c.state.Get(name) != nil {
+		return name, 0
+	}
+	if localID := c.state.GetByShelleyID(name); localID != "" {
+		return localID, 0
+	}
+	if localID := c.state.GetBySlug(name); localID != "" {
+		return localID, 0
+	}
+
+	op.SetPhase("HTTP GET GetConversation")
+	data, err := c.client.GetConversation(name)
+	var statusErr *shelley.StatusError
+	switch {
+	case err == nil:
+		var conv shelley.Conversation
+		if err := json.Unmarshal(data, &conv); err != nil || conv.ConversationID == "" {
+			conv.ConversationID = name
+		}
+		return c.adoptConversation(conv)
+	case errors.As(err, &statusErr) && statusErr.StatusCode == 404:
+		// Not a server ID; try it as a slug.
+	default:
+		return "", backendErrno(err)
+	}
+
+	op.SetPhase("HTTP GET ListConversations")
+	for _, fetch := range []func() ([]shelley.Conversation, error){c.fetchServerConversations, c.fetchArchivedConversations} {
+		convs, err := fetch()
+		if err != nil {
+			return "", backendErrno(err)
+		}
+		for _, conv := range convs {
+			if conv.Slug != nil && *conv.Slug == name {
+				return c.adoptConversation(conv)
+			}
+		}
+	}
+	return "", syscall.ENOENT
+}
+
+// adoptConversation tracks conv locally with its API metadata.
+func (c *ConversationListNode) adoptConversation(conv shelley.Conversation) (string, syscall.Errno) {
+	localID, err := c.state.AdoptWithMetadata(conv.ConversationID, derefStr(conv.Slug), conv.CreatedAt, conv.UpdatedAt, derefStr(conv.Model), derefStr(conv.Cwd))
+	if err != nil {
+		log.Printf("Attach: adopting %s failed: %v", conv.ConversationID, err)
+		return "", syscall.EIO
+	}
+	return localID, 0
+}
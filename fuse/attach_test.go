@@ -0,0 +1,63 @@
+package fuse
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestAttach(t *testing.T) {
+	slug := "nightly-build"
+	server := mockserver.New(
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-by-id"}, nil),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-by-slug", Slug: &slug}, nil),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-untouched"}, nil),
+	)
+	defer server.Close()
+	store := testStore(t)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	f, err := os.OpenFile(filepath.Join(mountPoint, "conversation", "attach"), os.O_RDWR, 0)
+	if err != nil {
+		t.Fatalf("open attach: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("conv-by-id\n"); err != nil {
+		t.Fatalf("attach by server ID: %v", err)
+	}
+	if _, err := f.WriteString(slug + "\n"); err != nil {
+		t.Fatalf("attach by slug: %v", err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	ids := strings.Fields(string(data))
+	if len(ids) != 2 || ids[0] != store.GetByShelleyID("conv-by-id") || ids[1] != store.GetByShelleyID("conv-by-slug") {
+		t.Errorf("read back %q, want the local IDs of both conversations", data)
+	}
+	if store.GetByShelleyID("conv-untouched") != "" {
+		t.Error("attach adopted a conversation it wasn't asked for")
+	}
+
+	// Attaching again resolves to the same local ID.
+	if _, err := f.WriteString("conv-by-id\n"); err != nil {
+		t.Fatalf("attach again: %v", err)
+	}
+	if data, _ := io.ReadAll(f); strings.TrimSpace(string(data)) != ids[0] {
+		t.Errorf("second attach read back %q, want %q", data, ids[0])
+	}
+
+	if _, err := f.WriteString("no-such-conversation\n"); !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("attach of an unknown name: got %v, want ENOENT", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	neturl "net/url"
 	"regexp"
 	"strings"
 	"syscall"
@@ -25,6 +26,20 @@ type ShelleyDirNode struct {
 	state        *state.Store
 	clientMgr    *shelley.ClientManager
 	cloneTimeout time.Duration
+	cloneByModel map[string]time.Duration
+	modelAliases map[string]string
+	layout       Layout
+	mdChunkSize  int
+	sparseMsgs   bool
+	maxSend      int
+	readyTimeout time.Duration
+	events       *EventBus
+	activity     *ActivityBoard
+	usage        *UsageBoard
+	budget       Budget
+	filter       *ConversationFilter
+	sends        *RecentSends
+	snapshots    *Snapshots
 	parsedCache  *ParsedMessageCache
 	startTime    time.Time
 	diag         *diag.Tracker
@@ -36,10 +51,10 @@ var _ = (fs.NodeGetattrer)((*ShelleyDirNode)(nil))
 
 func (s *ShelleyDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(s.diag, "ShelleyDirNode", "Lookup", name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&s.Inode).Conversation)
 
 	if name == "backend" {
-		return s.NewInode(ctx, &BackendListNode{state: s.state, clientMgr: s.clientMgr, cloneTimeout: s.cloneTimeout, parsedCache: s.parsedCache, startTime: s.startTime, diag: s.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		return s.NewInode(ctx, &BackendListNode{state: s.state, clientMgr: s.clientMgr, cloneTimeout: s.cloneTimeout, cloneByModel: s.cloneByModel, modelAliases: s.modelAliases, layout: s.layout, mdChunkSize: s.mdChunkSize, sparseMsgs: s.sparseMsgs, maxSend: s.maxSend, readyTimeout: s.readyTimeout, parsedCache: s.parsedCache, startTime: s.startTime, events: s.events, activity: s.activity, usage: s.usage, budget: s.budget, filter: s.filter, sends: s.sends, snapshots: s.snapshots, diag: s.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
 	}
 	return nil, syscall.ENOENT
 }
@@ -54,7 +69,7 @@ func (s *ShelleyDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Err
 func (s *ShelleyDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, s.startTime)
-	out.SetTimeout(cacheTTLConversation)
+	out.SetTimeout(cacheTimeouts(&s.Inode).Conversation)
 	return 0
 }
 
@@ -65,6 +80,20 @@ type BackendListNode struct {
 	state        *state.Store
 	clientMgr    *shelley.ClientManager
 	cloneTimeout time.Duration
+	cloneByModel map[string]time.Duration
+	modelAliases map[string]string
+	layout       Layout
+	mdChunkSize  int
+	sparseMsgs   bool
+	maxSend      int
+	readyTimeout time.Duration
+	events       *EventBus
+	activity     *ActivityBoard
+	usage        *UsageBoard
+	budget       Budget
+	filter       *ConversationFilter
+	sends        *RecentSends
+	snapshots    *Snapshots
 	parsedCache  *ParsedMessageCache
 	startTime    time.Time
 	diag         *diag.Tracker
@@ -126,12 +155,24 @@ func (b *BackendListNode) Lookup(ctx context.Context, name string, out *fuse.Ent
 				return b.state.GetDefaultBackend()
 			},
 			startTime: b.startTime,
-		}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		}, fs.StableAttr{Mode: backendListNames.mode("default")}), 0
+	}
+
+	if name == "latency.json" {
+		// Legacy single-client mode (NewFS) has no manager to ask.
+		if b.clientMgr == nil {
+			return nil, syscall.ENOENT
+		}
+		return b.NewInode(ctx, &LatencyNode{clientMgr: b.clientMgr, state: b.state, startTime: b.startTime, diag: b.diag}, fs.StableAttr{Mode: backendListNames.mode("latency.json")}), 0
+	}
+
+	if name == "state_status" {
+		return b.NewInode(ctx, &StateStatusNode{state: b.state, diag: b.diag}, fs.StableAttr{Mode: backendListNames.mode("state_status")}), 0
 	}
 
 	// Check if backend exists
 	if b.state.GetBackend(name) != nil {
-		return b.NewInode(ctx, &BackendNode{name: name, state: b.state, clientMgr: b.clientMgr, cloneTimeout: b.cloneTimeout, parsedCache: b.parsedCache, startTime: b.startTime, diag: b.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		return b.NewInode(ctx, &BackendNode{name: name, state: b.state, clientMgr: b.clientMgr, cloneTimeout: b.cloneTimeout, cloneByModel: b.cloneByModel, modelAliases: b.modelAliases, layout: b.layout, mdChunkSize: b.mdChunkSize, sparseMsgs: b.sparseMsgs, maxSend: b.maxSend, readyTimeout: b.readyTimeout, parsedCache: b.parsedCache, startTime: b.startTime, events: b.events, activity: b.activity, usage: b.usage, budget: b.budget, filter: b.filter, sends: b.sends, snapshots: b.snapshots, diag: b.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
 	}
 
 	return nil, syscall.ENOENT
@@ -146,8 +187,13 @@ func (b *BackendListNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Er
 	// "default" is a symlink to the current default backend
 	// Only include it if it's been explicitly set (not the default "main")
 	if b.state.GetDefaultBackend() != state.DefaultBackendName {
-		entries = append(entries, fuse.DirEntry{Name: "default", Mode: syscall.S_IFLNK})
+		entries = append(entries, backendListNames.entry("default"))
+	}
+
+	if b.clientMgr != nil {
+		entries = append(entries, backendListNames.entry("latency.json"))
 	}
+	entries = append(entries, backendListNames.entry("state_status"))
 
 	// Add backend directories
 	for _, name := range backends {
@@ -160,7 +206,7 @@ func (b *BackendListNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Er
 func (b *BackendListNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, b.startTime)
-	out.SetTimeout(cacheTTLConversation)
+	out.SetTimeout(cacheTimeouts(&b.Inode).Conversation)
 	return 0
 }
 
@@ -169,10 +215,11 @@ func (b *BackendListNode) Getattr(ctx context.Context, f fs.FileHandle, out *fus
 // Dotted names get empty URL. Reserved name 'default' returns EEXIST.
 func (b *BackendListNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(b.diag, "BackendListNode", "Mkdir", name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&b.Inode).Conversation)
 
-	// "default" is a reserved symlink name - return EEXIST to indicate it already exists
-	if name == "default" {
+	// "default" is a reserved symlink name and latency.json and state_status
+	// files - return EEXIST to indicate they already exist
+	if name == "default" || name == "latency.json" || name == "state_status" {
 		return nil, syscall.EEXIST
 	}
 
@@ -199,7 +246,7 @@ func (b *BackendListNode) Mkdir(ctx context.Context, name string, mode uint32, o
 	}
 
 	// Return the newly created backend directory node
-	return b.NewInode(ctx, &BackendNode{name: name, state: b.state, clientMgr: b.clientMgr, cloneTimeout: b.cloneTimeout, parsedCache: b.parsedCache, startTime: b.startTime, diag: b.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+	return b.NewInode(ctx, &BackendNode{name: name, state: b.state, clientMgr: b.clientMgr, cloneTimeout: b.cloneTimeout, cloneByModel: b.cloneByModel, modelAliases: b.modelAliases, layout: b.layout, mdChunkSize: b.mdChunkSize, sparseMsgs: b.sparseMsgs, maxSend: b.maxSend, readyTimeout: b.readyTimeout, parsedCache: b.parsedCache, startTime: b.startTime, events: b.events, activity: b.activity, usage: b.usage, budget: b.budget, filter: b.filter, sends: b.sends, snapshots: b.snapshots, diag: b.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
 }
 
 // Symlink creates a symlink within the backend directory.
@@ -209,7 +256,7 @@ func (b *BackendListNode) Mkdir(ctx context.Context, name string, mode uint32, o
 // Calls SetDefaultBackend on the state store when successful.
 func (b *BackendListNode) Symlink(ctx context.Context, target, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(b.diag, "BackendListNode", "Symlink", name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&b.Inode).Conversation)
 
 	// Only allow creating a symlink named "default"
 	if name != "default" {
@@ -277,12 +324,12 @@ var _ = (fs.NodeReadlinker)((*DynamicSymlinkNode)(nil))
 var _ = (fs.NodeGetattrer)((*DynamicSymlinkNode)(nil))
 
 func (s *DynamicSymlinkNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
-	return []byte(s.getTarget()), 0
+	return []byte(linkTarget(&s.Inode, s.getTarget())), 0
 }
 
 func (s *DynamicSymlinkNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = syscall.S_IFLNK | 0777
-	out.Size = uint64(len(s.getTarget()))
+	out.Size = uint64(len(linkTarget(&s.Inode, s.getTarget())))
 	setTimestamps(&out.Attr, s.startTime)
 	return 0
 }
@@ -291,17 +338,29 @@ func (s *DynamicSymlinkNode) Getattr(ctx context.Context, f fs.FileHandle, out *
 
 type BackendNode struct {
 	fs.Inode
-	name        string
-	state       *state.Store
-	clientMgr   *shelley.ClientManager
+	name         string
+	state        *state.Store
+	clientMgr    *shelley.ClientManager
 	cloneTimeout time.Duration
+	cloneByModel map[string]time.Duration
+	modelAliases map[string]string
+	layout       Layout
+	mdChunkSize  int
+	sparseMsgs   bool
+	maxSend      int
+	readyTimeout time.Duration
+	events       *EventBus
+	activity     *ActivityBoard
+	usage        *UsageBoard
+	budget       Budget
+	filter       *ConversationFilter
+	sends        *RecentSends
+	snapshots    *Snapshots
 	parsedCache  *ParsedMessageCache
-	startTime   time.Time
-	diag        *diag.Tracker
+	startTime    time.Time
+	diag         *diag.Tracker
 }
 
-
-
 // Rename renames a backend directory. Only supports renaming within the same directory.
 // Returns EXDEV for cross-directory rename.
 // Returns EINVAL for renaming to or from the reserved name "default".
@@ -344,24 +403,31 @@ func (b *BackendListNode) Rename(ctx context.Context, name string, newParent fs.
 
 	return 0
 }
+
 var _ = (fs.NodeLookuper)((*BackendNode)(nil))
 var _ = (fs.NodeReaddirer)((*BackendNode)(nil))
 var _ = (fs.NodeGetattrer)((*BackendNode)(nil))
 
 func (b *BackendNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(b.diag, "BackendNode", "Lookup", name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&b.Inode).Conversation)
 
 	switch name {
 	case "url":
+		if b.state.GetBackend(b.name) == nil {
+			return nil, syscall.ENOENT
+		}
+		return b.NewInode(ctx, &BackendURLNode{name: b.name, state: b.state, clientMgr: b.clientMgr, startTime: b.startTime, diag: b.diag}, fs.StableAttr{Mode: backendNames.mode("url")}), 0
+	case "capabilities":
 		backend := b.state.GetBackend(b.name)
-		if backend == nil {
+		if backend == nil || backend.URL == "" {
 			return nil, syscall.ENOENT
 		}
-		return b.NewInode(ctx, &BackendURLNode{url: backend.URL, startTime: b.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
-	case "connected":
-		// Presence file - needs BackendConnectedNode implementation (sf-u12r)
-		return nil, syscall.ENOENT
+		client, err := b.clientMgr.EnsureURL(b.name, backend.URL)
+		if err != nil {
+			return nil, syscall.EIO
+		}
+		return b.NewInode(ctx, &BackendCapabilitiesNode{name: b.name, client: client, state: b.state, startTime: b.startTime}, fs.StableAttr{Mode: backendNames.mode("capabilities")}), 0
 	case "model":
 		// Get or create client for this backend
 		backend := b.state.GetBackend(b.name)
@@ -372,7 +438,7 @@ func (b *BackendNode) Lookup(ctx context.Context, name string, out *fuse.EntryOu
 		if err != nil {
 			return nil, syscall.EIO
 		}
-		return b.NewInode(ctx, &ModelsDirNode{client: client, state: b.state, startTime: b.startTime, diag: b.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		return b.NewInode(ctx, &ModelsDirNode{client: client, state: b.state, aliases: b.modelAliases, startTime: b.startTime, readyTimeout: b.readyTimeout, diag: b.diag}, fs.StableAttr{Mode: backendNames.mode("model")}), 0
 	case "conversation":
 		// Get or create client for this backend
 		backend := b.state.GetBackend(b.name)
@@ -383,10 +449,10 @@ func (b *BackendNode) Lookup(ctx context.Context, name string, out *fuse.EntryOu
 		if err != nil {
 			return nil, syscall.EIO
 		}
-		return b.NewInode(ctx, &ConversationListNode{client: client, state: b.state, cloneTimeout: b.cloneTimeout, startTime: b.startTime, parsedCache: b.parsedCache, diag: b.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		return b.NewInode(ctx, &ConversationListNode{client: client, state: b.state, cloneTimeout: b.cloneTimeout, cloneByModel: b.cloneByModel, layout: b.layout, mdChunkSize: b.mdChunkSize, sparseMsgs: b.sparseMsgs, maxSend: b.maxSend, startTime: b.startTime, parsedCache: b.parsedCache, events: b.events, activity: b.activity, usage: b.usage, budget: b.budget, filter: b.filter, sends: b.sends, snapshots: b.snapshots, diag: b.diag}, fs.StableAttr{Mode: backendNames.mode("conversation")}), 0
 	case "new":
 		// Symlink to model/default/new (target doesn't need to exist yet)
-		return b.NewInode(ctx, &SymlinkNode{target: "model/default/new", startTime: b.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		return b.NewInode(ctx, &SymlinkNode{target: "model/default/new", startTime: b.startTime}, fs.StableAttr{Mode: backendNames.mode("new")}), 0
 	}
 	return nil, syscall.ENOENT
 }
@@ -394,47 +460,95 @@ func (b *BackendNode) Lookup(ctx context.Context, name string, out *fuse.EntryOu
 func (b *BackendNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 	defer diag.Track(b.diag, "BackendNode", "Readdir", "").Done()
 
-	entries := []fuse.DirEntry{
-		{Name: "url", Mode: fuse.S_IFREG},
-		{Name: "connected", Mode: fuse.S_IFREG}, // presence file (may not exist)
-		{Name: "model", Mode: fuse.S_IFDIR},
-		{Name: "conversation", Mode: fuse.S_IFDIR},
-		{Name: "new", Mode: syscall.S_IFLNK},
-	}
+	// The connected presence file (sf-u12r) isn't listed until it has a
+	// node; listing it while Lookup fails breaks ls -l and find.
+	entries := backendNames.entries("url", "capabilities", "model", "conversation", "new")
 	return fs.NewListDirStream(entries), 0
 }
 
 func (b *BackendNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, b.startTime)
-	out.SetTimeout(cacheTTLConversation)
+	out.SetTimeout(cacheTimeouts(&b.Inode).Conversation)
 	return 0
 }
 
 // --- BackendURLNode: /shelley/backend/{name}/url file ---
 
+// BackendURLNode is the server URL of a backend. Writing a new one points
+// the backend's model/ and conversation/ at that server from the next
+// lookup on; an empty write detaches the backend. Only http and https
+// URLs are accepted (EINVAL otherwise).
 type BackendURLNode struct {
 	fs.Inode
-	url       string
+	name      string
+	state     *state.Store
+	clientMgr *shelley.ClientManager
 	startTime time.Time
+	diag      *diag.Tracker
 }
 
 var _ = (fs.NodeOpener)((*BackendURLNode)(nil))
 var _ = (fs.NodeReader)((*BackendURLNode)(nil))
+var _ = (fs.NodeWriter)((*BackendURLNode)(nil))
 var _ = (fs.NodeGetattrer)((*BackendURLNode)(nil))
+var _ = (fs.NodeSetattrer)((*BackendURLNode)(nil))
 
 func (u *BackendURLNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
 	return nil, fuse.FOPEN_DIRECT_IO, 0
 }
 
+// url returns the backend's current URL, and false if the backend is gone.
+func (u *BackendURLNode) url() (string, bool) {
+	backend := u.state.GetBackend(u.name)
+	if backend == nil {
+		return "", false
+	}
+	return backend.URL, true
+}
+
 func (u *BackendURLNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
-	data := []byte(u.url + "\n")
+	url, ok := u.url()
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	data := []byte(url + "\n")
 	return fuse.ReadResultData(readAt(data, dest, off)), 0
 }
 
+func (u *BackendURLNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	defer diag.Track(u.diag, "BackendURLNode", "Write", u.name).Done()
+	raw := strings.TrimSpace(string(data))
+	if raw != "" {
+		if _, isUnix := shelley.UnixSocketPath(raw); !isUnix {
+			parsed, err := neturl.Parse(raw)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return 0, syscall.EINVAL
+			}
+			raw = strings.TrimRight(raw, "/")
+		}
+	}
+	if err := u.state.SetBackendURL(u.name, raw); err != nil {
+		if backendNotFoundError.MatchString(err.Error()) {
+			return 0, syscall.ENOENT
+		}
+		log.Printf("Setting the URL of backend %s failed: %v", u.name, err)
+		return 0, syscall.EIO
+	}
+	// The next lookup builds a client for the new URL.
+	u.clientMgr.InvalidateClient(u.name)
+	return uint32(len(data)), 0
+}
+
 func (u *BackendURLNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
-	out.Mode = fuse.S_IFREG | 0444
-	out.Size = uint64(len(u.url) + 1) // +1 for newline
+	url, _ := u.url()
+	out.Mode = fuse.S_IFREG | 0644
+	out.Size = uint64(len(url) + 1) // +1 for newline
 	setTimestamps(&out.Attr, u.startTime)
 	return 0
 }
+
+func (u *BackendURLNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	// Accept truncate (from shell > redirect) silently
+	return u.Getattr(ctx, f, out)
+}
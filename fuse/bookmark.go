@@ -0,0 +1,193 @@
+package fuse
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- BookmarkNode: /conversation/{id}/bookmark ---
+// Holds the index of the last message the reader has seen, the number its
+// messages/ directory starts with, one per reader (uid) so several consumers
+// can follow the same conversation. Writing an index, or a message directory
+// name such as "004-agent", moves the bookmark; writing nothing clears it.
+// messages/since/bookmark/ then lists only the messages after it. Bookmarks
+// are kept in the state file and never sent to the backend.
+
+type BookmarkNode struct {
+	fs.Inode
+	localID   string
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*BookmarkNode)(nil))
+var _ = (fs.NodeReader)((*BookmarkNode)(nil))
+var _ = (fs.NodeWriter)((*BookmarkNode)(nil))
+var _ = (fs.NodeGetattrer)((*BookmarkNode)(nil))
+var _ = (fs.NodeSetattrer)((*BookmarkNode)(nil))
+
+// bookmarkReader identifies the reader a bookmark belongs to: the uid of
+// the calling process.
+func bookmarkReader(ctx context.Context) string {
+	if caller, ok := fuse.FromContext(ctx); ok {
+		return strconv.FormatUint(uint64(caller.Uid), 10)
+	}
+	return ""
+}
+
+// parseBookmark parses a message index, alone or as the prefix of a
+// message directory name.
+func parseBookmark(s string) (int, bool) {
+	s, _, _ = strings.Cut(s, "-")
+	index, err := strconv.Atoi(s)
+	return index, err == nil && index >= 0
+}
+
+func (n *BookmarkNode) data(ctx context.Context) []byte {
+	index, ok := n.state.Bookmark(n.localID, bookmarkReader(ctx))
+	if !ok {
+		return nil
+	}
+	return []byte(strconv.Itoa(index) + "\n")
+}
+
+func (n *BookmarkNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return nil, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *BookmarkNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	return fuse.ReadResultData(readAt(n.data(ctx), dest, off)), 0
+}
+
+func (n *BookmarkNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	defer diag.Track(n.diag, "BookmarkNode", "Write", n.localID).Done()
+	reader := bookmarkReader(ctx)
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		if err := n.state.ClearBookmark(n.localID, reader); err != nil {
+			log.Printf("Failed to clear bookmark of %s: %v", n.localID, err)
+			return 0, syscall.EIO
+		}
+		return uint32(len(data)), 0
+	}
+	index, ok := parseBookmark(value)
+	if !ok {
+		return 0, syscall.EINVAL
+	}
+	if err := n.state.SetBookmark(n.localID, reader, index); err != nil {
+		log.Printf("Failed to set bookmark of %s: %v", n.localID, err)
+		return 0, syscall.EIO
+	}
+	return uint32(len(data)), 0
+}
+
+func (n *BookmarkNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0644
+	out.Size = uint64(len(n.data(ctx)))
+	setTimestamps(&out.Attr, metaTime(n.state, n.localID, n.startTime))
+	return 0
+}
+
+func (n *BookmarkNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	// Accept truncate (from shell > redirect) silently
+	return n.Getattr(ctx, f, out)
+}
+
+// --- BookmarkDirNode: /conversation/{id}/messages/since/bookmark/ ---
+// Symlinks to the messages after the reader's bookmark, named like their
+// directories. Without a bookmark every message is listed.
+
+type BookmarkDirNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	startTime   time.Time
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*BookmarkDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*BookmarkDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*BookmarkDirNode)(nil))
+
+// names returns the directory names of the messages after reader's bookmark.
+func (b *BookmarkDirNode) names(reader string) ([]string, error) {
+	cs := b.state.Get(b.localID)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return nil, nil
+	}
+	convData, err := b.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := b.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return nil, err
+	}
+	after, ok := b.state.Bookmark(b.localID, reader)
+	if !ok {
+		after = -1
+	}
+	var names []string
+	for i := range result.Messages {
+		msg := &result.Messages[i]
+		// Directory names count from 0, sequence IDs from 1.
+		if msg.SequenceID-1 > after {
+			slug := shelley.MessageSlug(msg, result.ToolMap)
+			names = append(names, messageFileBase(msg.SequenceID, slug, result.MaxSeqID))
+		}
+	}
+	return names, nil
+}
+
+func (b *BookmarkDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(b.diag, "BookmarkDirNode", "Lookup", b.localID+"/"+name).Done()
+	names, err := b.names(bookmarkReader(ctx))
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	for _, n := range names {
+		if n == name {
+			// The entry goes away when the bookmark moves past it.
+			out.SetEntryTimeout(volatileEntryTimeout)
+			return b.NewInode(ctx, &SymlinkNode{target: "../../" + name, startTime: b.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+func (b *BookmarkDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(b.diag, "BookmarkDirNode", "Readdir", b.localID).Done()
+	names, err := b.names(bookmarkReader(ctx))
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: syscall.S_IFLNK})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (b *BookmarkDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	cs := b.state.Get(b.localID)
+	if cs != nil && !cs.CreatedAt.IsZero() {
+		setTimestamps(&out.Attr, cs.CreatedAt)
+	} else {
+		setTimestamps(&out.Attr, b.startTime)
+	}
+	return 0
+}
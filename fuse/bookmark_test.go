@@ -0,0 +1,76 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestBookmark(t *testing.T) {
+	question, answer, followUp := "Which database?", "Postgres.", "ok"
+	server := mockserver.New(mockserver.WithConversation("conv-bookmark", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-bookmark", SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: "m2", ConversationID: "conv-bookmark", SequenceID: 2, Type: "agent", LLMData: &answer},
+		{MessageID: "m3", ConversationID: "conv-bookmark", SequenceID: 3, Type: "user", UserData: &followUp},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-bookmark", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+	bookmark := filepath.Join(convDir, "bookmark")
+	sinceDir := filepath.Join(convDir, "messages", "since", "bookmark")
+	if data, err := os.ReadFile(bookmark); err != nil || len(data) != 0 {
+		t.Errorf("bookmark before writing = %q, %v; want empty", data, err)
+	}
+	if names := listDir(t, sinceDir); !slices.Equal(names, []string{"0-user", "1-agent", "2-user"}) {
+		t.Errorf("since/bookmark/ without a bookmark = %v, want every message", names)
+	}
+
+	// A message directory name works as well as its index.
+	if err := os.WriteFile(bookmark, []byte("0-user\n"), 0644); err != nil {
+		t.Fatalf("write bookmark: %v", err)
+	}
+	if data, err := os.ReadFile(bookmark); err != nil || string(data) != "0\n" {
+		t.Errorf("bookmark = %q, %v; want %q", data, err, "0\n")
+	}
+	if names := listDir(t, sinceDir); !slices.Equal(names, []string{"1-agent", "2-user"}) {
+		t.Errorf("since/bookmark/ = %v, want [1-agent 2-user]", names)
+	}
+	if target, err := os.Readlink(filepath.Join(sinceDir, "1-agent")); err != nil || target != "../../1-agent" {
+		t.Errorf("since/bookmark/1-agent -> %q, %v; want ../../1-agent", target, err)
+	}
+	reader := strconv.Itoa(os.Getuid())
+	if index, ok := store.Bookmark(localID, reader); !ok || index != 0 {
+		t.Errorf("stored bookmark = %d, %v; want 0 for uid %s", index, ok, reader)
+	}
+
+	if err := os.WriteFile(bookmark, []byte("2"), 0644); err != nil {
+		t.Fatalf("move bookmark: %v", err)
+	}
+	if names := listDir(t, sinceDir); len(names) != 0 {
+		t.Errorf("since/bookmark/ at the last message = %v, want empty", names)
+	}
+
+	if err := os.WriteFile(bookmark, []byte("latest\n"), 0644); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("writing a non-index: %v, want EINVAL", err)
+	}
+
+	// Writing nothing clears the bookmark.
+	if err := os.WriteFile(bookmark, []byte("\n"), 0644); err != nil {
+		t.Fatalf("clear bookmark: %v", err)
+	}
+	if _, ok := store.Bookmark(localID, reader); ok {
+		t.Error("bookmark still stored after clearing it")
+	}
+}
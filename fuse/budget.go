@@ -0,0 +1,64 @@
+package fuse
+
+import (
+	"fmt"
+)
+
+// --- Budget: caps on what a conversation may use before send refuses ---
+// An agent stuck in a loop can burn through tokens quickly. A budget caps
+// a conversation's tokens or cost: once its messages' usage_data add up to
+// the cap, writes to send fail with EDQUOT and the reason goes to the
+// conversation's errors.log. Caps come from ctl (budget_tokens=,
+// budget_usd=) and, for every conversation alike, from SetBudget; either
+// one being reached is enough. Messages already being answered are not
+// interrupted: the check only guards new sends.
+
+// Budget caps a conversation's usage. Zero fields are no cap.
+type Budget struct {
+	Tokens  int64
+	CostUSD float64
+}
+
+func (b Budget) isZero() bool {
+	return b.Tokens == 0 && b.CostUSD == 0
+}
+
+// reached returns why u is at or over b, naming b as whose, or "" if it
+// isn't.
+func (b Budget) reached(u Usage, whose string) string {
+	if b.Tokens > 0 && u.TotalTokens() >= b.Tokens {
+		return fmt.Sprintf("%s token budget of %d reached (%d used)", whose, b.Tokens, u.TotalTokens())
+	}
+	if b.CostUSD > 0 && u.CostUSD >= b.CostUSD {
+		return fmt.Sprintf("%s cost budget of $%.2f reached ($%.2f used)", whose, b.CostUSD, u.CostUSD)
+	}
+	return ""
+}
+
+// overBudget returns why the conversation may not be sent more messages,
+// or "" if it may. Usage is summed from the conversation as the backend
+// has it now, fetched only when some budget applies; if it can't be
+// fetched the send goes ahead and reports the backend's error itself.
+func (n *ConvSendNode) overBudget() string {
+	cs := n.state.Get(n.localID)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return ""
+	}
+	own := Budget{Tokens: cs.BudgetTokens, CostUSD: cs.BudgetUSD}
+	if own.isZero() && n.budget.isZero() {
+		return ""
+	}
+	convData, err := n.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return ""
+	}
+	result, err := n.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return ""
+	}
+	u := conversationUsage(result.Messages)
+	if reason := own.reached(u, "conversation"); reason != "" {
+		return reason
+	}
+	return n.budget.reached(u, "global")
+}
@@ -0,0 +1,85 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestConversationBudget(t *testing.T) {
+	server := mockserver.New(mockserver.WithConversation("conv-budget", usageTestMessages("conv-budget", `{"input_tokens":100,"output_tokens":20}`)))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-budget", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+	ctl, send := filepath.Join(convDir, "ctl"), filepath.Join(convDir, "send")
+
+	if err := os.WriteFile(send, []byte("go on\n"), 0644); err != nil {
+		t.Fatalf("send without a budget: %v", err)
+	}
+
+	if err := os.WriteFile(ctl, []byte("budget_tokens=100\n"), 0644); err != nil {
+		t.Fatalf("set budget: %v", err)
+	}
+	if data, _ := os.ReadFile(ctl); !strings.Contains(string(data), "budget_tokens=100") {
+		t.Errorf("ctl = %q, want budget_tokens=100", data)
+	}
+	if err := os.WriteFile(send, []byte("and again\n"), 0644); !errors.Is(err, syscall.EDQUOT) {
+		t.Fatalf("send over budget: got %v, want EDQUOT", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(convDir, "errors.log")); !strings.Contains(string(data), "conversation token budget of 100 reached (120 used)") {
+		t.Errorf("errors.log = %q, want the budget reason", data)
+	}
+
+	// Raising the budget lets sends through again.
+	if err := os.WriteFile(ctl, []byte("budget_tokens=1000\n"), 0644); err != nil {
+		t.Fatalf("raise budget: %v", err)
+	}
+	if err := os.WriteFile(send, []byte("and again\n"), 0644); err != nil {
+		t.Errorf("send under budget: %v", err)
+	}
+
+	for _, bad := range []string{"budget_tokens=-1", "budget_tokens=lots", "budget_usd=NaN", "budget_usd=-0.5"} {
+		if err := os.WriteFile(ctl, []byte(bad+"\n"), 0644); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("%s: got %v, want EINVAL", bad, err)
+		}
+	}
+	if cs := store.Get(localID); cs.BudgetTokens != 1000 || cs.BudgetUSD != 0 {
+		t.Errorf("budget after bad writes = %d tokens, $%v", cs.BudgetTokens, cs.BudgetUSD)
+	}
+}
+
+func TestGlobalBudget(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithConversation("conv-cheap", usageTestMessages("conv-cheap", `{"input_tokens":10,"cost_usd":0.01}`)),
+		mockserver.WithConversation("conv-dear", usageTestMessages("conv-dear", `{"input_tokens":10,"cost_usd":0.75}`)),
+	)
+	defer server.Close()
+	store := testStore(t)
+	cheap, _ := store.AdoptWithSlug("conv-cheap", "")
+	dear, _ := store.AdoptWithSlug("conv-dear", "")
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetBudget(Budget{CostUSD: 0.5})
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+
+	if err := os.WriteFile(filepath.Join(convDir, cheap, "send"), []byte("more\n"), 0644); err != nil {
+		t.Errorf("send under the global budget: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(convDir, dear, "send"), []byte("more\n"), 0644); !errors.Is(err, syscall.EDQUOT) {
+		t.Errorf("send over the global budget: got %v, want EDQUOT", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(convDir, dear, "errors.log")); !strings.Contains(string(data), "global cost budget of $0.50 reached ($0.75 used)") {
+		t.Errorf("errors.log = %q, want the budget reason", data)
+	}
+}
@@ -0,0 +1,365 @@
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/state"
+)
+
+// --- BulkNode: /conversation/.bulk ---
+// Writing newline-delimited commands to .bulk and closing it applies them
+// as one batch, for housekeeping that would otherwise take a write per
+// conversation:
+//
+//	archive {id}      archive the conversation
+//	tag {id} {tag}    add tag to the conversation's meta/tags
+//	delete {id}       delete the conversation permanently
+//
+// {id} is a local ID, a slug or a backend conversation ID. Blank lines and
+// lines starting with '#' are ignored.
+//
+// The batch is all or nothing as far as it can be. Every command is checked
+// before any is applied, and one bad line rejects the whole batch. Tags are
+// applied first, archives next and deletes last; if a tag or archive fails,
+// the ones already applied are undone and nothing is deleted. A delete can't
+// be undone, so a failing delete only stops the deletes after it.
+//
+// Reading .bulk gives the report of the last batch: one line per command,
+// "ok", "failed", "undone" or "skipped", the command, and why. The same
+// report can be read back from the descriptor the batch was written to,
+// after fsync.
+
+type BulkNode struct {
+	fs.Inode
+	list *ConversationListNode
+}
+
+var _ = (fs.NodeOpener)((*BulkNode)(nil))
+var _ = (fs.NodeGetattrer)((*BulkNode)(nil))
+var _ = (fs.NodeSetattrer)((*BulkNode)(nil))
+
+// bulkLog holds the report of the last batch applied under a conversation
+// list, and keeps two batches from running at once.
+type bulkLog struct {
+	mu     sync.Mutex
+	report []byte
+}
+
+func (n *BulkNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return &bulkHandle{node: n}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *BulkNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0644
+	setTimestamps(&out.Attr, n.list.startTime)
+	return 0
+}
+
+func (n *BulkNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	// Accept truncate (from shell > redirect) silently
+	return n.Getattr(ctx, f, out)
+}
+
+// bulkHandle buffers commands and applies them on Flush or Fsync.
+type bulkHandle struct {
+	node    *BulkNode
+	mu      sync.Mutex
+	buffer  []byte
+	report  []byte // the batch's report once applied
+	applied bool
+}
+
+var _ = (fs.FileWriter)((*bulkHandle)(nil))
+var _ = (fs.FileReader)((*bulkHandle)(nil))
+var _ = (fs.FileFlusher)((*bulkHandle)(nil))
+var _ = (fs.FileFsyncer)((*bulkHandle)(nil))
+
+func (h *bulkHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.applied {
+		return 0, syscall.EBADF
+	}
+	h.buffer = append(h.buffer, data...)
+	return uint32(len(data)), 0
+}
+
+func (h *bulkHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	report := h.report
+	applied := h.applied
+	h.mu.Unlock()
+	if !applied {
+		last := &h.node.list.bulk
+		last.mu.Lock()
+		report = last.report
+		last.mu.Unlock()
+	}
+	return fuse.ReadResultData(readAt(report, dest, off)), 0
+}
+
+func (h *bulkHandle) Flush(ctx context.Context) syscall.Errno {
+	defer diag.Track(h.node.list.diag, "bulkHandle", "Flush", "").Done()
+	return h.apply()
+}
+
+func (h *bulkHandle) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	defer diag.Track(h.node.list.diag, "bulkHandle", "Fsync", "").Done()
+	return h.apply()
+}
+
+// apply runs the buffered batch once. An empty buffer is left alone, so
+// reading .bulk or a dup'd descriptor closing early runs nothing.
+func (h *bulkHandle) apply() syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.applied || len(bytes.TrimSpace(h.buffer)) == 0 {
+		return 0
+	}
+	report, err := h.node.list.applyBulk(h.buffer)
+	h.report = report
+	h.applied = true
+	h.buffer = nil
+	if err != nil {
+		log.Printf("Bulk: %v", err)
+		return syscall.EIO
+	}
+	return 0
+}
+
+// bulkCommand is one line of a batch.
+type bulkCommand struct {
+	line   string // as written, for the report
+	verb   string
+	tag    string
+	cs     *state.ConversationState
+	noop   bool // repeats an earlier command, or archives an archived conversation
+	status string
+	reason string
+}
+
+// applyBulk parses and applies a batch, records its report as the last one,
+// and returns it. The error is non-nil if any command didn't apply.
+func (c *ConversationListNode) applyBulk(data []byte) ([]byte, error) {
+	c.bulk.mu.Lock()
+	defer c.bulk.mu.Unlock()
+
+	cmds, bad := c.parseBulk(data)
+	var err error
+	if bad > 0 {
+		err = fmt.Errorf("%d invalid command(s), nothing applied", bad)
+		for _, cmd := range cmds {
+			if cmd.status == "" {
+				cmd.status = "skipped"
+			}
+		}
+	} else {
+		err = c.runBulk(cmds)
+	}
+
+	var b bytes.Buffer
+	for _, cmd := range cmds {
+		fmt.Fprintf(&b, "%s %s", cmd.status, cmd.line)
+		if reason := strings.TrimSpace(cmd.reason); reason != "" {
+			// Backend errors can carry a response body; keep one line each.
+			fmt.Fprintf(&b, ": %s", strings.ReplaceAll(reason, "\n", " "))
+		}
+		b.WriteByte('\n')
+	}
+	c.bulk.report = b.Bytes()
+	return c.bulk.report, err
+}
+
+// parseBulk parses a batch and checks each command against the state store
+// and the backend. It returns the commands and how many are invalid; those
+// are marked failed.
+func (c *ConversationListNode) parseBulk(data []byte) ([]*bulkCommand, int) {
+	var cmds []*bulkCommand
+	bad := 0
+	fail := func(cmd *bulkCommand, format string, args ...any) {
+		cmd.status = "failed"
+		cmd.reason = fmt.Sprintf(format, args...)
+		bad++
+	}
+	seen := make(map[string]string) // local ID -> verb, for archive and delete
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		cmd := &bulkCommand{line: line}
+		cmds = append(cmds, cmd)
+		fields := strings.Fields(line)
+		cmd.verb = fields[0]
+		switch {
+		case cmd.verb == "tag" && len(fields) == 3:
+			cmd.tag = fields[2]
+		case (cmd.verb == "archive" || cmd.verb == "delete") && len(fields) == 2:
+		case cmd.verb == "tag" || cmd.verb == "archive" || cmd.verb == "delete":
+			fail(cmd, "wrong number of arguments")
+			continue
+		default:
+			fail(cmd, "unknown command %q", cmd.verb)
+			continue
+		}
+
+		cmd.cs = c.resolveBulkID(fields[1])
+		if cmd.cs == nil {
+			fail(cmd, "no such conversation")
+			continue
+		}
+		if cmd.verb == "tag" {
+			continue
+		}
+		if prev, dup := seen[cmd.cs.LocalID]; dup {
+			if prev != cmd.verb {
+				fail(cmd, "conversation is also to be %sd", prev)
+			}
+			cmd.noop = true
+			continue
+		}
+		seen[cmd.cs.LocalID] = cmd.verb
+		if cmd.verb == "archive" {
+			if !cmd.cs.Created || cmd.cs.ShelleyConversationID == "" {
+				fail(cmd, "conversation not created yet")
+				continue
+			}
+			archived, err := c.client.IsConversationArchived(cmd.cs.ShelleyConversationID)
+			if err != nil {
+				fail(cmd, "%v", err)
+				continue
+			}
+			cmd.noop = archived
+		}
+	}
+	return cmds, bad
+}
+
+// resolveBulkID looks up the conversation id names: a local ID, a slug or
+// a backend conversation ID, in that order.
+func (c *ConversationListNode) resolveBulkID(id string) *state.ConversationState {
+	if cs := c.state.Get(id); cs != nil {
+		return cs
+	}
+	if localID := c.state.GetBySlug(id); localID != "" {
+		return c.state.Get(localID)
+	}
+	if localID := c.state.GetByShelleyID(id); localID != "" {
+		return c.state.Get(localID)
+	}
+	return nil
+}
+
+// runBulk applies checked commands: tags, then archives, then deletes.
+func (c *ConversationListNode) runBulk(cmds []*bulkCommand) error {
+	// The tags values before the batch, to put back if it fails.
+	oldTags := make(map[string]*string)
+	var archived []*bulkCommand
+	var failed error
+	undo := func() {
+		for _, cmd := range archived {
+			if err := c.client.UnarchiveConversation(cmd.cs.ShelleyConversationID); err != nil {
+				cmd.reason = fmt.Sprintf("undo failed: %v", err)
+				continue
+			}
+			cmd.status = "undone"
+		}
+		for localID, old := range oldTags {
+			if old == nil {
+				_ = c.state.DeleteMeta(localID, "tags")
+			} else {
+				_ = c.state.SetMeta(localID, "tags", *old)
+			}
+		}
+		for _, cmd := range cmds {
+			switch {
+			case cmd.verb == "tag" && cmd.status == "ok":
+				cmd.status = "undone"
+			case cmd.status == "":
+				cmd.status = "skipped"
+			}
+		}
+	}
+
+	for _, cmd := range cmds {
+		if cmd.verb != "tag" {
+			continue
+		}
+		localID := cmd.cs.LocalID
+		tags, ok := c.state.GetMeta(localID, "tags")
+		if _, saved := oldTags[localID]; !saved {
+			if ok {
+				v := tags
+				oldTags[localID] = &v
+			} else {
+				oldTags[localID] = nil
+			}
+		}
+		if err := c.state.SetMeta(localID, "tags", addTag(tags, cmd.tag)); err != nil {
+			cmd.status, cmd.reason = "failed", err.Error()
+			undo()
+			return err
+		}
+		cmd.status = "ok"
+	}
+
+	for _, cmd := range cmds {
+		if cmd.verb != "archive" {
+			continue
+		}
+		if cmd.noop {
+			cmd.status, cmd.reason = "ok", "nothing to do"
+			continue
+		}
+		if err := c.client.ArchiveConversation(cmd.cs.ShelleyConversationID); err != nil {
+			cmd.status, cmd.reason = "failed", err.Error()
+			undo()
+			return err
+		}
+		cmd.status = "ok"
+		archived = append(archived, cmd)
+	}
+
+	for _, cmd := range cmds {
+		if cmd.verb != "delete" {
+			continue
+		}
+		if failed != nil {
+			cmd.status = "skipped"
+			continue
+		}
+		if cmd.noop {
+			cmd.status, cmd.reason = "ok", "nothing to do"
+			continue
+		}
+		if err := c.deleteConversation(cmd.cs); err != nil {
+			cmd.status, cmd.reason = "failed", err.Error()
+			failed = err
+			continue
+		}
+		cmd.status = "ok"
+	}
+	return failed
+}
+
+// addTag adds tag to a tags value, one tag per line, unless it's there.
+func addTag(tags, tag string) string {
+	for _, t := range strings.Fields(tags) {
+		if t == tag {
+			return tags
+		}
+	}
+	if tags != "" && !strings.HasSuffix(tags, "\n") {
+		tags += "\n"
+	}
+	return tags + tag + "\n"
+}
@@ -0,0 +1,65 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+func TestBulk(t *testing.T) {
+	server := mockConversationsServer(t, []shelley.Conversation{
+		{ConversationID: "conv-keep", Slug: strPtr("keep-me")},
+		{ConversationID: "conv-drop", Slug: strPtr("drop-me")},
+	})
+	defer server.Close()
+	store := testStore(t)
+	keepID, _ := store.AdoptWithSlug("conv-keep", "keep-me")
+	dropID, _ := store.AdoptWithSlug("conv-drop", "drop-me")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	bulk := filepath.Join(mountPoint, "conversation", ".bulk")
+
+	run := func(batch, wantReport string) {
+		t.Helper()
+		_ = os.WriteFile(bulk, []byte(batch), 0644)
+		report, err := os.ReadFile(bulk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(report) != wantReport {
+			t.Errorf("report for\n%s\ngot\n%s\nwant\n%s", batch, report, wantReport)
+		}
+	}
+
+	// One bad line rejects the whole batch.
+	run("tag keep-me old\nfrobnicate keep-me\ndelete nope\n",
+		"skipped tag keep-me old\n"+
+			"failed frobnicate keep-me: unknown command \"frobnicate\"\n"+
+			"failed delete nope: no such conversation\n")
+	if _, ok := store.GetMeta(keepID, "tags"); ok {
+		t.Error("rejected batch tagged a conversation")
+	}
+
+	run("# housekeeping\ntag keep-me old\ntag "+keepID+" done\n\ndelete conv-drop\ndelete drop-me\n",
+		"ok tag keep-me old\n"+
+			"ok tag "+keepID+" done\n"+
+			"ok delete conv-drop\n"+
+			"ok delete drop-me: nothing to do\n")
+	if tags, _ := store.GetMeta(keepID, "tags"); tags != "old\ndone\n" {
+		t.Errorf("tags = %q, want %q", tags, "old\ndone\n")
+	}
+	if store.Get(dropID) != nil {
+		t.Error("deleted conversation is still in the state store")
+	}
+
+	// The mock backend can't archive, so the tag is undone.
+	run("tag keep-me new\narchive keep-me\n",
+		"undone tag keep-me new\n"+
+			"failed archive keep-me: API returned status 404: 404 page not found\n")
+	if tags, _ := store.GetMeta(keepID, "tags"); tags != "old\ndone\n" {
+		t.Errorf("tags after undo = %q, want %q", tags, "old\ndone\n")
+	}
+}
@@ -2,6 +2,7 @@ package fuse
 
 import (
 	"sync"
+	"unsafe"
 
 	"shelley-fuse/shelley"
 )
@@ -14,6 +15,9 @@ import (
 type ParsedMessageCache struct {
 	mu      sync.RWMutex
 	entries map[string]*parsedCacheEntry
+	chunks  map[string]*chunksCacheEntry // rendered all.md.d parts, by conversation ID
+	budget  *shelley.CacheBudget         // optional size limit shared with the client caches
+	onParse func(conversationID string, msgs []shelley.Message)
 }
 
 type parsedCacheEntry struct {
@@ -22,15 +26,61 @@ type parsedCacheEntry struct {
 	maxSeqID int    // highest SequenceID (cached to avoid O(N) recomputation)
 	checksum uint64 // FNV-1a hash of the raw data used to produce this entry
 	rawData  []byte // reference to the raw data slice for fast identity checks
+
+	// chunkedSize and chunked remember whether the markdown of these
+	// messages is split into chunks of chunkedSize (see Chunked); 0 if
+	// not decided yet.
+	chunkedSize int
+	chunked     bool
+}
+
+// chunksCacheEntry is the all.md.d rendering of one parse of a conversation.
+type chunksCacheEntry struct {
+	first  *shelley.Message // &messages[0] of the parse it was rendered from
+	count  int
+	size   int
+	chunks [][]byte
 }
 
 // NewParsedMessageCache creates a new content-addressed parse cache.
 func NewParsedMessageCache() *ParsedMessageCache {
 	return &ParsedMessageCache{
 		entries: make(map[string]*parsedCacheEntry),
+		chunks:  make(map[string]*chunksCacheEntry),
 	}
 }
 
+// SetBudget makes cached entries count against b, which may evict them least
+// recently used first. An entry is charged what its parsed messages occupy
+// (see parsedSize); the raw data they came from is the client cache's, and
+// charged there. It must be called before use.
+func (c *ParsedMessageCache) SetBudget(b *shelley.CacheBudget) {
+	c.budget = b
+}
+
+// SetParseHook makes the cache call fn with the messages whenever it parses
+// new data for a conversation, which is when new messages show up. fn must
+// not modify them. It must be called before use.
+func (c *ParsedMessageCache) SetParseHook(fn func(conversationID string, msgs []shelley.Message)) {
+	c.onParse = fn
+}
+
+// parsedSize estimates the memory msgs occupy: the Message values and the
+// strings decoded into them, which are copies, not slices of the raw data.
+func parsedSize(msgs []shelley.Message) int64 {
+	size := int64(len(msgs)) * int64(unsafe.Sizeof(shelley.Message{}))
+	for i := range msgs {
+		m := &msgs[i]
+		size += int64(len(m.MessageID) + len(m.ConversationID) + len(m.Type) + len(m.CreatedAt))
+		for _, data := range []*string{m.LLMData, m.UserData, m.UsageData} {
+			if data != nil {
+				size += int64(len(*data))
+			}
+		}
+	}
+	return size
+}
+
 // dataChecksum computes a fast FNV-1a hash of the raw data.
 func dataChecksum(data []byte) uint64 {
 	// FNV-1a 64-bit
@@ -76,10 +126,12 @@ func (c *ParsedMessageCache) GetOrParseResult(conversationID string, rawData []b
 			// the same cached slice, this avoids computing the checksum entirely.
 			if len(rawData) == len(entry.rawData) && len(rawData) > 0 &&
 				&rawData[0] == &entry.rawData[0] {
+				c.budget.Touch("parsed/" + conversationID)
 				return &ParseResult{Messages: entry.messages, ToolMap: entry.toolMap, MaxSeqID: entry.maxSeqID}, nil
 			}
 			// Slow path: content-addressed comparison via checksum
 			if entry.checksum == dataChecksum(rawData) {
+				c.budget.Touch("parsed/" + conversationID)
 				return &ParseResult{Messages: entry.messages, ToolMap: entry.toolMap, MaxSeqID: entry.maxSeqID}, nil
 			}
 		}
@@ -101,15 +153,26 @@ func (c *ParsedMessageCache) GetOrParseResult(conversationID string, rawData []b
 
 	// Cache the result
 	if c != nil {
-		c.mu.Lock()
-		c.entries[conversationID] = &parsedCacheEntry{
+		entry := &parsedCacheEntry{
 			messages: msgs,
 			toolMap:  toolMap,
 			maxSeqID: maxSeq,
 			checksum: dataChecksum(rawData),
 			rawData:  rawData,
 		}
+		c.mu.Lock()
+		c.entries[conversationID] = entry
 		c.mu.Unlock()
+		c.budget.Charge("parsed/"+conversationID, parsedSize(msgs), func() {
+			c.mu.Lock()
+			if c.entries[conversationID] == entry {
+				delete(c.entries, conversationID)
+			}
+			c.mu.Unlock()
+		})
+	}
+	if c != nil && c.onParse != nil {
+		c.onParse(conversationID, msgs)
 	}
 
 	return &ParseResult{Messages: msgs, ToolMap: toolMap, MaxSeqID: maxSeq}, nil
@@ -121,6 +184,85 @@ func (c *ParsedMessageCache) Invalidate(conversationID string) {
 	if c != nil {
 		c.mu.Lock()
 		delete(c.entries, conversationID)
+		delete(c.chunks, conversationID)
+		c.budget.Release("parsed/" + conversationID)
+		c.budget.Release("chunks/" + conversationID)
+		c.mu.Unlock()
+	}
+}
+
+// MarkdownChunks returns chunkedMarkdown(msgs, size), rendering it only
+// when msgs is not the parse the cached rendering came from. Every part of
+// all.md.d is opened separately, so without this a reader going through the
+// parts renders the whole conversation once per part.
+func (c *ParsedMessageCache) MarkdownChunks(conversationID string, msgs []shelley.Message, size int) [][]byte {
+	if c == nil || len(msgs) == 0 {
+		return chunkedMarkdown(msgs, size)
+	}
+	c.mu.RLock()
+	entry := c.chunks[conversationID]
+	c.mu.RUnlock()
+	if entry != nil && entry.first == &msgs[0] && entry.count == len(msgs) && entry.size == size {
+		c.budget.Touch("chunks/" + conversationID)
+		return entry.chunks
+	}
+
+	entry = &chunksCacheEntry{first: &msgs[0], count: len(msgs), size: size, chunks: chunkedMarkdown(msgs, size)}
+	var total int64
+	for _, chunk := range entry.chunks {
+		total += int64(len(chunk))
+	}
+	c.mu.Lock()
+	c.chunks[conversationID] = entry
+	c.mu.Unlock()
+	c.budget.Charge("chunks/"+conversationID, total, func() {
+		c.mu.Lock()
+		if c.chunks[conversationID] == entry {
+			delete(c.chunks, conversationID)
+		}
 		c.mu.Unlock()
+	})
+	return entry.chunks
+}
+
+// Chunked reports whether MarkdownChunks(conversationID, msgs, size)
+// returns chunks, that is whether all.md.d exists. The answer is kept with
+// the parse msgs came from, so listing the messages directory renders the
+// conversation once per change rather than on every listing.
+func (c *ParsedMessageCache) Chunked(conversationID string, msgs []shelley.Message, size int) bool {
+	if c == nil || len(msgs) == 0 {
+		return chunkedMarkdown(msgs, size) != nil
+	}
+	c.mu.RLock()
+	entry, rendered := c.entries[conversationID], c.chunks[conversationID]
+	if rendered != nil && rendered.first == &msgs[0] && rendered.count == len(msgs) && rendered.size == size {
+		c.mu.RUnlock()
+		return rendered.chunks != nil
+	}
+	fromEntry := entry != nil && len(entry.messages) > 0 && &entry.messages[0] == &msgs[0]
+	if fromEntry && entry.chunkedSize == size {
+		chunked := entry.chunked
+		c.mu.RUnlock()
+		return chunked
+	}
+	c.mu.RUnlock()
+
+	chunked := chunkedMarkdown(msgs, size) != nil
+	if fromEntry {
+		c.mu.Lock()
+		entry.chunkedSize, entry.chunked = size, chunked
+		c.mu.Unlock()
+	}
+	return chunked
+}
+
+// newestMessage returns the message with the highest SequenceID, or nil.
+func newestMessage(msgs []shelley.Message) *shelley.Message {
+	var newest *shelley.Message
+	for i := range msgs {
+		if newest == nil || msgs[i].SequenceID > newest.SequenceID {
+			newest = &msgs[i]
+		}
 	}
+	return newest
 }
@@ -1,7 +1,9 @@
 package fuse
 
 import (
+	"bytes"
 	"context"
+	"syscall"
 	"testing"
 	"time"
 
@@ -311,3 +313,89 @@ func TestParsedMessageCacheConsistencyAcrossCallers(t *testing.T) {
 		t.Error("Expected same slice from shared cache after update")
 	}
 }
+
+// TestParsedMessageCacheChunked verifies that whether all.md.d exists is
+// decided once per parse, not on every listing.
+func TestParsedMessageCacheChunked(t *testing.T) {
+	cache := NewParsedMessageCache()
+	convData := []byte(`{"messages":[{"message_id":"m1","sequence_id":1,"type":"user","user_data":"Hello"},{"message_id":"m2","sequence_id":2,"type":"user","user_data":"World"}]}`)
+	msgs, _, err := cache.GetOrParse("conv-1", convData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cache.Chunked("conv-1", msgs, 10) {
+		t.Error("Chunked at 10 bytes = false")
+	}
+	if cache.Chunked("conv-1", msgs, 1<<20) {
+		t.Error("Chunked at 1 MiB = true")
+	}
+
+	// The decision is kept with the parse and not made again.
+	cache.mu.Lock()
+	cache.entries["conv-1"].chunked = true
+	cache.mu.Unlock()
+	if !cache.Chunked("conv-1", msgs, 1<<20) {
+		t.Error("Chunked decided again for the same parse")
+	}
+	// New data is a new parse, decided afresh.
+	newMsgs, _, err := cache.GetOrParse("conv-1", bytes.Replace(convData, []byte("World"), []byte("There"), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Chunked("conv-1", newMsgs, 1<<20) {
+		t.Error("Chunked kept the decision of an older parse")
+	}
+}
+
+// TestParsedMessageCacheBudget verifies that parsed conversations are evicted
+// least recently used first once the shared budget is exceeded.
+func TestParsedMessageCacheBudget(t *testing.T) {
+	convData := []byte(`{"messages":[{"message_id":"m1","sequence_id":1,"type":"user","user_data":"Hello"}]}`)
+	msgs, err := shelley.ParseMessages(convData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget := shelley.NewCacheBudget(2 * parsedSize(msgs))
+	cache := NewParsedMessageCache()
+	cache.SetBudget(budget)
+
+	a, _, _ := cache.GetOrParse("conv-a", convData)
+	cache.GetOrParse("conv-b", convData)
+	cache.GetOrParse("conv-a", convData) // conv-b is now least recently used
+	cache.GetOrParse("conv-c", convData)
+
+	if u := budget.Usage(); u.Entries != 2 || u.Evictions != 1 {
+		t.Errorf("usage = %+v, want 2 entries and 1 eviction", u)
+	}
+	cache.mu.RLock()
+	_, hasB := cache.entries["conv-b"]
+	cache.mu.RUnlock()
+	if hasB {
+		t.Error("conv-b should have been evicted")
+	}
+	if again, _, _ := cache.GetOrParse("conv-a", convData); &again[0] != &a[0] {
+		t.Error("conv-a should still be cached")
+	}
+}
+
+// TestStatfsReportsCacheBudget verifies that df on the mount point shows
+// cache usage against the configured budget.
+func TestStatfsReportsCacheBudget(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+
+	budget := shelley.NewCacheBudget(1 << 20)
+	budget.Charge("x", 8192, func() {})
+	shelleyFS := NewFS(shelley.NewClient(server.URL), testStore(t), time.Hour)
+	shelleyFS.SetCacheBudget(budget)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(mountPoint, &st); err != nil {
+		t.Fatalf("Statfs failed: %v", err)
+	}
+	if st.Blocks != 256 || st.Bfree != 254 || st.Files != 1 {
+		t.Errorf("statfs = blocks %d, free %d, files %d; want 256, 254, 1", st.Blocks, st.Bfree, st.Files)
+	}
+}
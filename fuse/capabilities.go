@@ -0,0 +1,147 @@
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- BackendCapabilitiesNode: /shelley/backend/{name}/capabilities ---
+// Shows the features negotiated with the backend (shelley.FeatureNegotiator),
+// one key=value per line:
+//
+//	negotiated=true
+//	version=1.4.2
+//	delete=true
+//	edit=true
+//	settings=false
+//	streaming=false
+//
+// Features the backend didn't report are turned off in the filesystem:
+// without edit, message content.md files are read-only; without settings,
+// ctl is read-only once the conversation is created; without delete, rmdir
+// of a created conversation is EROFS; without streaming, messages/stream
+// polls the conversation. A backend without the capabilities endpoint
+// shows negotiated=false and every feature on. If negotiation hasn't
+// succeeded yet, reading the file tries it.
+//
+// Writing feature=false turns a feature off for the backend whatever it
+// reports, e.g. for a server whose stream endpoint misbehaves;
+// feature=true lifts that, and feature=auto leaves it to the backend
+// again. The flags are kept in the state file (see state/features.go), as
+// is what the backend last reported, so a remount knows both before it
+// negotiates again.
+
+type BackendCapabilitiesNode struct {
+	fs.Inode
+	name      string // the backend's name in the state
+	client    shelley.ShelleyClient
+	state     *state.Store
+	startTime time.Time
+}
+
+var _ = (fs.NodeOpener)((*BackendCapabilitiesNode)(nil))
+var _ = (fs.NodeReader)((*BackendCapabilitiesNode)(nil))
+var _ = (fs.NodeWriter)((*BackendCapabilitiesNode)(nil))
+var _ = (fs.NodeGetattrer)((*BackendCapabilitiesNode)(nil))
+var _ = (fs.NodeSetattrer)((*BackendCapabilitiesNode)(nil))
+
+func (c *BackendCapabilitiesNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return nil, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (c *BackendCapabilitiesNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	n, ok := c.client.(shelley.FeatureNegotiator)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	caps, ok := n.Capabilities()
+	if !ok {
+		var err error
+		if caps, err = n.Negotiate(); err != nil {
+			log.Printf("Capability negotiation failed: %v", err)
+			return nil, backendErrno(err)
+		}
+	}
+	enabled := func(feature string) bool {
+		return caps.Has(feature) && c.state.FeatureEnabledForBackend(c.name, feature)
+	}
+	return fuse.ReadResultData(readAt(capabilitiesData(caps, enabled), dest, off)), 0
+}
+
+// capabilitiesData renders caps for the capabilities file, with each
+// feature shown as enabled reports it.
+func capabilitiesData(caps shelley.Capabilities, enabled func(feature string) bool) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "negotiated=%t\n", caps.Negotiated)
+	if caps.Version != "" {
+		fmt.Fprintf(&b, "version=%s\n", caps.Version)
+	}
+	for _, feature := range shelley.KnownFeatures {
+		fmt.Fprintf(&b, "%s=%t\n", feature, enabled(feature))
+	}
+	return []byte(b.String())
+}
+
+// Write sets feature flags: whitespace-separated feature=true|false|auto.
+func (c *BackendCapabilitiesNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	type flag struct {
+		feature string
+		value   string
+	}
+	var flags []flag
+	for _, word := range strings.Fields(string(data)) {
+		feature, value, ok := strings.Cut(word, "=")
+		if !ok || !slices.Contains(shelley.KnownFeatures, feature) {
+			return 0, syscall.EINVAL
+		}
+		switch value {
+		case "true", "false", "auto":
+		default:
+			return 0, syscall.EINVAL
+		}
+		flags = append(flags, flag{feature, value})
+	}
+	for _, fl := range flags {
+		var err error
+		if fl.value == "auto" {
+			err = c.state.ClearBackendFeature(c.name, fl.feature)
+		} else {
+			err = c.state.SetBackendFeature(c.name, fl.feature, fl.value == "true")
+		}
+		if err != nil {
+			log.Printf("Setting %s=%s on backend %s failed: %v", fl.feature, fl.value, c.name, err)
+			return 0, syscall.EIO
+		}
+	}
+	return uint32(len(data)), 0
+}
+
+func (c *BackendCapabilitiesNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0644
+	setTimestamps(&out.Attr, c.startTime)
+	return 0
+}
+
+func (c *BackendCapabilitiesNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	// Accept truncate (from shell > redirect) silently
+	return c.Getattr(ctx, f, out)
+}
+
+// featureEnabled reports whether the backend behind client has feature:
+// negotiation in this session must not have ruled it out, and neither may
+// the state, which knows what the backend reported before and the flags
+// set through the capabilities file. Nodes reach conversations through
+// the default backend's state, so its flags are the ones that apply.
+func featureEnabled(client shelley.ShelleyClient, store *state.Store, feature string) bool {
+	return shelley.FeatureEnabled(client, feature) && store.FeatureEnabled(feature)
+}
@@ -0,0 +1,101 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestBackendCapabilities(t *testing.T) {
+	negotiating := mockserver.New(
+		mockserver.WithConversation("conv-edit", editTestMessages()),
+		mockserver.WithMessageEditing(),
+		mockserver.WithCapabilities("2.0", shelley.FeatureDelete),
+	)
+	defer negotiating.Close()
+	older := mockserver.New()
+	defer older.Close()
+
+	store := testStore(t)
+	if err := store.EnsureBackendURL(state.DefaultBackendName, negotiating.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateBackend("older", older.URL); err != nil {
+		t.Fatal(err)
+	}
+	localID, _ := store.AdoptWithSlug("conv-edit", "")
+	mountPoint, cleanup := mountFS(t, NewFSWithBackends(shelley.NewClientManager(0), store, time.Hour))
+	defer cleanup()
+
+	for backend, want := range map[string]string{
+		"main":  "negotiated=true\nversion=2.0\ndelete=true\nedit=false\nsettings=false\nstreaming=false\n",
+		"older": "negotiated=false\ndelete=true\nedit=true\nsettings=true\nstreaming=true\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(mountPoint, "backend", backend, "capabilities"))
+		if err != nil || string(data) != want {
+			t.Errorf("backend/%s/capabilities = %q, %v; want %q", backend, data, err, want)
+		}
+	}
+
+	// The backend can edit messages, but didn't say so.
+	content := filepath.Join(mountPoint, "backend", "main", "conversation", localID, "messages", "0-user", "content.md")
+	fi, err := os.Stat(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0444 {
+		t.Errorf("content.md without the edit feature has mode %v, want read-only", fi.Mode())
+	}
+	if err := os.WriteFile(content, []byte("rewritten\n"), 0644); err == nil {
+		t.Error("edit without the edit feature succeeded")
+	}
+}
+
+func TestBackendCapabilitiesWrite(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithConversation("conv-del", editTestMessages()),
+		mockserver.WithCapabilities("2.0", shelley.FeatureDelete, shelley.FeatureEdit),
+	)
+	defer server.Close()
+
+	store := testStore(t)
+	if err := store.EnsureBackendURL(state.DefaultBackendName, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	localID, _ := store.AdoptWithSlug("conv-del", "")
+	mountPoint, cleanup := mountFS(t, NewFSWithBackends(shelley.NewClientManager(0), store, time.Hour))
+	defer cleanup()
+
+	capsFile := filepath.Join(mountPoint, "backend", "main", "capabilities")
+	if err := os.WriteFile(capsFile, []byte("delete=false\n"), 0644); err != nil {
+		t.Fatalf("writing delete=false: %v", err)
+	}
+	if store.FeatureEnabled(shelley.FeatureDelete) {
+		t.Error("delete=false not recorded in state")
+	}
+	data, _ := os.ReadFile(capsFile)
+	want := "negotiated=true\nversion=2.0\ndelete=false\nedit=true\nsettings=false\nstreaming=false\n"
+	if string(data) != want {
+		t.Errorf("capabilities after delete=false = %q, want %q", data, want)
+	}
+	if err := os.Remove(filepath.Join(mountPoint, "conversation", localID)); err == nil {
+		t.Error("rmdir succeeded with the delete feature turned off")
+	}
+
+	if err := os.WriteFile(capsFile, []byte("delete=auto\n"), 0644); err != nil {
+		t.Fatalf("writing delete=auto: %v", err)
+	}
+	if !store.FeatureEnabled(shelley.FeatureDelete) {
+		t.Error("delete=auto didn't hand the decision back to the backend")
+	}
+	for _, bad := range []string{"shiny=true\n", "delete=maybe\n", "delete\n"} {
+		if err := os.WriteFile(capsFile, []byte(bad), 0644); err == nil {
+			t.Errorf("writing %q succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- MarkdownChunksDirNode: /conversation/{id}/messages/all.md.d/ ---
+// Holds all.md split into part-001.md, part-002.md, ... of at most chunkSize
+// bytes each, for conversations too large to open as a single file. Parts
+// break between messages, so `cat all.md.d/*` reproduces all.md exactly.
+// The directory only exists while all.md is larger than chunkSize.
+
+type MarkdownChunksDirNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	chunkSize   int
+	startTime   time.Time
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*MarkdownChunksDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*MarkdownChunksDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*MarkdownChunksDirNode)(nil))
+
+// chunkPartName returns the file name of the i'th (0-based) part.
+func chunkPartName(i int) string {
+	return fmt.Sprintf("part-%03d.md", i+1)
+}
+
+// parseChunkPartName is the inverse of chunkPartName.
+func parseChunkPartName(name string) (int, bool) {
+	var n int
+	if _, err := fmt.Sscanf(name, "part-%d.md", &n); err != nil || n < 1 || chunkPartName(n-1) != name {
+		return 0, false
+	}
+	return n - 1, true
+}
+
+// chunkedMarkdown splits the markdown rendering of msgs into chunks of at
+// most size bytes, or returns nil if it fits in size bytes and needs no
+// splitting.
+func chunkedMarkdown(msgs []shelley.Message, size int) [][]byte {
+	chunks := shelley.FormatMarkdownChunks(msgs, size)
+	if len(chunks) == 1 && len(chunks[0]) <= size {
+		return nil
+	}
+	return chunks
+}
+
+// loadMarkdownChunks fetches a conversation and returns its chunked markdown,
+// or nil if chunking is disabled or all.md is small enough.
+func loadMarkdownChunks(client shelley.ShelleyClient, store *state.Store, parsedCache *ParsedMessageCache, localID string, size int) ([][]byte, syscall.Errno) {
+	if size <= 0 {
+		return nil, 0
+	}
+	cs := store.Get(localID)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return nil, 0
+	}
+	convData, err := client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	msgs, _, err := parsedCache.GetOrParse(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	return parsedCache.MarkdownChunks(cs.ShelleyConversationID, msgs, size), 0
+}
+
+func (m *MessagesDirNode) markdownChunks() ([][]byte, syscall.Errno) {
+	return loadMarkdownChunks(m.client, m.state, m.parsedCache, m.localID, m.mdChunkSize)
+}
+
+func (d *MarkdownChunksDirNode) chunks() ([][]byte, syscall.Errno) {
+	return loadMarkdownChunks(d.client, d.state, d.parsedCache, d.localID, d.chunkSize)
+}
+
+func (d *MarkdownChunksDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(d.diag, "MarkdownChunksDirNode", "Lookup", d.localID+"/"+name).Done()
+	idx, ok := parseChunkPartName(name)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	chunks, errno := d.chunks()
+	if errno != 0 {
+		return nil, errno
+	}
+	if idx >= len(chunks) {
+		return nil, syscall.ENOENT
+	}
+	return d.NewInode(ctx, &MarkdownChunkNode{dir: d, index: idx}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+}
+
+func (d *MarkdownChunksDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(d.diag, "MarkdownChunksDirNode", "Readdir", d.localID).Done()
+	chunks, errno := d.chunks()
+	if errno != 0 {
+		return nil, errno
+	}
+	entries := make([]fuse.DirEntry, 0, len(chunks))
+	for i := range chunks {
+		entries = append(entries, fuse.DirEntry{Name: chunkPartName(i), Mode: fuse.S_IFREG})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (d *MarkdownChunksDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, metaTime(d.state, d.localID, d.startTime))
+	return 0
+}
+
+// --- MarkdownChunkNode: /conversation/{id}/messages/all.md.d/part-NNN.md ---
+
+type MarkdownChunkNode struct {
+	fs.Inode
+	dir   *MarkdownChunksDirNode
+	index int
+}
+
+var _ = (fs.NodeOpener)((*MarkdownChunkNode)(nil))
+var _ = (fs.NodeGetattrer)((*MarkdownChunkNode)(nil))
+
+// Open renders the part once so reads through the handle stay consistent,
+// like all.md itself. A part that no longer exists (the conversation was
+// re-chunked under a different size) reads as ENOENT. Reading a part from
+// start to end warms the caches for the next one (see readAhead).
+func (n *MarkdownChunkNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(n.dir.diag, "MarkdownChunkNode", "Open", n.dir.localID+"/"+chunkPartName(n.index)).Done()
+	chunks, errno := n.dir.chunks()
+	if errno == 0 && n.index >= len(chunks) {
+		errno = syscall.ENOENT
+	}
+	if errno != 0 {
+		return &ConvContentFileHandle{errno: errno}, fuse.FOPEN_DIRECT_IO, 0
+	}
+	return &ConvContentFileHandle{content: chunks[n.index], localID: n.dir.localID, state: n.dir.state, startTime: n.dir.startTime, readAhead: n.dir.readAheadChunks(n.index, len(chunks))}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *MarkdownChunkNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fga, ok := f.(fs.FileGetattrer); ok {
+		return fga.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	if exportCompat(&n.Inode) {
+		openedSize(ctx, n, out)
+	}
+	setTimestamps(&out.Attr, metaTime(n.dir.state, n.dir.localID, n.dir.startTime))
+	return 0
+}
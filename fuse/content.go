@@ -19,11 +19,11 @@ import (
 type queryKind int
 
 const (
-	queryAll   queryKind = iota
-	queryBySeq           // {N}.json
-	queryLast            // last/{N}
-	querySince           // since/{person}/{N}
-
+	queryAll    queryKind = iota
+	queryBySeq            // {N}.json
+	queryLast             // last/{N}
+	querySince            // since/{person}/{N}
+	queryFilter           // filter/{role} or filter/tool/{toolname}
 )
 
 type contentFormat int
@@ -31,6 +31,7 @@ type contentFormat int
 const (
 	formatJSON contentFormat = iota
 	formatMD
+	formatOrg
 )
 
 type contentQuery struct {
@@ -63,6 +64,8 @@ func (c *ConvContentNode) Open(ctx context.Context, flags uint32) (fs.FileHandle
 	// Fetch and cache content at open time to ensure consistent reads.
 	// Without caching, multiple read() calls would regenerate data each time,
 	// and if the conversation changed between reads, the result would be corrupted.
+	// The handle also answers fstat, so the size seen through the fd is the
+	// size of the snapshot rather than of whatever the server has now.
 	cs := c.state.Get(c.localID)
 	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
 		// Return handle that will report ENOENT on read (preserves original behavior)
@@ -71,7 +74,7 @@ func (c *ConvContentNode) Open(ctx context.Context, flags uint32) (fs.FileHandle
 
 	convData, err := c.client.GetConversation(cs.ShelleyConversationID)
 	if err != nil {
-		return &ConvContentFileHandle{errno: syscall.EIO}, fuse.FOPEN_DIRECT_IO, 0
+		return &ConvContentFileHandle{errno: backendErrno(err)}, fuse.FOPEN_DIRECT_IO, 0
 	}
 	msgs, toolMap, err := c.parsedCache.GetOrParse(cs.ShelleyConversationID, convData)
 	if err != nil {
@@ -91,7 +94,7 @@ func (c *ConvContentNode) Open(ctx context.Context, flags uint32) (fs.FileHandle
 	if c.query.kind == queryBySeq {
 		return &ConvContentFileHandle{content: data, messageTime: c.messageTime, startTime: c.startTime, localID: c.localID, state: c.state}, fuse.FOPEN_KEEP_CACHE, 0
 	}
-	return &ConvContentFileHandle{content: data}, fuse.FOPEN_DIRECT_IO, 0
+	return &ConvContentFileHandle{content: data, startTime: c.startTime, localID: c.localID, state: c.state}, fuse.FOPEN_DIRECT_IO, 0
 }
 
 // ConvContentFileHandle caches content for consistent reads across multiple read() calls
@@ -102,10 +105,12 @@ type ConvContentFileHandle struct {
 	startTime   time.Time    // fallback timestamp
 	localID     string       // for looking up conversation creation time
 	state       *state.Store // for looking up conversation creation time
+	readAhead   *readAhead   // loads what a sequential reader opens next (nil = nothing)
 }
 
 var _ = (fs.FileReader)((*ConvContentFileHandle)(nil))
 var _ = (fs.FileGetattrer)((*ConvContentFileHandle)(nil))
+var _ = (fs.FileLseeker)((*ConvContentFileHandle)(nil))
 
 func (h *ConvContentFileHandle) Getattr(ctx context.Context, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFREG | 0444
@@ -122,11 +127,17 @@ func (h *ConvContentFileHandle) Getattr(ctx context.Context, out *fuse.AttrOut)
 	return 0
 }
 
+func (h *ConvContentFileHandle) Lseek(ctx context.Context, off uint64, whence uint32) (uint64, syscall.Errno) {
+	return seekContent(len(h.content), off, whence)
+}
+
 func (h *ConvContentFileHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
 	if h.errno != 0 {
 		return nil, h.errno
 	}
-	return fuse.ReadResultData(readAt(h.content, dest, off)), 0
+	data := readAt(h.content, dest, off)
+	h.readAhead.observe(off, len(data), len(h.content))
+	return fuse.ReadResultData(data), 0
 }
 
 func (c *ConvContentNode) formatResult(msgs []shelley.Message, toolMap map[string]string) ([]byte, syscall.Errno) {
@@ -154,6 +165,8 @@ func (c *ConvContentNode) formatResult(msgs []shelley.Message, toolMap map[strin
 	switch c.query.format {
 	case formatMD:
 		return shelley.FormatMarkdown(filtered), 0
+	case formatOrg:
+		return shelley.FormatOrg(filtered), 0
 	default:
 		data, err := shelley.FormatJSON(filtered)
 		if err != nil {
@@ -165,7 +178,13 @@ func (c *ConvContentNode) formatResult(msgs []shelley.Message, toolMap map[strin
 }
 
 func (c *ConvContentNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fga, ok := f.(fs.FileGetattrer); ok {
+		return fga.Getattr(ctx, out)
+	}
 	out.Mode = fuse.S_IFREG | 0444
+	if exportCompat(&c.Inode) {
+		openedSize(ctx, c, out)
+	}
 	// For individual message files, use the message's timestamp
 	if !c.messageTime.IsZero() {
 		setTimestamps(&out.Attr, c.messageTime)
@@ -182,6 +201,7 @@ func (c *ConvContentNode) Getattr(ctx context.Context, f fs.FileHandle, out *fus
 }
 
 // --- QueryDirNode: handles last/, since/ and since/{person}/ ---
+// since/bookmark/ is a BookmarkDirNode instead (see bookmark.go).
 
 type QueryDirNode struct {
 	fs.Inode
@@ -201,6 +221,14 @@ var _ = (fs.NodeGetattrer)((*QueryDirNode)(nil))
 
 func (q *QueryDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(q.diag, "QueryDirNode", "Lookup", q.localID+"/"+name).Done()
+	// since/bookmark/ lists the messages after the reader's bookmark
+	if q.kind == querySince && q.person == "" && name == "bookmark" {
+		ino := stableIno("query-dir", q.localID, "since-bookmark")
+		return q.NewInode(ctx, &BookmarkDirNode{
+			localID: q.localID, client: q.client, state: q.state,
+			startTime: q.startTime, parsedCache: q.parsedCache, diag: q.diag,
+		}, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
+	}
 	// If this is since/ (no person set), the child is a person directory
 	if q.kind == querySince && q.person == "" {
 		// Use a stable inode number so go-fuse reuses the existing node
@@ -249,7 +277,7 @@ func (q *QueryDirNode) getSymlinkTarget(n int) (string, syscall.Errno) {
 
 	convData, err := q.client.GetConversation(cs.ShelleyConversationID)
 	if err != nil {
-		return "", syscall.EIO
+		return "", backendErrno(err)
 	}
 
 	result, err := q.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
@@ -297,7 +325,111 @@ func (q *QueryDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.A
 	return 0
 }
 
-// --- QueryResultDirNode: represents last/{N}/ or since/{person}/{N}/ ---
+// --- FilterDirNode: handles filter/ and filter/tool/ ---
+// filter/{role}/ selects messages by slug (user, agent, bash-result, ...) and
+// filter/tool/{toolname}/ selects both the calls to and the results of a tool.
+
+type FilterDirNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	tools       bool      // true for filter/tool/
+	startTime   time.Time // fallback if conversation has no CreatedAt
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*FilterDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*FilterDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*FilterDirNode)(nil))
+
+func (f *FilterDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(f.diag, "FilterDirNode", "Lookup", f.localID+"/"+name).Done()
+	if !f.tools && name == "tool" {
+		ino := stableIno("query-dir", f.localID, "filter", "tool")
+		return f.NewInode(ctx, &FilterDirNode{
+			localID: f.localID, client: f.client, state: f.state,
+			tools: true, startTime: f.startTime, parsedCache: f.parsedCache, diag: f.diag,
+		}, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
+	}
+
+	// Like since/{person}/, any name is accepted; unmatched roles and tools
+	// simply produce an empty directory. Stable inodes let the result node's
+	// filtered snapshot survive repeated traversals during ls -l.
+	node := &QueryResultDirNode{
+		localID:     f.localID,
+		client:      f.client,
+		state:       f.state,
+		kind:        queryFilter,
+		startTime:   f.startTime,
+		parsedCache: f.parsedCache,
+		diag:        f.diag,
+	}
+	var ino uint64
+	if f.tools {
+		node.tool = name
+		ino = stableIno("query-filter-tool", f.localID, name)
+	} else {
+		node.person = name
+		ino = stableIno("query-filter", f.localID, name)
+	}
+	return f.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
+}
+
+// Readdir lists the roles (or tool names, for filter/tool/) that occur in the
+// conversation, so the available filters are discoverable with ls.
+func (f *FilterDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(f.diag, "FilterDirNode", "Readdir", f.localID).Done()
+	var entries []fuse.DirEntry
+	if !f.tools {
+		entries = append(entries, fuse.DirEntry{Name: "tool", Mode: fuse.S_IFDIR})
+	}
+
+	cs := f.state.Get(f.localID)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return fs.NewListDirStream(entries), 0
+	}
+	convData, err := f.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	result, err := f.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return nil, syscall.EIO
+	}
+
+	seen := make(map[string]bool)
+	for i := range result.Messages {
+		var name string
+		tool := shelley.MessageToolName(&result.Messages[i], result.ToolMap)
+		if f.tools {
+			name = tool
+		} else if tool == "" {
+			name = shelley.MessageSlug(&result.Messages[i], result.ToolMap)
+		}
+		if name == "" || seen[name] || (!f.tools && name == "tool") || !isValidFilename(name) {
+			continue
+		}
+		seen[name] = true
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: fuse.S_IFDIR})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (f *FilterDirNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	cs := f.state.Get(f.localID)
+	if cs != nil && !cs.CreatedAt.IsZero() {
+		setTimestamps(&out.Attr, cs.CreatedAt)
+	} else {
+		setTimestamps(&out.Attr, f.startTime)
+	}
+	return 0
+}
+
+// --- QueryResultDirNode: represents last/{N}/, since/{person}/{N}/, ---
+// filter/{role}/ or filter/tool/{toolname}/.
 // Contains symlinks to the actual message directories.
 
 type QueryResultDirNode struct {
@@ -305,9 +437,10 @@ type QueryResultDirNode struct {
 	localID     string
 	client      shelley.ShelleyClient
 	state       *state.Store
-	kind        queryKind // queryLast or querySince
+	kind        queryKind // queryLast, querySince or queryFilter
 	n           int       // the N in last/{N} or since/{person}/{N}
-	person      string    // set for since/{person}/{N}
+	person      string    // set for since/{person}/{N} and filter/{role}
+	tool        string    // set for filter/tool/{toolname}
 	startTime   time.Time
 	parsedCache *ParsedMessageCache
 	diag        *diag.Tracker
@@ -327,7 +460,7 @@ type QueryResultDirNode struct {
 type queryResultSnapshot struct {
 	filtered []shelley.Message
 	maxSeqID int
-	nameIdx  map[string]int // entry name → index in filtered (for since/ queries)
+	nameIdx  map[string]int // entry name → index in filtered (for since/ and filter/ queries)
 }
 
 var _ = (fs.NodeLookuper)((*QueryResultDirNode)(nil))
@@ -373,6 +506,12 @@ func (q *QueryResultDirNode) getFilteredMessages() (snap *queryResultSnapshot, t
 		filtered = shelley.FilterLast(result.Messages, q.n)
 	case querySince:
 		filtered = shelley.FilterSinceWithToolMap(result.Messages, q.person, q.n, result.ToolMap)
+	case queryFilter:
+		if q.tool != "" {
+			filtered = shelley.FilterByToolWithToolMap(result.Messages, q.tool, result.ToolMap)
+		} else {
+			filtered = shelley.FilterBySlugWithToolMap(result.Messages, q.person, result.ToolMap)
+		}
 	}
 
 	snap = &queryResultSnapshot{
@@ -380,8 +519,8 @@ func (q *QueryResultDirNode) getFilteredMessages() (snap *queryResultSnapshot, t
 		maxSeqID: result.MaxSeqID,
 	}
 
-	// Build name index for since/ queries to enable O(1) lookup by name
-	if q.kind == querySince && filtered != nil {
+	// Build name index for since/ and filter/ queries to enable O(1) lookup by name
+	if q.kind != queryLast && filtered != nil {
 		snap.nameIdx = make(map[string]int, len(filtered))
 		for i := range filtered {
 			slug := shelley.MessageSlug(&filtered[i], result.ToolMap)
@@ -399,8 +538,9 @@ func (q *QueryResultDirNode) getFilteredMessages() (snap *queryResultSnapshot, t
 // symlinkPrefix returns the relative path prefix for symlinks.
 // For last/{N}/, this is "../../" (up to last/, up to messages/)
 // For since/{person}/{N}/, this is "../../../" (up to {N}/, up to {person}/, up to since/, up to messages/)
+// For filter/{role}/, this is "../../" and for filter/tool/{toolname}/ it is "../../../"
 func (q *QueryResultDirNode) symlinkPrefix() string {
-	if q.kind == queryLast {
+	if q.kind == queryLast || (q.kind == queryFilter && q.tool == "") {
 		return "../../"
 	}
 	return "../../../"
@@ -410,7 +550,7 @@ func (q *QueryResultDirNode) Lookup(ctx context.Context, name string, out *fuse.
 	defer diag.Track(q.diag, "QueryResultDirNode", "Lookup", q.localID+"/"+name).Done()
 	snap, toolMap, err := q.getFilteredMessages()
 	if err != nil {
-		return nil, syscall.EIO
+		return nil, backendErrno(err)
 	}
 	if snap == nil || snap.filtered == nil {
 		return nil, syscall.ENOENT
@@ -431,7 +571,7 @@ func (q *QueryResultDirNode) Lookup(ctx context.Context, name string, out *fuse.
 		return q.NewInode(ctx, &SymlinkNode{target: target, startTime: q.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
 	}
 
-	// For since/{person}/{N}, use the pre-built name index for O(1) lookup
+	// For since/{person}/{N} and filter/, use the pre-built name index for O(1) lookup
 	if snap.nameIdx != nil {
 		if _, ok := snap.nameIdx[name]; ok {
 			target := q.symlinkPrefix() + name
@@ -446,7 +586,7 @@ func (q *QueryResultDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall
 	defer diag.Track(q.diag, "QueryResultDirNode", "Readdir", q.localID).Done()
 	snap, toolMap, err := q.getFilteredMessages()
 	if err != nil {
-		return nil, syscall.EIO
+		return nil, backendErrno(err)
 	}
 	if snap == nil {
 		return fs.NewListDirStream(nil), 0
@@ -454,7 +594,7 @@ func (q *QueryResultDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall
 
 	entries := make([]fuse.DirEntry, 0, len(snap.filtered))
 	// For last/{N}, entries are ordinal (0, 1, 2, ...)
-	// For since/{person}/{N}, entries are message base names
+	// For since/{person}/{N} and filter/, entries are message base names
 	if q.kind == queryLast {
 		for i := range snap.filtered {
 			entries = append(entries, fuse.DirEntry{Name: strconv.Itoa(i), Mode: syscall.S_IFLNK})
@@ -3,8 +3,10 @@ package fuse
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -28,61 +30,108 @@ type ConversationListNode struct {
 	client       shelley.ShelleyClient
 	state        *state.Store
 	cloneTimeout time.Duration
+	cloneByModel map[string]time.Duration
+	layout       Layout
+	mdChunkSize  int
+	sparseMsgs   bool
+	maxSend      int
 	startTime    time.Time
 	parsedCache  *ParsedMessageCache
+	events       *EventBus
+	activity     *ActivityBoard
+	usage        *UsageBoard
+	budget       Budget
+	filter       *ConversationFilter
+	sends        *RecentSends
+	snapshots    *Snapshots
 	diag         *diag.Tracker
+	bulk         bulkLog // last .bulk report
 }
 
 var _ = (fs.NodeLookuper)((*ConversationListNode)(nil))
 var _ = (fs.NodeReaddirer)((*ConversationListNode)(nil))
 var _ = (fs.NodeGetattrer)((*ConversationListNode)(nil))
 var _ = (fs.NodeRmdirer)((*ConversationListNode)(nil))
+var _ = (fs.NodeMkdirer)((*ConversationListNode)(nil))
+var _ = (fs.NodeUnlinker)((*ConversationListNode)(nil))
 
+// Lookup fills in attributes as well: every entry is either a conversation
+// directory or a symlink whose attributes come from the state store, so
+// READDIRPLUS can return them with the listing.
 func (c *ConversationListNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(c.diag, "ConversationListNode", "Lookup", name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&c.Inode).Conversation)
+	child, errno := c.lookup(ctx, name)
+	if errno == 0 {
+		fillEntryAttr(ctx, child, out, cacheTimeouts(&c.Inode).Conversation)
+	}
+	return child, errno
+}
 
+func (c *ConversationListNode) lookup(ctx context.Context, name string) (*fs.Inode, syscall.Errno) {
 	// Handle the "last" virtual directory
 	if name == "last" {
 		return c.NewInode(ctx, &ConversationLastDirNode{
 			client:    c.client,
 			state:     c.state,
+			filter:    c.filter,
 			startTime: c.startTime,
 			diag:      c.diag,
-		}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		}, fs.StableAttr{Mode: conversationListNames.mode("last")}), 0
+	}
+
+	if name == "import" {
+		return c.NewInode(ctx, &ConvImportNode{client: c.client, state: c.state, startTime: c.startTime, diag: c.diag}, fs.StableAttr{Mode: conversationListNames.mode("import")}), 0
+	}
+
+	if name == "attach" {
+		return c.NewInode(ctx, &AttachNode{list: c}, fs.StableAttr{Mode: conversationListNames.mode("attach")}), 0
+	}
+
+	if name == "index.json" {
+		return c.NewInode(ctx, &IndexNode{list: c}, fs.StableAttr{Mode: conversationListNames.mode("index.json")}), 0
+	}
+
+	if name == ".bulk" {
+		return c.NewInode(ctx, &BulkNode{list: c}, fs.StableAttr{Mode: conversationListNames.mode(".bulk")}), 0
+	}
+
+	if name == "top" {
+		return c.NewInode(ctx, &TopDirNode{list: c}, fs.StableAttr{Mode: conversationListNames.mode("top")}), 0
+	}
+
+	if name == ".pending" {
+		return c.NewInode(ctx, &PendingDirNode{list: c}, fs.StableAttr{Mode: conversationListNames.mode(".pending")}), 0
+	}
+
+	if name == "by-title" && c.titleLinks() {
+		return c.NewInode(ctx, &TitleLinksDirNode{list: c}, fs.StableAttr{Mode: conversationListNames.mode("by-title")}), 0
+	}
+
+	if name == ".events" {
+		if c.events == nil {
+			return nil, syscall.ENOENT
+		}
+		return c.NewInode(ctx, &EventsNode{bus: c.events, startTime: c.startTime, diag: c.diag}, fs.StableAttr{Mode: conversationListNames.mode(".events")}), 0
+	}
+
+	if name == ".activity.json" && c.activity != nil {
+		return c.NewInode(ctx, &ActivityNode{board: c.activity, state: c.state, startTime: c.startTime, diag: c.diag}, fs.StableAttr{Mode: conversationListNames.mode(".activity.json")}), 0
 	}
 
 	// First check if it's a known local ID (the common case after Readdir adoption)
-	cs := c.state.Get(name)
-	if cs != nil {
-		return c.NewInode(ctx, &ConversationNode{
-			localID:     name,
-			client:      c.client,
-			state:       c.state,
-			startTime:   c.startTime,
-			parsedCache: c.parsedCache,
-			diag:        c.diag,
-		}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+	if cs := c.state.Get(name); cs != nil {
+		return c.entryFor(ctx, name, name, c.symlinkTime(name)), 0
 	}
 
-	// Check if it's a known server ID (return symlink to local ID)
+	// Check if it's a known server ID (return symlink to the conversation directory)
 	if localID := c.state.GetByShelleyID(name); localID != "" {
-		localCS := c.state.Get(localID)
-		symlinkTime := c.startTime
-		if localCS != nil && !localCS.CreatedAt.IsZero() {
-			symlinkTime = localCS.CreatedAt
-		}
-		return c.NewInode(ctx, &SymlinkNode{target: localID, startTime: symlinkTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		return c.entryFor(ctx, name, localID, c.symlinkTime(localID)), 0
 	}
 
-	// Check if it's a known slug (return symlink to local ID)
+	// Check if it's a known slug (a symlink, or the directory itself with -layout=slugs)
 	if localID := c.state.GetBySlug(name); localID != "" {
-		localCS := c.state.Get(localID)
-		symlinkTime := c.startTime
-		if localCS != nil && !localCS.CreatedAt.IsZero() {
-			symlinkTime = localCS.CreatedAt
-		}
-		return c.NewInode(ctx, &SymlinkNode{target: localID, startTime: symlinkTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		return c.entryFor(ctx, name, localID, c.symlinkTime(localID)), 0
 	}
 
 	// For backwards compatibility, also support lookup by Shelley server ID
@@ -120,39 +169,103 @@ func derefStr(p *string) string {
 }
 
 // lookupInConversationList searches for a conversation by ID or slug in the given list.
-// If found, it adopts the conversation locally and returns a symlink to the local ID.
+// If found, it adopts the conversation locally and returns a symlink to its directory.
 func (c *ConversationListNode) lookupInConversationList(ctx context.Context, name string, convs []shelley.Conversation) (*fs.Inode, syscall.Errno) {
 	for _, conv := range convs {
-		if conv.ConversationID == name {
-			// Adopt this server conversation locally with API metadata
-			slug := derefStr(conv.Slug)
-			localID, err := c.state.AdoptWithMetadata(name, slug, conv.CreatedAt, conv.UpdatedAt, derefStr(conv.Model), derefStr(conv.Cwd))
-			if err != nil {
-				return nil, syscall.EIO
-			}
-			// Return symlink to the local ID - use API timestamp if available
-			symlinkTime := c.getConversationTimestamps(localID).Ctime
-			if symlinkTime.IsZero() {
-				symlinkTime = c.startTime
-			}
-			return c.NewInode(ctx, &SymlinkNode{target: localID, startTime: symlinkTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		// Match by server ID, or by slug for not-yet-adopted conversations
+		if conv.ConversationID != name && (conv.Slug == nil || *conv.Slug != name) {
+			continue
 		}
-		// Also check by slug for not-yet-adopted conversations
-		if conv.Slug != nil && *conv.Slug == name {
-			localID, err := c.state.AdoptWithMetadata(conv.ConversationID, *conv.Slug, conv.CreatedAt, conv.UpdatedAt, derefStr(conv.Model), derefStr(conv.Cwd))
-			if err != nil {
-				return nil, syscall.EIO
-			}
-			symlinkTime := c.getConversationTimestamps(localID).Ctime
-			if symlinkTime.IsZero() {
-				symlinkTime = c.startTime
-			}
-			return c.NewInode(ctx, &SymlinkNode{target: localID, startTime: symlinkTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		// Adopt this server conversation locally with API metadata
+		localID, err := c.state.AdoptWithMetadata(conv.ConversationID, derefStr(conv.Slug), conv.CreatedAt, conv.UpdatedAt, derefStr(conv.Model), derefStr(conv.Cwd))
+		if err != nil {
+			return nil, syscall.EIO
 		}
+		// Use API timestamp for the symlink if available
+		symlinkTime := c.getConversationTimestamps(localID).Ctime
+		if symlinkTime.IsZero() {
+			symlinkTime = c.startTime
+		}
+		return c.entryFor(ctx, name, localID, symlinkTime), 0
 	}
 	return nil, syscall.ENOENT
 }
 
+// dirName returns the name a conversation's directory is listed under.
+// With LayoutIDs that is always the local ID. With LayoutSlugs it is the
+// slug, unless the conversation has none or the slug would clash with a
+// local ID, a reserved name (see reservedListName), or another
+// conversation's slug.
+func (c *ConversationListNode) dirName(cs *state.ConversationState) string {
+	if c.layout != LayoutSlugs || !isValidFilename(cs.Slug) || reservedListName(cs.Slug) {
+		return cs.LocalID
+	}
+	if c.state.Get(cs.Slug) != nil || c.state.GetBySlug(cs.Slug) != cs.LocalID {
+		return cs.LocalID
+	}
+	return cs.Slug
+}
+
+// reservedListName reports whether name is one of the fixed entries of
+// /conversation, which a slug directory must not shadow.
+func reservedListName(name string) bool {
+	_, ok := conversationListNames[name]
+	return ok
+}
+
+// entryFor returns the inode for name, which refers to the conversation
+// localID: the conversation directory when name is its directory name,
+// otherwise a symlink to that directory.
+func (c *ConversationListNode) entryFor(ctx context.Context, name, localID string, symlinkTime time.Time) *fs.Inode {
+	target := localID
+	if cs := c.state.Get(localID); cs != nil {
+		target = c.dirName(cs)
+	}
+	if target != name {
+		return c.NewInode(ctx, &SymlinkNode{target: target, startTime: symlinkTime}, fs.StableAttr{Mode: syscall.S_IFLNK})
+	}
+	return c.conversationDir(ctx, localID)
+}
+
+// conversationDir returns a new directory inode for the conversation localID.
+func (c *ConversationListNode) conversationDir(ctx context.Context, localID string) *fs.Inode {
+	return c.NewInode(ctx, &ConversationNode{
+		localID:     localID,
+		client:      c.client,
+		state:       c.state,
+		startTime:   c.startTime,
+		mdChunkSize: c.mdChunkSize,
+		sparseMsgs:  c.sparseMsgs,
+		maxSend:     c.maxSend,
+		budget:      c.budget,
+		parsedCache: c.parsedCache,
+		sends:       c.sends,
+		snapshots:   c.snapshots,
+		diag:        c.diag,
+	}, fs.StableAttr{Mode: fuse.S_IFDIR})
+}
+
+// cloneTimeoutFor returns how long an unconversed clone may live before it
+// is cleaned up: the override for its model (matched by display name or
+// model ID) if there is one, otherwise the default. Zero means never.
+func (c *ConversationListNode) cloneTimeoutFor(cs state.ConversationState) time.Duration {
+	for _, model := range []string{cs.Model, cs.ModelID} {
+		if d, ok := c.cloneByModel[model]; ok && model != "" {
+			return d
+		}
+	}
+	return c.cloneTimeout
+}
+
+// symlinkTime returns the timestamp for symlinks pointing at a conversation:
+// its local creation time, or the FS start time if unknown.
+func (c *ConversationListNode) symlinkTime(localID string) time.Time {
+	if cs := c.state.Get(localID); cs != nil && !cs.CreatedAt.IsZero() {
+		return cs.CreatedAt
+	}
+	return c.startTime
+}
+
 // getConversationTimestamps returns timestamps for a conversation using the metadata mapping.
 // Falls back to local CreatedAt if API timestamps are not available.
 func (c *ConversationListNode) getConversationTimestamps(localID string) metadata.Timestamps {
@@ -179,8 +292,11 @@ func (c *ConversationListNode) getConversationTimestamps(localID string) metadat
 	return metadata.Timestamps{}
 }
 
-func (c *ConversationListNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
-	defer diag.Track(c.diag, "ConversationListNode", "Readdir", "").Done()
+// listedConversations adopts the server's conversations and returns the
+// ones /conversation lists: created, and neither archived nor gone from
+// the server. It also reports whether any clone is still pending. Expired
+// clones are cleaned up along the way.
+func (c *ConversationListNode) listedConversations(op *diag.OpHandle) (listed []state.ConversationState, pending bool) {
 	// Adopt any server conversations that aren't tracked locally, and update
 	// slugs for already-tracked conversations (slugs are always provided immediately).
 	serverConvs, err := c.fetchServerConversations()
@@ -188,14 +304,12 @@ func (c *ConversationListNode) Readdir(ctx context.Context) (fs.DirStream, sysca
 	// Build a set of valid server conversation IDs for filtering stale entries
 	validServerIDs := make(map[string]bool)
 	serverFetchSucceeded := err == nil
+	var adopt []state.AdoptRequest
 
 	if serverFetchSucceeded {
 		for _, conv := range serverConvs {
 			validServerIDs[conv.ConversationID] = true
-			// AdoptWithMetadata handles the case where a conversation is not yet tracked locally
-			// and also updates API timestamps. Errors are non-fatal; worst case the conversation
-			// won't appear in this listing but will be adopted on next Lookup
-			_, _ = c.state.AdoptWithMetadata(conv.ConversationID, derefStr(conv.Slug), conv.CreatedAt, conv.UpdatedAt, derefStr(conv.Model), derefStr(conv.Cwd))
+			adopt = append(adopt, adoptRequest(conv))
 		}
 	}
 
@@ -210,29 +324,34 @@ func (c *ConversationListNode) Readdir(ctx context.Context) (fs.DirStream, sysca
 		for _, conv := range archivedConvs {
 			validServerIDs[conv.ConversationID] = true
 			archivedServerIDs[conv.ConversationID] = true
-			_, _ = c.state.AdoptWithMetadata(conv.ConversationID, derefStr(conv.Slug), conv.CreatedAt, conv.UpdatedAt, derefStr(conv.Model), derefStr(conv.Cwd))
+			adopt = append(adopt, adoptRequest(conv))
 		}
 	}
 
+	// Adoption also records new API timestamps of tracked conversations.
+	// Errors are non-fatal; worst case a conversation won't appear in this
+	// listing but will be adopted on next Lookup.
+	c.adoptBatched(op, adopt)
+
 	// Note: if fetchServerConversations fails, we still return local entries.
 	// This is intentional - local state should always be accessible.
 	// If fetchArchivedConversations fails, archived conversations may be
 	// filtered as stale, but they remain accessible via direct Lookup.
 
-	// Build entries: directories for local IDs, symlinks for server IDs and slugs
 	mappings := c.state.ListMappings()
 
 	// Filter mappings and handle cleanup:
 	// - Only include created conversations in listing (uncreated ones are still accessible via Lookup)
 	// - Clean up expired uncreated conversations (lazy cleanup)
 	// - Filter out stale mappings with Shelley IDs that no longer exist on server
-	var filteredMappings []state.ConversationState
 	for _, cs := range mappings {
 		if !cs.Created {
 			// Uncreated conversation - check if it should be cleaned up
-			if c.cloneTimeout > 0 && !cs.CreatedAt.IsZero() && time.Since(cs.CreatedAt) > c.cloneTimeout {
+			if c.cloneExpired(cs) {
 				// Expired - delete it (errors are non-fatal, will retry next Readdir)
 				_ = c.state.Delete(cs.LocalID)
+			} else {
+				pending = true
 			}
 			// Either way, don't include uncreated conversations in listing
 			continue
@@ -240,33 +359,76 @@ func (c *ConversationListNode) Readdir(ctx context.Context) (fs.DirStream, sysca
 
 		if cs.ShelleyConversationID == "" {
 			// Created but no server ID - shouldn't happen, but include it
-			filteredMappings = append(filteredMappings, cs)
+			listed = append(listed, cs)
 		} else if !serverFetchSucceeded {
 			// Server fetch failed, include all to avoid data loss
-			filteredMappings = append(filteredMappings, cs)
+			listed = append(listed, cs)
 		} else if validServerIDs[cs.ShelleyConversationID] && !archivedServerIDs[cs.ShelleyConversationID] {
 			// Has server ID, still exists on server, and is not archived
-			filteredMappings = append(filteredMappings, cs)
+			listed = append(listed, cs)
 		}
 		// Otherwise: has a Shelley ID that's not on server anymore - skip (stale)
 	}
 
+	return listed, pending
+}
+
+func (c *ConversationListNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	op := diag.Track(c.diag, "ConversationListNode", "Readdir", "")
+	defer op.Done()
+	filteredMappings, pending := c.listedConversations(op)
+
+	// Build entries: directories for local IDs, symlinks for server IDs and slugs
 	// Track names we've used to avoid duplicates
 	usedNames := make(map[string]bool)
 	var entries []fuse.DirEntry
 
 	// Add the "last" virtual directory
-	entries = append(entries, fuse.DirEntry{Name: "last", Mode: fuse.S_IFDIR})
+	entries = append(entries, conversationListNames.entry("last"))
 	usedNames["last"] = true
-
-	// First add all local IDs as directories (they take priority)
+	entries = append(entries, conversationListNames.entry("import"))
+	usedNames["import"] = true
+	entries = append(entries, conversationListNames.entry("attach"))
+	usedNames["attach"] = true
+	entries = append(entries, conversationListNames.entry("index.json"))
+	usedNames["index.json"] = true
+	entries = append(entries, conversationListNames.entry("top"))
+	usedNames["top"] = true
+	entries = append(entries, conversationListNames.entry(".bulk"))
+	usedNames[".bulk"] = true
+	if c.titleLinks() {
+		entries = append(entries, conversationListNames.entry("by-title"))
+		usedNames["by-title"] = true
+	}
+	if c.events != nil {
+		entries = append(entries, conversationListNames.entry(".events"))
+		usedNames[".events"] = true
+	}
+	if c.activity != nil {
+		entries = append(entries, conversationListNames.entry(".activity.json"))
+		usedNames[".activity.json"] = true
+	}
+	if pending {
+		entries = append(entries, conversationListNames.entry(".pending"))
+		usedNames[".pending"] = true
+	}
+
+	// First add the conversation directories (they take priority): local
+	// IDs, or slugs with -layout=slugs
+	for i := range filteredMappings {
+		name := c.dirName(&filteredMappings[i])
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: fuse.S_IFDIR})
+		usedNames[name] = true
+	}
+
+	// Then add symlinks for local IDs (when not the directory), server IDs
+	// and slugs (if they don't conflict)
 	for _, cs := range filteredMappings {
-		entries = append(entries, fuse.DirEntry{Name: cs.LocalID, Mode: fuse.S_IFDIR})
-		usedNames[cs.LocalID] = true
-	}
+		if !usedNames[cs.LocalID] {
+			entries = append(entries, fuse.DirEntry{Name: cs.LocalID, Mode: syscall.S_IFLNK})
+			usedNames[cs.LocalID] = true
+		}
 
-	// Then add symlinks for server IDs and slugs (if they don't conflict)
-	for _, cs := range filteredMappings {
 		// Add symlink for server ID if it exists and doesn't conflict
 		if cs.ShelleyConversationID != "" && !usedNames[cs.ShelleyConversationID] {
 			entries = append(entries, fuse.DirEntry{Name: cs.ShelleyConversationID, Mode: syscall.S_IFLNK})
@@ -308,10 +470,10 @@ func (c *ConversationListNode) fetchServerConversations() ([]shelley.Conversatio
 	}
 
 	var convs []shelley.Conversation
-	if err := json.Unmarshal(data, &convs); err != nil {
+	if err := shelley.UnmarshalLenient(data, &convs); err != nil {
 		return nil, err
 	}
-	return convs, nil
+	return c.filter.apply(convs), nil
 }
 
 // fetchArchivedConversations retrieves the list of archived conversations from the Shelley server.
@@ -322,43 +484,137 @@ func (c *ConversationListNode) fetchArchivedConversations() ([]shelley.Conversat
 	}
 
 	var convs []shelley.Conversation
-	if err := json.Unmarshal(data, &convs); err != nil {
+	if err := shelley.UnmarshalLenient(data, &convs); err != nil {
 		return nil, err
 	}
-	return convs, nil
+	return c.filter.apply(convs), nil
 }
 
+// Getattr reports the directory as modified when a conversation in it last
+// was (see state.Store.LastActivity), so `ls -lt` of its parent and find
+// -newer notice new messages.
 func (c *ConversationListNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, c.startTime)
-	out.SetTimeout(cacheTTLConversation)
+	if last := c.state.LastActivity(); last.After(c.startTime) {
+		out.Mtime = uint64(last.Unix())
+		out.Mtimensec = uint32(last.Nanosecond())
+	}
+	out.SetTimeout(cacheTimeouts(&c.Inode).Conversation)
 	return 0
 }
 
+// Mkdir handles `mkdir conversation/{slug}`: it allocates a new conversation
+// as reading clone does, with its slug preset to name. The slug is sent to
+// the backend with the first message. The new directory is returned under
+// name even with -layout=ids, where later lookups find a symlink to the
+// local ID instead. Like a clone, it is discarded if no message arrives
+// within the clone timeout.
+func (c *ConversationListNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(c.diag, "ConversationListNode", "Mkdir", name).Done()
+	setEntryTimeout(out, cacheTimeouts(&c.Inode).Conversation)
+
+	if reservedListName(name) || c.state.Get(name) != nil || c.state.GetByShelleyID(name) != "" || c.state.GetBySlug(name) != "" {
+		return nil, syscall.EEXIST
+	}
+	id, err := c.state.Clone()
+	if errors.Is(err, state.ErrTooManyClones) {
+		log.Printf("Mkdir refused: %v (rm conversation/.pending/{id} to discard some)", err)
+		return nil, syscall.EDQUOT
+	}
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	if err := c.state.SetCtl(id, "slug", name); err != nil {
+		_ = c.state.Delete(id)
+		return nil, syscall.EIO
+	}
+	child := c.conversationDir(ctx, id)
+	fillEntryAttr(ctx, child, out, cacheTimeouts(&c.Inode).Conversation)
+	return child, 0
+}
+
 // Rmdir handles `rmdir conversation/{id}` to permanently delete a conversation.
-// Only works on local IDs (not server IDs or slugs, which are symlinks).
+// Only works on conversation directories: local IDs, or slugs with
+// -layout=slugs (the other names are symlinks).
 func (c *ConversationListNode) Rmdir(ctx context.Context, name string) syscall.Errno {
 	defer diag.Track(c.diag, "ConversationListNode", "Rmdir", name).Done()
 
+	cs := c.state.Get(name)
+	if cs == nil && c.layout == LayoutSlugs {
+		if localID := c.state.GetBySlug(name); localID != "" {
+			cs = c.state.Get(localID)
+		}
+	}
+	if cs == nil || c.dirName(cs) != name {
+		return syscall.ENOENT
+	}
+	if err := c.deleteConversation(cs); err != nil {
+		if errors.Is(err, shelley.ErrDeleteUnsupported) {
+			return syscall.EROFS
+		}
+		return backendErrno(err)
+	}
+	return 0
+}
+
+// Unlink handles `rm conversation/{name}` for the symlinks to conversation
+// directories (slugs, server IDs, and local IDs with -layout=slugs): it
+// deletes the conversation the link points to, as rmdir of the directory
+// does. The fixed entries can't be removed.
+func (c *ConversationListNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	defer diag.Track(c.diag, "ConversationListNode", "Unlink", name).Done()
+
+	if reservedListName(name) {
+		return syscall.EPERM
+	}
 	cs := c.state.Get(name)
+	if cs == nil {
+		if localID := c.state.GetByShelleyID(name); localID != "" {
+			cs = c.state.Get(localID)
+		} else if localID := c.state.GetBySlug(name); localID != "" {
+			cs = c.state.Get(localID)
+		}
+	}
 	if cs == nil {
 		return syscall.ENOENT
 	}
+	if c.dirName(cs) == name {
+		return syscall.EISDIR
+	}
+	if err := c.deleteConversation(cs); err != nil {
+		if errors.Is(err, shelley.ErrDeleteUnsupported) {
+			return syscall.EROFS
+		}
+		return backendErrno(err)
+	}
+	return 0
+}
+
+// deleteConversation deletes cs on the backend and forgets it locally.
+func (c *ConversationListNode) deleteConversation(cs *state.ConversationState) error {
+	name := cs.LocalID
 
 	if !cs.Created || cs.ShelleyConversationID == "" {
 		// Not yet created on the backend — just clean up local state
 		_ = c.state.ForceDelete(name)
-		return 0
+		return nil
 	}
 
 	// Delete from the server
+	if !featureEnabled(c.client, c.state, shelley.FeatureDelete) {
+		return shelley.ErrDeleteUnsupported
+	}
 	if err := c.client.DeleteConversation(cs.ShelleyConversationID); err != nil {
-		log.Printf("DeleteConversation failed for %s (%s): %v", name, cs.ShelleyConversationID, err)
-		return syscall.EIO
+		if !errors.Is(err, shelley.ErrDeleteUnsupported) {
+			log.Printf("DeleteConversation failed for %s (%s): %v", name, cs.ShelleyConversationID, err)
+		}
+		return err
 	}
 
 	// Invalidate the parsed message cache
 	c.parsedCache.Invalidate(cs.ShelleyConversationID)
+	c.diag.ForgetErrors(name)
 
 	// Remove from local state
 	if err := c.state.ForceDelete(name); err != nil {
@@ -366,7 +622,7 @@ func (c *ConversationListNode) Rmdir(ctx context.Context, name string) syscall.E
 		// Server delete succeeded, so don't return error — state will be cleaned up on next Readdir
 	}
 
-	return 0
+	return nil
 }
 
 // --- ConversationNode: /conversation/{id}/ directory ---
@@ -377,7 +633,13 @@ type ConversationNode struct {
 	client      shelley.ShelleyClient
 	state       *state.Store
 	startTime   time.Time // FS start time, used as fallback
+	mdChunkSize int       // split all.md into all.md.d/ above this size (0 = never)
+	sparseMsgs  bool      // leave message directories out of messages/ listings
+	maxSend     int       // largest message send accepts (0 = no limit)
+	budget      Budget    // caps usage on top of the conversation's own ctl budget
 	parsedCache *ParsedMessageCache
+	sends       *RecentSends
+	snapshots   *Snapshots
 	diag        *diag.Tracker
 }
 
@@ -457,6 +719,16 @@ func (c *ConversationNode) buildConversationJSONMap() map[string]any {
 				if conv.UpdatedAt != "" {
 					result["updated_at"] = conv.UpdatedAt
 				}
+				// Epoch-second companions so scripts can compare ages with test/expr
+				if secs, ok := unixSeconds(conv.CreatedAt); ok {
+					result["created_at_unix"] = secs
+				}
+				if secs, ok := unixSeconds(conv.UpdatedAt); ok {
+					result["updated_at_unix"] = secs
+				}
+				for name, value := range conversationFieldValues(convData) {
+					result[name] = value
+				}
 			}
 		}
 	}
@@ -466,17 +738,38 @@ func (c *ConversationNode) buildConversationJSONMap() map[string]any {
 
 func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(c.diag, "ConversationNode", "Lookup", c.localID+"/"+name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&c.Inode).Conversation)
 	// Special files with custom behavior
 	switch name {
 	case "ctl":
-		return c.NewInode(ctx, &CtlNode{localID: c.localID, client: c.client, state: c.state, startTime: c.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return c.NewInode(ctx, &CtlNode{localID: c.localID, client: c.client, state: c.state, startTime: c.startTime}, fs.StableAttr{Mode: conversationNames.mode("ctl")}), 0
 	case "send":
-		return c.NewInode(ctx, &ConvSendNode{localID: c.localID, client: c.client, state: c.state, startTime: c.startTime, parsedCache: c.parsedCache, diag: c.diag}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return c.NewInode(ctx, &ConvSendNode{localID: c.localID, client: c.client, state: c.state, maxSend: c.maxSend, budget: c.budget, startTime: c.startTime, parsedCache: c.parsedCache, sends: c.sends, diag: c.diag}, fs.StableAttr{Mode: conversationNames.mode("send")}), 0
 	case "messages":
-		return c.NewInode(ctx, &MessagesDirNode{localID: c.localID, client: c.client, state: c.state, startTime: c.startTime, parsedCache: c.parsedCache, diag: c.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		return c.NewInode(ctx, &MessagesDirNode{localID: c.localID, client: c.client, state: c.state, startTime: c.startTime, mdChunkSize: c.mdChunkSize, sparseMsgs: c.sparseMsgs, parsedCache: c.parsedCache, diag: c.diag}, fs.StableAttr{Mode: conversationNames.mode("messages")}), 0
+	case "meta":
+		return c.NewInode(ctx, &MetaDirNode{localID: c.localID, state: c.state, startTime: c.startTime, diag: c.diag}, fs.StableAttr{Mode: conversationNames.mode("meta")}), 0
+	case "bookmark":
+		return c.NewInode(ctx, &BookmarkNode{localID: c.localID, state: c.state, startTime: c.startTime, diag: c.diag}, fs.StableAttr{Mode: conversationNames.mode("bookmark")}), 0
 	case "fuse_id":
-		return c.NewInode(ctx, &ConvStatusFieldNode{localID: c.localID, client: c.client, state: c.state, field: "fuse_id", startTime: c.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return c.NewInode(ctx, &ConvStatusFieldNode{localID: c.localID, client: c.client, state: c.state, field: "fuse_id", startTime: c.startTime}, fs.StableAttr{Mode: conversationNames.mode("fuse_id")}), 0
+	case "web":
+		// Presence/absence semantics: a conversation has a web page once it
+		// is created on a backend whose address the client knows.
+		cs := c.state.Get(c.localID)
+		if _, ok := webLinker(c.client); !ok || cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+			out.SetEntryTimeout(negTimeout)
+			return nil, syscall.ENOENT
+		}
+		out.SetEntryTimeout(immutableEntryTimeout)
+		return c.NewInode(ctx, &ConvStatusFieldNode{localID: c.localID, client: c.client, state: c.state, field: "web", startTime: c.startTime}, fs.StableAttr{Mode: conversationNames.mode("web")}), 0
+	case ".trace":
+		if !c.diag.TraceEnabled() {
+			return nil, syscall.ENOENT
+		}
+		return c.NewInode(ctx, &DiagLogNode{localID: c.localID, state: c.state, startTime: c.startTime, read: c.diag.Trace}, fs.StableAttr{Mode: conversationNames.mode(".trace")}), 0
+	case "errors.log":
+		return c.NewInode(ctx, &DiagLogNode{localID: c.localID, state: c.state, startTime: c.startTime, read: c.diag.ErrorLog}, fs.StableAttr{Mode: conversationNames.mode("errors.log")}), 0
 	case "created":
 		// Presence/absence semantics: file exists only when conversation is created on backend.
 		// Once created, it never disappears → long positive timeout.
@@ -487,18 +780,18 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 			return nil, syscall.ENOENT
 		}
 		out.SetEntryTimeout(immutableEntryTimeout)
-		return c.NewInode(ctx, &ConvCreatedNode{localID: c.localID, state: c.state, startTime: c.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return c.NewInode(ctx, &ConvCreatedNode{localID: c.localID, state: c.state, startTime: c.startTime}, fs.StableAttr{Mode: conversationNames.mode("created")}), 0
 	case "model":
-		// Set once via ctl, never changes after → long positive timeout.
-		// Before set, short negative timeout so we notice the ctl write.
+		// Set via ctl, and changed after creation by a live model switch
+		// (see settings.go) → short timeouts both ways.
 		cs := c.state.Get(c.localID)
 		if cs == nil || cs.Model == "" {
 			out.SetEntryTimeout(negTimeout)
 			return nil, syscall.ENOENT
 		}
-		out.SetEntryTimeout(immutableEntryTimeout)
+		out.SetEntryTimeout(volatileEntryTimeout)
 		target := "../../model/" + cs.Model
-		return c.NewInode(ctx, &SymlinkNode{target: target, startTime: c.getConversationTime()}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		return c.NewInode(ctx, &SymlinkNode{target: target, startTime: c.getConversationTime()}, fs.StableAttr{Mode: conversationNames.mode("model")}), 0
 	case "cwd":
 		// Set once via ctl, never changes after → long positive timeout.
 		// Before set, short negative timeout so we notice the ctl write.
@@ -512,7 +805,7 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 			localID:   c.localID,
 			state:     c.state,
 			startTime: c.startTime,
-		}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		}, fs.StableAttr{Mode: conversationNames.mode("cwd")}), 0
 	case "archived":
 		// Presence/absence semantics: file exists only when conversation is archived.
 		// Can appear and disappear (archive/unarchive) → short timeouts both ways.
@@ -532,7 +825,7 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 			client:    c.client,
 			state:     c.state,
 			startTime: c.startTime,
-		}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		}, fs.StableAttr{Mode: conversationNames.mode("archived")}), 0
 	case "continue":
 		cs := c.state.Get(c.localID)
 		if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
@@ -545,7 +838,54 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 			state:     c.state,
 			startTime: c.startTime,
 			diag:      c.diag,
-		}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		}, fs.StableAttr{Mode: conversationNames.mode("continue")}), 0
+	case "title":
+		cs := c.state.Get(c.localID)
+		if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+			out.SetEntryTimeout(negTimeout)
+			return nil, syscall.ENOENT
+		}
+		return c.NewInode(ctx, &ConvTitleNode{
+			localID:     c.localID,
+			client:      c.client,
+			state:       c.state,
+			startTime:   c.startTime,
+			parsedCache: c.parsedCache,
+			diag:        c.diag,
+		}, fs.StableAttr{Mode: conversationNames.mode("title")}), 0
+	case "snapshots":
+		cs := c.state.Get(c.localID)
+		if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+			out.SetEntryTimeout(negTimeout)
+			return nil, syscall.ENOENT
+		}
+		return c.NewInode(ctx, &SnapshotsDirNode{
+			localID:   c.localID,
+			client:    c.client,
+			state:     c.state,
+			snapshots: c.snapshots,
+			startTime: c.startTime,
+			diag:      c.diag,
+		}, fs.StableAttr{Mode: conversationNames.mode("snapshots")}), 0
+	case "export.tar", "export.zip":
+		cs := c.state.Get(c.localID)
+		if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+			out.SetEntryTimeout(negTimeout)
+			return nil, syscall.ENOENT
+		}
+		format := archiveTar
+		if name == "export.zip" {
+			format = archiveZip
+		}
+		return c.NewInode(ctx, &ConvArchiveNode{
+			localID:     c.localID,
+			client:      c.client,
+			state:       c.state,
+			format:      format,
+			startTime:   c.startTime,
+			parsedCache: c.parsedCache,
+			diag:        c.diag,
+		}, fs.StableAttr{Mode: conversationNames.mode(name)}), 0
 	case "subagents":
 		cs := c.state.Get(c.localID)
 		if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
@@ -558,7 +898,7 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 			state:     c.state,
 			startTime: c.startTime,
 			diag:      c.diag,
-		}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		}, fs.StableAttr{Mode: conversationNames.mode("subagents")}), 0
 	case "working":
 		// Presence/absence semantics: file exists only when agent is working.
 		// Can appear and disappear rapidly → short timeouts both ways.
@@ -574,7 +914,7 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 			return nil, syscall.ENOENT
 		}
 		out.SetEntryTimeout(volatileEntryTimeout)
-		return c.NewInode(ctx, &WorkingNode{startTime: c.getConversationTime()}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return c.NewInode(ctx, &WorkingNode{startTime: c.getConversationTime()}, fs.StableAttr{Mode: conversationNames.mode("working")}), 0
 	case "cancel":
 		// Presence/absence semantics: file exists only when agent is working.
 		// Writing anything to it cancels the in-progress agent loop.
@@ -596,7 +936,7 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 			state:     c.state,
 			startTime: c.getConversationTime(),
 			diag:      c.diag,
-		}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		}, fs.StableAttr{Mode: conversationNames.mode("cancel")}), 0
 	}
 
 	// For all other fields, use jsonfs to expose conversation JSON data
@@ -616,45 +956,39 @@ func (c *ConversationNode) Lookup(ctx context.Context, name string, out *fuse.En
 	}
 	node := jsonfs.NewNode(value, config)
 
-	// Determine mode based on value type
-	mode := uint32(fuse.S_IFREG)
-	switch value.(type) {
-	case map[string]any, []any:
-		mode = fuse.S_IFDIR
-	}
-
-	return c.NewInode(ctx, node, fs.StableAttr{Mode: mode}), 0
+	return c.NewInode(ctx, node, fs.StableAttr{Mode: jsonMode(value)}), 0
 }
 
 func (c *ConversationNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 	defer diag.Track(c.diag, "ConversationNode", "Readdir", c.localID).Done()
 	// Special files always present
-	entries := []fuse.DirEntry{
-		{Name: "ctl", Mode: fuse.S_IFREG},
-		{Name: "send", Mode: fuse.S_IFREG},
-		{Name: "messages", Mode: fuse.S_IFDIR},
-		{Name: "fuse_id", Mode: fuse.S_IFREG},
+	entries := conversationNames.entries("ctl", "send", "messages", "meta", "bookmark", "fuse_id", "errors.log")
+	if c.diag.TraceEnabled() {
+		entries = append(entries, conversationNames.entry(".trace"))
 	}
 
 	cs := c.state.Get(c.localID)
 	// Presence/absence semantics: only include "created" if conversation is created on backend
 	if cs != nil && cs.Created {
-		entries = append(entries, fuse.DirEntry{Name: "created", Mode: fuse.S_IFREG})
+		entries = append(entries, conversationNames.entry("created"))
+	}
+	if _, ok := webLinker(c.client); ok && cs != nil && cs.Created && cs.ShelleyConversationID != "" {
+		entries = append(entries, conversationNames.entry("web"))
 	}
 
 	// Include model and cwd symlinks only if set
 	if cs != nil && cs.Model != "" {
-		entries = append(entries, fuse.DirEntry{Name: "model", Mode: syscall.S_IFLNK})
+		entries = append(entries, conversationNames.entry("model"))
 	}
 	if cs != nil && cs.Cwd != "" {
-		entries = append(entries, fuse.DirEntry{Name: "cwd", Mode: syscall.S_IFLNK})
+		entries = append(entries, conversationNames.entry("cwd"))
 	}
 
 	// Include archived file only if the conversation is archived
 	if cs != nil && cs.Created && cs.ShelleyConversationID != "" {
 		archived, err := c.client.IsConversationArchived(cs.ShelleyConversationID)
 		if err == nil && archived {
-			entries = append(entries, fuse.DirEntry{Name: "archived", Mode: fuse.S_IFREG})
+			entries = append(entries, conversationNames.entry("archived"))
 		}
 	}
 
@@ -662,22 +996,26 @@ func (c *ConversationNode) Readdir(ctx context.Context) (fs.DirStream, syscall.E
 	if cs != nil && cs.Created && cs.ShelleyConversationID != "" {
 		working, err := c.client.IsConversationWorking(cs.ShelleyConversationID)
 		if err == nil && working {
-			entries = append(entries, fuse.DirEntry{Name: "working", Mode: fuse.S_IFREG})
-			entries = append(entries, fuse.DirEntry{Name: "cancel", Mode: fuse.S_IFREG})
+			entries = append(entries, conversationNames.entry("working"))
+			entries = append(entries, conversationNames.entry("cancel"))
 		}
 	}
 
-	// Include subagents directory and continue file for created conversations
+	// Include subagents and snapshots directories, continue, title and export files for created conversations
 	if cs != nil && cs.Created && cs.ShelleyConversationID != "" {
-		entries = append(entries, fuse.DirEntry{Name: "continue", Mode: fuse.S_IFREG})
-		entries = append(entries, fuse.DirEntry{Name: "subagents", Mode: fuse.S_IFDIR})
+		entries = append(entries, conversationNames.entry("continue"))
+		entries = append(entries, conversationNames.entry("subagents"))
+		entries = append(entries, conversationNames.entry("title"))
+		entries = append(entries, conversationNames.entry("export.tar"))
+		entries = append(entries, conversationNames.entry("export.zip"))
+		entries = append(entries, conversationNames.entry("snapshots"))
 	}
 
 	// Add JSON fields from conversation data via jsonfs
 	convMap := c.buildConversationJSONMap()
 	if convMap != nil {
-		for name := range convMap {
-			entries = append(entries, fuse.DirEntry{Name: name, Mode: fuse.S_IFREG})
+		for name, value := range convMap {
+			entries = append(entries, fuse.DirEntry{Name: name, Mode: jsonMode(value)})
 		}
 	}
 
@@ -687,7 +1025,7 @@ func (c *ConversationNode) Readdir(ctx context.Context) (fs.DirStream, syscall.E
 func (c *ConversationNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	c.getConversationTimestamps().ApplyWithFallback(&out.Attr, c.startTime)
-	out.SetTimeout(cacheTTLConversation)
+	out.SetTimeout(cacheTimeouts(&c.Inode).Conversation)
 	return 0
 }
 
@@ -705,9 +1043,20 @@ func (c *ConversationNode) Create(ctx context.Context, name string, flags uint32
 		return nil, nil, 0, syscall.ENOENT
 	}
 
+	if flags&syscall.O_EXCL != 0 {
+		// The kernel's lookup may predate an archive by someone else
+		archived, err := c.client.IsConversationArchived(cs.ShelleyConversationID)
+		if err != nil {
+			return nil, nil, 0, backendErrno(err)
+		}
+		if archived {
+			return nil, nil, 0, syscall.EEXIST
+		}
+	}
+
 	// Archive the conversation
 	if err := c.client.ArchiveConversation(cs.ShelleyConversationID); err != nil {
-		return nil, nil, 0, syscall.EIO
+		return nil, nil, 0, backendErrno(err)
 	}
 
 	// Return the archived file node
@@ -737,7 +1086,7 @@ func (c *ConversationNode) Unlink(ctx context.Context, name string) syscall.Errn
 	// Check if the conversation is actually archived
 	archived, err := c.client.IsConversationArchived(cs.ShelleyConversationID)
 	if err != nil {
-		return syscall.EIO
+		return backendErrno(err)
 	}
 	if !archived {
 		return syscall.ENOENT
@@ -745,13 +1094,14 @@ func (c *ConversationNode) Unlink(ctx context.Context, name string) syscall.Errn
 
 	// Unarchive the conversation
 	if err := c.client.UnarchiveConversation(cs.ShelleyConversationID); err != nil {
-		return syscall.EIO
+		return backendErrno(err)
 	}
 
 	return 0
 }
 
 // --- CtlNode: write key=value pairs, read-only after conversation created ---
+// (except the keys the backend can change live, see settings.go)
 
 type CtlNode struct {
 	fs.Inode
@@ -776,6 +1126,11 @@ func (c *CtlNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off in
 	if cs == nil {
 		return nil, syscall.ENOENT
 	}
+	return fuse.ReadResultData(readAt(ctlData(cs), dest, off)), 0
+}
+
+// ctlData renders ctl: the settings that are set, as key=value pairs.
+func ctlData(cs *state.ConversationState) []byte {
 	var parts []string
 	if cs.Model != "" {
 		parts = append(parts, "model="+cs.Model)
@@ -783,8 +1138,25 @@ func (c *CtlNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off in
 	if cs.Cwd != "" {
 		parts = append(parts, "cwd="+cs.Cwd)
 	}
-	data := []byte(strings.Join(parts, " ") + "\n")
-	return fuse.ReadResultData(readAt(data, dest, off)), 0
+	if cs.Temperature != "" {
+		parts = append(parts, "temperature="+cs.Temperature)
+	}
+	if !cs.Created && cs.Slug != "" {
+		parts = append(parts, "slug="+cs.Slug)
+	}
+	if cs.ReadOnly {
+		parts = append(parts, "readonly=true")
+	}
+	if cs.NoSendDedup {
+		parts = append(parts, "dedup=false")
+	}
+	if cs.BudgetTokens > 0 {
+		parts = append(parts, "budget_tokens="+strconv.FormatInt(cs.BudgetTokens, 10))
+	}
+	if cs.BudgetUSD > 0 {
+		parts = append(parts, "budget_usd="+strconv.FormatFloat(cs.BudgetUSD, 'f', -1, 64))
+	}
+	return []byte(strings.Join(parts, " ") + "\n")
 }
 
 func (c *CtlNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
@@ -792,14 +1164,29 @@ func (c *CtlNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off i
 	if cs == nil {
 		return 0, syscall.ENOENT
 	}
-	if cs.Created {
-		return 0, syscall.EROFS
-	}
-
 	content := strings.TrimSpace(string(data))
 	if content == "" {
 		return uint32(len(data)), 0
 	}
+	if content == "cancel" {
+		if errno := c.cancel(cs); errno != 0 {
+			return 0, errno
+		}
+		return uint32(len(data)), 0
+	}
+	content, errno := c.writeLocal(cs, content)
+	if errno != 0 {
+		return 0, errno
+	}
+	if content == "" {
+		return uint32(len(data)), 0
+	}
+	if cs.Created {
+		if errno := c.writeLive(cs, content); errno != 0 {
+			return 0, errno
+		}
+		return uint32(len(data)), 0
+	}
 
 	words := strings.Fields(content)
 	for _, word := range words {
@@ -814,7 +1201,7 @@ func (c *CtlNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off i
 			result, err := c.client.ListModels()
 			if err != nil {
 				log.Printf("CtlNode.Write: ListModels failed: %v", err)
-				return 0, syscall.EIO
+				return 0, backendErrno(err)
 			}
 			model := result.FindByName(v)
 			if model == nil {
@@ -824,6 +1211,14 @@ func (c *CtlNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off i
 				return 0, syscall.EINVAL
 			}
 		} else {
+			if k == "temperature" {
+				if _, err := strconv.ParseFloat(v, 64); err != nil {
+					return 0, syscall.EINVAL
+				}
+			}
+			if k == "slug" && (!isValidFilename(v) || reservedListName(v)) {
+				return 0, syscall.EINVAL
+			}
 			if err := c.state.SetCtl(c.localID, k, v); err != nil {
 				return 0, syscall.EINVAL
 			}
@@ -832,16 +1227,101 @@ func (c *CtlNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off i
 	return uint32(len(data)), 0
 }
 
+// cancel discards a clone that never got its first message, as rmdir of
+// its directory does. A created conversation can't be cancelled: EINVAL.
+func (c *CtlNode) cancel(cs *state.ConversationState) syscall.Errno {
+	if cs.Created {
+		return syscall.EINVAL
+	}
+	if cs.ReadOnly {
+		return syscall.EROFS
+	}
+	if err := c.state.Delete(c.localID); err != nil {
+		log.Printf("CtlNode.Write: cancel of %s failed: %v", c.localID, err)
+		return syscall.EIO
+	}
+	log.Printf("Cancelled clone %s", c.localID)
+	return 0
+}
+
+// writeLocal applies the settings in content that only this filesystem
+// acts on, readonly=, dedup=, budget_tokens= and budget_usd=, and returns
+// the rest of it. They can be changed at any time, created or not. While
+// readonly is set every other key is EROFS, unless the same write clears it.
+func (c *CtlNode) writeLocal(cs *state.ConversationState, content string) (string, syscall.Errno) {
+	var rest []string
+	readOnly, dedup := cs.ReadOnly, !cs.NoSendDedup
+	budgetTokens, budgetUSD := cs.BudgetTokens, cs.BudgetUSD
+	for _, word := range strings.Fields(content) {
+		k, v, ok := strings.Cut(word, "=")
+		if !ok {
+			rest = append(rest, word)
+			continue
+		}
+		switch k {
+		case "readonly", "dedup":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return "", syscall.EINVAL
+			}
+			if k == "readonly" {
+				readOnly = b
+			} else {
+				dedup = b
+			}
+		case "budget_tokens":
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				return "", syscall.EINVAL
+			}
+			budgetTokens = n
+		case "budget_usd":
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 || math.IsInf(f, 0) || math.IsNaN(f) {
+				return "", syscall.EINVAL
+			}
+			budgetUSD = f
+		default:
+			rest = append(rest, word)
+		}
+	}
+	dedupChanged := dedup == cs.NoSendDedup
+	budgetChanged := budgetTokens != cs.BudgetTokens || budgetUSD != cs.BudgetUSD
+	if readOnly && (len(rest) > 0 || dedupChanged || budgetChanged) {
+		return "", syscall.EROFS
+	}
+	if readOnly != cs.ReadOnly {
+		if err := c.state.SetReadOnly(c.localID, readOnly); err != nil {
+			log.Printf("CtlNode.Write: SetReadOnly failed: %v", err)
+			return "", syscall.EIO
+		}
+	}
+	if dedupChanged {
+		if err := c.state.SetSendDedup(c.localID, dedup); err != nil {
+			log.Printf("CtlNode.Write: SetSendDedup failed: %v", err)
+			return "", syscall.EIO
+		}
+	}
+	if budgetChanged {
+		if err := c.state.SetBudget(c.localID, budgetTokens, budgetUSD); err != nil {
+			log.Printf("CtlNode.Write: SetBudget failed: %v", err)
+			return "", syscall.EIO
+		}
+	}
+	return strings.Join(rest, " "), 0
+}
+
 func (c *CtlNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	cs := c.state.Get(c.localID)
 	if cs == nil {
 		return syscall.ENOENT
 	}
-	if cs.Created {
+	if cs.Created && !c.live() {
 		out.Mode = fuse.S_IFREG | 0444
 	} else {
 		out.Mode = fuse.S_IFREG | 0644
 	}
+	out.Size = uint64(len(ctlData(cs)))
 	// Use conversation creation time if available, otherwise fall back to FS start time
 	if !cs.CreatedAt.IsZero() {
 		setTimestamps(&out.Attr, cs.CreatedAt)
@@ -851,8 +1331,10 @@ func (c *CtlNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOu
 	return 0
 }
 
+// Setattr accepts truncation (shell > redirects, editors that rewrite in
+// place) and ignores it: every write to ctl is applied as it arrives, so
+// there is no buffered content to cut.
 func (c *CtlNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
-	// Accept truncate (from shell > redirect) silently
 	return c.Getattr(ctx, f, out)
 }
 
@@ -863,8 +1345,11 @@ type ConvSendNode struct {
 	localID     string
 	client      shelley.ShelleyClient
 	state       *state.Store
+	maxSend     int       // largest message accepted (0 = no limit)
+	budget      Budget    // caps usage on top of the conversation's own ctl budget
 	startTime   time.Time // fallback if conversation has no CreatedAt
 	parsedCache *ParsedMessageCache
+	sends       *RecentSends
 	diag        *diag.Tracker
 }
 
@@ -873,6 +1358,9 @@ var _ = (fs.NodeGetattrer)((*ConvSendNode)(nil))
 var _ = (fs.NodeSetattrer)((*ConvSendNode)(nil))
 
 func (n *ConvSendNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if cs := n.state.Get(n.localID); cs != nil && cs.ReadOnly {
+		return nil, 0, syscall.EROFS
+	}
 	return &ConvSendFileHandle{
 		node: n,
 	}, fuse.FOPEN_DIRECT_IO, 0
@@ -883,21 +1371,65 @@ type ConvSendFileHandle struct {
 	node    *ConvSendNode
 	buffer  []byte
 	flushed bool
-	mu      sync.Mutex
+	tooBig  bool // a write went over maxSend; nothing will be sent
+	// overBudget: the conversation had used up its budget when the
+	// message was started; nothing will be sent
+	overBudget bool
+	mu         sync.Mutex
 }
 
 var _ = (fs.FileWriter)((*ConvSendFileHandle)(nil))
 var _ = (fs.FileFlusher)((*ConvSendFileHandle)(nil))
+var _ = (fs.FileFsyncer)((*ConvSendFileHandle)(nil))
 
 func (h *ConvSendFileHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
+	// A message over the limit fails here, before the backend sees it,
+	// rather than as an opaque EIO on close. The part already written is
+	// dropped too: sending the first megabyte of it would be worse.
+	if max := h.node.maxSend; max > 0 && len(h.buffer)+len(data) > max {
+		if !h.tooBig {
+			h.node.diag.RecordError(h.node.localID, "send: message larger than %d bytes rejected", max)
+		}
+		h.tooBig = true
+		h.buffer = nil
+		return 0, syscall.EFBIG
+	}
+
+	// The budget is checked as a message starts, so a runaway writer is
+	// stopped at its first write rather than on close.
+	if len(h.buffer) == 0 && !h.overBudget {
+		if reason := h.node.overBudget(); reason != "" {
+			log.Printf("Send to %s refused: %s", h.node.localID, reason)
+			h.node.diag.RecordError(h.node.localID, "send: %s", reason)
+			h.overBudget = true
+		}
+	}
+	if h.overBudget {
+		return 0, syscall.EDQUOT
+	}
+
 	// Append to buffer - message will be sent on Flush
 	h.buffer = append(h.buffer, data...)
 	return uint32(len(data)), 0
 }
 
+// truncate cuts the unsent message to size bytes. Growing it fails with
+// EFBIG and leaves the message alone: the zero bytes a regular file would
+// be padded with would only be sent, and unlike writes they would slip
+// past maxSend.
+func (h *ConvSendFileHandle) truncate(size int64) syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if size > int64(len(h.buffer)) {
+		return syscall.EFBIG
+	}
+	h.buffer = h.buffer[:size]
+	return 0
+}
+
 // Flush is called synchronously during close(2), so the caller will block until
 // the message is sent. This ensures the conversation is created before close returns.
 // Note: Flush may be called multiple times for dup'd file descriptors.
@@ -911,6 +1443,12 @@ func (h *ConvSendFileHandle) Flush(ctx context.Context) syscall.Errno {
 	if h.flushed {
 		return 0
 	}
+	if h.tooBig {
+		return syscall.EFBIG
+	}
+	if h.overBudget {
+		return syscall.EDQUOT
+	}
 
 	cs := h.node.state.Get(h.node.localID)
 	if cs == nil {
@@ -923,14 +1461,84 @@ func (h *ConvSendFileHandle) Flush(ctx context.Context) syscall.Errno {
 	}
 
 	h.flushed = true // Only set when we actually have data to send
+	return h.sendLocked(op, cs, message)
+}
+
+// Fsync sends what has been written so far and blocks until the backend has
+// accepted it, for writers that want delivery confirmed before they go on
+// (e.g. `dd conv=fsync`). The buffer starts over afterwards, so anything
+// written after the fsync goes out as a separate message on the next fsync
+// or on close. On failure the buffer is kept and close retries the send.
+func (h *ConvSendFileHandle) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	op := diag.Track(h.node.diag, "ConvSendFileHandle", "Fsync", h.node.localID)
+	defer op.Done()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.flushed {
+		return 0
+	}
+	if h.tooBig {
+		return syscall.EFBIG
+	}
+	if h.overBudget {
+		return syscall.EDQUOT
+	}
+	cs := h.node.state.Get(h.node.localID)
+	if cs == nil {
+		return syscall.ENOENT
+	}
+	message := strings.TrimRight(string(h.buffer), "\n")
+	if message == "" {
+		return 0
+	}
+	if errno := h.sendLocked(op, cs, message); errno != 0 {
+		return errno
+	}
+	h.buffer = nil
+	return 0
+}
+
+// sendLocked delivers message to the conversation, creating it on the
+// backend first if this is its first message. @@include@@ references are
+// expanded first (see include.go), and a repeat of the message just sent is
+// dropped (see dedup.go). h.mu must be held.
+func (h *ConvSendFileHandle) sendLocked(op *diag.OpHandle, cs *state.ConversationState, message string) syscall.Errno {
+	if cs.ReadOnly {
+		// Frozen after the file was opened.
+		return syscall.EROFS
+	}
+	if !cs.NoSendDedup {
+		if !h.node.sends.claim(h.node.localID, message, time.Now()) {
+			log.Printf("Send to %s: dropped a repeat of the message just sent", h.node.localID)
+			return 0
+		}
+		errno := h.deliverLocked(op, cs, message)
+		if errno != 0 {
+			h.node.sends.release(h.node.localID, message)
+		}
+		return errno
+	}
+	return h.deliverLocked(op, cs, message)
+}
+
+// deliverLocked is sendLocked without duplicate suppression.
+func (h *ConvSendFileHandle) deliverLocked(op *diag.OpHandle, cs *state.ConversationState, message string) syscall.Errno {
+	message, errno := h.expandIncludes(op, cs, message)
+	if errno != 0 {
+		return errno
+	}
 
 	if !cs.Created {
 		// First write: create the conversation on the Shelley backend
 		op.SetPhase("HTTP POST StartConversation")
-		result, err := h.node.client.StartConversation(message, cs.EffectiveModelID(), cs.Cwd)
+		result, err := h.node.client.StartConversation(message, cs.EffectiveModelID(), cs.Cwd, cs.Slug)
 		if err != nil {
 			log.Printf("StartConversation failed for %s: %v", h.node.localID, err)
-			return syscall.EIO
+			// The request URL carries no conversation ID yet, so the
+			// request observer can't attribute this failure itself.
+			h.node.diag.RecordError(h.node.localID, "StartConversation: %v", err)
+			return backendErrno(err)
 		}
 		op.SetPhase("MarkCreated")
 		if err := h.node.state.MarkCreated(h.node.localID, result.ConversationID, result.Slug); err != nil {
@@ -938,13 +1546,17 @@ func (h *ConvSendFileHandle) Flush(ctx context.Context) syscall.Errno {
 		}
 		// Invalidate the parsed message cache since the conversation was just created
 		h.node.parsedCache.Invalidate(result.ConversationID)
+		if cs.Temperature != "" {
+			op.SetPhase("HTTP POST UpdateSettings")
+			applyCreationSettings(h.node.client, h.node.state, h.node.localID, result.ConversationID, cs.Temperature)
+		}
 	} else {
 		// Subsequent writes: send message to existing conversation
 		// Pass the internal model ID to ensure we use the correct API identifier
 		op.SetPhase("HTTP POST SendMessage")
 		if err := h.node.client.SendMessage(cs.ShelleyConversationID, message, cs.EffectiveModelID()); err != nil {
 			log.Printf("SendMessage failed for conversation %s: %v", cs.ShelleyConversationID, err)
-			return syscall.EIO
+			return backendErrno(err)
 		}
 		// Invalidate the parsed message cache since the conversation was modified
 		h.node.parsedCache.Invalidate(cs.ShelleyConversationID)
@@ -965,7 +1577,18 @@ func (n *ConvSendNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.A
 	return 0
 }
 
+// Setattr accepts truncation. Through an open handle it cuts the unsent
+// message, so a writer that rewrites the file in place (ftruncate, then
+// write again) sends the new text instead of both; other attribute
+// changes are ignored.
 func (n *ConvSendNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if size, ok := in.GetSize(); ok {
+		if h, ok := f.(*ConvSendFileHandle); ok {
+			if errno := h.truncate(int64(size)); errno != 0 {
+				return errno
+			}
+		}
+	}
 	return n.Getattr(ctx, f, out)
 }
 
@@ -989,6 +1612,22 @@ func (f *ConvStatusFieldNode) Open(ctx context.Context, flags uint32) (fs.FileHa
 }
 
 func (f *ConvStatusFieldNode) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	data, errno := f.data()
+	if errno != 0 {
+		return nil, errno
+	}
+	return fuse.ReadResultData(readAt(data, dest, off)), 0
+}
+
+// webLinker returns client as a WebLinker if its backend has a web UI to
+// link to; a backend on a unix socket has none, so it gets no web files.
+func webLinker(client shelley.ShelleyClient) (shelley.WebLinker, bool) {
+	linker, ok := client.(shelley.WebLinker)
+	return linker, ok && linker.WebURL() != ""
+}
+
+// data returns the file's content: the field's value and a newline.
+func (f *ConvStatusFieldNode) data() ([]byte, syscall.Errno) {
 	cs := f.state.Get(f.localID)
 	if cs == nil {
 		return nil, syscall.ENOENT
@@ -998,16 +1637,23 @@ func (f *ConvStatusFieldNode) Read(ctx context.Context, fh fs.FileHandle, dest [
 	switch f.field {
 	case "fuse_id":
 		value = cs.LocalID
+	case "web":
+		linker, ok := webLinker(f.client)
+		if !ok || cs.ShelleyConversationID == "" {
+			return nil, syscall.ENOENT
+		}
+		value = linker.ConversationWebURL(cs.ShelleyConversationID)
 	default:
 		return nil, syscall.ENOENT
 	}
-
-	data := []byte(value + "\n")
-	return fuse.ReadResultData(readAt(data, dest, off)), 0
+	return []byte(value + "\n"), 0
 }
 
 func (f *ConvStatusFieldNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFREG | 0444
+	if data, errno := f.data(); errno == 0 {
+		out.Size = uint64(len(data))
+	}
 	cs := f.state.Get(f.localID)
 	if cs != nil && !cs.CreatedAt.IsZero() {
 		setTimestamps(&out.Attr, cs.CreatedAt)
@@ -1186,7 +1832,7 @@ func (h *CancelFileHandle) Flush(ctx context.Context) syscall.Errno {
 
 	if err := h.node.client.CancelConversation(cs.ShelleyConversationID); err != nil {
 		log.Printf("CancelConversation failed for %s (%s): %v", h.node.localID, cs.ShelleyConversationID, err)
-		return syscall.EIO
+		return backendErrno(err)
 	}
 
 	return 0
@@ -1221,7 +1867,7 @@ func (n *SubagentsDirNode) fetchSubagents() ([]shelley.Conversation, error) {
 	}
 
 	var convs []shelley.Conversation
-	if err := json.Unmarshal(data, &convs); err != nil {
+	if err := shelley.UnmarshalLenient(data, &convs); err != nil {
 		return nil, err
 	}
 
@@ -1242,11 +1888,11 @@ func (n *SubagentsDirNode) fetchSubagents() ([]shelley.Conversation, error) {
 
 func (n *SubagentsDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(n.diag, "SubagentsDirNode", "Lookup", n.localID+"/subagents/"+name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&n.Inode).Conversation)
 
 	convs, err := n.fetchSubagents()
 	if err != nil {
-		return nil, syscall.EIO
+		return nil, backendErrno(err)
 	}
 
 	for _, conv := range convs {
@@ -1306,7 +1952,7 @@ func (n *SubagentsDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.E
 func (n *SubagentsDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, n.startTime)
-	out.SetTimeout(cacheTTLConversation)
+	out.SetTimeout(cacheTimeouts(&n.Inode).Conversation)
 	return 0
 }
 
@@ -1394,7 +2040,8 @@ func (c *ContinueNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, u
 	result, err := c.client.ContinueConversation(cs.ShelleyConversationID, "", "")
 	if err != nil {
 		log.Printf("ContinueConversation failed for %s: %v", c.localID, err)
-		return nil, 0, syscall.EIO
+		c.diag.RecordError(c.localID, "ContinueConversation: %v", err)
+		return nil, 0, backendErrno(err)
 	}
 
 	// Adopt the new conversation into local state
@@ -1428,6 +2075,7 @@ type ConversationLastDirNode struct {
 	fs.Inode
 	client    shelley.ShelleyClient
 	state     *state.Store
+	filter    *ConversationFilter
 	startTime time.Time
 	diag      *diag.Tracker
 }
@@ -1446,7 +2094,7 @@ func (n *ConversationLastDirNode) fetchAllConversationsSorted() []shelley.Conver
 	data, err := n.client.ListConversations()
 	if err == nil {
 		var convs []shelley.Conversation
-		if err := json.Unmarshal(data, &convs); err == nil {
+		if err := shelley.UnmarshalLenient(data, &convs); err == nil {
 			for _, conv := range convs {
 				if !seen[conv.ConversationID] {
 					seen[conv.ConversationID] = true
@@ -1460,7 +2108,7 @@ func (n *ConversationLastDirNode) fetchAllConversationsSorted() []shelley.Conver
 	data, err = n.client.ListArchivedConversations()
 	if err == nil {
 		var convs []shelley.Conversation
-		if err := json.Unmarshal(data, &convs); err == nil {
+		if err := shelley.UnmarshalLenient(data, &convs); err == nil {
 			for _, conv := range convs {
 				if !seen[conv.ConversationID] {
 					seen[conv.ConversationID] = true
@@ -1470,6 +2118,8 @@ func (n *ConversationLastDirNode) fetchAllConversationsSorted() []shelley.Conver
 		}
 	}
 
+	all = n.filter.apply(all)
+
 	// Adopt all into local state
 	for _, conv := range all {
 		_, _ = n.state.AdoptWithMetadata(
@@ -1504,7 +2154,7 @@ func (n *ConversationLastDirNode) fetchAllConversationsSorted() []shelley.Conver
 
 func (n *ConversationLastDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(n.diag, "ConversationLastDirNode", "Lookup", name).Done()
-	setEntryTimeout(out, cacheTTLConversation)
+	setEntryTimeout(out, cacheTimeouts(&n.Inode).Conversation)
 
 	// Parse N from name (must be a positive integer)
 	num, err := strconv.Atoi(name)
@@ -1549,6 +2199,6 @@ func (n *ConversationLastDirNode) Readdir(ctx context.Context) (fs.DirStream, sy
 func (n *ConversationLastDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, n.startTime)
-	out.SetTimeout(cacheTTLConversation)
+	out.SetTimeout(cacheTimeouts(&n.Inode).Conversation)
 	return 0
 }
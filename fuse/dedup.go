@@ -0,0 +1,70 @@
+package fuse
+
+import (
+	"crypto/sha256"
+	"sync"
+	"time"
+)
+
+// --- Duplicate-send suppression ---
+// Editor save hooks and retrying scripts sometimes write the same message to
+// send twice in a row. A message identical to the one sent to the same
+// conversation less than sendDedupWindow earlier is dropped instead of
+// posted again; the write still succeeds. ctl dedup=false turns this off
+// for a conversation that really means to repeat itself.
+
+// sendDedupWindow is how long after a send an identical one is dropped.
+const sendDedupWindow = 5 * time.Second
+
+// RecentSends remembers the last message sent to each conversation. A nil
+// *RecentSends suppresses nothing.
+type RecentSends struct {
+	mu   sync.Mutex
+	last map[string]recentSend // by local ID
+}
+
+type recentSend struct {
+	sum [sha256.Size]byte
+	at  time.Time
+}
+
+// NewRecentSends creates an empty RecentSends.
+func NewRecentSends() *RecentSends {
+	return &RecentSends{last: make(map[string]recentSend)}
+}
+
+// claim reports whether message may be sent to localID at now, and if so
+// records it as the conversation's last send. It may not if it repeats a
+// claim made less than sendDedupWindow earlier.
+func (r *RecentSends) claim(localID, message string, now time.Time) bool {
+	if r == nil {
+		return true
+	}
+	sum := sha256.Sum256([]byte(message))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, s := range r.last {
+		if now.Sub(s.at) >= sendDedupWindow {
+			delete(r.last, id)
+		}
+	}
+	if s, ok := r.last[localID]; ok && s.sum == sum {
+		return false
+	}
+	r.last[localID] = recentSend{sum: sum, at: now}
+	return true
+}
+
+// release forgets the claim of message on localID after its send failed,
+// so that retrying it goes through.
+func (r *RecentSends) release(localID, message string) {
+	if r == nil {
+		return
+	}
+	sum := sha256.Sum256([]byte(message))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.last[localID]; ok && s.sum == sum {
+		delete(r.last, localID)
+	}
+}
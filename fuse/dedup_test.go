@@ -0,0 +1,84 @@
+package fuse
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestSendDedup(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	server := mockserver.New(
+		mockserver.WithConversation("conv-dup", nil),
+		mockserver.WithChatHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req shelley.ChatRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			sent = append(sent, req.Message)
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-dup")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+
+	send := func(msg string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(convDir, "send"), []byte(msg), 0); err != nil {
+			t.Fatalf("send %q: %v", msg, err)
+		}
+	}
+	send("Save me\n")
+	send("Save me\n") // the editor hook fired twice
+	send("Something else\n")
+	if err := os.WriteFile(filepath.Join(convDir, "ctl"), []byte("dedup=false\n"), 0644); err != nil {
+		t.Fatalf("dedup=false: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(convDir, "ctl")); string(data) != "dedup=false\n" {
+		t.Errorf("ctl = %q", data)
+	}
+	send("Something else\n")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"Save me", "Something else", "Something else"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+}
+
+func TestRecentSends(t *testing.T) {
+	r := NewRecentSends()
+	now := time.Now()
+	if !r.claim("a", "hi", now) {
+		t.Fatal("first send suppressed")
+	}
+	if r.claim("a", "hi", now.Add(time.Second)) {
+		t.Error("repeat within the window not suppressed")
+	}
+	if !r.claim("b", "hi", now.Add(time.Second)) {
+		t.Error("same message to another conversation suppressed")
+	}
+	if !r.claim("a", "hi", now.Add(sendDedupWindow)) {
+		t.Error("repeat after the window suppressed")
+	}
+	r.release("a", "hi")
+	if !r.claim("a", "hi", now.Add(sendDedupWindow+time.Second)) {
+		t.Error("retry after a failed send suppressed")
+	}
+	if !(*RecentSends)(nil).claim("a", "hi", now) {
+		t.Error("nil RecentSends suppressed a send")
+	}
+}
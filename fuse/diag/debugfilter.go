@@ -0,0 +1,124 @@
+package diag
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DebugFilter is an io.Writer for the logger go-fuse writes its -debug
+// output to. It thins that output out before passing it on, since logging
+// every operation of a busy mount buries what matters and slows the mount
+// down. A request is kept or dropped as a whole: its "rx" line decides, and
+// the "tx" line of the reply follows it. Lines that aren't about a request
+// pass through.
+type DebugFilter struct {
+	out    io.Writer
+	ops    map[string]bool // opcodes to keep; nil keeps all
+	sample int             // keep one request in sample
+	rate   int             // most requests kept per second; 0 for no limit
+	now    func() time.Time
+
+	mu      sync.Mutex
+	seen    int             // requests matching ops, for sampling
+	kept    map[uint64]bool // requests logged whose reply is still due
+	window  time.Time       // start of the current one-second window
+	inRate  int             // requests kept in the window
+	dropped int             // requests over the rate limit since the last note
+}
+
+// maxPendingReplies bounds DebugFilter.kept, since some requests (FORGET)
+// are never replied to.
+const maxPendingReplies = 4096
+
+// NewDebugFilter returns a DebugFilter writing to out. ops lists the
+// opcodes to keep, such as LOOKUP or READ, case-insensitively; none keeps
+// all of them. sample keeps one request in every sample (values below 2
+// keep all), and rate caps the requests kept per second, noting how many
+// were dropped once the next second starts (0 for no cap).
+func NewDebugFilter(out io.Writer, ops []string, sample, rate int) *DebugFilter {
+	f := &DebugFilter{out: out, sample: sample, rate: rate, now: time.Now, kept: make(map[uint64]bool)}
+	for _, op := range ops {
+		if op = strings.ToUpper(strings.TrimSpace(op)); op != "" {
+			if f.ops == nil {
+				f.ops = make(map[string]bool)
+			}
+			f.ops[op] = true
+		}
+	}
+	return f
+}
+
+// debugRequest matches the start of go-fuse's debug lines about a request:
+// "rx {id}: {OPCODE} ..." when it arrives, "tx {id}: ..." for the reply.
+var debugRequest = regexp.MustCompile(`\b(rx|tx) (\d+):\s*(\S*)`)
+
+// Write filters one log line.
+func (f *DebugFilter) Write(p []byte) (int, error) {
+	m := debugRequest.FindSubmatch(p)
+	if m == nil {
+		return f.out.Write(p)
+	}
+	id, err := strconv.ParseUint(string(m[2]), 10, 64)
+	if err != nil {
+		return f.out.Write(p)
+	}
+
+	f.mu.Lock()
+	var note string
+	keep := false
+	if string(m[1]) == "tx" {
+		keep = f.kept[id]
+		delete(f.kept, id)
+	} else {
+		note, keep = f.admit(string(m[3]))
+		if keep {
+			if len(f.kept) >= maxPendingReplies {
+				f.kept = make(map[uint64]bool)
+			}
+			f.kept[id] = true
+		}
+	}
+	f.mu.Unlock()
+
+	if note != "" {
+		if _, err := io.WriteString(f.out, note); err != nil {
+			return 0, err
+		}
+	}
+	if !keep {
+		return len(p), nil
+	}
+	return f.out.Write(p)
+}
+
+// admit decides whether a request with opcode op is logged. note is a line
+// to log first, about requests dropped by the rate limit.
+func (f *DebugFilter) admit(op string) (note string, keep bool) {
+	if f.ops != nil && !f.ops[op] {
+		return "", false
+	}
+	f.seen++
+	if f.sample > 1 && (f.seen-1)%f.sample != 0 {
+		return "", false
+	}
+	if f.rate <= 0 {
+		return "", true
+	}
+	if now := f.now(); now.Sub(f.window) >= time.Second {
+		if f.dropped > 0 {
+			note = fmt.Sprintf("debug: dropped %d request(s) over the rate limit of %d/s\n", f.dropped, f.rate)
+		}
+		f.window, f.inRate, f.dropped = now, 0, 0
+	}
+	if f.inRate >= f.rate {
+		f.dropped++
+		return note, false
+	}
+	f.inRate++
+	return note, true
+}
@@ -0,0 +1,44 @@
+package diag
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"time"
+)
+
+func TestDebugFilter(t *testing.T) {
+	var out bytes.Buffer
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := NewDebugFilter(&out, []string{"lookup", "READ"}, 2, 2)
+	filter.now = func() time.Time { return now }
+	logger := log.New(filter, "", 0)
+
+	logger.Printf("mount: calling fusermount")
+	logger.Printf(`rx 1: LOOKUP n1 ["a"] 2b`)
+	logger.Printf(`rx 2: GETATTR n1 {Fh 0} p1`)
+	logger.Printf(`tx 2:     OK, {tA=1s}`)
+	logger.Printf(`tx 1:     OK, {n2 g1}`)
+	logger.Printf(`rx 3: LOOKUP n1 ["b"] 2b`) // sampled out
+	logger.Printf(`tx 3:     2=no such file or directory`)
+	logger.Printf(`rx 4: READ n2 {Fh 1 [0 +4096)} p1`)
+	logger.Printf(`rx 5: READ n2 {Fh 1 [4096 +4096)} p1`) // sampled out
+	logger.Printf(`rx 6: READ n2 {Fh 1 [8192 +4096)} p1`) // over the rate
+	logger.Printf(`rx 7: READ n2 {Fh 1 [8192 +4096)} p1`) // sampled out
+	logger.Printf(`rx 8: READ n2 {Fh 1 [8192 +4096)} p1`) // over the rate
+	logger.Printf(`tx 4:     OK,  4096b data "hello"...`)
+	now = now.Add(time.Second)
+	logger.Printf(`rx 9: LOOKUP n1 ["c"] 2b`) // sampled out
+	logger.Printf(`rx 10: LOOKUP n1 ["d"] 2b`)
+
+	want := "mount: calling fusermount\n" +
+		`rx 1: LOOKUP n1 ["a"] 2b` + "\n" +
+		`tx 1:     OK, {n2 g1}` + "\n" +
+		`rx 4: READ n2 {Fh 1 [0 +4096)} p1` + "\n" +
+		`tx 4:     OK,  4096b data "hello"...` + "\n" +
+		"debug: dropped 2 request(s) over the rate limit of 2/s\n" +
+		`rx 10: LOOKUP n1 ["d"] 2b` + "\n"
+	if out.String() != want {
+		t.Errorf("filtered output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -49,8 +49,12 @@ func (h *OpHandle) Done() {
 		return
 	}
 	h.tracker.mu.Lock()
+	op, ok := h.tracker.ops[h.id]
 	delete(h.tracker.ops, h.id)
 	h.tracker.mu.Unlock()
+	if ok {
+		h.tracker.recordOp(op, time.Since(op.Started))
+	}
 }
 
 // Tracker records in-flight FUSE operations.
@@ -58,12 +62,26 @@ type Tracker struct {
 	nextID atomic.Uint64
 	mu     sync.Mutex
 	ops    map[uint64]Op
+
+	warnings []func() string // see AddWarning, guarded by mu
+
+	// Per-conversation trace buffers (see EnableTrace) and error logs (see
+	// RecordError), both guarded by traceMu.
+	traceMu    sync.Mutex
+	traceSize  int
+	traceKnown func(key string) bool
+	traces     map[string]*traceRing
+	errSize    int
+	errLogs    map[string]*traceRing
+	onError    func(key, msg string) // see SetErrorHook
 }
 
 // NewTracker creates a new operation tracker.
 func NewTracker() *Tracker {
 	return &Tracker{
-		ops: make(map[uint64]Op),
+		ops:     make(map[uint64]Op),
+		errSize: DefaultErrorLogSize,
+		errLogs: make(map[string]*traceRing),
 	}
 }
 
@@ -125,7 +143,8 @@ func (t *Tracker) Dump() string {
 }
 
 // Handler returns an http.Handler that serves diagnostic information.
-// By default it returns human-readable text. With the ?json query parameter,
+// By default it returns human-readable text, starting with any warnings
+// (see AddWarning). With the ?json query parameter,
 // it returns a JSON array of in-flight operations.
 func (t *Tracker) Handler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -139,6 +158,9 @@ func (t *Tracker) Handler() http.Handler {
 			return
 		}
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, warning := range t.Warnings() {
+			fmt.Fprintf(w, "WARNING: %s\n", warning)
+		}
 		ops := t.InFlight()
 		if len(ops) == 0 {
 			fmt.Fprint(w, "no in-flight FUSE operations\n")
@@ -2,6 +2,7 @@ package diag
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -442,3 +443,100 @@ func TestGoroutineStacksUnderLimit(t *testing.T) {
 		t.Error("did not expect truncation in a normal test")
 	}
 }
+
+func TestTrace(t *testing.T) {
+	tr := NewTracker()
+	if tr.TraceEnabled() {
+		t.Fatal("trace should be disabled by default")
+	}
+	tr.Track("ConversationNode", "Lookup", "abc/ctl").Done()
+	if got := tr.Trace("abc"); got != "" {
+		t.Fatalf("trace recorded while disabled: %q", got)
+	}
+
+	tr.EnableTrace(3, func(key string) bool { return key == "abc" })
+	if !tr.TraceEnabled() {
+		t.Fatal("trace should be enabled")
+	}
+	tr.Track("ConversationNode", "Lookup", "abc/ctl").Done()
+	tr.Track("ConversationNode", "Lookup", "zzz/ctl").Done()
+	tr.Track("ConversationListNode", "Readdir", "").Done()
+	for i := 0; i < 3; i++ {
+		tr.Record("abc", "http", "GET /api/conversation/x %d", i)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(tr.Trace("abc"), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected ring of 3 lines, got %d: %q", len(lines), lines)
+	}
+	for i, line := range lines {
+		if want := fmt.Sprintf("http GET /api/conversation/x %d", i); !strings.HasSuffix(line, want) {
+			t.Errorf("line %d = %q, want suffix %q", i, line, want)
+		}
+	}
+	if got := tr.Trace("zzz"); got != "" {
+		t.Errorf("unknown key traced: %q", got)
+	}
+
+	var nilTracker *Tracker
+	nilTracker.Record("abc", "http", "ignored")
+	if nilTracker.TraceEnabled() || nilTracker.Trace("abc") != "" {
+		t.Error("nil tracker should have no trace")
+	}
+}
+
+func TestTraceRecordsOps(t *testing.T) {
+	tr := NewTracker()
+	tr.EnableTrace(10, nil)
+	tr.Track("MessagesDirNode", "Lookup", "abc/all.md").Done()
+	got := tr.Trace("abc")
+	if !strings.Contains(got, "fuse MessagesDirNode.Lookup abc/all.md") {
+		t.Errorf("trace = %q, want the completed op", got)
+	}
+}
+
+func TestErrorLog(t *testing.T) {
+	tr := NewTracker()
+	tr.SetErrorLogSize(2)
+	tr.RecordError("abc", "GET /api/conversation/x: %d", 500)
+	tr.RecordError("abc", "GET /api/conversation/x: %d", 502)
+	tr.RecordError("abc", "GET /api/conversation/x: %d", 503)
+
+	got := tr.ErrorLog("abc")
+	if strings.Contains(got, ": 500") || !strings.Contains(got, ": 502\n") || !strings.HasSuffix(got, ": 503\n") {
+		t.Errorf("error log should hold the last 2 errors, oldest first:\n%s", got)
+	}
+	if tr.ErrorLog("zzz") != "" {
+		t.Error("unrelated key should have an empty error log")
+	}
+
+	tr.ForgetErrors("abc")
+	if tr.ErrorLog("abc") != "" {
+		t.Error("ForgetErrors should drop the log")
+	}
+
+	tr.SetErrorLogSize(0)
+	tr.RecordError("abc", "ignored")
+	if tr.ErrorLog("abc") != "" {
+		t.Error("error log should be disabled at size 0")
+	}
+}
+
+func TestHandlerWarnings(t *testing.T) {
+	tr := NewTracker()
+	warning := ""
+	tr.AddWarning(func() string { return warning })
+
+	get := func() string {
+		rec := httptest.NewRecorder()
+		tr.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/diag", nil))
+		return rec.Body.String()
+	}
+	if body := get(); strings.Contains(body, "WARNING") {
+		t.Errorf("no warning expected, got %q", body)
+	}
+	warning = "state file memory-only"
+	if body := get(); !strings.HasPrefix(body, "WARNING: state file memory-only\n") {
+		t.Errorf("body = %q, want the warning first", body)
+	}
+}
@@ -0,0 +1,73 @@
+package diag
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultErrorLogSize is how many errors each conversation's error log keeps
+// unless changed with SetErrorLogSize.
+const DefaultErrorLogSize = 100
+
+// SetErrorLogSize changes how many errors are kept per conversation; 0
+// disables the error logs. It must be called before the tracker is used.
+func (t *Tracker) SetErrorLogSize(size int) {
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	t.errSize = size
+}
+
+// SetErrorHook makes RecordError call fn with the key and message of every
+// error, whether or not error logs are kept. fn runs with the tracker
+// locked, so it must be quick and must not call back into the tracker.
+// Pass nil to remove the hook.
+func (t *Tracker) SetErrorHook(fn func(key, msg string)) {
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	t.onError = fn
+}
+
+// RecordError appends a timestamped line to key's error log. Unlike traces,
+// error logs are always kept (up to the configured size), since they only
+// grow when something goes wrong. Safe to call on a nil receiver.
+func (t *Tracker) RecordError(key, format string, args ...any) {
+	if t == nil || key == "" {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	line := time.Now().UTC().Format(lineTimeFormat) + " " + msg
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	if t.onError != nil {
+		t.onError(key, msg)
+	}
+	if t.errSize <= 0 {
+		return
+	}
+	r := t.errLogs[key]
+	if r == nil {
+		r = &traceRing{}
+		t.errLogs[key] = r
+	}
+	r.add(line, t.errSize)
+}
+
+// ErrorLog returns key's error log, oldest line first.
+func (t *Tracker) ErrorLog(key string) string {
+	if t == nil {
+		return ""
+	}
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	return t.errLogs[key].text()
+}
+
+// ForgetErrors drops key's error log, e.g. when its conversation is deleted.
+func (t *Tracker) ForgetErrors(key string) {
+	if t == nil {
+		return
+	}
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	delete(t.errLogs, key)
+}
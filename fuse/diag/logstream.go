@@ -0,0 +1,127 @@
+package diag
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"shelley-fuse/journal"
+)
+
+// LogEvent is one log line as sent by LogStream.
+type LogEvent struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"` // syslog name guessed by journal.Classify
+	Message string    `json:"msg"`
+	// Dropped is how many events this client missed just before this one
+	// because it didn't keep up.
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// logStreamBuffer is how many events a slow client can fall behind before
+// events are dropped for it.
+const logStreamBuffer = 256
+
+// LogStream is an io.Writer for the log package that passes everything on
+// to an underlying writer and also hands each line, as a LogEvent, to the
+// HTTP clients currently attached through Handler. Logging never waits for
+// a client: events a client can't take in time are dropped and counted.
+type LogStream struct {
+	out io.Writer
+
+	mu   sync.Mutex
+	subs map[*logSubscriber]struct{}
+}
+
+type logSubscriber struct {
+	events  chan LogEvent
+	dropped int // guarded by LogStream.mu
+}
+
+// NewLogStream returns a LogStream writing through to out.
+func NewLogStream(out io.Writer) *LogStream {
+	return &LogStream{out: out, subs: make(map[*logSubscriber]struct{})}
+}
+
+// logHeader matches the date and time the log package puts before messages
+// with its standard flags.
+var logHeader = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? `)
+
+// Write writes p to the underlying writer and publishes one event per line.
+func (s *LogStream) Write(p []byte) (int, error) {
+	n, err := s.out.Write(p)
+	s.publish(string(p))
+	return n, err
+}
+
+func (s *LogStream) publish(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.subs) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		msg := logHeader.ReplaceAllString(line, "")
+		ev := LogEvent{Time: now, Level: journal.Classify(msg).String(), Message: msg}
+		for sub := range s.subs {
+			ev.Dropped = sub.dropped
+			select {
+			case sub.events <- ev:
+				sub.dropped = 0
+			default:
+				sub.dropped++
+			}
+		}
+	}
+}
+
+func (s *LogStream) subscribe() *logSubscriber {
+	sub := &logSubscriber{events: make(chan LogEvent, logStreamBuffer)}
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub
+}
+
+func (s *LogStream) unsubscribe(sub *logSubscriber) {
+	s.mu.Lock()
+	delete(s.subs, sub)
+	s.mu.Unlock()
+}
+
+// Handler returns an http.Handler that streams log events as newline-
+// delimited JSON for as long as the client stays connected. Only lines
+// logged after the client connects are sent.
+func (s *LogStream) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		sub := s.subscribe()
+		defer s.unsubscribe(sub)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		enc := json.NewEncoder(w)
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case ev := <-sub.events:
+				if err := enc.Encode(ev); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
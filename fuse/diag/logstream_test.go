@@ -0,0 +1,76 @@
+package diag
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLogStream(t *testing.T) {
+	var out bytes.Buffer
+	stream := NewLogStream(&out)
+	logger := log.New(stream, "", log.LstdFlags)
+	logger.Printf("before anyone listens")
+
+	srv := httptest.NewServer(stream.Handler())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	// The handler subscribes before sending the headers, so the client is
+	// attached once Get returns.
+	logger.Printf("Send failed for abcd1234: boom")
+	logger.Printf("first line\nsecond line")
+
+	lines := bufio.NewScanner(resp.Body)
+	var got []LogEvent
+	for len(got) < 3 && lines.Scan() {
+		var ev LogEvent
+		if err := json.Unmarshal(lines.Bytes(), &ev); err != nil {
+			t.Fatalf("bad event %q: %v", lines.Text(), err)
+		}
+		got = append(got, ev)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %d: %v", len(got), lines.Err())
+	}
+	if got[0].Message != "Send failed for abcd1234: boom" || got[0].Level != "err" {
+		t.Errorf("first event = %+v", got[0])
+	}
+	if time.Since(got[0].Time) > time.Minute {
+		t.Errorf("event time %v", got[0].Time)
+	}
+	if got[1].Message != "first line" || got[2].Message != "second line" || got[2].Level != "info" {
+		t.Errorf("multi-line events = %+v, %+v", got[1], got[2])
+	}
+	if !bytes.Contains(out.Bytes(), []byte("before anyone listens")) || !bytes.Contains(out.Bytes(), []byte("second line")) {
+		t.Errorf("underlying writer missed lines:\n%s", out.String())
+	}
+}
+
+func TestLogStreamDropsForSlowClients(t *testing.T) {
+	stream := NewLogStream(&bytes.Buffer{})
+	sub := stream.subscribe()
+	defer stream.unsubscribe(sub)
+	for i := 0; i < logStreamBuffer+5; i++ {
+		stream.Write([]byte("line\n"))
+	}
+	for i := 0; i < logStreamBuffer; i++ {
+		<-sub.events
+	}
+	stream.Write([]byte("after\n"))
+	if ev := <-sub.events; ev.Message != "after" || ev.Dropped != 5 {
+		t.Errorf("event after the drop = %+v, want 5 dropped", ev)
+	}
+}
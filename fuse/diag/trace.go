@@ -0,0 +1,110 @@
+package diag
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Per-conversation traces: when enabled, every completed operation whose
+// detail starts with a known key (a local conversation ID, as in
+// "a1b2c3d4/ctl") is appended to that key's ring buffer, alongside anything
+// recorded explicitly with Record (such as backend requests). The buffers
+// back the conversation/{id}/.trace files.
+
+// lineTimeFormat timestamps trace and error log lines.
+const lineTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// traceRing holds the most recent lines for one key.
+type traceRing struct {
+	lines []string
+	next  int // index of the oldest line once the ring is full
+}
+
+func (r *traceRing) add(line string, size int) {
+	if len(r.lines) < size {
+		r.lines = append(r.lines, line)
+		return
+	}
+	r.lines[r.next] = line
+	r.next = (r.next + 1) % size
+}
+
+// text returns the lines, oldest first, as newline-terminated text.
+func (r *traceRing) text() string {
+	if r == nil {
+		return ""
+	}
+	var b strings.Builder
+	for i := range r.lines {
+		b.WriteString(r.lines[(r.next+i)%len(r.lines)])
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// EnableTrace starts keeping the last size trace lines for each key that
+// known accepts. It must be called before the tracker is used.
+func (t *Tracker) EnableTrace(size int, known func(key string) bool) {
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	t.traceSize = size
+	t.traceKnown = known
+	t.traces = make(map[string]*traceRing)
+}
+
+// TraceEnabled reports whether EnableTrace has been called. Safe to call
+// on a nil receiver.
+func (t *Tracker) TraceEnabled() bool {
+	if t == nil {
+		return false
+	}
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	return t.traceSize > 0
+}
+
+// Record appends a line to key's trace, prefixed with the current time and
+// kind (e.g. "http"). It does nothing if tracing is disabled or key is
+// unknown. Safe to call on a nil receiver.
+func (t *Tracker) Record(key, kind, format string, args ...any) {
+	if t == nil {
+		return
+	}
+	t.record(key, time.Now(), kind, fmt.Sprintf(format, args...))
+}
+
+func (t *Tracker) record(key string, at time.Time, kind, msg string) {
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	if t.traceSize <= 0 || (t.traceKnown != nil && !t.traceKnown(key)) {
+		return
+	}
+	r := t.traces[key]
+	if r == nil {
+		r = &traceRing{}
+		t.traces[key] = r
+	}
+	r.add(fmt.Sprintf("%s %-4s %s", at.UTC().Format(lineTimeFormat), kind, msg), t.traceSize)
+}
+
+// recordOp adds a completed operation to the trace of the key its detail
+// starts with.
+func (t *Tracker) recordOp(op Op, elapsed time.Duration) {
+	key, _, _ := strings.Cut(op.Detail, "/")
+	if key == "" {
+		return
+	}
+	msg := fmt.Sprintf("%s.%s %s (%s)", op.Node, op.Method, op.Detail, elapsed.Truncate(time.Microsecond))
+	t.record(key, op.Started, "fuse", msg)
+}
+
+// Trace returns key's trace, oldest line first, as newline-terminated text.
+func (t *Tracker) Trace(key string) string {
+	if t == nil {
+		return ""
+	}
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	return t.traces[key].text()
+}
@@ -0,0 +1,24 @@
+package diag
+
+// AddWarning registers fn to be asked, on every request to the text form of
+// /diag, for a condition the user should know about; fn returns "" while
+// there is none. Warnings are printed above the in-flight operations.
+func (t *Tracker) AddWarning(fn func() string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.warnings = append(t.warnings, fn)
+}
+
+// Warnings returns the current warnings, in the order they were registered.
+func (t *Tracker) Warnings() []string {
+	t.mu.Lock()
+	fns := append([]func() string(nil), t.warnings...)
+	t.mu.Unlock()
+	var warnings []string
+	for _, fn := range fns {
+		if w := fn(); w != "" {
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
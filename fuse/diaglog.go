@@ -0,0 +1,47 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/state"
+)
+
+// --- DiagLogNode: /conversation/{id}/.trace and /conversation/{id}/errors.log ---
+// Read-only views of the diag tracker's per-conversation ring buffers:
+// .trace holds the most recent FUSE operations and backend requests that
+// touched the conversation (only present with -trace), errors.log the most
+// recent backend errors. Both list the oldest line first.
+
+type DiagLogNode struct {
+	fs.Inode
+	localID   string
+	state     *state.Store
+	startTime time.Time
+	read      func(localID string) string
+}
+
+var _ = (fs.NodeOpener)((*DiagLogNode)(nil))
+var _ = (fs.NodeGetattrer)((*DiagLogNode)(nil))
+
+// Open snapshots the log so a reader sees one consistent copy, without the
+// operations its own reads cause.
+func (n *DiagLogNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	content := []byte(n.read(n.localID))
+	return &ConvContentFileHandle{content: content, localID: n.localID, state: n.state, startTime: n.startTime}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *DiagLogNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fga, ok := f.(fs.FileGetattrer); ok {
+		return fga.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	if exportCompat(&n.Inode) {
+		openedSize(ctx, n, out)
+	}
+	setTimestamps(&out.Attr, metaTime(n.state, n.localID, n.startTime))
+	return 0
+}
@@ -0,0 +1,282 @@
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- DiffDirNode: /conversation/{id}/messages/diff/ directory ---
+// diff/{A}..{B} is a unified diff from the content.md of message A to that
+// of message B, where A and B are message indices as in the message
+// directory names (0-based, leading zeros allowed). It compares two retried
+// answers or an edited prompt with the original without copying either out.
+// Nothing is listed: there is a file for every pair of messages.
+
+type DiffDirNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	startTime   time.Time
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*DiffDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*DiffDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*DiffDirNode)(nil))
+
+// parseDiffName parses "{A}..{B}" into two 0-based message indices.
+func parseDiffName(name string) (int, int, bool) {
+	a, b, ok := strings.Cut(name, "..")
+	if !ok {
+		return 0, 0, false
+	}
+	from, errA := strconv.Atoi(a)
+	to, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil || from < 0 || to < 0 || strings.ContainsAny(name, "+-") {
+		return 0, 0, false
+	}
+	return from, to, true
+}
+
+func (d *DiffDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(d.diag, "DiffDirNode", "Lookup", d.localID+"/"+name).Done()
+	from, to, ok := parseDiffName(name)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	result, errno := d.messages()
+	if errno != 0 {
+		return nil, errno
+	}
+	a, b := shelley.GetMessage(result.Messages, from+1), shelley.GetMessage(result.Messages, to+1)
+	if a == nil || b == nil {
+		return nil, syscall.ENOENT
+	}
+	// Messages don't change, so neither does the diff between two of them.
+	content := messageDiff(a, b, result)
+	t := laterMessageTime(a, b, d.startTime)
+	setImmutableFieldAttrs(out, content, true, t, cacheTimeouts(&d.Inode).Immutable)
+	ino := stableIno("msg-diff", a.ConversationID, strconv.Itoa(from), strconv.Itoa(to))
+	return d.NewInode(ctx, &MessageFieldNode{value: content, startTime: t, noNewline: true}, fs.StableAttr{Mode: fuse.S_IFREG, Ino: ino}), 0
+}
+
+func (d *DiffDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	return fs.NewListDirStream(nil), 0
+}
+
+func (d *DiffDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, d.startTime)
+	return 0
+}
+
+// messages returns the parsed conversation.
+func (d *DiffDirNode) messages() (*ParseResult, syscall.Errno) {
+	cs := d.state.Get(d.localID)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return nil, syscall.ENOENT
+	}
+	convData, err := d.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	result, err := d.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	return result, 0
+}
+
+// laterMessageTime returns the creation time of the later of a and b.
+func laterMessageTime(a, b *shelley.Message, fallback time.Time) time.Time {
+	ta, tb := shelley.ParseMessageTime(a), shelley.ParseMessageTime(b)
+	if tb.After(ta) {
+		ta = tb
+	}
+	if ta.IsZero() {
+		return fallback
+	}
+	return ta
+}
+
+// messageDiff renders the unified diff between the content.md files of a
+// and b, named as in messages/. It is empty if they are the same.
+func messageDiff(a, b *shelley.Message, result *ParseResult) string {
+	name := func(m *shelley.Message) string {
+		return messageFileBase(m.SequenceID, shelley.MessageSlug(m, result.ToolMap), result.MaxSeqID) + "/content.md"
+	}
+	return unifiedDiff(name(a), name(b),
+		string(shelley.FormatMarkdown([]shelley.Message{*a})),
+		string(shelley.FormatMarkdown([]shelley.Message{*b})))
+}
+
+// diffContext is how many unchanged lines surround each change.
+const diffContext = 3
+
+// unifiedDiff returns the line diff from oldText to newText in unified
+// format, or "" if they are equal.
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	oldLines, newLines := splitDiffLines(oldText), splitDiffLines(newText)
+	ops := diffLines(oldLines, newLines)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// Find the next change and the run of ops making up its hunk: changes
+		// closer together than twice the context share one.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		first := max(start-diffContext, 0)
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		last := min(end+diffContext, len(ops))
+
+		oldStart, newStart := ops[first].oldLine, ops[first].newLine
+		var oldCount, newCount int
+		for _, op := range ops[first:last] {
+			if op.kind != '+' {
+				oldCount++
+			}
+			if op.kind != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, op := range ops[first:last] {
+			b.WriteByte(op.kind)
+			b.WriteString(op.text)
+			if !strings.HasSuffix(op.text, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		start = last
+	}
+	return b.String()
+}
+
+// hunkRange formats the start,count of one side of a hunk header. Lines are
+// numbered from 1; an empty range names the line before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return strconv.Itoa(start + 1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// splitDiffLines splits s after each newline, keeping the newlines.
+func splitDiffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added.
+// oldLine and newLine are the 0-based positions in either text where the
+// op applies.
+type diffOp struct {
+	kind             byte
+	text             string
+	oldLine, newLine int
+}
+
+// diffLines returns a shortest edit script from a to b (Myers' algorithm).
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	maxD := n + m
+	offset := maxD
+	v := make([]int, 2*maxD+2)
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		trace = append(trace, append([]int(nil), v...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, d, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved frontiers of diffLines back from the end to
+// recover the edit script.
+func backtrack(trace [][]int, a, b []string, d, offset int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for ; d >= 0; d-- {
+		k := x - y
+		var prevK int
+		if k == -d || k != d && trace[d][offset+k-1] < trace[d][offset+k+1] {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := 0
+		if d > 0 {
+			prevX = trace[d][offset+prevK]
+		}
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, diffOp{kind: ' ', text: a[x], oldLine: x, newLine: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, diffOp{kind: '+', text: b[y], oldLine: x, newLine: y})
+			} else {
+				x--
+				ops = append(ops, diffOp{kind: '-', text: a[x], oldLine: x, newLine: y})
+			}
+		}
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
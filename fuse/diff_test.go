@@ -0,0 +1,79 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"changed line", "a\nb\nc\n", "a\nB\nc\n",
+			"--- x\n+++ y\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n"},
+		{"added to empty", "", "a\n",
+			"--- x\n+++ y\n@@ -0,0 +1 @@\n+a\n"},
+		{"no final newline", "a\n", "a",
+			"--- x\n+++ y\n@@ -1 +1 @@\n-a\n+a\n\\ No newline at end of file\n"},
+		{"separate hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n", "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			"--- x\n+++ y\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := unifiedDiff("x", "y", tt.old, tt.new); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessageDiffFile(t *testing.T) {
+	question, first, second := "What is 2+2?", "It is 4.\nDone.", "It is four.\nDone."
+	server := mockserver.New(mockserver.WithConversation("conv-diff", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-diff", SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: "m2", ConversationID: "conv-diff", SequenceID: 2, Type: "user", UserData: &first},
+		{MessageID: "m3", ConversationID: "conv-diff", SequenceID: 3, Type: "user", UserData: &second},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-diff", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	diffDir := filepath.Join(mountPoint, "conversation", localID, "messages", "diff")
+
+	data, err := os.ReadFile(filepath.Join(diffDir, "1..2"))
+	if err != nil {
+		t.Fatalf("read diff: %v", err)
+	}
+	diff := string(data)
+	if !strings.HasPrefix(diff, "--- 1-user/content.md\n+++ 2-user/content.md\n@@ ") {
+		t.Errorf("unexpected header:\n%s", diff)
+	}
+	if !strings.Contains(diff, "\n-It is 4.\n+It is four.\n Done.\n") {
+		t.Errorf("diff missing the change:\n%s", diff)
+	}
+
+	// Indices may be zero-padded like the message directories.
+	if padded, err := os.ReadFile(filepath.Join(diffDir, "01..002")); err != nil || string(padded) != diff {
+		t.Errorf("padded name: %q, %v", padded, err)
+	}
+	if same, err := os.ReadFile(filepath.Join(diffDir, "2..2")); err != nil || len(same) != 0 {
+		t.Errorf("diff of a message with itself: %q, %v", same, err)
+	}
+	for _, name := range []string{"0..9", "1-3", "a..b", "-1..2"} {
+		if _, err := os.Stat(filepath.Join(diffDir, name)); !errors.Is(err, syscall.ENOENT) {
+			t.Errorf("%s: got %v, want ENOENT", name, err)
+		}
+	}
+}
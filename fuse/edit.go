@@ -0,0 +1,243 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+)
+
+// --- MessageContentNode: writable messages/{NNN}-user/content.md ---
+// Used instead of MessageFieldNode for user messages when the client can
+// edit messages (shelley.MessageEditor). Writing the file and closing it
+// replaces the message's text on the backend. The text written may keep the
+// "## user" heading that reading the file shows; it is dropped before the
+// edit, so an editor round trip changes only what was changed.
+//
+// After an edit the node re-renders itself from the backend, and the parsed
+// message cache is dropped so all.md, last/ and friends show the new text.
+// The xattr mirror on the message directory keeps the old text until the
+// kernel forgets the directory.
+
+type MessageContentNode struct {
+	fs.Inode
+	dir *MessageDirNode
+
+	mu      sync.Mutex
+	content string // rendered markdown, replaced after an edit
+}
+
+var _ = (fs.NodeOpener)((*MessageContentNode)(nil))
+var _ = (fs.NodeReader)((*MessageContentNode)(nil))
+var _ = (fs.NodeGetattrer)((*MessageContentNode)(nil))
+var _ = (fs.NodeSetattrer)((*MessageContentNode)(nil))
+
+// editable reports whether content.md of this message can be written: only
+// user messages are edited, and only through a client that supports it.
+func (m *MessageDirNode) editable() bool {
+	if m.state == nil || m.localID == "" || m.message.Type != "user" {
+		return false
+	}
+	_, ok := m.client.(shelley.MessageEditor)
+	return ok && featureEnabled(m.client, m.state, shelley.FeatureEdit)
+}
+
+func (n *MessageContentNode) current() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.content
+}
+
+func (n *MessageContentNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) == 0 {
+		return nil, fuse.FOPEN_KEEP_CACHE, 0
+	}
+	if cs := n.dir.state.Get(n.dir.localID); cs != nil && cs.ReadOnly {
+		return nil, 0, syscall.EROFS
+	}
+	h := &messageEditHandle{node: n}
+	if flags&syscall.O_TRUNC == 0 {
+		h.buf = []byte(n.current())
+	} else {
+		h.dirty = true
+	}
+	return h, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *MessageContentNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	if fr, ok := f.(fs.FileReader); ok {
+		return fr.Read(ctx, dest, off)
+	}
+	return fuse.ReadResultData(readAt([]byte(n.current()), dest, off)), 0
+}
+
+func (n *MessageContentNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0644
+	if h, ok := f.(*messageEditHandle); ok {
+		out.Size = uint64(h.size())
+	} else {
+		out.Size = uint64(len(n.current()))
+	}
+	setTimestamps(&out.Attr, n.dir.messageTime())
+	out.SetTimeout(cacheTimeouts(&n.Inode).Immutable)
+	return 0
+}
+
+// Setattr accepts truncation, which is how `>` and O_TRUNC reach an open
+// handle; other attribute changes are ignored.
+func (n *MessageContentNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if size, ok := in.GetSize(); ok {
+		if h, ok := f.(*messageEditHandle); ok {
+			if errno := h.truncate(size); errno != 0 {
+				return errno
+			}
+		}
+	}
+	return n.Getattr(ctx, f, out)
+}
+
+// edit sends text to the backend as the message's new text and refreshes
+// the node from the result.
+func (n *MessageContentNode) edit(op *diag.OpHandle, text string) syscall.Errno {
+	d := n.dir
+	cs := d.state.Get(d.localID)
+	if cs == nil || cs.ShelleyConversationID == "" {
+		return syscall.ENOENT
+	}
+	op.SetPhase("HTTP POST EditMessage")
+	editor := d.client.(shelley.MessageEditor)
+	if err := editor.EditMessage(cs.ShelleyConversationID, d.message.MessageID, text); err != nil {
+		log.Printf("EditMessage failed for %s message %s: %v", cs.ShelleyConversationID, d.message.MessageID, err)
+		if errors.Is(err, shelley.ErrMessageEditUnsupported) {
+			return syscall.ENOTSUP
+		}
+		return backendErrno(err)
+	}
+	d.parsedCache.Invalidate(cs.ShelleyConversationID)
+	if err := d.state.RecordMessageEdit(d.localID, d.message.MessageID, time.Now()); err != nil {
+		log.Printf("Failed to record edit of message %s: %v", d.message.MessageID, err)
+	}
+
+	op.SetPhase("refresh")
+	convData, err := d.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return 0 // the edit went through; the next lookup will show it
+	}
+	msgs, _, err := d.parsedCache.GetOrParse(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return 0
+	}
+	if msg := shelley.GetMessage(msgs, d.message.SequenceID); msg != nil {
+		n.mu.Lock()
+		n.content = string(shelley.FormatMarkdown([]shelley.Message{*msg}))
+		n.mu.Unlock()
+	}
+	// Drop the kernel's cached pages and size. Must be done in a goroutine:
+	// the notification waits for the kernel, which may be waiting for us.
+	go n.NotifyContent(0, 0)
+	return 0
+}
+
+// messageEditText turns what was written to content.md into message text:
+// the "## heading" line the file is read with is dropped if it was kept,
+// and so are the blank lines around the text.
+func messageEditText(written, rendered string) string {
+	if heading, _, ok := strings.Cut(rendered, "\n"); ok && strings.HasPrefix(heading, "## ") {
+		written = strings.TrimPrefix(written, heading+"\n")
+	}
+	return strings.Trim(written, "\n")
+}
+
+// maxEdit is the largest text content.md can grow to while it is being
+// rewritten. Writes and truncates beyond it fail with EFBIG rather than
+// allocating whatever offset they name.
+const maxEdit = 1 << 20
+
+// messageEditHandle buffers a rewrite of content.md and sends it as an
+// edit on Flush (close), like ConvSendFileHandle does for messages.
+type messageEditHandle struct {
+	node  *MessageContentNode
+	mu    sync.Mutex
+	buf   []byte
+	dirty bool
+}
+
+var _ = (fs.FileReader)((*messageEditHandle)(nil))
+var _ = (fs.FileWriter)((*messageEditHandle)(nil))
+var _ = (fs.FileFlusher)((*messageEditHandle)(nil))
+var _ = (fs.FileLseeker)((*messageEditHandle)(nil))
+
+func (h *messageEditHandle) size() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.buf)
+}
+
+func (h *messageEditHandle) truncate(size uint64) syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if size <= uint64(len(h.buf)) {
+		h.buf = h.buf[:size]
+	} else if size > maxEdit {
+		return syscall.EFBIG
+	} else {
+		h.buf = append(h.buf, make([]byte, int(size)-len(h.buf))...)
+	}
+	h.dirty = true
+	return 0
+}
+
+func (h *messageEditHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return fuse.ReadResultData(readAt(h.buf, dest, off)), 0
+}
+
+func (h *messageEditHandle) Lseek(ctx context.Context, off uint64, whence uint32) (uint64, syscall.Errno) {
+	return seekContent(h.size(), off, whence)
+}
+
+func (h *messageEditHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if end := off + int64(len(data)); end > int64(len(h.buf)) {
+		if end > maxEdit {
+			return 0, syscall.EFBIG
+		}
+		h.buf = append(h.buf, make([]byte, int(end)-len(h.buf))...)
+	}
+	copy(h.buf[off:], data)
+	h.dirty = true
+	return uint32(len(data)), 0
+}
+
+// Flush sends the edit if anything was written. Writing nothing but blank
+// lines is ignored rather than wiping the message.
+func (h *messageEditHandle) Flush(ctx context.Context) syscall.Errno {
+	d := h.node.dir
+	op := diag.Track(d.diag, "MessageContentNode", "Flush", d.localID+"/"+d.message.MessageID)
+	defer op.Done()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.dirty {
+		return 0
+	}
+	text := messageEditText(string(h.buf), h.node.current())
+	if text == "" {
+		return 0
+	}
+	if errno := h.node.edit(op, text); errno != 0 {
+		return errno
+	}
+	h.dirty = false
+	return 0
+}
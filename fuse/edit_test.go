@@ -0,0 +1,118 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func editTestMessages() []shelley.Message {
+	question, answer := "What is FUSE?", "A userspace filesystem interface."
+	return []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-edit", SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: "m2", ConversationID: "conv-edit", SequenceID: 2, Type: "shelley", UserData: &answer},
+	}
+}
+
+func TestMessageContentEdit(t *testing.T) {
+	server := mockserver.New(mockserver.WithConversation("conv-edit", editTestMessages()), mockserver.WithMessageEditing())
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-edit", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	msgDir := filepath.Join(mountPoint, "conversation", localID, "messages")
+	content := filepath.Join(msgDir, "0-user", "content.md")
+	fi, err := os.Stat(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0644 {
+		t.Errorf("user content.md mode = %v, want writable", fi.Mode())
+	}
+	if fi, err := os.Stat(filepath.Join(msgDir, "1-agent", "content.md")); err != nil || fi.Mode().Perm() != 0444 {
+		t.Errorf("agent content.md = %v, %v; want read-only", fi.Mode(), err)
+	}
+
+	// Keeping the heading, as an editor round trip does, only changes the text.
+	if err := os.WriteFile(content, []byte("## user\n\nWhat is FUSE, briefly?\n\n"), 0644); err != nil {
+		t.Fatalf("write content.md: %v", err)
+	}
+	data, err := os.ReadFile(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "## user\n\nWhat is FUSE, briefly?\n\n" {
+		t.Errorf("content.md after edit = %q", data)
+	}
+	all, err := os.ReadFile(filepath.Join(msgDir, "all.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(all), "What is FUSE, briefly?") {
+		t.Errorf("all.md does not show the edit:\n%s", all)
+	}
+	if _, ok := store.MessageEditedAt(localID, "m1"); !ok {
+		t.Error("edit not recorded in state")
+	}
+}
+
+func TestMessageContentEdit_Unsupported(t *testing.T) {
+	server := mockserver.New(mockserver.WithConversation("conv-edit", editTestMessages()))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-edit", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	content := filepath.Join(mountPoint, "conversation", localID, "messages", "0-user", "content.md")
+	err := os.WriteFile(content, []byte("changed\n"), 0644)
+	if !errors.Is(err, syscall.ENOTSUP) {
+		t.Errorf("write to content.md without backend support: got %v, want ENOTSUP", err)
+	}
+	if _, ok := store.MessageEditedAt(localID, "m1"); ok {
+		t.Error("failed edit recorded in state")
+	}
+}
+
+func TestMessageEditHandleSizeLimit(t *testing.T) {
+	h := &messageEditHandle{buf: []byte("old\n")}
+	if errno := h.truncate(8 << 60); errno != syscall.EFBIG {
+		t.Errorf("truncate to 8 EiB = %v, want EFBIG", errno)
+	}
+	if _, errno := h.Write(context.Background(), []byte("x"), maxEdit); errno != syscall.EFBIG {
+		t.Errorf("write past the limit = %v, want EFBIG", errno)
+	}
+	if string(h.buf) != "old\n" {
+		t.Errorf("buffer = %q after refused growth, want it unchanged", h.buf)
+	}
+	if errno := h.truncate(8); errno != 0 || string(h.buf) != "old\n\x00\x00\x00\x00" {
+		t.Errorf("truncate(8) = %v, buffer %q", errno, h.buf)
+	}
+	if n, errno := h.Write(context.Background(), []byte("new"), 0); errno != 0 || n != 3 || string(h.buf[:3]) != "new" {
+		t.Errorf("Write = %d, %v; buffer %q", n, errno, h.buf)
+	}
+}
+
+func TestMessageEditText(t *testing.T) {
+	rendered := "## user\n\nold\n\n"
+	for _, tc := range []struct{ written, want string }{
+		{"new\n", "new"},
+		{"## user\n\nnew text\n\n", "new text"},
+		{"## heading of my own\nbody\n", "## heading of my own\nbody"},
+		{"\n\n", ""},
+	} {
+		if got := messageEditText(tc.written, rendered); got != tc.want {
+			t.Errorf("messageEditText(%q) = %q, want %q", tc.written, got, tc.want)
+		}
+	}
+}
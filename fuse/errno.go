@@ -0,0 +1,95 @@
+package fuse
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"shelley-fuse/shelley"
+)
+
+// Backend failures reach applications as errnos. A failure with an HTTP
+// status from the server is translated with the table below, so that e.g.
+// reading a conversation the server no longer has fails with ENOENT rather
+// than a generic EIO:
+//
+//	404 Not Found          → ENOENT
+//	401 Unauthorized       → EACCES
+//	403 Forbidden          → EACCES
+//	409 Conflict           → EEXIST
+//	429 Too Many Requests  → EAGAIN
+//	5xx                    → EIO
+//
+// Any other status, and failures without one (connection refused, timeouts,
+// undecodable responses), map to EIO. Entries can be overridden per status
+// with SetStatusErrno (-status-errno on the command line).
+
+var (
+	statusErrnoMu sync.RWMutex
+	statusErrnos  = map[int]syscall.Errno{
+		404: syscall.ENOENT,
+		401: syscall.EACCES,
+		403: syscall.EACCES,
+		409: syscall.EEXIST,
+		429: syscall.EAGAIN,
+	}
+)
+
+// errnoNames are the errnos -status-errno accepts, by name.
+var errnoNames = map[string]syscall.Errno{
+	"EACCES":    syscall.EACCES,
+	"EAGAIN":    syscall.EAGAIN,
+	"EBUSY":     syscall.EBUSY,
+	"EEXIST":    syscall.EEXIST,
+	"EINVAL":    syscall.EINVAL,
+	"EIO":       syscall.EIO,
+	"ENOENT":    syscall.ENOENT,
+	"ENOSPC":    syscall.ENOSPC,
+	"EPERM":     syscall.EPERM,
+	"EROFS":     syscall.EROFS,
+	"ETIMEDOUT": syscall.ETIMEDOUT,
+}
+
+// ParseErrno returns the errno with the given name, e.g. "EAGAIN".
+func ParseErrno(name string) (syscall.Errno, error) {
+	if errno, ok := errnoNames[strings.ToUpper(name)]; ok {
+		return errno, nil
+	}
+	names := make([]string, 0, len(errnoNames))
+	for n := range errnoNames {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("unknown errno %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+// SetStatusErrno makes backend responses with the given HTTP status fail
+// with errno. It must be called before mounting.
+func SetStatusErrno(status int, errno syscall.Errno) {
+	statusErrnoMu.Lock()
+	defer statusErrnoMu.Unlock()
+	statusErrnos[status] = errno
+}
+
+// StatusErrno returns the errno for an HTTP status from the backend.
+func StatusErrno(status int) syscall.Errno {
+	statusErrnoMu.RLock()
+	errno, ok := statusErrnos[status]
+	statusErrnoMu.RUnlock()
+	if ok {
+		return errno
+	}
+	return syscall.EIO
+}
+
+// backendErrno translates an error from a backend call into an errno.
+func backendErrno(err error) syscall.Errno {
+	var se *shelley.StatusError
+	if errors.As(err, &se) {
+		return StatusErrno(se.StatusCode)
+	}
+	return syscall.EIO
+}
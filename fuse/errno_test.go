@@ -0,0 +1,71 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+func TestBackendErrno(t *testing.T) {
+	tests := []struct {
+		err  error
+		want syscall.Errno
+	}{
+		{&shelley.StatusError{StatusCode: 404}, syscall.ENOENT},
+		{&shelley.StatusError{StatusCode: 401}, syscall.EACCES},
+		{&shelley.StatusError{StatusCode: 403}, syscall.EACCES},
+		{&shelley.StatusError{StatusCode: 409}, syscall.EEXIST},
+		{&shelley.StatusError{StatusCode: 429}, syscall.EAGAIN},
+		{&shelley.StatusError{StatusCode: 500}, syscall.EIO},
+		{&shelley.StatusError{StatusCode: 503}, syscall.EIO},
+		{&shelley.StatusError{StatusCode: 418}, syscall.EIO},
+		{fmt.Errorf("fetch: %w", &shelley.StatusError{StatusCode: 404}), syscall.ENOENT},
+		{errors.New("connection refused"), syscall.EIO},
+	}
+	for _, tt := range tests {
+		if got := backendErrno(tt.err); got != tt.want {
+			t.Errorf("backendErrno(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestSetStatusErrno(t *testing.T) {
+	defer SetStatusErrno(429, StatusErrno(429))
+	SetStatusErrno(429, syscall.EBUSY)
+	if got := backendErrno(&shelley.StatusError{StatusCode: 429}); got != syscall.EBUSY {
+		t.Errorf("overridden 429 = %v, want EBUSY", got)
+	}
+
+	if errno, err := ParseErrno("etimedout"); err != nil || errno != syscall.ETIMEDOUT {
+		t.Errorf("ParseErrno(etimedout) = %v, %v", errno, err)
+	}
+	if _, err := ParseErrno("ENOPE"); err == nil {
+		t.Error("ParseErrno(ENOPE) should fail")
+	}
+}
+
+func TestModelsDirNode_StatusErrno(t *testing.T) {
+	for status, want := range map[int]syscall.Errno{
+		http.StatusNotFound:            syscall.ENOENT,
+		http.StatusForbidden:           syscall.EACCES,
+		http.StatusTooManyRequests:     syscall.EAGAIN,
+		http.StatusBadGateway:          syscall.EIO,
+		http.StatusInternalServerError: syscall.EIO,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", status)
+		}))
+		node := &ModelsDirNode{client: shelley.NewClient(server.URL), state: testStore(t), startTime: time.Now()}
+		if _, errno := node.Readdir(context.Background()); errno != want {
+			t.Errorf("status %d: Readdir errno = %v, want %v", status, errno, want)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,232 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- EventBus: conversation events for /events and /conversation/.events ---
+// The state store reports adoptions, creations, server-side updates and
+// removals as they happen (see state.Store.SetEventHook). New messages are
+// reported when a parse of a conversation, by a reader or the poller, finds
+// messages newer than the last one seen (see MessagesParsed). The bus keeps
+// the most recent events as JSON lines and wakes up readers waiting for more.
+
+// eventBacklog is how many events the bus keeps for readers that fall behind.
+const eventBacklog = 1024
+
+// EventMessageAppended: a conversation got a message newer than any seen
+// before. Only the bus reports it; the store doesn't know about messages.
+const EventMessageAppended state.EventType = "message_appended"
+
+// messageEvent is a message_appended event: the lifecycle event fields plus
+// the message.
+type messageEvent struct {
+	state.Event
+	MessageID   string `json:"message_id"`
+	SequenceID  int    `json:"sequence_id"`
+	MessageType string `json:"message_type"`
+}
+
+// EventBus fans conversation events out to /events and .events readers.
+type EventBus struct {
+	mu      sync.Mutex
+	lines   [][]byte      // the last eventBacklog events, oldest first
+	next    uint64        // sequence number of the next event
+	arrived chan struct{} // closed and replaced on every event
+
+	start  time.Time      // when the bus was created
+	seenMu sync.Mutex     // guards newest
+	newest map[string]int // newest sequence ID seen, by server conversation ID
+}
+
+// NewEventBus creates an empty event bus.
+func NewEventBus() *EventBus {
+	return &EventBus{arrived: make(chan struct{}), start: time.Now(), newest: make(map[string]int)}
+}
+
+// Publish adds an event and wakes up waiting readers. It is the store's
+// event hook, so it must not call into the store.
+func (b *EventBus) Publish(e state.Event) {
+	b.publish(e)
+}
+
+// MessagesParsed publishes a message_appended event for each of msgs newer
+// than the newest message seen in the conversation so far. The first parse
+// of a conversation only reports messages created after the bus was, so
+// mounting doesn't replay every conversation's history. It looks up the
+// conversation in store, so it must not be called with the store locked.
+func (b *EventBus) MessagesParsed(store *state.Store, conversationID string, msgs []shelley.Message) {
+	if b == nil || len(msgs) == 0 {
+		return
+	}
+	b.seenMu.Lock()
+	newest, seen := b.newest[conversationID]
+	if !seen {
+		newest = -1
+		for i := range msgs {
+			if t, err := time.Parse(time.RFC3339, msgs[i].CreatedAt); (err != nil || t.Before(b.start)) && msgs[i].SequenceID > newest {
+				newest = msgs[i].SequenceID
+			}
+		}
+	}
+	var appended []*shelley.Message
+	top := newest
+	for i := range msgs {
+		if msgs[i].SequenceID > newest {
+			appended = append(appended, &msgs[i])
+		}
+		if msgs[i].SequenceID > top {
+			top = msgs[i].SequenceID
+		}
+	}
+	b.newest[conversationID] = top
+	b.seenMu.Unlock()
+	if len(appended) == 0 {
+		return
+	}
+
+	var backend, localID string
+	for _, name := range store.ListBackends() {
+		if id := store.GetByShelleyIDForBackend(name, conversationID); id != "" {
+			backend, localID = name, id
+			break
+		}
+	}
+	sort.Slice(appended, func(i, j int) bool { return appended[i].SequenceID < appended[j].SequenceID })
+	for _, msg := range appended {
+		t, err := time.Parse(time.RFC3339, msg.CreatedAt)
+		if err != nil {
+			t = time.Now()
+		}
+		b.publish(messageEvent{
+			Event:       state.Event{Type: EventMessageAppended, Backend: backend, LocalID: localID, ShelleyConversationID: conversationID, Time: t},
+			MessageID:   msg.MessageID,
+			SequenceID:  msg.SequenceID,
+			MessageType: msg.Type,
+		})
+	}
+}
+
+// publish adds an event of any shape as a JSON line.
+func (b *EventBus) publish(e any) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+	b.mu.Lock()
+	b.lines = append(b.lines, line)
+	if len(b.lines) > eventBacklog {
+		b.lines = b.lines[len(b.lines)-eventBacklog:]
+	}
+	b.next++
+	close(b.arrived)
+	b.arrived = make(chan struct{})
+	b.mu.Unlock()
+}
+
+// cursor returns the sequence number of the next event to be published.
+func (b *EventBus) cursor() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.next
+}
+
+// wait blocks until events at or after seq exist and returns them along
+// with the sequence number following them. Events that already dropped out
+// of the backlog are skipped.
+func (b *EventBus) wait(ctx context.Context, seq uint64) ([]byte, uint64, syscall.Errno) {
+	for {
+		b.mu.Lock()
+		if seq < b.next {
+			first := b.next - uint64(len(b.lines))
+			if seq < first {
+				seq = first
+			}
+			var out []byte
+			for _, line := range b.lines[seq-first:] {
+				out = append(out, line...)
+			}
+			next := b.next
+			b.mu.Unlock()
+			return out, next, 0
+		}
+		arrived := b.arrived
+		b.mu.Unlock()
+
+		select {
+		case <-arrived:
+		case <-ctx.Done():
+			return nil, seq, syscall.EINTR
+		}
+	}
+}
+
+// --- EventsNode: /events, /conversation/.events ---
+// Reading blocks until the next event and returns one JSON line per event,
+// like `tail -f`: each open starts at the events that happen after it, so
+// `cat /events` follows the stream until interrupted. Both files read the
+// same stream.
+
+type EventsNode struct {
+	fs.Inode
+	bus       *EventBus
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*EventsNode)(nil))
+var _ = (fs.NodeGetattrer)((*EventsNode)(nil))
+
+func (n *EventsNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &eventsHandle{node: n, seq: n.bus.cursor()}, fuse.FOPEN_DIRECT_IO | fuse.FOPEN_NONSEEKABLE, 0
+}
+
+func (n *EventsNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0444
+	setTimestamps(&out.Attr, n.startTime)
+	return 0
+}
+
+// eventsHandle is one reader's position in the event stream. Lines that
+// didn't fit the caller's buffer are kept for the next read.
+type eventsHandle struct {
+	node    *EventsNode
+	mu      sync.Mutex
+	seq     uint64
+	pending []byte
+}
+
+var _ = (fs.FileReader)((*eventsHandle)(nil))
+
+func (h *eventsHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.pending) == 0 {
+		op := diag.Track(h.node.diag, "EventsNode", "Read", "")
+		op.SetPhase("waiting for events")
+		data, next, errno := h.node.bus.wait(ctx, h.seq)
+		op.Done()
+		if errno != 0 {
+			return nil, errno
+		}
+		h.pending, h.seq = data, next
+	}
+	n := copy(dest, h.pending)
+	h.pending = h.pending[n:]
+	return fuse.ReadResultData(dest[:n]), 0
+}
@@ -0,0 +1,136 @@
+package fuse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestConversationEvents(t *testing.T) {
+	server := mockConversationsServer(t, nil)
+	defer server.Close()
+	store := testStore(t)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	// Events from before the open are not replayed.
+	store.Adopt("before-open")
+
+	f, err := os.Open(filepath.Join(mountPoint, "conversation", ".events"))
+	if err != nil {
+		t.Fatalf("open .events: %v", err)
+	}
+	// Read exactly the events the test expects: a read(2) still blocked
+	// waiting for more would keep Close and the unmount waiting too.
+	const want = 2
+	lines := make(chan string, want)
+	go func() {
+		r := bufio.NewReader(f)
+		for i := 0; i < want; i++ {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- line
+		}
+	}()
+	next := func() state.Event {
+		t.Helper()
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal(".events closed")
+			}
+			var e state.Event
+			if err := json.Unmarshal([]byte(line), &e); err != nil {
+				t.Fatalf("bad event line %q: %v", line, err)
+			}
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+		}
+		return state.Event{}
+	}
+
+	localID, _ := store.Adopt("server-1")
+	if e := next(); e.Type != state.EventAdopted || e.LocalID != localID || e.ShelleyConversationID != "server-1" {
+		t.Errorf("first event = %+v, want adopted %s", e, localID)
+	}
+	store.ForceDelete(localID)
+	if e := next(); e.Type != state.EventRemoved || e.LocalID != localID {
+		t.Errorf("second event = %+v, want removed %s", e, localID)
+	}
+	f.Close()
+}
+
+func TestEventBusBacklog(t *testing.T) {
+	bus := NewEventBus()
+	for i := 0; i < eventBacklog+10; i++ {
+		bus.Publish(state.Event{Type: state.EventAdopted, LocalID: "x"})
+	}
+	// A reader from the start only gets what is still in the backlog.
+	data, next, errno := bus.wait(t.Context(), 0)
+	if errno != 0 {
+		t.Fatalf("wait: %v", errno)
+	}
+	if next != eventBacklog+10 {
+		t.Errorf("next = %d, want %d", next, eventBacklog+10)
+	}
+	if n := len(splitLines(data)); n != eventBacklog {
+		t.Errorf("got %d events, want the %d in the backlog", n, eventBacklog)
+	}
+}
+
+func splitLines(data []byte) []string {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	return lines
+}
+
+func TestMessageAppendedEvents(t *testing.T) {
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-ev")
+	bus := NewEventBus()
+	old := bus.start.Add(-time.Hour).Format(time.RFC3339)
+	recent := bus.start.Add(time.Second).Format(time.RFC3339)
+	msgs := []shelley.Message{
+		{MessageID: "m1", SequenceID: 1, Type: "user", CreatedAt: old},
+		{MessageID: "m2", SequenceID: 2, Type: "shelley", CreatedAt: recent},
+	}
+
+	// The first parse reports only what arrived after the bus started.
+	bus.MessagesParsed(store, "conv-ev", msgs)
+	// A parse finding nothing new reports nothing.
+	bus.MessagesParsed(store, "conv-ev", msgs)
+	msgs = append(msgs, shelley.Message{MessageID: "m3", SequenceID: 3, Type: "user", CreatedAt: recent})
+	bus.MessagesParsed(store, "conv-ev", msgs)
+
+	data, _, errno := bus.wait(t.Context(), 0)
+	if errno != 0 {
+		t.Fatalf("wait: %v", errno)
+	}
+	lines := splitLines(data)
+	if len(lines) != 2 {
+		t.Fatalf("events = %q, want m2 and m3", lines)
+	}
+	for i, want := range []string{"m2", "m3"} {
+		var e messageEvent
+		if err := json.Unmarshal([]byte(lines[i]), &e); err != nil {
+			t.Fatalf("bad event line %q: %v", lines[i], err)
+		}
+		if e.Type != EventMessageAppended || e.MessageID != want || e.LocalID != localID || e.Backend != state.DefaultBackendName || e.ShelleyConversationID != "conv-ev" {
+			t.Errorf("event %d = %+v, want %s appended to %s", i, e, want, localID)
+		}
+	}
+}
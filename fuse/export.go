@@ -0,0 +1,162 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// --- Export compatibility: re-exporting the mount over NFS or SMB ---
+// knfsd and Samba hand the files of a mount to clients that can't tell it
+// is FUSE, and those clients trust what stat says more than local tools do.
+// With SetExportCompat the mount behaves the way they expect:
+//
+//   - Inode numbers are stable. go-fuse numbers nodes without an explicit
+//     inode in the order they are first looked up, so the same file gets a
+//     different number after a remount, and NFS clients treat it as a new
+//     file. exportRawFS replaces those numbers with a hash of the path.
+//   - Entries carry a generation number that changes with every mount, so a
+//     file handle from before a restart fails with ESTALE instead of
+//     resolving to whatever reuses its node ID.
+//   - Rendered files (all.md, content.md, count, ...) report their real
+//     size. Normally they report 0 and rely on direct I/O to be read, which
+//     NFS clients don't honour: they read nothing past the size they saw.
+//   - READDIRPLUS is off, so every entry goes through a lookup and gets the
+//     inode and generation above.
+//
+// Files whose open has an effect (clone, continue, wait_ready, .events)
+// still report 0, as rendering them to measure the size would trigger it.
+
+// autoInoBase is where go-fuse starts numbering nodes that were added
+// without an inode number (fs.Options.FirstAutomaticIno is left unset). It
+// counts up from there, so numbers a little above it are automatic; the
+// hashes stableIno gives are spread over the whole range and practically
+// never land among them.
+const autoInoBase = 1 << 63
+
+// isAutoIno reports whether go-fuse picked ino.
+func isAutoIno(ino uint64) bool {
+	return ino >= autoInoBase && ino-autoInoBase < 1<<40
+}
+
+// exportRawFS wraps the raw protocol handler of a mount set up with
+// SetExportCompat. It relies on the node names a trackingRawFS beneath it
+// has recorded.
+type exportRawFS struct {
+	fuse.RawFileSystem
+	names      *HandleTracker
+	generation uint64
+}
+
+func newExportRawFS(raw fuse.RawFileSystem, names *HandleTracker) *exportRawFS {
+	return &exportRawFS{RawFileSystem: raw, names: names, generation: uint64(time.Now().Unix())}
+}
+
+// ino returns the inode number to report for nodeID: ino itself, or, if
+// go-fuse picked it, one derived from the node's path.
+func (r *exportRawFS) ino(nodeID, ino uint64) uint64 {
+	if !isAutoIno(ino) {
+		return ino
+	}
+	p, ok := r.names.path(nodeID)
+	if !ok {
+		return ino
+	}
+	// Kept below autoInoBase so it can't be taken for an automatic one.
+	return stableIno("export", p) &^ autoInoBase
+}
+
+func (r *exportRawFS) entry(out *fuse.EntryOut) {
+	out.Generation = r.generation
+	out.Attr.Ino = r.ino(out.NodeId, out.Attr.Ino)
+}
+
+func (r *exportRawFS) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Lookup(cancel, header, name, out)
+	if status.Ok() {
+		r.entry(out)
+	}
+	return status
+}
+
+func (r *exportRawFS) Mkdir(cancel <-chan struct{}, input *fuse.MkdirIn, name string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Mkdir(cancel, input, name, out)
+	if status.Ok() {
+		r.entry(out)
+	}
+	return status
+}
+
+func (r *exportRawFS) Mknod(cancel <-chan struct{}, input *fuse.MknodIn, name string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Mknod(cancel, input, name, out)
+	if status.Ok() {
+		r.entry(out)
+	}
+	return status
+}
+
+func (r *exportRawFS) Symlink(cancel <-chan struct{}, header *fuse.InHeader, pointedTo string, linkName string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Symlink(cancel, header, pointedTo, linkName, out)
+	if status.Ok() {
+		r.entry(out)
+	}
+	return status
+}
+
+func (r *exportRawFS) Create(cancel <-chan struct{}, input *fuse.CreateIn, name string, out *fuse.CreateOut) fuse.Status {
+	status := r.RawFileSystem.Create(cancel, input, name, out)
+	if status.Ok() {
+		r.entry(&out.EntryOut)
+	}
+	return status
+}
+
+func (r *exportRawFS) GetAttr(cancel <-chan struct{}, input *fuse.GetAttrIn, out *fuse.AttrOut) fuse.Status {
+	status := r.RawFileSystem.GetAttr(cancel, input, out)
+	if status.Ok() {
+		out.Ino = r.ino(input.NodeId, out.Ino)
+	}
+	return status
+}
+
+func (r *exportRawFS) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *fuse.AttrOut) fuse.Status {
+	status := r.RawFileSystem.SetAttr(cancel, input, out)
+	if status.Ok() {
+		out.Ino = r.ino(input.NodeId, out.Ino)
+	}
+	return status
+}
+
+// exportCompat reports whether n belongs to a mount set up with
+// SetExportCompat.
+func exportCompat(n *fs.Inode) bool {
+	root := rootFS(n)
+	return root != nil && root.exportCompat
+}
+
+// openedSize sets out.Size to the size of the content n renders on open,
+// for nodes that otherwise report 0 until opened. n's Open must have no
+// side effects.
+func openedSize(ctx context.Context, n fs.NodeOpener, out *fuse.AttrOut) {
+	fh, _, errno := n.Open(ctx, syscall.O_RDONLY)
+	if errno != 0 {
+		return
+	}
+	if r, ok := fh.(fs.FileReleaser); ok {
+		defer r.Release(ctx)
+	}
+	if h, ok := fh.(*ConvContentFileHandle); ok && h.errno != 0 {
+		return
+	}
+	fga, ok := fh.(fs.FileGetattrer)
+	if !ok {
+		return
+	}
+	var attr fuse.AttrOut
+	if fga.Getattr(ctx, &attr) == 0 {
+		out.Size = attr.Size
+	}
+}
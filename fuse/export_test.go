@@ -0,0 +1,77 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestExportCompat(t *testing.T) {
+	hello := "Hello"
+	server := mockserver.New(mockserver.WithConversation("conv-export", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-export", SequenceID: 1, Type: "user", UserData: &hello},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-export", "")
+	allMD := filepath.Join("conversation", localID, "messages", "all.md")
+
+	// stat mounts a fresh FS over the same store, as a restart would, and
+	// returns what stat says about all.md and what reading it gives.
+	stat := func(compat bool) (*syscall.Stat_t, []byte) {
+		t.Helper()
+		shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+		shelleyFS.SetExportCompat(compat)
+		mountPoint := mountWithMount(t, shelleyFS)
+		fi, err := os.Stat(filepath.Join(mountPoint, allMD))
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		data, err := os.ReadFile(filepath.Join(mountPoint, allMD))
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		return fi.Sys().(*syscall.Stat_t), data
+	}
+
+	if plain, _ := stat(false); plain.Size != 0 || !isAutoIno(plain.Ino) {
+		t.Fatalf("without export compat all.md has size %d and inode %d; the test expects 0 and an automatic one", plain.Size, plain.Ino)
+	}
+	first, data := stat(true)
+	if first.Size != int64(len(data)) || len(data) == 0 {
+		t.Errorf("all.md size = %d, want %d", first.Size, len(data))
+	}
+	if isAutoIno(first.Ino) {
+		t.Errorf("all.md has go-fuse's automatic inode %d", first.Ino)
+	}
+	if second, _ := stat(true); second.Ino != first.Ino {
+		t.Errorf("inode changed across mounts: %d, then %d", first.Ino, second.Ino)
+	}
+}
+
+// mountWithMount mounts shelleyFS with Mount rather than fs.Mount, so the
+// raw filesystem wrappers are in place, and unmounts it when t ends.
+func mountWithMount(t *testing.T, shelleyFS *FS) string {
+	t.Helper()
+	mountPoint, err := os.MkdirTemp("", "shelley-fuse-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	zero := time.Duration(0)
+	srv, err := Mount(mountPoint, shelleyFS, &fs.Options{EntryTimeout: &zero, AttrTimeout: &zero, NegativeTimeout: &zero})
+	if err != nil {
+		os.RemoveAll(mountPoint)
+		t.Fatalf("Mount failed: %v", err)
+	}
+	t.Cleanup(func() {
+		srv.Unmount()
+		os.RemoveAll(mountPoint)
+	})
+	return mountPoint
+}
@@ -0,0 +1,74 @@
+package fuse
+
+import (
+	"encoding/json"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// --- Conversation metadata fields ---
+// Besides its slug, model and times, a backend may report more about a
+// conversation in its JSON: labels, a locale, an owner. Each entry of
+// conversationFields shows one such field in the conversation directory,
+// through jsonfs like the other JSON fields: a string or number as a file, a
+// list or object as a directory. The entry is there only while the backend
+// reports a non-empty value. Exposing another field is one more line in the
+// table.
+
+// conversationField maps a field of the backend's conversation JSON to a
+// name in the conversation directory.
+type conversationField struct {
+	name string   // in the conversation directory
+	keys []string // in the conversation JSON; the first one present wins
+}
+
+var conversationFields = []conversationField{
+	{name: "labels", keys: []string{"labels", "tags"}},
+	{name: "locale", keys: []string{"locale", "language"}},
+	{name: "owner", keys: []string{"owner", "user_email"}},
+}
+
+// conversationFieldValues returns the values of conversationFields in the
+// conversation detail JSON, by name in the conversation directory.
+func conversationFieldValues(detail []byte) map[string]any {
+	var raw map[string]any
+	if json.Unmarshal(detail, &raw) != nil {
+		return nil
+	}
+	values := make(map[string]any)
+	for _, f := range conversationFields {
+		for _, key := range f.keys {
+			if v, ok := raw[key]; ok && !emptyJSON(v) {
+				values[f.name] = v
+				break
+			}
+		}
+	}
+	return values
+}
+
+// emptyJSON reports whether v, as unmarshaled from JSON, holds nothing
+// worth a file: null, "", [] or {}.
+func emptyJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// jsonMode returns the mode jsonfs gives v: a directory for a list or an
+// object, otherwise a file.
+func jsonMode(v any) uint32 {
+	switch v.(type) {
+	case map[string]any, []any:
+		return fuse.S_IFDIR
+	}
+	return fuse.S_IFREG
+}
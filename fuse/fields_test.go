@@ -0,0 +1,71 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestConversationFieldValues(t *testing.T) {
+	values := conversationFieldValues([]byte(`{"labels":["bug","ci"],"language":"de-DE","owner":"","user_email":null,"messages":[]}`))
+	if labels, ok := values["labels"].([]any); !ok || len(labels) != 2 {
+		t.Errorf("labels = %v", values["labels"])
+	}
+	// A later key stands in for an absent one.
+	if values["locale"] != "de-DE" {
+		t.Errorf("locale = %v, want de-DE", values["locale"])
+	}
+	if _, ok := values["owner"]; ok {
+		t.Errorf("empty owner reported as %v", values["owner"])
+	}
+
+	for _, f := range conversationFields {
+		if _, ok := conversationNames[f.name]; ok {
+			t.Errorf("field %s shadows a fixed conversation name", f.name)
+		}
+	}
+}
+
+func TestConversationFields(t *testing.T) {
+	server := mockserver.New(mockserver.WithConversationRawDetail(
+		shelley.Conversation{ConversationID: "conv-fields"},
+		[]byte(`{"conversation_id":"conv-fields","locale":"fr-FR","labels":["triage"],"messages":[]}`),
+	))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, "conv-fields", "")
+	node := &ConversationNode{localID: localID, client: shelley.NewClient(server.URL), state: store, startTime: time.Now()}
+	fs.NewNodeFS(node, &fs.Options{})
+	ctx := context.Background()
+
+	stream, errno := node.Readdir(ctx)
+	if errno != 0 {
+		t.Fatalf("Readdir: %v", errno)
+	}
+	modes := make(map[string]uint32)
+	for stream.HasNext() {
+		e, _ := stream.Next()
+		modes[e.Name] = e.Mode
+	}
+	if modes["locale"] != fuse.S_IFREG || modes["labels"] != fuse.S_IFDIR {
+		t.Errorf("locale/labels listed with modes %o/%o", modes["locale"], modes["labels"])
+	}
+	if _, ok := modes["owner"]; ok {
+		t.Error("owner listed without the backend reporting one")
+	}
+
+	var out fuse.EntryOut
+	if in, errno := node.Lookup(ctx, "labels", &out); errno != 0 || in.Mode() != fuse.S_IFDIR {
+		t.Errorf("Lookup(labels) = %v, %v", in, errno)
+	}
+	if _, errno := node.Lookup(ctx, "owner", &out); errno != syscall.ENOENT {
+		t.Errorf("Lookup(owner) = %v, want ENOENT", errno)
+	}
+}
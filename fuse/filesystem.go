@@ -5,6 +5,8 @@ import (
 	_ "embed"
 	"fmt"
 	"hash/fnv"
+	"io"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -18,28 +20,76 @@ import (
 	"shelley-fuse/state"
 )
 
-// Kernel cache timeout tiers for entry and attr caching.
-// These override the global 0 timeout set in mount options.
-// Per-node timeouts set via EntryOut.SetEntryTimeout/SetAttrTimeout (in Lookup)
-// and AttrOut.SetTimeout (in Getattr) take precedence over the global defaults.
-const (
-	// cacheTTLImmutable is for nodes whose content never changes once created:
+// CacheTimeouts are the kernel entry and attr cache timeouts of each tier of
+// node. They override the global 0 timeout set in mount options; nodes in no
+// tier (send, working, status files, ...) keep that 0 and are asked about on
+// every access. Per-node timeouts set via EntryOut.SetEntryTimeout/
+// SetAttrTimeout (in Lookup) and AttrOut.SetTimeout (in Getattr) take
+// precedence over the global defaults.
+type CacheTimeouts struct {
+	// Immutable is for nodes whose content never changes once created:
 	// message field nodes, message directories, jsonfs subtrees.
-	cacheTTLImmutable = 1 * time.Hour
+	Immutable time.Duration
 
-	// cacheTTLStatic is for nodes that essentially never change:
+	// Static is for nodes that essentially never change:
 	// FS root, ReadmeNode, /new symlink.
-	cacheTTLStatic = 1 * time.Hour
+	Static time.Duration
 
-	// cacheTTLModels is for model-related nodes that change very rarely:
+	// Models is for model-related nodes that change very rarely:
 	// ModelsDirNode, ModelNode, ModelFieldNode, ModelReadyNode, ModelNewDirNode, ModelCloneNode.
-	cacheTTLModels = 5 * time.Minute
+	Models time.Duration
 
-	// cacheTTLConversation is for conversation structural nodes that change
+	// Conversation is for conversation structural nodes that change
 	// infrequently (new messages, archive status changes):
 	// ConversationNode, ConversationListNode.
-	cacheTTLConversation = 10 * time.Second
+	Conversation time.Duration
+}
+
+// DefaultCacheTimeouts returns the cache timeouts used unless
+// SetCacheTimeouts says otherwise.
+func DefaultCacheTimeouts() CacheTimeouts {
+	return CacheTimeouts{
+		Immutable:    1 * time.Hour,
+		Static:       1 * time.Hour,
+		Models:       5 * time.Minute,
+		Conversation: 10 * time.Second,
+	}
+}
+
+// CacheTiers are the tier names CacheTimeouts.Set accepts.
+var CacheTiers = []string{"immutable", "static", "models", "conversation"}
+
+// Set sets the timeout of the named tier, one of CacheTiers.
+func (t *CacheTimeouts) Set(tier string, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("negative cache timeout %v for tier %s", d, tier)
+	}
+	switch tier {
+	case "immutable":
+		t.Immutable = d
+	case "static":
+		t.Static = d
+	case "models":
+		t.Models = d
+	case "conversation":
+		t.Conversation = d
+	default:
+		return fmt.Errorf("unknown cache tier %q (want one of %s)", tier, strings.Join(CacheTiers, ", "))
+	}
+	return nil
+}
+
+// cacheTimeouts returns the cache timeouts of the mount n belongs to, or the
+// defaults if n isn't attached to one (as in tests that build nodes directly).
+func cacheTimeouts(n *fs.Inode) CacheTimeouts {
+	if root := rootFS(n); root != nil && root.kernelTTL != nil {
+		return *root.kernelTTL
+	}
+	return DefaultCacheTimeouts()
+}
 
+// Fixed kernel cache timeouts of entries that come and go.
+const (
 	// negTimeout is the negative-entry timeout for dynamic presence files
 	// that may appear (e.g., "created" before backend creation, "model" before ctl write).
 	// Short enough to notice state changes promptly.
@@ -47,7 +97,7 @@ const (
 
 	// immutableEntryTimeout is the positive-entry timeout for files that,
 	// once they exist, never disappear or change identity (e.g., "created",
-	// "model" symlink, "cwd" symlink).
+	// "cwd" symlink).
 	immutableEntryTimeout = 1 * time.Hour
 
 	// volatileEntryTimeout is the positive-entry timeout for files whose
@@ -57,12 +107,30 @@ const (
 
 // setEntryTimeout sets the entry (name→inode) cache timeout on an EntryOut (used in Lookup).
 // This controls how long the kernel caches that a name exists in a directory.
-// Note: we intentionally do NOT set AttrTimeout here because our Lookup methods
+// Note: we intentionally do NOT set AttrTimeout here because most Lookup methods
 // don't populate out.Attr — attribute caching is handled by Getattr via SetTimeout.
+// Lookups that do populate it use fillEntryAttr.
 func setEntryTimeout(out *fuse.EntryOut, ttl time.Duration) {
 	out.SetEntryTimeout(ttl)
 }
 
+// fillEntryAttr populates out.Attr from the child's Getattr and lets the
+// kernel cache it for ttl. go-fuse answers READDIRPLUS by calling Lookup for
+// every entry, so a Lookup that fills attrs spares `ls -l` one GETATTR per
+// entry. Only use it where Getattr is cheap (no backend request).
+func fillEntryAttr(ctx context.Context, child *fs.Inode, out *fuse.EntryOut, ttl time.Duration) {
+	ga, ok := child.Operations().(fs.NodeGetattrer)
+	if !ok {
+		return
+	}
+	var attr fuse.AttrOut
+	if ga.Getattr(ctx, nil, &attr) != 0 {
+		return
+	}
+	out.Attr = attr.Attr
+	out.SetAttrTimeout(ttl)
+}
+
 // ParsedMessageCache caches parsed messages and toolMaps, keyed by conversation ID.
 // The cache is content-addressed: it stores a checksum of the raw data and only
 // returns the cached result if the raw data hasn't changed. This ensures that
@@ -81,16 +149,80 @@ var _ = (fs.NodeReadlinker)((*SymlinkNode)(nil))
 var _ = (fs.NodeGetattrer)((*SymlinkNode)(nil))
 
 func (s *SymlinkNode) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
-	return []byte(s.target), 0
+	return []byte(linkTarget(&s.Inode, s.target)), 0
 }
 
 func (s *SymlinkNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = syscall.S_IFLNK | 0777
-	out.Size = uint64(len(s.target))
+	out.Size = uint64(len(linkTarget(&s.Inode, s.target)))
 	setTimestamps(&out.Attr, s.startTime)
 	return 0
 }
 
+// linkTarget returns the target the symlink n presents: target itself or,
+// when the mount was set up with SetAbsoluteSymlinks, target resolved from
+// n's directory to an absolute path under the mount point.
+func linkTarget(n *fs.Inode, target string) string {
+	if filepath.IsAbs(target) {
+		return target
+	}
+	root := rootFS(n)
+	if root == nil || root.absLinks == "" {
+		return target
+	}
+	return filepath.Join(root.absLinks, filepath.Dir(n.Path(nil)), target)
+}
+
+// rootFS returns the FS at the top of the tree n is in, or nil if n isn't
+// attached to one (as in tests that build nodes directly).
+func rootFS(n *fs.Inode) *FS {
+	for {
+		_, parent := n.Parent()
+		if parent == nil {
+			break
+		}
+		n = parent
+	}
+	root, _ := n.Operations().(*FS)
+	return root
+}
+
+// --- listingDirHandle: a directory listing with its own READDIRPLUS lookups ---
+// Returned from OpendirHandle by directories that can resolve their entries
+// more cheaply from the listing just built than through their Lookup.
+
+type listingDirHandle struct {
+	entries []fuse.DirEntry
+	idx     int
+	lookup  func(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno)
+}
+
+var _ = (fs.FileReaddirenter)((*listingDirHandle)(nil))
+var _ = (fs.FileSeekdirer)((*listingDirHandle)(nil))
+var _ = (fs.FileLookuper)((*listingDirHandle)(nil))
+
+func (h *listingDirHandle) Readdirent(ctx context.Context) (*fuse.DirEntry, syscall.Errno) {
+	if h.idx >= len(h.entries) {
+		return nil, 0
+	}
+	e := h.entries[h.idx]
+	h.idx++
+	e.Off = uint64(h.idx)
+	return &e, 0
+}
+
+func (h *listingDirHandle) Seekdir(ctx context.Context, off uint64) syscall.Errno {
+	if off > uint64(len(h.entries)) {
+		return syscall.EINVAL
+	}
+	h.idx = int(off)
+	return 0
+}
+
+func (h *listingDirHandle) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	return h.lookup(ctx, name, out)
+}
+
 
 type FS struct {
 	fs.Inode
@@ -98,50 +230,270 @@ type FS struct {
 	clientMgr    *shelley.ClientManager // manager for multiple backend clients (optional)
 	state        *state.Store
 	cloneTimeout time.Duration
+	cloneByModel map[string]time.Duration
+	modelAliases map[string]string
 	startTime    time.Time
-	parsedCache  *ParsedMessageCache // caches parsed messages and toolMaps
-	Diag         *diag.Tracker       // tracks in-flight FUSE I/O operations
+	parsedCache  *ParsedMessageCache  // caches parsed messages and toolMaps
+	Diag         *diag.Tracker        // tracks in-flight FUSE I/O operations
+	Handles      *HandleTracker       // tracks open handles (when mounted with Mount)
+	layout       Layout               // how /conversation names conversation directories
+	mdChunkSize  int                  // size above which all.md is also split into all.md.d/ (0 = never)
+	sparseMsgs   bool                 // leave message directories out of messages/ listings
+	maxSend      int                  // largest message send accepts, in bytes (0 = no limit)
+	cacheBudget  *shelley.CacheBudget // size limit across caches, reported by Statfs (optional)
+	readyTimeout time.Duration        // how long model/{id}/wait_ready blocks (0 = default)
+	events       *EventBus            // lifecycle events from the store, for /conversation/.events
+	activity     *ActivityBoard       // what the poller last saw, for /conversation/.activity.json
+	usage        *UsageBoard          // token usage per conversation, for /conversation/top/usage
+	budget       Budget               // caps every conversation's usage, on top of its own ctl budget
+	filter       *ConversationFilter  // which server conversations are adopted and listed (nil = all)
+	sends        *RecentSends         // recent messages per conversation, to drop duplicate sends
+	snapshots    *Snapshots           // conversation snapshots taken with mkdir, in memory
+	absLinks     string               // mount point symlink targets are made absolute under ("" = relative)
+	exportCompat bool                 // behave for re-export over NFS or SMB (see exportRawFS)
+	titleLinks   bool                 // list /conversation/by-title
+	hooks        Hooks                // commands run on conversation events (see hooks.go)
+	accessLog    io.Writer            // where reads of conversations are logged (nil = nowhere; see access.go)
+	kernelTTL    *CacheTimeouts       // kernel cache timeouts per tier (nil = DefaultCacheTimeouts)
+}
+
+// Layout selects how /conversation names conversation directories.
+type Layout int
+
+const (
+	// LayoutIDs lists conversations as local-ID directories, with server IDs
+	// and slugs as symlinks. This is the default.
+	LayoutIDs Layout = iota
+	// LayoutSlugs lists conversations as slug directories, with local and
+	// server IDs as symlinks. Conversations without a slug keep their local ID.
+	LayoutSlugs
+)
+
+// ParseLayout parses a -layout flag value ("ids" or "slugs").
+func ParseLayout(s string) (Layout, error) {
+	switch s {
+	case "", "ids":
+		return LayoutIDs, nil
+	case "slugs":
+		return LayoutSlugs, nil
+	}
+	return LayoutIDs, fmt.Errorf("unknown layout %q (want ids or slugs)", s)
+}
+
+// SetConversationFilter limits the server conversations /conversation
+// adopts and lists to those f accepts. It must be called before mounting.
+func (f *FS) SetConversationFilter(filter *ConversationFilter) {
+	f.filter = filter
+}
+
+// SetLayout selects how /conversation names conversation directories.
+// It must be called before mounting.
+func (f *FS) SetLayout(l Layout) {
+	f.layout = l
+}
+
+// SetModelCloneTimeouts overrides the clone timeout for clones whose ctl
+// model is one of the given models (display name or model ID), so clones of
+// quick throwaway models can expire sooner than long-running sessions.
+// A zero duration keeps that model's clones forever.
+// It must be called before mounting.
+func (f *FS) SetModelCloneTimeouts(timeouts map[string]time.Duration) {
+	f.cloneByModel = timeouts
+}
+
+// SetModelAliases adds symlinks to /model/ (and each backend's model/)
+// named by the keys of aliases, pointing at the model each value names
+// (display name or model ID), so scripts can refer to a role like "fast"
+// and survive backend model renames. Aliases whose model doesn't exist, or
+// that collide with a model's name or ID, are left out.
+// It must be called before mounting.
+func (f *FS) SetModelAliases(aliases map[string]string) {
+	f.modelAliases = aliases
+}
+
+// SetMarkdownChunkSize makes messages/all.md.d/ available for conversations
+// whose all.md is larger than size bytes, holding the same markdown split
+// into parts of at most size bytes. Zero (the default) disables it.
+// It must be called before mounting.
+func (f *FS) SetMarkdownChunkSize(size int) {
+	f.mdChunkSize = size
+}
+
+// SetSparseMessages leaves the per-message directories (0-user/, 1-agent/,
+// ...) out of messages/ listings, keeping all.json, all.md and the query
+// directories. The directories can still be opened by name. Tools that walk
+// the whole mount otherwise visit every message of every conversation.
+// It must be called before mounting.
+func (f *FS) SetSparseMessages(sparse bool) {
+	f.sparseMsgs = sparse
+}
+
+// SetAbsoluteSymlinks makes every symlink in the mount that points inside it
+// (model/default, conversation/{slug}, messages/last/{N}/0, ...) read as an
+// absolute path under mountpoint instead of a relative one, for editors and
+// re-exports such as Samba that resolve relative targets wrongly. mountpoint
+// must be absolute. It must be called before mounting.
+func (f *FS) SetAbsoluteSymlinks(mountpoint string) {
+	f.absLinks = mountpoint
+}
+
+// SetExportCompat prepares the mount to be re-exported over NFS or SMB:
+// inode numbers stay the same across remounts, entries carry a generation
+// number, and rendered files report their real size, at the cost of
+// rendering them on stat. It takes effect only when mounted with Mount.
+// It must be called before mounting.
+func (f *FS) SetExportCompat(compat bool) {
+	f.exportCompat = compat
+}
+
+// SetTitleLinks adds /conversation/by-title/, with a symlink per created
+// conversation named after its title (see the title file). Listing it
+// fetches every conversation. It must be called before mounting.
+func (f *FS) SetTitleLinks(enabled bool) {
+	f.titleLinks = enabled
+}
+
+// SetHooks sets commands to run when a conversation is created, gets a new
+// message, or has a request fail. It takes effect only when mounted with
+// Mount. It must be called before mounting.
+func (f *FS) SetHooks(h Hooks) {
+	f.hooks = h
+}
+
+// SetAccessLog logs every open of a conversation's files for reading to w,
+// one JSON line each, with the user and process that opened it (see
+// access.go). It takes effect only when mounted with Mount. It must be
+// called before mounting.
+func (f *FS) SetAccessLog(w io.Writer) {
+	f.accessLog = w
+}
+
+// SetBudget caps the tokens and cost of every conversation: once one has
+// used that much, writes to its send fail with EDQUOT. A conversation's
+// own ctl budget_tokens= and budget_usd= apply as well. The zero Budget
+// (the default) caps nothing. It must be called before mounting.
+func (f *FS) SetBudget(b Budget) {
+	f.budget = b
+}
+
+// SetMaxSendSize limits messages written to send to size bytes. A write
+// that would go over fails with EFBIG, the message is not sent, and the
+// rejection is noted in the conversation's errors.log. Zero (the default)
+// means no limit.
+// It must be called before mounting.
+func (f *FS) SetMaxSendSize(size int) {
+	f.maxSend = size
+}
+
+// SetModelReadyTimeout sets how long reading model/{id}/wait_ready waits for
+// the model to become ready before failing with ETIMEDOUT. Zero selects
+// DefaultModelReadyTimeout. It must be called before mounting.
+func (f *FS) SetModelReadyTimeout(d time.Duration) {
+	f.readyTimeout = d
+}
+
+// SetCacheTimeouts sets how long the kernel may cache names and attributes
+// of each tier of node (see CacheTimeouts). Longer immutable timeouts save
+// a round trip per message file on big conversations; zero timeouts make
+// every access reach the filesystem. It must be called before mounting.
+func (f *FS) SetCacheTimeouts(t CacheTimeouts) {
+	f.kernelTTL = &t
+}
+
+// SetCacheBudget makes the parsed message cache count against b and reports
+// b's usage through statfs on the mount point. The backend clients should
+// share the same budget (see shelley.ClientManager.SetCacheBudget).
+// It must be called before mounting.
+func (f *FS) SetCacheBudget(b *shelley.CacheBudget) {
+	f.cacheBudget = b
+	f.parsedCache.SetBudget(b)
 }
 
 // NewFS creates a new Shelley FUSE filesystem.
 // cloneTimeout specifies how long to wait before cleaning up unconversed clone IDs.
 func NewFS(client shelley.ShelleyClient, store *state.Store, cloneTimeout time.Duration) *FS {
+	usage := NewUsageBoard()
+	events := newStoreEventBus(store)
 	return &FS{
 		client:       client,
 		state:        store,
 		cloneTimeout: cloneTimeout,
 		startTime:    time.Now(),
-		parsedCache:  NewParsedMessageCache(),
+		parsedCache:  newStoreParsedCache(store, usage, events),
 		Diag:         diag.NewTracker(),
+		Handles:      NewHandleTracker(),
+		events:       events,
+		activity:     NewActivityBoard(),
+		usage:        usage,
+		sends:        NewRecentSends(),
+		snapshots:    NewSnapshots(),
 	}
 }
 
 // NewFSWithBackends creates a new Shelley FUSE filesystem with backend support.
 // Takes a ClientManager for multi-backend operations and cloneTimeout.
 func NewFSWithBackends(clientMgr *shelley.ClientManager, store *state.Store, cloneTimeout time.Duration) *FS {
+	usage := NewUsageBoard()
+	events := newStoreEventBus(store)
 	return &FS{
 		client:       nil, // no default client - use ClientManager
 		clientMgr:    clientMgr,
 		state:        store,
 		cloneTimeout: cloneTimeout,
 		startTime:    time.Now(),
-		parsedCache:  NewParsedMessageCache(),
+		parsedCache:  newStoreParsedCache(store, usage, events),
 		Diag:         diag.NewTracker(),
+		Handles:      NewHandleTracker(),
+		events:       events,
+		activity:     NewActivityBoard(),
+		usage:        usage,
+		sends:        NewRecentSends(),
+		snapshots:    NewSnapshots(),
 	}
 }
 
 // NewFSWithCacheTTL creates a new Shelley FUSE filesystem with a custom cache TTL.
 func NewFSWithCacheTTL(client shelley.ShelleyClient, store *state.Store, cloneTimeout, cacheTTL time.Duration) *FS {
+	usage := NewUsageBoard()
+	events := newStoreEventBus(store)
 	return &FS{
 		client:       client,
 		state:        store,
 		cloneTimeout: cloneTimeout,
 		startTime:    time.Now(),
-		parsedCache:  NewParsedMessageCache(),
+		parsedCache:  newStoreParsedCache(store, usage, events),
 		Diag:         diag.NewTracker(),
+		Handles:      NewHandleTracker(),
+		events:       events,
+		activity:     NewActivityBoard(),
+		usage:        usage,
+		sends:        NewRecentSends(),
+		snapshots:    NewSnapshots(),
 	}
 }
 
+// newStoreParsedCache creates a parse cache that moves a conversation's
+// updated_at in store forward when new messages are parsed, records their
+// usage on usage and publishes them on events.
+func newStoreParsedCache(store *state.Store, usage *UsageBoard, events *EventBus) *ParsedMessageCache {
+	c := NewParsedMessageCache()
+	c.SetParseHook(func(conversationID string, msgs []shelley.Message) {
+		if newest := newestMessage(msgs); newest != nil {
+			store.NoteMessageTime(conversationID, newest.CreatedAt)
+		}
+		usage.Record(conversationID, msgs)
+		events.MessagesParsed(store, conversationID, msgs)
+	})
+	return c
+}
+
+// newStoreEventBus creates an event bus fed by store's lifecycle events.
+func newStoreEventBus(store *state.Store) *EventBus {
+	bus := NewEventBus()
+	store.SetEventHook(bus.Publish)
+	return bus
+}
+
 // StartTime returns the time when the FUSE filesystem was created.
 // Used by child nodes to set timestamps for static content.
 func (f *FS) StartTime() time.Time {
@@ -151,6 +503,7 @@ func (f *FS) StartTime() time.Time {
 var _ = (fs.NodeLookuper)((*FS)(nil))
 var _ = (fs.NodeReaddirer)((*FS)(nil))
 var _ = (fs.NodeGetattrer)((*FS)(nil))
+var _ = (fs.NodeStatfser)((*FS)(nil))
 
 func (f *FS) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	switch name {
@@ -159,69 +512,115 @@ func (f *FS) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.I
 		if f.clientMgr == nil {
 			return nil, syscall.ENOENT
 		}
-		setEntryTimeout(out, cacheTTLConversation)
-		return f.NewInode(ctx, &BackendListNode{state: f.state, clientMgr: f.clientMgr, cloneTimeout: f.cloneTimeout, parsedCache: f.parsedCache, startTime: f.startTime, diag: f.Diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Conversation)
+		return f.NewInode(ctx, &BackendListNode{state: f.state, clientMgr: f.clientMgr, cloneTimeout: f.cloneTimeout, cloneByModel: f.cloneByModel, modelAliases: f.modelAliases, layout: f.layout, mdChunkSize: f.mdChunkSize, sparseMsgs: f.sparseMsgs, maxSend: f.maxSend, readyTimeout: f.readyTimeout, parsedCache: f.parsedCache, startTime: f.startTime, events: f.events, activity: f.activity, usage: f.usage, budget: f.budget, filter: f.filter, sends: f.sends, snapshots: f.snapshots, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("backend")}), 0
 	case "model":
 		if f.clientMgr != nil {
 			// With backend support: symlink to backend/default/model
-			setEntryTimeout(out, cacheTTLStatic)
-			return f.NewInode(ctx, &SymlinkNode{target: "backend/default/model", startTime: f.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+			setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+			return f.NewInode(ctx, &SymlinkNode{target: "backend/default/model", startTime: f.startTime}, fs.StableAttr{Mode: f.names().mode("model")}), 0
 		}
 		// Without backend support: directory (legacy mode)
-		setEntryTimeout(out, cacheTTLModels)
-		return f.NewInode(ctx, &ModelsDirNode{client: f.client, state: f.state, startTime: f.startTime, diag: f.Diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Models)
+		return f.NewInode(ctx, &ModelsDirNode{client: f.client, state: f.state, aliases: f.modelAliases, startTime: f.startTime, readyTimeout: f.readyTimeout, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("model")}), 0
 	case "new":
 		if f.clientMgr != nil {
-			// With backend support: symlink to backend/default/model/default/new
-			setEntryTimeout(out, cacheTTLStatic)
-			return f.NewInode(ctx, &SymlinkNode{target: "backend/default/model/default/new", startTime: f.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+			// With backend support: symlink to the default backend's own new,
+			// which follows that backend's default model. Named directly rather
+			// than through backend/default, which doesn't exist while the
+			// default is "main", and resolved on every readlink so changing
+			// the default backend moves it.
+			setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+			return f.NewInode(ctx, &DynamicSymlinkNode{
+				getTarget: func() string {
+					return "backend/" + f.state.GetDefaultBackend() + "/new"
+				},
+				startTime: f.startTime,
+			}, fs.StableAttr{Mode: f.names().mode("new")}), 0
 		}
 		// Without backend support: symlink to model/default/new (legacy mode)
-		setEntryTimeout(out, cacheTTLStatic)
-		return f.NewInode(ctx, &SymlinkNode{target: "model/default/new", startTime: f.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+		return f.NewInode(ctx, &SymlinkNode{target: "model/default/new", startTime: f.startTime}, fs.StableAttr{Mode: f.names().mode("new")}), 0
 	case "conversation":
 		if f.clientMgr != nil {
 			// With backend support: symlink to backend/default/conversation
-			setEntryTimeout(out, cacheTTLStatic)
-			return f.NewInode(ctx, &SymlinkNode{target: "backend/default/conversation", startTime: f.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+			setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+			return f.NewInode(ctx, &SymlinkNode{target: "backend/default/conversation", startTime: f.startTime}, fs.StableAttr{Mode: f.names().mode("conversation")}), 0
 		}
 		// Without backend support: directory (legacy mode)
-		setEntryTimeout(out, cacheTTLConversation)
-		return f.NewInode(ctx, &ConversationListNode{client: f.client, state: f.state, cloneTimeout: f.cloneTimeout, startTime: f.startTime, parsedCache: f.parsedCache, diag: f.Diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Conversation)
+		return f.NewInode(ctx, &ConversationListNode{client: f.client, state: f.state, cloneTimeout: f.cloneTimeout, cloneByModel: f.cloneByModel, layout: f.layout, mdChunkSize: f.mdChunkSize, sparseMsgs: f.sparseMsgs, maxSend: f.maxSend, startTime: f.startTime, parsedCache: f.parsedCache, events: f.events, activity: f.activity, usage: f.usage, budget: f.budget, filter: f.filter, sends: f.sends, snapshots: f.snapshots, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("conversation")}), 0
 	case "shelley":
-		setEntryTimeout(out, cacheTTLConversation)
-		return f.NewInode(ctx, &ShelleyDirNode{state: f.state, clientMgr: f.clientMgr, cloneTimeout: f.cloneTimeout, parsedCache: f.parsedCache, startTime: f.startTime, diag: f.Diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Conversation)
+		return f.NewInode(ctx, &ShelleyDirNode{state: f.state, clientMgr: f.clientMgr, cloneTimeout: f.cloneTimeout, cloneByModel: f.cloneByModel, modelAliases: f.modelAliases, layout: f.layout, mdChunkSize: f.mdChunkSize, sparseMsgs: f.sparseMsgs, maxSend: f.maxSend, readyTimeout: f.readyTimeout, parsedCache: f.parsedCache, startTime: f.startTime, events: f.events, activity: f.activity, usage: f.usage, budget: f.budget, filter: f.filter, sends: f.sends, snapshots: f.snapshots, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("shelley")}), 0
+	case "usage":
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+		return f.NewInode(ctx, &UsageDirNode{board: f.usage, state: f.state, startTime: f.startTime, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("usage")}), 0
+	case "stats":
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+		return f.NewInode(ctx, &StatsDirNode{board: f.usage, state: f.state, startTime: f.startTime, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("stats")}), 0
+	case "search":
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+		return f.NewInode(ctx, &SearchDirNode{client: f.defaultClient, state: f.state, parsedCache: f.parsedCache, startTime: f.startTime, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("search")}), 0
+	case "events":
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+		return f.NewInode(ctx, &EventsNode{bus: f.events, startTime: f.startTime, diag: f.Diag}, fs.StableAttr{Mode: f.names().mode("events")}), 0
 	case "README.md":
-		setEntryTimeout(out, cacheTTLStatic)
-		return f.NewInode(ctx, &ReadmeNode{startTime: f.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		setEntryTimeout(out, cacheTimeouts(&f.Inode).Static)
+		return f.NewInode(ctx, &ReadmeNode{startTime: f.startTime}, fs.StableAttr{Mode: f.names().mode("README.md")}), 0
 	}
 	return nil, syscall.ENOENT
 }
 
-func (f *FS) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
-	entries := []fuse.DirEntry{
-		{Name: "README.md", Mode: fuse.S_IFREG},
+// names returns the naming registry table of the root directory: with
+// backend support, model and conversation are symlinks into backend/.
+func (f *FS) names() nameTable {
+	if f.clientMgr != nil {
+		return rootBackendNames
 	}
+	return rootNames
+}
+
+func (f *FS) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	var entries []fuse.DirEntry
 	if f.clientMgr != nil {
 		// With backend support: show backend dir and symlinks
-		entries = append(entries, fuse.DirEntry{Name: "backend", Mode: fuse.S_IFDIR})
-		entries = append(entries, fuse.DirEntry{Name: "model", Mode: syscall.S_IFLNK})
-		entries = append(entries, fuse.DirEntry{Name: "new", Mode: syscall.S_IFLNK})
-		entries = append(entries, fuse.DirEntry{Name: "conversation", Mode: syscall.S_IFLNK})
+		entries = rootBackendNames.entries("README.md", "backend", "model", "new", "conversation", "shelley", "usage", "stats", "search", "events")
 	} else {
 		// Without backend support: legacy mode with directories
-		entries = append(entries, fuse.DirEntry{Name: "model", Mode: fuse.S_IFDIR})
-		entries = append(entries, fuse.DirEntry{Name: "new", Mode: syscall.S_IFLNK})
-		entries = append(entries, fuse.DirEntry{Name: "conversation", Mode: fuse.S_IFDIR})
+		entries = rootNames.entries("README.md", "model", "new", "conversation", "shelley", "usage", "stats", "search", "events")
 	}
-	entries = append(entries, fuse.DirEntry{Name: "shelley", Mode: fuse.S_IFDIR})
 	return fs.NewListDirStream(entries), 0
 }
 
 func (f *FS) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, f.startTime)
-	out.SetTimeout(cacheTTLStatic)
+	out.SetTimeout(cacheTimeouts(&f.Inode).Static)
+	return 0
+}
+
+// statfsBlockSize is the block size cache usage is reported in.
+const statfsBlockSize = 4096
+
+// Statfs reports cache usage, so `df` on the mount point shows how much of
+// the cache budget is in use. Without a limit nothing is reported, as the
+// filesystem has no meaningful capacity.
+func (f *FS) Statfs(ctx context.Context, out *fuse.StatfsOut) syscall.Errno {
+	out.Bsize = statfsBlockSize
+	out.Frsize = statfsBlockSize
+	out.NameLen = 255
+	u := f.cacheBudget.Usage()
+	if u.MaxBytes <= 0 {
+		return 0
+	}
+	used := uint64(u.Bytes+statfsBlockSize-1) / statfsBlockSize
+	out.Blocks = uint64(u.MaxBytes+statfsBlockSize-1) / statfsBlockSize
+	if used < out.Blocks {
+		out.Bfree = out.Blocks - used
+		out.Bavail = out.Bfree
+	}
+	out.Files = uint64(u.Entries)
 	return 0
 }
 
@@ -256,7 +655,7 @@ func (r *ReadmeNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.Att
 	out.Mode = fuse.S_IFREG | 0444
 	out.Size = uint64(len(readmeContent))
 	setTimestamps(&out.Attr, r.startTime)
-	out.SetTimeout(cacheTTLStatic)
+	out.SetTimeout(cacheTimeouts(&r.Inode).Static)
 	return 0
 }
 
@@ -275,6 +674,16 @@ func setTimestamps(attr *fuse.Attr, t time.Time) {
 	attr.Ctimensec = nsec
 }
 
+// unixSeconds converts an RFC3339 API timestamp to epoch seconds, for the
+// *_unix companion files. ok is false if the timestamp is empty or invalid.
+func unixSeconds(rfc3339 string) (string, bool) {
+	t, err := time.Parse(time.RFC3339, rfc3339)
+	if err != nil {
+		return "", false
+	}
+	return strconv.FormatInt(t.Unix(), 10), true
+}
+
 func parseFormat(name string) (contentFormat, bool) {
 	if strings.HasSuffix(name, ".json") {
 		return formatJSON, true
@@ -282,6 +691,9 @@ func parseFormat(name string) (contentFormat, bool) {
 	if strings.HasSuffix(name, ".md") {
 		return formatMD, true
 	}
+	if strings.HasSuffix(name, ".org") {
+		return formatOrg, true
+	}
 	return 0, false
 }
 
@@ -296,6 +708,34 @@ func readAt(data, dest []byte, off int64) []byte {
 	return data[off:end]
 }
 
+// lseek(2) whence values that go to the filesystem; the kernel handles
+// SEEK_SET, SEEK_CUR and SEEK_END itself.
+const (
+	seekData = 3 // SEEK_DATA
+	seekHole = 4 // SEEK_HOLE
+)
+
+// seekContent answers SEEK_DATA and SEEK_HOLE for content of size bytes.
+// Virtual files have no holes: all of it is data, followed by the implicit
+// hole at EOF. Handles holding a snapshot use it, because go-fuse otherwise
+// answers from the node's attributes, which for most virtual files carry no
+// size, and SEEK_DATA would report an empty file to cp and tar.
+func seekContent(size int, off uint64, whence uint32) (uint64, syscall.Errno) {
+	switch whence {
+	case seekData:
+		if off >= uint64(size) {
+			return 0, syscall.ENXIO
+		}
+		return off, 0
+	case seekHole:
+		if off > uint64(size) {
+			return 0, syscall.ENXIO
+		}
+		return uint64(size), 0
+	}
+	return 0, syscall.EINVAL
+}
+
 // slugSanitizerRe matches non-alphanumeric characters for slug sanitization.
 var slugSanitizerRe = regexp.MustCompile(`[^a-z0-9]+`)
 
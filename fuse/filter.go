@@ -0,0 +1,98 @@
+package fuse
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+// ConversationFilter selects the server conversations a mount adopts and
+// lists, so a mount used for one project isn't cluttered by the whole
+// server's history. Conversations it rejects are neither adopted by
+// /conversation listings or lookups nor listed, even if adopted earlier;
+// those stay reachable by their local ID. A nil filter accepts everything.
+type ConversationFilter struct {
+	// IncludeSlugs, if set, keeps only conversations whose slug matches one
+	// of these globs (path.Match syntax). Conversations without a slug yet
+	// don't match.
+	IncludeSlugs []string
+	// ExcludeSlugs drops conversations whose slug matches one of these globs.
+	ExcludeSlugs []string
+	// Models, if set, keeps only conversations using one of these models.
+	Models []string
+	// MaxAge, if positive, drops conversations last updated longer ago.
+	MaxAge time.Duration
+}
+
+// Validate reports the first malformed glob.
+func (f *ConversationFilter) Validate() error {
+	for _, pattern := range append(append([]string(nil), f.IncludeSlugs...), f.ExcludeSlugs...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("bad slug pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether conv passes the filter at time now.
+func (f *ConversationFilter) Match(conv shelley.Conversation, now time.Time) bool {
+	if f == nil {
+		return true
+	}
+	slug := derefStr(conv.Slug)
+	if len(f.IncludeSlugs) > 0 && (slug == "" || !matchAnyGlob(f.IncludeSlugs, slug)) {
+		return false
+	}
+	if slug != "" && matchAnyGlob(f.ExcludeSlugs, slug) {
+		return false
+	}
+	if len(f.Models) > 0 && !containsString(f.Models, derefStr(conv.Model)) {
+		return false
+	}
+	if f.MaxAge > 0 {
+		updated := conv.UpdatedAt
+		if updated == "" {
+			updated = conv.CreatedAt
+		}
+		// Conversations without a readable timestamp are kept.
+		if t, err := time.Parse(time.RFC3339Nano, updated); err == nil && now.Sub(t) > f.MaxAge {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns the conversations of convs that pass the filter.
+func (f *ConversationFilter) apply(convs []shelley.Conversation) []shelley.Conversation {
+	if f == nil {
+		return convs
+	}
+	now := time.Now()
+	kept := convs[:0:0]
+	for _, conv := range convs {
+		if f.Match(conv, now) {
+			kept = append(kept, conv)
+		}
+	}
+	return kept
+}
+
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
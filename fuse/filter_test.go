@@ -0,0 +1,91 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+func TestConversationFilterMatch(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	conv := func(slug, model, updated string) shelley.Conversation {
+		c := shelley.Conversation{ConversationID: "c", UpdatedAt: updated}
+		if slug != "" {
+			c.Slug = strPtr(slug)
+		}
+		if model != "" {
+			c.Model = strPtr(model)
+		}
+		return c
+	}
+	tests := []struct {
+		name   string
+		filter *ConversationFilter
+		conv   shelley.Conversation
+		want   bool
+	}{
+		{"nil filter", nil, conv("", "", ""), true},
+		{"include match", &ConversationFilter{IncludeSlugs: []string{"proj-*"}}, conv("proj-x", "", ""), true},
+		{"include miss", &ConversationFilter{IncludeSlugs: []string{"proj-*"}}, conv("other", "", ""), false},
+		{"include without slug", &ConversationFilter{IncludeSlugs: []string{"*"}}, conv("", "", ""), false},
+		{"exclude", &ConversationFilter{ExcludeSlugs: []string{"scratch-*"}}, conv("scratch-1", "", ""), false},
+		{"exclude without slug", &ConversationFilter{ExcludeSlugs: []string{"*"}}, conv("", "", ""), true},
+		{"model", &ConversationFilter{Models: []string{"fast"}}, conv("", "fast", ""), true},
+		{"other model", &ConversationFilter{Models: []string{"fast"}}, conv("", "slow", ""), false},
+		{"recent", &ConversationFilter{MaxAge: 24 * time.Hour}, conv("", "", "2026-10-16T01:00:00Z"), true},
+		{"old", &ConversationFilter{MaxAge: 24 * time.Hour}, conv("", "", "2026-10-01T01:00:00Z"), false},
+		{"no timestamp", &ConversationFilter{MaxAge: time.Hour}, conv("", "", ""), true},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(tt.conv, now); got != tt.want {
+			t.Errorf("%s: Match = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if err := (&ConversationFilter{ExcludeSlugs: []string{"["}}).Validate(); err == nil {
+		t.Error("Validate accepted a malformed glob")
+	}
+}
+
+func TestConversationFilterListing(t *testing.T) {
+	recent := time.Now().UTC().Format(time.RFC3339)
+	server := mockConversationsServer(t, []shelley.Conversation{
+		{ConversationID: "conv-keep", Slug: strPtr("proj-api"), UpdatedAt: recent},
+		{ConversationID: "conv-other", Slug: strPtr("holiday-plans"), UpdatedAt: recent},
+		{ConversationID: "conv-old", Slug: strPtr("proj-legacy"), UpdatedAt: "2020-01-01T00:00:00Z"},
+	})
+	defer server.Close()
+	store := testStore(t)
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetConversationFilter(&ConversationFilter{IncludeSlugs: []string{"proj-*"}, MaxAge: 24 * time.Hour})
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+
+	listed := make(map[string]bool)
+	for _, name := range listDir(t, convDir) {
+		listed[name] = true
+	}
+	if !listed["proj-api"] || !listed["conv-keep"] {
+		t.Errorf("included conversation missing from the listing: %v", listed)
+	}
+	for _, name := range []string{"holiday-plans", "proj-legacy", "conv-other", "conv-old"} {
+		if listed[name] {
+			t.Errorf("filtered %s listed", name)
+		}
+	}
+	for _, id := range []string{"conv-other", "conv-old"} {
+		if store.GetByShelleyID(id) != "" {
+			t.Errorf("%s was adopted despite the filter", id)
+		}
+		if _, err := os.Lstat(filepath.Join(convDir, id)); !os.IsNotExist(err) {
+			t.Errorf("lookup of filtered %s: got %v, want ENOENT", id, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(convDir, "proj-api", "ctl")); err != nil {
+		t.Errorf("included conversation not reachable: %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package fuse
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// --- HandleTracker: open file and directory handles, for /diag/handles ---
+// Open handles keep the mount busy: `umount` fails with EBUSY until every
+// process has closed its files and left its working directory. The tracker
+// sits between the kernel and the node filesystem (see Mount) and records
+// which process opened what, so the culprit can be found without lsof.
+
+// OpenHandle describes one open file or directory handle.
+type OpenHandle struct {
+	Path    string    `json:"path"`              // relative to the mount point
+	Dir     bool      `json:"dir"`               // opendir rather than open
+	PID     uint32    `json:"pid"`               // 0 if the kernel didn't say
+	Command string    `json:"command,omitempty"` // from /proc/PID/comm, if readable
+	Opened  time.Time `json:"opened"`
+}
+
+type handleKey struct {
+	nodeID uint64
+	fh     uint64
+}
+
+// nodeName is where the kernel learned a node ID: a name in a parent.
+type nodeName struct {
+	parent  uint64
+	name    string
+	lookups uint64
+}
+
+// HandleTracker records open handles. Its zero value is not usable; create
+// one with NewHandleTracker.
+type HandleTracker struct {
+	mu      sync.Mutex
+	names   map[uint64]*nodeName
+	handles map[handleKey]OpenHandle
+}
+
+// NewHandleTracker creates an empty handle tracker.
+func NewHandleTracker() *HandleTracker {
+	return &HandleTracker{
+		names:   make(map[uint64]*nodeName),
+		handles: make(map[handleKey]OpenHandle),
+	}
+}
+
+// Open returns a snapshot of the open handles, oldest first.
+func (t *HandleTracker) Open() []OpenHandle {
+	t.mu.Lock()
+	handles := make([]OpenHandle, 0, len(t.handles))
+	for _, h := range t.handles {
+		handles = append(handles, h)
+	}
+	t.mu.Unlock()
+	sort.Slice(handles, func(i, j int) bool {
+		if handles[i].Opened.Equal(handles[j].Opened) {
+			return handles[i].Path < handles[j].Path
+		}
+		return handles[i].Opened.Before(handles[j].Opened)
+	})
+	return handles
+}
+
+// Dump returns a human-readable list of open handles.
+func (t *HandleTracker) Dump() string {
+	handles := t.Open()
+	if len(handles) == 0 {
+		return "no open handles\n"
+	}
+	now := time.Now()
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d open handle(s):\n", len(handles))
+	for _, h := range handles {
+		p := h.Path
+		if h.Dir {
+			p += "/"
+		}
+		fmt.Fprintf(&b, "  %s pid=%d", p, h.PID)
+		if h.Command != "" {
+			fmt.Fprintf(&b, " (%s)", h.Command)
+		}
+		fmt.Fprintf(&b, " open %s\n", now.Sub(h.Opened).Truncate(time.Millisecond))
+	}
+	return b.String()
+}
+
+// Handler returns an http.Handler that lists open handles as text, or as
+// JSON with the ?json query parameter.
+func (t *HandleTracker) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, wantJSON := r.URL.Query()["json"]; wantJSON {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(t.Open()); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, t.Dump())
+	})
+}
+
+// learn records that nodeID is called name inside parent.
+func (t *HandleTracker) learn(parent uint64, name string, nodeID uint64) {
+	if nodeID == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n, ok := t.names[nodeID]; ok {
+		n.parent, n.name = parent, name
+		n.lookups++
+		return
+	}
+	t.names[nodeID] = &nodeName{parent: parent, name: name, lookups: 1}
+}
+
+func (t *HandleTracker) forget(nodeID, nlookup uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if n, ok := t.names[nodeID]; ok {
+		if n.lookups <= nlookup {
+			delete(t.names, nodeID)
+		} else {
+			n.lookups -= nlookup
+		}
+	}
+}
+
+func (t *HandleTracker) rename(oldParent uint64, oldName string, newParent uint64, newName string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, n := range t.names {
+		if n.parent == oldParent && n.name == oldName {
+			n.parent, n.name = newParent, newName
+		}
+	}
+}
+
+// pathLocked rebuilds the path of nodeID from the recorded names. Nodes the
+// tracker never saw a name for (e.g. from readdirplus) are shown by ID.
+func (t *HandleTracker) pathLocked(nodeID uint64) string {
+	var parts []string
+	for depth := 0; nodeID != fuse.FUSE_ROOT_ID && depth < 256; depth++ {
+		n, ok := t.names[nodeID]
+		if !ok {
+			parts = append(parts, fmt.Sprintf("<node %d>", nodeID))
+			break
+		}
+		parts = append(parts, n.name)
+		nodeID = n.parent
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return path.Join(append([]string{"."}, parts...)...)
+}
+
+// path returns the path of nodeID relative to the mount point, if the
+// tracker knows every name on the way.
+func (t *HandleTracker) path(nodeID uint64) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var parts []string
+	for depth := 0; nodeID != fuse.FUSE_ROOT_ID; depth++ {
+		n, ok := t.names[nodeID]
+		if !ok || depth >= 256 {
+			return "", false
+		}
+		parts = append(parts, n.name)
+		nodeID = n.parent
+	}
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+	return path.Join(append([]string{"."}, parts...)...), true
+}
+
+func (t *HandleTracker) opened(nodeID, fh uint64, dir bool, pid uint32) {
+	h := OpenHandle{Dir: dir, PID: pid, Command: processCommand(pid), Opened: time.Now()}
+	t.mu.Lock()
+	h.Path = t.pathLocked(nodeID)
+	t.handles[handleKey{nodeID, fh}] = h
+	t.mu.Unlock()
+}
+
+// processCommand returns the command name of pid from /proc/PID/comm, or
+// "" if it can't be read.
+func processCommand(pid uint32) string {
+	if pid == 0 {
+		return ""
+	}
+	comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(comm))
+}
+
+func (t *HandleTracker) released(nodeID, fh uint64) {
+	t.mu.Lock()
+	delete(t.handles, handleKey{nodeID, fh})
+	t.mu.Unlock()
+}
+
+// trackingRawFS wraps the node filesystem's raw protocol handler, passing
+// every request through and recording names and handles on the way.
+type trackingRawFS struct {
+	fuse.RawFileSystem
+	t      *HandleTracker
+	access *accessLogger // nil unless SetAccessLog was called
+}
+
+func (r *trackingRawFS) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Lookup(cancel, header, name, out)
+	if status.Ok() {
+		r.t.learn(header.NodeId, name, out.NodeId)
+	}
+	return status
+}
+
+func (r *trackingRawFS) Forget(nodeid, nlookup uint64) {
+	r.t.forget(nodeid, nlookup)
+	r.RawFileSystem.Forget(nodeid, nlookup)
+}
+
+func (r *trackingRawFS) Mkdir(cancel <-chan struct{}, input *fuse.MkdirIn, name string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Mkdir(cancel, input, name, out)
+	if status.Ok() {
+		r.t.learn(input.NodeId, name, out.NodeId)
+	}
+	return status
+}
+
+func (r *trackingRawFS) Mknod(cancel <-chan struct{}, input *fuse.MknodIn, name string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Mknod(cancel, input, name, out)
+	if status.Ok() {
+		r.t.learn(input.NodeId, name, out.NodeId)
+	}
+	return status
+}
+
+func (r *trackingRawFS) Symlink(cancel <-chan struct{}, header *fuse.InHeader, pointedTo string, linkName string, out *fuse.EntryOut) fuse.Status {
+	status := r.RawFileSystem.Symlink(cancel, header, pointedTo, linkName, out)
+	if status.Ok() {
+		r.t.learn(header.NodeId, linkName, out.NodeId)
+	}
+	return status
+}
+
+func (r *trackingRawFS) Rename(cancel <-chan struct{}, input *fuse.RenameIn, oldName string, newName string) fuse.Status {
+	status := r.RawFileSystem.Rename(cancel, input, oldName, newName)
+	if status.Ok() {
+		r.t.rename(input.NodeId, oldName, input.Newdir, newName)
+	}
+	return status
+}
+
+func (r *trackingRawFS) Create(cancel <-chan struct{}, input *fuse.CreateIn, name string, out *fuse.CreateOut) fuse.Status {
+	status := r.RawFileSystem.Create(cancel, input, name, out)
+	if status.Ok() {
+		r.t.learn(input.NodeId, name, out.NodeId)
+		r.t.opened(out.NodeId, out.Fh, false, input.Caller.Pid)
+	}
+	return status
+}
+
+func (r *trackingRawFS) Open(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) fuse.Status {
+	status := r.RawFileSystem.Open(cancel, input, out)
+	if status.Ok() {
+		r.t.opened(input.NodeId, out.Fh, false, input.Caller.Pid)
+		if r.access != nil && input.Flags&syscall.O_ACCMODE != syscall.O_WRONLY {
+			r.access.opened(r.t, input.NodeId, input.Caller)
+		}
+	}
+	return status
+}
+
+func (r *trackingRawFS) Release(cancel <-chan struct{}, input *fuse.ReleaseIn) {
+	r.t.released(input.NodeId, input.Fh)
+	r.RawFileSystem.Release(cancel, input)
+}
+
+func (r *trackingRawFS) OpenDir(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) fuse.Status {
+	status := r.RawFileSystem.OpenDir(cancel, input, out)
+	if status.Ok() {
+		r.t.opened(input.NodeId, out.Fh, true, input.Caller.Pid)
+	}
+	return status
+}
+
+func (r *trackingRawFS) ReleaseDir(input *fuse.ReleaseIn) {
+	r.t.released(input.NodeId, input.Fh)
+	r.RawFileSystem.ReleaseDir(input)
+}
+
+// Mount is like fs.Mount, but routes requests through root.Handles so open
+// handles show up in /diag/handles (and reads go to the access log, with
+// SetAccessLog), and unmounts and exits if a request
+// panics (see recoveringRawFS). With SetExportCompat it also sets up the
+// mount for re-export (see exportRawFS).
+func Mount(dir string, root *FS, options *fs.Options) (*fuse.Server, error) {
+	if options == nil {
+		oneSec := time.Second
+		options = &fs.Options{
+			EntryTimeout: &oneSec,
+			AttrTimeout:  &oneSec,
+		}
+	}
+
+	tracking := &trackingRawFS{RawFileSystem: fs.NewNodeFS(root, options), t: root.Handles}
+	if root.accessLog != nil {
+		tracking.access = &accessLogger{w: root.accessLog, state: root.state}
+	}
+	var raw fuse.RawFileSystem = tracking
+	if root.exportCompat {
+		options.MountOptions.DisableReadDirPlus = true
+		raw = newExportRawFS(raw, root.Handles)
+	}
+	rawFS := &recoveringRawFS{
+		RawFileSystem: raw,
+		onPanic:       crashUnmount(dir),
+	}
+	server, err := fuse.NewServer(rawFS, dir, &options.MountOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	go server.Serve()
+	if err := server.WaitMount(); err != nil {
+		return nil, err
+	}
+	if !root.hooks.isZero() {
+		stop := root.startHooks(dir)
+		go func() {
+			server.Wait()
+			stop()
+		}()
+	}
+	return server, nil
+}
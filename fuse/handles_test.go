@@ -0,0 +1,74 @@
+package fuse
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestHandleTracker(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+
+	shelleyFS := NewFS(shelley.NewClient(server.URL), testStore(t), time.Hour)
+	mountPoint, err := os.MkdirTemp("", "shelley-fuse-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(mountPoint)
+	zero := time.Duration(0)
+	srv, err := Mount(mountPoint, shelleyFS, &fs.Options{EntryTimeout: &zero, AttrTimeout: &zero, NegativeTimeout: &zero})
+	if err != nil {
+		t.Fatalf("Mount failed: %v", err)
+	}
+	defer srv.Unmount()
+
+	f, err := os.Open(filepath.Join(mountPoint, "README.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := os.Open(filepath.Join(mountPoint, "conversation"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byPath := make(map[string]OpenHandle)
+	for _, h := range shelleyFS.Handles.Open() {
+		byPath[h.Path] = h
+	}
+	readme, ok := byPath["README.md"]
+	if !ok {
+		t.Fatalf("README.md not among open handles: %v", byPath)
+	}
+	// The kernel reports the calling thread, which needn't be the main one.
+	if readme.Dir || readme.PID == 0 || readme.Command == "" {
+		t.Errorf("README.md handle = %+v, want a file with its opener's pid and command", readme)
+	}
+	if h, ok := byPath["conversation"]; !ok || !h.Dir {
+		t.Errorf("conversation/ handle missing or not a directory: %+v", h)
+	}
+
+	rec := httptest.NewRecorder()
+	shelleyFS.Handles.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/diag/handles", nil))
+	if !strings.Contains(rec.Body.String(), "README.md pid=") || !strings.Contains(rec.Body.String(), "conversation/ pid=") {
+		t.Errorf("unexpected /diag/handles output:\n%s", rec.Body.String())
+	}
+
+	f.Close()
+	d.Close()
+	// Release is sent asynchronously after close.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(shelleyFS.Handles.Open()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if open := shelleyFS.Handles.Open(); len(open) != 0 {
+		t.Errorf("handles still open after close: %+v", open)
+	}
+}
@@ -0,0 +1,210 @@
+package fuse
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"shelley-fuse/state"
+)
+
+// --- Hooks: shell commands run on conversation events ---
+// A hook is a command run with /bin/sh -c when something happens to a
+// conversation, so a desktop notification or a chat message can follow a
+// reply without a watcher daemon:
+//
+//   - on_create: a new conversation was created on the backend by its first
+//     message.
+//   - on_message: a conversation got a newer message, or the backend
+//     reported it updated. New messages are noticed when the conversation
+//     is read or, with -poll-active, by the background poller.
+//   - on_error: a request about a conversation failed; the same lines go to
+//     its errors.log.
+//
+// The command learns about the conversation from its environment:
+//
+//	SHELLEY_HOOK               on_create, on_message or on_error
+//	SHELLEY_LOCAL_ID           the conversation's local ID
+//	SHELLEY_CONVERSATION_ID    its server ID, if created
+//	SHELLEY_BACKEND            the backend it belongs to
+//	SHELLEY_CONVERSATION_PATH  its directory in the mount
+//	SHELLEY_ERROR              the error (on_error only)
+//
+// Hooks run one at a time, in the order their events happened, with their
+// output discarded. A hook that runs longer than hookTimeout is killed, and
+// events that arrive while hookQueueSize others wait are dropped.
+
+// Hooks are the commands to run on conversation events; empty ones are
+// not run.
+type Hooks struct {
+	OnMessage string
+	OnCreate  string
+	OnError   string
+}
+
+func (h Hooks) isZero() bool {
+	return h.OnMessage == "" && h.OnCreate == "" && h.OnError == ""
+}
+
+const (
+	hookTimeout   = time.Minute
+	hookQueueSize = 256
+)
+
+// hookRun is one pending run of a hook. backend and conversationID are
+// looked up when the run starts if they are empty.
+type hookRun struct {
+	name           string
+	command        string
+	localID        string
+	backend        string
+	conversationID string
+	err            string
+}
+
+// hookRunner queues hook runs and executes them on one goroutine.
+type hookRunner struct {
+	hooks        Hooks
+	mountpoint   string
+	multiBackend bool // conversation paths go through backend/{name}/
+	state        *state.Store
+	queue        chan hookRun
+
+	mu      sync.Mutex   // orders enqueue against close
+	closed  bool         // the queue is closed; runs are dropped
+	dropped atomic.Int64 // runs dropped on a full queue, not yet logged
+}
+
+func newHookRunner(hooks Hooks, mountpoint string, multiBackend bool, store *state.Store) *hookRunner {
+	return &hookRunner{
+		hooks:        hooks,
+		mountpoint:   mountpoint,
+		multiBackend: multiBackend,
+		state:        store,
+		queue:        make(chan hookRun, hookQueueSize),
+	}
+}
+
+// enqueue adds a run without blocking, as it is called with the store or
+// the diag tracker locked. For the same reason it doesn't log: log output
+// may look conversations up in the store. Dropped runs are counted, and
+// logged by loop.
+func (r *hookRunner) enqueue(run hookRun) {
+	if run.command == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	select {
+	case r.queue <- run:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+// event is the store's event hook.
+func (r *hookRunner) event(e state.Event) {
+	run := hookRun{localID: e.LocalID, backend: e.Backend, conversationID: e.ShelleyConversationID}
+	switch e.Type {
+	case state.EventCreated:
+		run.name, run.command = "on_create", r.hooks.OnCreate
+	case state.EventUpdated:
+		run.name, run.command = "on_message", r.hooks.OnMessage
+	default:
+		return
+	}
+	r.enqueue(run)
+}
+
+// error is the diag tracker's error hook; key is a local conversation ID.
+func (r *hookRunner) error(key, msg string) {
+	r.enqueue(hookRun{name: "on_error", command: r.hooks.OnError, localID: key, err: msg})
+}
+
+// close stops the runner once the queued runs are done. Runs enqueued
+// afterwards, by hooks that were already being called, are dropped.
+func (r *hookRunner) close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+}
+
+// loop runs queued hooks until the queue is closed.
+func (r *hookRunner) loop() {
+	for run := range r.queue {
+		r.run(run)
+		r.logDropped()
+	}
+	r.logDropped()
+}
+
+// logDropped logs the runs enqueue dropped since it was last called.
+func (r *hookRunner) logDropped() {
+	if n := r.dropped.Swap(0); n > 0 {
+		log.Printf("%d hooks dropped: %d hooks already waiting", n, hookQueueSize)
+	}
+}
+
+func (r *hookRunner) run(run hookRun) {
+	if run.backend == "" {
+		for _, backend := range r.state.ListBackends() {
+			if cs := r.state.GetForBackend(backend, run.localID); cs != nil {
+				run.backend, run.conversationID = backend, cs.ShelleyConversationID
+				break
+			}
+		}
+	}
+	dir := filepath.Join(r.mountpoint, "conversation", run.localID)
+	if r.multiBackend {
+		dir = filepath.Join(r.mountpoint, "backend", run.backend, "conversation", run.localID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", run.command)
+	cmd.Env = append(os.Environ(),
+		"SHELLEY_HOOK="+run.name,
+		"SHELLEY_LOCAL_ID="+run.localID,
+		"SHELLEY_CONVERSATION_ID="+run.conversationID,
+		"SHELLEY_BACKEND="+run.backend,
+		"SHELLEY_CONVERSATION_PATH="+dir,
+	)
+	if run.err != "" {
+		cmd.Env = append(cmd.Env, "SHELLEY_ERROR="+run.err)
+	}
+	if err := cmd.Run(); err != nil {
+		log.Printf("hook %s for %s: %v", run.name, run.localID, err)
+	}
+}
+
+// startHooks makes f run its hooks for the mount at mountpoint, and returns
+// a function that stops them once the mount is gone.
+func (f *FS) startHooks(mountpoint string) (stop func()) {
+	if abs, err := filepath.Abs(mountpoint); err == nil {
+		mountpoint = abs
+	}
+	r := newHookRunner(f.hooks, mountpoint, f.clientMgr != nil, f.state)
+	events := f.events
+	f.state.SetEventHook(func(e state.Event) {
+		events.Publish(e)
+		r.event(e)
+	})
+	f.Diag.SetErrorHook(r.error)
+	go r.loop()
+	return func() {
+		f.state.SetEventHook(events.Publish)
+		f.Diag.SetErrorHook(nil)
+		r.close()
+	}
+}
@@ -0,0 +1,88 @@
+package fuse
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+func TestHooks(t *testing.T) {
+	server := mockConversationsServer(t, nil)
+	defer server.Close()
+	store := testStore(t)
+	out := filepath.Join(t.TempDir(), "hooks.log")
+	line := `echo "$SHELLEY_HOOK $SHELLEY_LOCAL_ID $SHELLEY_CONVERSATION_ID $SHELLEY_BACKEND $SHELLEY_CONVERSATION_PATH $SHELLEY_ERROR" >> ` + out
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetHooks(Hooks{OnCreate: line, OnMessage: line, OnError: line})
+	mountPoint := mountWithMount(t, shelleyFS)
+
+	localID, _ := store.Clone()
+	if err := store.MarkCreated(localID, "conv-hooked", ""); err != nil {
+		t.Fatal(err)
+	}
+	store.NoteMessageTime("conv-hooked", "2030-01-01T00:00:00Z")
+	shelleyFS.Diag.RecordError(localID, "send: too big")
+
+	dir := filepath.Join(mountPoint, "conversation", localID)
+	backend := store.GetDefaultBackend()
+	want := []string{
+		"on_create " + localID + " conv-hooked " + backend + " " + dir + " ",
+		"on_message " + localID + " conv-hooked " + backend + " " + dir + " ",
+		"on_error " + localID + " conv-hooked " + backend + " " + dir + " send: too big",
+	}
+	var got []string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, _ := os.ReadFile(out)
+		if got = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); len(got) >= len(want) {
+			break
+		}
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("hooks ran as\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// Hooks already being called when the runner stops must not panic.
+func TestHookRunnerCloseWhileEnqueueing(t *testing.T) {
+	r := newHookRunner(Hooks{OnError: "true"}, t.TempDir(), false, testStore(t))
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				r.enqueue(hookRun{name: "on_error", command: "true", localID: "x"})
+			}
+		}()
+	}
+	r.close()
+	wg.Wait()
+	r.close()
+}
+
+// Runs dropped on a full queue are counted, not logged with the store
+// locked, and logged later by the runner.
+func TestHookRunnerCountsDrops(t *testing.T) {
+	r := newHookRunner(Hooks{OnError: "true"}, t.TempDir(), false, testStore(t))
+	for i := 0; i < hookQueueSize+3; i++ {
+		r.error("x", "failed")
+	}
+	if n := r.dropped.Load(); n != 3 {
+		t.Fatalf("dropped %d runs, want 3", n)
+	}
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+	r.logDropped()
+	r.logDropped()
+	if got := strings.Count(buf.String(), "3 hooks dropped"); got != 1 {
+		t.Errorf("log = %q, want the 3 drops once", buf.String())
+	}
+}
@@ -0,0 +1,254 @@
+package fuse
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- ConvImportNode: /conversation/import ---
+// Writing a transcript to import and closing it creates a conversation on
+// the backend already holding those messages (shelley.ConversationImporter),
+// and adopts it like any other. The transcript is JSON or JSONL in the
+// shapes other chat tools export: an array of messages, an object with a
+// "messages" array, or one message object per line. A message has a role
+// ("role" or "type": user/human, or assistant/agent/model/ai) and its text
+// ("content", "text" or "message"); content may also be a list of parts
+// with "text" fields. System messages are skipped.
+//
+// The new conversation shows up in the listing and on .events. To learn
+// its local ID directly, open import read-write, write, fsync, and read it
+// back from the same descriptor.
+
+type ConvImportNode struct {
+	fs.Inode
+	client    shelley.ShelleyClient
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*ConvImportNode)(nil))
+var _ = (fs.NodeGetattrer)((*ConvImportNode)(nil))
+var _ = (fs.NodeSetattrer)((*ConvImportNode)(nil))
+
+func (n *ConvImportNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) == 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &convImportHandle{node: n}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *ConvImportNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0622
+	setTimestamps(&out.Attr, n.startTime)
+	return 0
+}
+
+func (n *ConvImportNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	// Accept truncate (from shell > redirect) silently
+	return n.Getattr(ctx, f, out)
+}
+
+// importTranscript creates the conversation and adopts it.
+func (n *ConvImportNode) importTranscript(op *diag.OpHandle, data []byte) (string, syscall.Errno) {
+	importer, ok := n.client.(shelley.ConversationImporter)
+	if !ok {
+		return "", syscall.EROFS
+	}
+	messages, err := parseTranscript(data)
+	if err != nil {
+		log.Printf("Import: %v", err)
+		return "", syscall.EINVAL
+	}
+	op.SetPhase("HTTP POST ImportConversation")
+	result, err := importer.ImportConversation(messages)
+	if errors.Is(err, shelley.ErrImportUnsupported) {
+		return "", syscall.EROFS
+	}
+	if err != nil {
+		log.Printf("ImportConversation failed: %v", err)
+		return "", backendErrno(err)
+	}
+	localID, err := n.state.AdoptWithSlug(result.ConversationID, result.Slug)
+	if err != nil {
+		log.Printf("Import: adopting %s failed: %v", result.ConversationID, err)
+		return "", syscall.EIO
+	}
+	log.Printf("Imported %d messages as conversation %s (%s)", len(messages), localID, result.ConversationID)
+	return localID, 0
+}
+
+// convImportHandle buffers a transcript and imports it on Flush or Fsync.
+type convImportHandle struct {
+	node     *ConvImportNode
+	mu       sync.Mutex
+	buffer   []byte
+	imported string // local ID once imported
+}
+
+var _ = (fs.FileWriter)((*convImportHandle)(nil))
+var _ = (fs.FileReader)((*convImportHandle)(nil))
+var _ = (fs.FileFlusher)((*convImportHandle)(nil))
+var _ = (fs.FileFsyncer)((*convImportHandle)(nil))
+
+func (h *convImportHandle) Write(ctx context.Context, data []byte, off int64) (uint32, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.imported != "" {
+		return 0, syscall.EBADF
+	}
+	h.buffer = append(h.buffer, data...)
+	return uint32(len(data)), 0
+}
+
+func (h *convImportHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.imported == "" {
+		return fuse.ReadResultData(nil), 0
+	}
+	return fuse.ReadResultData(readAt([]byte(h.imported+"\n"), dest, off)), 0
+}
+
+func (h *convImportHandle) Flush(ctx context.Context) syscall.Errno {
+	op := diag.Track(h.node.diag, "convImportHandle", "Flush", "")
+	defer op.Done()
+	return h.doImport(op)
+}
+
+func (h *convImportHandle) Fsync(ctx context.Context, flags uint32) syscall.Errno {
+	op := diag.Track(h.node.diag, "convImportHandle", "Fsync", "")
+	defer op.Done()
+	return h.doImport(op)
+}
+
+// doImport imports the buffered transcript once. An empty buffer is
+// left alone, so a dup'd descriptor closing early doesn't import nothing.
+func (h *convImportHandle) doImport(op *diag.OpHandle) syscall.Errno {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.imported != "" || len(bytes.TrimSpace(h.buffer)) == 0 {
+		return 0
+	}
+	localID, errno := h.node.importTranscript(op, h.buffer)
+	if errno != 0 {
+		return errno
+	}
+	h.imported = localID
+	h.buffer = nil
+	return 0
+}
+
+// transcriptMessage is a message as chat tools export it.
+type transcriptMessage struct {
+	Role    string          `json:"role"`
+	Type    string          `json:"type"`
+	Content json.RawMessage `json:"content"`
+	Text    json.RawMessage `json:"text"`
+	Message json.RawMessage `json:"message"`
+}
+
+// parseTranscript reads a JSON or JSONL transcript.
+func parseTranscript(data []byte) ([]shelley.ImportedMessage, error) {
+	data = bytes.TrimSpace(data)
+	var raw []transcriptMessage
+	switch {
+	case len(data) == 0:
+		return nil, errors.New("empty transcript")
+	case data[0] == '[':
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("transcript: %w", err)
+		}
+	default:
+		var wrapped struct {
+			Messages []transcriptMessage `json:"messages"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err == nil && wrapped.Messages != nil {
+			raw = wrapped.Messages
+			break
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, len(data)+1)
+		for line := 1; scanner.Scan(); line++ {
+			if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+				continue
+			}
+			var m transcriptMessage
+			if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+				return nil, fmt.Errorf("transcript line %d: %w", line, err)
+			}
+			raw = append(raw, m)
+		}
+	}
+
+	var messages []shelley.ImportedMessage
+	for i, m := range raw {
+		role := m.Role
+		if role == "" {
+			role = m.Type
+		}
+		var typ string
+		switch strings.ToLower(role) {
+		case "user", "human":
+			typ = "user"
+		case "assistant", "agent", "model", "ai", "bot":
+			typ = "agent"
+		case "system":
+			continue
+		default:
+			return nil, fmt.Errorf("transcript message %d: unknown role %q", i+1, role)
+		}
+		text, err := transcriptText(m)
+		if err != nil {
+			return nil, fmt.Errorf("transcript message %d: %w", i+1, err)
+		}
+		messages = append(messages, shelley.ImportedMessage{Type: typ, Text: text})
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("transcript has no messages")
+	}
+	return messages, nil
+}
+
+// transcriptText returns the text of m: a string, or the text parts of a
+// list of content parts joined by blank lines.
+func transcriptText(m transcriptMessage) (string, error) {
+	for _, field := range []json.RawMessage{m.Content, m.Text, m.Message} {
+		if len(field) == 0 || string(field) == "null" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(field, &s); err == nil {
+			return s, nil
+		}
+		var parts []struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(field, &parts); err != nil {
+			return "", errors.New("content is neither text nor a list of parts")
+		}
+		var texts []string
+		for _, p := range parts {
+			if p.Text != "" {
+				texts = append(texts, p.Text)
+			}
+		}
+		return strings.Join(texts, "\n\n"), nil
+	}
+	return "", errors.New("no content")
+}
@@ -0,0 +1,98 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestConversationImport(t *testing.T) {
+	server := mockserver.New(mockserver.WithImport())
+	defer server.Close()
+	store := testStore(t)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+	importPath := filepath.Join(convDir, "import")
+
+	transcript := `{"role":"user","content":"What is FUSE?"}
+{"role":"assistant","content":[{"type":"text","text":"A userspace filesystem."}]}
+`
+	if err := os.WriteFile(importPath, []byte(transcript), 0); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	localID := store.GetByShelleyID("imported-1")
+	if localID == "" {
+		t.Fatal("imported conversation not adopted")
+	}
+	all, err := os.ReadFile(filepath.Join(convDir, localID, "messages", "all.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"What is FUSE?", "A userspace filesystem."} {
+		if !strings.Contains(string(all), want) {
+			t.Errorf("all.md of the imported conversation misses %q:\n%s", want, all)
+		}
+	}
+
+	// Read-write: the local ID is read back after fsync.
+	f, err := os.OpenFile(importPath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(`[{"role":"user","content":"Again"}]`); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatalf("fsync: %v", err)
+	}
+	buf := make([]byte, 64)
+	n, _ := f.ReadAt(buf, 0)
+	if got, want := strings.TrimSpace(string(buf[:n])), store.GetByShelleyID("imported-2"); got == "" || got != want {
+		t.Errorf("read after fsync = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(importPath, []byte("not a transcript\n"), 0); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("import of garbage: got %v, want EINVAL", err)
+	}
+}
+
+func TestConversationImportUnsupported(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), testStore(t), time.Hour))
+	defer cleanup()
+
+	err := os.WriteFile(filepath.Join(mountPoint, "conversation", "import"), []byte(`[{"role":"user","content":"Hi"}]`), 0)
+	if !errors.Is(err, syscall.EROFS) {
+		t.Errorf("import without backend support: got %v, want EROFS", err)
+	}
+}
+
+func TestParseTranscript(t *testing.T) {
+	want := []shelley.ImportedMessage{{Type: "user", Text: "Hi"}, {Type: "agent", Text: "Hello\n\nthere"}}
+	for _, in := range []string{
+		`[{"role":"system","content":"Be brief."},{"role":"user","content":"Hi"},{"role":"assistant","content":[{"type":"text","text":"Hello"},{"type":"text","text":"there"}]}]`,
+		`{"messages":[{"type":"human","text":"Hi"},{"type":"ai","text":"Hello\n\nthere"}]}`,
+		"{\"role\":\"user\",\"message\":\"Hi\"}\n\n{\"role\":\"model\",\"content\":\"Hello\\n\\nthere\"}\n",
+	} {
+		got, err := parseTranscript([]byte(in))
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("parseTranscript(%s) = %+v, %v", in, got, err)
+		}
+	}
+	for _, in := range []string{``, `[]`, `[{"role":"narrator","content":"x"}]`, `[{"role":"user"}]`, `{"role":"user","content":"x"`} {
+		if _, err := parseTranscript([]byte(in)); err == nil {
+			t.Errorf("parseTranscript(%q) succeeded", in)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package fuse
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"syscall"
+
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- @@include@@ references in send ---
+// A message written to send may quote earlier messages of the same
+// conversation with `@@include PATH@@`, PATH being relative to the
+// conversation directory. The reference is replaced by what reading that
+// file shows before the message goes out, so an agent can quote context
+// without reading the files and concatenating them itself. Supported are
+// messages/all.md and messages/{NNN}-{slug}/content.md; the number may be
+// written with or without its zero padding. A reference that doesn't
+// resolve fails the send.
+
+var includeRe = regexp.MustCompile(`@@include\s+(\S+?)\s*@@`)
+
+// expandIncludes replaces the @@include@@ references in message. Messages
+// without any are returned as they are, without fetching the conversation.
+func (h *ConvSendFileHandle) expandIncludes(op *diag.OpHandle, cs *state.ConversationState, message string) (string, syscall.Errno) {
+	if !includeRe.MatchString(message) {
+		return message, 0
+	}
+	if !cs.Created || cs.ShelleyConversationID == "" {
+		log.Printf("Send to %s: @@include@@ in the first message has nothing to refer to", h.node.localID)
+		return "", syscall.ENOENT
+	}
+	op.SetPhase("HTTP GET GetConversation (include)")
+	convData, err := h.node.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return "", backendErrno(err)
+	}
+	result, err := h.node.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return "", syscall.EIO
+	}
+	expanded, err := expandIncludes(message, result)
+	if err != nil {
+		log.Printf("Send to %s: %v", h.node.localID, err)
+		return "", syscall.ENOENT
+	}
+	return expanded, 0
+}
+
+// expandIncludes replaces each @@include PATH@@ in message with the content
+// of PATH rendered from result.
+func expandIncludes(message string, result *ParseResult) (string, error) {
+	var firstErr error
+	expanded := includeRe.ReplaceAllStringFunc(message, func(ref string) string {
+		path := includeRe.FindStringSubmatch(ref)[1]
+		content, err := includeContent(path, result)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return content
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return expanded, nil
+}
+
+// includeContent renders the file at path, relative to the conversation
+// directory, the way reading it would.
+func includeContent(path string, result *ParseResult) (string, error) {
+	parts := strings.Split(strings.TrimPrefix(path, "./"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "messages" && parts[1] == "all.md":
+		return string(shelley.FormatMarkdown(result.Messages)), nil
+	case len(parts) == 3 && parts[0] == "messages" && parts[2] == "content.md":
+		seqNum, ok := parseMessageDirName(parts[1])
+		if !ok {
+			break
+		}
+		msg := shelley.GetMessage(result.Messages, seqNum)
+		if msg == nil {
+			return "", fmt.Errorf("include %s: no such message", path)
+		}
+		// The slug must match, so a stale reference doesn't quote the
+		// wrong message.
+		num, slug, _ := strings.Cut(parts[1], "-")
+		if actual := shelley.MessageSlug(msg, result.ToolMap); slug != actual {
+			return "", fmt.Errorf("include %s: message %s is %s", path, num, actual)
+		}
+		return string(shelley.FormatMarkdown([]shelley.Message{*msg})), nil
+	}
+	return "", fmt.Errorf("include %s: only messages/all.md and messages/{NNN}-{slug}/content.md can be included", path)
+}
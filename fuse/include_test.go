@@ -0,0 +1,89 @@
+package fuse
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestSendIncludes(t *testing.T) {
+	question, answer := "Which port?", `{"Content":[{"Type":2,"Text":"Port 8080."}]}`
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-inc", SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: "m2", ConversationID: "conv-inc", SequenceID: 2, Type: "agent", LLMData: &answer},
+	}
+	var mu sync.Mutex
+	var sent []string
+	server := mockserver.New(
+		mockserver.WithConversation("conv-inc", msgs),
+		mockserver.WithChatHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req shelley.ChatRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			sent = append(sent, req.Message)
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-inc")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	sendPath := filepath.Join(mountPoint, "conversation", localID, "send")
+
+	// The reference is replaced by what messages/1-agent/content.md shows.
+	if err := os.WriteFile(sendPath, []byte("You said:\n@@include messages/1-agent/content.md@@\nWhy?\n"), 0); err != nil {
+		t.Fatalf("send with include: %v", err)
+	}
+	want := "You said:\n" + string(shelley.FormatMarkdown(msgs[1:])) + "\nWhy?"
+	mu.Lock()
+	if len(sent) != 1 || sent[0] != want {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+	mu.Unlock()
+
+	// A reference to the wrong message fails the send.
+	err := os.WriteFile(sendPath, []byte("@@include messages/0-agent/content.md@@\n"), 0)
+	if !errors.Is(err, syscall.ENOENT) {
+		t.Errorf("send with a stale include: got %v, want ENOENT", err)
+	}
+	mu.Lock()
+	if len(sent) != 1 {
+		t.Errorf("stale include was sent: %q", sent[1:])
+	}
+	mu.Unlock()
+}
+
+func TestExpandIncludes(t *testing.T) {
+	text := "Hi"
+	result := &ParseResult{Messages: []shelley.Message{
+		{MessageID: "m1", SequenceID: 1, Type: "user", UserData: &text},
+	}}
+	all := string(shelley.FormatMarkdown(result.Messages))
+	for _, tc := range []struct {
+		in, want string
+		ok       bool
+	}{
+		{"no references", "no references", true},
+		{"@@include messages/all.md@@", all, true},
+		{"[@@include ./messages/000-user/content.md @@]", "[" + all + "]", true},
+		{"@@include messages/0-user/content.md@@", all, true},
+		{"@@include messages/1-user/content.md@@", "", false},
+		{"@@include ctl@@", "", false},
+	} {
+		got, err := expandIncludes(tc.in, result)
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("expandIncludes(%q) = %q, %v; want %q (ok=%v)", tc.in, got, err, tc.want, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+)
+
+// --- IndexNode: /conversation/index.json ---
+// Every conversation /conversation lists, as one JSON array, so a program
+// gets IDs, slugs, models and times in a single read instead of a Readdir
+// and a stat or two per conversation. Reading it adopts the server's
+// conversations just like listing the directory does.
+
+type IndexNode struct {
+	fs.Inode
+	list *ConversationListNode
+}
+
+var _ = (fs.NodeOpener)((*IndexNode)(nil))
+var _ = (fs.NodeGetattrer)((*IndexNode)(nil))
+
+// indexEntry is one conversation in index.json. Times are the server's,
+// RFC3339; created_at falls back to the local creation time.
+type indexEntry struct {
+	LocalID   string `json:"local_id"`
+	ShelleyID string `json:"shelley_id"`
+	Slug      string `json:"slug"`
+	Model     string `json:"model"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// index renders index.json, in the order the conversations are listed.
+func (n *IndexNode) index(op *diag.OpHandle) []byte {
+	listed, _ := n.list.listedConversations(op)
+	entries := make([]indexEntry, 0, len(listed))
+	for _, cs := range listed {
+		created := cs.APICreatedAt
+		if created == "" && !cs.CreatedAt.IsZero() {
+			created = cs.CreatedAt.UTC().Format(time.RFC3339)
+		}
+		entries = append(entries, indexEntry{
+			LocalID:   cs.LocalID,
+			ShelleyID: cs.ShelleyConversationID,
+			Slug:      cs.Slug,
+			Model:     cs.Model,
+			CreatedAt: created,
+			UpdatedAt: cs.APIUpdatedAt,
+		})
+	}
+	data, _ := json.MarshalIndent(entries, "", "  ")
+	return append(data, '\n')
+}
+
+// Open renders the index once; the handle reads and sizes that snapshot.
+func (n *IndexNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	op := diag.Track(n.list.diag, "IndexNode", "Open", "")
+	defer op.Done()
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &ConvContentFileHandle{content: n.index(op), messageTime: n.list.startTime}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *IndexNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fg, ok := f.(fs.FileGetattrer); ok {
+		return fg.Getattr(ctx, out)
+	}
+	// Without an open handle the size isn't known without listing the
+	// server; report 0, and DIRECT_IO still makes the kernel read.
+	out.Mode = fuse.S_IFREG | 0444
+	setTimestamps(&out.Attr, n.list.startTime)
+	return 0
+}
@@ -0,0 +1,47 @@
+package fuse
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestConversationIndex(t *testing.T) {
+	slug, model := "fix-the-build", "claude-test"
+	server := mockserver.New(
+		mockserver.WithFullConversation(shelley.Conversation{
+			ConversationID: "conv-indexed", Slug: &slug, Model: &model,
+			CreatedAt: "2024-01-15T10:30:00Z", UpdatedAt: "2024-01-15T11:00:00Z",
+		}, nil),
+	)
+	defer server.Close()
+	store := testStore(t)
+	store.Clone() // pending clones aren't listed
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	data, err := os.ReadFile(filepath.Join(mountPoint, "conversation", "index.json"))
+	if err != nil {
+		t.Fatalf("read index.json: %v", err)
+	}
+	var entries []indexEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatalf("index.json isn't a JSON array: %v\n%s", err, data)
+	}
+	want := indexEntry{
+		LocalID:   store.GetByShelleyID("conv-indexed"),
+		ShelleyID: "conv-indexed",
+		Slug:      slug,
+		Model:     model,
+		CreatedAt: "2024-01-15T10:30:00Z",
+		UpdatedAt: "2024-01-15T11:00:00Z",
+	}
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("index.json = %+v, want [%+v]", entries, want)
+	}
+}
@@ -115,7 +115,29 @@ func skipIfNoShelley(t *testing.T) {
 	}
 }
 
+// startShelleyServer starts /usr/local/bin/shelley on a free port and
+// returns its URL. A port is only known to be free while it is being
+// picked: a test run in another job on the same machine can bind it before
+// this server does, and would then answer this run's health checks. So the
+// server only counts as up once the port listens on a socket of its own
+// process, and if it exits first (having lost the port) it is started
+// again on another one.
 func startShelleyServer(t *testing.T) string {
+	t.Helper()
+	const attempts = 5
+	for i := 0; i < attempts; i++ {
+		if serverURL, ok := tryStartShelleyServer(t); ok {
+			return serverURL
+		}
+	}
+	t.Fatalf("Shelley server failed to start after %d attempts", attempts)
+	return ""
+}
+
+// tryStartShelleyServer starts a Shelley server on a port that is free
+// right now. It reports false if the server exited before it was up, which
+// is what losing the port to another process looks like.
+func tryStartShelleyServer(t *testing.T) (string, bool) {
 	t.Helper()
 	l, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
@@ -144,22 +166,65 @@ func startShelleyServer(t *testing.T) string {
 	if err := cmd.Start(); err != nil {
 		t.Fatalf("Failed to start shelley server: %v", err)
 	}
-	t.Cleanup(func() { cmd.Process.Kill(); cmd.Wait() })
+	exited := make(chan struct{})
+	go func() { cmd.Wait(); close(exited) }()
+	t.Cleanup(func() { cmd.Process.Kill(); <-exited })
 
 	serverURL := fmt.Sprintf("http://localhost:%d", port)
 	deadline := time.Now().Add(10 * time.Second)
 	client := &http.Client{Timeout: time.Second}
 	for time.Now().Before(deadline) {
-		if resp, err := client.Get(serverURL); err == nil {
-			resp.Body.Close()
-			if resp.StatusCode == http.StatusOK {
-				return serverURL
+		select {
+		case <-exited:
+			t.Logf("Shelley server exited before listening on port %d, retrying on another port", port)
+			return "", false
+		default:
+		}
+		if listensOn(cmd.Process.Pid, port) {
+			if resp, err := client.Get(serverURL); err == nil {
+				resp.Body.Close()
+				if resp.StatusCode == http.StatusOK {
+					return serverURL, true
+				}
 			}
 		}
 		time.Sleep(100 * time.Millisecond)
 	}
 	t.Fatalf("Shelley server failed to start on port %d", port)
-	return ""
+	return "", false
+}
+
+// listensOn reports whether process pid has a TCP socket listening on
+// port, going by /proc: the socket inodes among its file descriptors
+// against the listening sockets of /proc/net/tcp and tcp6.
+func listensOn(pid, port int) bool {
+	fds, err := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	if err != nil {
+		return false
+	}
+	inodes := make(map[string]bool)
+	for _, fd := range fds {
+		link, err := os.Readlink(fmt.Sprintf("/proc/%d/fd/%s", pid, fd.Name()))
+		if err == nil && strings.HasPrefix(link, "socket:[") {
+			inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] = true
+		}
+	}
+	portHex := fmt.Sprintf(":%04X", port)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		data, err := os.ReadFile(table)
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n")[1:] {
+			// sl local_address rem_address st ... uid timeout inode
+			fields := strings.Fields(line)
+			const listen = "0A"
+			if len(fields) > 9 && strings.HasSuffix(fields[1], portHex) && fields[3] == listen && inodes[fields[9]] {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // testMount holds the resources created by mountTestFSFull: the FUSE mount
@@ -779,7 +844,7 @@ func TestServerConversationAdoption(t *testing.T) {
 
 	// Create conversation directly via API
 	client := shelley.NewClient(serverURL)
-	result, err := client.StartConversation("Hello from API", "predictable", t.TempDir())
+	result, err := client.StartConversation("Hello from API", "predictable", t.TempDir(), "")
 	if err != nil {
 		t.Fatalf("Failed to create server conversation: %v", err)
 	}
@@ -1064,7 +1129,7 @@ func TestSlugSymlink(t *testing.T) {
 
 	// Create conversation with slug via API
 	client := shelley.NewClient(serverURL)
-	result, err := client.StartConversation("Test for slug", "predictable", t.TempDir())
+	result, err := client.StartConversation("Test for slug", "predictable", t.TempDir(), "")
 	if err != nil {
 		t.Fatalf("Failed to create conversation: %v", err)
 	}
@@ -2160,9 +2225,8 @@ func TestBackendDirectory(t *testing.T) {
 	// --- Test BackendNode directory contents ---
 
 	// List backend/main directory entries
-	// Note: ioutil.ReadDir filters out entries that can't be stat'd, so we only
-	// see entries that actually exist (url, new). The placeholder entries
-	// (connected, model, conversation) appear in Readdir but return ENOENT on Lookup.
+	// The connected presence file isn't implemented yet (sf-u12r) and is
+	// not listed.
 	mainDirEntries, err := ioutil.ReadDir(filepath.Join(mountPoint, "shelley", "backend", "main"))
 	if err != nil {
 		t.Fatalf("Failed to read backend/main directory: %v", err)
@@ -2180,8 +2244,9 @@ func TestBackendDirectory(t *testing.T) {
 		t.Error("Expected 'new' symlink in backend/main")
 	}
 
-	// Note: connected, model, conversation are in Readdir but return ENOENT on Lookup,
-	// so they don't appear in ReadDir results. They'll be implemented in future tickets.
+	if mainDirNames["connected"] {
+		t.Error("backend/main lists 'connected', which has no node yet")
+	}
 
 	// Read url file - should contain the server URL
 	urlContent, err := ioutil.ReadFile(filepath.Join(mountPoint, "shelley", "backend", "main", "url"))
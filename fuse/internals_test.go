@@ -9,16 +9,24 @@ package fuse
 // - Internal hash functions
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
@@ -557,7 +565,7 @@ func TestTimestamps_NeverZero(t *testing.T) {
 
 func TestReadmeNode_Read(t *testing.T) {
 	node := &ReadmeNode{}
-	dest := make([]byte, 8192)
+	dest := make([]byte, len(readmeContent)+1)
 	result, errno := node.Read(context.Background(), nil, dest, 0)
 	if errno != 0 {
 		t.Fatalf("Read failed with errno %d", errno)
@@ -669,11 +677,11 @@ func TestModelNode_Readdir(t *testing.T) {
 		entries = append(entries, entry)
 	}
 
-	if len(entries) != 3 {
-		t.Fatalf("expected 3 entries (id, new, ready), got %d", len(entries))
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries (id, new, wait_ready, ready), got %d", len(entries))
 	}
 
-	expectedModes := map[string]uint32{"id": fuse.S_IFREG, "new": fuse.S_IFDIR, "ready": fuse.S_IFREG}
+	expectedModes := map[string]uint32{"id": fuse.S_IFREG, "new": fuse.S_IFDIR, "wait_ready": fuse.S_IFREG, "ready": fuse.S_IFREG}
 	found := map[string]bool{}
 	for _, e := range entries {
 		expMode, ok := expectedModes[e.Name]
@@ -761,10 +769,10 @@ func TestModelNewDirNode_Readdir(t *testing.T) {
 		entries = append(entries, entry)
 	}
 
-	if len(entries) != 2 {
-		t.Fatalf("expected 2 entries (clone, start), got %d", len(entries))
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries (clone, clone.json, start), got %d", len(entries))
 	}
-	expected := map[string]bool{"clone": false, "start": false}
+	expected := map[string]bool{"clone": false, "clone.json": false, "start": false}
 	for _, e := range entries {
 		if _, ok := expected[e.Name]; !ok {
 			t.Errorf("unexpected entry %q", e.Name)
@@ -782,6 +790,41 @@ func TestModelNewDirNode_Readdir(t *testing.T) {
 	}
 }
 
+func TestModelCloneNode_JSON(t *testing.T) {
+	store := testStore(t)
+	model := shelley.Model{ID: "test-model", DisplayName: "Test Model", Ready: true}
+	node := &ModelCloneNode{model: model, state: store, asJSON: true}
+
+	fh, _, errno := node.Open(context.Background(), 0)
+	if errno != 0 {
+		t.Fatalf("Open failed with errno %d", errno)
+	}
+	dest := make([]byte, 256)
+	result, errno := fh.(*CloneFileHandle).Read(context.Background(), dest, 0)
+	if errno != 0 {
+		t.Fatalf("Read failed with errno %d", errno)
+	}
+	data, _ := result.Bytes(nil)
+
+	var got struct {
+		LocalID string `json:"local_id"`
+		Path    string `json:"path"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("clone.json is not valid JSON: %v (%q)", err, data)
+	}
+	if got.Path != "conversation/"+got.LocalID {
+		t.Errorf("path = %q, want %q", got.Path, "conversation/"+got.LocalID)
+	}
+	cs := store.Get(got.LocalID)
+	if cs == nil {
+		t.Fatalf("clone.json returned unknown local ID %q", got.LocalID)
+	}
+	if cs.ModelID != "test-model" {
+		t.Errorf("cloned conversation model ID = %q, want test-model", cs.ModelID)
+	}
+}
+
 func TestModelsDirNode_EmptyModels(t *testing.T) {
 	// Server returns empty model list
 	server := mockModelsServer(t, []shelley.Model{})
@@ -961,10 +1004,10 @@ func TestMessagesDirNodeReaddirWithToolCalls(t *testing.T) {
 	}
 
 	// Expected entries:
-	// - Static: all.json, all.md, count, last, since
+	// - Static: all.json, all.md, all.org, count, diff, filter, last, pinned, since, stream, tokens, words
 	// - Message directories: 0-user, 1-bash-tool, 2-bash-result, 3-agent (0-indexed)
 	expected := []string{
-		"all.json", "all.md", "count", "last", "since",
+		"all.json", "all.md", "all.org", "count", "diff", "filter", "last", "pinned", "since", "stream", "tokens", "words",
 		"0-user",
 		"1-bash-tool",
 		"2-bash-result",
@@ -988,6 +1031,231 @@ func TestMessagesDirNodeReaddirWithToolCalls(t *testing.T) {
 	}
 }
 
+// TestMessageDirToolResultFiles verifies that tool result messages expose
+// their raw payload as result.{ext}, with the extension and size matching the
+// detected payload type, and that other messages have no result file.
+func TestMessageDirToolResultFiles(t *testing.T) {
+	convID := "test-conv-results"
+	png := []byte("\x89PNG\r\n\x1a\nimage-bytes")
+	pngResult := fmt.Sprintf(`{"Content": [{"Type": 6, "ToolUseID": "tu_2", "ToolResult": [{"MediaType": "image/png", "Data": %q}]}]}`,
+		base64.StdEncoding.EncodeToString(png))
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: convID, SequenceID: 1, Type: "shelley", LLMData: strPtr(`{"Content": [{"Type": 5, "ID": "tu_1", "ToolName": "bash"}, {"Type": 5, "ID": "tu_2", "ToolName": "browser"}]}`)},
+		{MessageID: "m2", ConversationID: convID, SequenceID: 2, Type: "user", UserData: strPtr(`{"Content": [{"Type": 6, "ToolUseID": "tu_1", "ToolResult": [{"Text": "{\"files\": 2}"}]}]}`)},
+		{MessageID: "m3", ConversationID: convID, SequenceID: 3, Type: "user", UserData: strPtr(pngResult)},
+	}
+
+	server := mockserver.New(mockserver.WithConversation(convID, msgs))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, convID, "")
+
+	tmpDir, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+	msgDir := filepath.Join(tmpDir, "conversation", localID, "messages")
+
+	jsonPath := filepath.Join(msgDir, "1-bash-result", "result.json")
+	data, err := ioutil.ReadFile(jsonPath)
+	if err != nil {
+		t.Fatalf("Failed to read result.json: %v", err)
+	}
+	if string(data) != `{"files": 2}` {
+		t.Errorf("result.json = %q, want %q", data, `{"files": 2}`)
+	}
+	if _, err := os.Stat(filepath.Join(msgDir, "1-bash-result", "result.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected ENOENT for result.txt on a JSON result, got %v", err)
+	}
+
+	pngPath := filepath.Join(msgDir, "2-browser-result", "result.png")
+	info, err := os.Stat(pngPath)
+	if err != nil {
+		t.Fatalf("Failed to stat result.png: %v", err)
+	}
+	if info.Size() != int64(len(png)) {
+		t.Errorf("result.png size = %d, want %d", info.Size(), len(png))
+	}
+	data, err = ioutil.ReadFile(pngPath)
+	if err != nil {
+		t.Fatalf("Failed to read result.png: %v", err)
+	}
+	if !bytes.Equal(data, png) {
+		t.Errorf("result.png = %q, want %q", data, png)
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Join(msgDir, "2-browser-result"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	found := false
+	for _, e := range entries {
+		if e.Name() == "result.png" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("result.png missing from Readdir: %v", entries)
+	}
+
+	entries, err = ioutil.ReadDir(filepath.Join(msgDir, "0-bash-tool"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "result.") {
+			t.Errorf("Tool call message should not have %s", e.Name())
+		}
+	}
+}
+
+func TestMessageDirXattrs(t *testing.T) {
+	convID := "test-conv-xattr"
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: convID, SequenceID: 1, Type: "user", CreatedAt: "2024-01-15T10:30:00Z",
+			UserData: strPtr(`{"Content": [{"Type": 2, "Text": "hello"}]}`)},
+	}
+	server := mockserver.New(mockserver.WithConversation(convID, msgs))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, convID, "")
+
+	tmpDir, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+	dir := filepath.Join(tmpDir, "conversation", localID, "messages", "0-user")
+
+	size, err := syscall.Listxattr(dir, nil)
+	if err != nil {
+		t.Fatalf("Listxattr size query failed: %v", err)
+	}
+	buf := make([]byte, size)
+	n, err := syscall.Listxattr(dir, buf)
+	if err != nil {
+		t.Fatalf("Listxattr failed: %v", err)
+	}
+	names := strings.Split(strings.TrimSuffix(string(buf[:n]), "\x00"), "\x00")
+	want := []string{"user.shelley.message_id", "user.shelley.conversation_id", "user.shelley.sequence_id",
+		"user.shelley.type", "user.shelley.created_at", "user.shelley.created_at_unix", "user.shelley.content.md"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("xattr names = %v, want %v", names, want)
+	}
+
+	get := func(name string) string {
+		t.Helper()
+		val := make([]byte, 4096)
+		n, err := syscall.Getxattr(dir, name, val)
+		if err != nil {
+			t.Fatalf("Getxattr %s failed: %v", name, err)
+		}
+		return string(val[:n])
+	}
+	if got := get("user.shelley.message_id"); got != "m1" {
+		t.Errorf("message_id = %q, want m1", got)
+	}
+	if got := get("user.shelley.created_at_unix"); got != "1705314600" {
+		t.Errorf("created_at_unix = %q, want 1705314600", got)
+	}
+	if got := get("user.shelley.content.md"); !strings.Contains(got, "hello") {
+		t.Errorf("content.md = %q, want it to contain the message text", got)
+	}
+
+	if _, err := syscall.Getxattr(dir, "user.shelley.llm_data", make([]byte, 64)); err != syscall.ENODATA {
+		t.Errorf("expected ENODATA for absent llm_data, got %v", err)
+	}
+	if _, err := syscall.Getxattr(dir, "user.shelley.message_id", make([]byte, 1)); err != syscall.ERANGE {
+		t.Errorf("expected ERANGE for short buffer, got %v", err)
+	}
+}
+
+func TestConversationDirXattrs(t *testing.T) {
+	convID := "test-conv-dir-xattr"
+	slug := "xattr-slug"
+	usage := `{"input_tokens":100,"cache_read_input_tokens":20,"output_tokens":7}`
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: convID, SequenceID: 1, Type: "user", UserData: strPtr("hi")},
+		{MessageID: "m2", ConversationID: convID, SequenceID: 2, Type: "shelley", LLMData: strPtr("hello"), UsageData: &usage},
+	}
+	server := mockserver.New(mockserver.WithFullConversation(shelley.Conversation{
+		ConversationID: convID, Slug: &slug, CreatedAt: "2024-01-15T10:30:00Z", UpdatedAt: "2024-01-15T10:31:00Z",
+	}, msgs))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.SetCtl(localID, "model", "claude-test")
+	store.MarkCreated(localID, convID, slug)
+	// The server's timestamps reach the state from its conversation list.
+	store.AdoptWithMetadata(convID, slug, "2024-01-15T10:30:00Z", "2024-01-15T10:31:00Z", "", "")
+
+	// The fields don't depend on the mount.
+	node := &ConversationNode{localID: localID, client: shelley.NewClient(server.URL), state: store, parsedCache: NewParsedMessageCache()}
+	_, values := node.xattrFields()
+	for name, want := range map[string]string{
+		"created_at":      "2024-01-15T10:30:00Z",
+		"created_at_unix": "1705314600",
+		"updated_at":      "2024-01-15T10:31:00Z",
+		"updated_at_unix": "1705314660",
+		"message_count":   "2",
+	} {
+		if values[name] != want {
+			t.Errorf("field %s = %q, want %q", name, values[name], want)
+		}
+	}
+
+	tmpDir, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+	dir := filepath.Join(tmpDir, "conversation", localID)
+
+	buf := make([]byte, 4096)
+	n, err := syscall.Listxattr(dir, buf)
+	if err != nil {
+		t.Fatalf("Listxattr failed: %v", err)
+	}
+	names := strings.Split(strings.TrimSuffix(string(buf[:n]), "\x00"), "\x00")
+	want := []string{"user.shelley.local_id", "user.shelley.conversation_id", "user.shelley.slug", "user.shelley.model",
+		"user.shelley.created_at", "user.shelley.created_at_unix", "user.shelley.updated_at", "user.shelley.updated_at_unix",
+		"user.shelley.message_count", "user.shelley.input_tokens", "user.shelley.output_tokens"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("xattr names = %v, want %v", names, want)
+	}
+
+	get := func(path, name string) string {
+		t.Helper()
+		val := make([]byte, 4096)
+		n, err := syscall.Getxattr(path, name, val)
+		if err != nil {
+			t.Fatalf("Getxattr %s failed: %v", name, err)
+		}
+		return string(val[:n])
+	}
+	for name, want := range map[string]string{
+		"conversation_id": convID,
+		"slug":            slug,
+		"model":           "claude-test",
+		"message_count":   "2",
+		"input_tokens":    "120",
+		"output_tokens":   "7",
+	} {
+		if got := get(dir, "user.shelley."+name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := syscall.Getxattr(dir, "security.selinux", make([]byte, 64)); err != syscall.ENODATA {
+		t.Errorf("expected ENODATA outside user.shelley., got %v", err)
+	}
+
+	// Messages with usage_data carry their own token counts.
+	msgDir := filepath.Join(dir, "messages", "1-agent")
+	if got := get(msgDir, "user.shelley.input_tokens"); got != "120" {
+		t.Errorf("message input_tokens = %q, want 120", got)
+	}
+	if got := get(msgDir, "user.shelley.output_tokens"); got != "7" {
+		t.Errorf("message output_tokens = %q, want 7", got)
+	}
+}
+
 // TestMessageFieldStableInodes verifies that message field nodes use stable,
 // deterministic inode numbers derived from (conversationID, sequenceID, fieldName).
 // This allows the kernel to recognize the same logical file across lookups.
@@ -1047,7 +1315,7 @@ func TestMessageFieldStableInodes(t *testing.T) {
 	userDir := filepath.Join(msgDir, "0-user")
 	agentDir := filepath.Join(msgDir, "1-agent")
 
-	fields := []string{"message_id", "conversation_id", "sequence_id", "type", "created_at", "content.md"}
+	fields := []string{"message_id", "conversation_id", "sequence_id", "type", "created_at", "content.md", "content.txt"}
 
 	// Collect inodes from first stat pass
 	userInodes := make(map[string]uint64)
@@ -1188,6 +1456,9 @@ func TestConversationListNode_ReaddirLocalOnly(t *testing.T) {
 	var dirs, symlinks []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		if entry.Mode&syscall.S_IFLNK != 0 {
 			symlinks = append(symlinks, entry.Name)
 		} else if entry.Name != "last" {
@@ -1214,6 +1485,42 @@ func TestConversationListNode_ReaddirLocalOnly(t *testing.T) {
 	}
 }
 
+func TestConversationListNode_ModelCloneTimeouts(t *testing.T) {
+	server := mockConversationsServer(t, nil)
+	defer server.Close()
+	store := testStore(t)
+
+	quick, _ := store.Clone()
+	store.SetCtl(quick, "model", "claude-haiku")
+	slow, _ := store.Clone()
+	store.SetCtl(slow, "model", "claude-opus")
+	plain, _ := store.Clone()
+
+	node := &ConversationListNode{
+		client:       shelley.NewClient(server.URL),
+		state:        store,
+		cloneTimeout: time.Hour,
+		cloneByModel: map[string]time.Duration{"claude-haiku": time.Nanosecond, "claude-opus": 0},
+	}
+	time.Sleep(time.Millisecond)
+	if _, errno := node.Readdir(context.Background()); errno != 0 {
+		t.Fatalf("Readdir failed with errno %d", errno)
+	}
+
+	if store.Get(quick) != nil {
+		t.Error("clone with a short per-model timeout should have been cleaned up")
+	}
+	if store.Get(slow) == nil {
+		t.Error("clone with a zero per-model timeout should be kept")
+	}
+	if store.Get(plain) == nil {
+		t.Error("clone without a model should use the default timeout")
+	}
+	if got := node.cloneTimeoutFor(*store.Get(plain)); got != time.Hour {
+		t.Errorf("cloneTimeoutFor(no model) = %v, want 1h", got)
+	}
+}
+
 func TestConversationListNode_ReaddirServerConversationsAdopted(t *testing.T) {
 	// Server returns conversations, no prior local state.
 	// Readdir should adopt them all immediately, returning:
@@ -1243,6 +1550,9 @@ func TestConversationListNode_ReaddirServerConversationsAdopted(t *testing.T) {
 	var dirs, symlinks []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		if entry.Mode&syscall.S_IFLNK != 0 {
 			symlinks = append(symlinks, entry.Name)
 		} else if entry.Mode&syscall.S_IFDIR != 0 && entry.Name != "last" {
@@ -1336,6 +1646,9 @@ func TestConversationListNode_ReaddirMergedLocalAndServer(t *testing.T) {
 	var dirs, symlinks []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		if entry.Mode&syscall.S_IFLNK != 0 {
 			symlinks = append(symlinks, entry.Name)
 		} else if entry.Mode&syscall.S_IFDIR != 0 && entry.Name != "last" {
@@ -1431,6 +1744,9 @@ func TestConversationListNode_ReaddirServerError(t *testing.T) {
 	var dirs, symlinks []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		if entry.Mode&syscall.S_IFLNK != 0 {
 			symlinks = append(symlinks, entry.Name)
 		} else if entry.Name != "last" {
@@ -1481,6 +1797,9 @@ func TestConversationListNode_ReaddirFiltersStaleConversations(t *testing.T) {
 	var names []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		names = append(names, entry.Name)
 	}
 
@@ -1544,6 +1863,9 @@ func TestConversationListNode_ReaddirShowsStaleWhenServerFails(t *testing.T) {
 	var names []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		names = append(names, entry.Name)
 	}
 
@@ -1683,6 +2005,190 @@ func TestConversationListNode_LookupServerConversationIdempotent(t *testing.T) {
 	}
 }
 
+func TestConversationListNode_Passthrough(t *testing.T) {
+	serverConvs := []shelley.Conversation{
+		{ConversationID: "server-conv-pass", Slug: strPtr("pass-slug")},
+	}
+	server := mockConversationsServer(t, serverConvs)
+	defer server.Close()
+
+	store := testStore(t)
+	store.SetPassthrough(true)
+
+	mountPoint, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+
+	entries, err := os.ReadDir(filepath.Join(mountPoint, "conversation"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	modes := make(map[string]os.FileMode)
+	for _, e := range entries {
+		modes[e.Name()] = e.Type()
+	}
+	if !modes["server-conv-pass"].IsDir() {
+		t.Errorf("expected server ID as directory, got entries %v", modes)
+	}
+	if modes["pass-slug"]&os.ModeSymlink == 0 {
+		t.Errorf("expected slug symlink, got entries %v", modes)
+	}
+	target, err := os.Readlink(filepath.Join(mountPoint, "conversation", "pass-slug"))
+	if err != nil || target != "server-conv-pass" {
+		t.Errorf("slug symlink target = %q, %v; want server-conv-pass", target, err)
+	}
+
+	// Direct lookup resolves to the directory, not a self-referencing symlink.
+	info, err := os.Lstat(filepath.Join(mountPoint, "conversation", "server-conv-pass"))
+	if err != nil || !info.IsDir() {
+		t.Fatalf("Lstat server ID: %v, %v", info, err)
+	}
+
+	data, err := os.ReadFile(store.Path)
+	if err == nil && strings.Contains(string(data), "server-conv-pass") {
+		t.Errorf("passthrough conversation was written to the state file:\n%s", data)
+	}
+}
+
+func TestConversationListNode_SlugLayout(t *testing.T) {
+	serverConvs := []shelley.Conversation{
+		{ConversationID: "server-conv-slugged", Slug: strPtr("fix-the-build")},
+		{ConversationID: "server-conv-bare"},
+	}
+	server := mockConversationsServer(t, serverConvs)
+	defer server.Close()
+
+	store := testStore(t)
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetLayout(LayoutSlugs)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+
+	convDir := filepath.Join(mountPoint, "conversation")
+	entries, err := os.ReadDir(convDir)
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var dirs []string
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != "last" && e.Name() != "top" {
+			dirs = append(dirs, e.Name())
+		}
+	}
+	sluggedID := store.GetByShelleyID("server-conv-slugged")
+	bareID := store.GetByShelleyID("server-conv-bare")
+	sort.Strings(dirs)
+	want := []string{bareID, "fix-the-build"}
+	sort.Strings(want)
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("directories = %v, want %v", dirs, want)
+	}
+
+	// Local and server IDs of the slugged conversation point at the slug.
+	for _, name := range []string{sluggedID, "server-conv-slugged"} {
+		target, err := os.Readlink(filepath.Join(convDir, name))
+		if err != nil || target != "fix-the-build" {
+			t.Errorf("readlink %s = %q, %v; want fix-the-build", name, target, err)
+		}
+	}
+	// The conversation without a slug keeps its local-ID directory.
+	target, err := os.Readlink(filepath.Join(convDir, "server-conv-bare"))
+	if err != nil || target != bareID {
+		t.Errorf("readlink server-conv-bare = %q, %v; want %s", target, err, bareID)
+	}
+
+	data, err := os.ReadFile(filepath.Join(convDir, "fix-the-build", "fuse_id"))
+	if err != nil {
+		t.Fatalf("read fuse_id through slug directory: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != sluggedID {
+		t.Errorf("fuse_id = %q, want %s", data, sluggedID)
+	}
+}
+
+func TestMessagesDirNode_MarkdownChunks(t *testing.T) {
+	convID := "conv-chunked"
+	var msgs []shelley.Message
+	for i := 1; i <= 6; i++ {
+		msgs = append(msgs, shelley.Message{
+			MessageID: fmt.Sprintf("m%d", i), ConversationID: convID, SequenceID: i,
+			Type: "user", UserData: strPtr(strings.Repeat("x", 100)),
+		})
+	}
+	server := mockserver.New(mockserver.WithConversation(convID, msgs))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, convID, "")
+
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetMarkdownChunkSize(250)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+
+	msgDir := filepath.Join(mountPoint, "conversation", localID, "messages")
+	whole, err := os.ReadFile(filepath.Join(msgDir, "all.md"))
+	if err != nil {
+		t.Fatalf("read all.md: %v", err)
+	}
+	listing, err := os.ReadDir(msgDir)
+	if err != nil {
+		t.Fatalf("ReadDir messages: %v", err)
+	}
+	listed := false
+	for _, e := range listing {
+		listed = listed || (e.Name() == "all.md.d" && e.IsDir())
+	}
+	if !listed {
+		t.Error("all.md.d not listed in messages/")
+	}
+	entries, err := os.ReadDir(filepath.Join(msgDir, "all.md.d"))
+	if err != nil {
+		t.Fatalf("ReadDir all.md.d: %v", err)
+	}
+	if len(entries) < 2 || entries[0].Name() != "part-001.md" {
+		t.Fatalf("unexpected parts: %v", entries)
+	}
+	var joined []byte
+	for _, e := range entries {
+		part, err := os.ReadFile(filepath.Join(msgDir, "all.md.d", e.Name()))
+		if err != nil {
+			t.Fatalf("read %s: %v", e.Name(), err)
+		}
+		if len(part) > 250 {
+			t.Errorf("%s is %d bytes, over the chunk size", e.Name(), len(part))
+		}
+		joined = append(joined, part...)
+	}
+	if !bytes.Equal(joined, whole) {
+		t.Errorf("parts don't concatenate to all.md:\n%s", joined)
+	}
+	if _, err := os.Stat(filepath.Join(msgDir, "all.md.d", fmt.Sprintf("part-%03d.md", len(entries)+1))); !os.IsNotExist(err) {
+		t.Errorf("expected ENOENT past the last part, got %v", err)
+	}
+
+	// Below the threshold the directory doesn't exist.
+	shelleyFS2 := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS2.SetMarkdownChunkSize(len(whole))
+	mountPoint2, cleanup2 := mountFS(t, shelleyFS2)
+	defer cleanup2()
+	if _, err := os.Stat(filepath.Join(mountPoint2, "conversation", localID, "messages", "all.md.d")); !os.IsNotExist(err) {
+		t.Errorf("all.md.d should not exist below the threshold, got %v", err)
+	}
+}
+
+func TestParseLayout(t *testing.T) {
+	for in, want := range map[string]Layout{"": LayoutIDs, "ids": LayoutIDs, "slugs": LayoutSlugs} {
+		got, err := ParseLayout(in)
+		if err != nil || got != want {
+			t.Errorf("ParseLayout(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLayout("titles"); err == nil {
+		t.Error("expected error for unknown layout")
+	}
+}
+
 func TestConversationListNode_LookupServerError(t *testing.T) {
 	server := mockErrorServer(t)
 	defer server.Close()
@@ -1780,7 +2286,7 @@ func TestConversationListingMounted(t *testing.T) {
 	for _, entry := range entries {
 		if entry.Mode()&os.ModeSymlink != 0 {
 			symlinks = append(symlinks, entry.Name())
-		} else if entry.IsDir() && entry.Name() != "last" {
+		} else if entry.IsDir() && entry.Name() != "last" && entry.Name() != "top" {
 			dirs = append(dirs, entry.Name())
 		}
 	}
@@ -1858,6 +2364,9 @@ func TestConversationListNode_ReaddirUpdatesEmptySlugs(t *testing.T) {
 	var dirs, symlinks []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		if entry.Mode&syscall.S_IFLNK != 0 {
 			symlinks = append(symlinks, entry.Name)
 		} else if entry.Mode&syscall.S_IFDIR != 0 && entry.Name != "last" {
@@ -1923,6 +2432,9 @@ func TestConversationListNode_ReaddirWithSlugs(t *testing.T) {
 	var dirs, symlinks []string
 	for stream.HasNext() {
 		entry, _ := stream.Next()
+		if entry.Name == "import" || entry.Name == "attach" || entry.Name == "index.json" || entry.Name == "top" || entry.Name == ".bulk" {
+			continue // conversation/import, attach, top/ and .bulk, not conversations
+		}
 		if entry.Mode&syscall.S_IFLNK != 0 {
 			symlinks = append(symlinks, entry.Name)
 		} else if entry.Mode&syscall.S_IFDIR != 0 && entry.Name != "last" {
@@ -2916,6 +3428,51 @@ func TestConversationAPITimestampFields(t *testing.T) {
 	if !entryMap["updated_at"] {
 		t.Error("updated_at should be listed in directory")
 	}
+
+	// Epoch-second companions
+	for name, want := range map[string]string{"created_at_unix": "1705314600\n", "updated_at_unix": "1705316400\n"} {
+		if !entryMap[name] {
+			t.Errorf("%s should be listed in directory", name)
+		}
+		data, err := ioutil.ReadFile(filepath.Join(convDir, name))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", name, err)
+		}
+		if string(data) != want {
+			t.Errorf("%s: expected %q, got %q", name, want, string(data))
+		}
+	}
+}
+
+func TestMessageCreatedAtUnix(t *testing.T) {
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: "c1", SequenceID: 1, Type: "user", CreatedAt: "2024-01-15T10:30:00.5Z"},
+		{MessageID: "m2", ConversationID: "c1", SequenceID: 2, Type: "user", CreatedAt: "not-a-time"},
+	}
+	node := &MessageDirNode{message: msgs[0], startTime: time.Now()}
+	stream, errno := node.Readdir(context.Background())
+	if errno != 0 {
+		t.Fatalf("Readdir failed: %d", errno)
+	}
+	found := false
+	for stream.HasNext() {
+		e, _ := stream.Next()
+		found = found || e.Name == "created_at_unix"
+	}
+	if !found {
+		t.Error("created_at_unix should be listed for a valid timestamp")
+	}
+	if secs, ok := unixSeconds(msgs[0].CreatedAt); !ok || secs != "1705314600" {
+		t.Errorf("unixSeconds = %q, %v; want 1705314600", secs, ok)
+	}
+
+	node = &MessageDirNode{message: msgs[1], startTime: time.Now()}
+	stream, _ = node.Readdir(context.Background())
+	for stream.HasNext() {
+		if e, _ := stream.Next(); e.Name == "created_at_unix" {
+			t.Error("created_at_unix should be omitted for an unparseable timestamp")
+		}
+	}
 }
 
 // TestConversationAPITimestampFields_UncreatedConversation tests that timestamp fields don't exist for uncreated conversations.
@@ -3425,19 +3982,62 @@ func TestConversationListNode_Rmdir_ServerError(t *testing.T) {
 	}
 }
 
-func TestConversationListNode_Rmdir_ConversationDisappearsFromReaddir(t *testing.T) {
-	conv := shelley.Conversation{ConversationID: "server-conv-rmdir"}
+func TestConversationListNode_Unlink(t *testing.T) {
 	server := mockserver.New(
-		mockserver.WithFullConversation(conv, nil),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "server-conv-slug"}, nil),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "server-conv-id"}, nil),
 	)
 	defer server.Close()
 
 	store := testStore(t)
-	id, err := store.Clone()
-	if err != nil {
+	bySlug, _ := store.Clone()
+	if err := store.MarkCreated(bySlug, "server-conv-slug", "unlink-me"); err != nil {
 		t.Fatal(err)
 	}
-	if err := store.MarkCreated(id, "server-conv-rmdir", ""); err != nil {
+	byServerID, _ := store.Clone()
+	if err := store.MarkCreated(byServerID, "server-conv-id", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	mountDir, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+	convDir := filepath.Join(mountDir, "conversation")
+
+	// rm of a slug or server-ID symlink deletes the conversation behind it
+	if err := os.Remove(filepath.Join(convDir, "unlink-me")); err != nil {
+		t.Fatalf("rm slug: %v", err)
+	}
+	if store.Get(bySlug) != nil {
+		t.Error("conversation still in state after rm of its slug")
+	}
+	if err := os.Remove(filepath.Join(convDir, "server-conv-id")); err != nil {
+		t.Fatalf("rm server ID: %v", err)
+	}
+	if store.Get(byServerID) != nil {
+		t.Error("conversation still in state after rm of its server ID")
+	}
+
+	if err := syscall.Unlink(filepath.Join(convDir, "last")); err != syscall.EPERM && err != syscall.EISDIR {
+		t.Errorf("unlink last: got %v, want EPERM", err)
+	}
+	if err := syscall.Unlink(filepath.Join(convDir, "nonexistent-id")); err != syscall.ENOENT {
+		t.Errorf("unlink of a missing name: got %v, want ENOENT", err)
+	}
+}
+
+func TestConversationListNode_Rmdir_ConversationDisappearsFromReaddir(t *testing.T) {
+	conv := shelley.Conversation{ConversationID: "server-conv-rmdir"}
+	server := mockserver.New(
+		mockserver.WithFullConversation(conv, nil),
+	)
+	defer server.Close()
+
+	store := testStore(t)
+	id, err := store.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkCreated(id, "server-conv-rmdir", ""); err != nil {
 		t.Fatal(err)
 	}
 
@@ -4088,6 +4688,112 @@ func TestQueryResultDirNode_SincePersonN(t *testing.T) {
 	}
 }
 
+func TestQueryResultDirNode_Filter(t *testing.T) {
+	convID := "test-conv-filter"
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: convID, SequenceID: 1, Type: "user", UserData: strPtr("Run ls")},
+		{MessageID: "m2", ConversationID: convID, SequenceID: 2, Type: "shelley", LLMData: strPtr(`{"Content": [{"Type": 5, "ID": "tu_1", "ToolName": "bash"}]}`)},
+		{MessageID: "m3", ConversationID: convID, SequenceID: 3, Type: "user", UserData: strPtr(`{"Content": [{"Type": 6, "ToolUseID": "tu_1"}]}`)},
+		{MessageID: "m4", ConversationID: convID, SequenceID: 4, Type: "shelley", LLMData: strPtr("Done")},
+		{MessageID: "m5", ConversationID: convID, SequenceID: 5, Type: "user", UserData: strPtr("Thanks")},
+	}
+
+	server := mockserver.New(mockserver.WithConversation(convID, msgs))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, convID, "")
+
+	tmpDir, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+	filterDir := filepath.Join(tmpDir, "conversation", localID, "messages", "filter")
+
+	// filter/ lists the tool/ subdirectory plus the non-tool roles present
+	entries, err := ioutil.ReadDir(filterDir)
+	if err != nil {
+		t.Fatalf("Failed to read filter/: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "agent,tool,user" {
+		t.Errorf("filter/ entries = %v, want [agent tool user]", names)
+	}
+
+	// filter/user/ excludes the tool result, even though its type is "user"
+	userDir := filepath.Join(filterDir, "user")
+	entries, err = ioutil.ReadDir(userDir)
+	if err != nil {
+		t.Fatalf("Failed to read filter/user: %v", err)
+	}
+	names = nil
+	for _, e := range entries {
+		if e.Mode()&os.ModeSymlink == 0 {
+			t.Errorf("Expected symlink, got %s with mode %v", e.Name(), e.Mode())
+		}
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "0-user,4-user" {
+		t.Errorf("filter/user entries = %v, want [0-user 4-user]", names)
+	}
+	target, err := os.Readlink(filepath.Join(userDir, "4-user"))
+	if err != nil {
+		t.Fatalf("Readlink failed: %v", err)
+	}
+	if target != "../../4-user" {
+		t.Errorf("filter/user/4-user target = %q, want %q", target, "../../4-user")
+	}
+	data, err := ioutil.ReadFile(filepath.Join(userDir, "4-user", "message_id"))
+	if err != nil {
+		t.Fatalf("Failed to read through symlink: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "m5" {
+		t.Errorf("message_id = %q, want m5", data)
+	}
+
+	// filter/tool/ lists tool names; filter/tool/bash/ has the call and result
+	entries, err = ioutil.ReadDir(filepath.Join(filterDir, "tool"))
+	if err != nil {
+		t.Fatalf("Failed to read filter/tool: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "bash" {
+		t.Errorf("filter/tool entries = %v, want [bash]", entries)
+	}
+	bashDir := filepath.Join(filterDir, "tool", "bash")
+	entries, err = ioutil.ReadDir(bashDir)
+	if err != nil {
+		t.Fatalf("Failed to read filter/tool/bash: %v", err)
+	}
+	names = nil
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if strings.Join(names, ",") != "1-bash-tool,2-bash-result" {
+		t.Errorf("filter/tool/bash entries = %v, want [1-bash-tool 2-bash-result]", names)
+	}
+	target, err = os.Readlink(filepath.Join(bashDir, "2-bash-result"))
+	if err != nil {
+		t.Fatalf("Readlink failed: %v", err)
+	}
+	if target != "../../../2-bash-result" {
+		t.Errorf("filter/tool/bash/2-bash-result target = %q, want %q", target, "../../../2-bash-result")
+	}
+
+	// Unknown roles are empty directories, and non-matching names are ENOENT
+	entries, err = ioutil.ReadDir(filepath.Join(filterDir, "nobody"))
+	if err != nil {
+		t.Fatalf("Failed to read filter/nobody: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected empty filter/nobody, got %d entries", len(entries))
+	}
+	if _, err := os.Lstat(filepath.Join(userDir, "3-agent")); !os.IsNotExist(err) {
+		t.Errorf("Expected ENOENT for filter/user/3-agent, got %v", err)
+	}
+}
+
 func TestSinceDirLsDoesNotMakeExcessiveAPICalls(t *testing.T) {
 	convID := "conv-since-perf"
 	numMessages := 100
@@ -4275,3 +4981,489 @@ func TestSinceDirPerformance(t *testing.T) {
 		t.Errorf("since/user/1/ avg %v exceeds %v threshold", sinceAvg, maxAcceptable)
 	}
 }
+
+// =============================================================================
+// Conversation Metadata Tests
+// =============================================================================
+
+func TestMetaDir_CreateWriteReadRemove(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, "server-meta", "")
+
+	tmpDir, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+	metaDir := filepath.Join(tmpDir, "conversation", localID, "meta")
+
+	entries, err := ioutil.ReadDir(metaDir)
+	if err != nil {
+		t.Fatalf("ReadDir meta failed: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("Expected empty meta/, got %d entries", len(entries))
+	}
+
+	// Create a key, even though the conversation is already created
+	path := filepath.Join(metaDir, "checkpoint")
+	if err := ioutil.WriteFile(path, []byte("step-10\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	if v, ok := store.GetMeta(localID, "checkpoint"); !ok || v != "step-10\n" {
+		t.Errorf("stored meta = %q, %v; want %q, true", v, ok, "step-10\n")
+	}
+
+	// Overwrite with a shorter value: the truncate must take effect
+	if err := ioutil.WriteFile(path, []byte("step-9\n"), 0644); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(data) != "step-9\n" {
+		t.Errorf("meta/checkpoint = %q, want %q", data, "step-9\n")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat failed: %v", err)
+	}
+	if info.Size() != int64(len("step-9\n")) {
+		t.Errorf("size = %d, want %d", info.Size(), len("step-9\n"))
+	}
+
+	// Append
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("OpenFile for append failed: %v", err)
+	}
+	if _, err := f.WriteString("done\n"); err != nil {
+		t.Fatalf("append failed: %v", err)
+	}
+	f.Close()
+	if v, _ := store.GetMeta(localID, "checkpoint"); v != "step-9\ndone\n" {
+		t.Errorf("after append stored meta = %q, want %q", v, "step-9\ndone\n")
+	}
+
+	entries, err = ioutil.ReadDir(metaDir)
+	if err != nil {
+		t.Fatalf("ReadDir meta failed: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "checkpoint" {
+		t.Errorf("meta/ entries = %v, want [checkpoint]", entryNames(entries))
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, ok := store.GetMeta(localID, "checkpoint"); ok {
+		t.Error("Expected checkpoint to be removed from state")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected ENOENT after remove, got %v", err)
+	}
+}
+
+func TestMetaDir_CreateExclusive(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Clone()
+
+	tmpDir, cleanup := mountTestFSWithServer(t, server, store)
+	defer cleanup()
+	lock := filepath.Join(tmpDir, "conversation", localID, "meta", "lock")
+
+	f, err := os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		t.Fatalf("first exclusive create: %v", err)
+	}
+	f.WriteString("held\n")
+	f.Close()
+
+	_, err = os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if !errors.Is(err, syscall.EEXIST) {
+		t.Errorf("second exclusive create: got %v, want EEXIST", err)
+	}
+	if v, _ := store.GetMeta(localID, "lock"); v != "held\n" {
+		t.Errorf("lock value = %q, want the first holder's", v)
+	}
+
+	// Once released, the lock can be taken again
+	if err := os.Remove(lock); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	f, err = os.OpenFile(lock, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		t.Fatalf("exclusive create after remove: %v", err)
+	}
+	f.Close()
+}
+
+func TestConversationNode_Trace(t *testing.T) {
+	server := mockConversationsServer(t, nil)
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Clone()
+
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+	if _, err := os.Stat(filepath.Join(convDir, ".trace")); !os.IsNotExist(err) {
+		t.Fatalf(".trace should not exist while tracing is disabled, got %v", err)
+	}
+
+	shelleyFS.Diag.EnableTrace(100, func(id string) bool { return store.Get(id) != nil })
+	shelleyFS.Diag.Record(localID, "http", "GET /api/conversation/x 200")
+	if _, err := os.ReadFile(filepath.Join(convDir, "ctl")); err != nil {
+		t.Fatalf("read ctl: %v", err)
+	}
+	trace, err := os.ReadFile(filepath.Join(convDir, ".trace"))
+	if err != nil {
+		t.Fatalf("read .trace: %v", err)
+	}
+	for _, want := range []string{"http GET /api/conversation/x 200", "ConversationNode.Lookup " + localID + "/ctl"} {
+		if !strings.Contains(string(trace), want) {
+			t.Errorf(".trace missing %q:\n%s", want, trace)
+		}
+	}
+}
+
+func TestConversationNode_ErrorsLog(t *testing.T) {
+	server := mockserver.New(mockserver.WithNewConversationHandler(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Clone()
+
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+	if data, err := os.ReadFile(filepath.Join(convDir, "errors.log")); err != nil || len(data) != 0 {
+		t.Fatalf("errors.log should start empty, got %q, %v", data, err)
+	}
+	if err := os.WriteFile(filepath.Join(convDir, "send"), []byte("hello\n"), 0); err == nil {
+		t.Fatal("send should fail when the backend rejects the conversation")
+	}
+	data, err := os.ReadFile(filepath.Join(convDir, "errors.log"))
+	if err != nil {
+		t.Fatalf("read errors.log: %v", err)
+	}
+	if !strings.Contains(string(data), "StartConversation: API returned status 503: model unavailable") {
+		t.Errorf("errors.log missing the failed send:\n%s", data)
+	}
+}
+
+func TestConvSendFileHandle_Fsync(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	server := mockserver.New(
+		mockserver.WithConversation("conv-fsync", nil),
+		mockserver.WithChatHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req shelley.ChatRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			sent = append(sent, req.Message)
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer server.Close()
+	sentSoFar := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), sent...)
+	}
+
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-fsync")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	f, err := os.OpenFile(filepath.Join(mountPoint, "conversation", localID, "send"), os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open send: %v", err)
+	}
+	f.WriteString("first\n")
+	if got := sentSoFar(); len(got) != 0 {
+		t.Fatalf("write alone should not send, got %q", got)
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatalf("fsync: %v", err)
+	}
+	if got := sentSoFar(); !reflect.DeepEqual(got, []string{"first"}) {
+		t.Fatalf("after fsync sent %q, want [first]", got)
+	}
+	if err := f.Sync(); err != nil {
+		t.Fatalf("second fsync: %v", err)
+	}
+	f.WriteString("second\n")
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got := sentSoFar(); !reflect.DeepEqual(got, []string{"first", "second"}) {
+		t.Errorf("after close sent %q, want [first second]", got)
+	}
+}
+
+func TestConvSendFileHandle_Truncate(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	server := mockserver.New(
+		mockserver.WithConversation("conv-trunc", nil),
+		mockserver.WithChatHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req shelley.ChatRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			sent = append(sent, req.Message)
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-trunc")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	// An editor rewriting the file in place: write, truncate, write again.
+	f, err := os.OpenFile(filepath.Join(mountPoint, "conversation", localID, "send"), os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		t.Fatalf("open send: %v", err)
+	}
+	f.WriteString("draft\n")
+	if err := f.Truncate(0); err != nil {
+		t.Fatalf("ftruncate: %v", err)
+	}
+	f.WriteString("final\n")
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(sent, []string{"final"}) {
+		t.Errorf("sent %q, want [final]", sent)
+	}
+}
+
+func TestModelWaitReadyNode_Open(t *testing.T) {
+	defer func(d time.Duration) { modelReadyPollInterval = d }(modelReadyPollInterval)
+	modelReadyPollInterval = time.Millisecond
+
+	var polls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The model becomes ready on the third poll.
+		ready := polls.Add(1) >= 3
+		json.NewEncoder(w).Encode([]shelley.Model{{ID: "warming", Ready: ready}})
+	}))
+	defer server.Close()
+	client := shelley.NewClient(server.URL)
+
+	node := &ModelWaitReadyNode{modelID: "warming", client: client, timeout: time.Minute}
+	fh, _, errno := node.Open(context.Background(), 0)
+	if errno != 0 {
+		t.Fatalf("Open failed with errno %v", errno)
+	}
+	if polls.Load() < 3 {
+		t.Errorf("Open returned after %d polls, before the model was ready", polls.Load())
+	}
+	buf := make([]byte, 16)
+	res, _ := fh.(fs.FileReader).Read(context.Background(), buf, 0)
+	if data, _ := res.Bytes(buf); string(data) != "ready\n" {
+		t.Errorf("read %q, want ready", data)
+	}
+
+	missing := &ModelWaitReadyNode{modelID: "gone", client: client, timeout: time.Minute}
+	if _, _, errno := missing.Open(context.Background(), 0); errno != syscall.ENOENT {
+		t.Errorf("unknown model: errno %v, want ENOENT", errno)
+	}
+
+	polls.Store(-1000)
+	slow := &ModelWaitReadyNode{modelID: "warming", client: client, timeout: 20 * time.Millisecond}
+	if _, _, errno := slow.Open(context.Background(), 0); errno != syscall.ETIMEDOUT {
+		t.Errorf("never-ready model: errno %v, want ETIMEDOUT", errno)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, errno := node.Open(ctx, 0); errno != syscall.EINTR {
+		t.Errorf("interrupted wait: errno %v, want EINTR", errno)
+	}
+}
+
+// TestConvContentSnapshotPerOpen checks that all.json and all.md read through
+// one fd come from the conversation as it was at open, even when it grows
+// between read() calls, and that fstat on the fd reports that snapshot's size.
+func TestConvContentSnapshotPerOpen(t *testing.T) {
+	first := "first"
+	long := strings.Repeat("grown ", 4096)
+	var mu sync.Mutex
+	msgs := []shelley.Message{{MessageID: "m1", ConversationID: "conv-snap", SequenceID: 1, Type: "user", UserData: &first}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"conversation_id": "conv-snap", "messages": msgs})
+	}))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-snap", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	for _, name := range []string{"all.json", "all.md"} {
+		t.Run(name, func(t *testing.T) {
+			mu.Lock()
+			msgs = msgs[:1]
+			mu.Unlock()
+
+			path := filepath.Join(mountPoint, "conversation", localID, "messages", name)
+			want, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read %s: %v", name, err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("open %s: %v", name, err)
+			}
+			defer f.Close()
+			head := make([]byte, 8)
+			if _, err := f.Read(head); err != nil {
+				t.Fatalf("first read: %v", err)
+			}
+
+			mu.Lock()
+			msgs = append(msgs, shelley.Message{MessageID: "m2", ConversationID: "conv-snap", SequenceID: 2, Type: "user", UserData: &long})
+			mu.Unlock()
+
+			rest, err := ioutil.ReadAll(f)
+			if err != nil {
+				t.Fatalf("second read: %v", err)
+			}
+			if got := append(head, rest...); !bytes.Equal(got, want) {
+				t.Errorf("read through one fd = %d bytes, want the %d bytes from open", len(got), len(want))
+			}
+			fi, err := f.Stat()
+			if err != nil {
+				t.Fatalf("fstat: %v", err)
+			}
+			if fi.Size() != int64(len(want)) {
+				t.Errorf("fstat size = %d, want %d", fi.Size(), len(want))
+			}
+		})
+	}
+}
+
+func TestRootNewFollowsDefaultBackend(t *testing.T) {
+	models := []shelley.Model{{ID: "predictable", Ready: true}}
+	mainServer := mockModelsServerWithDefault(t, models, "predictable")
+	defer mainServer.Close()
+	secondServer := mockModelsServerWithDefault(t, models, "predictable")
+	defer secondServer.Close()
+
+	store := testStore(t)
+	if err := store.EnsureBackendURL(state.DefaultBackendName, mainServer.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateBackend("second", secondServer.URL); err != nil {
+		t.Fatal(err)
+	}
+	mountPoint, cleanup := mountFS(t, NewFSWithBackends(shelley.NewClientManager(0), store, time.Hour))
+	defer cleanup()
+
+	clone := func(wantBackend string) {
+		t.Helper()
+		target, err := os.Readlink(filepath.Join(mountPoint, "new"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "backend/" + wantBackend + "/new"; target != want {
+			t.Errorf("new -> %q, want %q", target, want)
+		}
+		data, err := os.ReadFile(filepath.Join(mountPoint, "new", "clone"))
+		if err != nil {
+			t.Fatalf("read new/clone with default backend %s: %v", wantBackend, err)
+		}
+		id := strings.TrimSpace(string(data))
+		cs := store.GetForBackend(wantBackend, id)
+		if cs == nil {
+			t.Fatalf("clone %s not on backend %s", id, wantBackend)
+		}
+		if cs.Model != "predictable" {
+			t.Errorf("clone model = %q, want the backend's default", cs.Model)
+		}
+	}
+
+	// The implicit default "main" has no backend/default symlink to go through.
+	clone(state.DefaultBackendName)
+	if err := store.SetDefaultBackend("second"); err != nil {
+		t.Fatal(err)
+	}
+	clone("second")
+}
+
+func TestBackendURLWrite(t *testing.T) {
+	models := []shelley.Model{{ID: "predictable", Ready: true}}
+	server := mockModelsServerWithDefault(t, models, "predictable")
+	defer server.Close()
+
+	store := testStore(t)
+	if err := store.CreateBackend("spare.local", ""); err != nil {
+		t.Fatal(err)
+	}
+	mountPoint, cleanup := mountFS(t, NewFSWithBackends(shelley.NewClientManager(0), store, time.Hour))
+	defer cleanup()
+	backendDir := filepath.Join(mountPoint, "backend", "spare.local")
+
+	if _, err := os.Stat(filepath.Join(backendDir, "model")); err == nil {
+		t.Error("model/ reachable on a backend without a URL")
+	}
+	if err := os.WriteFile(filepath.Join(backendDir, "url"), []byte(server.URL+"/\n"), 0644); err != nil {
+		t.Fatalf("write url: %v", err)
+	}
+	if got := store.GetBackend("spare.local").URL; got != server.URL {
+		t.Errorf("URL in state = %q, want %q", got, server.URL)
+	}
+	data, err := os.ReadFile(filepath.Join(backendDir, "url"))
+	if err != nil || string(data) != server.URL+"\n" {
+		t.Errorf("url = %q, %v", data, err)
+	}
+	names := listDir(t, filepath.Join(backendDir, "model"))
+	if !slices.Contains(names, "predictable") {
+		t.Errorf("model/ after setting the URL = %v, want the server's models", names)
+	}
+
+	for _, bad := range []string{"ftp://example.com\n", "not a url\n", "http://\n", "unix://relative.sock\n"} {
+		if err := os.WriteFile(filepath.Join(backendDir, "url"), []byte(bad), 0644); !errors.Is(err, syscall.EINVAL) {
+			t.Errorf("write %q: got %v, want EINVAL", bad, err)
+		}
+	}
+	if got := store.GetBackend("spare.local").URL; got != server.URL {
+		t.Errorf("rejected writes changed the URL to %q", got)
+	}
+}
+
+func TestConvSendFileHandle_TruncateGrowth(t *testing.T) {
+	h := &ConvSendFileHandle{node: &ConvSendNode{maxSend: 1 << 20}, buffer: []byte("draft\n")}
+	if errno := h.truncate(200 << 20); errno != syscall.EFBIG {
+		t.Errorf("growing truncate = %v, want EFBIG", errno)
+	}
+	if errno := h.truncate(10); errno != syscall.EFBIG {
+		t.Errorf("growing truncate within max-send-size = %v, want EFBIG", errno)
+	}
+	if string(h.buffer) != "draft\n" {
+		t.Errorf("buffer = %q after refused growth, want it unchanged", h.buffer)
+	}
+	if errno := h.truncate(2); errno != 0 || string(h.buffer) != "dr" {
+		t.Errorf("truncate(2) = %v, buffer %q", errno, h.buffer)
+	}
+}
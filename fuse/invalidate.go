@@ -0,0 +1,244 @@
+package fuse
+
+import (
+	"log"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"shelley-fuse/shelley"
+)
+
+// --- Invalidator: keeping kernel caches in step with the backend ---
+// The kernel caches names and attributes for their entry and attr timeouts,
+// so a conversation started or answered elsewhere shows up late even when
+// the client's caches are fresh. The invalidator lists every backend's
+// conversations each interval and, for what changed since the last list,
+// tells the kernel to drop what it has cached: conversations that appeared
+// or went away in /conversation, and the names and messages/ of
+// conversations whose updated_at moved. A changed conversation the kernel
+// has cached is refetched, and its messages/ listing dropped only if its
+// message count changed; otherwise only cached file contents go. Only the
+// inodes the kernel has looked up are touched, so its cost doesn't grow
+// with the number of conversations on the backend.
+
+// conversationVolatileNames are the names in a conversation directory that
+// a change on the backend can make appear, disappear or read differently.
+var conversationVolatileNames = []string{
+	"working", "cancel", "archived", "created", "continue", "subagents", "title", "web", "export.tar", "export.zip", "snapshots",
+	"model", "slug", "updated_at", "updated_at_unix",
+}
+
+// Invalidator drops kernel caches of conversations that changed on the
+// backend.
+type Invalidator struct {
+	fs       *FS
+	interval time.Duration
+	listed   map[string]map[string]listedConversation // by backend, then server conversation ID
+	counts   map[string]int                           // message counts, by backend + "/" + server conversation ID
+}
+
+// listedConversation is what the last list said about a conversation.
+type listedConversation struct {
+	slug      string
+	updatedAt string
+}
+
+// NewInvalidator creates an invalidator for f that lists conversations
+// every interval.
+func NewInvalidator(f *FS, interval time.Duration) *Invalidator {
+	return &Invalidator{
+		fs:       f,
+		interval: interval,
+		listed:   make(map[string]map[string]listedConversation),
+		counts:   make(map[string]int),
+	}
+}
+
+// Run lists conversations every interval until stop is closed.
+func (v *Invalidator) Run(stop <-chan struct{}) {
+	v.poll()
+	ticker := time.NewTicker(v.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			v.poll()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// poll lists every backend's conversations and invalidates what changed
+// since the last list. It returns how many conversations appeared, went
+// away or were updated. The first list of a backend only sets the baseline.
+func (v *Invalidator) poll() int {
+	changed := 0
+	for _, b := range v.fs.backendClients() {
+		data, err := freshConversationList(b.client)
+		if err != nil {
+			log.Printf("Listing conversations of backend %s for invalidation failed: %v", b.name, err)
+			continue
+		}
+		var convs []shelley.Conversation
+		if err := shelley.UnmarshalLenient(data, &convs); err != nil {
+			log.Printf("Listing conversations of backend %s for invalidation: %v", b.name, err)
+			continue
+		}
+		current := make(map[string]listedConversation, len(convs))
+		for _, c := range convs {
+			current[c.ConversationID] = listedConversation{slug: derefStr(c.Slug), updatedAt: c.UpdatedAt}
+		}
+		prev, seen := v.listed[b.name]
+		v.listed[b.name] = current
+		if !seen {
+			continue
+		}
+
+		list := v.fs.conversationListInode(b.name)
+		var stale []string // names that appeared or went away
+		for id, c := range current {
+			old, ok := prev[id]
+			switch {
+			case !ok:
+				stale = append(stale, v.conversationNames(b.name, id, c.slug)...)
+			case old.updatedAt != c.updatedAt:
+				v.conversationUpdated(b, list, id)
+			default:
+				continue
+			}
+			changed++
+		}
+		for id, c := range prev {
+			if _, ok := current[id]; !ok {
+				stale = append(stale, v.conversationNames(b.name, id, c.slug)...)
+				delete(v.counts, b.name+"/"+id)
+				changed++
+			}
+		}
+		if list != nil && len(stale) > 0 {
+			list.NotifyContent(0, 0)
+			for _, name := range stale {
+				if child := list.GetChild(name); child != nil {
+					list.NotifyDelete(name, child)
+				} else {
+					list.NotifyEntry(name)
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// conversationNames returns the names a conversation has in /conversation:
+// its server ID, its slug if it has one, and its local ID if it is tracked.
+func (v *Invalidator) conversationNames(backend, conversationID, slug string) []string {
+	names := []string{conversationID}
+	if slug != "" {
+		names = append(names, slug)
+	}
+	if localID := v.fs.state.GetByShelleyIDForBackend(backend, conversationID); localID != "" {
+		names = append(names, localID)
+	}
+	return names
+}
+
+// conversationUpdated invalidates a conversation whose updated_at moved.
+// If the kernel hasn't looked it up, only the client's copy is dropped.
+func (v *Invalidator) conversationUpdated(b backendClient, list *fs.Inode, conversationID string) {
+	key := b.name + "/" + conversationID
+	localID := v.fs.state.GetByShelleyIDForBackend(b.name, conversationID)
+	var conv *fs.Inode
+	if list != nil && localID != "" {
+		conv = list.GetChild(localID)
+		if cs := v.fs.state.GetForBackend(b.name, localID); conv == nil && cs != nil && cs.Slug != "" {
+			conv = list.GetChild(cs.Slug) // -layout=slugs
+		}
+	}
+	if conv == nil {
+		if cc, ok := b.client.(*shelley.CachingClient); ok {
+			cc.InvalidateConversation(conversationID)
+		}
+		delete(v.counts, key)
+		return
+	}
+
+	countChanged := true
+	data, err := freshConversation(b.client, conversationID)
+	if err == nil {
+		if msgs, _, err := v.fs.parsedCache.GetOrParse(conversationID, data); err == nil {
+			old, known := v.counts[key]
+			countChanged = !known || old != len(msgs)
+			v.counts[key] = len(msgs)
+		}
+	}
+
+	conv.NotifyContent(0, 0)
+	for _, name := range conversationVolatileNames {
+		conv.NotifyEntry(name)
+	}
+	for _, f := range conversationFields {
+		conv.NotifyEntry(f.name)
+	}
+	if messages := conv.GetChild("messages"); messages != nil {
+		if countChanged {
+			invalidateEntries(messages)
+		} else {
+			invalidateContent(messages)
+		}
+	}
+}
+
+// invalidateEntries drops every name under dir the kernel has cached, and
+// the contents of what they name.
+func invalidateEntries(dir *fs.Inode) {
+	for name, child := range dir.Children() {
+		invalidateEntries(child)
+		dir.NotifyEntry(name)
+	}
+	dir.NotifyContent(0, 0)
+}
+
+// invalidateContent drops the cached contents of in and everything the
+// kernel has looked up under it, leaving the names.
+func invalidateContent(in *fs.Inode) {
+	for _, child := range in.Children() {
+		invalidateContent(child)
+	}
+	in.NotifyContent(0, 0)
+}
+
+// conversationListInode returns the inode of the backend's conversation
+// list, or nil if the kernel hasn't looked it up.
+func (f *FS) conversationListInode(backend string) *fs.Inode {
+	if f.clientMgr == nil {
+		return f.GetChild("conversation")
+	}
+	backends := f.GetChild("backend")
+	if backends == nil {
+		return nil
+	}
+	b := backends.GetChild(backend)
+	if b == nil {
+		return nil
+	}
+	return b.GetChild("conversation")
+}
+
+// freshConversationList lists conversations from the backend, bypassing
+// (and refreshing) the client's cache.
+func freshConversationList(client shelley.ShelleyClient) ([]byte, error) {
+	if cc, ok := client.(*shelley.CachingClient); ok {
+		return cc.RefreshConversations()
+	}
+	return client.ListConversations()
+}
+
+// freshConversation fetches a conversation from the backend, bypassing (and
+// refreshing) the client's cache.
+func freshConversation(client shelley.ShelleyClient, conversationID string) ([]byte, error) {
+	if cc, ok := client.(*shelley.CachingClient); ok {
+		return cc.RefreshConversation(conversationID, 0)
+	}
+	return client.GetConversation(conversationID)
+}
@@ -0,0 +1,59 @@
+package fuse
+
+import (
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestInvalidator(t *testing.T) {
+	hello := "Hello"
+	server := mockserver.New(
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-a", UpdatedAt: "2024-01-15T10:30:00Z"}, []shelley.Message{
+			{MessageID: "m1", ConversationID: "conv-a", SequenceID: 1, Type: "user", UserData: &hello},
+		}),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-b", UpdatedAt: "2024-01-15T10:30:00Z"}, nil),
+	)
+	defer server.Close()
+	client := shelley.NewCachingClient(shelley.NewClient(server.URL), time.Hour)
+	store := testStore(t)
+	store.AdoptWithSlug("conv-a", "")
+	store.AdoptWithSlug("conv-b", "")
+
+	v := NewInvalidator(NewFS(client, store, time.Hour), time.Second)
+	if got := v.poll(); got != 0 {
+		t.Errorf("first poll found %d changes, want 0 (it sets the baseline)", got)
+	}
+	if got := v.poll(); got != 0 {
+		t.Errorf("poll without changes found %d", got)
+	}
+
+	// An updated conversation's cached copy is dropped.
+	if _, err := client.GetConversation("conv-a"); err != nil {
+		t.Fatal(err)
+	}
+	server.AddMessage("conv-a", shelley.Message{MessageID: "m2", ConversationID: "conv-a", SequenceID: 2, Type: "shelley", CreatedAt: "2024-01-15T10:31:00Z"})
+	if got := v.poll(); got != 1 {
+		t.Errorf("poll after a new message found %d changes, want 1", got)
+	}
+	server.ResetFetchCount()
+	if _, err := client.GetConversation("conv-a"); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.FetchCount(); n != 1 {
+		t.Errorf("read after the change fetched %d times, want 1", n)
+	}
+
+	// A conversation that goes away is noticed too.
+	if err := client.DeleteConversation("conv-b"); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.poll(); got != 1 {
+		t.Errorf("poll after a deletion found %d changes, want 1", got)
+	}
+	if _, ok := v.listed[store.GetDefaultBackend()]["conv-b"]; ok {
+		t.Error("deleted conversation is still listed")
+	}
+}
@@ -0,0 +1,80 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- LatencyNode: /backend/latency.json ---
+// How long the requests of the last few minutes took on each backend, as
+// percentiles. Comparing them with how slow the mount feels tells whether
+// the time goes to the server or to the mount itself.
+
+type LatencyNode struct {
+	fs.Inode
+	clientMgr *shelley.ClientManager
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*LatencyNode)(nil))
+var _ = (fs.NodeGetattrer)((*LatencyNode)(nil))
+
+// backendLatency is one backend's entry in latency.json.
+type backendLatency struct {
+	URL string `json:"url"`
+	shelley.LatencyStats
+}
+
+// latencySummary is the content of latency.json.
+type latencySummary struct {
+	WindowSeconds int                       `json:"window_seconds"`
+	Backends      map[string]backendLatency `json:"backends"`
+}
+
+// summary renders latency.json. Backends no request has been sent to yet
+// are listed with a count of 0.
+func (n *LatencyNode) summary() []byte {
+	stats := n.clientMgr.Latency()
+	sum := latencySummary{
+		WindowSeconds: int(shelley.LatencyWindowAge / time.Second),
+		Backends:      make(map[string]backendLatency),
+	}
+	for _, name := range n.state.ListBackends() {
+		var url string
+		if backend := n.state.GetBackend(name); backend != nil {
+			url = backend.URL
+		}
+		sum.Backends[name] = backendLatency{URL: url, LatencyStats: stats[name]}
+	}
+	data, _ := json.MarshalIndent(sum, "", "  ")
+	return append(data, '\n')
+}
+
+// Open renders the summary once; the handle reads and sizes that snapshot.
+func (n *LatencyNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(n.diag, "LatencyNode", "Open", "").Done()
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &ConvContentFileHandle{content: n.summary(), messageTime: time.Now()}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *LatencyNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fg, ok := f.(fs.FileGetattrer); ok {
+		return fg.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(len(n.summary()))
+	setTimestamps(&out.Attr, time.Now())
+	return 0
+}
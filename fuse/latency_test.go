@@ -0,0 +1,89 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestBackendLatency(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	store := testStore(t)
+	if err := store.EnsureBackendURL(state.DefaultBackendName, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.CreateBackend("idle", "http://127.0.0.1:1"); err != nil {
+		t.Fatal(err)
+	}
+	mountPoint, cleanup := mountFS(t, NewFSWithBackends(shelley.NewClientManager(0), store, time.Hour))
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		if _, err := os.ReadDir(filepath.Join(mountPoint, "backend", "main", "model")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(mountPoint, "backend", "latency.json")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sum struct {
+		WindowSeconds int `json:"window_seconds"`
+		Backends      map[string]struct {
+			URL   string  `json:"url"`
+			Count int     `json:"count"`
+			P50   float64 `json:"p50_ms"`
+			Max   float64 `json:"max_ms"`
+		} `json:"backends"`
+	}
+	if err := json.Unmarshal(data, &sum); err != nil {
+		t.Fatalf("latency.json: %v\n%s", err, data)
+	}
+	if sum.WindowSeconds != int(shelley.LatencyWindowAge/time.Second) {
+		t.Errorf("window_seconds = %d", sum.WindowSeconds)
+	}
+	mainStats := sum.Backends["main"]
+	if mainStats.URL != server.URL || mainStats.Count < 3 || mainStats.P50 <= 0 || mainStats.Max < mainStats.P50 {
+		t.Errorf("main = %+v, want %s with at least 3 requests\n%s", mainStats, server.URL, data)
+	}
+	if idle, ok := sum.Backends["idle"]; !ok || idle.Count != 0 {
+		t.Errorf("idle = %+v, %v; want listed with no requests", idle, ok)
+	}
+
+	if err := os.WriteFile(path, []byte("{}"), 0644); err == nil {
+		t.Error("latency.json should not be writable")
+	}
+	if err := os.Mkdir(path, 0755); !os.IsExist(err) {
+		t.Errorf("mkdir backend/latency.json: %v, want EEXIST", err)
+	}
+}
+
+// Without a client manager, as with NewFS, there is no latency to report.
+func TestBackendLatencyWithoutManager(t *testing.T) {
+	list := &BackendListNode{state: testStore(t)}
+	fs.NewNodeFS(list, &fs.Options{})
+	ctx := context.Background()
+	var out fuse.EntryOut
+	if _, errno := list.Lookup(ctx, "latency.json", &out); errno != syscall.ENOENT {
+		t.Errorf("Lookup(latency.json) = %v, want ENOENT", errno)
+	}
+	stream, _ := list.Readdir(ctx)
+	for stream.HasNext() {
+		if e, _ := stream.Next(); e.Name == "latency.json" {
+			t.Error("/backend lists latency.json without a client manager")
+		}
+	}
+}
@@ -24,6 +24,8 @@ type MessagesDirNode struct {
 	client      shelley.ShelleyClient
 	state       *state.Store
 	startTime   time.Time
+	mdChunkSize int  // all.md.d/ appears once all.md exceeds this (0 = never)
+	sparseMsgs  bool // Readdir leaves out message directories; Lookup still finds them
 	parsedCache *ParsedMessageCache
 	diag        *diag.Tracker
 }
@@ -31,6 +33,7 @@ type MessagesDirNode struct {
 var _ = (fs.NodeLookuper)((*MessagesDirNode)(nil))
 var _ = (fs.NodeReaddirer)((*MessagesDirNode)(nil))
 var _ = (fs.NodeGetattrer)((*MessagesDirNode)(nil))
+var _ = (fs.NodeOpendirHandler)((*MessagesDirNode)(nil))
 
 // getConversationTimestamps returns timestamps for the conversation using the metadata mapping.
 func (m *MessagesDirNode) getConversationTimestamps() metadata.Timestamps {
@@ -70,24 +73,45 @@ func (m *MessagesDirNode) Lookup(ctx context.Context, name string, out *fuse.Ent
 	switch name {
 	case "last":
 		ino := stableIno("query-dir", m.localID, "last")
-		return m.NewInode(ctx, &QueryDirNode{localID: m.localID, client: m.client, state: m.state, kind: queryLast, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
+		return m.NewInode(ctx, &QueryDirNode{localID: m.localID, client: m.client, state: m.state, kind: queryLast, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: messagesNames.mode("last"), Ino: ino}), 0
 	case "since":
 		ino := stableIno("query-dir", m.localID, "since")
-		return m.NewInode(ctx, &QueryDirNode{localID: m.localID, client: m.client, state: m.state, kind: querySince, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
-	case "count":
-		return m.NewInode(ctx, &MessageCountNode{localID: m.localID, client: m.client, state: m.state, startTime: m.startTime, parsedCache: m.parsedCache}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return m.NewInode(ctx, &QueryDirNode{localID: m.localID, client: m.client, state: m.state, kind: querySince, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: messagesNames.mode("since"), Ino: ino}), 0
+	case "filter":
+		ino := stableIno("query-dir", m.localID, "filter")
+		return m.NewInode(ctx, &FilterDirNode{localID: m.localID, client: m.client, state: m.state, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: messagesNames.mode("filter"), Ino: ino}), 0
+	case "diff":
+		ino := stableIno("query-dir", m.localID, "diff")
+		return m.NewInode(ctx, &DiffDirNode{localID: m.localID, client: m.client, state: m.state, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: messagesNames.mode("diff"), Ino: ino}), 0
+	case "count", "tokens", "words":
+		return m.NewInode(ctx, &MessageCountNode{localID: m.localID, client: m.client, state: m.state, measure: name, startTime: m.startTime, parsedCache: m.parsedCache}, fs.StableAttr{Mode: messagesNames.mode(name)}), 0
+	case "pinned":
+		ino := stableIno("query-dir", m.localID, "pinned")
+		return m.NewInode(ctx, &PinnedDirNode{localID: m.localID, client: m.client, state: m.state, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: messagesNames.mode("pinned"), Ino: ino}), 0
+	case "stream":
+		return m.NewInode(ctx, &MessageStreamNode{localID: m.localID, client: m.client, state: m.state, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: messagesNames.mode("stream")}), 0
+	case "all.md.d":
+		chunks, errno := m.markdownChunks()
+		if errno != 0 {
+			return nil, errno
+		}
+		if chunks == nil {
+			return nil, syscall.ENOENT
+		}
+		ino := stableIno("query-dir", m.localID, "all.md.d")
+		return m.NewInode(ctx, &MarkdownChunksDirNode{localID: m.localID, client: m.client, state: m.state, chunkSize: m.mdChunkSize, startTime: m.startTime, parsedCache: m.parsedCache, diag: m.diag}, fs.StableAttr{Mode: messagesNames.mode("all.md.d"), Ino: ino}), 0
 	}
 
-	// all.json, all.md
+	// all.json, all.md, all.org
 	format, ok := parseFormat(name)
 	if ok {
-		base := strings.TrimSuffix(strings.TrimSuffix(name, ".json"), ".md")
+		base := name[:strings.LastIndexByte(name, '.')]
 		if base == "all" {
 			return m.NewInode(ctx, &ConvContentNode{
 				localID: m.localID, client: m.client, state: m.state,
 				query: contentQuery{kind: queryAll, format: format}, startTime: m.startTime,
 				parsedCache: m.parsedCache, diag: m.diag,
-			}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+			}, fs.StableAttr{Mode: messagesNames.mode(name)}), 0
 		}
 	}
 
@@ -102,7 +126,7 @@ func (m *MessagesDirNode) Lookup(ctx context.Context, name string, out *fuse.Ent
 
 		convData, err := m.client.GetConversation(cs.ShelleyConversationID)
 		if err != nil {
-			return nil, syscall.EIO
+			return nil, backendErrno(err)
 		}
 
 		// Use the parsed message cache for efficient repeated lookups
@@ -126,53 +150,98 @@ func (m *MessagesDirNode) Lookup(ctx context.Context, name string, out *fuse.Ent
 			return nil, syscall.ENOENT
 		}
 
-		node := &MessageDirNode{
-			message:   *msg,
-			toolMap:   result.ToolMap,
-			startTime: m.startTime,
-		}
-		// Message directories are immutable once created — cache aggressively.
-		// Populate attrs in EntryOut so the kernel has valid data to cache.
-		out.SetEntryTimeout(cacheTTLImmutable)
-		out.SetAttrTimeout(cacheTTLImmutable)
-		out.Attr.Mode = fuse.S_IFDIR | 0755
-		node.messageTimestamps().ApplyWithFallback(&out.Attr, m.startTime)
-		ino := stableIno("msg-dir", msg.ConversationID, strconv.Itoa(msg.SequenceID))
-		return m.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
+		return m.messageDirEntry(ctx, msg, result.ToolMap, out), 0
 	}
 
 	return nil, syscall.ENOENT
 }
 
+// messageDirEntry returns the inode for a message directory and fills out
+// with its attributes.
+func (m *MessagesDirNode) messageDirEntry(ctx context.Context, msg *shelley.Message, toolMap map[string]string, out *fuse.EntryOut) *fs.Inode {
+	node := &MessageDirNode{
+		message:     *msg,
+		toolMap:     toolMap,
+		startTime:   m.startTime,
+		localID:     m.localID,
+		client:      m.client,
+		state:       m.state,
+		parsedCache: m.parsedCache,
+		diag:        m.diag,
+	}
+	// Message directories are immutable once created — cache aggressively.
+	// Populate attrs in EntryOut so the kernel has valid data to cache.
+	out.SetEntryTimeout(cacheTimeouts(&m.Inode).Immutable)
+	out.SetAttrTimeout(cacheTimeouts(&m.Inode).Immutable)
+	out.Attr.Mode = fuse.S_IFDIR | 0755
+	node.messageTimestamps().ApplyWithFallback(&out.Attr, m.startTime)
+	ino := stableIno("msg-dir", msg.ConversationID, strconv.Itoa(msg.SequenceID))
+	return m.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino})
+}
+
 func (m *MessagesDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 	defer diag.Track(m.diag, "MessagesDirNode", "Readdir", m.localID).Done()
-	entries := []fuse.DirEntry{
-		{Name: "all.json", Mode: fuse.S_IFREG},
-		{Name: "all.md", Mode: fuse.S_IFREG},
-		{Name: "count", Mode: fuse.S_IFREG},
-		{Name: "last", Mode: fuse.S_IFDIR},
-		{Name: "since", Mode: fuse.S_IFDIR},
+	entries, _ := m.listing()
+	return fs.NewListDirStream(entries), 0
+}
+
+// OpendirHandle lists the directory once per open and answers the lookups
+// of READDIRPLUS from that listing. Without it, go-fuse would call Lookup
+// for every message directory, and each Lookup fetches the conversation and
+// checks it against the parse cache again: `ls -l` on a conversation with
+// n messages did n of those instead of one.
+func (m *MessagesDirNode) OpendirHandle(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(m.diag, "MessagesDirNode", "Opendir", m.localID).Done()
+	entries, result := m.listing()
+	byName := make(map[string]*shelley.Message)
+	if result != nil {
+		for i := range result.Messages {
+			msg := &result.Messages[i]
+			slug := shelley.MessageSlug(msg, result.ToolMap)
+			byName[messageFileBase(msg.SequenceID, slug, result.MaxSeqID)] = msg
+		}
+	}
+	lookup := func(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+		if msg, ok := byName[name]; ok {
+			return m.messageDirEntry(ctx, msg, result.ToolMap, out), 0
+		}
+		return m.Lookup(ctx, name, out)
 	}
+	return &listingDirHandle{entries: entries, lookup: lookup}, 0, 0
+}
+
+// listing returns the directory entries and, once the conversation exists
+// on the server, the parsed messages they were built from (nil otherwise).
+func (m *MessagesDirNode) listing() ([]fuse.DirEntry, *ParseResult) {
+	entries := messagesNames.entries("all.json", "all.md", "all.org", "count", "diff", "filter", "last", "pinned", "since", "stream", "tokens", "words")
 
 	// List individual messages as directories (0-user/, 1-agent/, ...)
 	cs := m.state.Get(m.localID)
-	if cs != nil && cs.Created && cs.ShelleyConversationID != "" {
-		convData, err := m.client.GetConversation(cs.ShelleyConversationID)
-		if err == nil {
-			// Use the parsed message cache for efficiency
-			result, err := m.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
-			if err == nil {
-				for i := range result.Messages {
-					slug := shelley.MessageSlug(&result.Messages[i], result.ToolMap)
-					base := messageFileBase(result.Messages[i].SequenceID, slug, result.MaxSeqID)
-					ino := stableIno("msg-dir", result.Messages[i].ConversationID, strconv.Itoa(result.Messages[i].SequenceID))
-					entries = append(entries, fuse.DirEntry{Name: base, Mode: fuse.S_IFDIR, Ino: ino})
-				}
-			}
-		}
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return entries, nil
 	}
-
-	return fs.NewListDirStream(entries), 0
+	convData, err := m.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return entries, nil
+	}
+	// Use the parsed message cache for efficiency
+	result, err := m.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return entries, nil
+	}
+	if m.mdChunkSize > 0 && m.parsedCache.Chunked(cs.ShelleyConversationID, result.Messages, m.mdChunkSize) {
+		entries = append(entries, messagesNames.entry("all.md.d"))
+	}
+	if m.sparseMsgs {
+		return entries, result
+	}
+	for i := range result.Messages {
+		slug := shelley.MessageSlug(&result.Messages[i], result.ToolMap)
+		base := messageFileBase(result.Messages[i].SequenceID, slug, result.MaxSeqID)
+		ino := stableIno("msg-dir", result.Messages[i].ConversationID, strconv.Itoa(result.Messages[i].SequenceID))
+		entries = append(entries, fuse.DirEntry{Name: base, Mode: fuse.S_IFDIR, Ino: ino})
+	}
+	return entries, result
 }
 
 func (m *MessagesDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
@@ -189,11 +258,21 @@ type MessageDirNode struct {
 	message   shelley.Message
 	toolMap   map[string]string // for computing markdown content
 	startTime time.Time
+
+	// For editing content.md (see MessageContentNode); unset in tests that
+	// only read.
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
 }
 
 var _ = (fs.NodeLookuper)((*MessageDirNode)(nil))
 var _ = (fs.NodeReaddirer)((*MessageDirNode)(nil))
 var _ = (fs.NodeGetattrer)((*MessageDirNode)(nil))
+var _ = (fs.NodeGetxattrer)((*MessageDirNode)(nil))
+var _ = (fs.NodeListxattrer)((*MessageDirNode)(nil))
 
 // messageTimestamps returns timestamps for this message using the metadata mapping.
 func (m *MessageDirNode) messageTimestamps() metadata.Timestamps {
@@ -212,11 +291,12 @@ func (m *MessageDirNode) messageTime() time.Time {
 	return m.startTime
 }
 
-// setImmutableFieldAttrs populates the EntryOut with immutable cache timeouts
-// and file attrs for a MessageFieldNode, so the kernel has valid data to cache.
-func setImmutableFieldAttrs(out *fuse.EntryOut, value string, noNewline bool, t time.Time) {
-	out.SetEntryTimeout(cacheTTLImmutable)
-	out.SetAttrTimeout(cacheTTLImmutable)
+// setImmutableFieldAttrs populates the EntryOut with the immutable cache
+// timeout ttl and file attrs for a MessageFieldNode, so the kernel has valid
+// data to cache.
+func setImmutableFieldAttrs(out *fuse.EntryOut, value string, noNewline bool, t time.Time, ttl time.Duration) {
+	out.SetEntryTimeout(ttl)
+	out.SetAttrTimeout(ttl)
 	out.Attr.Mode = fuse.S_IFREG | 0444
 	size := len(value)
 	if !noNewline {
@@ -226,11 +306,11 @@ func setImmutableFieldAttrs(out *fuse.EntryOut, value string, noNewline bool, t
 	setTimestamps(&out.Attr, t)
 }
 
-// setImmutableDirAttrs populates the EntryOut with immutable cache timeouts
-// and directory attrs, so the kernel has valid data to cache.
-func setImmutableDirAttrs(out *fuse.EntryOut, t time.Time) {
-	out.SetEntryTimeout(cacheTTLImmutable)
-	out.SetAttrTimeout(cacheTTLImmutable)
+// setImmutableDirAttrs populates the EntryOut with the immutable cache
+// timeout ttl and directory attrs, so the kernel has valid data to cache.
+func setImmutableDirAttrs(out *fuse.EntryOut, t time.Time, ttl time.Duration) {
+	out.SetEntryTimeout(ttl)
+	out.SetAttrTimeout(ttl)
 	out.Attr.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, t)
 }
@@ -246,13 +326,14 @@ func (m *MessageDirNode) Lookup(ctx context.Context, name string, out *fuse.Entr
 	t := m.messageTime()
 	convID := m.message.ConversationID
 	seqID := m.message.SequenceID
+	ttl := cacheTimeouts(&m.Inode).Immutable
 
 	// Helper to create and return an immutable field node with cached attrs
 	// and a stable inode number derived from (conversationID, sequenceID, fieldName).
 	fieldNode := func(value string) (*fs.Inode, syscall.Errno) {
-		setImmutableFieldAttrs(out, value, false, t)
+		setImmutableFieldAttrs(out, value, false, t, ttl)
 		ino := msgFieldIno(convID, seqID, name)
-		return m.NewInode(ctx, &MessageFieldNode{value: value, startTime: t}, fs.StableAttr{Mode: fuse.S_IFREG, Ino: ino}), 0
+		return m.NewInode(ctx, &MessageFieldNode{value: value, startTime: t}, fs.StableAttr{Mode: messageNames.mode(name), Ino: ino}), 0
 	}
 
 	switch name {
@@ -266,40 +347,88 @@ func (m *MessageDirNode) Lookup(ctx context.Context, name string, out *fuse.Entr
 		return fieldNode(m.message.Type)
 	case "created_at":
 		return fieldNode(m.message.CreatedAt)
+	case "created_at_unix":
+		secs, ok := unixSeconds(m.message.CreatedAt)
+		if !ok {
+			return nil, syscall.ENOENT
+		}
+		return fieldNode(secs)
 	case "llm_data":
 		if m.message.LLMData == nil || *m.message.LLMData == "" {
 			return nil, syscall.ENOENT
 		}
 		ino := msgFieldIno(convID, seqID, name)
-		config := &jsonfs.Config{StartTime: t, CacheTimeout: cacheTTLImmutable}
+		config := &jsonfs.Config{StartTime: t, CacheTimeout: ttl}
 		node, err := jsonfs.NewNodeFromJSON([]byte(*m.message.LLMData), config)
 		if err != nil {
 			// If JSON parsing fails, return as a file
-			setImmutableFieldAttrs(out, *m.message.LLMData, false, t)
+			setImmutableFieldAttrs(out, *m.message.LLMData, false, t, ttl)
 			return m.NewInode(ctx, &MessageFieldNode{value: *m.message.LLMData, startTime: t}, fs.StableAttr{Mode: fuse.S_IFREG, Ino: ino}), 0
 		}
-		setImmutableDirAttrs(out, t)
+		setImmutableDirAttrs(out, t, ttl)
 		return m.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
 	case "usage_data":
 		if m.message.UsageData == nil || *m.message.UsageData == "" {
 			return nil, syscall.ENOENT
 		}
 		ino := msgFieldIno(convID, seqID, name)
-		config := &jsonfs.Config{StartTime: t, CacheTimeout: cacheTTLImmutable}
+		config := &jsonfs.Config{StartTime: t, CacheTimeout: ttl}
 		node, err := jsonfs.NewNodeFromJSON([]byte(*m.message.UsageData), config)
 		if err != nil {
 			// If JSON parsing fails, return as a file
-			setImmutableFieldAttrs(out, *m.message.UsageData, false, t)
+			setImmutableFieldAttrs(out, *m.message.UsageData, false, t, ttl)
 			return m.NewInode(ctx, &MessageFieldNode{value: *m.message.UsageData, startTime: t}, fs.StableAttr{Mode: fuse.S_IFREG, Ino: ino}), 0
 		}
-		setImmutableDirAttrs(out, t)
+		setImmutableDirAttrs(out, t, ttl)
 		return m.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
 	case "content.md":
 		// Generate markdown rendering of this single message
 		content := string(shelley.FormatMarkdown([]shelley.Message{m.message}))
-		setImmutableFieldAttrs(out, content, true, t)
+		setImmutableFieldAttrs(out, content, true, t, ttl)
 		ino := msgFieldIno(convID, seqID, name)
-		return m.NewInode(ctx, &MessageFieldNode{value: content, startTime: t, noNewline: true}, fs.StableAttr{Mode: fuse.S_IFREG, Ino: ino}), 0
+		if m.editable() {
+			out.Attr.Mode = fuse.S_IFREG | 0644
+			return m.NewInode(ctx, &MessageContentNode{dir: m, content: content}, fs.StableAttr{Mode: messageNames.mode("content.md"), Ino: ino}), 0
+		}
+		return m.NewInode(ctx, &MessageFieldNode{value: content, startTime: t, noNewline: true}, fs.StableAttr{Mode: messageNames.mode("content.md"), Ino: ino}), 0
+	case "duration_ms":
+		d, ok := shelley.ToolResultDuration(&m.message)
+		if !ok {
+			return nil, syscall.ENOENT
+		}
+		return fieldNode(strconv.FormatInt(d.Milliseconds(), 10))
+	case "bytes":
+		data, _, ok := shelley.ToolResultPayload(&m.message)
+		if !ok {
+			return nil, syscall.ENOENT
+		}
+		return fieldNode(strconv.Itoa(len(data)))
+	case "ctl":
+		if !m.pinnable() {
+			return nil, syscall.ENOENT
+		}
+		out.Attr.Mode = fuse.S_IFREG | 0644
+		setTimestamps(&out.Attr, t)
+		ino := msgFieldIno(convID, seqID, name)
+		return m.NewInode(ctx, &MessageCtlNode{dir: m}, fs.StableAttr{Mode: messageNames.mode("ctl"), Ino: ino}), 0
+	case "content.txt":
+		// content.md's body as plain text, for readers that don't want markup
+		content := string(shelley.FormatPlainText(&m.message))
+		setImmutableFieldAttrs(out, content, true, t, ttl)
+		ino := msgFieldIno(convID, seqID, name)
+		return m.NewInode(ctx, &MessageFieldNode{value: content, startTime: t, noNewline: true}, fs.StableAttr{Mode: messageNames.mode("content.txt"), Ino: ino}), 0
+	}
+
+	// Tool results: result.{json,txt,png,...} holds the raw payload, with the
+	// extension chosen from its detected type.
+	if strings.HasPrefix(name, "result.") {
+		data, ext, ok := shelley.ToolResultPayload(&m.message)
+		if !ok || name != "result."+ext {
+			return nil, syscall.ENOENT
+		}
+		setImmutableFieldAttrs(out, string(data), true, t, ttl)
+		ino := msgFieldIno(convID, seqID, name)
+		return m.NewInode(ctx, &MessageFieldNode{value: string(data), startTime: t, noNewline: true}, fs.StableAttr{Mode: fuse.S_IFREG, Ino: ino}), 0
 	}
 	return nil, syscall.ENOENT
 }
@@ -310,14 +439,23 @@ func (m *MessageDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Err
 	fieldIno := func(name string) uint64 {
 		return msgFieldIno(convID, seqID, name)
 	}
+	fieldEntry := func(name string) fuse.DirEntry {
+		entry := messageNames.entry(name)
+		entry.Ino = fieldIno(name)
+		return entry
+	}
 
 	entries := []fuse.DirEntry{
-		{Name: "message_id", Mode: fuse.S_IFREG, Ino: fieldIno("message_id")},
-		{Name: "conversation_id", Mode: fuse.S_IFREG, Ino: fieldIno("conversation_id")},
-		{Name: "sequence_id", Mode: fuse.S_IFREG, Ino: fieldIno("sequence_id")},
-		{Name: "type", Mode: fuse.S_IFREG, Ino: fieldIno("type")},
-		{Name: "created_at", Mode: fuse.S_IFREG, Ino: fieldIno("created_at")},
-		{Name: "content.md", Mode: fuse.S_IFREG, Ino: fieldIno("content.md")},
+		fieldEntry("message_id"),
+		fieldEntry("conversation_id"),
+		fieldEntry("sequence_id"),
+		fieldEntry("type"),
+		fieldEntry("created_at"),
+		fieldEntry("content.md"),
+		fieldEntry("content.txt"),
+	}
+	if _, ok := unixSeconds(m.message.CreatedAt); ok {
+		entries = append(entries, fieldEntry("created_at_unix"))
 	}
 	// Only include llm_data if present
 	if m.message.LLMData != nil && *m.message.LLMData != "" {
@@ -340,6 +478,18 @@ func (m *MessageDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Err
 			entries = append(entries, fuse.DirEntry{Name: "usage_data", Mode: fuse.S_IFREG, Ino: fieldIno("usage_data")})
 		}
 	}
+	// Only include result.{ext} and its size for tool results with a payload
+	if _, ext, ok := shelley.ToolResultPayload(&m.message); ok {
+		entries = append(entries, fuse.DirEntry{Name: "result." + ext, Mode: fuse.S_IFREG, Ino: fieldIno("result." + ext)})
+		entries = append(entries, fieldEntry("bytes"))
+	}
+	// Only include duration_ms when the backend timed the tool
+	if _, ok := shelley.ToolResultDuration(&m.message); ok {
+		entries = append(entries, fieldEntry("duration_ms"))
+	}
+	if m.pinnable() {
+		entries = append(entries, fieldEntry("ctl"))
+	}
 	return fs.NewListDirStream(entries), 0
 }
 
@@ -347,10 +497,65 @@ func (m *MessageDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Err
 func (m *MessageDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	m.messageTimestamps().ApplyWithFallback(&out.Attr, m.startTime)
-	out.SetTimeout(cacheTTLImmutable)
+	out.SetTimeout(cacheTimeouts(&m.Inode).Immutable)
 	return 0
 }
 
+// xattrFields returns the message fields mirrored as xattrs, in listing
+// order. Values are raw: no trailing newline, llm_data/usage_data as JSON.
+// input_tokens and output_tokens come from usage_data, cached input included.
+func (m *MessageDirNode) xattrFields() ([]string, map[string]string) {
+	names := []string{"message_id", "conversation_id", "sequence_id", "type", "created_at"}
+	values := map[string]string{
+		"message_id":      m.message.MessageID,
+		"conversation_id": m.message.ConversationID,
+		"sequence_id":     strconv.Itoa(m.message.SequenceID),
+		"type":            m.message.Type,
+		"created_at":      m.message.CreatedAt,
+	}
+	add := func(name, value string) {
+		names = append(names, name)
+		values[name] = value
+	}
+	if secs, ok := unixSeconds(m.message.CreatedAt); ok {
+		add("created_at_unix", secs)
+	}
+	if m.message.LLMData != nil && *m.message.LLMData != "" {
+		add("llm_data", *m.message.LLMData)
+	}
+	if m.message.UsageData != nil && *m.message.UsageData != "" {
+		add("usage_data", *m.message.UsageData)
+	}
+	if u, ok := messageUsage(&m.message); ok {
+		add("input_tokens", strconv.FormatInt(u.TotalInputTokens(), 10))
+		add("output_tokens", strconv.FormatInt(u.OutputTokens, 10))
+	}
+	if data, _, ok := shelley.ToolResultPayload(&m.message); ok {
+		add("bytes", strconv.Itoa(len(data)))
+	}
+	if d, ok := shelley.ToolResultDuration(&m.message); ok {
+		add("duration_ms", strconv.FormatInt(d.Milliseconds(), 10))
+	}
+	add("content.md", string(shelley.FormatMarkdown([]shelley.Message{m.message})))
+	if m.state != nil {
+		if at, ok := m.state.MessageEditedAt(m.localID, m.message.MessageID); ok {
+			add("edited_at", at.UTC().Format(time.RFC3339))
+		}
+	}
+	return names, values
+}
+
+// Getxattr returns a message field as user.shelley.{field}, so a whole
+// message can be read in one listxattr/getxattr pass.
+func (m *MessageDirNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	return getXattr(attr, dest, m.xattrFields)
+}
+
+func (m *MessageDirNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	names, _ := m.xattrFields()
+	return listXattr(names, dest)
+}
+
 // --- MessageFieldNode: read-only file for message field values ---
 
 type MessageFieldNode struct {
@@ -384,17 +589,21 @@ func (m *MessageFieldNode) Getattr(ctx context.Context, f fs.FileHandle, out *fu
 	}
 	out.Size = uint64(size)
 	setTimestamps(&out.Attr, m.startTime)
-	out.SetTimeout(cacheTTLImmutable)
+	out.SetTimeout(cacheTimeouts(&m.Inode).Immutable)
 	return 0
 }
 
-// --- MessageCountNode: /conversation/{id}/messages/count ---
+// --- MessageCountNode: /conversation/{id}/messages/{count,tokens,words} ---
+// count is the number of messages. tokens and words measure all.md, so a
+// prompt budget can be checked with cat before continuing: words as wc -w
+// counts them, tokens as estimated by shelley.EstimateTokens.
 
 type MessageCountNode struct {
 	fs.Inode
 	localID     string
 	client      shelley.ShelleyClient
 	state       *state.Store
+	measure     string // "count", "tokens" or "words"
 	startTime   time.Time
 	parsedCache *ParsedMessageCache
 }
@@ -407,17 +616,38 @@ func (m *MessageCountNode) messageCountData() []byte {
 	cs := m.state.Get(m.localID)
 	value := "0"
 	if cs != nil && cs.Created && cs.ShelleyConversationID != "" {
-		convData, err := m.client.GetConversation(cs.ShelleyConversationID)
+		convData, err := liveConversation(m.client, cs.ShelleyConversationID)
 		if err == nil {
 			msgs, _, err := m.parsedCache.GetOrParse(cs.ShelleyConversationID, convData)
 			if err == nil {
-				value = strconv.Itoa(len(msgs))
+				value = strconv.Itoa(m.measureMessages(msgs))
 			}
 		}
 	}
 	return []byte(value + "\n")
 }
 
+// liveConversation fetches a conversation for a file that scripts poll,
+// such as count, on the client's live TTL where it has one (see
+// shelley.CachingClient.GetConversationLive).
+func liveConversation(client shelley.ShelleyClient, conversationID string) ([]byte, error) {
+	if cc, ok := client.(*shelley.CachingClient); ok {
+		return cc.GetConversationLive(conversationID)
+	}
+	return client.GetConversation(conversationID)
+}
+
+// measureMessages returns the file's number for msgs.
+func (m *MessageCountNode) measureMessages(msgs []shelley.Message) int {
+	switch m.measure {
+	case "tokens":
+		return shelley.EstimateTokens(string(shelley.FormatMarkdown(msgs)))
+	case "words":
+		return shelley.CountWords(string(shelley.FormatMarkdown(msgs)))
+	}
+	return len(msgs)
+}
+
 func (m *MessageCountNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
 	// Compute content at open time so the file handle reports accurate size.
 	data := m.messageCountData()
@@ -439,6 +669,9 @@ func (m *MessageCountNode) Getattr(ctx context.Context, f fs.FileHandle, out *fu
 	out.Mode = fuse.S_IFREG | 0444
 	// Without an open handle we don't know the exact size; report 0.
 	// DIRECT_IO ensures the kernel still issues a read.
+	if exportCompat(&m.Inode) {
+		openedSize(ctx, m, out)
+	}
 	cs := m.state.Get(m.localID)
 	if cs != nil && !cs.CreatedAt.IsZero() {
 		setTimestamps(&out.Attr, cs.CreatedAt)
@@ -456,11 +689,16 @@ type messageCountFileHandle struct {
 
 var _ = (fs.FileReader)((*messageCountFileHandle)(nil))
 var _ = (fs.FileGetattrer)((*messageCountFileHandle)(nil))
+var _ = (fs.FileLseeker)((*messageCountFileHandle)(nil))
 
 func (h *messageCountFileHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
 	return fuse.ReadResultData(readAt(h.content, dest, off)), 0
 }
 
+func (h *messageCountFileHandle) Lseek(ctx context.Context, off uint64, whence uint32) (uint64, syscall.Errno) {
+	return seekContent(len(h.content), off, whence)
+}
+
 func (h *messageCountFileHandle) Getattr(ctx context.Context, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFREG | 0444
 	out.Size = uint64(len(h.content))
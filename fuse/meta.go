@@ -0,0 +1,233 @@
+package fuse
+
+import (
+	"context"
+	"log"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/state"
+)
+
+// --- MetaDirNode: /conversation/{id}/meta/ — free-form key/value files ---
+// Each file is one metadata key; its content is the value. Files can be
+// created, rewritten and removed at any time (even after the conversation is
+// created), and everything is persisted in the state store.
+
+type MetaDirNode struct {
+	fs.Inode
+	localID   string
+	state     *state.Store
+	startTime time.Time // fallback if conversation has no CreatedAt
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*MetaDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*MetaDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*MetaDirNode)(nil))
+var _ = (fs.NodeCreater)((*MetaDirNode)(nil))
+var _ = (fs.NodeUnlinker)((*MetaDirNode)(nil))
+
+// metaTime returns the conversation creation time, used for meta timestamps.
+func metaTime(store *state.Store, localID string, fallback time.Time) time.Time {
+	if cs := store.Get(localID); cs != nil && !cs.CreatedAt.IsZero() {
+		return cs.CreatedAt
+	}
+	return fallback
+}
+
+// maxMetaValue is the largest value a meta file can hold. Values live in
+// the state file, which is rewritten on every change, so writes and
+// truncates beyond it fail with EFBIG.
+const maxMetaValue = 1 << 20
+
+func (m *MetaDirNode) newFileNode(key string) *MetaFileNode {
+	return &MetaFileNode{localID: m.localID, key: key, state: m.state, startTime: m.startTime, diag: m.diag}
+}
+
+func (m *MetaDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(m.diag, "MetaDirNode", "Lookup", m.localID+"/"+name).Done()
+	if _, ok := m.state.GetMeta(m.localID, name); !ok {
+		return nil, syscall.ENOENT
+	}
+	return m.NewInode(ctx, m.newFileNode(name), fs.StableAttr{Mode: fuse.S_IFREG, Ino: stableIno("meta", m.localID, name)}), 0
+}
+
+func (m *MetaDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(m.diag, "MetaDirNode", "Readdir", m.localID).Done()
+	keys := m.state.ListMeta(m.localID)
+	entries := make([]fuse.DirEntry, 0, len(keys))
+	for _, k := range keys {
+		entries = append(entries, fuse.DirEntry{Name: k, Mode: fuse.S_IFREG, Ino: stableIno("meta", m.localID, k)})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (m *MetaDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, metaTime(m.state, m.localID, m.startTime))
+	return 0
+}
+
+// Create adds a new, empty metadata key. The value is filled in by the
+// writes that follow. With O_EXCL an existing key fails with EEXIST, and of
+// several processes racing to create the same key exactly one wins, so
+// meta/ files work as lockfiles.
+func (m *MetaDirNode) Create(ctx context.Context, name string, flags uint32, mode uint32, out *fuse.EntryOut) (*fs.Inode, fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(m.diag, "MetaDirNode", "Create", m.localID+"/"+name).Done()
+	if !isValidFilename(name) {
+		return nil, nil, 0, syscall.EINVAL
+	}
+	created, err := m.state.CreateMeta(m.localID, name)
+	if err != nil {
+		log.Printf("CreateMeta failed for %s/%s: %v", m.localID, name, err)
+		return nil, nil, 0, syscall.EIO
+	}
+	if !created {
+		if flags&syscall.O_EXCL != 0 {
+			return nil, nil, 0, syscall.EEXIST
+		}
+		if flags&syscall.O_TRUNC != 0 {
+			if err := m.state.SetMeta(m.localID, name, ""); err != nil {
+				log.Printf("SetMeta failed for %s/%s: %v", m.localID, name, err)
+				return nil, nil, 0, syscall.EIO
+			}
+		}
+	}
+	node := m.newFileNode(name)
+	inode := m.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFREG, Ino: stableIno("meta", m.localID, name)})
+	return inode, &metaFileHandle{appendMode: flags&syscall.O_APPEND != 0}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (m *MetaDirNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	defer diag.Track(m.diag, "MetaDirNode", "Unlink", m.localID+"/"+name).Done()
+	if _, ok := m.state.GetMeta(m.localID, name); !ok {
+		return syscall.ENOENT
+	}
+	if err := m.state.DeleteMeta(m.localID, name); err != nil {
+		log.Printf("DeleteMeta failed for %s/%s: %v", m.localID, name, err)
+		return syscall.EIO
+	}
+	return 0
+}
+
+// --- MetaFileNode: /conversation/{id}/meta/{key} ---
+
+type MetaFileNode struct {
+	fs.Inode
+	localID   string
+	key       string
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+	mu        sync.Mutex // serializes read-modify-write of the stored value
+}
+
+var _ = (fs.NodeOpener)((*MetaFileNode)(nil))
+var _ = (fs.NodeReader)((*MetaFileNode)(nil))
+var _ = (fs.NodeWriter)((*MetaFileNode)(nil))
+var _ = (fs.NodeGetattrer)((*MetaFileNode)(nil))
+var _ = (fs.NodeSetattrer)((*MetaFileNode)(nil))
+
+func (n *MetaFileNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if _, ok := n.state.GetMeta(n.localID, n.key); !ok {
+		return nil, 0, syscall.ENOENT
+	}
+	return &metaFileHandle{appendMode: flags&syscall.O_APPEND != 0}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *MetaFileNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	value, ok := n.state.GetMeta(n.localID, n.key)
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	return fuse.ReadResultData(readAt([]byte(value), dest, off)), 0
+}
+
+// Write splices data into the stored value and persists it immediately, so
+// every write is visible to other readers without waiting for close.
+// O_APPEND writes always go to the end: with DIRECT_IO the kernel's idea of
+// the file size may be stale, so the offset it passes can't be trusted.
+func (n *MetaFileNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	defer diag.Track(n.diag, "MetaFileNode", "Write", n.localID+"/"+n.key).Done()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	value, ok := n.state.GetMeta(n.localID, n.key)
+	if !ok {
+		return 0, syscall.ENOENT
+	}
+	buf := []byte(value)
+	if h, ok := f.(*metaFileHandle); ok && h.appendMode {
+		off = int64(len(buf))
+	}
+	if off+int64(len(data)) > maxMetaValue {
+		return 0, syscall.EFBIG
+	}
+	if end := int(off) + len(data); end > len(buf) {
+		buf = append(buf, make([]byte, end-len(buf))...)
+	}
+	copy(buf[off:], data)
+	if err := n.state.SetMeta(n.localID, n.key, string(buf)); err != nil {
+		log.Printf("SetMeta failed for %s/%s: %v", n.localID, n.key, err)
+		return 0, syscall.EIO
+	}
+	return uint32(len(data)), 0
+}
+
+func (n *MetaFileNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	value, ok := n.state.GetMeta(n.localID, n.key)
+	if !ok {
+		return syscall.ENOENT
+	}
+	out.Mode = fuse.S_IFREG | 0644
+	out.Size = uint64(len(value))
+	setTimestamps(&out.Attr, metaTime(n.state, n.localID, n.startTime))
+	return 0
+}
+
+// Setattr handles truncation (shell > redirects, editors that rewrite in place).
+func (n *MetaFileNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	if size, ok := in.GetSize(); ok {
+		if size > maxMetaValue {
+			return syscall.EFBIG
+		}
+		if errno := n.truncate(int(size)); errno != 0 {
+			return errno
+		}
+	}
+	return n.Getattr(ctx, f, out)
+}
+
+func (n *MetaFileNode) truncate(size int) syscall.Errno {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	value, ok := n.state.GetMeta(n.localID, n.key)
+	if !ok {
+		return syscall.ENOENT
+	}
+	if size == len(value) {
+		return 0
+	}
+	buf := []byte(value)
+	if size < len(buf) {
+		buf = buf[:size]
+	} else {
+		buf = append(buf, make([]byte, size-len(buf))...)
+	}
+	if err := n.state.SetMeta(n.localID, n.key, string(buf)); err != nil {
+		log.Printf("SetMeta failed for %s/%s: %v", n.localID, n.key, err)
+		return syscall.EIO
+	}
+	return 0
+}
+
+// metaFileHandle remembers the open flags that affect writes.
+type metaFileHandle struct {
+	appendMode bool
+}
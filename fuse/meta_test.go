@@ -0,0 +1,39 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func TestMetaFileSizeLimit(t *testing.T) {
+	store := testStore(t)
+	localID, _ := store.Clone()
+	if err := store.SetMeta(localID, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	n := &MetaFileNode{localID: localID, key: "k", state: store, startTime: time.Now()}
+	ctx := context.Background()
+
+	var in fuse.SetAttrIn
+	in.Valid = fuse.FATTR_SIZE
+	in.Size = 200 << 20
+	var out fuse.AttrOut
+	if errno := n.Setattr(ctx, nil, &in, &out); errno != syscall.EFBIG {
+		t.Errorf("truncate to 200M = %v, want EFBIG", errno)
+	}
+	if _, errno := n.Write(ctx, nil, []byte("x"), maxMetaValue); errno != syscall.EFBIG {
+		t.Errorf("write past the limit = %v, want EFBIG", errno)
+	}
+	if v, _ := store.GetMeta(localID, "k"); v != "v" {
+		t.Errorf("value = %q after refused changes, want it unchanged", v)
+	}
+
+	in.Size = 4
+	if errno := n.Setattr(ctx, nil, &in, &out); errno != 0 || out.Size != 4 {
+		t.Errorf("truncate to 4 = %v, size %d", errno, out.Size)
+	}
+}
@@ -0,0 +1,67 @@
+package fuse
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestMkdirConversation(t *testing.T) {
+	var mu sync.Mutex
+	var started []shelley.ChatRequest
+	server := mockserver.New(mockserver.WithNewConversationHandler(func(w http.ResponseWriter, r *http.Request) {
+		var req shelley.ChatRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		started = append(started, req)
+		mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"conversation_id": "conv-mkdir", "slug": req.Slug})
+	}))
+	defer server.Close()
+	store := testStore(t)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+
+	if err := os.Mkdir(filepath.Join(convDir, "nightly-build"), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	localID := store.GetBySlug("nightly-build")
+	if localID == "" {
+		t.Fatal("mkdir didn't allocate a conversation with the slug")
+	}
+	if cs := store.Get(localID); cs == nil || cs.Created {
+		t.Fatalf("new conversation = %+v, want a pending clone", cs)
+	}
+	ctl, err := os.ReadFile(filepath.Join(convDir, "nightly-build", "ctl"))
+	if err != nil || string(ctl) != "slug=nightly-build\n" {
+		t.Errorf("ctl = %q, %v", ctl, err)
+	}
+
+	for _, name := range []string{"nightly-build", localID, "last"} {
+		if err := os.Mkdir(filepath.Join(convDir, name), 0755); !errors.Is(err, syscall.EEXIST) {
+			t.Errorf("mkdir %s: got %v, want EEXIST", name, err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(convDir, localID, "send"), []byte("hello\n"), 0); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(started) != 1 || started[0].Slug != "nightly-build" {
+		t.Errorf("StartConversation requests = %+v, want one with the slug", started)
+	}
+	if cs := store.Get(localID); !cs.Created || cs.Slug != "nightly-build" {
+		t.Errorf("after the first message: %+v", cs)
+	}
+}
@@ -2,6 +2,11 @@ package fuse
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"sort"
 	"syscall"
 	"time"
 
@@ -16,10 +21,12 @@ import (
 
 type ModelsDirNode struct {
 	fs.Inode
-	client    shelley.ShelleyClient
-	state     *state.Store
-	startTime time.Time
-	diag      *diag.Tracker
+	client       shelley.ShelleyClient
+	state        *state.Store
+	aliases      map[string]string // alias name -> model display name or ID
+	startTime    time.Time
+	readyTimeout time.Duration // for wait_ready (0 = DefaultModelReadyTimeout)
+	diag         *diag.Tracker
 }
 
 var _ = (fs.NodeLookuper)((*ModelsDirNode)(nil))
@@ -29,7 +36,7 @@ var _ = (fs.NodeGetattrer)((*ModelsDirNode)(nil))
 func (m *ModelsDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
 	defer diag.Track(m.diag, "ModelsDirNode", "Lookup", name).Done()
 
-	setEntryTimeout(out, cacheTTLModels)
+	setEntryTimeout(out, cacheTimeouts(&m.Inode).Models)
 
 	// Handle "default" symlink — target uses display name
 	if name == "default" {
@@ -40,7 +47,7 @@ func (m *ModelsDirNode) Lookup(ctx context.Context, name string, out *fuse.Entry
 		// Resolve model ID to display name
 		result, err := m.client.ListModels()
 		if err != nil {
-			return nil, syscall.EIO
+			return nil, backendErrno(err)
 		}
 		defName := ""
 		for _, model := range result.Models {
@@ -57,13 +64,13 @@ func (m *ModelsDirNode) Lookup(ctx context.Context, name string, out *fuse.Entry
 
 	result, err := m.client.ListModels()
 	if err != nil {
-		return nil, syscall.EIO
+		return nil, backendErrno(err)
 	}
 
 	// Primary lookup: match by display name
 	for _, model := range result.Models {
 		if model.Name() == name {
-			return m.NewInode(ctx, &ModelNode{model: model, client: m.client, state: m.state, startTime: m.startTime, diag: m.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+			return m.NewInode(ctx, &ModelNode{model: model, client: m.client, state: m.state, startTime: m.startTime, readyTimeout: m.readyTimeout, diag: m.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
 		}
 	}
 	// Fallback: match by internal ID — return symlink to display name
@@ -72,6 +79,17 @@ func (m *ModelsDirNode) Lookup(ctx context.Context, name string, out *fuse.Entry
 			return m.NewInode(ctx, &SymlinkNode{target: model.Name(), startTime: m.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
 		}
 	}
+	// An ID the backend renamed the model from, so conversations' model
+	// symlinks keep resolving. Not listed.
+	for _, model := range result.Models {
+		if slices.Contains(model.Aliases, name) {
+			return m.NewInode(ctx, &SymlinkNode{target: model.Name(), startTime: m.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		}
+	}
+	// Configured aliases — also symlinks to the display name
+	if target := m.aliasTarget(name, result); target != "" {
+		return m.NewInode(ctx, &SymlinkNode{target: target, startTime: m.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+	}
 	return nil, syscall.ENOENT
 }
 
@@ -79,7 +97,7 @@ func (m *ModelsDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errn
 	defer diag.Track(m.diag, "ModelsDirNode", "Readdir", "").Done()
 	result, err := m.client.ListModels()
 	if err != nil {
-		return nil, syscall.EIO
+		return nil, backendErrno(err)
 	}
 
 	// Capacity for models + optional default symlink + ID alias symlinks
@@ -98,13 +116,42 @@ func (m *ModelsDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errn
 			entries = append(entries, fuse.DirEntry{Name: model.ID, Mode: syscall.S_IFLNK})
 		}
 	}
+	var aliases []string
+	for alias := range m.aliases {
+		if m.aliasTarget(alias, result) != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		entries = append(entries, fuse.DirEntry{Name: alias, Mode: syscall.S_IFLNK})
+	}
 	return fs.NewListDirStream(entries), 0
 }
 
+// aliasTarget returns the display name of the model the alias name points
+// to, or "" if name is not an alias, its model doesn't exist, or a model
+// (or "default") already goes by that name.
+func (m *ModelsDirNode) aliasTarget(name string, result shelley.ModelsResult) string {
+	target, ok := m.aliases[name]
+	if !ok || name == "default" {
+		return ""
+	}
+	for _, model := range result.Models {
+		if model.Name() == name || model.ID == name {
+			return ""
+		}
+	}
+	if model := result.FindByName(target); model != nil {
+		return model.Name()
+	}
+	return ""
+}
+
 func (m *ModelsDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, m.startTime)
-	out.SetTimeout(cacheTTLModels)
+	out.SetTimeout(cacheTimeouts(&m.Inode).Models)
 	return 0
 }
 
@@ -112,11 +159,12 @@ func (m *ModelsDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.
 
 type ModelNode struct {
 	fs.Inode
-	model     shelley.Model
-	client    shelley.ShelleyClient
-	state     *state.Store
-	startTime time.Time
-	diag      *diag.Tracker
+	model        shelley.Model
+	client       shelley.ShelleyClient
+	state        *state.Store
+	startTime    time.Time
+	readyTimeout time.Duration
+	diag         *diag.Tracker
 }
 
 var _ = (fs.NodeLookuper)((*ModelNode)(nil))
@@ -124,42 +172,90 @@ var _ = (fs.NodeReaddirer)((*ModelNode)(nil))
 var _ = (fs.NodeGetattrer)((*ModelNode)(nil))
 
 func (m *ModelNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
-	setEntryTimeout(out, cacheTTLModels)
+	setEntryTimeout(out, cacheTimeouts(&m.Inode).Models)
 	switch name {
 	case "id":
-		return m.NewInode(ctx, &ModelFieldNode{value: m.model.ID, startTime: m.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return m.NewInode(ctx, &ModelFieldNode{value: m.model.ID, startTime: m.startTime}, fs.StableAttr{Mode: modelNames.mode("id")}), 0
+	case "web":
+		linker, ok := webLinker(m.client)
+		if !ok {
+			return nil, syscall.ENOENT
+		}
+		return m.NewInode(ctx, &ModelFieldNode{value: linker.ModelWebURL(m.model.ID), startTime: m.startTime}, fs.StableAttr{Mode: modelNames.mode("web")}), 0
 	case "ready":
 		// Presence/absence semantics: file exists only when model is ready
 		if !m.model.Ready {
 			return nil, syscall.ENOENT
 		}
-		return m.NewInode(ctx, &ModelReadyNode{startTime: m.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		return m.NewInode(ctx, &ModelReadyNode{startTime: m.startTime}, fs.StableAttr{Mode: modelNames.mode("ready")}), 0
+	case "deprecated":
+		// Present only for deprecated models; reads the suggested
+		// replacement's ID, if the backend names one.
+		if !m.model.Deprecated {
+			return nil, syscall.ENOENT
+		}
+		if m.model.ReplacedBy == "" {
+			return m.NewInode(ctx, &ModelReadyNode{startTime: m.startTime}, fs.StableAttr{Mode: modelNames.mode("deprecated")}), 0
+		}
+		return m.NewInode(ctx, &ModelFieldNode{value: m.model.ReplacedBy, startTime: m.startTime}, fs.StableAttr{Mode: modelNames.mode("deprecated")}), 0
+	case "replacement":
+		target, errno := m.replacement()
+		if errno != 0 {
+			return nil, errno
+		}
+		return m.NewInode(ctx, &SymlinkNode{target: target, startTime: m.startTime}, fs.StableAttr{Mode: modelNames.mode("replacement")}), 0
+	case "wait_ready":
+		return m.NewInode(ctx, &ModelWaitReadyNode{modelID: m.model.ID, client: m.client, timeout: m.readyTimeout, startTime: m.startTime, diag: m.diag}, fs.StableAttr{Mode: modelNames.mode("wait_ready")}), 0
 	case "new":
-		return m.NewInode(ctx, &ModelNewDirNode{model: m.model, state: m.state, startTime: m.startTime, diag: m.diag}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+		return m.NewInode(ctx, &ModelNewDirNode{model: m.model, state: m.state, startTime: m.startTime, diag: m.diag}, fs.StableAttr{Mode: modelNames.mode("new")}), 0
 	}
 	return nil, syscall.ENOENT
 }
 
 func (m *ModelNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
-	entries := []fuse.DirEntry{
-		{Name: "id", Mode: fuse.S_IFREG},
-		{Name: "new", Mode: fuse.S_IFDIR},
+	entries := modelNames.entries("id", "new", "wait_ready")
+	if _, ok := webLinker(m.client); ok {
+		entries = append(entries, modelNames.entry("web"))
 	}
 	// Presence/absence semantics: only include "ready" if model is ready
 	if m.model.Ready {
-		entries = append(entries, fuse.DirEntry{Name: "ready", Mode: fuse.S_IFREG})
+		entries = append(entries, modelNames.entry("ready"))
+	}
+	if m.model.Deprecated {
+		entries = append(entries, modelNames.entry("deprecated"))
+		if _, errno := m.replacement(); errno == 0 {
+			entries = append(entries, modelNames.entry("replacement"))
+		}
 	}
 	return fs.NewListDirStream(entries), 0
 }
 
+// replacement returns the symlink target of the model the backend suggests
+// instead of this deprecated one: its directory next to this one. ENOENT if
+// the model isn't deprecated, no replacement is named, or it isn't listed.
+func (m *ModelNode) replacement() (string, syscall.Errno) {
+	if !m.model.Deprecated || m.model.ReplacedBy == "" {
+		return "", syscall.ENOENT
+	}
+	result, err := m.client.ListModels()
+	if err != nil {
+		return "", backendErrno(err)
+	}
+	model := result.FindByName(m.model.ReplacedBy)
+	if model == nil {
+		return "", syscall.ENOENT
+	}
+	return "../" + model.Name(), 0
+}
+
 func (m *ModelNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, m.startTime)
-	out.SetTimeout(cacheTTLModels)
+	out.SetTimeout(cacheTimeouts(&m.Inode).Models)
 	return 0
 }
 
-// --- ModelFieldNode: read-only file for a model field (id or ready) ---
+// --- ModelFieldNode: read-only file for a model field (id or web) ---
 
 type ModelFieldNode struct {
 	fs.Inode
@@ -184,11 +280,11 @@ func (m *ModelFieldNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse
 	out.Mode = fuse.S_IFREG | 0444
 	out.Size = uint64(len(m.value) + 1)
 	setTimestamps(&out.Attr, m.startTime)
-	out.SetTimeout(cacheTTLModels)
+	out.SetTimeout(cacheTimeouts(&m.Inode).Models)
 	return 0
 }
 
-// --- ModelReadyNode: empty file indicating model is ready (presence/absence semantics) ---
+// --- ModelReadyNode: empty file whose presence is the signal (ready, deprecated) ---
 
 type ModelReadyNode struct {
 	fs.Inode
@@ -212,11 +308,92 @@ func (m *ModelReadyNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse
 	out.Mode = fuse.S_IFREG | 0444
 	out.Size = 0
 	setTimestamps(&out.Attr, m.startTime)
-	out.SetTimeout(cacheTTLModels)
+	out.SetTimeout(cacheTimeouts(&m.Inode).Models)
+	return 0
+}
+
+// --- ModelWaitReadyNode: /model/{model-id}/wait_ready — blocks until the model is ready ---
+// Opening the file polls the model list until the model reports ready, then
+// reads as "ready\n". Provisioning scripts can `cat model/X/wait_ready`
+// instead of looping on the presence of the ready file. Fails with
+// ETIMEDOUT once the timeout passes, ENOENT if the model is removed, and
+// EINTR if the reader is interrupted.
+
+// DefaultModelReadyTimeout is how long wait_ready waits unless changed with
+// FS.SetModelReadyTimeout.
+const DefaultModelReadyTimeout = 5 * time.Minute
+
+// modelReadyPollInterval is how often wait_ready re-fetches the model list.
+var modelReadyPollInterval = 2 * time.Second
+
+type ModelWaitReadyNode struct {
+	fs.Inode
+	modelID   string
+	client    shelley.ShelleyClient
+	timeout   time.Duration
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*ModelWaitReadyNode)(nil))
+var _ = (fs.NodeGetattrer)((*ModelWaitReadyNode)(nil))
+
+// modelReady reports whether the model is listed and ready. A failed fetch
+// counts as not ready yet, so a backend restart doesn't end the wait.
+func (w *ModelWaitReadyNode) modelReady() (ready, found bool) {
+	result, err := w.client.ListModels()
+	if err != nil {
+		return false, true
+	}
+	for _, model := range result.Models {
+		if model.ID == w.modelID {
+			return model.Ready, true
+		}
+	}
+	return false, false
+}
+
+func (w *ModelWaitReadyNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	op := diag.Track(w.diag, "ModelWaitReadyNode", "Open", w.modelID)
+	defer op.Done()
+	timeout := w.timeout
+	if timeout <= 0 {
+		timeout = DefaultModelReadyTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(modelReadyPollInterval)
+	defer ticker.Stop()
+
+	op.SetPhase("waiting for ready")
+	for {
+		ready, found := w.modelReady()
+		if !found {
+			return nil, 0, syscall.ENOENT
+		}
+		if ready {
+			return &ConvContentFileHandle{content: []byte("ready\n"), messageTime: w.startTime}, fuse.FOPEN_DIRECT_IO, 0
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return nil, 0, syscall.ETIMEDOUT
+		case <-ctx.Done():
+			return nil, 0, syscall.EINTR
+		}
+	}
+}
+
+func (w *ModelWaitReadyNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fga, ok := f.(fs.FileGetattrer); ok {
+		return fga.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	setTimestamps(&out.Attr, w.startTime)
 	return 0
 }
 
-// --- ModelNewDirNode: /model/{model-id}/new/ directory containing clone ---
+// --- ModelNewDirNode: /model/{model-id}/new/ directory containing clone, clone.json and start ---
 
 type ModelNewDirNode struct {
 	fs.Inode
@@ -231,10 +408,12 @@ var _ = (fs.NodeReaddirer)((*ModelNewDirNode)(nil))
 var _ = (fs.NodeGetattrer)((*ModelNewDirNode)(nil))
 
 func (n *ModelNewDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
-	setEntryTimeout(out, cacheTTLModels)
+	setEntryTimeout(out, cacheTimeouts(&n.Inode).Models)
 	switch name {
 	case "clone":
 		return n.NewInode(ctx, &ModelCloneNode{model: n.model, state: n.state, startTime: n.startTime, diag: n.diag}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+	case "clone.json":
+		return n.NewInode(ctx, &ModelCloneNode{model: n.model, state: n.state, startTime: n.startTime, diag: n.diag, asJSON: true}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
 	case "start":
 		return n.NewInode(ctx, &ModelStartNode{model: n.model, startTime: n.startTime}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
 	}
@@ -244,6 +423,7 @@ func (n *ModelNewDirNode) Lookup(ctx context.Context, name string, out *fuse.Ent
 func (n *ModelNewDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
 	return fs.NewListDirStream([]fuse.DirEntry{
 		{Name: "clone", Mode: fuse.S_IFREG},
+		{Name: "clone.json", Mode: fuse.S_IFREG},
 		{Name: "start", Mode: fuse.S_IFREG},
 	}), 0
 }
@@ -251,11 +431,12 @@ func (n *ModelNewDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Er
 func (n *ModelNewDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFDIR | 0755
 	setTimestamps(&out.Attr, n.startTime)
-	out.SetTimeout(cacheTTLModels)
+	out.SetTimeout(cacheTimeouts(&n.Inode).Models)
 	return 0
 }
 
 // --- ModelCloneNode: /model/{model-id}/new/clone — clones with model preconfigured ---
+// new/clone.json is the same node with asJSON set.
 
 type ModelCloneNode struct {
 	fs.Inode
@@ -263,6 +444,7 @@ type ModelCloneNode struct {
 	state     *state.Store
 	startTime time.Time
 	diag      *diag.Tracker
+	asJSON    bool // report {"local_id", "path"} instead of the bare ID
 }
 
 var _ = (fs.NodeOpener)((*ModelCloneNode)(nil))
@@ -271,6 +453,10 @@ var _ = (fs.NodeGetattrer)((*ModelCloneNode)(nil))
 func (c *ModelCloneNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
 	defer diag.Track(c.diag, "ModelCloneNode", "Open", c.model.Name()).Done()
 	id, err := c.state.Clone()
+	if errors.Is(err, state.ErrTooManyClones) {
+		log.Printf("Clone refused: %v (rm conversation/.pending/{id} to discard some)", err)
+		return nil, 0, syscall.EDQUOT
+	}
 	if err != nil {
 		return nil, 0, syscall.EIO
 	}
@@ -278,28 +464,43 @@ func (c *ModelCloneNode) Open(ctx context.Context, flags uint32) (fs.FileHandle,
 	if err := c.state.SetModel(id, c.model.Name(), c.model.ID); err != nil {
 		return nil, 0, syscall.EIO
 	}
-	return &CloneFileHandle{id: id, diag: c.diag}, fuse.FOPEN_DIRECT_IO, 0
+	return &CloneFileHandle{id: id, diag: c.diag, asJSON: c.asJSON}, fuse.FOPEN_DIRECT_IO, 0
 }
 
 func (c *ModelCloneNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
 	out.Mode = fuse.S_IFREG | 0444
 	setTimestamps(&out.Attr, c.startTime)
-	out.SetTimeout(cacheTTLModels)
+	out.SetTimeout(cacheTimeouts(&c.Inode).Models)
 	return 0
 }
 
 // --- CloneFileHandle: shared file handle for clone nodes ---
 
 type CloneFileHandle struct {
-	id   string
-	diag *diag.Tracker
+	id     string
+	diag   *diag.Tracker
+	asJSON bool
 }
 
 var _ = (fs.FileReader)((*CloneFileHandle)(nil))
 
+// cloneResult is the clone.json payload. Path is relative to the mount root.
+type cloneResult struct {
+	LocalID string `json:"local_id"`
+	Path    string `json:"path"`
+}
+
 func (h *CloneFileHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
 	defer diag.Track(h.diag, "CloneFileHandle", "Read", h.id).Done()
 	data := []byte(h.id + "\n")
+	if h.asJSON {
+		var err error
+		data, err = json.Marshal(cloneResult{LocalID: h.id, Path: "conversation/" + h.id})
+		if err != nil {
+			return nil, syscall.EIO
+		}
+		data = append(data, '\n')
+	}
 	return fuse.ReadResultData(readAt(data, dest, off)), 0
 }
 
@@ -0,0 +1,104 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+func TestModelAliases(t *testing.T) {
+	server := mockModelsServer(t, []shelley.Model{
+		{ID: "claude-haiku", Ready: true},
+		{ID: "custom-1", DisplayName: "opus-tuned", Ready: true},
+	})
+	defer server.Close()
+	shelleyFS := NewFS(shelley.NewClient(server.URL), testStore(t), time.Hour)
+	shelleyFS.SetModelAliases(map[string]string{
+		"fast":         "claude-haiku",
+		"smart":        "custom-1", // by ID, linked by display name
+		"gone":         "retired-model",
+		"claude-haiku": "custom-1", // a model's own name wins
+	})
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	modelDir := filepath.Join(mountPoint, "model")
+
+	for alias, want := range map[string]string{"fast": "claude-haiku", "smart": "opus-tuned"} {
+		target, err := os.Readlink(filepath.Join(modelDir, alias))
+		if err != nil || target != want {
+			t.Errorf("readlink %s = %q, %v; want %q", alias, target, err, want)
+		}
+	}
+	if id, err := os.ReadFile(filepath.Join(modelDir, "smart", "id")); err != nil || strings.TrimSpace(string(id)) != "custom-1" {
+		t.Errorf("model/smart/id = %q, %v", id, err)
+	}
+	if _, err := os.Lstat(filepath.Join(modelDir, "gone")); !os.IsNotExist(err) {
+		t.Errorf("alias of a missing model: got %v, want ENOENT", err)
+	}
+	if fi, err := os.Lstat(filepath.Join(modelDir, "claude-haiku")); err != nil || !fi.IsDir() {
+		t.Errorf("alias shadowing a model: got %v, %v; want the model directory", fi, err)
+	}
+
+	entries, err := os.ReadDir(modelDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var links []string
+	for _, e := range entries {
+		if e.Type()&os.ModeSymlink != 0 {
+			links = append(links, e.Name())
+		}
+	}
+	sort.Strings(links)
+	if want := []string{"custom-1", "fast", "smart"}; !reflect.DeepEqual(links, want) {
+		t.Errorf("symlinks in model/ = %v, want %v", links, want)
+	}
+}
+
+func TestModelDeprecation(t *testing.T) {
+	server := mockModelsServer(t, []shelley.Model{
+		{ID: "claude-old", Ready: true, Deprecated: true, ReplacedBy: "claude-new-id"},
+		{ID: "claude-new-id", DisplayName: "claude-new", Ready: true, Aliases: []string{"claude-renamed-from"}},
+		{ID: "claude-sunset", Ready: true, Deprecated: true},
+	})
+	defer server.Close()
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), testStore(t), time.Hour))
+	defer cleanup()
+	modelDir := filepath.Join(mountPoint, "model")
+
+	if data, err := os.ReadFile(filepath.Join(modelDir, "claude-old", "deprecated")); err != nil || string(data) != "claude-new-id\n" {
+		t.Errorf("claude-old/deprecated = %q, %v; want the replacement's ID", data, err)
+	}
+	if target, err := os.Readlink(filepath.Join(modelDir, "claude-old", "replacement")); err != nil || target != "../claude-new" {
+		t.Errorf("claude-old/replacement -> %q, %v; want ../claude-new", target, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(modelDir, "claude-sunset", "deprecated")); err != nil || len(data) != 0 {
+		t.Errorf("claude-sunset/deprecated = %q, %v; want an empty file", data, err)
+	}
+	if _, err := os.Lstat(filepath.Join(modelDir, "claude-sunset", "replacement")); !os.IsNotExist(err) {
+		t.Errorf("replacement without a suggestion: got %v, want ENOENT", err)
+	}
+	if _, err := os.Stat(filepath.Join(modelDir, "claude-new", "deprecated")); !os.IsNotExist(err) {
+		t.Errorf("deprecated of a current model: got %v, want ENOENT", err)
+	}
+
+	// The ID a model was renamed from still resolves, without being listed.
+	if target, err := os.Readlink(filepath.Join(modelDir, "claude-renamed-from")); err != nil || target != "claude-new" {
+		t.Errorf("readlink claude-renamed-from = %q, %v; want claude-new", target, err)
+	}
+	entries, err := os.ReadDir(modelDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Name() == "claude-renamed-from" {
+			t.Error("renamed-from ID is listed in model/")
+		}
+	}
+}
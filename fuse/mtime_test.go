@@ -0,0 +1,44 @@
+package fuse
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestConversationMtimeFollowsMessages(t *testing.T) {
+	question, answer := "Anything new?", "Yes."
+	messages := []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-mtime", SequenceID: 1, Type: "user", UserData: &question, CreatedAt: "2025-06-01T11:59:00Z"},
+		{MessageID: "m2", ConversationID: "conv-mtime", SequenceID: 2, Type: "shelley", UserData: &answer, CreatedAt: "2025-06-01T12:00:00Z"},
+	}
+	server := mockserver.New(mockserver.WithConversation("conv-mtime", messages))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithMetadata("conv-mtime", "", "2024-01-01T00:00:00Z", "2024-01-01T00:00:00Z", "", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	if _, err := os.ReadFile(filepath.Join(mountPoint, "conversation", localID, "messages", "all.md")); err != nil {
+		t.Fatalf("read all.md: %v", err)
+	}
+
+	// The kernel keeps the directory's attributes for the conversation cache timeout,
+	// so ask the nodes rather than stat.
+	want := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var out fuse.AttrOut
+	(&ConversationNode{localID: localID, state: store}).Getattr(context.Background(), nil, &out)
+	if got := time.Unix(int64(out.Mtime), int64(out.Mtimensec)); !got.Equal(want) {
+		t.Errorf("conversation mtime = %v, want the newest message's %v", got.UTC(), want)
+	}
+	(&ConversationListNode{state: store, startTime: want.Add(-time.Hour)}).Getattr(context.Background(), nil, &out)
+	if got := time.Unix(int64(out.Mtime), int64(out.Mtimensec)); !got.Equal(want) {
+		t.Errorf("conversation/ mtime = %v, want %v", got.UTC(), want)
+	}
+}
@@ -0,0 +1,216 @@
+package fuse
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// Naming registry. Every fixed name a directory of the filesystem can hold
+// is listed below with the type of node it is, and both Readdir and Lookup
+// take the type from here, so the two can't disagree about a name (ls -l and
+// find trust the type Readdir reports; stat trusts Lookup).
+//
+// Names follow the suffix conventions of fileNameSuffixes: a name ending in
+// .json, .md, ... is a regular file, and a name ending in .d a directory, so
+// `find -name '*.json'` finds every JSON file and nothing else. Names whose
+// type depends on content (llm_data/, usage_data/ and conversation fields
+// unpacked from JSON) or that are made up at run time (conversation IDs,
+// message directories, result.{ext}) aren't in the registry; they must
+// still follow the conventions.
+
+// nameTable maps the fixed names of one kind of directory to their node
+// type (fuse.S_IFREG, fuse.S_IFDIR or syscall.S_IFLNK).
+type nameTable map[string]uint32
+
+// mode returns the node type of name. Asking for a name that isn't in the
+// table is a programming error.
+func (t nameTable) mode(name string) uint32 {
+	mode, ok := t[name]
+	if !ok {
+		panic(fmt.Sprintf("fuse: %q is not in the naming registry", name))
+	}
+	return mode
+}
+
+// entry returns the directory entry for name.
+func (t nameTable) entry(name string) fuse.DirEntry {
+	return fuse.DirEntry{Name: name, Mode: t.mode(name)}
+}
+
+// entries returns the directory entries for names, in order.
+func (t nameTable) entries(names ...string) []fuse.DirEntry {
+	entries := make([]fuse.DirEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, t.entry(name))
+	}
+	return entries
+}
+
+// fileNameSuffixes are the suffixes that make a name a regular file.
+var fileNameSuffixes = []string{".json", ".jsonl", ".md", ".org", ".txt", ".csv", ".log"}
+
+// dirNameSuffix is the suffix that makes a name a directory.
+const dirNameSuffix = ".d"
+
+// conventionalMode returns the node type name's suffix calls for, if any.
+func conventionalMode(name string) (uint32, bool) {
+	if strings.HasSuffix(name, dirNameSuffix) {
+		return fuse.S_IFDIR, true
+	}
+	for _, suffix := range fileNameSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fuse.S_IFREG, true
+		}
+	}
+	return 0, false
+}
+
+var (
+	// rootNames are the names in the mount's root directory on a mount
+	// without backends (NewFS).
+	rootNames = nameTable{
+		"README.md":    fuse.S_IFREG,
+		"model":        fuse.S_IFDIR,
+		"new":          syscall.S_IFLNK,
+		"conversation": fuse.S_IFDIR,
+		"shelley":      fuse.S_IFDIR,
+		"usage":        fuse.S_IFDIR,
+		"stats":        fuse.S_IFDIR,
+		"search":       fuse.S_IFDIR,
+		"events":       fuse.S_IFREG,
+	}
+
+	// rootBackendNames are the names in the mount's root directory on a
+	// mount with backends (NewFSWithBackends): model/ and conversation/
+	// lead to the default backend's.
+	rootBackendNames = nameTable{
+		"README.md":    fuse.S_IFREG,
+		"backend":      fuse.S_IFDIR,
+		"model":        syscall.S_IFLNK,
+		"new":          syscall.S_IFLNK,
+		"conversation": syscall.S_IFLNK,
+		"shelley":      fuse.S_IFDIR,
+		"usage":        fuse.S_IFDIR,
+		"stats":        fuse.S_IFDIR,
+		"search":       fuse.S_IFDIR,
+		"events":       fuse.S_IFREG,
+	}
+
+	// backendListNames are the fixed names in /backend, next to one
+	// directory per backend.
+	backendListNames = nameTable{
+		"default":      syscall.S_IFLNK,
+		"latency.json": fuse.S_IFREG,
+		"state_status": fuse.S_IFREG,
+	}
+
+	// backendNames are the names in /backend/{name}.
+	backendNames = nameTable{
+		"url":          fuse.S_IFREG,
+		"capabilities": fuse.S_IFREG,
+		"model":        fuse.S_IFDIR,
+		"conversation": fuse.S_IFDIR,
+		"new":          syscall.S_IFLNK,
+	}
+
+	// modelNames are the names in model/{model}.
+	modelNames = nameTable{
+		"id":          fuse.S_IFREG,
+		"new":         fuse.S_IFDIR,
+		"wait_ready":  fuse.S_IFREG,
+		"web":         fuse.S_IFREG,
+		"ready":       fuse.S_IFREG,
+		"deprecated":  fuse.S_IFREG,
+		"replacement": syscall.S_IFLNK,
+	}
+
+	// conversationListNames are the fixed names in /conversation, next to
+	// the conversations.
+	conversationListNames = nameTable{
+		"last":           fuse.S_IFDIR,
+		"import":         fuse.S_IFREG,
+		"attach":         fuse.S_IFREG,
+		"index.json":     fuse.S_IFREG,
+		"top":            fuse.S_IFDIR,
+		".bulk":          fuse.S_IFREG,
+		"by-title":       fuse.S_IFDIR,
+		".events":        fuse.S_IFREG,
+		".activity.json": fuse.S_IFREG,
+		".pending":       fuse.S_IFDIR,
+	}
+
+	// conversationNames are the fixed names in /conversation/{id}, next to
+	// the fields of the conversation's JSON.
+	conversationNames = nameTable{
+		"ctl":        fuse.S_IFREG,
+		"send":       fuse.S_IFREG,
+		"messages":   fuse.S_IFDIR,
+		"meta":       fuse.S_IFDIR,
+		"bookmark":   fuse.S_IFREG,
+		"fuse_id":    fuse.S_IFREG,
+		"web":        fuse.S_IFREG,
+		".trace":     fuse.S_IFREG,
+		"errors.log": fuse.S_IFREG,
+		"created":    fuse.S_IFREG,
+		"model":      syscall.S_IFLNK,
+		"cwd":        syscall.S_IFLNK,
+		"archived":   fuse.S_IFREG,
+		"continue":   fuse.S_IFREG,
+		"title":      fuse.S_IFREG,
+		"subagents":  fuse.S_IFDIR,
+		"export.tar": fuse.S_IFREG,
+		"export.zip": fuse.S_IFREG,
+		"snapshots":  fuse.S_IFDIR,
+		"working":    fuse.S_IFREG,
+		"cancel":     fuse.S_IFREG,
+	}
+
+	// messagesNames are the fixed names in /conversation/{id}/messages,
+	// next to the message directories.
+	messagesNames = nameTable{
+		"all.json": fuse.S_IFREG,
+		"all.md":   fuse.S_IFREG,
+		"all.org":  fuse.S_IFREG,
+		"all.md.d": fuse.S_IFDIR,
+		"count":    fuse.S_IFREG,
+		"diff":     fuse.S_IFDIR,
+		"filter":   fuse.S_IFDIR,
+		"last":     fuse.S_IFDIR,
+		"pinned":   fuse.S_IFDIR,
+		"since":    fuse.S_IFDIR,
+		"stream":   fuse.S_IFREG,
+		"tokens":   fuse.S_IFREG,
+		"words":    fuse.S_IFREG,
+	}
+
+	// messageNames are the fixed names in a message directory.
+	messageNames = nameTable{
+		"message_id":      fuse.S_IFREG,
+		"conversation_id": fuse.S_IFREG,
+		"sequence_id":     fuse.S_IFREG,
+		"type":            fuse.S_IFREG,
+		"created_at":      fuse.S_IFREG,
+		"created_at_unix": fuse.S_IFREG,
+		"content.md":      fuse.S_IFREG,
+		"content.txt":     fuse.S_IFREG,
+		"bytes":           fuse.S_IFREG,
+		"duration_ms":     fuse.S_IFREG,
+		"ctl":             fuse.S_IFREG,
+	}
+)
+
+// nameTables lists every table, for the checks in tests.
+var nameTables = map[string]nameTable{
+	"root":              rootNames,
+	"root (backends)":   rootBackendNames,
+	"backend":           backendListNames,
+	"backend/{name}":    backendNames,
+	"model/{model}":     modelNames,
+	"conversation":      conversationListNames,
+	"conversation/{id}": conversationNames,
+	"messages":          messagesNames,
+	"messages/{NNN}":    messageNames,
+}
@@ -0,0 +1,122 @@
+package fuse
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+func TestNameRegistryConventions(t *testing.T) {
+	for dir, table := range nameTables {
+		for name, mode := range table {
+			switch mode {
+			case fuse.S_IFREG, fuse.S_IFDIR, syscall.S_IFLNK:
+			default:
+				t.Errorf("%s/%s: mode %o is not a file, directory or symlink", dir, name, mode)
+			}
+			if want, ok := conventionalMode(name); ok && mode != want {
+				t.Errorf("%s/%s: mode %o, but its suffix calls for %o", dir, name, mode, want)
+			}
+		}
+	}
+}
+
+// walkTypes walks the tree under root, without following symlinks, and
+// checks that the type each directory listing reports for an entry is the
+// type stat finds, and that names follow the suffix conventions.
+func walkTypes(t *testing.T, root string, maxDepth int) int {
+	t.Helper()
+	checked := 0
+	var walk func(dir string, depth int)
+	walk = func(dir string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Errorf("ReadDir %s: %v", dir, err)
+			return
+		}
+		for _, e := range entries {
+			path := filepath.Join(dir, e.Name())
+			rel, _ := filepath.Rel(root, path)
+			fi, err := os.Lstat(path)
+			if err != nil {
+				t.Errorf("%s is listed but lstat fails: %v", rel, err)
+				continue
+			}
+			checked++
+			if got, want := fi.Mode().Type(), e.Type(); got != want {
+				t.Errorf("%s: listed as %v, stat says %v", rel, want, got)
+			}
+			if want, ok := conventionalMode(e.Name()); ok {
+				isDir := want == fuse.S_IFDIR
+				if fi.IsDir() != isDir || (!isDir && fi.Mode().Type() != 0) {
+					t.Errorf("%s: type %v breaks the naming convention for its suffix", rel, fi.Mode().Type())
+				}
+			}
+			if e.Type() == fs.ModeDir && depth < maxDepth {
+				walk(path, depth+1)
+			}
+		}
+	}
+	walk(root, 0)
+	return checked
+}
+
+func namesTestServer(t *testing.T) (*mockserver.Server, *state.Store, string) {
+	convID := "conv-names"
+	text := "hello"
+	msgs := []shelley.Message{
+		{MessageID: "m0", ConversationID: convID, SequenceID: 1, Type: "user", UserData: &text, CreatedAt: "2026-10-16T09:00:00Z"},
+		{MessageID: "m1", ConversationID: convID, SequenceID: 2, Type: "shelley", LLMData: strPtr(`{"Content": [{"Type": 5, "ID": "tu_1", "ToolName": "bash", "Input": {"command": "ls"}}]}`), UsageData: strPtr(`{"input_tokens": 3}`)},
+		{MessageID: "m2", ConversationID: convID, SequenceID: 3, Type: "user", UserData: strPtr(`{"Content": [{"Type": 6, "ToolUseID": "tu_1", "ToolUseStartTime": "2026-01-02T03:04:05Z", "ToolUseEndTime": "2026-01-02T03:04:05.5Z", "ToolResult": [{"Text": "{\"a\": 1}"}]}]}`)},
+	}
+	server := mockserver.New(mockserver.WithConversation(convID, msgs))
+	t.Cleanup(server.Close)
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug(convID, "names")
+	if err := store.SetMessagePinned(localID, "m1", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMeta(localID, "tags", "x\n"); err != nil {
+		t.Fatal(err)
+	}
+	return server, store, localID
+}
+
+func TestReaddirTypesMatchLookup(t *testing.T) {
+	server, store, _ := namesTestServer(t)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	if n := walkTypes(t, mountPoint, 6); n < 50 {
+		t.Errorf("checked only %d entries", n)
+	}
+}
+
+func TestReaddirTypesMatchLookupWithBackends(t *testing.T) {
+	server, store, _ := namesTestServer(t)
+	if err := store.EnsureBackendURL(state.DefaultBackendName, server.URL); err != nil {
+		t.Fatal(err)
+	}
+	mountPoint, cleanup := mountFS(t, NewFSWithBackends(shelley.NewClientManager(0), store, time.Hour))
+	defer cleanup()
+	if n := walkTypes(t, mountPoint, 7); n < 50 {
+		t.Errorf("checked only %d entries", n)
+	}
+}
+
+func TestNameTablePanicsOnUnregisteredName(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(r.(string), "naming registry") {
+			t.Errorf("recover() = %v, want a naming registry panic", r)
+		}
+	}()
+	messagesNames.entry("all.yaml")
+}
@@ -0,0 +1,51 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestMessagesAllOrg(t *testing.T) {
+	question, answer := "Which database?", "Postgres."
+	server := mockserver.New(mockserver.WithConversation("conv-org", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-org", SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: "m2", ConversationID: "conv-org", SequenceID: 2, Type: "agent", LLMData: &answer},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-org", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	msgDir := filepath.Join(mountPoint, "conversation", localID, "messages")
+	data, err := os.ReadFile(filepath.Join(msgDir, "all.org"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	org := string(data)
+	for _, want := range []string{"* user\n:PROPERTIES:\n:MESSAGE_ID: m1\n", "\nWhich database?\n", "* agent\n", ":SEQUENCE_ID: 2\n", "\nPostgres.\n"} {
+		if !strings.Contains(org, want) {
+			t.Errorf("all.org lacks %q:\n%s", want, org)
+		}
+	}
+	if strings.Count(org, ":END:\n") != 2 {
+		t.Errorf("all.org should have one properties drawer per message:\n%s", org)
+	}
+
+	found := false
+	for _, name := range listDir(t, msgDir) {
+		found = found || name == "all.org"
+	}
+	if !found {
+		t.Error("all.org not listed in messages/")
+	}
+	if _, err := os.Stat(filepath.Join(msgDir, "some.org")); !os.IsNotExist(err) {
+		t.Errorf("stat some.org: %v, want not exist", err)
+	}
+}
@@ -0,0 +1,277 @@
+package fuse
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime/debug"
+	"sync"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// --- recoveringRawFS: crash cleanly on panics in FUSE requests ---
+// go-fuse serves each request on its own goroutine, where a panic kills the
+// process and leaves the kernel mount behind: every later access fails with
+// ENOTCONN and the next mount on the same directory fails until someone runs
+// `fusermount -u`. recoveringRawFS sits in front of the node filesystem (see
+// Mount) and turns a panic in any request into a logged stack trace, a lazy
+// unmount and a non-zero exit, so a supervisor can simply start it again.
+
+// panicExitCode is the exit status after a recovered panic, the same as an
+// unrecovered one.
+const panicExitCode = 2
+
+type recoveringRawFS struct {
+	fuse.RawFileSystem
+	// onPanic is called once, with the operation and the panic value, by the
+	// first request to panic. Requests panicking after that block, since the
+	// process is on its way out.
+	onPanic func(op string, value any, stack []byte)
+	once    sync.Once
+}
+
+var _ = (fuse.RawFileSystem)((*recoveringRawFS)(nil))
+
+// catch must be deferred directly by each request method.
+func (r *recoveringRawFS) catch(op string) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	stack := debug.Stack()
+	first := false
+	r.once.Do(func() {
+		first = true
+		r.onPanic(op, v, stack)
+	})
+	if !first {
+		select {}
+	}
+}
+
+// crashUnmount returns the onPanic handler Mount uses for dir: it logs the
+// panic, detaches the mount and exits.
+func crashUnmount(dir string) func(op string, value any, stack []byte) {
+	return func(op string, value any, stack []byte) {
+		log.Printf("panic in FUSE %s: %v\n%s", op, value, stack)
+		if err := lazyUnmount(dir); err != nil {
+			log.Printf("Unmounting %s after panic failed: %v; run `fusermount -u -z %s`", dir, err, dir)
+		}
+		os.Exit(panicExitCode)
+	}
+}
+
+// lazyUnmount detaches the mount at dir even while it is busy. Unmounting
+// directly needs privileges; otherwise the fusermount helper does it.
+func lazyUnmount(dir string) error {
+	if err := syscall.Unmount(dir, syscall.MNT_DETACH); err == nil {
+		return nil
+	}
+	var lastErr error
+	for _, bin := range []string{"fusermount3", "fusermount"} {
+		path, err := exec.LookPath(bin)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		out, err := exec.Command(path, "-u", "-z", dir).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("%s: %v: %s", bin, err, out)
+	}
+	return lastErr
+}
+
+func (r *recoveringRawFS) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name string, out *fuse.EntryOut) fuse.Status {
+	defer r.catch("Lookup")
+	return r.RawFileSystem.Lookup(cancel, header, name, out)
+}
+
+func (r *recoveringRawFS) Forget(nodeid, nlookup uint64) {
+	defer r.catch("Forget")
+	r.RawFileSystem.Forget(nodeid, nlookup)
+}
+
+func (r *recoveringRawFS) GetAttr(cancel <-chan struct{}, input *fuse.GetAttrIn, out *fuse.AttrOut) fuse.Status {
+	defer r.catch("GetAttr")
+	return r.RawFileSystem.GetAttr(cancel, input, out)
+}
+
+func (r *recoveringRawFS) SetAttr(cancel <-chan struct{}, input *fuse.SetAttrIn, out *fuse.AttrOut) fuse.Status {
+	defer r.catch("SetAttr")
+	return r.RawFileSystem.SetAttr(cancel, input, out)
+}
+
+func (r *recoveringRawFS) Mknod(cancel <-chan struct{}, input *fuse.MknodIn, name string, out *fuse.EntryOut) fuse.Status {
+	defer r.catch("Mknod")
+	return r.RawFileSystem.Mknod(cancel, input, name, out)
+}
+
+func (r *recoveringRawFS) Mkdir(cancel <-chan struct{}, input *fuse.MkdirIn, name string, out *fuse.EntryOut) fuse.Status {
+	defer r.catch("Mkdir")
+	return r.RawFileSystem.Mkdir(cancel, input, name, out)
+}
+
+func (r *recoveringRawFS) Unlink(cancel <-chan struct{}, header *fuse.InHeader, name string) fuse.Status {
+	defer r.catch("Unlink")
+	return r.RawFileSystem.Unlink(cancel, header, name)
+}
+
+func (r *recoveringRawFS) Rmdir(cancel <-chan struct{}, header *fuse.InHeader, name string) fuse.Status {
+	defer r.catch("Rmdir")
+	return r.RawFileSystem.Rmdir(cancel, header, name)
+}
+
+func (r *recoveringRawFS) Rename(cancel <-chan struct{}, input *fuse.RenameIn, oldName string, newName string) fuse.Status {
+	defer r.catch("Rename")
+	return r.RawFileSystem.Rename(cancel, input, oldName, newName)
+}
+
+func (r *recoveringRawFS) Link(cancel <-chan struct{}, input *fuse.LinkIn, filename string, out *fuse.EntryOut) fuse.Status {
+	defer r.catch("Link")
+	return r.RawFileSystem.Link(cancel, input, filename, out)
+}
+
+func (r *recoveringRawFS) Symlink(cancel <-chan struct{}, header *fuse.InHeader, pointedTo string, linkName string, out *fuse.EntryOut) fuse.Status {
+	defer r.catch("Symlink")
+	return r.RawFileSystem.Symlink(cancel, header, pointedTo, linkName, out)
+}
+
+func (r *recoveringRawFS) Readlink(cancel <-chan struct{}, header *fuse.InHeader) ([]byte, fuse.Status) {
+	defer r.catch("Readlink")
+	return r.RawFileSystem.Readlink(cancel, header)
+}
+
+func (r *recoveringRawFS) Access(cancel <-chan struct{}, input *fuse.AccessIn) fuse.Status {
+	defer r.catch("Access")
+	return r.RawFileSystem.Access(cancel, input)
+}
+
+func (r *recoveringRawFS) GetXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr string, dest []byte) (uint32, fuse.Status) {
+	defer r.catch("GetXAttr")
+	return r.RawFileSystem.GetXAttr(cancel, header, attr, dest)
+}
+
+func (r *recoveringRawFS) ListXAttr(cancel <-chan struct{}, header *fuse.InHeader, dest []byte) (uint32, fuse.Status) {
+	defer r.catch("ListXAttr")
+	return r.RawFileSystem.ListXAttr(cancel, header, dest)
+}
+
+func (r *recoveringRawFS) SetXAttr(cancel <-chan struct{}, input *fuse.SetXAttrIn, attr string, data []byte) fuse.Status {
+	defer r.catch("SetXAttr")
+	return r.RawFileSystem.SetXAttr(cancel, input, attr, data)
+}
+
+func (r *recoveringRawFS) RemoveXAttr(cancel <-chan struct{}, header *fuse.InHeader, attr string) fuse.Status {
+	defer r.catch("RemoveXAttr")
+	return r.RawFileSystem.RemoveXAttr(cancel, header, attr)
+}
+
+func (r *recoveringRawFS) Create(cancel <-chan struct{}, input *fuse.CreateIn, name string, out *fuse.CreateOut) fuse.Status {
+	defer r.catch("Create")
+	return r.RawFileSystem.Create(cancel, input, name, out)
+}
+
+func (r *recoveringRawFS) Open(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) fuse.Status {
+	defer r.catch("Open")
+	return r.RawFileSystem.Open(cancel, input, out)
+}
+
+func (r *recoveringRawFS) Read(cancel <-chan struct{}, input *fuse.ReadIn, buf []byte) (fuse.ReadResult, fuse.Status) {
+	defer r.catch("Read")
+	return r.RawFileSystem.Read(cancel, input, buf)
+}
+
+func (r *recoveringRawFS) Lseek(cancel <-chan struct{}, in *fuse.LseekIn, out *fuse.LseekOut) fuse.Status {
+	defer r.catch("Lseek")
+	return r.RawFileSystem.Lseek(cancel, in, out)
+}
+
+func (r *recoveringRawFS) GetLk(cancel <-chan struct{}, input *fuse.LkIn, out *fuse.LkOut) fuse.Status {
+	defer r.catch("GetLk")
+	return r.RawFileSystem.GetLk(cancel, input, out)
+}
+
+func (r *recoveringRawFS) SetLk(cancel <-chan struct{}, input *fuse.LkIn) fuse.Status {
+	defer r.catch("SetLk")
+	return r.RawFileSystem.SetLk(cancel, input)
+}
+
+func (r *recoveringRawFS) SetLkw(cancel <-chan struct{}, input *fuse.LkIn) fuse.Status {
+	defer r.catch("SetLkw")
+	return r.RawFileSystem.SetLkw(cancel, input)
+}
+
+func (r *recoveringRawFS) Release(cancel <-chan struct{}, input *fuse.ReleaseIn) {
+	defer r.catch("Release")
+	r.RawFileSystem.Release(cancel, input)
+}
+
+func (r *recoveringRawFS) Write(cancel <-chan struct{}, input *fuse.WriteIn, data []byte) (uint32, fuse.Status) {
+	defer r.catch("Write")
+	return r.RawFileSystem.Write(cancel, input, data)
+}
+
+func (r *recoveringRawFS) CopyFileRange(cancel <-chan struct{}, input *fuse.CopyFileRangeIn) (uint32, fuse.Status) {
+	defer r.catch("CopyFileRange")
+	return r.RawFileSystem.CopyFileRange(cancel, input)
+}
+
+func (r *recoveringRawFS) Ioctl(cancel <-chan struct{}, input *fuse.IoctlIn, inbuf []byte, output *fuse.IoctlOut, outbuf []byte) fuse.Status {
+	defer r.catch("Ioctl")
+	return r.RawFileSystem.Ioctl(cancel, input, inbuf, output, outbuf)
+}
+
+func (r *recoveringRawFS) Flush(cancel <-chan struct{}, input *fuse.FlushIn) fuse.Status {
+	defer r.catch("Flush")
+	return r.RawFileSystem.Flush(cancel, input)
+}
+
+func (r *recoveringRawFS) Fsync(cancel <-chan struct{}, input *fuse.FsyncIn) fuse.Status {
+	defer r.catch("Fsync")
+	return r.RawFileSystem.Fsync(cancel, input)
+}
+
+func (r *recoveringRawFS) Fallocate(cancel <-chan struct{}, input *fuse.FallocateIn) fuse.Status {
+	defer r.catch("Fallocate")
+	return r.RawFileSystem.Fallocate(cancel, input)
+}
+
+func (r *recoveringRawFS) OpenDir(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.OpenOut) fuse.Status {
+	defer r.catch("OpenDir")
+	return r.RawFileSystem.OpenDir(cancel, input, out)
+}
+
+func (r *recoveringRawFS) ReadDir(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) fuse.Status {
+	defer r.catch("ReadDir")
+	return r.RawFileSystem.ReadDir(cancel, input, out)
+}
+
+func (r *recoveringRawFS) ReadDirPlus(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) fuse.Status {
+	defer r.catch("ReadDirPlus")
+	return r.RawFileSystem.ReadDirPlus(cancel, input, out)
+}
+
+func (r *recoveringRawFS) ReleaseDir(input *fuse.ReleaseIn) {
+	defer r.catch("ReleaseDir")
+	r.RawFileSystem.ReleaseDir(input)
+}
+
+func (r *recoveringRawFS) FsyncDir(cancel <-chan struct{}, input *fuse.FsyncIn) fuse.Status {
+	defer r.catch("FsyncDir")
+	return r.RawFileSystem.FsyncDir(cancel, input)
+}
+
+func (r *recoveringRawFS) StatFs(cancel <-chan struct{}, input *fuse.InHeader, out *fuse.StatfsOut) fuse.Status {
+	defer r.catch("StatFs")
+	return r.RawFileSystem.StatFs(cancel, input, out)
+}
+
+func (r *recoveringRawFS) Statx(cancel <-chan struct{}, input *fuse.StatxIn, out *fuse.StatxOut) fuse.Status {
+	defer r.catch("Statx")
+	return r.RawFileSystem.Statx(cancel, input, out)
+}
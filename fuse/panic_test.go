@@ -0,0 +1,72 @@
+package fuse
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+type panickingRawFS struct {
+	fuse.RawFileSystem
+}
+
+func (panickingRawFS) Lookup(cancel <-chan struct{}, header *fuse.InHeader, name string, out *fuse.EntryOut) fuse.Status {
+	panic("boom in " + name)
+}
+
+func TestRecoveringRawFS(t *testing.T) {
+	var gotOp string
+	var gotValue any
+	var gotStack []byte
+	r := &recoveringRawFS{
+		RawFileSystem: panickingRawFS{fuse.NewDefaultRawFileSystem()},
+		onPanic: func(op string, value any, stack []byte) {
+			gotOp, gotValue, gotStack = op, value, stack
+		},
+	}
+
+	if status := r.GetAttr(nil, &fuse.GetAttrIn{}, &fuse.AttrOut{}); status != fuse.ENOSYS {
+		t.Errorf("GetAttr = %v, want the wrapped filesystem's ENOSYS", status)
+	}
+	if gotOp != "" {
+		t.Fatalf("onPanic called without a panic")
+	}
+
+	r.Lookup(nil, &fuse.InHeader{}, "x", &fuse.EntryOut{})
+	if gotOp != "Lookup" || gotValue != "boom in x" {
+		t.Errorf("onPanic(%q, %v), want Lookup and the panic value", gotOp, gotValue)
+	}
+	if !strings.Contains(string(gotStack), "panickingRawFS.Lookup") {
+		t.Errorf("stack lacks the panicking method:\n%s", gotStack)
+	}
+}
+
+func TestLazyUnmount(t *testing.T) {
+	dir := t.TempDir()
+	server, err := fs.Mount(dir, &fs.Inode{}, &fs.Options{})
+	if err != nil {
+		t.Fatalf("mount: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		server.Wait()
+		close(done)
+	}()
+
+	if err := lazyUnmount(dir); err != nil {
+		t.Fatalf("lazyUnmount: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server still serving after lazy unmount")
+	}
+	mounts, _ := os.ReadFile("/proc/self/mounts")
+	if strings.Contains(string(mounts), " "+dir+" ") {
+		t.Errorf("%s still mounted", dir)
+	}
+}
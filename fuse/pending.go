@@ -0,0 +1,139 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/state"
+)
+
+// --- PendingDirNode: /conversation/.pending/ directory ---
+// Conversations that were cloned but never sent to exist only locally and
+// stay out of the conversation listing. .pending lists them, as symlinks
+// to their directories dated when they were cloned, so forgotten clones
+// can be found before -clone-timeout reaps them. `rm .pending/{id}`
+// discards one right away.
+//
+// With -max-clones, cloning past that many pending conversations fails with
+// EDQUOT (see state.Store.SetMaxPendingClones), so a script cloning in a
+// loop can't fill the state file. PendingClonesHandler reports the count.
+
+type PendingDirNode struct {
+	fs.Inode
+	list *ConversationListNode // for the state and clone timeouts
+}
+
+var _ = (fs.NodeLookuper)((*PendingDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*PendingDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*PendingDirNode)(nil))
+var _ = (fs.NodeUnlinker)((*PendingDirNode)(nil))
+
+// pending returns the conversation localID if it is still pending.
+func (n *PendingDirNode) pending(localID string) *state.ConversationState {
+	cs := n.list.state.Get(localID)
+	if cs == nil || cs.Created || n.list.cloneExpired(*cs) {
+		return nil
+	}
+	return cs
+}
+
+func (n *PendingDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(n.list.diag, "PendingDirNode", "Lookup", name).Done()
+	cs := n.pending(name)
+	if cs == nil {
+		return nil, syscall.ENOENT
+	}
+	return n.NewInode(ctx, &SymlinkNode{target: "../" + cs.LocalID, startTime: n.list.symlinkTime(cs.LocalID)}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+}
+
+func (n *PendingDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(n.list.diag, "PendingDirNode", "Readdir", "").Done()
+	var entries []fuse.DirEntry
+	for _, cs := range n.list.pendingClones() {
+		entries = append(entries, fuse.DirEntry{Name: cs.LocalID, Mode: syscall.S_IFLNK})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *PendingDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.list.startTime)
+	out.SetTimeout(cacheTimeouts(&n.Inode).Conversation)
+	return 0
+}
+
+// Unlink discards a pending conversation.
+func (n *PendingDirNode) Unlink(ctx context.Context, name string) syscall.Errno {
+	defer diag.Track(n.list.diag, "PendingDirNode", "Unlink", name).Done()
+	if n.pending(name) == nil {
+		return syscall.ENOENT
+	}
+	if err := n.list.state.Delete(name); err != nil {
+		return syscall.EIO
+	}
+	return 0
+}
+
+// cloneExpired reports whether the unconversed clone cs has outlived its
+// clone timeout.
+func (c *ConversationListNode) cloneExpired(cs state.ConversationState) bool {
+	timeout := c.cloneTimeoutFor(cs)
+	return timeout > 0 && !cs.CreatedAt.IsZero() && time.Since(cs.CreatedAt) > timeout
+}
+
+// pendingClones returns the unconversed clones, oldest first, deleting
+// the expired ones on the way (errors are non-fatal; the next listing
+// retries).
+func (c *ConversationListNode) pendingClones() []state.ConversationState {
+	var pending []state.ConversationState
+	for _, cs := range c.state.ListMappings() {
+		if cs.Created {
+			continue
+		}
+		if c.cloneExpired(cs) {
+			_ = c.state.Delete(cs.LocalID)
+			continue
+		}
+		pending = append(pending, cs)
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if !pending[i].CreatedAt.Equal(pending[j].CreatedAt) {
+			return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+		}
+		return pending[i].LocalID < pending[j].LocalID
+	})
+	return pending
+}
+
+// PendingClonesHandler returns an http.Handler that reports the number of
+// pending clones and their limit as text, or as JSON with the ?json query
+// parameter.
+func PendingClonesHandler(store *state.Store) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count, limit := store.PendingClones()
+		if _, wantJSON := r.URL.Query()["json"]; wantJSON {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(struct {
+				Pending int `json:"pending"`
+				Limit   int `json:"limit"`
+			}{count, limit}); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		limitText := "unlimited"
+		if limit > 0 {
+			limitText = fmt.Sprint(limit)
+		}
+		fmt.Fprintf(w, "clones: %d pending (limit %s)\n", count, limitText)
+	})
+}
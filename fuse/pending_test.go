@@ -0,0 +1,136 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestPendingConversations(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	store := testStore(t)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+	pendingDir := filepath.Join(convDir, ".pending")
+
+	if names := listDir(t, pendingDir); len(names) != 0 {
+		t.Errorf("expected no pending conversations, got %v", names)
+	}
+	if _, err := os.Lstat(pendingDir); err != nil {
+		t.Errorf(".pending should always be reachable: %v", err)
+	}
+
+	first, _ := store.Clone()
+	second, _ := store.Clone()
+	if names := listDir(t, pendingDir); len(names) != 2 {
+		t.Fatalf("expected 2 pending conversations, got %v", names)
+	}
+	target, err := os.Readlink(filepath.Join(pendingDir, first))
+	if err != nil || target != "../"+first {
+		t.Errorf("readlink = %q, %v", target, err)
+	}
+	if _, err := os.Stat(filepath.Join(pendingDir, first, "ctl")); err != nil {
+		t.Errorf("pending conversation not reachable through .pending: %v", err)
+	}
+	found := false
+	for _, name := range listDir(t, convDir) {
+		if name == ".pending" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error(".pending missing from the conversation listing")
+	}
+
+	if err := os.Remove(filepath.Join(pendingDir, first)); err != nil {
+		t.Fatalf("rm: %v", err)
+	}
+	if store.Get(first) != nil {
+		t.Error("removed conversation still in state")
+	}
+	if names := listDir(t, pendingDir); len(names) != 1 || names[0] != second {
+		t.Errorf("after rm: %v", names)
+	}
+
+	store.MarkCreated(second, "server-2", "")
+	if _, err := os.Lstat(filepath.Join(pendingDir, second)); !os.IsNotExist(err) {
+		t.Errorf("created conversation still pending: %v", err)
+	}
+	if err := os.Remove(filepath.Join(pendingDir, second)); !os.IsNotExist(err) {
+		t.Errorf("rm of created conversation through .pending: got %v, want ENOENT", err)
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir %s: %v", dir, err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestCancelClone(t *testing.T) {
+	server := mockserver.New()
+	defer server.Close()
+	store := testStore(t)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+
+	clone, _ := store.Clone()
+	if err := os.WriteFile(filepath.Join(convDir, clone, "ctl"), []byte("cancel\n"), 0644); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if store.Get(clone) != nil {
+		t.Error("cancelled clone still in state")
+	}
+
+	created, _ := store.Clone()
+	store.MarkCreated(created, "server-1", "")
+	if err := os.WriteFile(filepath.Join(convDir, created, "ctl"), []byte("cancel\n"), 0644); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("cancel of a created conversation: got %v, want EINVAL", err)
+	}
+	if store.Get(created) == nil {
+		t.Error("created conversation was discarded")
+	}
+}
+
+func TestMaxPendingClones(t *testing.T) {
+	server := mockserver.New(mockserver.WithModels([]shelley.Model{{ID: "fast", Ready: true}}))
+	defer server.Close()
+	store := testStore(t)
+	store.SetMaxPendingClones(1)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	clonePath := filepath.Join(mountPoint, "model", "fast", "new", "clone")
+
+	data, err := os.ReadFile(clonePath)
+	if err != nil {
+		t.Fatalf("first clone: %v", err)
+	}
+	if _, err := os.ReadFile(clonePath); !errors.Is(err, syscall.EDQUOT) {
+		t.Fatalf("clone past the limit: got %v, want EDQUOT", err)
+	}
+
+	// Discarding the pending clone makes room again.
+	if err := os.Remove(filepath.Join(mountPoint, "conversation", ".pending", strings.TrimSpace(string(data)))); err != nil {
+		t.Fatalf("rm: %v", err)
+	}
+	if _, err := os.ReadFile(clonePath); err != nil {
+		t.Errorf("clone after discarding: %v", err)
+	}
+}
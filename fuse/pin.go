@@ -0,0 +1,178 @@
+package fuse
+
+import (
+	"context"
+	"log"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- MessageCtlNode: /conversation/{id}/messages/{NNN}-{slug}/ctl ---
+// Writing "pin" pins the message and "unpin" unpins it; reading gives
+// "pinned" for a pinned message and nothing otherwise. Pins are kept in the
+// state file by message ID, so they survive remounts and slug changes, and
+// are never sent to the backend.
+
+type MessageCtlNode struct {
+	fs.Inode
+	dir *MessageDirNode
+}
+
+var _ = (fs.NodeOpener)((*MessageCtlNode)(nil))
+var _ = (fs.NodeReader)((*MessageCtlNode)(nil))
+var _ = (fs.NodeWriter)((*MessageCtlNode)(nil))
+var _ = (fs.NodeGetattrer)((*MessageCtlNode)(nil))
+var _ = (fs.NodeSetattrer)((*MessageCtlNode)(nil))
+
+// pinnable reports whether the message can be pinned: it needs the state
+// store to keep the pin in, and an ID to keep it by.
+func (m *MessageDirNode) pinnable() bool {
+	return m.state != nil && m.localID != "" && m.message.MessageID != ""
+}
+
+func (n *MessageCtlNode) data() []byte {
+	d := n.dir
+	for _, id := range d.state.PinnedMessages(d.localID) {
+		if id == d.message.MessageID {
+			return []byte("pinned\n")
+		}
+	}
+	return nil
+}
+
+func (n *MessageCtlNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	return nil, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *MessageCtlNode) Read(ctx context.Context, f fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	return fuse.ReadResultData(readAt(n.data(), dest, off)), 0
+}
+
+func (n *MessageCtlNode) Write(ctx context.Context, f fs.FileHandle, data []byte, off int64) (uint32, syscall.Errno) {
+	d := n.dir
+	defer diag.Track(d.diag, "MessageCtlNode", "Write", d.localID+"/"+d.message.MessageID).Done()
+	var pinned bool
+	switch strings.TrimSpace(string(data)) {
+	case "":
+		return uint32(len(data)), 0
+	case "pin":
+		pinned = true
+	case "unpin":
+		pinned = false
+	default:
+		return 0, syscall.EINVAL
+	}
+	if err := d.state.SetMessagePinned(d.localID, d.message.MessageID, pinned); err != nil {
+		log.Printf("Failed to pin message %s: %v", d.message.MessageID, err)
+		return 0, syscall.EIO
+	}
+	return uint32(len(data)), 0
+}
+
+func (n *MessageCtlNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0644
+	out.Size = uint64(len(n.data()))
+	setTimestamps(&out.Attr, n.dir.messageTime())
+	return 0
+}
+
+func (n *MessageCtlNode) Setattr(ctx context.Context, f fs.FileHandle, in *fuse.SetAttrIn, out *fuse.AttrOut) syscall.Errno {
+	// Accept truncate (from shell > redirect) silently
+	return n.Getattr(ctx, f, out)
+}
+
+// --- PinnedDirNode: /conversation/{id}/messages/pinned/ ---
+// Symlinks to the pinned messages' directories, named like them, in the
+// order the messages were pinned. Pins of messages the conversation no
+// longer has are left out.
+
+type PinnedDirNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	startTime   time.Time
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*PinnedDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*PinnedDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*PinnedDirNode)(nil))
+
+// names returns the directory names of the pinned messages.
+func (p *PinnedDirNode) names() ([]string, error) {
+	pins := p.state.PinnedMessages(p.localID)
+	if len(pins) == 0 {
+		return nil, nil
+	}
+	cs := p.state.Get(p.localID)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return nil, nil
+	}
+	convData, err := p.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*shelley.Message, len(result.Messages))
+	for i := range result.Messages {
+		byID[result.Messages[i].MessageID] = &result.Messages[i]
+	}
+	var names []string
+	for _, id := range pins {
+		if msg, ok := byID[id]; ok {
+			slug := shelley.MessageSlug(msg, result.ToolMap)
+			names = append(names, messageFileBase(msg.SequenceID, slug, result.MaxSeqID))
+		}
+	}
+	return names, nil
+}
+
+func (p *PinnedDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(p.diag, "PinnedDirNode", "Lookup", p.localID+"/"+name).Done()
+	names, err := p.names()
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	for _, n := range names {
+		if n == name {
+			return p.NewInode(ctx, &SymlinkNode{target: "../" + name, startTime: p.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+func (p *PinnedDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(p.diag, "PinnedDirNode", "Readdir", p.localID).Done()
+	names, err := p.names()
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	entries := make([]fuse.DirEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: syscall.S_IFLNK})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (p *PinnedDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	cs := p.state.Get(p.localID)
+	if cs != nil && !cs.CreatedAt.IsZero() {
+		setTimestamps(&out.Attr, cs.CreatedAt)
+	} else {
+		setTimestamps(&out.Attr, p.startTime)
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestPinMessages(t *testing.T) {
+	question, answer, followUp := "Which database?", "Postgres, for the JSON support.", "ok"
+	server := mockserver.New(mockserver.WithConversation("conv-pin", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-pin", SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: "m2", ConversationID: "conv-pin", SequenceID: 2, Type: "agent", LLMData: &answer},
+		{MessageID: "m3", ConversationID: "conv-pin", SequenceID: 3, Type: "user", UserData: &followUp},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-pin", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	msgDir := filepath.Join(mountPoint, "conversation", localID, "messages")
+	pinnedDir := filepath.Join(msgDir, "pinned")
+	if names := listDir(t, pinnedDir); len(names) != 0 {
+		t.Errorf("pinned/ before pinning = %v, want empty", names)
+	}
+
+	ctl := filepath.Join(msgDir, "1-agent", "ctl")
+	if data, err := os.ReadFile(ctl); err != nil || len(data) != 0 {
+		t.Errorf("ctl of an unpinned message = %q, %v; want empty", data, err)
+	}
+	for _, dir := range []string{"1-agent", "0-user"} {
+		if err := os.WriteFile(filepath.Join(msgDir, dir, "ctl"), []byte("pin\n"), 0644); err != nil {
+			t.Fatalf("pin %s: %v", dir, err)
+		}
+	}
+	if data, err := os.ReadFile(ctl); err != nil || string(data) != "pinned\n" {
+		t.Errorf("ctl of a pinned message = %q, %v; want %q", data, err, "pinned\n")
+	}
+
+	// Pins are listed in the order they were made.
+	d, err := os.Open(pinnedDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"1-agent", "0-user"}) {
+		t.Errorf("pinned/ = %v, want [1-agent 0-user]", names)
+	}
+	if target, err := os.Readlink(filepath.Join(pinnedDir, "1-agent")); err != nil || target != "../1-agent" {
+		t.Errorf("pinned/1-agent -> %q, %v; want ../1-agent", target, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(pinnedDir, "1-agent", "content.txt")); err != nil || string(data) != answer+"\n" {
+		t.Errorf("content.txt through pinned/ = %q, %v", data, err)
+	}
+	if got := store.PinnedMessages(localID); !slices.Equal(got, []string{"m2", "m1"}) {
+		t.Errorf("stored pins = %v, want [m2 m1]", got)
+	}
+
+	if err := os.WriteFile(ctl, []byte("unpin"), 0644); err != nil {
+		t.Fatalf("unpin: %v", err)
+	}
+	if names := listDir(t, pinnedDir); !slices.Equal(names, []string{"0-user"}) {
+		t.Errorf("pinned/ after unpinning = %v, want [0-user]", names)
+	}
+	if _, err := os.Lstat(filepath.Join(pinnedDir, "1-agent")); !os.IsNotExist(err) {
+		t.Errorf("lstat of an unpinned message's link: %v, want not exist", err)
+	}
+
+	err = os.WriteFile(ctl, []byte("star\n"), 0644)
+	if !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("writing an unknown command: %v, want EINVAL", err)
+	}
+}
+
+func TestPinnedSkipsMissingMessages(t *testing.T) {
+	text := "hello"
+	server := mockserver.New(mockserver.WithConversation("conv-pin-gone", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-pin-gone", SequenceID: 1, Type: "user", UserData: &text},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-pin-gone", "")
+	if err := store.SetMessagePinned(localID, "gone", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMessagePinned(localID, "m1", true); err != nil {
+		t.Fatal(err)
+	}
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	pinnedDir := filepath.Join(mountPoint, "conversation", localID, "messages", "pinned")
+	if names := listDir(t, pinnedDir); !slices.Equal(names, []string{"0-user"}) {
+		t.Errorf("pinned/ = %v, want [0-user]", names)
+	}
+}
@@ -0,0 +1,33 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestMessageContentTxt(t *testing.T) {
+	text := "Please look at `main.go` and **fix** the [build](https://ci.example/42)."
+	server := mockserver.New(mockserver.WithConversation("conv-txt", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-txt", SequenceID: 1, Type: "user", UserData: &text},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-txt", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	path := filepath.Join(mountPoint, "conversation", localID, "messages", "0-user", "content.txt")
+	want := "Please look at main.go and fix the build.\n"
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != want {
+		t.Errorf("content.txt = %q, %v; want %q", data, err, want)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != int64(len(want)) {
+		t.Errorf("stat content.txt: %v, %v; want size %d", fi, err, len(want))
+	}
+}
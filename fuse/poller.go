@@ -0,0 +1,242 @@
+package fuse
+
+import (
+	"hash/fnv"
+	"log"
+	"strings"
+	"time"
+
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- Poller: background refresh of cached conversations ---
+// Without it a read of messages/ that finds the cache expired waits for the
+// backend. The poller refetches conversations ahead of the readers instead:
+// conversations with recent activity every active interval, keeping them
+// cached until the next poll, and the rest every idle interval, which is how
+// it notices an idle conversation waking up. Activity is the backend's
+// updated_at as last listed, or a poll that found the conversation changed.
+// What each poll finds is recorded on the FS's ActivityBoard. Polling needs
+// a caching client; backends without one are skipped.
+
+// pollActiveWindow is how long after its last activity a conversation is
+// still polled at the active interval.
+const pollActiveWindow = 10 * time.Minute
+
+// Poller refreshes the conversations of an FS in the background.
+type Poller struct {
+	fs     *FS
+	active time.Duration
+	idle   time.Duration
+	seen   map[string]*pollRecord // by backend + "/" + server conversation ID
+}
+
+// pollRecord is what the poller knows about one conversation.
+type pollRecord struct {
+	polled  time.Time // last poll, successful or not
+	changed time.Time // last poll that found the conversation changed
+	sum     uint64    // hash of the conversation at the last successful poll
+	hashed  bool      // sum is set
+}
+
+// pollTarget is a backend whose conversations can be polled.
+type pollTarget struct {
+	backend string
+	client  *shelley.CachingClient
+}
+
+// NewPoller creates a poller for f that refreshes active conversations
+// every active and idle ones every idle. An idle interval of 0 leaves idle
+// conversations alone.
+func NewPoller(f *FS, active, idle time.Duration) *Poller {
+	return &Poller{fs: f, active: active, idle: idle, seen: make(map[string]*pollRecord)}
+}
+
+// Run polls every active interval until stop is closed.
+func (p *Poller) Run(stop <-chan struct{}) {
+	p.poll(time.Now())
+	ticker := time.NewTicker(p.active)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			p.poll(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// targets returns the backends with a caching client.
+func (p *Poller) targets() []pollTarget {
+	var targets []pollTarget
+	for _, b := range p.fs.backendClients() {
+		if cc, ok := b.client.(*shelley.CachingClient); ok {
+			targets = append(targets, pollTarget{backend: b.name, client: cc})
+		}
+	}
+	return targets
+}
+
+// backendClient is a backend and the client that talks to it.
+type backendClient struct {
+	name   string
+	client shelley.ShelleyClient
+}
+
+// backendClients returns the backends of f that have a server to talk to.
+func (f *FS) backendClients() []backendClient {
+	if f.clientMgr == nil {
+		return []backendClient{{name: f.state.GetDefaultBackend(), client: f.client}}
+	}
+	var clients []backendClient
+	for _, name := range f.state.ListBackends() {
+		backend := f.state.GetBackend(name)
+		if backend == nil || backend.URL == "" {
+			continue
+		}
+		client, err := f.clientMgr.EnsureURL(name, backend.URL)
+		if err != nil {
+			continue
+		}
+		clients = append(clients, backendClient{name: name, client: client})
+	}
+	return clients
+}
+
+// poll refreshes the conversations due at now and returns how many it
+// refreshed. Conversations no longer in the store are forgotten.
+func (p *Poller) poll(now time.Time) int {
+	polled := 0
+	current := make(map[string]bool)
+	for _, t := range p.targets() {
+		var working map[string]bool // fetched on the first refresh of the backend
+		for _, cs := range p.fs.state.ListMappingsForBackend(t.backend) {
+			if !cs.Created || cs.ShelleyConversationID == "" {
+				continue
+			}
+			key := t.backend + "/" + cs.ShelleyConversationID
+			current[key] = true
+			rec := p.seen[key]
+			if rec == nil {
+				rec = p.track(key, now)
+			}
+
+			interval, ttl := p.idle, time.Duration(0)
+			if now.Sub(lastActivity(cs, rec)) < pollActiveWindow {
+				// Twice the interval, so a late tick doesn't let it expire.
+				interval, ttl = p.active, 2*p.active
+			}
+			if interval <= 0 || now.Sub(rec.polled) < interval {
+				continue
+			}
+			if working == nil {
+				working = workingConversations(t.client)
+			}
+			p.refresh(t.client, cs, rec, ttl, working, now)
+			polled++
+		}
+	}
+	for key := range p.seen {
+		if !current[key] {
+			delete(p.seen, key)
+			if _, conversationID, ok := strings.Cut(key, "/"); ok {
+				p.fs.activity.Forget(conversationID)
+			}
+		}
+	}
+	return polled
+}
+
+// track starts tracking a conversation. Its first idle poll is spread over
+// the idle interval, so mounting with many conversations doesn't fetch them
+// all at once; active ones are polled right away.
+func (p *Poller) track(key string, now time.Time) *pollRecord {
+	rec := &pollRecord{polled: now}
+	if p.idle > 0 {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		rec.polled = now.Add(-time.Duration(h.Sum64() % uint64(p.idle)))
+	}
+	p.seen[key] = rec
+	return rec
+}
+
+// refresh refetches one conversation, records whether it changed, and
+// posts what it found to the activity board. Refreshed conversations are
+// parsed too, so reads don't pay for that either.
+func (p *Poller) refresh(client *shelley.CachingClient, cs state.ConversationState, rec *pollRecord, ttl time.Duration, working map[string]bool, now time.Time) {
+	conversationID := cs.ShelleyConversationID
+	rec.polled = now
+	data, err := client.RefreshConversation(conversationID, ttl)
+	if err != nil {
+		log.Printf("Poll of conversation %s failed: %v", conversationID, err)
+		return
+	}
+	h := fnv.New64a()
+	h.Write(data)
+	if sum := h.Sum64(); !rec.hashed || sum != rec.sum {
+		if rec.hashed {
+			rec.changed = now
+		}
+		rec.sum, rec.hashed = sum, true
+	}
+	msgs, toolMap, err := p.fs.parsedCache.GetOrParse(conversationID, data)
+	if err != nil {
+		log.Printf("Poll of conversation %s: %v", conversationID, err)
+		return
+	}
+
+	a := ConversationActivity{
+		LocalID:        cs.LocalID,
+		ConversationID: conversationID,
+		Slug:           cs.Slug,
+		Messages:       len(msgs),
+		Working:        working[conversationID],
+		PolledAt:       now.UTC().Format(time.RFC3339),
+	}
+	last := lastActivity(cs, rec)
+	if len(msgs) > 0 {
+		m := &msgs[len(msgs)-1]
+		a.LastMessage = shelley.MessageSlug(m, toolMap)
+		a.AwaitingReply = a.LastMessage == "agent" && !a.Working
+		if t := shelley.ParseMessageTime(m); t.After(last) {
+			last = t
+		}
+	}
+	if !last.IsZero() {
+		a.LastActivity = last.UTC().Format(time.RFC3339)
+	}
+	p.fs.activity.Record(a)
+}
+
+// workingConversations returns the server IDs of the backend's
+// conversations the agent is working on. On error none are.
+func workingConversations(client *shelley.CachingClient) map[string]bool {
+	working := make(map[string]bool)
+	data, err := client.ListConversations()
+	if err != nil {
+		return working
+	}
+	var convs []shelley.Conversation
+	if err := shelley.UnmarshalLenient(data, &convs); err != nil {
+		return working
+	}
+	for _, c := range convs {
+		if c.Working {
+			working[c.ConversationID] = true
+		}
+	}
+	return working
+}
+
+// lastActivity is the latest sign of life of a conversation: the backend's
+// updated_at or a poll that found it changed.
+func lastActivity(cs state.ConversationState, rec *pollRecord) time.Time {
+	last := rec.changed
+	if t, err := time.Parse(time.RFC3339Nano, cs.APIUpdatedAt); err == nil && t.After(last) {
+		last = t
+	}
+	return last
+}
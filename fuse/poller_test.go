@@ -0,0 +1,73 @@
+package fuse
+
+import (
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestPoller(t *testing.T) {
+	hello := "Hello"
+	server := mockserver.New(
+		mockserver.WithConversation("conv-active", nil),
+		mockserver.WithConversation("conv-idle", []shelley.Message{
+			{MessageID: "m1", ConversationID: "conv-idle", SequenceID: 1, Type: "user", UserData: &hello},
+		}),
+		mockserver.WithMessageEditing(),
+	)
+	defer server.Close()
+	client := shelley.NewCachingClient(shelley.NewClient(server.URL), time.Hour)
+	store := testStore(t)
+	t0 := time.Now()
+	store.AdoptWithMetadata("conv-active", "", "", t0.Format(time.RFC3339), "", "")
+	store.AdoptWithMetadata("conv-idle", "", "", t0.Add(-24*time.Hour).Format(time.RFC3339), "", "")
+
+	p := NewPoller(NewFS(client, store, time.Hour), time.Second, time.Hour)
+	steps := []struct {
+		at   time.Duration
+		want int
+	}{
+		{0, 1},                       // the active conversation, right away
+		{500 * time.Millisecond, 0},  // not due yet
+		{time.Second, 1},             // the active one again
+		{time.Hour + time.Second, 2}, // both idle by now, both due
+	}
+	for _, s := range steps {
+		if got := p.poll(t0.Add(s.at)); got != s.want {
+			t.Errorf("poll at +%v refreshed %d conversations, want %d", s.at, got, s.want)
+		}
+	}
+
+	// Polled conversations are served from the cache.
+	server.ResetFetchCount()
+	if _, err := client.GetConversation("conv-idle"); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.FetchCount(); n != 0 {
+		t.Errorf("read after poll fetched %d times, want 0", n)
+	}
+
+	// An idle conversation that changes is polled as an active one from then on.
+	if err := client.EditMessage("conv-idle", "m1", "Hello again"); err != nil {
+		t.Fatal(err)
+	}
+	t1 := t0.Add(2*time.Hour + time.Second)
+	if got := p.poll(t1); got != 2 {
+		t.Fatalf("poll at +2h refreshed %d conversations, want 2", got)
+	}
+	if got := p.poll(t1.Add(time.Second)); got != 1 {
+		t.Errorf("poll after the change refreshed %d conversations, want 1 (the changed one)", got)
+	}
+
+	// Removed conversations are forgotten.
+	localID := store.GetByShelleyID("conv-idle")
+	if err := store.ForceDelete(localID); err != nil {
+		t.Fatal(err)
+	}
+	p.poll(t1.Add(2 * time.Second))
+	if _, ok := p.seen[store.GetDefaultBackend()+"/conv-idle"]; ok {
+		t.Error("removed conversation is still tracked")
+	}
+}
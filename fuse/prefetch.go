@@ -0,0 +1,80 @@
+package fuse
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+// prefetchWorkers is how many conversations Prefetch fetches at once.
+const prefetchWorkers = 4
+
+// prefetchTTL is how long prefetched conversations stay cached, or the
+// client's cache TTL if that is longer. It is meant to cover the time
+// between mounting and the first look at them.
+const prefetchTTL = time.Minute
+
+// Prefetch fetches and parses the n most recently updated conversations on
+// the default backend that the conversation filter lets through, so the
+// first reads of them after mounting are served from the cache instead of
+// waiting for the backend. It returns how many were fetched. Prefetching
+// needs a caching client; with any other client it does nothing.
+func (f *FS) Prefetch(n int) int {
+	client, ok := f.client.(*shelley.CachingClient)
+	if !ok || n <= 0 {
+		return 0
+	}
+	data, err := client.ListConversations()
+	if err != nil {
+		log.Printf("Prefetch: listing conversations failed: %v", err)
+		return 0
+	}
+	var convs []shelley.Conversation
+	if err := shelley.UnmarshalLenient(data, &convs); err != nil {
+		log.Printf("Prefetch: %v", err)
+		return 0
+	}
+	convs = f.filter.apply(convs)
+	sort.SliceStable(convs, func(i, j int) bool {
+		ti, _ := time.Parse(time.RFC3339Nano, convs[i].UpdatedAt)
+		tj, _ := time.Parse(time.RFC3339Nano, convs[j].UpdatedAt)
+		return ti.After(tj)
+	})
+	if len(convs) > n {
+		convs = convs[:n]
+	}
+
+	ids := make(chan string)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	fetched := 0
+	for i := 0; i < prefetchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				data, err := client.RefreshConversation(id, prefetchTTL)
+				if err != nil {
+					log.Printf("Prefetch of conversation %s failed: %v", id, err)
+					continue
+				}
+				if _, _, err := f.parsedCache.GetOrParse(id, data); err != nil {
+					log.Printf("Prefetch of conversation %s: %v", id, err)
+					continue
+				}
+				mu.Lock()
+				fetched++
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, conv := range convs {
+		ids <- conv.ConversationID
+	}
+	close(ids)
+	wg.Wait()
+	return fetched
+}
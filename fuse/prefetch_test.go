@@ -0,0 +1,48 @@
+package fuse
+
+import (
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestPrefetch(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-old", UpdatedAt: "2026-01-01T00:00:00Z"}, nil),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-new", UpdatedAt: "2026-03-01T00:00:00Z"}, nil),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-mid", UpdatedAt: "2026-02-01T00:00:00Z"}, nil),
+	)
+	defer server.Close()
+	client := shelley.NewCachingClient(shelley.NewClient(server.URL), time.Millisecond)
+	shelleyFS := NewFS(client, testStore(t), time.Hour)
+
+	if got := shelleyFS.Prefetch(2); got != 2 {
+		t.Fatalf("Prefetch(2) fetched %d conversations, want 2", got)
+	}
+
+	// The two most recent are cached past the client's own TTL; the other
+	// one wasn't fetched.
+	time.Sleep(5 * time.Millisecond)
+	server.ResetFetchCount()
+	for _, id := range []string{"conv-new", "conv-mid"} {
+		if _, err := client.GetConversation(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := server.FetchCount(); n != 0 {
+		t.Errorf("reads of prefetched conversations fetched %d times, want 0", n)
+	}
+	if _, err := client.GetConversation("conv-old"); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.FetchCount(); n != 1 {
+		t.Errorf("read of conv-old fetched %d times, want 1", n)
+	}
+
+	// Without a caching client there is nothing to fill.
+	if got := NewFS(shelley.NewClient(server.URL), testStore(t), time.Hour).Prefetch(2); got != 0 {
+		t.Errorf("Prefetch without a cache fetched %d conversations, want 0", got)
+	}
+}
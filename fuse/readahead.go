@@ -0,0 +1,83 @@
+package fuse
+
+import (
+	"sync"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+// readAheadStreak is how many back-to-back reads make a reader sequential.
+const readAheadStreak = 2
+
+// readAheadSlack is how far from where the last read ended a read may start
+// and still count as back-to-back. The kernel splits a large read into
+// requests that can arrive out of order.
+const readAheadSlack = 1 << 20
+
+// readAheadTTL is how long a conversation refreshed by read-ahead stays
+// cached, so the next part is still cached when the reader opens it.
+const readAheadTTL = 30 * time.Second
+
+// readAhead watches the reads of one open file and, once they look like a
+// sequential pass (grep, less, cat) that is past the middle of the file,
+// runs fetch in the background, once. fetch loads whatever the reader is
+// likely to open next.
+type readAhead struct {
+	fetch func()
+
+	mu     sync.Mutex
+	next   int64 // furthest offset read up to
+	streak int
+	fired  bool
+}
+
+// observe records a read of n bytes at off from a file of size bytes.
+// It is safe to call on a nil readAhead.
+func (r *readAhead) observe(off int64, n, size int) {
+	if r == nil || n == 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if gap := off - r.next; gap >= -readAheadSlack && gap <= readAheadSlack {
+		r.streak++
+	} else {
+		r.streak = 0
+	}
+	r.next = max(r.next, off+int64(n))
+	if r.fired || r.streak < readAheadStreak || 2*r.next < int64(size) {
+		return
+	}
+	r.fired = true
+	go r.fetch()
+}
+
+// readAheadChunks returns a readAhead for part index of all.md.d, or nil if
+// it is the last part. It refreshes the conversation and renders its parts
+// again, so opening the next one finds both in the caches even if the
+// client's cache has expired meanwhile or the conversation has grown.
+func (d *MarkdownChunksDirNode) readAheadChunks(index, parts int) *readAhead {
+	if index+1 >= parts {
+		return nil
+	}
+	return &readAhead{fetch: func() {
+		cs := d.state.Get(d.localID)
+		if cs == nil || cs.ShelleyConversationID == "" {
+			return
+		}
+		var data []byte
+		var err error
+		if cc, ok := d.client.(*shelley.CachingClient); ok {
+			data, err = cc.RefreshConversation(cs.ShelleyConversationID, readAheadTTL)
+		} else {
+			data, err = d.client.GetConversation(cs.ShelleyConversationID)
+		}
+		if err != nil {
+			return
+		}
+		if msgs, _, err := d.parsedCache.GetOrParse(cs.ShelleyConversationID, data); err == nil {
+			d.parsedCache.MarkdownChunks(cs.ShelleyConversationID, msgs, d.chunkSize)
+		}
+	}}
+}
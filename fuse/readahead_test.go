@@ -0,0 +1,91 @@
+package fuse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestReadAheadObserve(t *testing.T) {
+	fired := make(chan struct{}, 2)
+	r := &readAhead{fetch: func() { fired <- struct{}{} }}
+	const mb = 1 << 20
+	r.observe(0, mb, 100*mb)
+	r.observe(60*mb, mb, 100*mb) // a seek: not sequential
+	r.observe(62*mb, mb, 100*mb)
+	select {
+	case <-fired:
+		t.Fatal("read-ahead fired before a sequential streak")
+	case <-time.After(20 * time.Millisecond):
+	}
+	r.observe(64*mb, mb, 100*mb) // out of order, but close enough
+	r.observe(63*mb, mb, 100*mb)
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("read-ahead did not fire for sequential reads past the middle")
+	}
+	r.observe(65*mb, 0, 100*mb)
+	r.observe(65*mb, mb, 100*mb)
+	select {
+	case <-fired:
+		t.Error("read-ahead fired twice")
+	case <-time.After(20 * time.Millisecond):
+	}
+	(*readAhead)(nil).observe(0, 10, 100)
+}
+
+func TestMarkdownChunksReadAhead(t *testing.T) {
+	convID := "conv-readahead"
+	var msgs []shelley.Message
+	for i := 1; i <= 8; i++ {
+		msgs = append(msgs, shelley.Message{
+			MessageID: fmt.Sprintf("m%d", i), ConversationID: convID, SequenceID: i,
+			Type: "user", UserData: strPtr(strings.Repeat("x", 200<<10)),
+		})
+	}
+	server := mockserver.New(mockserver.WithConversation(convID, msgs))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug(convID, "")
+	// A client cache that has always expired by the time the next part opens.
+	client := shelley.NewCachingClient(shelley.NewClient(server.URL), time.Millisecond)
+	shelleyFS := NewFS(client, store, time.Hour)
+	shelleyFS.SetMarkdownChunkSize(1 << 20)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	parts := filepath.Join(mountPoint, "conversation", localID, "messages", "all.md.d")
+
+	if _, err := os.ReadFile(filepath.Join(parts, "part-001.md")); err != nil {
+		t.Fatal(err)
+	}
+	// Wait for the background refresh to land.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		server.ResetFetchCount()
+		if _, err := client.GetConversation(convID); err != nil {
+			t.Fatal(err)
+		}
+		if server.FetchCount() == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reading part-001.md through did not refresh the conversation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.ResetFetchCount()
+	if _, err := os.ReadFile(filepath.Join(parts, "part-002.md")); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.FetchCount(); n != 0 {
+		t.Errorf("opening part-002.md fetched the conversation %d times, want 0", n)
+	}
+}
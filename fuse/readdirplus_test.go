@@ -0,0 +1,181 @@
+package fuse
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+// TestMessagesDir_ReaddirPlusFetchesOnce lists a messages directory through
+// the mount and stats every message directory, as `ls -l` does. The listing
+// carries the attributes, so the conversation is fetched once in total
+// rather than once more per message.
+func TestMessagesDir_ReaddirPlusFetchesOnce(t *testing.T) {
+	var msgs []shelley.Message
+	for i := 1; i <= 5; i++ {
+		text := strings.Repeat("x", i)
+		typ := "user"
+		if i%2 == 0 {
+			typ = "agent"
+		}
+		msgs = append(msgs, shelley.Message{MessageID: "m" + text, ConversationID: "conv-plus", SequenceID: i, Type: typ, UserData: &text})
+	}
+	server := mockserver.New(mockserver.WithConversation("conv-plus", msgs))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-plus", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	dir := filepath.Join(mountPoint, "conversation", localID, "messages")
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatalf("stat messages: %v", err)
+	}
+	server.ResetFetchCount()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var msgDirs int
+	for _, e := range entries {
+		if _, ok := parseMessageDirName(e.Name()); !ok {
+			continue
+		}
+		msgDirs++
+		fi, err := os.Lstat(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatalf("Lstat %s: %v", e.Name(), err)
+		}
+		if !fi.IsDir() {
+			t.Errorf("%s is not a directory", e.Name())
+		}
+	}
+	if msgDirs != len(msgs) {
+		t.Fatalf("listed %d message directories, want %d", msgDirs, len(msgs))
+	}
+	if n := server.FetchCount(); n != 1 {
+		t.Errorf("conversation fetched %d times for the listing, want 1", n)
+	}
+}
+
+func TestConversationListNode_LookupFillsAttr(t *testing.T) {
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("server-attr", "my-slug")
+	node := &ConversationListNode{state: store, startTime: time.Now()}
+	fs.NewNodeFS(node, &fs.Options{}) // attach node as a root so Lookup can create children
+	want := store.Get(localID).CreatedAt
+
+	for _, tc := range []struct {
+		name string
+		mode uint32
+	}{
+		{localID, fuse.S_IFDIR},
+		{"my-slug", syscall.S_IFLNK},
+		{"server-attr", syscall.S_IFLNK},
+	} {
+		var out fuse.EntryOut
+		if _, errno := node.Lookup(context.Background(), tc.name, &out); errno != 0 {
+			t.Fatalf("Lookup(%s): %v", tc.name, errno)
+		}
+		if out.Mode&syscall.S_IFMT != tc.mode {
+			t.Errorf("Lookup(%s) mode = %o, want type %o", tc.name, out.Mode, tc.mode)
+		}
+		if out.Mtime != uint64(want.Unix()) {
+			t.Errorf("Lookup(%s) mtime = %d, want %d", tc.name, out.Mtime, want.Unix())
+		}
+		if out.AttrTimeout() != DefaultCacheTimeouts().Conversation {
+			t.Errorf("Lookup(%s) attr timeout = %v, want %v", tc.name, out.AttrTimeout(), DefaultCacheTimeouts().Conversation)
+		}
+	}
+}
+
+func TestMessagesDir_SparseListing(t *testing.T) {
+	text := "hello"
+	msgs := []shelley.Message{{MessageID: "m1", ConversationID: "conv-sparse", SequenceID: 1, Type: "user", UserData: &text}}
+	server := mockserver.New(mockserver.WithConversation("conv-sparse", msgs))
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-sparse", "")
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetSparseMessages(true)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+
+	dir := filepath.Join(mountPoint, "conversation", localID, "messages")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	names := make(map[string]bool)
+	for _, e := range entries {
+		if _, ok := parseMessageDirName(e.Name()); ok {
+			t.Errorf("message directory %s listed in sparse mode", e.Name())
+		}
+		names[e.Name()] = true
+	}
+	for _, want := range []string{"all.json", "all.md", "last", "since"} {
+		if !names[want] {
+			t.Errorf("%s missing from sparse listing", want)
+		}
+	}
+
+	// Hidden from listings, but still there by name.
+	data, err := os.ReadFile(filepath.Join(dir, "0-user", "content.md"))
+	if err != nil || !strings.Contains(string(data), "hello") {
+		t.Errorf("0-user/content.md = %q, %v", data, err)
+	}
+}
+
+func TestCacheTimeouts(t *testing.T) {
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("server-ttl", "")
+	f := NewFS(nil, store, time.Hour)
+	timeouts := DefaultCacheTimeouts()
+	if err := timeouts.Set("conversation", 0); err != nil {
+		t.Fatalf("Set conversation: %v", err)
+	}
+	if err := timeouts.Set("static", 24*time.Hour); err != nil {
+		t.Fatalf("Set static: %v", err)
+	}
+	if err := timeouts.Set("messages", time.Hour); err == nil {
+		t.Error("Set accepted an unknown tier")
+	}
+	f.SetCacheTimeouts(timeouts)
+	fs.NewNodeFS(f, &fs.Options{})
+	ctx := context.Background()
+
+	var out fuse.EntryOut
+	if _, errno := f.Lookup(ctx, "README.md", &out); errno != 0 {
+		t.Fatalf("Lookup(README.md): %v", errno)
+	}
+	if out.EntryTimeout() != 24*time.Hour {
+		t.Errorf("README.md entry timeout = %v, want 24h", out.EntryTimeout())
+	}
+
+	// Nodes further down find the timeouts through the root.
+	listInode, errno := f.Lookup(ctx, "conversation", &out)
+	if errno != 0 {
+		t.Fatalf("Lookup(conversation): %v", errno)
+	}
+	f.AddChild("conversation", listInode, false)
+	list := listInode.Operations().(*ConversationListNode)
+	out = fuse.EntryOut{}
+	if _, errno := list.Lookup(ctx, localID, &out); errno != 0 {
+		t.Fatalf("Lookup(%s): %v", localID, errno)
+	}
+	if out.EntryTimeout() != 0 || out.AttrTimeout() != 0 {
+		t.Errorf("conversation timeouts = %v/%v, want 0", out.EntryTimeout(), out.AttrTimeout())
+	}
+}
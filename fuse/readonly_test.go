@@ -0,0 +1,50 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestReadOnlyConversation(t *testing.T) {
+	server := mockserver.New(mockserver.WithConversation("conv-edit", editTestMessages()), mockserver.WithMessageEditing())
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-edit", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+	ctl := filepath.Join(convDir, "ctl")
+
+	if err := os.WriteFile(ctl, []byte("readonly=true\n"), 0644); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	if data, _ := os.ReadFile(ctl); string(data) != "readonly=true\n" {
+		t.Errorf("ctl = %q", data)
+	}
+	for path, data := range map[string]string{
+		"send":                       "Hello?\n",
+		"ctl":                        "model=predictable\n",
+		"messages/0-user/content.md": "rewritten\n",
+	} {
+		if err := os.WriteFile(filepath.Join(convDir, path), []byte(data), 0644); !errors.Is(err, syscall.EROFS) {
+			t.Errorf("write %s while locked: got %v, want EROFS", path, err)
+		}
+	}
+	if err := os.WriteFile(ctl, []byte("readonly=maybe\n"), 0644); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("readonly=maybe: got %v, want EINVAL", err)
+	}
+
+	if err := os.WriteFile(ctl, []byte("readonly=false\n"), 0644); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(convDir, "send"), []byte("Hello?\n"), 0); err != nil {
+		t.Errorf("send after unlocking: %v", err)
+	}
+}
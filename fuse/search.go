@@ -0,0 +1,189 @@
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- SearchDirNode: /search/ ---
+// Looking up any name under /search runs it as a query over the default
+// backend's conversations:
+//
+//	ls /shelley/search/"flaky test"/
+//
+// lists a symlink per conversation with a message whose text contains the
+// query, ignoring case, named by local ID and pointing at the conversation,
+// and one per matching message, named {local ID}-{NNN-slug} and pointing at
+// its directory under messages/. Text is what content.txt holds, so markup,
+// headers and tool call JSON don't match. The backend has no search API, so
+// every created conversation is fetched (from the cache if fresh) and
+// searched on each listing. /search itself lists nothing.
+
+type SearchDirNode struct {
+	fs.Inode
+	client      func() (shelley.ShelleyClient, error) // the default backend's
+	state       *state.Store
+	parsedCache *ParsedMessageCache
+	startTime   time.Time
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*SearchDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*SearchDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*SearchDirNode)(nil))
+
+func (s *SearchDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if strings.TrimSpace(name) == "" {
+		return nil, syscall.ENOENT
+	}
+	ino := stableIno("search", name)
+	return s.NewInode(ctx, &SearchResultNode{search: s, query: name}, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino}), 0
+}
+
+func (s *SearchDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	return fs.NewListDirStream(nil), 0
+}
+
+func (s *SearchDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, s.startTime)
+	return 0
+}
+
+// --- SearchResultNode: /search/{query}/ ---
+
+type SearchResultNode struct {
+	fs.Inode
+	search *SearchDirNode
+	query  string
+}
+
+var _ = (fs.NodeLookuper)((*SearchResultNode)(nil))
+var _ = (fs.NodeReaddirer)((*SearchResultNode)(nil))
+var _ = (fs.NodeGetattrer)((*SearchResultNode)(nil))
+
+// matches returns the directory names of the messages of the conversation
+// cs whose text contains the query.
+func (r *SearchResultNode) matches(client shelley.ShelleyClient, cs *state.ConversationState) ([]string, error) {
+	if !cs.Created || cs.ShelleyConversationID == "" {
+		return nil, nil
+	}
+	data, err := client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := r.search.parsedCache.GetOrParseResult(cs.ShelleyConversationID, data)
+	if err != nil {
+		return nil, err
+	}
+	query := strings.ToLower(r.query)
+	var names []string
+	for i := range result.Messages {
+		msg := &result.Messages[i]
+		if strings.Contains(strings.ToLower(string(shelley.FormatPlainText(msg))), query) {
+			slug := shelley.MessageSlug(msg, result.ToolMap)
+			names = append(names, messageFileBase(msg.SequenceID, slug, result.MaxSeqID))
+		}
+	}
+	return names, nil
+}
+
+// searchTarget returns the symlink target of a result for the conversation with
+// the given local ID, or of one of its messages if message isn't empty.
+func searchTarget(localID, message string) string {
+	target := "../../conversation/" + localID
+	if message != "" {
+		target += "/messages/" + message
+	}
+	return target
+}
+
+func (r *SearchResultNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(r.search.diag, "SearchResultNode", "Lookup", r.query+"/"+name).Done()
+	client, err := r.search.client()
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	// name is a local ID, or a local ID and a message directory name joined
+	// by "-". Local IDs may contain "-" themselves (see -passthrough), so
+	// try each split.
+	localID, message := name, ""
+	cs := r.search.state.Get(name)
+	for i := 0; cs == nil && i < len(name); i++ {
+		if name[i] == '-' {
+			localID, message = name[:i], name[i+1:]
+			cs = r.search.state.Get(localID)
+		}
+	}
+	if cs == nil {
+		return nil, syscall.ENOENT
+	}
+	names, err := r.matches(client, cs)
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	for _, n := range names {
+		if message == "" || n == message {
+			// Results change as conversations grow.
+			out.SetEntryTimeout(volatileEntryTimeout)
+			return r.NewInode(ctx, &SymlinkNode{target: searchTarget(localID, message), startTime: r.search.startTime}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+func (r *SearchResultNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	op := diag.Track(r.search.diag, "SearchResultNode", "Readdir", r.query)
+	defer op.Done()
+	client, err := r.search.client()
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	mappings := r.search.state.ListMappings()
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].LocalID < mappings[j].LocalID })
+	var entries []fuse.DirEntry
+	for i := range mappings {
+		cs := &mappings[i]
+		op.SetPhase("searching " + cs.LocalID)
+		names, err := r.matches(client, cs)
+		if err != nil || len(names) == 0 {
+			// A conversation that can't be fetched is left out rather than
+			// failing the whole search.
+			continue
+		}
+		entries = append(entries, fuse.DirEntry{Name: cs.LocalID, Mode: syscall.S_IFLNK})
+		for _, n := range names {
+			entries = append(entries, fuse.DirEntry{Name: cs.LocalID + "-" + n, Mode: syscall.S_IFLNK})
+		}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (r *SearchResultNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, r.search.startTime)
+	return 0
+}
+
+// defaultClient returns the client of the default backend.
+func (f *FS) defaultClient() (shelley.ShelleyClient, error) {
+	if f.clientMgr == nil {
+		return f.client, nil
+	}
+	name := f.state.GetDefaultBackend()
+	backend := f.state.GetBackend(name)
+	if backend == nil || backend.URL == "" {
+		return nil, fmt.Errorf("default backend %q has no URL", name)
+	}
+	return f.clientMgr.EnsureURL(name, backend.URL)
+}
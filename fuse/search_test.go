@@ -0,0 +1,85 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestSearch(t *testing.T) {
+	msgs := func(convID, user, agent string) []shelley.Message {
+		return []shelley.Message{
+			{MessageID: convID + "-m1", ConversationID: convID, SequenceID: 1, Type: "user", UserData: strPtr(user)},
+			{MessageID: convID + "-m2", ConversationID: convID, SequenceID: 2, Type: "shelley", LLMData: strPtr(agent)},
+		}
+	}
+	server := mockserver.New(
+		mockserver.WithConversation("conv-a", msgs("conv-a", "why is the test flaky?", "It races on a Flaky Test timer.")),
+		mockserver.WithConversation("conv-b", msgs("conv-b", "hello", "hi there")),
+	)
+	defer server.Close()
+	store := testStore(t)
+	a, _ := store.Clone()
+	store.MarkCreated(a, "conv-a", "")
+	b, _ := store.Clone()
+	store.MarkCreated(b, "conv-b", "")
+
+	f := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	fs.NewNodeFS(f, &fs.Options{})
+	ctx := context.Background()
+	var out fuse.EntryOut
+	searchInode, errno := f.Lookup(ctx, "search", &out)
+	if errno != 0 {
+		t.Fatalf("Lookup(search): %v", errno)
+	}
+	f.AddChild("search", searchInode, false)
+	search := searchInode.Operations().(*SearchDirNode)
+	if _, errno := search.Lookup(ctx, " ", &out); errno != syscall.ENOENT {
+		t.Errorf("Lookup of a blank query = %v, want ENOENT", errno)
+	}
+	resultInode, errno := search.Lookup(ctx, "flaky test", &out)
+	if errno != 0 {
+		t.Fatalf("Lookup(flaky test): %v", errno)
+	}
+	search.AddChild("flaky test", resultInode, false)
+	result := resultInode.Operations().(*SearchResultNode)
+
+	stream, errno := result.Readdir(ctx)
+	if errno != 0 {
+		t.Fatalf("Readdir: %v", errno)
+	}
+	var names []string
+	for stream.HasNext() {
+		e, _ := stream.Next()
+		names = append(names, e.Name)
+	}
+	want := []string{a, a + "-1-agent"}
+	if len(names) != len(want) || names[0] != want[0] || names[1] != want[1] {
+		t.Fatalf("results = %v, want %v", names, want)
+	}
+
+	for name, target := range map[string]string{
+		a:              "../../conversation/" + a,
+		a + "-1-agent": "../../conversation/" + a + "/messages/1-agent",
+	} {
+		in, errno := result.Lookup(ctx, name, &out)
+		if errno != 0 {
+			t.Errorf("Lookup(%s): %v", name, errno)
+			continue
+		}
+		if got := in.Operations().(*SymlinkNode).target; got != target {
+			t.Errorf("%s -> %s, want %s", name, got, target)
+		}
+	}
+	for _, name := range []string{b, a + "-0-user", "nonexistent"} {
+		if _, errno := result.Lookup(ctx, name, &out); errno != syscall.ENOENT {
+			t.Errorf("Lookup(%s) = %v, want ENOENT", name, errno)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+// TestCopyHints checks what cp and tar look at before reading a file: the
+// size and block count from stat, and SEEK_DATA/SEEK_HOLE on the open file.
+func TestCopyHints(t *testing.T) {
+	hello := "Hello"
+	server := mockserver.New(mockserver.WithConversation("conv-seek", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-seek", SequenceID: 1, Type: "user", UserData: &hello},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-seek")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+
+	// Small files are sized on stat, so a copy doesn't take them for empty.
+	for _, name := range []string{"ctl", "fuse_id", "web"} {
+		path := filepath.Join(convDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		var st syscall.Stat_t
+		if err := syscall.Stat(path, &st); err != nil {
+			t.Fatalf("stat %s: %v", name, err)
+		}
+		if st.Size != int64(len(data)) {
+			t.Errorf("%s: size %d, want %d", name, st.Size, len(data))
+		}
+		if st.Blocks == 0 {
+			t.Errorf("%s: st_blocks = 0 for %d bytes of data", name, len(data))
+		}
+	}
+
+	// Rendered files answer SEEK_DATA/SEEK_HOLE from what the handle holds.
+	f, err := os.Open(filepath.Join(convDir, "messages", "all.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := fi.Size()
+	if size == 0 {
+		t.Fatal("all.md is empty")
+	}
+	fd := int(f.Fd())
+	if off, err := syscall.Seek(fd, 0, seekData); err != nil || off != 0 {
+		t.Errorf("SEEK_DATA at 0 = %d, %v; want 0", off, err)
+	}
+	if off, err := syscall.Seek(fd, 0, seekHole); err != nil || off != size {
+		t.Errorf("SEEK_HOLE at 0 = %d, %v; want %d", off, err, size)
+	}
+	if _, err := syscall.Seek(fd, size, seekData); err != syscall.ENXIO {
+		t.Errorf("SEEK_DATA at EOF: got %v, want ENXIO", err)
+	}
+}
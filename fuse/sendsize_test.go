@@ -0,0 +1,64 @@
+package fuse
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestMaxSendSize(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	server := mockserver.New(
+		mockserver.WithConversation("conv-big", nil),
+		mockserver.WithChatHandler(func(w http.ResponseWriter, r *http.Request) {
+			var req shelley.ChatRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			sent = append(sent, req.Message)
+			mu.Unlock()
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	defer server.Close()
+
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-big")
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetMaxSendSize(16)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation", localID)
+
+	err := os.WriteFile(filepath.Join(convDir, "send"), []byte(strings.Repeat("x", 17)), 0)
+	if !errors.Is(err, syscall.EFBIG) {
+		t.Fatalf("oversized send: got %v, want EFBIG", err)
+	}
+	if err := os.WriteFile(filepath.Join(convDir, "send"), []byte("short enough\n"), 0); err != nil {
+		t.Fatalf("send under the limit: %v", err)
+	}
+	mu.Lock()
+	if !reflect.DeepEqual(sent, []string{"short enough"}) {
+		t.Errorf("sent %q, want only the short message", sent)
+	}
+	mu.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(convDir, "errors.log"))
+	if err != nil {
+		t.Fatalf("read errors.log: %v", err)
+	}
+	if !strings.Contains(string(data), "message larger than 16 bytes rejected") {
+		t.Errorf("errors.log missing the rejected send:\n%s", data)
+	}
+}
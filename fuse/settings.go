@@ -0,0 +1,131 @@
+package fuse
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- Live ctl settings ---
+// Once a conversation is created its ctl is read-only, except for the keys
+// the backend can change while the conversation runs (liveCtlKeys). When the
+// client supports it (shelley.SettingsUpdater), writing those keys sends the
+// change to the backend immediately instead of failing with EROFS. Whatever
+// the backend answers, including a refusal, is written back to the state so
+// ctl always shows the settings in effect.
+
+// liveCtlKeys are the ctl keys that stay writable after creation.
+var liveCtlKeys = map[string]bool{"model": true, "temperature": true}
+
+// live reports whether this ctl accepts live settings once created.
+func (c *CtlNode) live() bool {
+	_, ok := c.client.(shelley.SettingsUpdater)
+	return ok && featureEnabled(c.client, c.state, shelley.FeatureSettings)
+}
+
+// writeLive sends the key=value pairs in content to the backend as one
+// settings change. Keys other than liveCtlKeys are EROFS, as is everything
+// against a backend that can't change settings. A conflict (the backend
+// refused the change, e.g. a model switch while the agent is working) is
+// EBUSY, after ctl has been reconciled with the backend.
+func (c *CtlNode) writeLive(cs *state.ConversationState, content string) syscall.Errno {
+	updater, ok := c.client.(shelley.SettingsUpdater)
+	if !ok || cs.ShelleyConversationID == "" {
+		return syscall.EROFS
+	}
+
+	var settings shelley.ConversationSettings
+	for _, word := range strings.Fields(content) {
+		k, v, ok := strings.Cut(word, "=")
+		if !ok {
+			return syscall.EINVAL
+		}
+		if !liveCtlKeys[k] {
+			return syscall.EROFS
+		}
+		switch k {
+		case "model":
+			result, err := c.client.ListModels()
+			if err != nil {
+				log.Printf("CtlNode.Write: ListModels failed: %v", err)
+				return backendErrno(err)
+			}
+			model := result.FindByName(v)
+			if model == nil {
+				return syscall.EINVAL
+			}
+			settings.Model = model.ID
+		case "temperature":
+			t, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return syscall.EINVAL
+			}
+			settings.Temperature = &t
+		}
+	}
+
+	current, err := updater.UpdateSettings(cs.ShelleyConversationID, settings)
+	var conflict *shelley.SettingsConflictError
+	switch {
+	case errors.As(err, &conflict):
+		log.Printf("UpdateSettings conflict for %s, backend has %+v", cs.ShelleyConversationID, conflict.Current)
+		reconcileSettings(c.client, c.state, c.localID, conflict.Current)
+		return syscall.EBUSY
+	case errors.Is(err, shelley.ErrSettingsUnsupported):
+		return syscall.EROFS
+	case err != nil:
+		log.Printf("UpdateSettings failed for %s: %v", cs.ShelleyConversationID, err)
+		return backendErrno(err)
+	}
+	reconcileSettings(c.client, c.state, c.localID, current)
+	return 0
+}
+
+// reconcileSettings records the settings the backend reported for localID.
+// The backend speaks model IDs; ctl shows display names.
+func reconcileSettings(client shelley.ShelleyClient, store *state.Store, localID string, current shelley.ConversationSettings) {
+	displayName := current.Model
+	if current.Model != "" {
+		if result, err := client.ListModels(); err == nil {
+			if model := result.FindByName(current.Model); model != nil {
+				displayName = model.Name()
+			}
+		}
+	}
+	var temperature string
+	if current.Temperature != nil {
+		temperature = strconv.FormatFloat(*current.Temperature, 'g', -1, 64)
+	}
+	if err := store.SetLiveSettings(localID, displayName, current.Model, temperature); err != nil {
+		log.Printf("Failed to record settings of %s: %v", localID, err)
+	}
+}
+
+// applyCreationSettings sends the ctl settings StartConversation has no
+// field for to a conversation that was just created. Failures are logged
+// only: the message has been sent, and ctl is reconciled either way.
+func applyCreationSettings(client shelley.ShelleyClient, store *state.Store, localID, conversationID, temperature string) {
+	updater, ok := client.(shelley.SettingsUpdater)
+	if !ok {
+		return
+	}
+	t, err := strconv.ParseFloat(temperature, 64)
+	if err != nil {
+		return
+	}
+	current, err := updater.UpdateSettings(conversationID, shelley.ConversationSettings{Temperature: &t})
+	var conflict *shelley.SettingsConflictError
+	if errors.As(err, &conflict) {
+		current, err = conflict.Current, nil
+	}
+	if err != nil {
+		log.Printf("Failed to apply temperature to new conversation %s: %v", conversationID, err)
+		return
+	}
+	reconcileSettings(client, store, localID, current)
+}
@@ -0,0 +1,96 @@
+package fuse
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+var settingsTestModels = []shelley.Model{
+	{ID: "model-a", Ready: true},
+	{ID: "custom-b1", DisplayName: "model-b", Ready: true},
+}
+
+func TestCtlLiveSettings(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithModels(settingsTestModels),
+		mockserver.WithConversation("conv-live", nil),
+		mockserver.WithLiveSettings(),
+	)
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-live")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	ctl := filepath.Join(mountPoint, "conversation", localID, "ctl")
+	if err := os.WriteFile(ctl, []byte("model=model-b temperature=0.3\n"), 0644); err != nil {
+		t.Fatalf("live ctl write: %v", err)
+	}
+	data, err := os.ReadFile(ctl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "model=model-b temperature=0.3" {
+		t.Errorf("ctl = %q after live write", got)
+	}
+	if cs := store.Get(localID); cs.ModelID != "custom-b1" {
+		t.Errorf("ModelID = %q, want the backend's ID", cs.ModelID)
+	}
+
+	// Keys the backend can't change live stay read-only.
+	if err := os.WriteFile(ctl, []byte("cwd=/tmp\n"), 0644); !errors.Is(err, syscall.EROFS) {
+		t.Errorf("cwd write after creation: got %v, want EROFS", err)
+	}
+}
+
+func TestCtlLiveSettingsConflict(t *testing.T) {
+	model := "model-a"
+	server := mockserver.New(
+		mockserver.WithModels(settingsTestModels),
+		mockserver.WithFullConversation(shelley.Conversation{ConversationID: "conv-busy", Model: &model, Working: true}, nil),
+		mockserver.WithLiveSettings(),
+	)
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-busy")
+	store.SetLiveSettings(localID, "model-b", "custom-b1", "") // stale local view
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	ctl := filepath.Join(mountPoint, "conversation", localID, "ctl")
+	if err := os.WriteFile(ctl, []byte("model=model-b\n"), 0644); !errors.Is(err, syscall.EBUSY) {
+		t.Fatalf("model switch while working: got %v, want EBUSY", err)
+	}
+	data, err := os.ReadFile(ctl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "model=model-a" {
+		t.Errorf("ctl = %q, want it reconciled with the backend", got)
+	}
+}
+
+func TestCtlLiveSettingsUnsupported(t *testing.T) {
+	server := mockserver.New(mockserver.WithModels(settingsTestModels), mockserver.WithConversation("conv-old", nil))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-old")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	ctl := filepath.Join(mountPoint, "conversation", localID, "ctl")
+	if err := os.WriteFile(ctl, []byte("model=model-b\n"), 0644); !errors.Is(err, syscall.EROFS) {
+		t.Errorf("got %v, want EROFS from a backend without live settings", err)
+	}
+	if cs := store.Get(localID); cs.Model != "" {
+		t.Errorf("model = %q, want it unchanged", cs.Model)
+	}
+}
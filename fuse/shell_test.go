@@ -113,7 +113,7 @@ func TestShellRootDirectory(t *testing.T) {
 	}
 }
 
-// TestShellNewSymlink tests that /new is a symlink to model/default/new.
+// TestShellNewSymlink tests that /new is a symlink to the default backend's new.
 func TestShellNewSymlink(t *testing.T) {
 	skipIfNoFusermount(t)
 	skipIfNoShelley(t)
@@ -125,10 +125,10 @@ func TestShellNewSymlink(t *testing.T) {
 	runShellDiagOK(t, tm.MountPoint, "test -L new", tm.DiagURL)
 
 	// Verify symlink target
-	// With backend support, /new points to backend/default/model/default/new
+	// With backend support, /new points to the default backend's new
 	target := strings.TrimSpace(runShellDiagOK(t, tm.MountPoint, "readlink new", tm.DiagURL))
-	if target != "backend/default/model/default/new" {
-		t.Errorf("Expected symlink target 'backend/default/model/default/new', got %q", target)
+	if target != "backend/main/new" {
+		t.Errorf("Expected symlink target 'backend/main/new', got %q", target)
 	}
 }
 
@@ -0,0 +1,279 @@
+package fuse
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- Snapshots: /conversation/{id}/snapshots/ ---
+// `mkdir snapshots/{name}` freezes the conversation's messages as they are
+// on the backend right then; snapshots/{name}/ keeps showing exactly those
+// messages, read-only, however many arrive later, so a long analysis
+// doesn't see the conversation move under it. `rmdir` drops a snapshot.
+// Snapshots are kept in memory, not in the state file, and are gone after
+// a remount.
+
+// Snapshots holds the frozen message lists, by server conversation ID and
+// snapshot name.
+type Snapshots struct {
+	mu    sync.Mutex
+	convs map[string]map[string]*snapshot
+}
+
+// snapshot is a conversation's messages as of taken.
+type snapshot struct {
+	taken  time.Time
+	result *ParseResult
+}
+
+// NewSnapshots creates an empty snapshot store.
+func NewSnapshots() *Snapshots {
+	return &Snapshots{convs: make(map[string]map[string]*snapshot)}
+}
+
+func (s *Snapshots) get(conversationID, name string) *snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.convs[conversationID][name]
+}
+
+// names returns the names of a conversation's snapshots, sorted.
+func (s *Snapshots) names(conversationID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.convs[conversationID]))
+	for name := range s.convs[conversationID] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// add stores snap under name, reporting false if the name is taken.
+func (s *Snapshots) add(conversationID, name string, snap *snapshot) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.convs[conversationID] == nil {
+		s.convs[conversationID] = make(map[string]*snapshot)
+	}
+	if _, ok := s.convs[conversationID][name]; ok {
+		return false
+	}
+	s.convs[conversationID][name] = snap
+	return true
+}
+
+// remove drops a snapshot, reporting false if there is none by that name.
+func (s *Snapshots) remove(conversationID, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.convs[conversationID][name]; !ok {
+		return false
+	}
+	delete(s.convs[conversationID], name)
+	if len(s.convs[conversationID]) == 0 {
+		delete(s.convs, conversationID)
+	}
+	return true
+}
+
+type SnapshotsDirNode struct {
+	fs.Inode
+	localID   string
+	client    shelley.ShelleyClient
+	state     *state.Store
+	snapshots *Snapshots
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*SnapshotsDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*SnapshotsDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*SnapshotsDirNode)(nil))
+var _ = (fs.NodeMkdirer)((*SnapshotsDirNode)(nil))
+var _ = (fs.NodeRmdirer)((*SnapshotsDirNode)(nil))
+
+// conversationID returns the server ID of the conversation, or "" if it
+// isn't created.
+func (n *SnapshotsDirNode) conversationID() string {
+	cs := n.state.Get(n.localID)
+	if cs == nil || !cs.Created {
+		return ""
+	}
+	return cs.ShelleyConversationID
+}
+
+func (n *SnapshotsDirNode) newSnapshotNode(ctx context.Context, conversationID, name string, snap *snapshot, out *fuse.EntryOut) *fs.Inode {
+	out.Attr.Mode = fuse.S_IFDIR | 0555
+	setTimestamps(&out.Attr, snap.taken)
+	ino := stableIno("snapshot", conversationID, name, strconv.FormatInt(snap.taken.UnixNano(), 10))
+	return n.NewInode(ctx, &SnapshotNode{snap: snap, diag: n.diag}, fs.StableAttr{Mode: fuse.S_IFDIR, Ino: ino})
+}
+
+func (n *SnapshotsDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	id := n.conversationID()
+	snap := n.snapshots.get(id, name)
+	if id == "" || snap == nil {
+		return nil, syscall.ENOENT
+	}
+	return n.newSnapshotNode(ctx, id, name, snap, out), 0
+}
+
+func (n *SnapshotsDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	id := n.conversationID()
+	if id == "" {
+		return fs.NewListDirStream(nil), 0
+	}
+	names := n.snapshots.names(id)
+	entries := make([]fuse.DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = fuse.DirEntry{Name: name, Mode: fuse.S_IFDIR}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *SnapshotsDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, metaTime(n.state, n.localID, n.startTime))
+	return 0
+}
+
+// Mkdir takes a snapshot of the messages on the backend now, bypassing the
+// client's cache so it doesn't freeze an older copy.
+func (n *SnapshotsDirNode) Mkdir(ctx context.Context, name string, mode uint32, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(n.diag, "SnapshotsDirNode", "Mkdir", n.localID+"/"+name).Done()
+	if !isValidFilename(name) {
+		return nil, syscall.EINVAL
+	}
+	id := n.conversationID()
+	if id == "" {
+		return nil, syscall.ENOENT
+	}
+	if n.snapshots.get(id, name) != nil {
+		return nil, syscall.EEXIST
+	}
+	data, err := freshConversation(n.client, id)
+	if err != nil {
+		return nil, backendErrno(err)
+	}
+	// Parsed here rather than through the shared cache, so neither
+	// replaces the other's entry.
+	msgs, err := shelley.ParseMessages(data)
+	if err != nil {
+		return nil, syscall.EIO
+	}
+	msgPtrs := make([]*shelley.Message, len(msgs))
+	for i := range msgs {
+		msgPtrs[i] = &msgs[i]
+	}
+	result := &ParseResult{Messages: msgs, ToolMap: shelley.BuildToolNameMap(msgPtrs), MaxSeqID: maxSeqIDFromMessages(msgs)}
+	snap := &snapshot{taken: time.Now(), result: result}
+	if !n.snapshots.add(id, name, snap) {
+		return nil, syscall.EEXIST
+	}
+	return n.newSnapshotNode(ctx, id, name, snap, out), 0
+}
+
+func (n *SnapshotsDirNode) Rmdir(ctx context.Context, name string) syscall.Errno {
+	defer diag.Track(n.diag, "SnapshotsDirNode", "Rmdir", n.localID+"/"+name).Done()
+	if !n.snapshots.remove(n.conversationID(), name) {
+		return syscall.ENOENT
+	}
+	return 0
+}
+
+// --- SnapshotNode: /conversation/{id}/snapshots/{name}/ ---
+// Like messages/ as of the snapshot, with what doesn't depend on the time
+// of reading: all.json, all.md, all.org, count and the message
+// directories. Nothing in it is writable.
+
+type SnapshotNode struct {
+	fs.Inode
+	snap *snapshot
+	diag *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*SnapshotNode)(nil))
+var _ = (fs.NodeReaddirer)((*SnapshotNode)(nil))
+var _ = (fs.NodeGetattrer)((*SnapshotNode)(nil))
+
+// snapshotFiles are the files of a snapshot next to its message directories.
+var snapshotFiles = []string{"all.json", "all.md", "all.org", "count"}
+
+// file renders one of snapshotFiles.
+func (n *SnapshotNode) file(name string) (string, bool) {
+	msgs := n.snap.result.Messages
+	switch name {
+	case "all.json":
+		data, err := shelley.FormatJSON(msgs)
+		if err != nil {
+			return "", false
+		}
+		return string(data) + "\n", true
+	case "all.md":
+		return string(shelley.FormatMarkdown(msgs)), true
+	case "all.org":
+		return string(shelley.FormatOrg(msgs)), true
+	case "count":
+		return strconv.Itoa(len(msgs)) + "\n", true
+	}
+	return "", false
+}
+
+// messageDirs returns the snapshot's messages by directory name.
+func (n *SnapshotNode) messageDirs() map[string]*shelley.Message {
+	result := n.snap.result
+	dirs := make(map[string]*shelley.Message, len(result.Messages))
+	for i := range result.Messages {
+		msg := &result.Messages[i]
+		dirs[messageFileBase(msg.SequenceID, shelley.MessageSlug(msg, result.ToolMap), result.MaxSeqID)] = msg
+	}
+	return dirs
+}
+
+func (n *SnapshotNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	ttl := cacheTimeouts(&n.Inode).Immutable
+	if value, ok := n.file(name); ok {
+		setImmutableFieldAttrs(out, value, true, n.snap.taken, ttl)
+		return n.NewInode(ctx, &MessageFieldNode{value: value, startTime: n.snap.taken, noNewline: true}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+	}
+	msg, ok := n.messageDirs()[name]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	// Without a local ID and state the message directory has nothing to
+	// edit or pin.
+	node := &MessageDirNode{message: *msg, toolMap: n.snap.result.ToolMap, startTime: n.snap.taken, diag: n.diag}
+	setImmutableDirAttrs(out, n.snap.taken, ttl)
+	node.messageTimestamps().ApplyWithFallback(&out.Attr, n.snap.taken)
+	return n.NewInode(ctx, node, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+func (n *SnapshotNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	result := n.snap.result
+	entries := make([]fuse.DirEntry, 0, len(snapshotFiles)+len(result.Messages))
+	for _, name := range snapshotFiles {
+		entries = append(entries, fuse.DirEntry{Name: name, Mode: fuse.S_IFREG})
+	}
+	for i := range result.Messages {
+		msg := &result.Messages[i]
+		entries = append(entries, fuse.DirEntry{Name: messageFileBase(msg.SequenceID, shelley.MessageSlug(msg, result.ToolMap), result.MaxSeqID), Mode: fuse.S_IFDIR})
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *SnapshotNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0555
+	setTimestamps(&out.Attr, n.snap.taken)
+	return 0
+}
@@ -0,0 +1,72 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestSnapshots(t *testing.T) {
+	server := mockserver.New(mockserver.WithConversation("conv-snap", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-snap", SequenceID: 1, Type: "user", UserData: strPtr("start")},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Clone()
+	store.MarkCreated(localID, "conv-snap", "")
+	dir := &SnapshotsDirNode{localID: localID, client: shelley.NewClient(server.URL), state: store, snapshots: NewSnapshots(), startTime: time.Now()}
+	fs.NewNodeFS(dir, &fs.Options{})
+	ctx := context.Background()
+	var out fuse.EntryOut
+
+	snapInode, errno := dir.Mkdir(ctx, "before", 0755, &out)
+	if errno != 0 {
+		t.Fatalf("Mkdir: %v", errno)
+	}
+	if _, errno := dir.Mkdir(ctx, "before", 0755, &out); errno != syscall.EEXIST {
+		t.Errorf("second Mkdir = %v, want EEXIST", errno)
+	}
+	server.AddMessage("conv-snap", shelley.Message{MessageID: "m2", ConversationID: "conv-snap", SequenceID: 2, Type: "shelley", LLMData: strPtr("later")})
+
+	snap := snapInode.Operations().(*SnapshotNode)
+	if count, _ := snap.file("count"); count != "1\n" {
+		t.Errorf("count = %q, want 1", count)
+	}
+	if _, errno := snap.Lookup(ctx, "0-user", &out); errno != 0 {
+		t.Errorf("Lookup(0-user): %v", errno)
+	}
+	if _, errno := snap.Lookup(ctx, "1-agent", &out); errno != syscall.ENOENT {
+		t.Errorf("Lookup(1-agent) = %v, want ENOENT: the message came after the snapshot", errno)
+	}
+
+	// A later snapshot has the new message.
+	after, errno := dir.Mkdir(ctx, "after", 0755, &out)
+	if errno != 0 {
+		t.Fatalf("Mkdir(after): %v", errno)
+	}
+	if count, _ := after.Operations().(*SnapshotNode).file("count"); count != "2\n" {
+		t.Errorf("later count = %q, want 2", count)
+	}
+
+	if errno := dir.Rmdir(ctx, "before"); errno != 0 {
+		t.Fatalf("Rmdir: %v", errno)
+	}
+	stream, _ := dir.Readdir(ctx)
+	var names []string
+	for stream.HasNext() {
+		e, _ := stream.Next()
+		names = append(names, e.Name)
+	}
+	if len(names) != 1 || names[0] != "after" {
+		t.Errorf("snapshots = %v, want [after]", names)
+	}
+	if _, errno := dir.Lookup(ctx, "before", &out); errno != syscall.ENOENT {
+		t.Errorf("Lookup of a removed snapshot = %v, want ENOENT", errno)
+	}
+}
@@ -0,0 +1,56 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/state"
+)
+
+// --- StateStatusNode: /backend/state_status ---
+// Whether the state file is being written. "ok: {path}" normally; when the
+// disk is full (or over quota, or read-only) the mount keeps its state in
+// memory and this says "memory-only since {time}: {error} ...", so a
+// script can check it before trusting that its clones survive an unmount.
+// Saves keep trying the disk and the file goes back to ok once one gets
+// through.
+
+type StateStatusNode struct {
+	fs.Inode
+	state *state.Store
+	diag  *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*StateStatusNode)(nil))
+var _ = (fs.NodeGetattrer)((*StateStatusNode)(nil))
+
+func (n *StateStatusNode) content() []byte {
+	return []byte(n.state.PersistStatus().String() + "\n")
+}
+
+// Open renders the status once; the handle reads and sizes that snapshot.
+func (n *StateStatusNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(n.diag, "StateStatusNode", "Open", "").Done()
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &ConvContentFileHandle{content: n.content(), messageTime: time.Now()}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *StateStatusNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fg, ok := f.(fs.FileGetattrer); ok {
+		return fg.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(len(n.content()))
+	t := time.Now()
+	if status := n.state.PersistStatus(); status.MemoryOnly {
+		t = status.Since
+	}
+	setTimestamps(&out.Attr, t)
+	return 0
+}
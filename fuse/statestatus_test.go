@@ -0,0 +1,47 @@
+package fuse
+
+import (
+	"context"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func TestStateStatus(t *testing.T) {
+	store := testStore(t)
+	list := &BackendListNode{state: store}
+	fs.NewNodeFS(list, &fs.Options{})
+	ctx := context.Background()
+	var out fuse.EntryOut
+
+	in, errno := list.Lookup(ctx, "state_status", &out)
+	if errno != 0 {
+		t.Fatalf("Lookup(state_status): %v", errno)
+	}
+	fh, _, errno := in.Operations().(*StateStatusNode).Open(ctx, syscall.O_RDONLY)
+	if errno != 0 {
+		t.Fatalf("Open: %v", errno)
+	}
+	if got := string(fh.(*ConvContentFileHandle).content); got != "ok: "+store.Path+"\n" {
+		t.Errorf("state_status = %q", got)
+	}
+	if _, _, errno := in.Operations().(*StateStatusNode).Open(ctx, syscall.O_WRONLY); errno != syscall.EACCES {
+		t.Errorf("Open for writing = %v, want EACCES", errno)
+	}
+	if _, errno := list.Mkdir(ctx, "state_status", 0755, &out); errno != syscall.EEXIST {
+		t.Errorf("Mkdir(state_status) = %v, want EEXIST", errno)
+	}
+
+	stream, _ := list.Readdir(ctx)
+	var names []string
+	for stream.HasNext() {
+		e, _ := stream.Next()
+		names = append(names, e.Name)
+	}
+	if !strings.Contains(strings.Join(names, " "), "state_status") {
+		t.Errorf("/backend lists %v, want state_status", names)
+	}
+}
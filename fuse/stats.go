@@ -0,0 +1,252 @@
+package fuse
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/state"
+)
+
+// --- StatsDirNode: /stats/ ---
+// The usage board summed up three ways, one number per file so scripts and
+// status bars need no CSV parsing:
+//
+//	stats/total_input_tokens, total_output_tokens, total_tokens, total_cost_usd
+//	stats/per-model/{model}/input_tokens, output_tokens, tokens, cost_usd
+//	stats/per-conversation/{local-id}/input_tokens, output_tokens, tokens, cost_usd
+//
+// Input counts cached input too. Like usage.csv, only conversations whose
+// messages were loaded since the mount are counted, and nothing is fetched
+// to fill the tree. Conversations without a model are left out of
+// per-model/ but counted in the totals.
+
+// usageFields are the files of a per-model or per-conversation directory;
+// the top of /stats has them with a "total_" prefix.
+var usageFields = []string{"input_tokens", "output_tokens", "tokens", "cost_usd"}
+
+// Groups under /stats.
+const (
+	statsPerModel        = "per-model"
+	statsPerConversation = "per-conversation"
+)
+
+// usageField renders one of usageFields of u.
+func usageField(u Usage, field string) string {
+	switch field {
+	case "input_tokens":
+		return strconv.FormatInt(u.TotalInputTokens(), 10)
+	case "output_tokens":
+		return strconv.FormatInt(u.OutputTokens, 10)
+	case "tokens":
+		return strconv.FormatInt(u.TotalTokens(), 10)
+	case "cost_usd":
+		return strconv.FormatFloat(u.CostUSD, 'f', 4, 64)
+	}
+	return ""
+}
+
+// usageStats is the recorded usage summed over every conversation, and by
+// group and key: model name or local ID.
+type usageStats struct {
+	total  Usage
+	groups map[string]map[string]Usage
+}
+
+// stats sums up the recorded usage of the conversations store tracks.
+func (b *UsageBoard) stats(store *state.Store) usageStats {
+	s := usageStats{groups: map[string]map[string]Usage{
+		statsPerModel:        {},
+		statsPerConversation: {},
+	}}
+	for _, e := range b.byTotal(store) {
+		s.total.add(e.usage)
+		s.groups[statsPerConversation][e.cs.LocalID] = e.usage
+		if e.cs.Model != "" {
+			u := s.groups[statsPerModel][e.cs.Model]
+			u.add(e.usage)
+			s.groups[statsPerModel][e.cs.Model] = u
+		}
+	}
+	return s
+}
+
+type StatsDirNode struct {
+	fs.Inode
+	board     *UsageBoard
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*StatsDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*StatsDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*StatsDirNode)(nil))
+
+// mtime is when usage last changed.
+func (n *StatsDirNode) mtime() time.Time {
+	if t := n.board.lastUpdate(); !t.IsZero() {
+		return t
+	}
+	return n.startTime
+}
+
+// usage returns the usage of key in group, or the total if group is "".
+func (n *StatsDirNode) usage(group, key string) (Usage, bool) {
+	s := n.board.stats(n.state)
+	if group == "" {
+		return s.total, true
+	}
+	u, ok := s.groups[group][key]
+	return u, ok
+}
+
+func (n *StatsDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	switch name {
+	case statsPerModel, statsPerConversation:
+		return n.NewInode(ctx, &StatsGroupNode{stats: n, group: name}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+	}
+	for _, field := range usageFields {
+		if name == "total_"+field {
+			return n.NewInode(ctx, &StatsFileNode{stats: n, field: field}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+func (n *StatsDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	var entries []fuse.DirEntry
+	for _, field := range usageFields {
+		entries = append(entries, fuse.DirEntry{Name: "total_" + field, Mode: fuse.S_IFREG})
+	}
+	entries = append(entries,
+		fuse.DirEntry{Name: statsPerModel, Mode: fuse.S_IFDIR},
+		fuse.DirEntry{Name: statsPerConversation, Mode: fuse.S_IFDIR})
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *StatsDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.mtime())
+	return 0
+}
+
+// --- StatsGroupNode: /stats/per-model/, /stats/per-conversation/ ---
+
+type StatsGroupNode struct {
+	fs.Inode
+	stats *StatsDirNode
+	group string
+}
+
+var _ = (fs.NodeLookuper)((*StatsGroupNode)(nil))
+var _ = (fs.NodeReaddirer)((*StatsGroupNode)(nil))
+var _ = (fs.NodeGetattrer)((*StatsGroupNode)(nil))
+
+func (n *StatsGroupNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(n.stats.diag, "StatsGroupNode", "Lookup", n.group+"/"+name).Done()
+	if _, ok := n.stats.usage(n.group, name); !ok {
+		return nil, syscall.ENOENT
+	}
+	return n.NewInode(ctx, &StatsUsageNode{stats: n.stats, group: n.group, key: name}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+func (n *StatsGroupNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(n.stats.diag, "StatsGroupNode", "Readdir", n.group).Done()
+	usage := n.stats.board.stats(n.stats.state).groups[n.group]
+	keys := make([]string, 0, len(usage))
+	for k := range usage {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	entries := make([]fuse.DirEntry, len(keys))
+	for i, k := range keys {
+		entries[i] = fuse.DirEntry{Name: k, Mode: fuse.S_IFDIR}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *StatsGroupNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.stats.mtime())
+	return 0
+}
+
+// --- StatsUsageNode: /stats/per-model/{model}/, /stats/per-conversation/{local-id}/ ---
+
+type StatsUsageNode struct {
+	fs.Inode
+	stats *StatsDirNode
+	group string
+	key   string
+}
+
+var _ = (fs.NodeLookuper)((*StatsUsageNode)(nil))
+var _ = (fs.NodeReaddirer)((*StatsUsageNode)(nil))
+var _ = (fs.NodeGetattrer)((*StatsUsageNode)(nil))
+
+func (n *StatsUsageNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	for _, field := range usageFields {
+		if name == field {
+			return n.NewInode(ctx, &StatsFileNode{stats: n.stats, group: n.group, key: n.key, field: field}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+		}
+	}
+	return nil, syscall.ENOENT
+}
+
+func (n *StatsUsageNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	entries := make([]fuse.DirEntry, len(usageFields))
+	for i, field := range usageFields {
+		entries[i] = fuse.DirEntry{Name: field, Mode: fuse.S_IFREG}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *StatsUsageNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.stats.mtime())
+	return 0
+}
+
+// --- StatsFileNode: one number under /stats ---
+
+type StatsFileNode struct {
+	fs.Inode
+	stats *StatsDirNode
+	group string // "" for the totals
+	key   string
+	field string
+}
+
+var _ = (fs.NodeOpener)((*StatsFileNode)(nil))
+var _ = (fs.NodeGetattrer)((*StatsFileNode)(nil))
+
+// data renders the number. A conversation or model whose usage is gone
+// reads as zero.
+func (n *StatsFileNode) data() []byte {
+	u, _ := n.stats.usage(n.group, n.key)
+	return []byte(usageField(u, n.field) + "\n")
+}
+
+// Open renders the number once; the handle reads and sizes that snapshot.
+func (n *StatsFileNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &ConvContentFileHandle{content: n.data(), messageTime: n.stats.mtime()}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *StatsFileNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fg, ok := f.(fs.FileGetattrer); ok {
+		return fg.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(len(n.data()))
+	setTimestamps(&out.Attr, n.stats.mtime())
+	return 0
+}
@@ -0,0 +1,75 @@
+package fuse
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+func TestStats(t *testing.T) {
+	store := testStore(t)
+	a, _ := store.AdoptWithMetadata("conv-a", "", "", "", "model-x", "")
+	b, _ := store.AdoptWithMetadata("conv-b", "", "", "", "model-x", "")
+	c, _ := store.AdoptWithMetadata("conv-c", "", "", "", "", "")
+	board := NewUsageBoard()
+	board.Record("conv-a", usageTestMessages("conv-a", `{"input_tokens":100,"cache_read_input_tokens":10,"output_tokens":20,"cost_usd":0.5}`))
+	board.Record("conv-b", usageTestMessages("conv-b", `{"input_tokens":1,"output_tokens":2}`))
+	board.Record("conv-c", usageTestMessages("conv-c", `{"input_tokens":1000}`))
+	board.Record("conv-untracked", usageTestMessages("conv-untracked", `{"input_tokens":99999}`))
+
+	stats := &StatsDirNode{board: board, state: store, startTime: time.Now()}
+	fs.NewNodeFS(stats, &fs.Options{})
+	ctx := context.Background()
+	read := func(parent *fs.Inode, path ...string) string {
+		t.Helper()
+		in := parent
+		for _, name := range path {
+			var out fuse.EntryOut
+			child, errno := in.Operations().(fs.NodeLookuper).Lookup(ctx, name, &out)
+			if errno != 0 {
+				t.Fatalf("Lookup(%v): %v", path, errno)
+			}
+			in.AddChild(name, child, false)
+			in = child
+		}
+		return string(in.Operations().(*StatsFileNode).data())
+	}
+
+	for _, tc := range []struct {
+		path []string
+		want string
+	}{
+		{[]string{"total_input_tokens"}, "1111\n"},
+		{[]string{"total_output_tokens"}, "22\n"},
+		{[]string{"total_tokens"}, "1133\n"},
+		{[]string{"total_cost_usd"}, "0.5000\n"},
+		{[]string{"per-model", "model-x", "tokens"}, "133\n"},
+		{[]string{"per-conversation", a, "input_tokens"}, "110\n"},
+		{[]string{"per-conversation", b, "output_tokens"}, "2\n"},
+		{[]string{"per-conversation", c, "tokens"}, "1000\n"},
+	} {
+		if got := read(&stats.Inode, tc.path...); got != tc.want {
+			t.Errorf("%v = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	// Conversations without a model only count towards the totals.
+	var out fuse.EntryOut
+	perModel, _ := stats.Lookup(ctx, "per-model", &out)
+	stream, _ := perModel.Operations().(*StatsGroupNode).Readdir(ctx)
+	var models []string
+	for stream.HasNext() {
+		e, _ := stream.Next()
+		models = append(models, e.Name)
+	}
+	if len(models) != 1 || models[0] != "model-x" {
+		t.Errorf("per-model lists %v, want [model-x]", models)
+	}
+	if _, errno := perModel.Operations().(*StatsGroupNode).Lookup(ctx, "model-y", &out); errno != syscall.ENOENT {
+		t.Errorf("Lookup(per-model/model-y) = %v, want ENOENT", errno)
+	}
+}
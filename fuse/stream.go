@@ -0,0 +1,242 @@
+package fuse
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- MessageStreamNode: /conversation/{id}/messages/stream ---
+// Reading blocks until the conversation gets new messages and returns them
+// rendered like all.md, so `tail -f messages/stream` (or cat) follows an
+// agent run as it happens. Like .events, each open starts at the messages
+// written after it; all.md has the ones before.
+//
+// Every open follows the conversation on its own: over the backend's
+// stream endpoint when the client has one (shelley.MessageStreamer) and
+// the streaming feature is on, and by fetching the conversation every
+// streamPollInterval otherwise.
+// A conversation that hasn't been created yet is followed from its first
+// message once it is.
+
+const (
+	// streamPollInterval is how often a conversation is fetched when the
+	// backend can't stream it, or before it exists on the backend.
+	streamPollInterval = time.Second
+	// streamRetryDelay is how long to wait before reconnecting a stream
+	// the backend closed or broke off.
+	streamRetryDelay = 2 * time.Second
+)
+
+type MessageStreamNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	startTime   time.Time
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*MessageStreamNode)(nil))
+var _ = (fs.NodeGetattrer)((*MessageStreamNode)(nil))
+
+func (n *MessageStreamNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(n.diag, "MessageStreamNode", "Open", n.localID).Done()
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	cs := n.state.Get(n.localID)
+	if cs == nil {
+		return nil, 0, syscall.ENOENT
+	}
+	h := &messageStreamHandle{node: n, arrived: make(chan struct{})}
+	// Messages already written are where the stream starts, not part of it.
+	if cs.Created && cs.ShelleyConversationID != "" {
+		convData, err := n.client.GetConversation(cs.ShelleyConversationID)
+		if err != nil {
+			return nil, 0, backendErrno(err)
+		}
+		msgs, _, err := n.parsedCache.GetOrParse(cs.ShelleyConversationID, convData)
+		if err != nil {
+			return nil, 0, syscall.EIO
+		}
+		h.seen = append(h.seen, msgs...)
+		h.lastSeq = maxSequenceID(msgs)
+	}
+	followCtx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	go h.follow(followCtx)
+	return h, fuse.FOPEN_DIRECT_IO | fuse.FOPEN_NONSEEKABLE, 0
+}
+
+func (n *MessageStreamNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0444
+	if cs := n.state.Get(n.localID); cs != nil && !cs.CreatedAt.IsZero() {
+		setTimestamps(&out.Attr, cs.CreatedAt)
+	} else {
+		setTimestamps(&out.Attr, n.startTime)
+	}
+	return 0
+}
+
+func maxSequenceID(msgs []shelley.Message) int {
+	maxSeq := 0
+	for i := range msgs {
+		if msgs[i].SequenceID > maxSeq {
+			maxSeq = msgs[i].SequenceID
+		}
+	}
+	return maxSeq
+}
+
+// messageStreamHandle is one reader's stream: the messages followed so far
+// and the rendered text of new ones that hasn't been read yet.
+type messageStreamHandle struct {
+	node   *MessageStreamNode
+	cancel context.CancelFunc
+
+	readMu sync.Mutex // serializes readers, so each gets whole chunks in order
+
+	mu      sync.Mutex
+	seen    []shelley.Message // every message so far, to name tool results
+	lastSeq int               // highest sequence ID rendered or skipped
+	pending []byte            // rendered text not yet read
+	arrived chan struct{}     // closed and replaced when pending grows
+}
+
+var _ = (fs.FileReader)((*messageStreamHandle)(nil))
+var _ = (fs.FileReleaser)((*messageStreamHandle)(nil))
+
+func (h *messageStreamHandle) Read(ctx context.Context, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	h.readMu.Lock()
+	defer h.readMu.Unlock()
+	h.mu.Lock()
+	if len(h.pending) == 0 {
+		op := diag.Track(h.node.diag, "MessageStreamNode", "Read", h.node.localID)
+		op.SetPhase("waiting for messages")
+		for len(h.pending) == 0 {
+			arrived := h.arrived
+			h.mu.Unlock()
+			select {
+			case <-arrived:
+			case <-ctx.Done():
+				op.Done()
+				return nil, syscall.EINTR
+			}
+			h.mu.Lock()
+		}
+		op.Done()
+	}
+	n := copy(dest, h.pending)
+	h.pending = h.pending[n:]
+	h.mu.Unlock()
+	return fuse.ReadResultData(dest[:n]), 0
+}
+
+func (h *messageStreamHandle) Release(ctx context.Context) syscall.Errno {
+	h.cancel()
+	return 0
+}
+
+// add takes in messages from the backend and renders those newer than any
+// seen before.
+func (h *messageStreamHandle) add(msgs []shelley.Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	newSeq := h.lastSeq
+	for _, m := range msgs {
+		if m.SequenceID > h.lastSeq {
+			h.seen = append(h.seen, m)
+			newSeq = max(newSeq, m.SequenceID)
+		}
+	}
+	if newSeq == h.lastSeq {
+		return
+	}
+	h.pending = append(h.pending, shelley.FormatMarkdownSince(h.seen, h.lastSeq)...)
+	h.lastSeq = newSeq
+	close(h.arrived)
+	h.arrived = make(chan struct{})
+}
+
+// follow feeds the handle until ctx is cancelled.
+func (h *messageStreamHandle) follow(ctx context.Context) {
+	conversationID := h.waitCreated(ctx)
+	if conversationID == "" {
+		return
+	}
+	streamer, ok := h.node.client.(shelley.MessageStreamer)
+	if ok && featureEnabled(h.node.client, h.node.state, shelley.FeatureStreaming) {
+		for {
+			err := streamer.StreamConversation(ctx, conversationID, h.add)
+			if ctx.Err() != nil {
+				return
+			}
+			if errors.Is(err, shelley.ErrStreamingUnsupported) {
+				break
+			}
+			if err != nil {
+				h.node.diag.RecordError(h.node.localID, "stream: %v", err)
+			}
+			if !sleepCtx(ctx, streamRetryDelay) {
+				return
+			}
+		}
+	}
+	h.poll(ctx, conversationID)
+}
+
+// waitCreated returns the server ID of the conversation, waiting for its
+// first message to create it if needed. It returns "" if ctx is cancelled
+// first or the conversation is removed.
+func (h *messageStreamHandle) waitCreated(ctx context.Context) string {
+	for {
+		cs := h.node.state.Get(h.node.localID)
+		if cs == nil {
+			return ""
+		}
+		if cs.Created && cs.ShelleyConversationID != "" {
+			return cs.ShelleyConversationID
+		}
+		if !sleepCtx(ctx, streamPollInterval) {
+			return ""
+		}
+	}
+}
+
+// poll fetches the conversation every streamPollInterval, for backends
+// without a stream endpoint.
+func (h *messageStreamHandle) poll(ctx context.Context, conversationID string) {
+	for sleepCtx(ctx, streamPollInterval) {
+		convData, err := h.node.client.GetConversation(conversationID)
+		if err != nil {
+			continue
+		}
+		msgs, _, err := h.node.parsedCache.GetOrParse(conversationID, convData)
+		if err != nil {
+			continue
+		}
+		h.add(msgs)
+	}
+}
+
+// sleepCtx waits for d and reports whether ctx is still live afterwards.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,113 @@
+package fuse
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func streamTestMessages(convID string) []shelley.Message {
+	return []shelley.Message{
+		{MessageID: "m1", ConversationID: convID, SequenceID: 1, Type: "user", UserData: strPtr("before open")},
+	}
+}
+
+// readStream reads once from h and returns what it got.
+func readStream(t *testing.T, ctx context.Context, h *messageStreamHandle) (string, syscall.Errno) {
+	t.Helper()
+	res, errno := h.Read(ctx, make([]byte, 64*1024), 0)
+	if errno != 0 {
+		return "", errno
+	}
+	data, _ := res.Bytes(nil)
+	return string(data), 0
+}
+
+func testMessageStream(t *testing.T, opts ...mockserver.Option) {
+	convID := "conv-stream"
+	server := mockserver.New(append([]mockserver.Option{mockserver.WithConversation(convID, streamTestMessages(convID))}, opts...)...)
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt(convID)
+	n := &MessageStreamNode{localID: localID, client: shelley.NewClient(server.URL), state: store, parsedCache: NewParsedMessageCache()}
+
+	fh, flags, errno := n.Open(context.Background(), syscall.O_RDONLY)
+	if errno != 0 {
+		t.Fatalf("Open: %v", errno)
+	}
+	h := fh.(*messageStreamHandle)
+	defer h.Release(context.Background())
+	if flags&fuse.FOPEN_NONSEEKABLE == 0 {
+		t.Errorf("Open flags = %#x, want FOPEN_NONSEEKABLE", flags)
+	}
+
+	server.AddMessage(convID, shelley.Message{MessageID: "m2", ConversationID: convID, SequenceID: 2, Type: "shelley", LLMData: strPtr(`{"Content": [{"Type": 2, "Text": "after open"}]}`)})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got, errno := readStream(t, ctx, h)
+	if errno != 0 {
+		t.Fatalf("Read: %v", errno)
+	}
+	if !strings.Contains(got, "after open") || strings.Contains(got, "before open") {
+		t.Errorf("stream = %q, want only the message added after open", got)
+	}
+
+	// Nothing new: the read blocks until interrupted.
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, errno := readStream(t, ctx, h); errno != syscall.EINTR {
+		t.Errorf("Read with nothing new = %v, want EINTR", errno)
+	}
+}
+
+func TestMessageStream(t *testing.T) {
+	testMessageStream(t, mockserver.WithStreaming())
+}
+
+func TestMessageStreamPolls(t *testing.T) {
+	// Without the stream endpoint, the conversation is fetched until the
+	// new message shows up.
+	testMessageStream(t)
+}
+
+func TestMessageStreamRejectsWrites(t *testing.T) {
+	store := testStore(t)
+	localID, _ := store.Adopt("conv-stream")
+	n := &MessageStreamNode{localID: localID, state: store, parsedCache: NewParsedMessageCache()}
+	if _, _, errno := n.Open(context.Background(), syscall.O_WRONLY); errno != syscall.EACCES {
+		t.Errorf("Open(O_WRONLY) = %v, want EACCES", errno)
+	}
+}
+
+func TestMessageStreamFile(t *testing.T) {
+	convID := "conv-stream"
+	server := mockserver.New(mockserver.WithConversation(convID, streamTestMessages(convID)), mockserver.WithStreaming())
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.Adopt(convID)
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	f, err := os.Open(filepath.Join(mountPoint, "conversation", localID, "messages", "stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	server.AddMessage(convID, shelley.Message{MessageID: "m2", ConversationID: convID, SequenceID: 2, Type: "user", UserData: strPtr("streamed")})
+	buf := make([]byte, 4096)
+	n, err := f.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); !strings.Contains(got, "## user") || !strings.Contains(got, "streamed") {
+		t.Errorf("stream = %q, want the new user message", got)
+	}
+}
@@ -0,0 +1,36 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/shelley"
+)
+
+func TestAbsoluteSymlinks(t *testing.T) {
+	server := mockModelsServer(t, []shelley.Model{{ID: "claude-haiku", Ready: true}})
+	defer server.Close()
+	store := testStore(t)
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetModelAliases(map[string]string{"fast": "claude-haiku"})
+	shelleyFS.SetAbsoluteSymlinks("/mnt/shelley")
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	clone, _ := store.Clone()
+
+	for link, want := range map[string]string{
+		"new":                            "/mnt/shelley/model/default/new",
+		"model/fast":                     "/mnt/shelley/model/claude-haiku",
+		"conversation/.pending/" + clone: "/mnt/shelley/conversation/" + clone,
+	} {
+		target, err := os.Readlink(filepath.Join(mountPoint, link))
+		if err != nil || target != want {
+			t.Errorf("readlink %s = %q, %v; want %q", link, target, err, want)
+		}
+		if fi, err := os.Lstat(filepath.Join(mountPoint, link)); err != nil || fi.Size() != int64(len(want)) {
+			t.Errorf("lstat %s: %v, %v; want size %d", link, fi, err, len(want))
+		}
+	}
+}
@@ -58,7 +58,12 @@ func mountTestFSWithServer(t *testing.T, server *mockserver.Server, store *state
 	t.Helper()
 
 	client := shelley.NewClient(server.URL)
-	shelleyFS := NewFS(client, store, time.Hour)
+	return mountFS(t, NewFS(client, store, time.Hour))
+}
+
+// mountFS mounts an already configured FS in a temporary directory.
+func mountFS(t *testing.T, shelleyFS *FS) (string, func()) {
+	t.Helper()
 
 	tmpDir, err := ioutil.TempDir("", "shelley-fuse-test")
 	if err != nil {
@@ -0,0 +1,216 @@
+package fuse
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- Conversation titles ---
+// Slugs are often missing, or say little ("brave-otter-42"). Every created
+// conversation has a title file: the title the backend generated for it if
+// it reports one, otherwise an excerpt of the first user message. With
+// SetTitleLinks, /conversation/by-title/ also holds a symlink per created
+// conversation named after its title.
+
+// titleExcerptRunes is roughly how long a title taken from a message is.
+const titleExcerptRunes = 60
+
+// conversationTitle returns the title of a conversation from its detail
+// JSON and parsed messages, or "" if it has neither a backend title nor a
+// user message with text.
+func conversationTitle(detail []byte, messages []shelley.Message) string {
+	var conv shelley.Conversation
+	if json.Unmarshal(detail, &conv) == nil && conv.Title != nil {
+		if title := strings.Join(strings.Fields(*conv.Title), " "); title != "" {
+			return title
+		}
+	}
+	for i := range messages {
+		m := &messages[i]
+		if shelley.MessageSlug(m, nil) != "user" {
+			continue
+		}
+		if text := strings.Join(strings.Fields(shelley.MessageText(m)), " "); text != "" {
+			return excerpt(text, titleExcerptRunes)
+		}
+	}
+	return ""
+}
+
+// excerpt shortens s to about max runes, cutting at the last space before
+// the limit when there is one, and marks the cut with an ellipsis.
+func excerpt(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	cut := []rune(s)[:max]
+	if i := strings.LastIndexByte(string(cut), ' '); i > 0 {
+		return string(cut)[:i] + "…"
+	}
+	return string(cut) + "…"
+}
+
+// titleOf fetches the title of the conversation cs, returning "" if it
+// isn't created or its messages can't be fetched.
+func titleOf(client shelley.ShelleyClient, parsedCache *ParsedMessageCache, cs *state.ConversationState) string {
+	if cs == nil || !cs.Created || cs.ShelleyConversationID == "" {
+		return ""
+	}
+	convData, err := client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return ""
+	}
+	result, err := parsedCache.GetOrParseResult(cs.ShelleyConversationID, convData)
+	if err != nil {
+		return ""
+	}
+	return conversationTitle(convData, result.Messages)
+}
+
+// --- ConvTitleNode: /conversation/{id}/title ---
+// The conversation's title and a newline. Backends may generate a title
+// some time into the conversation, so it is read fresh on every open.
+
+type ConvTitleNode struct {
+	fs.Inode
+	localID     string
+	client      shelley.ShelleyClient
+	state       *state.Store
+	startTime   time.Time
+	parsedCache *ParsedMessageCache
+	diag        *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*ConvTitleNode)(nil))
+var _ = (fs.NodeReader)((*ConvTitleNode)(nil))
+var _ = (fs.NodeGetattrer)((*ConvTitleNode)(nil))
+
+func (n *ConvTitleNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return nil, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *ConvTitleNode) Read(ctx context.Context, fh fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	defer diag.Track(n.diag, "ConvTitleNode", "Read", n.localID).Done()
+	return fuse.ReadResultData(readAt(n.data(), dest, off)), 0
+}
+
+// data returns the file's content, empty if the conversation has no title
+// yet.
+func (n *ConvTitleNode) data() []byte {
+	title := titleOf(n.client, n.parsedCache, n.state.Get(n.localID))
+	if title == "" {
+		return nil
+	}
+	return []byte(title + "\n")
+}
+
+func (n *ConvTitleNode) Getattr(ctx context.Context, fh fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(len(n.data()))
+	cs := n.state.Get(n.localID)
+	if cs != nil && !cs.CreatedAt.IsZero() {
+		setTimestamps(&out.Attr, cs.CreatedAt)
+	} else {
+		setTimestamps(&out.Attr, n.startTime)
+	}
+	return 0
+}
+
+// --- TitleLinksDirNode: /conversation/by-title/ ---
+// One symlink per created conversation with a title, named after the title
+// and pointing to the conversation's directory. Titles are made into valid
+// names by replacing "/" and dropping leading dots; when two conversations
+// end up with the same name, the later one gets its local ID appended:
+// "Fix the build (a1b2c3d4)". Listing or looking up a name fetches every
+// conversation, so it is slow with many of them.
+
+type TitleLinksDirNode struct {
+	fs.Inode
+	list *ConversationListNode
+}
+
+var _ = (fs.NodeLookuper)((*TitleLinksDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*TitleLinksDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*TitleLinksDirNode)(nil))
+
+// titleLinkName turns a title into a directory entry name, or "" if
+// nothing usable is left of it.
+func titleLinkName(title string) string {
+	name := strings.TrimLeft(strings.ReplaceAll(title, "/", "∕"), ".")
+	name = strings.ReplaceAll(name, "\x00", "")
+	return excerpt(strings.TrimSpace(name), titleExcerptRunes)
+}
+
+// links returns the local ID each entry name refers to, and the names in
+// the order conversations were created.
+func (n *TitleLinksDirNode) links() (map[string]string, []string) {
+	mappings := n.list.state.ListMappings()
+	sort.SliceStable(mappings, func(i, j int) bool {
+		return mappings[i].CreatedAt.Before(mappings[j].CreatedAt)
+	})
+	byName := make(map[string]string)
+	var names []string
+	for i := range mappings {
+		cs := &mappings[i]
+		name := titleLinkName(titleOf(n.list.client, n.list.parsedCache, cs))
+		if name == "" {
+			continue
+		}
+		if _, taken := byName[name]; taken {
+			name += " (" + cs.LocalID + ")"
+		}
+		byName[name] = cs.LocalID
+		names = append(names, name)
+	}
+	return byName, names
+}
+
+func (n *TitleLinksDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(n.list.diag, "TitleLinksDirNode", "Lookup", name).Done()
+	byName, _ := n.links()
+	localID, ok := byName[name]
+	cs := n.list.state.Get(localID)
+	if !ok || cs == nil {
+		return nil, syscall.ENOENT
+	}
+	out.SetEntryTimeout(0) // titles can change under the same name
+	target := "../" + n.list.dirName(cs)
+	return n.NewInode(ctx, &SymlinkNode{target: target, startTime: n.list.symlinkTime(localID)}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+}
+
+func (n *TitleLinksDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(n.list.diag, "TitleLinksDirNode", "Readdir", "").Done()
+	_, names := n.links()
+	entries := make([]fuse.DirEntry, len(names))
+	for i, name := range names {
+		entries[i] = fuse.DirEntry{Name: name, Mode: syscall.S_IFLNK}
+	}
+	return fs.NewListDirStream(entries), 0
+}
+
+func (n *TitleLinksDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.list.startTime)
+	return 0
+}
+
+// titleLinks reports whether c belongs to a mount set up with
+// SetTitleLinks.
+func (c *ConversationListNode) titleLinks() bool {
+	root := rootFS(c.EmbeddedInode())
+	return root != nil && root.titleLinks
+}
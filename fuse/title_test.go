@@ -0,0 +1,85 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestConversationTitle(t *testing.T) {
+	long := "Please  look at why the nightly build\nfails on arm64 and fix whatever is wrong with the linker flags"
+	server := mockserver.New(
+		mockserver.WithConversation("conv-excerpt", []shelley.Message{
+			{MessageID: "m1", ConversationID: "conv-excerpt", SequenceID: 1, Type: "user", UserData: &long},
+		}),
+		mockserver.WithConversationRawDetail(shelley.Conversation{ConversationID: "conv-titled"},
+			[]byte(`{"title":"Nightly arm64 build","messages":[]}`)),
+	)
+	defer server.Close()
+	store := testStore(t)
+	excerptID, _ := store.AdoptWithSlug("conv-excerpt", "")
+	titledID, _ := store.AdoptWithSlug("conv-titled", "")
+	clone, _ := store.Clone()
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+
+	for id, want := range map[string]string{
+		excerptID: "Please look at why the nightly build fails on arm64 and fix…\n",
+		titledID:  "Nightly arm64 build\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(convDir, id, "title"))
+		if err != nil || string(data) != want {
+			t.Errorf("%s/title = %q, %v; want %q", id, data, err, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(convDir, clone, "title")); !os.IsNotExist(err) {
+		t.Errorf("title of an uncreated conversation: got %v, want ENOENT", err)
+	}
+	if _, err := os.Stat(filepath.Join(convDir, "by-title")); !os.IsNotExist(err) {
+		t.Errorf("by-title without SetTitleLinks: got %v, want ENOENT", err)
+	}
+}
+
+func TestTitleLinks(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithConversationRawDetail(shelley.Conversation{ConversationID: "conv-a"}, []byte(`{"title":"Fix CI/CD","messages":[]}`)),
+		mockserver.WithConversationRawDetail(shelley.Conversation{ConversationID: "conv-b"}, []byte(`{"title":"Fix CI/CD","messages":[]}`)),
+		mockserver.WithConversationRawDetail(shelley.Conversation{ConversationID: "conv-c"}, []byte(`{"title":"..hidden?","messages":[]}`)),
+	)
+	defer server.Close()
+	store := testStore(t)
+	a, _ := store.AdoptWithSlug("conv-a", "")
+	time.Sleep(time.Millisecond) // keep the creation order unambiguous
+	b, _ := store.AdoptWithSlug("conv-b", "")
+	c, _ := store.AdoptWithSlug("conv-c", "")
+	shelleyFS := NewFS(shelley.NewClient(server.URL), store, time.Hour)
+	shelleyFS.SetTitleLinks(true)
+	mountPoint, cleanup := mountFS(t, shelleyFS)
+	defer cleanup()
+	byTitle := filepath.Join(mountPoint, "conversation", "by-title")
+
+	want := map[string]string{
+		"Fix CI∕CD":             "../" + a,
+		"Fix CI∕CD (" + b + ")": "../" + b,
+		"hidden?":               "../" + c,
+	}
+	names := listDir(t, byTitle)
+	if len(names) != len(want) {
+		t.Errorf("by-title lists %q, want %d entries", names, len(want))
+	}
+	for name, target := range want {
+		got, err := os.Readlink(filepath.Join(byTitle, name))
+		if err != nil || got != target {
+			t.Errorf("readlink by-title/%s = %q, %v; want %q", name, got, err, target)
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(byTitle, "hidden?", "title")); err != nil || strings.TrimSpace(string(data)) != "..hidden?" {
+		t.Errorf("title through the link = %q, %v", data, err)
+	}
+}
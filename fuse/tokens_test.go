@@ -0,0 +1,41 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestMessagesTokensAndWords(t *testing.T) {
+	question, answer := "How big is the build cache?", "About 2 GB after a clean build."
+	server := mockserver.New(mockserver.WithConversation("conv-words", []shelley.Message{
+		{MessageID: "m1", ConversationID: "conv-words", SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: "m2", ConversationID: "conv-words", SequenceID: 2, Type: "shelley", LLMData: &answer},
+	}))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug("conv-words", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	msgDir := filepath.Join(mountPoint, "conversation", localID, "messages")
+
+	allMD, err := os.ReadFile(filepath.Join(msgDir, "all.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{
+		"words":  len(strings.Fields(string(allMD))),
+		"tokens": shelley.EstimateTokens(string(allMD)),
+	} {
+		data, err := os.ReadFile(filepath.Join(msgDir, name))
+		if err != nil || string(data) != strconv.Itoa(want)+"\n" {
+			t.Errorf("messages/%s = %q, %v; want %d", name, data, err, want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestToolResultDurationAndBytes(t *testing.T) {
+	convID := "conv-toolstats"
+	msgs := []shelley.Message{
+		{MessageID: "m1", ConversationID: convID, SequenceID: 1, Type: "shelley", LLMData: strPtr(`{"Content": [{"Type": 5, "ID": "tu_1", "ToolName": "bash"}, {"Type": 5, "ID": "tu_2", "ToolName": "bash"}]}`)},
+		{MessageID: "m2", ConversationID: convID, SequenceID: 2, Type: "user", UserData: strPtr(`{"Content": [{"Type": 6, "ToolUseID": "tu_1", "ToolUseStartTime": "2026-01-02T03:04:05Z", "ToolUseEndTime": "2026-01-02T03:04:05.5Z", "ToolResult": [{"Text": "hello"}]}]}`)},
+		{MessageID: "m3", ConversationID: convID, SequenceID: 3, Type: "user", UserData: strPtr(`{"Content": [{"Type": 6, "ToolUseID": "tu_2", "ToolResult": [{"Text": "untimed"}]}]}`)},
+	}
+	server := mockserver.New(mockserver.WithConversation(convID, msgs))
+	defer server.Close()
+	store := testStore(t)
+	localID, _ := store.AdoptWithSlug(convID, "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	msgDir := filepath.Join(mountPoint, "conversation", localID, "messages")
+
+	for path, want := range map[string]string{
+		"1-bash-result/duration_ms": "500\n",
+		"1-bash-result/bytes":       "5\n",
+		"2-bash-result/bytes":       "7\n",
+	} {
+		if data, err := os.ReadFile(filepath.Join(msgDir, path)); err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v; want %q", path, data, err, want)
+		}
+	}
+	for _, path := range []string{"2-bash-result/duration_ms", "0-bash-tool/bytes", "0-bash-tool/duration_ms"} {
+		if _, err := os.Stat(filepath.Join(msgDir, path)); !os.IsNotExist(err) {
+			t.Errorf("%s: got %v, want ENOENT", path, err)
+		}
+	}
+	names := listDir(t, filepath.Join(msgDir, "1-bash-result"))
+	found := map[string]bool{}
+	for _, name := range names {
+		found[name] = true
+	}
+	if !found["duration_ms"] || !found["bytes"] {
+		t.Errorf("1-bash-result lists %v, want duration_ms and bytes", names)
+	}
+}
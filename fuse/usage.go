@@ -0,0 +1,363 @@
+package fuse
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/fuse/diag"
+	"shelley-fuse/shelley"
+	"shelley-fuse/state"
+)
+
+// --- UsageBoard: token usage per conversation ---
+// Agent messages carry the backend's token accounting in usage_data. Every
+// time a conversation's messages are parsed (because something read them,
+// or the -poll-active poller refreshed them) their usage is summed up and
+// recorded here, so usage reports never fetch conversations themselves.
+// Conversations not loaded since the mount are missing until they are.
+
+// Usage is token usage as reported in a message's usage_data, or summed
+// over a conversation.
+type Usage struct {
+	InputTokens              int64   `json:"input_tokens"`
+	CacheCreationInputTokens int64   `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64   `json:"cache_read_input_tokens"`
+	OutputTokens             int64   `json:"output_tokens"`
+	CostUSD                  float64 `json:"cost_usd"`
+}
+
+// TotalTokens is every token the usage counts, cached input included.
+func (u Usage) TotalTokens() int64 {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens + u.OutputTokens
+}
+
+func (u *Usage) add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.CacheCreationInputTokens += o.CacheCreationInputTokens
+	u.CacheReadInputTokens += o.CacheReadInputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CostUSD += o.CostUSD
+}
+
+// TotalInputTokens is the input the usage counts, cached input included.
+func (u Usage) TotalInputTokens() int64 {
+	return u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+}
+
+// messageUsage decodes the usage_data of msg. It reports false for messages
+// without usage, or with usage the backend encoded differently.
+func messageUsage(msg *shelley.Message) (Usage, bool) {
+	var u Usage
+	if msg.UsageData == nil || *msg.UsageData == "" {
+		return u, false
+	}
+	if err := json.Unmarshal([]byte(*msg.UsageData), &u); err != nil {
+		return Usage{}, false
+	}
+	return u, true
+}
+
+// conversationUsage sums the usage_data of msgs. Messages without usage,
+// or with usage the backend encoded differently, count as nothing.
+func conversationUsage(msgs []shelley.Message) Usage {
+	var total Usage
+	for i := range msgs {
+		if u, ok := messageUsage(&msgs[i]); ok {
+			total.add(u)
+		}
+	}
+	return total
+}
+
+// UsageBoard holds the usage of each conversation as of its last parse, by
+// server conversation ID. A nil *UsageBoard records nothing.
+type UsageBoard struct {
+	mu      sync.Mutex
+	entries map[string]Usage
+	updated time.Time
+}
+
+// NewUsageBoard creates an empty board.
+func NewUsageBoard() *UsageBoard {
+	return &UsageBoard{entries: make(map[string]Usage)}
+}
+
+// Record replaces the usage of a conversation with that of msgs.
+func (b *UsageBoard) Record(conversationID string, msgs []shelley.Message) {
+	if b == nil {
+		return
+	}
+	u := conversationUsage(msgs)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if old, ok := b.entries[conversationID]; !ok || old != u {
+		b.updated = time.Now()
+	}
+	b.entries[conversationID] = u
+}
+
+// lastUpdate returns when the board last changed.
+func (b *UsageBoard) lastUpdate() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.updated
+}
+
+// conversationUsageEntry is the recorded usage of a conversation store
+// tracks.
+type conversationUsageEntry struct {
+	cs    *state.ConversationState
+	usage Usage
+}
+
+// byTotal returns the recorded usage of the conversations store tracks,
+// highest total first.
+func (b *UsageBoard) byTotal(store *state.Store) []conversationUsageEntry {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	var entries []conversationUsageEntry
+	for id, u := range b.entries {
+		localID := store.GetByShelleyID(id)
+		if localID == "" {
+			continue
+		}
+		if cs := store.Get(localID); cs != nil {
+			entries = append(entries, conversationUsageEntry{cs: cs, usage: u})
+		}
+	}
+	b.mu.Unlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		ti, tj := entries[i].usage.TotalTokens(), entries[j].usage.TotalTokens()
+		if ti != tj {
+			return ti > tj
+		}
+		return entries[i].cs.LocalID < entries[j].cs.LocalID
+	})
+	return entries
+}
+
+// --- TopDirNode: /conversation/top/ ---
+// Rankings of conversations. top/usage/{N}/ holds symlinks 1 to N to the N
+// conversations with the highest total token usage, so
+// `ls -l conversation/top/usage/10/` shows which sessions burn the budget.
+
+type TopDirNode struct {
+	fs.Inode
+	list *ConversationListNode // for the state, usage board and directory names
+}
+
+var _ = (fs.NodeLookuper)((*TopDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*TopDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*TopDirNode)(nil))
+
+func (n *TopDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if name != "usage" {
+		return nil, syscall.ENOENT
+	}
+	return n.NewInode(ctx, &TopUsageDirNode{list: n.list}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+func (n *TopDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	return fs.NewListDirStream([]fuse.DirEntry{{Name: "usage", Mode: fuse.S_IFDIR}}), 0
+}
+
+func (n *TopDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.list.startTime)
+	return 0
+}
+
+// --- TopUsageDirNode: /conversation/top/usage/ ---
+// Any positive number can be looked up; none are listed.
+
+type TopUsageDirNode struct {
+	fs.Inode
+	list *ConversationListNode
+}
+
+var _ = (fs.NodeLookuper)((*TopUsageDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*TopUsageDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*TopUsageDirNode)(nil))
+
+func (n *TopUsageDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	count, err := strconv.Atoi(name)
+	if err != nil || count < 1 || strconv.Itoa(count) != name {
+		return nil, syscall.ENOENT
+	}
+	return n.NewInode(ctx, &TopUsageNode{list: n.list, count: count}, fs.StableAttr{Mode: fuse.S_IFDIR}), 0
+}
+
+func (n *TopUsageDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	return fs.NewListDirStream(nil), 0
+}
+
+func (n *TopUsageDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.list.startTime)
+	return 0
+}
+
+// --- TopUsageNode: /conversation/top/usage/{N}/ ---
+
+type TopUsageNode struct {
+	fs.Inode
+	list  *ConversationListNode
+	count int
+}
+
+var _ = (fs.NodeLookuper)((*TopUsageNode)(nil))
+var _ = (fs.NodeReaddirer)((*TopUsageNode)(nil))
+var _ = (fs.NodeGetattrer)((*TopUsageNode)(nil))
+
+// ranked returns the conversations listed, highest usage first.
+func (n *TopUsageNode) ranked() []conversationUsageEntry {
+	entries := n.list.usage.byTotal(n.list.state)
+	if len(entries) > n.count {
+		entries = entries[:n.count]
+	}
+	return entries
+}
+
+func (n *TopUsageNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	defer diag.Track(n.list.diag, "TopUsageNode", "Lookup", name).Done()
+	rank, err := strconv.Atoi(name)
+	entries := n.ranked()
+	if err != nil || rank < 1 || rank > len(entries) {
+		return nil, syscall.ENOENT
+	}
+	cs := entries[rank-1].cs
+	target := "../../../" + n.list.dirName(cs)
+	out.SetEntryTimeout(0) // the ranking changes as conversations grow
+	return n.NewInode(ctx, &SymlinkNode{target: target, startTime: n.list.symlinkTime(cs.LocalID)}, fs.StableAttr{Mode: syscall.S_IFLNK}), 0
+}
+
+func (n *TopUsageNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	defer diag.Track(n.list.diag, "TopUsageNode", "Readdir", "").Done()
+	entries := n.ranked()
+	dirEntries := make([]fuse.DirEntry, len(entries))
+	for i := range entries {
+		dirEntries[i] = fuse.DirEntry{Name: strconv.Itoa(i + 1), Mode: syscall.S_IFLNK}
+	}
+	return fs.NewListDirStream(dirEntries), 0
+}
+
+func (n *TopUsageNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.list.startTime)
+	return 0
+}
+
+// --- UsageDirNode: /usage/ ---
+// Usage reports for the whole mount. usage.csv has a header and one row per
+// conversation with recorded usage, highest total first:
+//
+//	local_id,conversation_id,slug,model,input_tokens,cached_input_tokens,output_tokens,cost_usd
+//
+// cached_input_tokens adds up cache writes and reads; cost_usd is the
+// backend's own estimate, 0 if it reports none.
+
+type UsageDirNode struct {
+	fs.Inode
+	board     *UsageBoard
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeLookuper)((*UsageDirNode)(nil))
+var _ = (fs.NodeReaddirer)((*UsageDirNode)(nil))
+var _ = (fs.NodeGetattrer)((*UsageDirNode)(nil))
+
+func (n *UsageDirNode) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	if name != "usage.csv" {
+		return nil, syscall.ENOENT
+	}
+	return n.NewInode(ctx, &UsageCSVNode{board: n.board, state: n.state, startTime: n.startTime, diag: n.diag}, fs.StableAttr{Mode: fuse.S_IFREG}), 0
+}
+
+func (n *UsageDirNode) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	return fs.NewListDirStream([]fuse.DirEntry{{Name: "usage.csv", Mode: fuse.S_IFREG}}), 0
+}
+
+func (n *UsageDirNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	out.Mode = fuse.S_IFDIR | 0755
+	setTimestamps(&out.Attr, n.startTime)
+	return 0
+}
+
+// --- UsageCSVNode: /usage/usage.csv ---
+
+type UsageCSVNode struct {
+	fs.Inode
+	board     *UsageBoard
+	state     *state.Store
+	startTime time.Time
+	diag      *diag.Tracker
+}
+
+var _ = (fs.NodeOpener)((*UsageCSVNode)(nil))
+var _ = (fs.NodeGetattrer)((*UsageCSVNode)(nil))
+
+// Open renders the report once; the handle reads and sizes that snapshot.
+func (n *UsageCSVNode) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	defer diag.Track(n.diag, "UsageCSVNode", "Open", "").Done()
+	if flags&(syscall.O_WRONLY|syscall.O_RDWR) != 0 {
+		return nil, 0, syscall.EACCES
+	}
+	return &ConvContentFileHandle{content: n.board.csv(n.state), messageTime: n.mtime()}, fuse.FOPEN_DIRECT_IO, 0
+}
+
+func (n *UsageCSVNode) Getattr(ctx context.Context, f fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	if fg, ok := f.(fs.FileGetattrer); ok {
+		return fg.Getattr(ctx, out)
+	}
+	out.Mode = fuse.S_IFREG | 0444
+	out.Size = uint64(len(n.board.csv(n.state)))
+	setTimestamps(&out.Attr, n.mtime())
+	return 0
+}
+
+// mtime is when usage last changed.
+func (n *UsageCSVNode) mtime() time.Time {
+	if t := n.board.lastUpdate(); !t.IsZero() {
+		return t
+	}
+	return n.startTime
+}
+
+// csv renders usage.csv for the conversations store tracks.
+func (b *UsageBoard) csv(store *state.Store) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"local_id", "conversation_id", "slug", "model", "input_tokens", "cached_input_tokens", "output_tokens", "cost_usd"})
+	for _, e := range b.byTotal(store) {
+		u := e.usage
+		w.Write([]string{
+			e.cs.LocalID,
+			e.cs.ShelleyConversationID,
+			e.cs.Slug,
+			e.cs.Model,
+			strconv.FormatInt(u.InputTokens, 10),
+			strconv.FormatInt(u.CacheCreationInputTokens+u.CacheReadInputTokens, 10),
+			strconv.FormatInt(u.OutputTokens, 10),
+			strconv.FormatFloat(u.CostUSD, 'f', 4, 64),
+		})
+	}
+	w.Flush()
+	return buf.Bytes()
+}
@@ -0,0 +1,115 @@
+package fuse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+// usageTestMessages is a question and an answer that used the given tokens.
+func usageTestMessages(conversationID, usage string) []shelley.Message {
+	question, answer := "How much?", "This much."
+	return []shelley.Message{
+		{MessageID: conversationID + "-1", ConversationID: conversationID, SequenceID: 1, Type: "user", UserData: &question},
+		{MessageID: conversationID + "-2", ConversationID: conversationID, SequenceID: 2, Type: "shelley", UserData: &answer, UsageData: &usage},
+	}
+}
+
+func TestTopUsage(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithConversation("conv-small", usageTestMessages("conv-small", `{"input_tokens":100,"output_tokens":20}`)),
+		mockserver.WithConversation("conv-big", usageTestMessages("conv-big", `{"input_tokens":50,"cache_read_input_tokens":5000,"output_tokens":300}`)),
+		mockserver.WithConversation("conv-unread", usageTestMessages("conv-unread", `{"input_tokens":99999}`)),
+	)
+	defer server.Close()
+	store := testStore(t)
+	small, _ := store.AdoptWithSlug("conv-small", "")
+	big, _ := store.AdoptWithSlug("conv-big", "")
+	store.AdoptWithSlug("conv-unread", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	convDir := filepath.Join(mountPoint, "conversation")
+
+	for _, id := range []string{small, big} {
+		if _, err := os.ReadFile(filepath.Join(convDir, id, "messages", "all.md")); err != nil {
+			t.Fatalf("read %s: %v", id, err)
+		}
+	}
+
+	// Only conversations whose messages were loaded are ranked.
+	if names := listDir(t, filepath.Join(convDir, "top", "usage", "10")); len(names) != 2 {
+		t.Errorf("top/usage/10 = %v, want 2 entries", names)
+	}
+	for rank, want := range map[string]string{"1": big, "2": small} {
+		target, err := os.Readlink(filepath.Join(convDir, "top", "usage", "10", rank))
+		if err != nil || target != "../../../"+want {
+			t.Errorf("top/usage/10/%s -> %q, %v; want %s", rank, target, err, want)
+		}
+	}
+	if names := listDir(t, filepath.Join(convDir, "top", "usage", "1")); len(names) != 1 || names[0] != "1" {
+		t.Errorf("top/usage/1 = %v", names)
+	}
+	for _, name := range []string{"0", "-1", "01", "ten"} {
+		if _, err := os.Stat(filepath.Join(convDir, "top", "usage", name)); !os.IsNotExist(err) {
+			t.Errorf("top/usage/%s: got %v, want ENOENT", name, err)
+		}
+	}
+}
+
+func TestConversationUsage(t *testing.T) {
+	bad := "not json"
+	msgs := append(usageTestMessages("c", `{"input_tokens":10,"cache_creation_input_tokens":1,"cache_read_input_tokens":2,"output_tokens":3,"cost_usd":0.25}`),
+		usageTestMessages("c", `{"output_tokens":7,"cost_usd":0.5}`)...)
+	msgs = append(msgs, shelley.Message{SequenceID: 5, Type: "shelley", UsageData: &bad})
+	u := conversationUsage(msgs)
+	want := Usage{InputTokens: 10, CacheCreationInputTokens: 1, CacheReadInputTokens: 2, OutputTokens: 10, CostUSD: 0.75}
+	if u != want {
+		t.Errorf("conversationUsage() = %+v, want %+v", u, want)
+	}
+	if u.TotalTokens() != 23 {
+		t.Errorf("TotalTokens() = %d, want 23", u.TotalTokens())
+	}
+}
+
+func TestUsageCSV(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithConversation("conv-a", usageTestMessages("conv-a", `{"input_tokens":100,"cache_read_input_tokens":40,"cache_creation_input_tokens":2,"output_tokens":20,"cost_usd":0.0125}`)),
+		mockserver.WithConversation("conv-b", usageTestMessages("conv-b", `{"input_tokens":7,"output_tokens":3}`)),
+	)
+	defer server.Close()
+	store := testStore(t)
+	a, _ := store.AdoptWithMetadata("conv-a", "fix, the \"build\"", "", "", "fast", "")
+	b, _ := store.AdoptWithSlug("conv-b", "")
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+	csvPath := filepath.Join(mountPoint, "usage", "usage.csv")
+
+	data, err := os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := "local_id,conversation_id,slug,model,input_tokens,cached_input_tokens,output_tokens,cost_usd\n"
+	if string(data) != header {
+		t.Errorf("usage.csv before any conversation was loaded:\n%s", data)
+	}
+
+	for _, id := range []string{a, b} {
+		if _, err := os.ReadFile(filepath.Join(mountPoint, "conversation", id, "messages", "all.md")); err != nil {
+			t.Fatalf("read %s: %v", id, err)
+		}
+	}
+	data, err = os.ReadFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := header +
+		a + `,conv-a,"fix, the ""build""",fast,100,42,20,0.0125` + "\n" +
+		b + ",conv-b,,,7,0,3,0.0000\n"
+	if string(data) != want {
+		t.Errorf("usage.csv =\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,71 @@
+package fuse
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"shelley-fuse/mockserver"
+	"shelley-fuse/shelley"
+)
+
+func TestWebLinks(t *testing.T) {
+	server := mockserver.New(
+		mockserver.WithModels([]shelley.Model{{ID: "custom-1", DisplayName: "fast", Ready: true}}),
+		mockserver.WithConversation("conv-web", nil),
+	)
+	defer server.Close()
+	store := testStore(t)
+	adopted, _ := store.Adopt("conv-web")
+	clone, _ := store.Clone()
+	mountPoint, cleanup := mountFS(t, NewFS(shelley.NewClient(server.URL), store, time.Hour))
+	defer cleanup()
+
+	for path, want := range map[string]string{
+		filepath.Join("conversation", adopted, "web"): server.URL + "/c/conv-web\n",
+		filepath.Join("model", "fast", "web"):         server.URL + "/?model=custom-1\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(mountPoint, path))
+		if err != nil {
+			t.Errorf("read %s: %v", path, err)
+			continue
+		}
+		if string(data) != want {
+			t.Errorf("%s = %q, want %q", path, data, want)
+		}
+	}
+
+	// A clone has no server conversation to link to yet.
+	if _, err := os.Stat(filepath.Join(mountPoint, "conversation", clone, "web")); !os.IsNotExist(err) {
+		t.Errorf("web of an unconversed clone: got %v, want ENOENT", err)
+	}
+}
+
+// A backend on a unix socket has no address a browser could open.
+func TestWebLinksUnixSocket(t *testing.T) {
+	client := shelley.NewClient("unix:///run/shelley.sock")
+	store := testStore(t)
+	adopted, _ := store.Adopt("conv-web")
+
+	field := &ConvStatusFieldNode{localID: adopted, client: client, state: store, field: "web"}
+	if _, errno := field.data(); errno != syscall.ENOENT {
+		t.Errorf("conversation web = %v, want ENOENT", errno)
+	}
+	model := &ModelNode{model: shelley.Model{ID: "custom-1"}, client: client}
+	if _, errno := model.Lookup(context.Background(), "web", &fuse.EntryOut{}); errno != syscall.ENOENT {
+		t.Errorf("model web lookup = %v, want ENOENT", errno)
+	}
+	stream, errno := model.Readdir(context.Background())
+	if errno != 0 {
+		t.Fatal(errno)
+	}
+	for stream.HasNext() {
+		if e, _ := stream.Next(); e.Name == "web" {
+			t.Error("model directory lists web")
+		}
+	}
+}
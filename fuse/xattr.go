@@ -0,0 +1,116 @@
+package fuse
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+)
+
+// xattrPrefix namespaces the xattr mirror of conversation and message
+// fields.
+const xattrPrefix = "user.shelley."
+
+// getXattr answers a getxattr for user.shelley.{field} from the values
+// fields returns. Other attributes, such as the security.* ones ls -l asks
+// for, are refused without computing the fields.
+func getXattr(attr string, dest []byte, fields func() ([]string, map[string]string)) (uint32, syscall.Errno) {
+	name, ok := strings.CutPrefix(attr, xattrPrefix)
+	if !ok {
+		return 0, syscall.ENODATA
+	}
+	_, values := fields()
+	value, ok := values[name]
+	if !ok {
+		return 0, syscall.ENODATA
+	}
+	if len(dest) < len(value) {
+		return uint32(len(value)), syscall.ERANGE
+	}
+	return uint32(copy(dest, value)), 0
+}
+
+// listXattr answers a listxattr with user.shelley.{field} for each name.
+func listXattr(names []string, dest []byte) (uint32, syscall.Errno) {
+	var buf []byte
+	for _, name := range names {
+		buf = append(buf, xattrPrefix+name...)
+		buf = append(buf, 0)
+	}
+	if len(dest) < len(buf) {
+		return uint32(len(buf)), syscall.ERANGE
+	}
+	return uint32(copy(dest, buf)), 0
+}
+
+// --- ConversationNode xattrs ---
+// The conversation directory mirrors its metadata as user.shelley.{field},
+// so getfattr -d reads a conversation's ID, slug, model and times, and its
+// token usage, in one go instead of opening a file for each.
+
+var _ = (fs.NodeGetxattrer)((*ConversationNode)(nil))
+var _ = (fs.NodeListxattrer)((*ConversationNode)(nil))
+
+// xattrFields returns the conversation fields mirrored as xattrs, in
+// listing order. Fields the conversation doesn't have yet, such as the
+// server ID before creation, are left out. The timestamps are the ones the
+// state has from the server's list; usage comes from the same cached
+// conversation data as the files.
+func (c *ConversationNode) xattrFields() ([]string, map[string]string) {
+	cs := c.state.Get(c.localID)
+	if cs == nil {
+		return nil, nil
+	}
+	names := []string{"local_id"}
+	values := map[string]string{"local_id": c.localID}
+	add := func(name, value string) {
+		if value == "" {
+			return
+		}
+		names = append(names, name)
+		values[name] = value
+	}
+	add("conversation_id", cs.ShelleyConversationID)
+	add("slug", cs.Slug)
+	add("model", cs.Model)
+	add("cwd", cs.Cwd)
+	if !cs.Created || cs.ShelleyConversationID == "" {
+		return names, values
+	}
+
+	// The server's timestamps come from its conversation list, as for the
+	// directory's own times; the conversation detail doesn't carry them.
+	add("created_at", cs.APICreatedAt)
+	if secs, ok := unixSeconds(cs.APICreatedAt); ok {
+		add("created_at_unix", secs)
+	}
+	add("updated_at", cs.APIUpdatedAt)
+	if secs, ok := unixSeconds(cs.APIUpdatedAt); ok {
+		add("updated_at_unix", secs)
+	}
+
+	convData, err := c.client.GetConversation(cs.ShelleyConversationID)
+	if err != nil {
+		return names, values
+	}
+	msgs, _, err := c.parsedCache.GetOrParse(cs.ShelleyConversationID, convData)
+	if err == nil {
+		u := conversationUsage(msgs)
+		add("message_count", strconv.Itoa(len(msgs)))
+		add("input_tokens", strconv.FormatInt(u.TotalInputTokens(), 10))
+		add("output_tokens", strconv.FormatInt(u.OutputTokens, 10))
+	}
+	return names, values
+}
+
+// Getxattr returns a conversation field as user.shelley.{field}.
+func (c *ConversationNode) Getxattr(ctx context.Context, attr string, dest []byte) (uint32, syscall.Errno) {
+	return getXattr(attr, dest, c.xattrFields)
+}
+
+func (c *ConversationNode) Listxattr(ctx context.Context, dest []byte) (uint32, syscall.Errno) {
+	names, _ := c.xattrFields()
+	return listXattr(names, dest)
+}
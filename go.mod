@@ -4,9 +4,9 @@ go 1.24.0
 
 toolchain go1.24.12
 
-require github.com/hanwen/go-fuse/v2 v2.9.0
-
 require (
-	golang.org/x/sync v0.19.0 // indirect
-	golang.org/x/sys v0.28.0 // indirect
+	github.com/hanwen/go-fuse/v2 v2.9.0
+	golang.org/x/sync v0.19.0
 )
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,143 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// listen starts a fake journald on a temporary unixgram socket.
+func listen(t *testing.T) (string, *net.UnixConn) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return path, conn
+}
+
+// receive reads one datagram and decodes the simple NAME=value form.
+func receive(t *testing.T, conn *net.UnixConn) map[string]string {
+	t.Helper()
+	buf := make([]byte, 65536)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSuffix(string(buf[:n]), "\n"), "\n") {
+		k, v, _ := strings.Cut(line, "=")
+		fields[k] = v
+	}
+	return fields
+}
+
+func TestWriterSendsStructuredEntry(t *testing.T) {
+	path, conn := listen(t)
+	w, err := Dial(path, "shelley-fuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.KnownConversation = func(id string) bool { return id == "abcd1234" }
+
+	logger := log.New(w, "", 0)
+	logger.Printf("SetMeta failed for abcd1234/key: boom")
+
+	got := receive(t, conn)
+	if got["MESSAGE"] != "SetMeta failed for abcd1234/key: boom" {
+		t.Errorf("MESSAGE = %q", got["MESSAGE"])
+	}
+	if got["PRIORITY"] != "3" {
+		t.Errorf("PRIORITY = %q, want 3", got["PRIORITY"])
+	}
+	if got["SYSLOG_IDENTIFIER"] != "shelley-fuse" {
+		t.Errorf("SYSLOG_IDENTIFIER = %q", got["SYSLOG_IDENTIFIER"])
+	}
+	if got["CONVERSATION_ID"] != "abcd1234" {
+		t.Errorf("CONVERSATION_ID = %q, want abcd1234", got["CONVERSATION_ID"])
+	}
+}
+
+func TestWithPriority(t *testing.T) {
+	path, conn := listen(t)
+	w, err := Dial(path, "shelley-fuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	debug := w.WithPriority(PriDebug, map[string]string{"SUBSYSTEM": "go-fuse"})
+	debug.Write([]byte("rx 12: LOOKUP failed\n"))
+
+	got := receive(t, conn)
+	if got["PRIORITY"] != "7" {
+		t.Errorf("PRIORITY = %q, want 7", got["PRIORITY"])
+	}
+	if got["SUBSYSTEM"] != "go-fuse" {
+		t.Errorf("SUBSYSTEM = %q", got["SUBSYSTEM"])
+	}
+	if _, ok := got["CONVERSATION_ID"]; ok {
+		t.Error("unexpected CONVERSATION_ID without KnownConversation")
+	}
+}
+
+func TestConversationIDIgnoresUUIDs(t *testing.T) {
+	w := &Writer{KnownConversation: func(string) bool { return true }}
+	if id := w.conversationID("fetching 12345678-9abc-def0-1234-56789abcdef0"); id != "" {
+		t.Errorf("matched UUID segment %q", id)
+	}
+	if id := w.conversationID("conversation deadbeef adopted"); id != "deadbeef" {
+		t.Errorf("conversationID = %q, want deadbeef", id)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want Priority
+	}{
+		{"Using backend URL: http://localhost:9999", PriInfo},
+		{"GetConversation failed for abcd1234: timeout", PriErr},
+		{"json error: unexpected EOF", PriErr},
+		{"Skipping mapping x -> y", PriWarning},
+		{"recovered panic in Read", PriCrit},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.msg); got != tt.want {
+			t.Errorf("Classify(%q) = %d, want %d", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeMultilineValue(t *testing.T) {
+	data := encode(PriInfo, "", "line1\nline2", nil)
+	prefix := "PRIORITY=6\nMESSAGE\n"
+	if !bytes.HasPrefix(data, []byte(prefix)) {
+		t.Fatalf("unexpected encoding: %q", data)
+	}
+	rest := data[len(prefix):]
+	if n := binary.LittleEndian.Uint64(rest[:8]); n != uint64(len("line1\nline2")) {
+		t.Errorf("length prefix = %d", n)
+	}
+	if string(rest[8:]) != "line1\nline2\n" {
+		t.Errorf("value = %q", rest[8:])
+	}
+}
+
+func TestEnabledWithoutJournalStream(t *testing.T) {
+	t.Setenv("JOURNAL_STREAM", "")
+	if Enabled() {
+		t.Error("Enabled() = true without JOURNAL_STREAM")
+	}
+	t.Setenv("JOURNAL_STREAM", "0:0")
+	if Enabled() {
+		t.Error("Enabled() = true for a stream that is not stderr")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"shelley-fuse/shelley"
 )
@@ -62,6 +63,42 @@ type Server struct {
 
 	// requestHook, if set, is called on every request before routing.
 	requestHook func(r *http.Request)
+
+	// mappings backs GET/PUT /api/fuse/mappings. The endpoint returns 404
+	// unless mappingsEnabled is set (see WithMappings).
+	mappings        []shelley.MappingRecord
+	mappingsEnabled bool
+
+	// editingEnabled turns on POST /api/conversation/{id}/messages/{mid}/edit
+	// (see WithMessageEditing); without it the endpoint is a 404.
+	editingEnabled bool
+
+	// settingsEnabled turns on POST /api/conversation/{id}/settings (see
+	// WithLiveSettings); temperatures holds what was set through it.
+	settingsEnabled bool
+	temperatures    map[string]float64
+
+	// capabilities, if set, is served from GET /api/capabilities (see
+	// WithCapabilities); without it the endpoint is a 404.
+	capabilities *shelley.Capabilities
+
+	// importEnabled turns on POST /api/conversations/import (see
+	// WithImport); without it the endpoint is a 404.
+	importEnabled bool
+	imported      int
+
+	// streamingEnabled turns on GET /api/conversation/{id}/stream (see
+	// WithStreaming); changed is closed and replaced by AddMessage to wake
+	// up open streams.
+	streamingEnabled bool
+	changed          chan struct{}
+
+	// streamedReply, if set, makes POST /api/conversation/{id}/chat write
+	// an agent reply in pieces (see WithStreamingChat). Replies still being
+	// written stop when quit is closed by Close.
+	streamedReply *StreamedReply
+	quit          chan struct{}
+	closeOnce     sync.Once
 }
 
 type conversationData struct {
@@ -168,6 +205,87 @@ func WithRequestHook(h func(r *http.Request)) Option {
 	}
 }
 
+// WithMappings enables the /api/fuse/mappings endpoint, seeded with records.
+// Pass nil to enable it with an empty table.
+func WithMappings(records []shelley.MappingRecord) Option {
+	return func(s *Server) {
+		s.mappings = records
+		s.mappingsEnabled = true
+	}
+}
+
+// WithMessageEditing enables the message edit endpoint. An edit replaces
+// the message's user_data with the text sent.
+func WithMessageEditing() Option {
+	return func(s *Server) {
+		s.editingEnabled = true
+	}
+}
+
+// WithLiveSettings enables the conversation settings endpoint. A model
+// change is refused with 409 while the conversation is working.
+func WithLiveSettings() Option {
+	return func(s *Server) {
+		s.settingsEnabled = true
+	}
+}
+
+// WithCapabilities enables GET /api/capabilities, reporting version and
+// features. It doesn't turn the endpoints of the features on or off.
+func WithCapabilities(version string, features ...string) Option {
+	return func(s *Server) {
+		s.capabilities = &shelley.Capabilities{Version: version, Features: features}
+	}
+}
+
+// WithImport enables POST /api/conversations/import. Imported conversations
+// are served like the others, as conversations "imported-1", "imported-2"...
+func WithImport() Option {
+	return func(s *Server) {
+		s.importEnabled = true
+	}
+}
+
+// WithStreaming enables GET /api/conversation/{id}/stream, a server-sent
+// event stream that sends the conversation's messages, then every message
+// added with AddMessage.
+func WithStreaming() Option {
+	return func(s *Server) {
+		s.streamingEnabled = true
+	}
+}
+
+// StreamedReply is how the agent replies to a chat message with
+// WithStreamingChat.
+type StreamedReply struct {
+	// Text is the reply; if empty, the agent echoes the message as
+	// "echo: {message}".
+	Text string
+	// Chunks is how many pieces the reply is written in; less than 1
+	// means 1.
+	Chunks int
+	// Interval is the pause before each piece, so a stream reader sees
+	// the reply grow over time.
+	Interval time.Duration
+}
+
+// WithStreamingChat simulates an agent writing its reply while streams
+// watch. POST /api/conversation/{id}/chat adds the user's message, marks
+// the conversation working and returns; the agent message then appears
+// with the reply's first piece after reply.Interval, and grows by one piece
+// every reply.Interval, each version going out on open streams, until the
+// reply is complete and the conversation stops working. It turns on
+// WithStreaming, and is overridden by WithChatHandler.
+func WithStreamingChat(reply StreamedReply) Option {
+	return func(s *Server) {
+		if reply.Chunks < 1 {
+			reply.Chunks = 1
+		}
+		s.streamingEnabled = true
+		s.streamedReply = &reply
+	}
+}
+
 // New creates and starts a mock Shelley backend server.
 // WithSubagent registers a child conversation (subagent) under a parent conversation.
 // Both parent and child must be registered via WithConversation or WithFullConversation.
@@ -181,6 +299,9 @@ func New(opts ...Option) *Server {
 	s := &Server{
 		conversations: make(map[string]conversationData),
 		subagents:     make(map[string][]string),
+		temperatures:  make(map[string]float64),
+		changed:       make(chan struct{}),
+		quit:          make(chan struct{}),
 	}
 	for _, opt := range opts {
 		opt(s)
@@ -199,6 +320,44 @@ func (s *Server) ResetFetchCount() {
 	atomic.StoreInt32(&s.fetchCount, 0)
 }
 
+// AddMessage appends a message to a registered conversation, as if the
+// agent had just written it, and sends it to open streams. A message with a
+// created_at moves the conversation's updated_at to it.
+func (s *Server) AddMessage(conversationID string, msg shelley.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cd, ok := s.conversations[conversationID]
+	if !ok {
+		return
+	}
+	cd.messages = append(cd.messages, msg)
+	if msg.CreatedAt != "" {
+		cd.conv.UpdatedAt = msg.CreatedAt
+	}
+	s.conversations[conversationID] = cd
+	s.changedLocked()
+}
+
+// changedLocked wakes up open streams. s.mu must be held.
+func (s *Server) changedLocked() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Close stops replies still being written (see WithStreamingChat) and shuts
+// the server down.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() { close(s.quit) })
+	s.Server.Close()
+}
+
+// Mappings returns the mapping table last stored via PUT /api/fuse/mappings.
+func (s *Server) Mappings() []shelley.MappingRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]shelley.MappingRecord(nil), s.mappings...)
+}
+
 func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
 	if s.requestHook != nil {
 		s.requestHook(r)
@@ -225,6 +384,13 @@ func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// GET /api/capabilities → version and feature list
+	if path == "/api/capabilities" && r.Method == "GET" && s.capabilities != nil {
+		data, _ := json.Marshal(s.capabilities)
+		w.Write(data)
+		return
+	}
+
 	// GET /api/conversations → conversation list
 	if path == "/api/conversations" && r.Method == "GET" {
 		s.mu.Lock()
@@ -248,6 +414,12 @@ func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// POST /api/conversations/import → create conversation with messages
+	if path == "/api/conversations/import" && r.Method == "POST" && s.importEnabled {
+		s.serveImport(w, r)
+		return
+	}
+
 	// POST /api/conversations/continue → continue conversation
 	if path == "/api/conversations/continue" && r.Method == "POST" {
 		if s.continueHandler != nil {
@@ -258,6 +430,12 @@ func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// GET/PUT /api/fuse/mappings → stored local-ID mapping table
+	if path == "/api/fuse/mappings" && s.mappingsEnabled {
+		s.serveMappings(w, r)
+		return
+	}
+
 	// GET /api/conversation/{id}/subagents → subagents list
 	if strings.HasPrefix(path, "/api/conversation/") && strings.HasSuffix(path, "/subagents") && r.Method == "GET" {
 		convID := strings.TrimPrefix(path, "/api/conversation/")
@@ -320,16 +498,38 @@ func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// POST /api/conversation/{id}/messages/{message_id}/edit → edit message text
+	if strings.HasSuffix(path, "/edit") && r.Method == "POST" && s.editingEnabled {
+		s.serveEdit(w, r)
+		return
+	}
+
+	// POST /api/conversation/{id}/settings → change model/temperature
+	if strings.HasSuffix(path, "/settings") && r.Method == "POST" && s.settingsEnabled {
+		s.serveSettings(w, r)
+		return
+	}
+
 	// POST /api/conversation/{id}/chat → send message
 	if strings.HasSuffix(path, "/chat") && r.Method == "POST" {
 		if s.chatHandler != nil {
 			s.chatHandler(w, r)
 			return
 		}
+		if s.streamedReply != nil {
+			s.serveStreamingChat(w, r)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
+	// GET /api/conversation/{id}/stream → server-sent message updates
+	if strings.HasSuffix(path, "/stream") && r.Method == "GET" && s.streamingEnabled {
+		s.serveStream(w, r)
+		return
+	}
+
 	// GET /api/conversation/{id} → conversation detail
 	if strings.HasPrefix(path, "/api/conversation/") && r.Method == "GET" {
 		convID := strings.TrimPrefix(path, "/api/conversation/")
@@ -353,6 +553,230 @@ func (s *Server) handler(w http.ResponseWriter, r *http.Request) {
 	http.NotFound(w, r)
 }
 
+// serveStream sends the conversation's messages, then every message that
+// is added or changes (as a reply being written does), as server-sent
+// events.
+func (s *Server) serveStream(w http.ResponseWriter, r *http.Request) {
+	convID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/conversation/"), "/stream")
+	flusher, _ := w.(http.Flusher)
+	var sent map[string]string // message ID → JSON as last sent; nil until the first event
+	for {
+		s.mu.Lock()
+		cd, ok := s.conversations[convID]
+		changed := s.changed
+		s.mu.Unlock()
+		if !ok {
+			if sent == nil {
+				http.NotFound(w, r)
+			}
+			return
+		}
+		first := sent == nil
+		if first {
+			w.Header().Set("Content-Type", "text/event-stream")
+			sent = make(map[string]string)
+		}
+		var update []shelley.Message
+		for _, msg := range cd.messages {
+			data, _ := json.Marshal(msg)
+			if sent[msg.MessageID] != string(data) {
+				sent[msg.MessageID] = string(data)
+				update = append(update, msg)
+			}
+		}
+		if first || len(update) > 0 {
+			data, _ := json.Marshal(shelley.StreamResponse{Messages: update})
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// serveStreamingChat adds the user's message and starts the agent's reply
+// (see WithStreamingChat).
+func (s *Server) serveStreamingChat(w http.ResponseWriter, r *http.Request) {
+	convID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/conversation/"), "/chat")
+	var req shelley.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cd, ok := s.conversations[convID]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	seq := 1
+	for _, msg := range cd.messages {
+		if msg.SequenceID >= seq {
+			seq = msg.SequenceID + 1
+		}
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	text := req.Message
+	cd.messages = append(cd.messages, shelley.Message{MessageID: fmt.Sprintf("%s-m%d", convID, seq), ConversationID: convID, SequenceID: seq, Type: "user", UserData: &text, CreatedAt: now})
+	cd.conv.Working = true
+	cd.conv.UpdatedAt = now
+	s.conversations[convID] = cd
+	s.changedLocked()
+
+	reply := s.streamedReply.Text
+	if reply == "" {
+		reply = "echo: " + req.Message
+	}
+	go s.writeReply(convID, seq+1, reply)
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeReply writes reply as agent message seq of the conversation, one
+// piece every s.streamedReply.Interval.
+func (s *Server) writeReply(convID string, seq int, reply string) {
+	runes := []rune(reply)
+	chunks := s.streamedReply.Chunks
+	for i := 1; i <= chunks; i++ {
+		select {
+		case <-time.After(s.streamedReply.Interval):
+		case <-s.quit:
+			return
+		}
+		content, _ := json.Marshal(map[string]any{"Content": []map[string]any{{"Type": 2, "Text": string(runes[:len(runes)*i/chunks])}}})
+		llm := string(content)
+		now := time.Now().UTC().Format(time.RFC3339)
+		s.mu.Lock()
+		cd, ok := s.conversations[convID]
+		if !ok {
+			s.mu.Unlock()
+			return
+		}
+		messages := append([]shelley.Message(nil), cd.messages...)
+		if i == 1 {
+			messages = append(messages, shelley.Message{MessageID: fmt.Sprintf("%s-m%d", convID, seq), ConversationID: convID, SequenceID: seq, Type: "shelley", CreatedAt: now})
+		}
+		for j := range messages {
+			if messages[j].SequenceID == seq {
+				messages[j].LLMData = &llm
+			}
+		}
+		cd.messages = messages
+		cd.conv.UpdatedAt = now
+		if i == chunks {
+			cd.conv.Working = false
+		}
+		s.conversations[convID] = cd
+		s.changedLocked()
+		s.mu.Unlock()
+	}
+}
+
+func (s *Server) serveEdit(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/conversation/"), "/edit")
+	convID, messageID, ok := strings.Cut(rest, "/messages/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	var req struct {
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cd, exists := s.conversations[convID]
+	if !exists {
+		http.NotFound(w, r)
+		return
+	}
+	messages := append([]shelley.Message(nil), cd.messages...)
+	for i := range messages {
+		if messages[i].MessageID == messageID {
+			text := req.Message
+			messages[i].UserData = &text
+			cd.messages = messages
+			s.conversations[convID] = cd
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+	http.Error(w, "message not found", http.StatusUnprocessableEntity)
+}
+
+func (s *Server) serveSettings(w http.ResponseWriter, r *http.Request) {
+	convID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/conversation/"), "/settings")
+	var req shelley.ConversationSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cd, exists := s.conversations[convID]
+	if !exists {
+		http.Error(w, "conversation not found", http.StatusUnprocessableEntity)
+		return
+	}
+	status := http.StatusOK
+	if req.Model != "" && cd.conv.Working {
+		status = http.StatusConflict
+	} else {
+		if req.Model != "" {
+			model := req.Model
+			cd.conv.Model = &model
+			s.conversations[convID] = cd
+		}
+		if req.Temperature != nil {
+			s.temperatures[convID] = *req.Temperature
+		}
+	}
+	var current shelley.ConversationSettings
+	if cd.conv.Model != nil {
+		current.Model = *cd.conv.Model
+	}
+	if t, ok := s.temperatures[convID]; ok {
+		current.Temperature = &t
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(current)
+}
+
+func (s *Server) serveMappings(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		s.mu.Lock()
+		records := s.mappings
+		s.mu.Unlock()
+		if records == nil {
+			records = []shelley.MappingRecord{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records)
+	case "PUT":
+		var records []shelley.MappingRecord
+		if err := json.NewDecoder(r.Body).Decode(&records); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		s.mappings = records
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // continueSeqNum is used to generate unique conversation IDs for continue operations.
 var continueSeqNum int32
 
@@ -395,6 +819,37 @@ func (s *Server) handleContinueDefault(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) serveImport(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Messages []shelley.ImportedMessage `json:"messages"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "messages are required")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.imported++
+	id := fmt.Sprintf("imported-%d", s.imported)
+	var msgs []shelley.Message
+	for i, m := range req.Messages {
+		msg := shelley.Message{MessageID: fmt.Sprintf("%s-m%d", id, i+1), ConversationID: id, SequenceID: i + 1, Type: m.Type}
+		text := m.Text
+		if m.Type == "user" {
+			msg.UserData = &text
+		} else {
+			content, _ := json.Marshal(map[string]any{"Content": []map[string]any{{"Type": 2, "Text": text}}})
+			llm := string(content)
+			msg.LLMData = &llm
+		}
+		msgs = append(msgs, msg)
+	}
+	s.conversations[id] = conversationData{conv: shelley.Conversation{ConversationID: id}, messages: msgs}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{"conversation_id": id})
+}
+
 func (s *Server) serveInit(w http.ResponseWriter, r *http.Request) {
 	defaultModelJSON, _ := json.Marshal(s.defaultModel)
 	w.Header().Set("Content-Type", "text/html")
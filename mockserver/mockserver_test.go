@@ -1,10 +1,14 @@
 package mockserver
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"shelley-fuse/shelley"
 )
@@ -107,3 +111,125 @@ func TestNew_NotFoundForUnknown(t *testing.T) {
 		t.Errorf("expected 404, got %d", resp.StatusCode)
 	}
 }
+
+func TestNew_Mappings(t *testing.T) {
+	s := New(WithMappings([]shelley.MappingRecord{{LocalID: "aaaa0001", ConversationID: "conv-1"}}))
+	defer s.Close()
+
+	client := shelley.NewClient(s.URL)
+	records, err := client.GetMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].LocalID != "aaaa0001" {
+		t.Fatalf("unexpected mappings: %+v", records)
+	}
+
+	put := []shelley.MappingRecord{{LocalID: "bbbb0002", ConversationID: "conv-2", Slug: "two"}}
+	if err := client.PutMappings(put); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Mappings(); len(got) != 1 || got[0].Slug != "two" {
+		t.Errorf("stored mappings = %+v", got)
+	}
+}
+
+func TestNew_MappingsDisabledByDefault(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	if _, err := shelley.NewClient(s.URL).GetMappings(); err != shelley.ErrMappingsUnsupported {
+		t.Errorf("expected ErrMappingsUnsupported, got %v", err)
+	}
+}
+
+func TestNew_Streaming(t *testing.T) {
+	s := New(WithConversation("conv-1", []shelley.Message{{MessageID: "m1", SequenceID: 1}}), WithStreaming())
+	defer s.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	batches := make(chan []shelley.Message, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- shelley.NewClient(s.URL).StreamConversation(ctx, "conv-1", func(msgs []shelley.Message) { batches <- msgs })
+	}()
+
+	if first := <-batches; len(first) != 1 || first[0].MessageID != "m1" {
+		t.Fatalf("first update = %+v, want m1", first)
+	}
+	s.AddMessage("conv-1", shelley.Message{MessageID: "m2", SequenceID: 2})
+	if next := <-batches; len(next) != 1 || next[0].MessageID != "m2" {
+		t.Fatalf("next update = %+v, want only m2", next)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("StreamConversation = %v, want context.Canceled", err)
+	}
+}
+
+func TestNew_StreamingChat(t *testing.T) {
+	s := New(WithConversation("conv-1", nil), WithStreamingChat(StreamedReply{Text: "abcdef", Chunks: 3, Interval: 30 * time.Millisecond}))
+	defer s.Close()
+	client := shelley.NewClient(s.URL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	batches := make(chan []shelley.Message, 16)
+	go client.StreamConversation(ctx, "conv-1", func(msgs []shelley.Message) { batches <- msgs })
+
+	if err := client.SendMessage("conv-1", "hi", ""); err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	// The reply grows piece by piece; a slow reader may see pieces merged,
+	// but never one that isn't a prefix of the next.
+	var replies []string
+	for len(replies) == 0 || replies[len(replies)-1] != "abcdef" {
+		select {
+		case msgs := <-batches:
+			for _, msg := range msgs {
+				switch msg.Type {
+				case "user":
+					if msg.UserData == nil || *msg.UserData != "hi" || msg.SequenceID != 1 {
+						t.Errorf("user message = %+v", msg)
+					}
+				case "shelley":
+					var llm struct{ Content []struct{ Text string } }
+					json.Unmarshal([]byte(*msg.LLMData), &llm)
+					text := llm.Content[0].Text
+					if len(replies) > 0 && !strings.HasPrefix(text, replies[len(replies)-1]) {
+						t.Fatalf("reply went from %q to %q", replies[len(replies)-1], text)
+					}
+					if msg.SequenceID != 2 {
+						t.Errorf("reply sequence_id = %d, want 2", msg.SequenceID)
+					}
+					replies = append(replies, text)
+				}
+			}
+		case <-ctx.Done():
+			t.Fatalf("reply incomplete after %q", replies)
+		}
+	}
+	if len(replies) < 2 {
+		t.Errorf("reply arrived in one piece: %q", replies)
+	}
+	convs, err := client.ListConversations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list []shelley.Conversation
+	json.Unmarshal(convs, &list)
+	if len(list) != 1 || list[0].Working {
+		t.Errorf("conversations = %+v, want conv-1 no longer working", list)
+	}
+}
+
+func TestNew_StreamingDisabledByDefault(t *testing.T) {
+	s := New(WithConversation("conv-1", nil))
+	defer s.Close()
+
+	err := shelley.NewClient(s.URL).StreamConversation(context.Background(), "conv-1", func([]shelley.Message) {})
+	if !errors.Is(err, shelley.ErrStreamingUnsupported) {
+		t.Errorf("StreamConversation = %v, want ErrStreamingUnsupported", err)
+	}
+}
@@ -0,0 +1,62 @@
+// Package sdnotify implements the sd_notify(3) protocol: it tells systemd
+// the service is ready, is stopping, or is still alive, by sending datagrams
+// to the socket named in $NOTIFY_SOCKET. Outside systemd (or without
+// Type=notify / WatchdogSec= in the unit) everything here is a no-op.
+//
+// Example usage:
+//
+//	sdnotify.Notify(sdnotify.Ready)
+//	if interval := sdnotify.WatchdogInterval(); interval > 0 {
+//		// call sdnotify.Notify(sdnotify.Watchdog) at least every interval
+//	}
+package sdnotify
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// States understood by systemd; see sd_notify(3).
+const (
+	Ready    = "READY=1"
+	Stopping = "STOPPING=1"
+	Watchdog = "WATCHDOG=1"
+)
+
+// Notify sends state to systemd. It reports false, with no error, when
+// $NOTIFY_SOCKET is not set.
+func Notify(state string) (bool, error) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return false, nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:] // abstract socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to notify socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the watchdog timeout systemd set for this
+// process (WatchdogSec= in the unit), or 0 if there is none. Keep-alives
+// must be sent well within it; half the interval is customary.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0 // meant for another process, e.g. our parent
+	}
+	return time.Duration(usec) * time.Microsecond
+}
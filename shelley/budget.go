@@ -0,0 +1,134 @@
+package shelley
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// CacheBudget enforces a total size limit across several caches. Caches
+// charge the budget for each entry they store and are called back to drop
+// entries when the total goes over the limit, least recently used first.
+// A nil *CacheBudget accepts every charge and never evicts.
+type CacheBudget struct {
+	mu        sync.Mutex
+	maxBytes  int64 // 0 means unlimited; usage is still tracked
+	used      int64
+	evictions uint64
+	lru       *list.List // of *budgetItem, most recently used at the front
+	items     map[string]*list.Element
+}
+
+type budgetItem struct {
+	key   string
+	size  int64
+	evict func()
+}
+
+// CacheUsage is a snapshot of a CacheBudget.
+type CacheUsage struct {
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+	Entries   int    `json:"entries"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// NewCacheBudget creates a budget of maxBytes in total; 0 means unlimited.
+func NewCacheBudget(maxBytes int64) *CacheBudget {
+	return &CacheBudget{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Charge records an entry of size bytes under key, replacing any previous
+// charge for the same key, and marks it most recently used. evict is called
+// (without any budget lock held) if the entry is later pushed out; it must
+// remove the entry from its cache. Entries are evicted until the total fits
+// again, but the entry just charged is never evicted by its own charge.
+func (b *CacheBudget) Charge(key string, size int64, evict func()) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if el, ok := b.items[key]; ok {
+		b.used -= el.Value.(*budgetItem).size
+		b.lru.Remove(el)
+	}
+	b.items[key] = b.lru.PushFront(&budgetItem{key: key, size: size, evict: evict})
+	b.used += size
+
+	var evicted []func()
+	for b.maxBytes > 0 && b.used > b.maxBytes && b.lru.Len() > 1 {
+		item := b.lru.Remove(b.lru.Back()).(*budgetItem)
+		delete(b.items, item.key)
+		b.used -= item.size
+		b.evictions++
+		evicted = append(evicted, item.evict)
+	}
+	b.mu.Unlock()
+
+	for _, evict := range evicted {
+		evict()
+	}
+}
+
+// Touch marks the entry under key as most recently used.
+func (b *CacheBudget) Touch(key string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if el, ok := b.items[key]; ok {
+		b.lru.MoveToFront(el)
+	}
+	b.mu.Unlock()
+}
+
+// Release removes the charge for key, for entries a cache dropped by itself.
+func (b *CacheBudget) Release(key string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if el, ok := b.items[key]; ok {
+		b.used -= el.Value.(*budgetItem).size
+		b.lru.Remove(el)
+		delete(b.items, key)
+	}
+	b.mu.Unlock()
+}
+
+// Usage returns the current usage of the budget.
+func (b *CacheBudget) Usage() CacheUsage {
+	if b == nil {
+		return CacheUsage{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return CacheUsage{Bytes: b.used, MaxBytes: b.maxBytes, Entries: len(b.items), Evictions: b.evictions}
+}
+
+// Handler returns an http.Handler that reports cache usage as text, or as
+// JSON with the ?json query parameter.
+func (b *CacheBudget) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := b.Usage()
+		if _, wantJSON := r.URL.Query()["json"]; wantJSON {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(u); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		limit := "unlimited"
+		if u.MaxBytes > 0 {
+			limit = fmt.Sprintf("%d bytes", u.MaxBytes)
+		}
+		fmt.Fprintf(w, "cache: %d bytes in %d entries (limit %s), %d evictions\n", u.Bytes, u.Entries, limit, u.Evictions)
+	})
+}
@@ -0,0 +1,116 @@
+package shelley
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheBudget_EvictsLeastRecentlyUsed(t *testing.T) {
+	b := NewCacheBudget(100)
+	var evicted []string
+	charge := func(key string, size int64) {
+		b.Charge(key, size, func() { evicted = append(evicted, key) })
+	}
+
+	charge("a", 40)
+	charge("b", 40)
+	b.Touch("a")
+	charge("c", 40) // over budget: b is the least recently used
+
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted = %v, want [b]", evicted)
+	}
+	u := b.Usage()
+	if u.Bytes != 80 || u.Entries != 2 || u.Evictions != 1 {
+		t.Errorf("usage = %+v, want 80 bytes in 2 entries, 1 eviction", u)
+	}
+
+	// An entry larger than the whole budget evicts everything else but stays.
+	charge("huge", 500)
+	if u := b.Usage(); u.Entries != 1 || u.Bytes != 500 {
+		t.Errorf("usage after oversized charge = %+v", u)
+	}
+
+	b.Release("huge")
+	if u := b.Usage(); u.Entries != 0 || u.Bytes != 0 {
+		t.Errorf("usage after release = %+v", u)
+	}
+}
+
+func TestCacheBudget_Recharge(t *testing.T) {
+	b := NewCacheBudget(0)
+	b.Charge("a", 10, func() { t.Error("unlimited budget evicted an entry") })
+	b.Charge("a", 30, func() {})
+	if u := b.Usage(); u.Bytes != 30 || u.Entries != 1 {
+		t.Errorf("usage = %+v, want 30 bytes in 1 entry", u)
+	}
+}
+
+func TestCacheBudget_Nil(t *testing.T) {
+	var b *CacheBudget
+	b.Charge("a", 10, func() {})
+	b.Touch("a")
+	b.Release("a")
+	if u := b.Usage(); u != (CacheUsage{}) {
+		t.Errorf("nil budget usage = %+v", u)
+	}
+}
+
+func TestCacheBudget_Handler(t *testing.T) {
+	b := NewCacheBudget(1000)
+	b.Charge("a", 10, func() {})
+
+	rec := httptest.NewRecorder()
+	b.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/diag/cache", nil))
+	if !strings.Contains(rec.Body.String(), "10 bytes in 1 entries (limit 1000 bytes)") {
+		t.Errorf("unexpected text output: %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	b.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/diag/cache?json", nil))
+	if !strings.Contains(rec.Body.String(), `"max_bytes":1000`) {
+		t.Errorf("unexpected JSON output: %q", rec.Body.String())
+	}
+}
+
+// TestCachingClient_BudgetEvictsConversations verifies that conversations
+// evicted by the budget are fetched from the backend again.
+func TestCachingClient_BudgetEvictsConversations(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"messages":[],"pad":"` + strings.Repeat("x", 60) + `"}`))
+	}))
+	defer server.Close()
+
+	budget := NewCacheBudget(200)
+	caching := NewCachingClient(NewClient(server.URL), time.Minute)
+	caching.SetBudget(budget)
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := caching.GetConversation(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Fatalf("expected 3 backend calls, got %d", n)
+	}
+	if u := budget.Usage(); u.Entries != 2 || u.Evictions != 1 {
+		t.Errorf("usage = %+v, want 2 entries and 1 eviction", u)
+	}
+
+	caching.GetConversation("c") // still cached
+	caching.GetConversation("a") // evicted, refetched
+	if n := atomic.LoadInt32(&calls); n != 4 {
+		t.Errorf("expected 4 backend calls, got %d", n)
+	}
+
+	caching.InvalidateAll()
+	if u := budget.Usage(); u.Entries != 0 || u.Bytes != 0 {
+		t.Errorf("usage after InvalidateAll = %+v", u)
+	}
+}
@@ -1,6 +1,8 @@
 package shelley
 
 import (
+	"bytes"
+	"fmt"
 	"sync"
 	"time"
 
@@ -33,6 +35,18 @@ type CachingClient struct {
 	archivedListCache      *cacheEntry
 	modelsCache            *cacheEntry
 	defaultModelCache      *cacheEntry
+
+	// budget, if set, bounds the size of the per-conversation caches.
+	budget *CacheBudget
+
+	// serveStale makes reads fall back to expired entries while the
+	// backend is unavailable, see SetServeStale.
+	serveStale bool
+
+	// State behind the live files, see live.go.
+	liveTTL       time.Duration
+	liveListCache *cacheEntry
+	liveSeen      map[string]string // updated_at in the live list when each conversation was last fetched live
 }
 
 // cacheEntry holds cached data with an expiration time.
@@ -54,6 +68,59 @@ func NewCachingClient(client *Client, cacheTTL time.Duration) *CachingClient {
 	}
 }
 
+// SetBudget makes the per-conversation caches (conversations and subagent
+// lists) count against b, which may evict them least recently used first.
+// It must be called before the client is used.
+func (c *CachingClient) SetBudget(b *CacheBudget) {
+	c.budget = b
+}
+
+// SetServeStale makes reads answer from the cache, however old the entry,
+// when the backend can't be reached or fails with a server error (see
+// Unavailable), instead of failing. Writes and refreshes still fail. It must
+// be called before the client is used.
+func (c *CachingClient) SetServeStale(enabled bool) {
+	c.serveStale = enabled
+}
+
+// staleEntry returns the entry get finds in place of the failure err, if
+// serving stale is enabled and err means the backend is unavailable. The
+// caller must not hold c.mu.
+func (c *CachingClient) staleEntry(err error, get func() *cacheEntry) *cacheEntry {
+	if !c.serveStale || !Unavailable(err) {
+		return nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return get()
+}
+
+// budgetKey names a per-conversation cache entry in the budget. Budgets are
+// shared between clients, so the key includes the client.
+func (c *CachingClient) budgetKey(kind, conversationID string) string {
+	return fmt.Sprintf("%p/%s/%s", c, kind, conversationID)
+}
+
+// chargeBudget charges the budget for an entry just stored in cache. If the
+// budget later evicts it, the entry is removed unless it has been replaced.
+// The caller must not hold c.mu.
+func (c *CachingClient) chargeBudget(cache map[string]*cacheEntry, kind, conversationID string, entry *cacheEntry) {
+	c.budget.Charge(c.budgetKey(kind, conversationID), int64(len(entry.data)), func() {
+		c.mu.Lock()
+		if cache[conversationID] == entry {
+			delete(cache, conversationID)
+		}
+		c.mu.Unlock()
+	})
+}
+
+// dropLocked removes a per-conversation cache entry and its budget charge.
+// The caller must hold c.mu.
+func (c *CachingClient) dropLocked(cache map[string]*cacheEntry, kind, conversationID string) {
+	delete(cache, conversationID)
+	c.budget.Release(c.budgetKey(kind, conversationID))
+}
+
 // isValid returns true if the cache entry exists and hasn't expired.
 func (e *cacheEntry) isValid() bool {
 	return e != nil && time.Now().Before(e.expiresAt)
@@ -70,6 +137,7 @@ func (c *CachingClient) GetConversation(conversationID string) ([]byte, error) {
 		c.mu.RUnlock()
 
 		if entry.isValid() {
+			c.budget.Touch(c.budgetKey("conversation", conversationID))
 			// Return cached slice directly — callers must not mutate.
 			// Returning the same slice enables downstream caches
 			// (e.g. ParsedMessageCache) to use pointer identity for
@@ -84,16 +152,27 @@ func (c *CachingClient) GetConversation(conversationID string) ([]byte, error) {
 	result, err, _ := c.sf.Do("conversation:"+conversationID, func() (interface{}, error) {
 		data, err := c.client.GetConversation(conversationID)
 		if err != nil {
+			if stale := c.staleEntry(err, func() *cacheEntry { return c.conversationCache[conversationID] }); stale != nil {
+				return stale.data, nil
+			}
 			return nil, err
 		}
 
 		if c.cacheTTL > 0 {
-			c.mu.Lock()
-			c.conversationCache[conversationID] = &cacheEntry{
+			entry := &cacheEntry{
 				data:      data,
 				expiresAt: time.Now().Add(c.cacheTTL),
 			}
+			c.mu.Lock()
+			// A RefreshConversation that finished meanwhile cached an equally
+			// fresh copy, possibly for longer; don't cut its TTL short.
+			if old := c.conversationCache[conversationID]; old.isValid() && old.expiresAt.After(entry.expiresAt) {
+				c.mu.Unlock()
+				return old.data, nil
+			}
+			c.conversationCache[conversationID] = entry
 			c.mu.Unlock()
+			c.chargeBudget(c.conversationCache, "conversation", conversationID, entry)
 		}
 
 		return data, nil
@@ -105,6 +184,45 @@ func (c *CachingClient) GetConversation(conversationID string) ([]byte, error) {
 	return result.([]byte), nil
 }
 
+// RefreshConversation fetches a conversation from the backend even if it is
+// cached, and caches the result for ttl or the client's TTL, whichever is
+// longer. If the conversation is unchanged the cached slice is kept, so
+// caches keyed on its identity (ParsedMessageCache) stay valid. With caching
+// disabled it only fetches.
+func (c *CachingClient) RefreshConversation(conversationID string, ttl time.Duration) ([]byte, error) {
+	result, err, _ := c.sf.Do("refresh:"+conversationID, func() (interface{}, error) {
+		data, err := c.client.GetConversation(conversationID)
+		if err != nil {
+			return nil, err
+		}
+		if c.cacheTTL == 0 {
+			return data, nil
+		}
+		if ttl < c.cacheTTL {
+			ttl = c.cacheTTL
+		}
+
+		c.mu.Lock()
+		if old := c.conversationCache[conversationID]; old != nil && bytes.Equal(old.data, data) {
+			data = old.data
+		}
+		entry := &cacheEntry{
+			data:      data,
+			expiresAt: time.Now().Add(ttl),
+		}
+		c.conversationCache[conversationID] = entry
+		c.mu.Unlock()
+		c.chargeBudget(c.conversationCache, "conversation", conversationID, entry)
+
+		return data, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
 // ListConversations lists all conversations, using cache if available.
 // Uses singleflight to coalesce duplicate requests without holding locks during HTTP calls.
 // The returned byte slice must not be modified by callers.
@@ -126,6 +244,9 @@ func (c *CachingClient) ListConversations() ([]byte, error) {
 	result, err, _ := c.sf.Do("conversations:list", func() (interface{}, error) {
 		data, err := c.client.ListConversations()
 		if err != nil {
+			if stale := c.staleEntry(err, func() *cacheEntry { return c.conversationsListCache }); stale != nil {
+				return stale.data, nil
+			}
 			return nil, err
 		}
 
@@ -147,6 +268,30 @@ func (c *CachingClient) ListConversations() ([]byte, error) {
 	return result.([]byte), nil
 }
 
+// RefreshConversations lists all conversations from the backend even if the
+// list is cached, and caches the result.
+func (c *CachingClient) RefreshConversations() ([]byte, error) {
+	result, err, _ := c.sf.Do("conversations:refresh", func() (interface{}, error) {
+		data, err := c.client.ListConversations()
+		if err != nil {
+			return nil, err
+		}
+		if c.cacheTTL > 0 {
+			c.mu.Lock()
+			c.conversationsListCache = &cacheEntry{
+				data:      data,
+				expiresAt: time.Now().Add(c.cacheTTL),
+			}
+			c.mu.Unlock()
+		}
+		return data, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]byte), nil
+}
+
 // ListArchivedConversations lists all archived conversations, using cache if available.
 // Uses singleflight to coalesce duplicate requests without holding locks during HTTP calls.
 // The returned byte slice must not be modified by callers.
@@ -166,6 +311,9 @@ func (c *CachingClient) ListArchivedConversations() ([]byte, error) {
 	result, err, _ := c.sf.Do("conversations:archived", func() (interface{}, error) {
 		data, err := c.client.ListArchivedConversations()
 		if err != nil {
+			if stale := c.staleEntry(err, func() *cacheEntry { return c.archivedListCache }); stale != nil {
+				return stale.data, nil
+			}
 			return nil, err
 		}
 
@@ -207,6 +355,9 @@ func (c *CachingClient) ListModels() (ModelsResult, error) {
 	result, err, _ := c.sf.Do("models:list", func() (interface{}, error) {
 		modelsResult, err := c.client.ListModels()
 		if err != nil {
+			if stale := c.staleEntry(err, func() *cacheEntry { return c.modelsCache }); stale != nil && stale.result != nil {
+				return *stale.result, nil
+			}
 			return ModelsResult{}, err
 		}
 
@@ -246,6 +397,9 @@ func (c *CachingClient) DefaultModel() (string, error) {
 	result, err, _ := c.sf.Do("models:default", func() (interface{}, error) {
 		defaultModel, err := c.client.DefaultModel()
 		if err != nil {
+			if stale := c.staleEntry(err, func() *cacheEntry { return c.defaultModelCache }); stale != nil {
+				return stale.strVal, nil
+			}
 			return "", err
 		}
 
@@ -268,8 +422,8 @@ func (c *CachingClient) DefaultModel() (string, error) {
 }
 
 // StartConversation starts a new conversation and invalidates the conversations list cache.
-func (c *CachingClient) StartConversation(message, model, cwd string) (StartConversationResult, error) {
-	result, err := c.client.StartConversation(message, model, cwd)
+func (c *CachingClient) StartConversation(message, model, cwd, slug string) (StartConversationResult, error) {
+	result, err := c.client.StartConversation(message, model, cwd, slug)
 	if err != nil {
 		return result, err
 	}
@@ -294,7 +448,7 @@ func (c *CachingClient) SendMessage(conversationID, message, model string) error
 	// Invalidate this conversation's cache since it was modified
 	if c.cacheTTL > 0 {
 		c.mu.Lock()
-		delete(c.conversationCache, conversationID)
+		c.dropLocked(c.conversationCache, "conversation", conversationID)
 		c.mu.Unlock()
 	}
 
@@ -306,7 +460,7 @@ func (c *CachingClient) SendMessage(conversationID, message, model string) error
 func (c *CachingClient) InvalidateConversation(conversationID string) {
 	if c.cacheTTL > 0 {
 		c.mu.Lock()
-		delete(c.conversationCache, conversationID)
+		c.dropLocked(c.conversationCache, "conversation", conversationID)
 		c.mu.Unlock()
 	}
 }
@@ -315,8 +469,12 @@ func (c *CachingClient) InvalidateConversation(conversationID string) {
 func (c *CachingClient) InvalidateAll() {
 	if c.cacheTTL > 0 {
 		c.mu.Lock()
-		c.conversationCache = make(map[string]*cacheEntry)
-		c.subagentsCache = make(map[string]*cacheEntry)
+		for id := range c.conversationCache {
+			c.dropLocked(c.conversationCache, "conversation", id)
+		}
+		for id := range c.subagentsCache {
+			c.dropLocked(c.subagentsCache, "subagents", id)
+		}
 		c.conversationsListCache = nil
 		c.archivedListCache = nil
 		c.modelsCache = nil
@@ -370,7 +528,7 @@ func (c *CachingClient) CancelConversation(conversationID string) error {
 	// Invalidate this conversation's cache since working state changed
 	if c.cacheTTL > 0 {
 		c.mu.Lock()
-		delete(c.conversationCache, conversationID)
+		c.dropLocked(c.conversationCache, "conversation", conversationID)
 		c.mu.Unlock()
 	}
 	return nil
@@ -387,8 +545,8 @@ func (c *CachingClient) DeleteConversation(conversationID string) error {
 		c.mu.Lock()
 		c.conversationsListCache = nil
 		c.archivedListCache = nil
-		delete(c.conversationCache, conversationID)
-		delete(c.subagentsCache, conversationID)
+		c.dropLocked(c.conversationCache, "conversation", conversationID)
+		c.dropLocked(c.subagentsCache, "subagents", conversationID)
 		c.mu.Unlock()
 	}
 
@@ -403,6 +561,9 @@ func (c *CachingClient) IsConversationArchived(conversationID string) (bool, err
 
 // IsConversationWorking checks if the agent is currently working on a conversation.
 func (c *CachingClient) IsConversationWorking(conversationID string) (bool, error) {
+	if c.liveTTL > 0 {
+		return c.isWorkingLive(conversationID)
+	}
 	// Don't cache this - working state is volatile and should always be fresh
 	return c.client.IsConversationWorking(conversationID)
 }
@@ -435,6 +596,7 @@ func (c *CachingClient) ListSubagents(conversationID string) ([]byte, error) {
 		c.mu.RUnlock()
 
 		if entry.isValid() {
+			c.budget.Touch(c.budgetKey("subagents", conversationID))
 			return entry.data, nil
 		}
 	}
@@ -443,16 +605,21 @@ func (c *CachingClient) ListSubagents(conversationID string) ([]byte, error) {
 	result, err, _ := c.sf.Do("subagents:"+conversationID, func() (interface{}, error) {
 		data, err := c.client.ListSubagents(conversationID)
 		if err != nil {
+			if stale := c.staleEntry(err, func() *cacheEntry { return c.subagentsCache[conversationID] }); stale != nil {
+				return stale.data, nil
+			}
 			return nil, err
 		}
 
 		if c.cacheTTL > 0 {
-			c.mu.Lock()
-			c.subagentsCache[conversationID] = &cacheEntry{
+			entry := &cacheEntry{
 				data:      data,
 				expiresAt: time.Now().Add(c.cacheTTL),
 			}
+			c.mu.Lock()
+			c.subagentsCache[conversationID] = entry
 			c.mu.Unlock()
+			c.chargeBudget(c.subagentsCache, "subagents", conversationID, entry)
 		}
 
 		return data, nil
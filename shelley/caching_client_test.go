@@ -329,7 +329,7 @@ func TestCachingClient_StartConversation_InvalidatesListCache(t *testing.T) {
 	}
 
 	// Start a new conversation (should invalidate list cache)
-	_, err := caching.StartConversation("hello", "", "")
+	_, err := caching.StartConversation("hello", "", "", "")
 	if err != nil {
 		t.Fatalf("StartConversation failed: %v", err)
 	}
@@ -785,7 +785,7 @@ func TestCachingClient_Singleflight_ReadDirPlusAndFlushDontBlock(t *testing.T) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			_, err := caching.StartConversation("test message", "model", "/tmp")
+			_, err := caching.StartConversation("test message", "model", "/tmp", "")
 			if err != nil {
 				t.Errorf("StartConversation failed: %v", err)
 			}
@@ -1000,3 +1000,56 @@ func TestCachingClient_DeleteConversation_InvalidatesCache(t *testing.T) {
 		t.Fatalf("Expected 2 archived list calls after delete invalidation, got %d", archivedCount)
 	}
 }
+
+// TestCachingClient_RefreshConversation verifies that a refresh always goes
+// to the backend, keeps the cached slice when nothing changed, and leaves
+// the result cached for the requested TTL.
+func TestCachingClient_RefreshConversation(t *testing.T) {
+	var callCount int32
+	var body atomic.Value
+	body.Store(`{"messages":[]}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&callCount, 1)
+		w.Write([]byte(body.Load().(string)))
+	}))
+	defer server.Close()
+
+	caching := NewCachingClient(NewClient(server.URL), 10*time.Millisecond)
+
+	cached, err := caching.GetConversation("conv-123")
+	if err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	refreshed, err := caching.RefreshConversation("conv-123", time.Hour)
+	if err != nil {
+		t.Fatalf("RefreshConversation failed: %v", err)
+	}
+	if atomic.LoadInt32(&callCount) != 2 {
+		t.Fatalf("Expected refresh to hit the backend, got %d calls", callCount)
+	}
+	if &refreshed[0] != &cached[0] {
+		t.Error("Unchanged conversation: expected the cached slice to be kept")
+	}
+
+	// The refresh asked for an hour, longer than the client's TTL.
+	time.Sleep(20 * time.Millisecond)
+	if _, err := caching.GetConversation("conv-123"); err != nil {
+		t.Fatalf("GetConversation failed: %v", err)
+	}
+	if atomic.LoadInt32(&callCount) != 2 {
+		t.Errorf("Expected refreshed entry to be served from cache, got %d calls", callCount)
+	}
+
+	body.Store(`{"messages":[{"message_id":"m1"}]}`)
+	refreshed, err = caching.RefreshConversation("conv-123", 0)
+	if err != nil {
+		t.Fatalf("RefreshConversation failed: %v", err)
+	}
+	if string(refreshed) != body.Load().(string) {
+		t.Errorf("Refresh returned %s", refreshed)
+	}
+	got, _ := caching.GetConversation("conv-123")
+	if string(got) != string(refreshed) {
+		t.Errorf("Cache holds %s after refresh, want %s", got, refreshed)
+	}
+}
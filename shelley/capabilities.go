@@ -0,0 +1,154 @@
+package shelley
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+// ErrDeleteUnsupported is returned by DeleteConversation when the backend
+// reported that it can't delete conversations.
+var ErrDeleteUnsupported = errors.New("backend does not support deleting conversations")
+
+// Features a backend can report from GET /api/capabilities.
+const (
+	FeatureStreaming = "streaming" // messages can be streamed as they are written
+	FeatureDelete    = "delete"    // conversations can be deleted
+	FeatureEdit      = "edit"      // user messages can be edited (MessageEditor)
+	FeatureSettings  = "settings"  // settings can change mid-conversation (SettingsUpdater)
+)
+
+// KnownFeatures lists the features the filesystem acts on, in the order
+// they are reported.
+var KnownFeatures = []string{FeatureDelete, FeatureEdit, FeatureSettings, FeatureStreaming}
+
+// Capabilities is what a backend reports about itself.
+type Capabilities struct {
+	Version  string   `json:"version,omitempty"`
+	Features []string `json:"features"`
+
+	// Negotiated is false for a backend without the capabilities endpoint
+	// (it answers 404). Such a backend predates negotiation: Features is
+	// empty, every feature is assumed and found out by trying it.
+	Negotiated bool `json:"-"`
+}
+
+// Has reports whether feature is enabled. Without negotiation everything is.
+func (c Capabilities) Has(feature string) bool {
+	if !c.Negotiated {
+		return true
+	}
+	for _, f := range c.Features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// FeatureNegotiator is implemented by clients that ask the backend which
+// features it supports. Like MessageEditor it is optional: callers should
+// type-assert a ShelleyClient, or use FeatureEnabled.
+type FeatureNegotiator interface {
+	// Negotiate fetches the backend's capabilities and remembers them. A
+	// backend without the endpoint is not an error: every feature is
+	// assumed. On other errors nothing is remembered.
+	Negotiate() (Capabilities, error)
+
+	// Capabilities returns what Negotiate last found, and false if it
+	// hasn't succeeded yet. It doesn't contact the backend.
+	Capabilities() (Capabilities, bool)
+}
+
+var _ FeatureNegotiator = (*Client)(nil)
+var _ FeatureNegotiator = (*CachingClient)(nil)
+
+// FeatureEnabled reports whether client's backend supports feature. Clients
+// that don't negotiate, or haven't yet, are assumed to support everything;
+// the request itself then decides.
+func FeatureEnabled(client ShelleyClient, feature string) bool {
+	n, ok := client.(FeatureNegotiator)
+	if !ok {
+		return true
+	}
+	caps, _ := n.Capabilities()
+	return caps.Has(feature)
+}
+
+// Negotiate fetches the backend's capabilities from GET /api/capabilities.
+func (c *Client) Negotiate() (Capabilities, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/api/capabilities", nil)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("X-Exedev-Userid", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var caps Capabilities
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := json.NewDecoder(resp.Body).Decode(&caps); err != nil {
+			return Capabilities{}, fmt.Errorf("failed to decode capabilities: %w", err)
+		}
+		caps.Negotiated = true
+		sort.Strings(caps.Features)
+	case http.StatusNotFound:
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return Capabilities{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	c.capsMu.Lock()
+	c.caps = &caps
+	hook := c.capsHook
+	c.capsMu.Unlock()
+	if hook != nil {
+		hook(caps)
+	}
+	return caps, nil
+}
+
+// SetNegotiationHook makes the client call fn with what Negotiate found
+// each time it succeeds, e.g. to remember it across restarts. It must be
+// called before the client is used.
+func (c *Client) SetNegotiationHook(fn func(Capabilities)) {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	c.capsHook = fn
+}
+
+// Capabilities returns the capabilities Negotiate last found.
+func (c *Client) Capabilities() (Capabilities, bool) {
+	c.capsMu.Lock()
+	defer c.capsMu.Unlock()
+	if c.caps == nil {
+		return Capabilities{}, false
+	}
+	return *c.caps, true
+}
+
+// disabled reports whether negotiation has ruled feature out, so the
+// request for it can fail without a round trip.
+func (c *Client) disabled(feature string) bool {
+	caps, ok := c.Capabilities()
+	return ok && !caps.Has(feature)
+}
+
+// Negotiate fetches the backend's capabilities.
+func (c *CachingClient) Negotiate() (Capabilities, error) {
+	return c.client.Negotiate()
+}
+
+// Capabilities returns the backend's negotiated capabilities.
+func (c *CachingClient) Capabilities() (Capabilities, bool) {
+	return c.client.Capabilities()
+}
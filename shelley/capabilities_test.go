@@ -0,0 +1,97 @@
+package shelley
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNegotiate(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path == "/api/capabilities" {
+			w.Write([]byte(`{"version":"1.4.2","features":["settings","edit","shiny"]}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if _, ok := client.Capabilities(); ok {
+		t.Error("capabilities known before negotiating")
+	}
+	if !FeatureEnabled(client, FeatureDelete) {
+		t.Error("features should be assumed before negotiating")
+	}
+	caps, err := client.Negotiate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.Negotiated || caps.Version != "1.4.2" || !reflect.DeepEqual(caps.Features, []string{"edit", "settings", "shiny"}) {
+		t.Errorf("Negotiate() = %+v", caps)
+	}
+	if !FeatureEnabled(client, FeatureEdit) || FeatureEnabled(client, FeatureDelete) {
+		t.Error("FeatureEnabled doesn't follow the negotiated features")
+	}
+
+	// A feature negotiated away fails without a request.
+	requests = nil
+	if err := client.DeleteConversation("c1"); err != ErrDeleteUnsupported {
+		t.Errorf("DeleteConversation: got %v, want ErrDeleteUnsupported", err)
+	}
+	if len(requests) != 0 {
+		t.Errorf("requests sent for a disabled feature: %v", requests)
+	}
+}
+
+func TestNegotiateWithoutEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := NewCachingClient(NewClient(server.URL), 0)
+	caps, err := client.Negotiate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caps.Negotiated {
+		t.Errorf("Negotiate() against an older backend = %+v", caps)
+	}
+	if _, ok := client.Capabilities(); !ok {
+		t.Error("the missing endpoint should be remembered")
+	}
+	for _, f := range KnownFeatures {
+		if !FeatureEnabled(client, f) {
+			t.Errorf("feature %s off against a backend without negotiation", f)
+		}
+	}
+}
+
+func TestNegotiationHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/capabilities" {
+			w.Write([]byte(`{"version":"2.0","features":["delete"]}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	mgr := NewClientManager(0)
+	reported := make(map[string]Capabilities)
+	mgr.SetNegotiationHook(func(backend string, caps Capabilities) {
+		reported[backend] = caps
+	})
+	client, err := mgr.EnsureURL("edge", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.(*Client).Negotiate(); err != nil {
+		t.Fatal(err)
+	}
+	if caps, ok := reported["edge"]; !ok || !caps.Negotiated || caps.Version != "2.0" {
+		t.Errorf("hook got %+v, want the edge backend's capabilities", reported)
+	}
+}
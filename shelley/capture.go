@@ -0,0 +1,308 @@
+package shelley
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Capture writes a copy of every backend request and response to a
+// directory, one file per round trip, for attaching to bug reports.
+// Secrets are scrubbed before anything reaches the disk: credential
+// headers, credential-looking query parameters and JSON fields, and
+// bearer tokens in bodies. Bodies are cut off after a bound, and only the
+// newest files are kept.
+type Capture struct {
+	dir      string
+	maxBody  int
+	maxFiles int
+
+	mu    sync.Mutex
+	seq   uint64
+	files []string // oldest first
+}
+
+// Defaults for NewCapture.
+const (
+	DefaultCaptureMaxBody  = 64 << 10
+	DefaultCaptureMaxFiles = 500
+)
+
+// redacted replaces scrubbed values in captures.
+const redacted = "[REDACTED]"
+
+// NewCapture creates dir if needed and returns a Capture writing to it.
+// Bodies longer than maxBody bytes are truncated, and beyond maxFiles
+// captures the oldest are removed, including those left by earlier runs.
+func NewCapture(dir string, maxBody, maxFiles int) (*Capture, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("capture dir: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("capture dir: %w", err)
+	}
+	c := &Capture{dir: dir, maxBody: maxBody, maxFiles: maxFiles}
+	for _, e := range entries {
+		if e.Type().IsRegular() && strings.HasSuffix(e.Name(), ".http") {
+			c.files = append(c.files, e.Name())
+		}
+	}
+	sort.Strings(c.files) // names start with the capture time
+	return c, nil
+}
+
+// SetCapture makes the client record every request it sends with c. It
+// must be called before the client is used.
+func (c *Client) SetCapture(capture *Capture) {
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.httpClient.Transport = &capturingTransport{base: base, capture: capture}
+}
+
+// capturingTransport hands every round trip to a Capture. The response is
+// written out once the caller closes its body, so streamed responses are
+// captured as far as they were read without being held up.
+type capturingTransport struct {
+	base    http.RoundTripper
+	capture *Capture
+}
+
+func (t *capturingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &captureRecord{start: time.Now(), req: req}
+	rec.reqBody = t.capture.requestBody(req)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		rec.err = err
+		t.capture.write(rec)
+		return resp, err
+	}
+	rec.resp = resp
+	resp.Body = &capturedBody{ReadCloser: resp.Body, rec: rec, capture: t.capture}
+	return resp, nil
+}
+
+// captureRecord is one round trip being captured.
+type captureRecord struct {
+	start    time.Time
+	req      *http.Request
+	reqBody  []byte
+	resp     *http.Response
+	respBody []byte
+	err      error
+}
+
+// requestBody returns up to maxBody bytes of req's body, leaving the body
+// for the transport to send.
+func (c *Capture) requestBody(req *http.Request) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(io.LimitReader(body, int64(c.maxBody)+1))
+		return data
+	}
+	data, _ := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	if len(data) > c.maxBody {
+		return data[:c.maxBody+1]
+	}
+	return data
+}
+
+// capturedBody keeps the start of a response body as the caller reads it
+// and writes the capture when the body is closed.
+type capturedBody struct {
+	io.ReadCloser
+	rec     *captureRecord
+	capture *Capture
+	buf     []byte
+	once    sync.Once
+}
+
+func (b *capturedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := b.capture.maxBody + 1 - len(b.buf); room > 0 {
+		b.buf = append(b.buf, p[:min(n, room)]...)
+	}
+	return n, err
+}
+
+func (b *capturedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.rec.respBody = b.buf
+		b.capture.write(b.rec)
+	})
+	return err
+}
+
+// write renders rec to a new file and removes the oldest beyond maxFiles.
+// Failures are ignored: capturing must never break a request.
+func (c *Capture) write(rec *captureRecord) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s %s (%s)\n\n", rec.start.UTC().Format(time.RFC3339Nano), rec.req.Method, time.Since(rec.start).Round(time.Millisecond))
+
+	fmt.Fprintf(&b, "%s %s\n", rec.req.Method, scrubURL(rec.req.URL))
+	writeHeaders(&b, rec.req.Header)
+	c.writeBody(&b, rec.reqBody)
+
+	switch {
+	case rec.err != nil:
+		fmt.Fprintf(&b, "\nerror: %s\n", scrubText(rec.err.Error()))
+	case rec.resp != nil:
+		fmt.Fprintf(&b, "\n%s %s\n", rec.resp.Proto, rec.resp.Status)
+		writeHeaders(&b, rec.resp.Header)
+		c.writeBody(&b, rec.respBody)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	name := fmt.Sprintf("%s-%06d-%s%s.http", rec.start.UTC().Format("20060102T150405.000"), c.seq, rec.req.Method, captureSlug(rec.req.URL.Path))
+	if err := os.WriteFile(filepath.Join(c.dir, name), b.Bytes(), 0600); err != nil {
+		return
+	}
+	c.files = append(c.files, name)
+	for c.maxFiles > 0 && len(c.files) > c.maxFiles {
+		os.Remove(filepath.Join(c.dir, c.files[0]))
+		c.files = c.files[1:]
+	}
+}
+
+// writeBody writes a scrubbed body, noting when it was cut off.
+func (c *Capture) writeBody(b *bytes.Buffer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	truncated := len(body) > c.maxBody
+	if truncated {
+		body = body[:c.maxBody]
+	}
+	b.WriteString("\n")
+	b.WriteString(scrubText(string(body)))
+	if truncated {
+		fmt.Fprintf(b, "\n[truncated after %d bytes]", c.maxBody)
+	}
+	b.WriteString("\n")
+}
+
+// writeHeaders writes h sorted by name, with credentials scrubbed.
+func writeHeaders(b *bytes.Buffer, h http.Header) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range h[name] {
+			if secretHeader(name) {
+				v = redacted
+			}
+			fmt.Fprintf(b, "%s: %s\n", name, v)
+		}
+	}
+}
+
+// secretHeader reports whether a header carries credentials or identity.
+func secretHeader(name string) bool {
+	name = strings.ToLower(name)
+	if strings.HasPrefix(name, "x-exedev-") {
+		return true
+	}
+	return secretName(name) || strings.Contains(name, "cookie")
+}
+
+// secretName reports whether a field, parameter or header name looks like
+// it holds a credential.
+func secretName(name string) bool {
+	name = strings.ToLower(name)
+	if name == "auth" {
+		return true
+	}
+	for _, s := range []string{"authorization", "token", "secret", "password", "passwd", "api_key", "apikey", "api-key", "session", "credential"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubURL returns u without userinfo and with credential query
+// parameters redacted.
+func scrubURL(u *url.URL) string {
+	scrubbed := *u
+	if scrubbed.User != nil {
+		scrubbed.User = url.User(redacted)
+	}
+	if scrubbed.RawQuery != "" {
+		q := scrubbed.Query()
+		for name := range q {
+			if secretName(name) {
+				q[name] = []string{redacted}
+			}
+		}
+		scrubbed.RawQuery = q.Encode()
+	}
+	return scrubbed.String()
+}
+
+var (
+	// secretField matches a JSON string field with a credential-looking
+	// name. It works on truncated bodies that no longer parse.
+	secretField = regexp.MustCompile(`"([^"\\]*)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	bearerToken = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+)
+
+// scrubText redacts credential-looking JSON fields and authorization
+// tokens in text.
+func scrubText(s string) string {
+	s = secretField.ReplaceAllStringFunc(s, func(m string) string {
+		sub := secretField.FindStringSubmatch(m)
+		if !secretName(sub[1]) {
+			return m
+		}
+		return `"` + sub[1] + `"` + sub[2] + `"` + redacted + `"`
+	})
+	return bearerToken.ReplaceAllString(s, "$1 "+redacted)
+}
+
+// captureSlug turns a URL path into a file name suffix, e.g.
+// /api/conversation/abc/chat becomes -api-conversation-abc-chat.
+func captureSlug(p string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(p, "/") {
+		if part == "" {
+			continue
+		}
+		b.WriteByte('-')
+		for _, r := range part {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '.' {
+				b.WriteRune(r)
+			} else {
+				b.WriteByte('_')
+			}
+		}
+		if b.Len() > 80 {
+			break
+		}
+	}
+	return b.String()
+}
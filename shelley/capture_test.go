@@ -0,0 +1,104 @@
+package shelley
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		w.Write([]byte(`{"conversation_id":"c1","slug":"hello","api_key":"sk-live-123"}`))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), "captures")
+	capture, err := NewCapture(dir, DefaultCaptureMaxBody, DefaultCaptureMaxFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(server.URL)
+	client.SetCapture(capture)
+	if _, err := client.StartConversation(`my token is "Bearer abc.def"`, "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || !strings.HasSuffix(entries[0].Name(), "-POST-api-conversations-new.http") {
+		t.Fatalf("expected one capture file, got %v", entries)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{"POST " + server.URL + "/api/conversations/new", `"conversation_id":"c1"`, "200 OK", redacted} {
+		if !strings.Contains(got, want) {
+			t.Errorf("capture lacks %q:\n%s", want, got)
+		}
+	}
+	for _, secret := range []string{"cookie-secret", "sk-live-123", "abc.def"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("capture leaks %q:\n%s", secret, got)
+		}
+	}
+}
+
+func TestCaptureBounds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	capture, err := NewCapture(dir, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := NewClient(server.URL)
+	client.SetCapture(capture)
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListConversations(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected the 2 newest captures, got %d", len(entries))
+	}
+	if !strings.Contains(entries[0].Name(), "-000002-") {
+		t.Errorf("expected the oldest capture to be removed, have %s", entries[0].Name())
+	}
+	data, err := os.ReadFile(filepath.Join(dir, entries[1].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); strings.Contains(got, strings.Repeat("x", 11)) || !strings.Contains(got, "[truncated after 10 bytes]") {
+		t.Errorf("body not truncated:\n%s", got)
+	}
+}
+
+func TestScrubText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`{"password": "hunter2", "text": "hi"}`, `{"password": "[REDACTED]", "text": "hi"}`},
+		{`{"author":"me","refresh_token":"abc`, `{"author":"me","refresh_token":"abc`}, // truncated value
+		{`Authorization: Basic dXNlcjpwYXNz`, `Authorization: Basic [REDACTED]`},
+		{`{"input_tokens": 12}`, `{"input_tokens": 12}`},
+	}
+	for _, tt := range tests {
+		if got := scrubText(tt.in); got != tt.want {
+			t.Errorf("scrubText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
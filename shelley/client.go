@@ -2,27 +2,50 @@ package shelley
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Client is a Shelley API client
 type Client struct {
 	baseURL    string
+	webURL     string // baseURL as given, or "" for a unix socket (see WebURL)
 	httpClient *http.Client
+
+	capsMu   sync.Mutex
+	caps     *Capabilities      // set by Negotiate; nil until it succeeds
+	capsHook func(Capabilities) // called after each successful Negotiate (see SetNegotiationHook)
 }
 
-// NewClient creates a new Shelley API client
+// NewClient creates a new Shelley API client for an http://, https:// or
+// unix:// URL (see UnixSocketPath).
 func NewClient(baseURL string) *Client {
+	return NewClientWithTLS(baseURL, nil)
+}
+
+// NewClientWithTLS is NewClient with tlsConfig for https URLs (see
+// TLSOptions); nil uses the system defaults.
+func NewClientWithTLS(baseURL string, tlsConfig *tls.Config) *Client {
+	baseURL = strings.TrimRight(baseURL, "/")
+	base, transport := newTransport(baseURL, tlsConfig)
+	webURL := baseURL
+	if _, isUnix := UnixSocketPath(baseURL); isUnix {
+		webURL = ""
+	}
 	return &Client{
-		baseURL: strings.TrimRight(baseURL, "/"),
+		baseURL: base,
+		webURL:  webURL,
 		httpClient: &http.Client{
-			Timeout: 2 * time.Minute, // Prevent hanging on unresponsive servers
+			Transport: transport,
+			Timeout:   2 * time.Minute, // Prevent hanging on unresponsive servers
 		},
 	}
 }
@@ -32,12 +55,14 @@ type ChatRequest struct {
 	Message string `json:"message"`
 	Model   string `json:"model,omitempty"`
 	Cwd     string `json:"cwd,omitempty"`
+	Slug    string `json:"slug,omitempty"`
 }
 
 // Conversation represents a conversation response
 type Conversation struct {
 	ConversationID string  `json:"conversation_id"`
 	Slug           *string `json:"slug"`
+	Title          *string `json:"title,omitempty"`
 	Model          *string `json:"model"`
 	Cwd            *string `json:"cwd"`
 	CreatedAt      string  `json:"created_at"`
@@ -69,6 +94,12 @@ type Model struct {
 	Source           string `json:"source,omitempty"`
 	Ready            bool   `json:"ready"`
 	MaxContextTokens int    `json:"max_context_tokens,omitempty"`
+	// Deprecated models still work but are going away; ReplacedBy is the
+	// model ID the backend suggests instead, if any.
+	Deprecated bool   `json:"deprecated,omitempty"`
+	ReplacedBy string `json:"replaced_by,omitempty"`
+	// Aliases are earlier IDs the model went by before being renamed.
+	Aliases []string `json:"aliases,omitempty"`
 }
 
 // Name returns the user-facing name for this model.
@@ -85,8 +116,8 @@ type ModelsResult struct {
 	Models []Model
 }
 
-// FindByName looks up a model by display name first, then by ID.
-// Returns nil if no model matches.
+// FindByName looks up a model by display name first, then by ID, then by
+// an earlier ID it was renamed from. Returns nil if no model matches.
 func (r *ModelsResult) FindByName(name string) *Model {
 	for i := range r.Models {
 		if r.Models[i].Name() == name {
@@ -98,6 +129,11 @@ func (r *ModelsResult) FindByName(name string) *Model {
 			return &r.Models[i]
 		}
 	}
+	for i := range r.Models {
+		if slices.Contains(r.Models[i].Aliases, name) {
+			return &r.Models[i]
+		}
+	}
 	return nil
 }
 
@@ -107,8 +143,9 @@ type StartConversationResult struct {
 	Slug           string
 }
 
-// StartConversation starts a new conversation
-func (c *Client) StartConversation(message, model, cwd string) (StartConversationResult, error) {
+// StartConversation starts a new conversation. A non-empty slug is sent
+// along for the backend to use; backends that ignore it generate their own.
+func (c *Client) StartConversation(message, model, cwd, slug string) (StartConversationResult, error) {
 	reqBody := ChatRequest{
 		Message: message,
 	}
@@ -121,6 +158,8 @@ func (c *Client) StartConversation(message, model, cwd string) (StartConversatio
 		reqBody.Cwd = cwd
 	}
 
+	reqBody.Slug = slug
+
 	body, err := json.Marshal(reqBody)
 	if err != nil {
 		return StartConversationResult{}, fmt.Errorf("failed to marshal request: %w", err)
@@ -143,7 +182,7 @@ func (c *Client) StartConversation(message, model, cwd string) (StartConversatio
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return StartConversationResult{}, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return StartConversationResult{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result struct {
@@ -179,7 +218,7 @@ func (c *Client) GetConversation(conversationID string) ([]byte, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return io.ReadAll(resp.Body)
@@ -218,7 +257,7 @@ func (c *Client) SendMessage(conversationID, message, model string) error {
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -240,11 +279,15 @@ func (c *Client) ListModels() (ModelsResult, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return ModelsResult{}, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return ModelsResult{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ModelsResult{}, fmt.Errorf("failed to read models response: %w", err)
+	}
 	var models []Model
-	if err := json.NewDecoder(resp.Body).Decode(&models); err != nil {
+	if err := UnmarshalLenient(data, &models); err != nil {
 		return ModelsResult{}, fmt.Errorf("failed to decode models response: %w", err)
 	}
 
@@ -269,7 +312,7 @@ func (c *Client) DefaultModel() (string, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return "", &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -306,7 +349,7 @@ func (c *Client) ListConversations() ([]byte, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return io.ReadAll(resp.Body)
@@ -333,7 +376,7 @@ func (c *Client) ListArchivedConversations() ([]byte, error) {
 			return []byte("[]"), nil
 		}
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return io.ReadAll(resp.Body)
@@ -357,7 +400,7 @@ func (c *Client) ArchiveConversation(conversationID string) error {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -381,7 +424,7 @@ func (c *Client) UnarchiveConversation(conversationID string) error {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -405,7 +448,7 @@ func (c *Client) CancelConversation(conversationID string) error {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -413,6 +456,9 @@ func (c *Client) CancelConversation(conversationID string) error {
 
 // DeleteConversation permanently deletes a conversation.
 func (c *Client) DeleteConversation(conversationID string) error {
+	if c.disabled(FeatureDelete) {
+		return ErrDeleteUnsupported
+	}
 	req, err := http.NewRequest("POST", c.baseURL+"/api/conversation/"+conversationID+"/delete", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
@@ -429,7 +475,7 @@ func (c *Client) DeleteConversation(conversationID string) error {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return nil
@@ -452,11 +498,11 @@ func (c *Client) IsConversationWorking(conversationID string) (bool, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return false, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var convs []Conversation
-	if err := json.NewDecoder(resp.Body).Decode(&convs); err != nil {
+	if err := decodeLenient(resp.Body, &convs); err != nil {
 		return false, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -488,11 +534,11 @@ func (c *Client) IsConversationArchived(conversationID string) (bool, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return false, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return false, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var convs []Conversation
-	if err := json.NewDecoder(resp.Body).Decode(&convs); err != nil {
+	if err := decodeLenient(resp.Body, &convs); err != nil {
 		return false, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -522,7 +568,7 @@ func (c *Client) IsConversationArchived(conversationID string) (bool, error) {
 		return false, nil
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&convs); err != nil {
+	if err := decodeLenient(resp.Body, &convs); err != nil {
 		return false, fmt.Errorf("failed to decode response: %w", err)
 	}
 
@@ -549,7 +595,7 @@ func (c *Client) ListSubagents(conversationID string) ([]byte, error) {
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 	return io.ReadAll(resp.Body)
 }
@@ -605,7 +651,7 @@ func (c *Client) ContinueConversation(sourceConversationID, model, cwd string) (
 
 	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return ContinueConversationResult{}, fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return ContinueConversationResult{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	var result struct {
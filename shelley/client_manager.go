@@ -1,7 +1,9 @@
 package shelley
 
 import (
+	"crypto/tls"
 	"fmt"
+	"log"
 	"sync"
 	"time"
 )
@@ -12,15 +14,26 @@ import (
 type ClientManager struct {
 	mu          sync.RWMutex
 	cacheTTL    time.Duration
+	liveTTL     time.Duration // freshness of live files, see CachingClient.SetLiveTTL
 	backends    map[string]*managedClient
 	defaultName string
+	budget      *CacheBudget                            // shared by the caching clients of all backends
+	observer    func(RequestInfo)                       // called after every request to any backend
+	negotiate   bool                                    // clients ask their backend for its capabilities
+	capture     *Capture                                // records the requests of all backends, if set
+	schema      *SchemaWatch                            // checks the responses of all backends, if set
+	capsHook    func(backend string, caps Capabilities) // told what each backend reports, if set
+	tlsConfig   *tls.Config                             // for https backends, if set
+	resilience  *ResilienceOptions                      // retries and circuit breaker of all backends, if set
+	serveStale  bool                                    // caching clients answer from expired entries during outages
 }
 
 // managedClient holds a ShelleyClient and the URL it was created with.
 // Used to detect URL changes for client invalidation.
 type managedClient struct {
-	client ShelleyClient
-	url    string
+	client  ShelleyClient
+	url     string
+	latency *LatencyWindow // the client's recent requests
 }
 
 // NewClientManager creates a new ClientManager.
@@ -32,6 +45,89 @@ func NewClientManager(cacheTTL time.Duration) *ClientManager {
 	}
 }
 
+// SetCacheBudget makes the caches of all backend clients created from now on
+// share budget b.
+func (cm *ClientManager) SetCacheBudget(b *CacheBudget) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.budget = b
+}
+
+// SetLiveTTL sets the live TTL of all caching backend clients created
+// from now on (see CachingClient.SetLiveTTL).
+func (cm *ClientManager) SetLiveTTL(ttl time.Duration) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.liveTTL = ttl
+}
+
+// SetRequestObserver makes all backend clients created from now on report
+// their requests to fn.
+func (cm *ClientManager) SetRequestObserver(fn func(RequestInfo)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.observer = fn
+}
+
+// SetCapture makes all backend clients created from now on record their
+// requests and responses with c.
+func (cm *ClientManager) SetCapture(c *Capture) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.capture = c
+}
+
+// SetSchemaWatch makes all backend clients created from now on check
+// their responses with w.
+func (cm *ClientManager) SetSchemaWatch(w *SchemaWatch) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.schema = w
+}
+
+// SetNegotiation makes all backend clients created from now on negotiate
+// features with their backend (see FeatureNegotiator). Negotiation runs in
+// the background; until it completes every feature is assumed.
+func (cm *ClientManager) SetNegotiation(enabled bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.negotiate = enabled
+}
+
+// SetNegotiationHook makes all backend clients created from now on call fn
+// with the backend's name and capabilities whenever negotiation with it
+// succeeds (see Client.SetNegotiationHook).
+func (cm *ClientManager) SetNegotiationHook(fn func(backend string, caps Capabilities)) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.capsHook = fn
+}
+
+// SetTLSConfig makes all backend clients created from now on use cfg for
+// https backends (see TLSOptions).
+func (cm *ClientManager) SetTLSConfig(cfg *tls.Config) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.tlsConfig = cfg
+}
+
+// SetResilience makes all backend clients created from now on retry,
+// time out and fail fast as configured by opts (see Client.SetResilience).
+func (cm *ClientManager) SetResilience(opts ResilienceOptions) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.resilience = &opts
+}
+
+// SetServeStale makes all caching backend clients created from now on
+// serve expired entries while their backend is unavailable (see
+// CachingClient.SetServeStale).
+func (cm *ClientManager) SetServeStale(enabled bool) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.serveStale = enabled
+}
+
 // GetClient returns the ShelleyClient for the given backend name.
 // Creates the client on first access if it doesn't exist.
 // Returns an error if there's no URL configured for this backend.
@@ -75,22 +171,76 @@ func (cm *ClientManager) EnsureURL(backendName, url string) (ShelleyClient, erro
 	}
 
 	// Create new client
-	baseClient := NewClient(url)
+	baseClient := NewClientWithTLS(url, cm.tlsConfig)
+	latency := NewLatencyWindow()
+	observer := latency.Observe
+	if cm.observer != nil {
+		observer = func(info RequestInfo) {
+			latency.Observe(info)
+			cm.observer(info)
+		}
+	}
+	baseClient.SetObserver(observer)
+	if cm.capture != nil {
+		baseClient.SetCapture(cm.capture)
+	}
+	if cm.schema != nil {
+		baseClient.SetSchemaWatch(cm.schema)
+	}
+	if cm.resilience != nil {
+		baseClient.SetResilience(*cm.resilience)
+	}
+	if cm.capsHook != nil {
+		hook := cm.capsHook
+		baseClient.SetNegotiationHook(func(caps Capabilities) { hook(backendName, caps) })
+	}
 	var client ShelleyClient
 	if cm.cacheTTL > 0 {
-		client = NewCachingClient(baseClient, cm.cacheTTL)
+		cc := NewCachingClient(baseClient, cm.cacheTTL)
+		cc.SetBudget(cm.budget)
+		cc.SetServeStale(cm.serveStale)
+		if cm.liveTTL > 0 {
+			cc.SetLiveTTL(cm.liveTTL)
+		}
+		client = cc
 	} else {
 		client = baseClient
 	}
 
 	cm.backends[backendName] = &managedClient{
-		client: client,
-		url:    url,
+		client:  client,
+		url:     url,
+		latency: latency,
+	}
+	if cm.negotiate {
+		go func() {
+			caps, err := baseClient.Negotiate()
+			switch {
+			case err != nil:
+				log.Printf("Backend %s: capability negotiation failed, assuming all features: %v", backendName, err)
+			case !caps.Negotiated:
+				log.Printf("Backend %s: no capabilities endpoint, assuming all features", backendName)
+			default:
+				log.Printf("Backend %s: version %q, features %v", backendName, caps.Version, caps.Features)
+			}
+		}()
 	}
 
 	return client, nil
 }
 
+// Latency summarizes the recent requests of each backend that has a
+// client, by backend name.
+func (cm *ClientManager) Latency() map[string]LatencyStats {
+	cm.mu.RLock()
+	defer cm.mu.RUnlock()
+	stats := make(map[string]LatencyStats, len(cm.backends))
+	for name, mc := range cm.backends {
+		stats[name] = mc.latency.Stats()
+	}
+	return stats
+}
+
 // InvalidateClient removes the client for the given backend name.
 // The next call to GetClient or EnsureURL will create a new client.
 func (cm *ClientManager) InvalidateClient(backendName string) {
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -56,7 +57,7 @@ func TestStartConversation(t *testing.T) {
 	client := NewClient(server.URL)
 
 	// Test starting a conversation
-	result, err := client.StartConversation("Hello, world!", "test-model", "/test/cwd")
+	result, err := client.StartConversation("Hello, world!", "test-model", "/test/cwd", "my-slug")
 	if err != nil {
 		t.Fatalf("StartConversation failed: %v", err)
 	}
@@ -107,6 +108,10 @@ func TestStartConversation(t *testing.T) {
 	if reqBody.Cwd != "/test/cwd" {
 		t.Errorf("Expected cwd '/test/cwd', got '%s'", reqBody.Cwd)
 	}
+
+	if reqBody.Slug != "my-slug" {
+		t.Errorf("Expected slug 'my-slug', got '%s'", reqBody.Slug)
+	}
 }
 
 func TestGetConversation(t *testing.T) {
@@ -279,6 +284,7 @@ func TestModelsResultFindByName(t *testing.T) {
 			{ID: "predictable", Ready: true},
 			{ID: "custom-f999b9b0", DisplayName: "kimi-2.5-fireworks", Ready: true},
 			{ID: "claude-sonnet", DisplayName: "claude-sonnet", Ready: true},
+			{ID: "claude-sonnet-5", Ready: true, Aliases: []string{"claude-sonnet-4", "predictable"}},
 		},
 	}
 
@@ -307,6 +313,12 @@ func TestModelsResultFindByName(t *testing.T) {
 		t.Errorf("FindByName(claude-sonnet) = %v, want claude-sonnet", m)
 	}
 
+	// Find by an ID the model was renamed from; current names win
+	m = result.FindByName("claude-sonnet-4")
+	if m == nil || m.ID != "claude-sonnet-5" {
+		t.Errorf("FindByName(claude-sonnet-4) = %v, want claude-sonnet-5", m)
+	}
+
 	// Not found
 	m = result.FindByName("nonexistent")
 	if m != nil {
@@ -466,3 +478,67 @@ func TestDeleteConversationError(t *testing.T) {
 		t.Fatal("Expected error for non-existent conversation")
 	}
 }
+
+func TestClientObserver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"messages":[]}`))
+	}))
+	defer server.Close()
+
+	var seen []RequestInfo
+	client := NewClient(server.URL)
+	client.SetObserver(func(info RequestInfo) { seen = append(seen, info) })
+
+	if _, err := client.GetConversation("conv-1"); err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	if len(seen) != 1 {
+		t.Fatalf("expected 1 observed request, got %d", len(seen))
+	}
+	info := seen[0]
+	if info.Method != "GET" || info.Path != "/api/conversation/conv-1" {
+		t.Errorf("observed %s %s", info.Method, info.Path)
+	}
+	if info.ConversationID != "conv-1" {
+		t.Errorf("ConversationID = %q, want conv-1", info.ConversationID)
+	}
+	if info.Status != http.StatusOK || info.Err != nil {
+		t.Errorf("status = %d, err = %v", info.Status, info.Err)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).GetConversation("conv-1")
+	if got := HTTPStatus(err); got != http.StatusTooManyRequests {
+		t.Errorf("HTTPStatus = %d, want 429 (err: %v)", got, err)
+	}
+	if HTTPStatus(fmt.Errorf("plain")) != 0 {
+		t.Error("HTTPStatus of a non-status error should be 0")
+	}
+}
+
+func TestClientObserverErrorBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var seen RequestInfo
+	client := NewClient(server.URL)
+	client.SetObserver(func(info RequestInfo) { seen = info })
+
+	_, err := client.GetConversation("conv-1")
+	if seen.Status != http.StatusServiceUnavailable || seen.ErrorBody != "model overloaded" {
+		t.Errorf("observed status %d, body %q", seen.Status, seen.ErrorBody)
+	}
+	// The caller still sees the whole body.
+	if err == nil || !strings.Contains(err.Error(), "model overloaded") {
+		t.Errorf("err = %v, want the response body", err)
+	}
+}
@@ -0,0 +1,78 @@
+package shelley
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrMessageEditUnsupported is returned when the backend has no message
+// editing endpoint (the server responds 404).
+var ErrMessageEditUnsupported = errors.New("backend does not support editing messages")
+
+// MessageEditor is implemented by clients that can change the text of a
+// message already stored on the backend. Like MappingStore it is optional:
+// callers should type-assert a ShelleyClient and treat a missing
+// implementation like ErrMessageEditUnsupported.
+type MessageEditor interface {
+	// EditMessage replaces the text of a user message.
+	EditMessage(conversationID, messageID, text string) error
+}
+
+var _ MessageEditor = (*Client)(nil)
+var _ MessageEditor = (*CachingClient)(nil)
+
+// EditMessage replaces the text of a message with
+// POST /api/conversation/{id}/messages/{message_id}/edit.
+func (c *Client) EditMessage(conversationID, messageID, text string) error {
+	if c.disabled(FeatureEdit) {
+		return ErrMessageEditUnsupported
+	}
+	body, err := json.Marshal(struct {
+		Message string `json:"message"`
+	}{Message: text})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/api/conversation/"+conversationID+"/messages/"+messageID+"/edit", bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Shelley-Request", "1")
+	req.Header.Set("X-Exedev-Userid", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrMessageEditUnsupported
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
+// EditMessage edits the message on the backend and drops the cached
+// conversation, which still holds the old text.
+func (c *CachingClient) EditMessage(conversationID, messageID, text string) error {
+	if err := c.client.EditMessage(conversationID, messageID, text); err != nil {
+		return err
+	}
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		c.dropLocked(c.conversationCache, "conversation", conversationID)
+		c.mu.Unlock()
+	}
+	return nil
+}
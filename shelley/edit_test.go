@@ -0,0 +1,71 @@
+package shelley
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEditMessage(t *testing.T) {
+	var path, text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("X-Shelley-Request") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Message string `json:"message"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		path, text = r.URL.Path, req.Message
+	}))
+	defer server.Close()
+
+	if err := NewClient(server.URL).EditMessage("c1", "m2", "new text"); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/api/conversation/c1/messages/m2/edit" || text != "new text" {
+		t.Errorf("server got %s %q", path, text)
+	}
+}
+
+func TestEditMessageUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if err := NewClient(server.URL).EditMessage("c1", "m1", "x"); err != ErrMessageEditUnsupported {
+		t.Errorf("expected ErrMessageEditUnsupported, got %v", err)
+	}
+}
+
+func TestCachingClientEditMessageInvalidates(t *testing.T) {
+	body := `{"messages":[{"message_id":"m1","type":"user","user_data":"old"}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			body = `{"messages":[{"message_id":"m1","type":"user","user_data":"new"}]}`
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := NewCachingClient(NewClient(server.URL), time.Hour)
+	if _, err := client.GetConversation("c1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.EditMessage("c1", "m1", "new"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := client.GetConversation("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != body {
+		t.Errorf("GetConversation after edit = %s, want the edited conversation", data)
+	}
+}
@@ -0,0 +1,28 @@
+package shelley
+
+import (
+	"errors"
+	"fmt"
+)
+
+// StatusError is returned when the Shelley server answers a request with an
+// unexpected HTTP status. Callers translate the status into a more specific
+// failure (see fuse.StatusErrno) with errors.As or HTTPStatus.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// HTTPStatus returns the HTTP status carried by err, or 0 if err did not
+// come from a server response.
+func HTTPStatus(err error) int {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode
+	}
+	return 0
+}
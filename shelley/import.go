@@ -0,0 +1,96 @@
+package shelley
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrImportUnsupported is returned when the backend has no conversation
+// import endpoint (the server responds 404).
+var ErrImportUnsupported = errors.New("backend does not support importing conversations")
+
+// ImportedMessage is one message of a transcript to import.
+type ImportedMessage struct {
+	// Type is "user" or "agent".
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// ConversationImporter is implemented by clients that can create a
+// conversation already holding messages, e.g. a transcript from another
+// chat tool. Like MessageEditor it is optional: callers should type-assert
+// a ShelleyClient and treat a missing implementation like
+// ErrImportUnsupported.
+type ConversationImporter interface {
+	// ImportConversation creates a conversation with messages, in order.
+	ImportConversation(messages []ImportedMessage) (StartConversationResult, error)
+}
+
+var _ ConversationImporter = (*Client)(nil)
+var _ ConversationImporter = (*CachingClient)(nil)
+
+// ImportConversation creates a conversation with
+// POST /api/conversations/import.
+func (c *Client) ImportConversation(messages []ImportedMessage) (StartConversationResult, error) {
+	body, err := json.Marshal(struct {
+		Messages []ImportedMessage `json:"messages"`
+	}{Messages: messages})
+	if err != nil {
+		return StartConversationResult{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/api/conversations/import", bytes.NewBuffer(body))
+	if err != nil {
+		return StartConversationResult{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Shelley-Request", "1")
+	req.Header.Set("X-Exedev-Userid", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return StartConversationResult{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return StartConversationResult{}, ErrImportUnsupported
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return StartConversationResult{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var result struct {
+		ConversationID string  `json:"conversation_id"`
+		Slug           *string `json:"slug"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return StartConversationResult{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	res := StartConversationResult{ConversationID: result.ConversationID}
+	if result.Slug != nil {
+		res.Slug = *result.Slug
+	}
+	return res, nil
+}
+
+// ImportConversation imports the conversation and drops the cached
+// conversation list, which doesn't have it yet.
+func (c *CachingClient) ImportConversation(messages []ImportedMessage) (StartConversationResult, error) {
+	result, err := c.client.ImportConversation(messages)
+	if err != nil {
+		return result, err
+	}
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		c.conversationsListCache = nil
+		c.mu.Unlock()
+	}
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package shelley
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestImportConversation(t *testing.T) {
+	var got []ImportedMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/conversations/import" {
+			http.NotFound(w, r)
+			return
+		}
+		var req struct {
+			Messages []ImportedMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		got = req.Messages
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"conversation_id":"c9","slug":"imported"}`))
+	}))
+	defer server.Close()
+
+	messages := []ImportedMessage{{Type: "user", Text: "Hi"}, {Type: "agent", Text: "Hello"}}
+	result, err := NewClient(server.URL).ImportConversation(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ConversationID != "c9" || result.Slug != "imported" {
+		t.Errorf("ImportConversation() = %+v", result)
+	}
+	if !reflect.DeepEqual(got, messages) {
+		t.Errorf("server got %+v", got)
+	}
+}
+
+func TestImportConversationUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	if _, err := NewClient(server.URL).ImportConversation([]ImportedMessage{{Type: "user", Text: "Hi"}}); err != ErrImportUnsupported {
+		t.Errorf("expected ErrImportUnsupported, got %v", err)
+	}
+}
@@ -63,7 +63,7 @@ func TestIntegrationWithRealServer(t *testing.T) {
 	client := NewClient(serverURL)
 
 	// Test starting a conversation
-	result, err := client.StartConversation("Hello, predictable model!", "predictable", tmpDir)
+	result, err := client.StartConversation("Hello, predictable model!", "predictable", tmpDir, "")
 	if err != nil {
 		t.Fatalf("Failed to start conversation: %v", err)
 	}
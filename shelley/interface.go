@@ -18,8 +18,9 @@ type ShelleyClient interface {
 	// DefaultModel returns the default model ID.
 	DefaultModel() (string, error)
 
-	// StartConversation starts a new conversation.
-	StartConversation(message, model, cwd string) (StartConversationResult, error)
+	// StartConversation starts a new conversation. A non-empty slug asks
+	// the backend to use it instead of generating one.
+	StartConversation(message, model, cwd, slug string) (StartConversationResult, error)
 
 	// SendMessage sends a message to an existing conversation.
 	SendMessage(conversationID, message, model string) error
@@ -0,0 +1,100 @@
+package shelley
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Latency windows keep the last latencySamples requests to a backend, and
+// summarize the ones from the last LatencyWindowAge.
+const (
+	latencySamples   = 1024
+	LatencyWindowAge = 5 * time.Minute
+)
+
+// LatencyStats summarizes the requests in a latency window. Times are in
+// milliseconds.
+type LatencyStats struct {
+	Count  int     `json:"count"`
+	Errors int     `json:"errors"` // failed requests and 5xx responses
+	P50    float64 `json:"p50_ms"`
+	P90    float64 `json:"p90_ms"`
+	P99    float64 `json:"p99_ms"`
+	Max    float64 `json:"max_ms"`
+}
+
+// LatencyWindow records how long requests to one backend take, keeping
+// the most recent ones in a ring. It is safe for concurrent use.
+type LatencyWindow struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	samples [latencySamples]latencySample
+	next    int // where the next sample goes
+	n       int // samples recorded, up to latencySamples
+}
+
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+	failed   bool
+}
+
+// NewLatencyWindow returns an empty latency window.
+func NewLatencyWindow() *LatencyWindow {
+	return &LatencyWindow{now: time.Now}
+}
+
+// Observe records a completed request. It has the signature of a request
+// observer (see Client.SetObserver).
+func (w *LatencyWindow) Observe(info RequestInfo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.samples[w.next] = latencySample{
+		at:       w.now(),
+		duration: info.Duration,
+		failed:   info.Err != nil || info.Status >= 500,
+	}
+	w.next = (w.next + 1) % latencySamples
+	w.n = min(w.n+1, latencySamples)
+}
+
+// Stats summarizes the requests recorded in the last LatencyWindowAge.
+func (w *LatencyWindow) Stats() LatencyStats {
+	w.mu.Lock()
+	cutoff := w.now().Add(-LatencyWindowAge)
+	var stats LatencyStats
+	durations := make([]time.Duration, 0, w.n)
+	for _, s := range w.samples[:w.n] {
+		if s.at.Before(cutoff) {
+			continue
+		}
+		durations = append(durations, s.duration)
+		if s.failed {
+			stats.Errors++
+		}
+	}
+	w.mu.Unlock()
+
+	stats.Count = len(durations)
+	if stats.Count == 0 {
+		return stats
+	}
+	slices.Sort(durations)
+	percentile := func(p int) float64 {
+		// Nearest rank: the smallest duration at least p% of requests took.
+		rank := (p*len(durations) + 99) / 100
+		return milliseconds(durations[max(rank, 1)-1])
+	}
+	stats.P50 = percentile(50)
+	stats.P90 = percentile(90)
+	stats.P99 = percentile(99)
+	stats.Max = milliseconds(durations[len(durations)-1])
+	return stats
+}
+
+// milliseconds converts d to milliseconds, to the microsecond.
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
@@ -0,0 +1,47 @@
+package shelley
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLatencyWindowStats(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	w := NewLatencyWindow()
+	w.now = func() time.Time { return now }
+
+	if got := w.Stats(); got != (LatencyStats{}) {
+		t.Errorf("Stats of an empty window = %+v", got)
+	}
+
+	// A slow request from before the window must not count.
+	w.Observe(RequestInfo{Duration: time.Minute})
+	now = now.Add(LatencyWindowAge + time.Second)
+
+	for i := 1; i <= 100; i++ {
+		w.Observe(RequestInfo{Duration: time.Duration(i) * time.Millisecond, Status: 200})
+	}
+	w.Observe(RequestInfo{Duration: 2 * time.Second, Err: errors.New("timeout")})
+	w.Observe(RequestInfo{Duration: 3 * time.Millisecond, Status: 502})
+	w.Observe(RequestInfo{Duration: 4 * time.Millisecond, Status: 404})
+
+	want := LatencyStats{Count: 103, Errors: 2, P50: 50, P90: 91, P99: 100, Max: 2000}
+	if got := w.Stats(); got != want {
+		t.Errorf("Stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestLatencyWindowKeepsRecentSamples(t *testing.T) {
+	w := NewLatencyWindow()
+	for i := 0; i < latencySamples; i++ {
+		w.Observe(RequestInfo{Duration: time.Second})
+	}
+	for i := 0; i < latencySamples/2; i++ {
+		w.Observe(RequestInfo{Duration: time.Millisecond})
+	}
+	got := w.Stats()
+	if got.Count != latencySamples || got.P50 != 1 || got.Max != 1000 {
+		t.Errorf("Stats after the ring wrapped = %+v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package shelley
+
+import "time"
+
+// Live files (working, cancel, messages/count and the like) are read by
+// scripts that poll them, so they want fresher answers than the cache TTL
+// gives, but refetching a whole conversation every time one is read would
+// load the backend more than the content itself is worth. With a live TTL
+// set, they are answered from the conversation list instead, which is
+// fetched at most once per live TTL for all conversations together. A
+// conversation's content is only fetched again for a live file when the
+// list shows it was updated since the last fetch.
+
+// SetLiveTTL sets how old the answers behind live files may be. 0, the
+// default, keeps working state uncached and live files on the cache TTL.
+// It must be called before the client is used.
+func (c *CachingClient) SetLiveTTL(ttl time.Duration) {
+	c.liveTTL = ttl
+	c.liveSeen = make(map[string]string)
+}
+
+// liveConversations returns the conversation list, fetched at most once
+// per live TTL.
+func (c *CachingClient) liveConversations() (map[string]Conversation, error) {
+	c.mu.RLock()
+	entry := c.liveListCache
+	c.mu.RUnlock()
+
+	data := []byte(nil)
+	if entry.isValid() {
+		data = entry.data
+	} else {
+		result, err, _ := c.sf.Do("conversations:live", func() (interface{}, error) {
+			data, err := c.client.ListConversations()
+			if err != nil {
+				return nil, err
+			}
+			c.mu.Lock()
+			c.liveListCache = &cacheEntry{data: data, expiresAt: time.Now().Add(c.liveTTL)}
+			c.mu.Unlock()
+			return data, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		data = result.([]byte)
+	}
+
+	var convs []Conversation
+	if err := UnmarshalLenient(data, &convs); err != nil {
+		return nil, err
+	}
+	byID := make(map[string]Conversation, len(convs))
+	for _, conv := range convs {
+		byID[conv.ConversationID] = conv
+	}
+	return byID, nil
+}
+
+// isWorkingLive is IsConversationWorking answered from the live list.
+func (c *CachingClient) isWorkingLive(conversationID string) (bool, error) {
+	convs, err := c.liveConversations()
+	if err != nil {
+		return false, err
+	}
+	return convs[conversationID].Working, nil
+}
+
+// GetConversationLive returns a conversation for a live file. Without a
+// live TTL, or without caching, it is GetConversation. Otherwise the cached
+// copy is returned, even past the cache TTL, for as long as the live list
+// shows the conversation unchanged since it was fetched. A conversation
+// that isn't in the list, or has no updated_at there, is fetched as usual.
+func (c *CachingClient) GetConversationLive(conversationID string) ([]byte, error) {
+	if c.liveTTL <= 0 || c.cacheTTL <= 0 {
+		return c.GetConversation(conversationID)
+	}
+	convs, err := c.liveConversations()
+	if err != nil {
+		return nil, err
+	}
+	conv, listed := convs[conversationID]
+	if !listed || conv.UpdatedAt == "" {
+		return c.GetConversation(conversationID)
+	}
+
+	c.mu.RLock()
+	entry := c.conversationCache[conversationID]
+	seen, ok := c.liveSeen[conversationID]
+	c.mu.RUnlock()
+	if entry != nil && ok && seen == conv.UpdatedAt {
+		c.budget.Touch(c.budgetKey("conversation", conversationID))
+		return entry.data, nil
+	}
+
+	data, err := c.RefreshConversation(conversationID, 0)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.liveSeen[conversationID] = conv.UpdatedAt
+	c.mu.Unlock()
+	return data, nil
+}
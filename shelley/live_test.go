@@ -0,0 +1,79 @@
+package shelley
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachingClient_Live(t *testing.T) {
+	var mu sync.Mutex
+	updatedAt, working := "2026-01-01T00:00:00Z", true
+	calls := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[r.URL.Path]++
+		switch r.URL.Path {
+		case "/api/conversations":
+			fmt.Fprintf(w, `[{"conversation_id":"conv-1","updated_at":%q,"working":%t}]`, updatedAt, working)
+		case "/api/conversation/conv-1":
+			fmt.Fprintf(w, `{"messages":[],"updated":%q}`, updatedAt)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	count := func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		n := calls[path]
+		calls[path] = 0
+		return n
+	}
+
+	// Content expires from the cache right away; the live list lasts 50ms.
+	client := NewCachingClient(NewClient(server.URL), time.Nanosecond)
+	client.SetLiveTTL(50 * time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		if w, err := client.IsConversationWorking("conv-1"); err != nil || !w {
+			t.Fatalf("IsConversationWorking = %v, %v; want true", w, err)
+		}
+		if _, err := client.GetConversationLive("conv-1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := count("/api/conversations"); n != 1 {
+		t.Errorf("three rounds listed conversations %d times, want 1", n)
+	}
+	if n := count("/api/conversation/conv-1"); n != 1 {
+		t.Errorf("three live reads of an unchanged conversation fetched it %d times, want 1", n)
+	}
+
+	// Once the list shows a change, the next live read after the live TTL
+	// sees it and fetches the conversation again.
+	mu.Lock()
+	updatedAt, working = "2026-01-02T00:00:00Z", false
+	mu.Unlock()
+	if w, _ := client.IsConversationWorking("conv-1"); !w {
+		t.Error("working changed before the live TTL ran out")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if w, _ := client.IsConversationWorking("conv-1"); w {
+		t.Error("working still set after the live TTL ran out")
+	}
+	data, err := client.GetConversationLive("conv-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"messages":[],"updated":"2026-01-02T00:00:00Z"}`; string(data) != want {
+		t.Errorf("GetConversationLive = %s, want %s", data, want)
+	}
+	if n := count("/api/conversation/conv-1"); n != 1 {
+		t.Errorf("live read of a changed conversation fetched it %d times, want 1", n)
+	}
+}
@@ -0,0 +1,116 @@
+package shelley
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrMappingsUnsupported is returned when the backend has no mapping
+// metadata endpoint (the server responds 404).
+var ErrMappingsUnsupported = errors.New("backend does not support mapping metadata")
+
+// MappingRecord is one local-ID↔Shelley-ID association as stored on the
+// backend. It carries enough to rebuild the same directory names on a
+// fresh mount: the local ID, the slug and the user metadata.
+type MappingRecord struct {
+	LocalID        string            `json:"local_id"`
+	ConversationID string            `json:"conversation_id"`
+	Slug           string            `json:"slug,omitempty"`
+	Meta           map[string]string `json:"meta,omitempty"`
+}
+
+// MappingStore is implemented by clients that can persist the FUSE
+// mapping table on the backend. It is optional: callers should type-assert
+// a ShelleyClient and treat a missing implementation like
+// ErrMappingsUnsupported.
+type MappingStore interface {
+	// GetMappings returns the mapping table last stored on the backend.
+	GetMappings() ([]MappingRecord, error)
+
+	// PutMappings replaces the mapping table stored on the backend.
+	PutMappings(records []MappingRecord) error
+}
+
+var _ MappingStore = (*Client)(nil)
+var _ MappingStore = (*CachingClient)(nil)
+
+// GetMappings fetches the mapping table from GET /api/fuse/mappings.
+// An empty table is returned as a nil slice without error.
+func (c *Client) GetMappings() ([]MappingRecord, error) {
+	req, err := http.NewRequest("GET", c.baseURL+"/api/fuse/mappings", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("X-Exedev-Userid", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrMappingsUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var records []MappingRecord
+	if err := json.NewDecoder(resp.Body).Decode(&records); err != nil {
+		return nil, fmt.Errorf("failed to decode mappings: %w", err)
+	}
+	return records, nil
+}
+
+// PutMappings stores the mapping table with PUT /api/fuse/mappings.
+func (c *Client) PutMappings(records []MappingRecord) error {
+	if records == nil {
+		records = []MappingRecord{}
+	}
+	body, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mappings: %w", err)
+	}
+
+	req, err := http.NewRequest("PUT", c.baseURL+"/api/fuse/mappings", bytes.NewBuffer(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Shelley-Request", "1")
+	req.Header.Set("X-Exedev-Userid", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrMappingsUnsupported
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	return nil
+}
+
+// GetMappings fetches the mapping table. It is never cached: it is only
+// read once at mount time.
+func (c *CachingClient) GetMappings() ([]MappingRecord, error) {
+	return c.client.GetMappings()
+}
+
+// PutMappings stores the mapping table on the backend.
+func (c *CachingClient) PutMappings(records []MappingRecord) error {
+	return c.client.PutMappings(records)
+}
@@ -0,0 +1,79 @@
+package shelley
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetMappings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/fuse/mappings" || r.Method != "GET" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"local_id":"abcd1234","conversation_id":"c1","slug":"s","meta":{"k":"v"}}]`))
+	}))
+	defer server.Close()
+
+	records, err := NewClient(server.URL).GetMappings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	r := records[0]
+	if r.LocalID != "abcd1234" || r.ConversationID != "c1" || r.Slug != "s" || r.Meta["k"] != "v" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+}
+
+func TestGetMappingsUnsupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if _, err := client.GetMappings(); err != ErrMappingsUnsupported {
+		t.Errorf("GetMappings: expected ErrMappingsUnsupported, got %v", err)
+	}
+	if err := client.PutMappings(nil); err != ErrMappingsUnsupported {
+		t.Errorf("PutMappings: expected ErrMappingsUnsupported, got %v", err)
+	}
+}
+
+func TestPutMappings(t *testing.T) {
+	var got []MappingRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.Header.Get("X-Shelley-Request") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	err := NewClient(server.URL).PutMappings([]MappingRecord{{LocalID: "abcd1234", ConversationID: "c1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].ConversationID != "c1" {
+		t.Errorf("server received %+v", got)
+	}
+}
+
+func TestPutMappingsServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewClient(server.URL).PutMappings(nil); err == nil {
+		t.Error("expected error for 500 response")
+	}
+}
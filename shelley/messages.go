@@ -1,6 +1,7 @@
 package shelley
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -13,7 +14,7 @@ func ParseMessages(data []byte) ([]Message, error) {
 	var resp struct {
 		Messages []Message `json:"messages"`
 	}
-	if err := json.Unmarshal(data, &resp); err != nil {
+	if err := UnmarshalLenient(data, &resp); err != nil {
 		return nil, fmt.Errorf("failed to parse conversation: %w", err)
 	}
 	return resp.Messages, nil
@@ -37,15 +38,70 @@ func FormatMarkdown(messages []Message) []byte {
 
 	var b strings.Builder
 	for _, m := range messages {
-		header, content := formatMessageMarkdown(&m, toolCallMap)
-		b.WriteString("## ")
-		b.WriteString(header)
-		b.WriteString("\n\n")
-		if content != "" {
-			b.WriteString(content)
-			b.WriteString("\n\n")
+		b.WriteString(markdownSection(&m, toolCallMap))
+	}
+	return []byte(b.String())
+}
+
+// markdownSection renders one message as a "## header" section.
+func markdownSection(m *Message, toolCallMap map[string]ToolCallInfo) string {
+	header, content := formatMessageMarkdown(m, toolCallMap)
+	section := "## " + header + "\n\n"
+	if content != "" {
+		section += content + "\n\n"
+	}
+	return section
+}
+
+// FormatMarkdownChunks renders messages like FormatMarkdown, split into
+// chunks of at most maxBytes. Chunks break only between messages, so a
+// single message larger than maxBytes gets a chunk of its own. The chunks
+// concatenate to exactly FormatMarkdown(messages).
+func FormatMarkdownChunks(messages []Message, maxBytes int) [][]byte {
+	msgPtrs := make([]*Message, len(messages))
+	for i := range messages {
+		msgPtrs[i] = &messages[i]
+	}
+	toolCallMap := BuildToolCallMap(msgPtrs)
+
+	var chunks [][]byte
+	var b strings.Builder
+	for _, m := range messages {
+		section := markdownSection(&m, toolCallMap)
+		if b.Len() > 0 && b.Len()+len(section) > maxBytes {
+			chunks = append(chunks, []byte(b.String()))
+			b.Reset()
+		}
+		b.WriteString(section)
+	}
+	if b.Len() > 0 {
+		chunks = append(chunks, []byte(b.String()))
+	}
+	return chunks
+}
+
+// FormatMarkdownSince renders the messages with a sequence ID above
+// afterSeq like FormatMarkdown, in sequence order. Tool results are named
+// after their calls even when the call is one of the earlier messages.
+func FormatMarkdownSince(messages []Message, afterSeq int) []byte {
+	msgPtrs := make([]*Message, len(messages))
+	for i := range messages {
+		msgPtrs[i] = &messages[i]
+	}
+	toolCallMap := BuildToolCallMap(msgPtrs)
+
+	var newer []*Message
+	for _, m := range msgPtrs {
+		if m.SequenceID > afterSeq {
+			newer = append(newer, m)
 		}
 	}
+	sort.SliceStable(newer, func(i, j int) bool { return newer[i].SequenceID < newer[j].SequenceID })
+
+	var b strings.Builder
+	for _, m := range newer {
+		b.WriteString(markdownSection(m, toolCallMap))
+	}
 	return []byte(b.String())
 }
 
@@ -297,6 +353,92 @@ func formatToolResultContent(item ContentItem, toolCallMap map[string]ToolCallIn
 	return b.String()
 }
 
+// toolResultExtensions maps binary tool result media types to file extensions.
+var toolResultExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// ToolResultPayload extracts the raw payload of a tool_result message along with
+// a file extension describing it: the first decodable binary item of a known
+// media type wins ("png", "jpg", ...); otherwise the text items are concatenated
+// and reported as "json" when they form a JSON object or array, or "txt".
+// ok is false if the message is not a tool result or has no payload.
+func ToolResultPayload(msg *Message) (data []byte, ext string, ok bool) {
+	if msg == nil {
+		return nil, "", false
+	}
+	var raw string
+	if msg.LLMData != nil {
+		raw = *msg.LLMData
+	} else if msg.UserData != nil {
+		raw = *msg.UserData
+	}
+	if raw == "" {
+		return nil, "", false
+	}
+	var content MessageContent
+	if err := json.Unmarshal([]byte(raw), &content); err != nil {
+		return nil, "", false
+	}
+
+	var text strings.Builder
+	found := false
+	for _, item := range content.Content {
+		if item.Type != ContentTypeToolResult {
+			continue
+		}
+		found = true
+		for _, result := range item.ToolResult {
+			if result.Data != "" {
+				if ext, known := toolResultExtensions[strings.ToLower(result.MediaType)]; known {
+					if decoded, err := base64.StdEncoding.DecodeString(result.Data); err == nil {
+						return decoded, ext, true
+					}
+				}
+			}
+			text.WriteString(result.Text)
+		}
+	}
+	if !found || text.Len() == 0 {
+		return nil, "", false
+	}
+
+	out := text.String()
+	trimmed := strings.TrimSpace(out)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return []byte(out), "json", true
+	}
+	return []byte(out), "txt", true
+}
+
+// ToolResultDuration returns how long the tool behind a tool result message
+// ran, from the start and end times the backend recorded on its result.
+// ok is false if the message is not a tool result or lacks either time.
+func ToolResultDuration(msg *Message) (d time.Duration, ok bool) {
+	if msg == nil {
+		return 0, false
+	}
+	var raw string
+	if msg.LLMData != nil {
+		raw = *msg.LLMData
+	} else if msg.UserData != nil {
+		raw = *msg.UserData
+	}
+	var content MessageContent
+	if raw == "" || json.Unmarshal([]byte(raw), &content) != nil {
+		return 0, false
+	}
+	for _, item := range content.Content {
+		if item.Type == ContentTypeToolResult && item.ToolUseStartTime != nil && item.ToolUseEndTime != nil {
+			return item.ToolUseEndTime.Sub(*item.ToolUseStartTime), true
+		}
+	}
+	return 0, false
+}
+
 // extractCommandFromInput extracts a command string from tool input JSON.
 // For bash tools, this extracts the "command" field.
 // For other tools, it returns a formatted representation of the input.
@@ -471,6 +613,61 @@ func FilterFromWithToolMap(messages []Message, person string, n int, toolMap map
 	return nil
 }
 
+// FilterBySlugWithToolMap returns every message whose slug (computed by MessageSlug)
+// matches the given slug, case-insensitively, in conversation order.
+// If toolMap is nil, it builds one from the messages.
+func FilterBySlugWithToolMap(messages []Message, slug string, toolMap map[string]string) []Message {
+	slug = strings.ToLower(slug)
+
+	if toolMap == nil {
+		toolMap = buildToolMapFromSlice(messages)
+	}
+
+	var result []Message
+	for i := range messages {
+		if MessageSlug(&messages[i], toolMap) == slug {
+			result = append(result, messages[i])
+		}
+	}
+	return result
+}
+
+// FilterByToolWithToolMap returns every tool call and tool result message for the
+// named tool, case-insensitively, in conversation order.
+// If toolMap is nil, it builds one from the messages.
+func FilterByToolWithToolMap(messages []Message, tool string, toolMap map[string]string) []Message {
+	tool = strings.ToLower(tool)
+
+	if toolMap == nil {
+		toolMap = buildToolMapFromSlice(messages)
+	}
+
+	var result []Message
+	for i := range messages {
+		if name := MessageToolName(&messages[i], toolMap); name != "" && name == tool {
+			result = append(result, messages[i])
+		}
+	}
+	return result
+}
+
+// MessageToolName returns the lowercased tool name for a tool_use or tool_result
+// message, derived from its slug. Returns "" for messages that are not tool
+// messages, and for tool results whose tool name could not be resolved.
+func MessageToolName(msg *Message, toolMap map[string]string) string {
+	slug := MessageSlug(msg, toolMap)
+	if name, ok := strings.CutSuffix(slug, "-tool"); ok {
+		return name
+	}
+	if slug == "tool-result" {
+		return ""
+	}
+	if name, ok := strings.CutSuffix(slug, "-result"); ok {
+		return name
+	}
+	return ""
+}
+
 // buildToolMapFromSlice builds a tool name map from a slice of Message values.
 // This is a convenience wrapper around BuildToolNameMap for use with []Message.
 func buildToolMapFromSlice(messages []Message) map[string]string {
@@ -481,6 +678,12 @@ func buildToolMapFromSlice(messages []Message) map[string]string {
 	return BuildToolNameMap(msgPtrs)
 }
 
+// MessageText returns the text of a message: the text content of its user
+// or LLM data, or the raw data when no text can be extracted from it.
+func MessageText(m *Message) string {
+	return messageContent(*m)
+}
+
 func messageContent(m Message) string {
 	if m.UserData != nil {
 		text := extractTextContent(*m.UserData)
@@ -620,11 +823,18 @@ type ContentItem struct {
 	ToolUseID  string           `json:"ToolUseID,omitempty"` // References tool use ID in tool_result (Type 6)
 	Input      json.RawMessage  `json:"ToolInput,omitempty"`
 	ToolResult []ToolResultItem `json:"ToolResult,omitempty"`
+	// ToolUseStartTime and ToolUseEndTime bracket the tool's execution on
+	// tool_result items (Type 6), when the backend records them.
+	ToolUseStartTime *time.Time `json:"ToolUseStartTime,omitempty"`
+	ToolUseEndTime   *time.Time `json:"ToolUseEndTime,omitempty"`
 }
 
 // ToolResultItem represents an item in the ToolResult array of a tool_result content.
+// Binary results (e.g. screenshots) carry base64 Data with a MediaType instead of Text.
 type ToolResultItem struct {
-	Text string `json:"Text"`
+	Text      string `json:"Text"`
+	MediaType string `json:"MediaType,omitempty"`
+	Data      string `json:"Data,omitempty"`
 }
 
 // MessageContent represents the parsed content from LLMData or UserData.
@@ -1,6 +1,8 @@
 package shelley
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -75,6 +77,34 @@ func TestFormatMarkdown(t *testing.T) {
 	}
 }
 
+func TestFormatMarkdownChunks(t *testing.T) {
+	whole := FormatMarkdown(sampleMessages)
+
+	chunks := FormatMarkdownChunks(sampleMessages, len(whole)/2)
+	if len(chunks) < 2 {
+		t.Errorf("expected several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > len(whole)/2 {
+			t.Errorf("chunk %d is %d bytes, over the %d byte limit", i, len(c), len(whole)/2)
+		}
+	}
+	if joined := bytes.Join(chunks, nil); !bytes.Equal(joined, whole) {
+		t.Errorf("chunks don't concatenate to FormatMarkdown output:\n%s", joined)
+	}
+
+	// A limit smaller than any message still yields one message per chunk.
+	if got := len(FormatMarkdownChunks(sampleMessages, 1)); got != len(sampleMessages) {
+		t.Errorf("expected %d chunks with a tiny limit, got %d", len(sampleMessages), got)
+	}
+	if got := len(FormatMarkdownChunks(sampleMessages, len(whole))); got != 1 {
+		t.Errorf("expected 1 chunk when everything fits, got %d", got)
+	}
+	if got := FormatMarkdownChunks(nil, 100); got != nil {
+		t.Errorf("expected no chunks for no messages, got %d", len(got))
+	}
+}
+
 func TestGetMessage(t *testing.T) {
 	m := GetMessage(sampleMessages, 3)
 	if m == nil {
@@ -1652,3 +1682,164 @@ func TestFormatMarkdownMultipleToolResults(t *testing.T) {
 		}
 	}
 }
+
+// toolMessages is a conversation with a bash call/result pair and a
+// tool result whose tool name cannot be resolved.
+func toolMessages() []Message {
+	toolUseJSON := `{"Content": [{"Type": 5, "ID": "tool123", "ToolName": "bash", "ToolInput": {"command": "ls"}}]}`
+	toolResultJSON := `{"Content": [{"Type": 6, "ToolUseID": "tool123", "ToolResult": [{"Text": "file1.txt"}]}]}`
+	orphanResultJSON := `{"Content": [{"Type": 6, "ToolUseID": "missing", "ToolResult": [{"Text": "?"}]}]}`
+	return []Message{
+		{MessageID: "m1", ConversationID: "c1", SequenceID: 1, Type: "user", UserData: strPtr("Run ls")},
+		{MessageID: "m2", ConversationID: "c1", SequenceID: 2, Type: "shelley", LLMData: strPtr(toolUseJSON)},
+		{MessageID: "m3", ConversationID: "c1", SequenceID: 3, Type: "user", UserData: strPtr(toolResultJSON)},
+		{MessageID: "m4", ConversationID: "c1", SequenceID: 4, Type: "user", UserData: strPtr(orphanResultJSON)},
+		{MessageID: "m5", ConversationID: "c1", SequenceID: 5, Type: "shelley", LLMData: strPtr("Here are your files")},
+	}
+}
+
+func TestFilterBySlug(t *testing.T) {
+	result := FilterBySlugWithToolMap(sampleMessages, "User", nil)
+	if len(result) != 3 {
+		t.Fatalf("expected 3 user messages, got %d", len(result))
+	}
+	for i, want := range []int{1, 3, 5} {
+		if result[i].SequenceID != want {
+			t.Errorf("result[%d] seq = %d, want %d", i, result[i].SequenceID, want)
+		}
+	}
+}
+
+func TestFilterBySlugExcludesToolResults(t *testing.T) {
+	result := FilterBySlugWithToolMap(toolMessages(), "user", nil)
+	if len(result) != 1 || result[0].SequenceID != 1 {
+		t.Fatalf("expected only seq 1, got %+v", result)
+	}
+}
+
+func TestFilterBySlugNotFound(t *testing.T) {
+	if result := FilterBySlugWithToolMap(sampleMessages, "nobody", nil); result != nil {
+		t.Errorf("expected nil, got %v", result)
+	}
+}
+
+func TestFilterByTool(t *testing.T) {
+	result := FilterByToolWithToolMap(toolMessages(), "Bash", nil)
+	if len(result) != 2 {
+		t.Fatalf("expected tool call and result, got %d messages", len(result))
+	}
+	if result[0].SequenceID != 2 || result[1].SequenceID != 3 {
+		t.Errorf("expected seqs 2,3, got %d,%d", result[0].SequenceID, result[1].SequenceID)
+	}
+}
+
+func TestFilterByToolNotFound(t *testing.T) {
+	if result := FilterByToolWithToolMap(toolMessages(), "patch", nil); result != nil {
+		t.Errorf("expected nil, got %v", result)
+	}
+}
+
+func TestMessageToolName(t *testing.T) {
+	msgs := toolMessages()
+	toolMap := buildToolMapFromSlice(msgs)
+	want := []string{"", "bash", "bash", "", ""}
+	for i := range msgs {
+		if got := MessageToolName(&msgs[i], toolMap); got != want[i] {
+			t.Errorf("seq %d: MessageToolName = %q, want %q", msgs[i].SequenceID, got, want[i])
+		}
+	}
+}
+
+func TestToolResultPayload(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake")
+	tests := []struct {
+		name     string
+		data     string
+		wantExt  string
+		wantData string
+		wantOK   bool
+	}{
+		{
+			name:     "plain text",
+			data:     `{"Content": [{"Type": 6, "ToolUseID": "t1", "ToolResult": [{"Text": "file1.txt\n"}, {"Text": "file2.txt\n"}]}]}`,
+			wantExt:  "txt",
+			wantData: "file1.txt\nfile2.txt\n",
+			wantOK:   true,
+		},
+		{
+			name:     "json object",
+			data:     `{"Content": [{"Type": 6, "ToolUseID": "t1", "ToolResult": [{"Text": " {\"ok\": true}\n"}]}]}`,
+			wantExt:  "json",
+			wantData: " {\"ok\": true}\n",
+			wantOK:   true,
+		},
+		{
+			name:     "invalid json stays text",
+			data:     `{"Content": [{"Type": 6, "ToolUseID": "t1", "ToolResult": [{"Text": "{not json"}]}]}`,
+			wantExt:  "txt",
+			wantData: "{not json",
+			wantOK:   true,
+		},
+		{
+			name:     "png image",
+			data:     `{"Content": [{"Type": 6, "ToolUseID": "t1", "ToolResult": [{"Text": "screenshot"}, {"MediaType": "image/png", "Data": "` + base64.StdEncoding.EncodeToString(png) + `"}]}]}`,
+			wantExt:  "png",
+			wantData: string(png),
+			wantOK:   true,
+		},
+		{
+			name:     "unknown media type falls back to text",
+			data:     `{"Content": [{"Type": 6, "ToolUseID": "t1", "ToolResult": [{"Text": "blob", "MediaType": "application/x-foo", "Data": "AAAA"}]}]}`,
+			wantExt:  "txt",
+			wantData: "blob",
+			wantOK:   true,
+		},
+		{
+			name: "empty result",
+			data: `{"Content": [{"Type": 6, "ToolUseID": "t1"}]}`,
+		},
+		{
+			name: "tool call is not a result",
+			data: `{"Content": [{"Type": 5, "ID": "t1", "ToolName": "bash"}]}`,
+		},
+		{
+			name: "plain message",
+			data: "hello",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := &Message{SequenceID: 1, Type: "user", UserData: strPtr(tt.data)}
+			data, ext, ok := ToolResultPayload(msg)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ext != tt.wantExt {
+				t.Errorf("ext = %q, want %q", ext, tt.wantExt)
+			}
+			if string(data) != tt.wantData {
+				t.Errorf("data = %q, want %q", data, tt.wantData)
+			}
+		})
+	}
+}
+
+func TestToolResultPayloadNil(t *testing.T) {
+	if _, _, ok := ToolResultPayload(nil); ok {
+		t.Error("expected ok=false for nil message")
+	}
+}
+
+func TestToolResultDuration(t *testing.T) {
+	timed := `{"Content": [{"Type": 6, "ToolUseID": "tu_1", "ToolUseStartTime": "2026-01-02T03:04:05Z", "ToolUseEndTime": "2026-01-02T03:04:06.25Z", "ToolResult": [{"Text": "ok"}]}]}`
+	untimed := `{"Content": [{"Type": 6, "ToolUseID": "tu_1", "ToolResult": [{"Text": "ok"}]}]}`
+	if d, ok := ToolResultDuration(&Message{UserData: &timed}); !ok || d != 1250*time.Millisecond {
+		t.Errorf("timed result: got %v, %v; want 1.25s", d, ok)
+	}
+	if _, ok := ToolResultDuration(&Message{UserData: &untimed}); ok {
+		t.Error("expected ok=false without execution times")
+	}
+	if _, ok := ToolResultDuration(nil); ok {
+		t.Error("expected ok=false for nil message")
+	}
+}
@@ -0,0 +1,81 @@
+package shelley
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// RequestInfo describes one completed request to the Shelley server.
+type RequestInfo struct {
+	Method         string
+	Path           string // URL path, e.g. /api/conversation/abc/chat
+	ConversationID string // server conversation ID from the path, if any
+	Status         int    // HTTP status; 0 if the request failed
+	Duration       time.Duration
+	Err            error
+	ErrorBody      string // start of the response body for statuses >= 400
+}
+
+// maxErrorBody bounds RequestInfo.ErrorBody.
+const maxErrorBody = 512
+
+// conversationIDFromPath extracts the conversation ID from an
+// /api/conversation/{id}[/...] path.
+func conversationIDFromPath(p string) string {
+	rest, ok := strings.CutPrefix(p, "/api/conversation/")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, "/")
+	return id
+}
+
+// observingTransport reports every round trip to fn.
+type observingTransport struct {
+	base http.RoundTripper
+	fn   func(RequestInfo)
+}
+
+func (t *observingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	info := RequestInfo{
+		Method:         req.Method,
+		Path:           req.URL.Path,
+		ConversationID: conversationIDFromPath(req.URL.Path),
+		Duration:       time.Since(start),
+		Err:            err,
+	}
+	if resp != nil {
+		info.Status = resp.StatusCode
+		if resp.StatusCode >= 400 {
+			info.ErrorBody = peekBody(resp, maxErrorBody)
+		}
+	}
+	t.fn(info)
+	return resp, err
+}
+
+// peekBody returns up to n bytes from the start of resp's body, leaving the
+// body readable from the beginning for the caller.
+func peekBody(resp *http.Response, n int64) string {
+	peeked, _ := io.ReadAll(io.LimitReader(resp.Body, n))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(peeked), resp.Body), resp.Body}
+	return strings.TrimSpace(string(peeked))
+}
+
+// SetObserver makes the client call fn after every request it sends. It
+// must be called before the client is used.
+func (c *Client) SetObserver(fn func(RequestInfo)) {
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.httpClient.Transport = &observingTransport{base: base, fn: fn}
+}
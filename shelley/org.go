@@ -0,0 +1,87 @@
+package shelley
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FormatOrg formats messages as an Org document: one top-level heading per
+// message, titled like the "## header" of FormatMarkdown, with the
+// message's metadata in a properties drawer and its body below. Fenced code
+// blocks become source blocks. Lines Org would read as a heading or a
+// keyword ("* ...", "#+...") are escaped with a leading comma, as Org
+// itself escapes them in blocks.
+func FormatOrg(messages []Message) []byte {
+	msgPtrs := make([]*Message, len(messages))
+	for i := range messages {
+		msgPtrs[i] = &messages[i]
+	}
+	toolCallMap := BuildToolCallMap(msgPtrs)
+
+	var b strings.Builder
+	for i := range messages {
+		m := &messages[i]
+		header, content := formatMessageMarkdown(m, toolCallMap)
+		b.WriteString("* " + header + "\n")
+		b.WriteString(":PROPERTIES:\n")
+		orgProperty(&b, "MESSAGE_ID", m.MessageID)
+		orgProperty(&b, "SEQUENCE_ID", strconv.Itoa(m.SequenceID))
+		orgProperty(&b, "TYPE", m.Type)
+		orgProperty(&b, "CREATED_AT", m.CreatedAt)
+		if d, ok := ToolResultDuration(m); ok {
+			orgProperty(&b, "DURATION_MS", strconv.FormatInt(d.Milliseconds(), 10))
+		}
+		b.WriteString(":END:\n")
+		if body := orgBody(content); body != "" {
+			b.WriteString("\n" + body + "\n")
+		}
+		b.WriteString("\n")
+	}
+	return []byte(b.String())
+}
+
+// orgProperty writes one line of a properties drawer, unless value is
+// empty. Values are single lines in Org, so newlines become spaces.
+func orgProperty(b *strings.Builder, name, value string) {
+	if value == "" {
+		return
+	}
+	b.WriteString(":" + name + ": " + strings.ReplaceAll(value, "\n", " ") + "\n")
+}
+
+var (
+	orgFence      = regexp.MustCompile("^\\s*(```|~~~)\\s*(\\S*)")
+	orgNeedsComma = regexp.MustCompile(`^(\*+\s|\*+$|#\+|,\*|,#\+)`)
+)
+
+// orgBody turns a message's Markdown body into Org text.
+func orgBody(content string) string {
+	content = strings.Trim(content, "\n")
+	if content == "" {
+		return ""
+	}
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if m := orgFence.FindStringSubmatch(line); m != nil {
+			if inFence {
+				out = append(out, "#+end_src")
+			} else if m[2] != "" {
+				out = append(out, "#+begin_src "+m[2])
+			} else {
+				out = append(out, "#+begin_src")
+			}
+			inFence = !inFence
+			continue
+		}
+		if orgNeedsComma.MatchString(line) {
+			line = "," + line
+		}
+		out = append(out, line)
+	}
+	if inFence {
+		out = append(out, "#+end_src")
+	}
+	return strings.Join(out, "\n")
+}
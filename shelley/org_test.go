@@ -0,0 +1,46 @@
+package shelley
+
+import "testing"
+
+func TestFormatOrg(t *testing.T) {
+	user := "* not a heading\n#+TITLE: nor a keyword\n\n```go\nfmt.Println(1)\n```"
+	agent := `{"Content":[{"Type":2,"Text":"Done."}]}`
+	msgs := []Message{
+		{MessageID: "m1", SequenceID: 1, Type: "user", CreatedAt: "2026-10-16T09:00:00Z", UserData: &user},
+		{MessageID: "m2", SequenceID: 2, Type: "shelley", LLMData: &agent},
+	}
+	want := `* user
+:PROPERTIES:
+:MESSAGE_ID: m1
+:SEQUENCE_ID: 1
+:TYPE: user
+:CREATED_AT: 2026-10-16T09:00:00Z
+:END:
+
+,* not a heading
+,#+TITLE: nor a keyword
+
+#+begin_src go
+fmt.Println(1)
+#+end_src
+
+* agent
+:PROPERTIES:
+:MESSAGE_ID: m2
+:SEQUENCE_ID: 2
+:TYPE: shelley
+:END:
+
+Done.
+
+`
+	if got := string(FormatOrg(msgs)); got != want {
+		t.Errorf("FormatOrg =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestOrgBodyUnclosedFence(t *testing.T) {
+	if got, want := orgBody("```\n* x"), "#+begin_src\n,* x\n#+end_src"; got != want {
+		t.Errorf("orgBody = %q, want %q", got, want)
+	}
+}
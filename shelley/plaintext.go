@@ -0,0 +1,103 @@
+package shelley
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FormatPlainText renders a single message as plain text: the body
+// FormatMarkdown gives it, without the "## header" line and with Markdown
+// markup stripped (see StripMarkdown), ending in a newline. A message
+// without a body gives an empty result.
+func FormatPlainText(m *Message) []byte {
+	_, content := formatMessageMarkdown(m, BuildToolCallMap([]*Message{m}))
+	text := strings.TrimSpace(StripMarkdown(content))
+	if text == "" {
+		return nil
+	}
+	return []byte(text + "\n")
+}
+
+var (
+	mdFence      = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading    = regexp.MustCompile(`^\s{0,3}#{1,6}\s+`)
+	mdQuote      = regexp.MustCompile(`^\s{0,3}>\s?`)
+	mdBullet     = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdRule       = regexp.MustCompile(`^\s{0,3}(-(\s*-){2,}|\*(\s*\*){2,}|_(\s*_){2,})\s*$`)
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	mdAutolink   = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdCode       = regexp.MustCompile("`([^`]+)`")
+	mdStrong     = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdStrike     = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	mdEmStar     = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	mdEmUnder    = regexp.MustCompile(`(^|[^\w])_(\S(?:[^_]*?\S)?)_([^\w]|$)`)
+	mdEscape     = regexp.MustCompile(`\\([\\` + "`" + `*_{}\[\]()#+\-.!~>|])`)
+	mdBlankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// StripMarkdown removes Markdown markup from s, for readers such as speech
+// synthesis or SMS that want the words only. Headings, quotes, list
+// bullets, rules and code fences lose their markers; links and images are
+// replaced by their text; emphasis and inline code by what they enclose.
+// The contents of fenced code blocks are kept as they are.
+func StripMarkdown(s string) string {
+	var out []string
+	inFence := false
+	for _, line := range strings.Split(s, "\n") {
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			out = append(out, line)
+			continue
+		}
+		if mdRule.MatchString(line) {
+			out = append(out, "")
+			continue
+		}
+		for mdQuote.MatchString(line) {
+			line = mdQuote.ReplaceAllString(line, "")
+		}
+		line = mdHeading.ReplaceAllString(line, "")
+		line = mdBullet.ReplaceAllString(line, "$1")
+		out = append(out, stripInline(line))
+	}
+	return mdBlankLines.ReplaceAllString(strings.Join(out, "\n"), "\n\n")
+}
+
+// stripInline removes the span-level markup of one line. Inline code is
+// split out first, so markup inside it is left alone.
+func stripInline(line string) string {
+	parts := mdCode.Split(line, -1)
+	codes := mdCode.FindAllStringSubmatch(line, -1)
+	var b strings.Builder
+	for i, part := range parts {
+		// Escaped characters are hidden from the patterns until the end.
+		part = mdEscape.ReplaceAllStringFunc(part, func(esc string) string {
+			return string(escapeBase + rune(esc[1]))
+		})
+		part = mdImage.ReplaceAllString(part, "$1")
+		part = mdLink.ReplaceAllString(part, "$1")
+		part = mdAutolink.ReplaceAllString(part, "$1")
+		part = mdStrong.ReplaceAllString(part, "$2")
+		part = mdStrike.ReplaceAllString(part, "$1")
+		part = mdEmStar.ReplaceAllString(part, "$1")
+		part = mdEmUnder.ReplaceAllString(part, "$1$2$3")
+		b.WriteString(strings.Map(func(r rune) rune {
+			if r >= escapeBase && r < escapeBase+128 {
+				return r - escapeBase
+			}
+			return r
+		}, part))
+		if i < len(codes) {
+			b.WriteString(codes[i][1])
+		}
+	}
+	return b.String()
+}
+
+// escapeBase maps a backslash-escaped ASCII character to a rune in the
+// Unicode private use area while a line is being stripped.
+const escapeBase = 0xE000
@@ -0,0 +1,33 @@
+package shelley
+
+import "testing"
+
+func TestStripMarkdown(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"# Plan\n\nFix the **build** first.", "Plan\n\nFix the build first."},
+		{"Read *this* and _that_, not snake_case_names.", "Read this and that, not snake_case_names."},
+		{"See [the docs](https://example.com) or <https://example.org>.", "See the docs or https://example.org."},
+		{"![diagram](d.png)", "diagram"},
+		{"Run `go test ./...` with `-run *Foo*`.", "Run go test ./... with -run *Foo*."},
+		{"- one\n* two\n  + nested\n1. numbered", "one\ntwo\n  nested\n1. numbered"},
+		{"> quoted\n> > twice", "quoted\ntwice"},
+		{"above\n\n---\n\nbelow", "above\n\nbelow"},
+		{"```go\nx := *p // **not bold**\n```", "x := *p // **not bold**"},
+		{"~~old~~ new, 2 * 3 * 4, \\*literal\\*", "old new, 2 * 3 * 4, *literal*"},
+	} {
+		if got := StripMarkdown(tc.in); got != tc.want {
+			t.Errorf("StripMarkdown(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestFormatPlainText(t *testing.T) {
+	user := "Please **fix** the build"
+	if got := string(FormatPlainText(&Message{Type: "user", UserData: &user})); got != "Please fix the build\n" {
+		t.Errorf("FormatPlainText = %q", got)
+	}
+	empty := ""
+	if got := FormatPlainText(&Message{Type: "user", UserData: &empty}); len(got) != 0 {
+		t.Errorf("FormatPlainText of an empty message = %q, want nothing", got)
+	}
+}
@@ -0,0 +1,244 @@
+package shelley
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// A client with resilience options rides out a flaky or restarting backend
+// instead of failing every read at the first hiccup:
+//
+//   - Reads (GET and HEAD) that fail to connect, time out or get a 500,
+//     502, 503 or 504 are tried again, up to Retries more times, waiting
+//     Backoff, then twice as long, and so on, at most maxBackoff. Writes
+//     are never repeated, since the first attempt may have reached the
+//     server.
+//   - Each attempt, including reading the response, is bounded by
+//     RequestTimeout, well below the client's overall two minutes.
+//   - With a BreakerThreshold, after that many requests in a row have
+//     failed that way, the circuit opens: for BreakerCooldown every
+//     request fails at once with ErrCircuitOpen rather than waiting on a
+//     dead server. Then one request is let through; if it succeeds the
+//     circuit closes again.
+//
+// It works on the transport, like SetObserver, so the client keeps its type
+// and its optional interfaces. Streams are passed through untouched: they
+// stay open as long as a conversation is followed.
+
+// ErrCircuitOpen is the error of requests refused because the backend has
+// been failing (see ResilienceOptions).
+var ErrCircuitOpen = errors.New("backend unavailable: circuit open after repeated failures")
+
+// Defaults of ResilienceOptions fields left zero.
+const (
+	DefaultBackoff         = 250 * time.Millisecond
+	DefaultBreakerCooldown = 10 * time.Second
+)
+
+// maxBackoff bounds the wait between retries.
+const maxBackoff = 5 * time.Second
+
+// ResilienceOptions configures retries, timeouts and the circuit breaker of
+// a client.
+type ResilienceOptions struct {
+	Retries          int           // further attempts of a failed read; 0 for none
+	RequestTimeout   time.Duration // bound on each attempt; 0 for only the client's timeout
+	Backoff          time.Duration // wait before the first retry (default DefaultBackoff)
+	BreakerThreshold int           // failed requests in a row that open the circuit; 0 for no circuit breaker
+	BreakerCooldown  time.Duration // how long the circuit stays open (default DefaultBreakerCooldown)
+}
+
+// Unavailable reports whether err means the backend couldn't be reached or
+// couldn't answer: a connection failure, a timeout, a 500, 502, 503 or 504,
+// or ErrCircuitOpen. Errors about the request itself, like a 404, are not.
+func Unavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) {
+		return true
+	}
+	if retryableStatus(HTTPStatus(err)) {
+		return true
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// retryableStatus reports whether a response with the given status means
+// the server is in trouble rather than refusing the request.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// SetResilience makes the client retry failed reads, bound each request
+// and fail fast while the backend is down, as configured by opts. It must
+// be called before the client is used.
+func (c *Client) SetResilience(opts ResilienceOptions) {
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = DefaultBreakerCooldown
+	}
+	c.httpClient.Transport = &resilientTransport{
+		base:    base,
+		opts:    opts,
+		breaker: &breaker{name: c.baseURL, threshold: opts.BreakerThreshold, cooldown: opts.BreakerCooldown},
+	}
+}
+
+// resilientTransport applies ResilienceOptions to the requests of a client.
+type resilientTransport struct {
+	base    http.RoundTripper
+	opts    ResilienceOptions
+	breaker *breaker
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept") == "text/event-stream" {
+		return t.base.RoundTrip(req)
+	}
+	probe, ok := t.breaker.allow()
+	if !ok {
+		return nil, ErrCircuitOpen
+	}
+	retries := 0
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		retries = t.opts.Retries
+	}
+	backoff := t.opts.Backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req)
+		if req.Context().Err() != nil {
+			// The caller gave up; that says nothing about the backend.
+			t.breaker.abandon(probe)
+			return resp, err
+		}
+		failed := err != nil || retryableStatus(resp.StatusCode)
+		if !failed || attempt >= retries {
+			t.breaker.record(probe, !failed)
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(backoff):
+		case <-req.Context().Done():
+			t.breaker.abandon(probe)
+			return nil, req.Context().Err()
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// attempt sends req once, bounded by RequestTimeout until its response
+// body is closed.
+func (t *resilientTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.opts.RequestTimeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.opts.RequestTimeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases an attempt's timeout once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// breaker counts consecutive failed requests to one backend and opens the
+// circuit when there are too many.
+type breaker struct {
+	name      string
+	threshold int // 0: never open
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int       // failed requests in a row
+	openUntil time.Time // while failures >= threshold, when to let a probe through
+	probing   bool      // a probe is in flight
+}
+
+// allow reports whether a request may be sent and whether it is the probe
+// of an open circuit.
+func (b *breaker) allow() (probe, ok bool) {
+	if b.threshold <= 0 {
+		return false, true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return false, true
+	}
+	if b.probing || time.Now().Before(b.openUntil) {
+		return false, false
+	}
+	b.probing = true
+	return true, true
+}
+
+// record counts the outcome of a request allowed by allow.
+func (b *breaker) record(probe, ok bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if ok {
+		if b.failures >= b.threshold {
+			log.Printf("Backend %s is answering again; circuit closed", b.name)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures == b.threshold || probe {
+		log.Printf("Backend %s failed %d requests in a row; failing requests for %s", b.name, b.failures, b.cooldown)
+	}
+	if b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// abandon forgets a request allowed by allow whose caller gave up on it.
+func (b *breaker) abandon(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
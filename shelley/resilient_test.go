@@ -0,0 +1,177 @@
+package shelley
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer serves conversation conv-1, failing the first fail requests
+// with status.
+func flakyServer(t *testing.T, fail int32, status int) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= fail {
+			http.Error(w, "restarting", status)
+			return
+		}
+		w.Write([]byte(`{"messages":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestResilienceRetriesReads(t *testing.T) {
+	server, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	client := NewClient(server.URL)
+	client.SetResilience(ResilienceOptions{Retries: 2, Backoff: time.Millisecond})
+	if _, err := client.GetConversation("conv-1"); err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 3 {
+		t.Errorf("backend saw %d requests, want 3", got)
+	}
+
+	// Writes are sent once.
+	server, calls = flakyServer(t, 1, http.StatusServiceUnavailable)
+	client = NewClient(server.URL)
+	client.SetResilience(ResilienceOptions{Retries: 2, Backoff: time.Millisecond})
+	if err := client.SendMessage("conv-1", "hi", ""); HTTPStatus(err) != http.StatusServiceUnavailable {
+		t.Errorf("SendMessage = %v, want the 503", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("backend saw %d sends, want 1", got)
+	}
+
+	// Errors about the request itself aren't retried.
+	server, calls = flakyServer(t, 1, http.StatusNotFound)
+	client = NewClient(server.URL)
+	client.SetResilience(ResilienceOptions{Retries: 2, Backoff: time.Millisecond})
+	if _, err := client.GetConversation("conv-1"); HTTPStatus(err) != http.StatusNotFound || Unavailable(err) {
+		t.Errorf("GetConversation = %v, want a 404 that isn't Unavailable", err)
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("backend saw %d requests for a 404, want 1", got)
+	}
+}
+
+func TestResilienceRequestTimeout(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`{"messages":[]}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL)
+	client.SetResilience(ResilienceOptions{Retries: 1, RequestTimeout: 100 * time.Millisecond, Backoff: time.Millisecond})
+	start := time.Now()
+	if _, err := client.GetConversation("conv-1"); err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("GetConversation took %v; the hung attempt should have timed out", elapsed)
+	}
+}
+
+func TestResilienceCircuitBreaker(t *testing.T) {
+	var down atomic.Bool
+	var calls int32
+	down.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if down.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"messages":[]}`))
+	}))
+	defer server.Close()
+	client := NewClient(server.URL)
+	client.SetResilience(ResilienceOptions{BreakerThreshold: 3, BreakerCooldown: 100 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetConversation("conv-1"); HTTPStatus(err) != http.StatusBadGateway {
+			t.Fatalf("request %d = %v, want the 502", i, err)
+		}
+	}
+	_, err := client.GetConversation("conv-1")
+	if !errors.Is(err, ErrCircuitOpen) || !Unavailable(err) {
+		t.Fatalf("request after 3 failures = %v, want ErrCircuitOpen", err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 3 {
+		t.Errorf("backend saw %d requests, want 3: the open circuit shouldn't reach it", got)
+	}
+
+	// Without a threshold there is no circuit breaker.
+	plain := NewClient(server.URL)
+	plain.SetResilience(ResilienceOptions{})
+	for i := 0; i < 10; i++ {
+		if _, err := plain.GetConversation("conv-1"); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("request %d without a threshold = %v", i, err)
+		}
+	}
+
+	// After the cooldown a probe gets through, and closes the circuit.
+	down.Store(false)
+	time.Sleep(150 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetConversation("conv-1"); err != nil {
+			t.Fatalf("request %d after cooldown: %v", i, err)
+		}
+	}
+}
+
+func TestCachingClientServeStale(t *testing.T) {
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/conversation/conv-1":
+			w.Write([]byte(`{"messages":[]}`))
+		case "/api/conversations":
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	caching := NewCachingClient(NewClient(server.URL), 10*time.Millisecond)
+	caching.SetServeStale(true)
+	want, err := caching.GetConversation("conv-1")
+	if err != nil {
+		t.Fatalf("GetConversation: %v", err)
+	}
+	if _, err := caching.ListConversations(); err != nil {
+		t.Fatalf("ListConversations: %v", err)
+	}
+
+	down.Store(true)
+	time.Sleep(20 * time.Millisecond)
+	got, err := caching.GetConversation("conv-1")
+	if err != nil || string(got) != string(want) {
+		t.Errorf("GetConversation during outage = %q, %v; want the expired copy", got, err)
+	}
+	if _, err := caching.ListConversations(); err != nil {
+		t.Errorf("ListConversations during outage: %v", err)
+	}
+	// Nothing cached, nothing to serve.
+	if _, err := caching.GetConversation("conv-2"); HTTPStatus(err) != http.StatusServiceUnavailable {
+		t.Errorf("GetConversation of an uncached conversation = %v, want the 503", err)
+	}
+	// Refreshes ask for the backend's copy and still fail.
+	if _, err := caching.RefreshConversation("conv-1", 0); err == nil {
+		t.Error("RefreshConversation during outage succeeded")
+	}
+}
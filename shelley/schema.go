@@ -0,0 +1,456 @@
+package shelley
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaWatch compares the JSON the backend sends with the fields this
+// client knows, so API drift shows up as a warning and in a report instead
+// of as files that are mysteriously empty. For every endpoint with a known
+// response shape it records fields the backend sent that the client doesn't
+// know, fields the client expects that were absent, and fields of the wrong
+// JSON type. The first time a mismatch is seen it is logged.
+//
+// Responses are parsed leniently anyway (see UnmarshalLenient), so a
+// mismatch costs at most the fields involved.
+type SchemaWatch struct {
+	mu        sync.Mutex
+	endpoints map[string]*endpointReport
+	logf      func(format string, args ...any)
+}
+
+// schemaCheckInterval spaces out the responses checked per endpoint: the
+// shape of a backend's responses doesn't change from one request to the
+// next, and conversation bodies can be large.
+const schemaCheckInterval = time.Minute
+
+// maxSchemaBody is the largest response body checked.
+const maxSchemaBody = 4 << 20
+
+// NewSchemaWatch creates a SchemaWatch that logs new mismatches with
+// log.Printf.
+func NewSchemaWatch() *SchemaWatch {
+	return &SchemaWatch{endpoints: make(map[string]*endpointReport), logf: log.Printf}
+}
+
+// SetSchemaWatch makes the client check its responses with w. It must be
+// called before the client is used.
+func (c *Client) SetSchemaWatch(w *SchemaWatch) {
+	base := c.httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	c.httpClient.Transport = &schemaTransport{base: base, watch: w}
+}
+
+// responseSchema is the shape of one endpoint's successful responses: a
+// JSON array of objects, or an object holding such an array in a field.
+type responseSchema struct {
+	endpoint string // method and path pattern, as reported
+	method   string
+	match    func(path string) bool
+	list     string // field holding the array; "" if the body is the array
+	elem     reflect.Type
+}
+
+// apiSegments returns the segments of path after /api/.
+func apiSegments(path string) []string {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(rest, "/"), "/")
+}
+
+func pathIs(want ...string) func(string) bool {
+	return func(path string) bool {
+		segs := apiSegments(path)
+		if len(segs) != len(want) {
+			return false
+		}
+		for i, w := range want {
+			if w != "*" && segs[i] != w {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+var responseSchemas = []responseSchema{
+	{"GET /api/conversations", "GET", pathIs("conversations"), "", reflect.TypeOf(Conversation{})},
+	{"GET /api/conversations/archived", "GET", pathIs("conversations", "archived"), "", reflect.TypeOf(Conversation{})},
+	{"GET /api/conversation/{id}/subagents", "GET", pathIs("conversation", "*", "subagents"), "", reflect.TypeOf(Conversation{})},
+	{"GET /api/conversation/{id}", "GET", pathIs("conversation", "*"), "messages", reflect.TypeOf(Message{})},
+	{"GET /api/models", "GET", pathIs("models"), "", reflect.TypeOf(Model{})},
+}
+
+func schemaFor(method, path string) *responseSchema {
+	for i := range responseSchemas {
+		if s := &responseSchemas[i]; s.method == method && s.match(path) {
+			return s
+		}
+	}
+	return nil
+}
+
+// schemaTransport hands successful responses of known endpoints to a
+// SchemaWatch once the caller has read and closed them.
+type schemaTransport struct {
+	base  http.RoundTripper
+	watch *SchemaWatch
+}
+
+func (t *schemaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	schema := schemaFor(req.Method, req.URL.Path)
+	if schema == nil || !t.watch.due(schema.endpoint) {
+		return resp, err
+	}
+	resp.Body = &schemaBody{ReadCloser: resp.Body, schema: schema, watch: t.watch}
+	return resp, nil
+}
+
+// schemaBody keeps a copy of the body as it is read and checks it on Close
+// if it was read to the end.
+type schemaBody struct {
+	io.ReadCloser
+	schema   *responseSchema
+	watch    *SchemaWatch
+	buf      bytes.Buffer
+	complete bool
+	tooLarge bool
+	once     sync.Once
+}
+
+func (b *schemaBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.tooLarge {
+		if b.buf.Len()+n > maxSchemaBody {
+			b.tooLarge = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		b.complete = true
+	}
+	return n, err
+}
+
+func (b *schemaBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		if b.complete && !b.tooLarge {
+			b.watch.Check(b.schema.endpoint, b.buf.Bytes())
+		}
+	})
+	return err
+}
+
+// endpointReport accumulates what was seen on one endpoint.
+type endpointReport struct {
+	checked   int       // responses checked
+	lastCheck time.Time // when a response was last picked for checking
+	issues    map[string]*SchemaIssue
+}
+
+// SchemaIssue is one kind of mismatch seen on an endpoint.
+type SchemaIssue struct {
+	Kind     string    `json:"kind"` // "unknown", "missing" or "type"
+	Field    string    `json:"field"`
+	Detail   string    `json:"detail,omitempty"` // for "type": what was sent and expected
+	Count    int       `json:"count"`            // responses it was seen in
+	LastSeen time.Time `json:"last_seen"`
+}
+
+func (i *SchemaIssue) String() string {
+	switch i.Kind {
+	case "unknown":
+		return fmt.Sprintf("unknown field %q", i.Field)
+	case "missing":
+		return fmt.Sprintf("missing field %q", i.Field)
+	}
+	return fmt.Sprintf("field %q %s", i.Field, i.Detail)
+}
+
+// due reports whether the next response of endpoint should be checked, and
+// if so marks it as picked.
+func (w *SchemaWatch) due(endpoint string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r := w.report(endpoint)
+	if !r.lastCheck.IsZero() && time.Since(r.lastCheck) < schemaCheckInterval {
+		return false
+	}
+	r.lastCheck = time.Now()
+	return true
+}
+
+func (w *SchemaWatch) report(endpoint string) *endpointReport {
+	r := w.endpoints[endpoint]
+	if r == nil {
+		r = &endpointReport{issues: make(map[string]*SchemaIssue)}
+		w.endpoints[endpoint] = r
+	}
+	return r
+}
+
+// Check compares a response body of endpoint (as named in the report, e.g.
+// "GET /api/conversations") with the fields the client knows.
+func (w *SchemaWatch) Check(endpoint string, body []byte) {
+	var schema *responseSchema
+	for i := range responseSchemas {
+		if responseSchemas[i].endpoint == endpoint {
+			schema = &responseSchemas[i]
+			break
+		}
+	}
+	if schema == nil {
+		return
+	}
+	found := checkResponse(schema, body)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	r := w.report(endpoint)
+	r.checked++
+	now := time.Now()
+	for _, issue := range found {
+		key := issue.Kind + " " + issue.Field + " " + issue.Detail
+		seen := r.issues[key]
+		if seen == nil {
+			seen = &issue
+			r.issues[key] = seen
+			w.logf("warning: backend API drift on %s: %s", endpoint, seen)
+		}
+		seen.Count++
+		seen.LastSeen = now
+	}
+}
+
+// checkResponse returns the mismatches in body, one per field and kind.
+func checkResponse(schema *responseSchema, body []byte) []SchemaIssue {
+	list := json.RawMessage(body)
+	if schema.list != "" {
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(body, &obj); err != nil {
+			return []SchemaIssue{{Kind: "type", Field: "(response)", Detail: "is not a JSON object"}}
+		}
+		var ok bool
+		if list, ok = obj[schema.list]; !ok {
+			return []SchemaIssue{{Kind: "missing", Field: schema.list}}
+		}
+	}
+	var elems []map[string]json.RawMessage
+	if err := json.Unmarshal(list, &elems); err != nil {
+		field := schema.list
+		if field == "" {
+			field = "(response)"
+		}
+		if bytes.Equal(bytes.TrimSpace(list), []byte("null")) {
+			return nil
+		}
+		return []SchemaIssue{{Kind: "type", Field: field, Detail: "is not an array of objects"}}
+	}
+
+	fields := knownFields(schema.elem)
+	issues := make(map[string]SchemaIssue)
+	add := func(i SchemaIssue) { issues[i.Kind+" "+i.Field] = i }
+	for _, elem := range elems {
+		for name, raw := range elem {
+			f, ok := fields[name]
+			if !ok {
+				add(SchemaIssue{Kind: "unknown", Field: name})
+				continue
+			}
+			if got := jsonKind(raw); !f.accepts(got) {
+				add(SchemaIssue{Kind: "type", Field: name, Detail: fmt.Sprintf("is %s, want %s", got, f.kind)})
+			}
+		}
+		for name, f := range fields {
+			if _, ok := elem[name]; !ok && f.required {
+				add(SchemaIssue{Kind: "missing", Field: name})
+			}
+		}
+	}
+	out := make([]SchemaIssue, 0, len(issues))
+	for _, i := range issues {
+		out = append(out, i)
+	}
+	return out
+}
+
+// knownField is what the client expects of one JSON field.
+type knownField struct {
+	kind     string // JSON type: string, number, boolean, array or object
+	nullable bool
+	required bool // not omitempty: the backend is expected to send it
+}
+
+func (f knownField) accepts(kind string) bool {
+	return kind == f.kind || kind == "null" && f.nullable
+}
+
+// knownFields derives the expected fields from t's json tags.
+func knownFields(t reflect.Type) map[string]knownField {
+	fields := make(map[string]knownField)
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("json")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" || !sf.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		ft := sf.Type
+		f := knownField{required: !strings.Contains(opts, "omitempty")}
+		if ft.Kind() == reflect.Pointer {
+			f.nullable = true
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.String:
+			f.kind = "string"
+		case reflect.Bool:
+			f.kind = "boolean"
+		case reflect.Int, reflect.Int64, reflect.Int32, reflect.Float64, reflect.Float32, reflect.Uint, reflect.Uint64:
+			f.kind = "number"
+		case reflect.Slice:
+			f.kind, f.nullable = "array", true
+		default:
+			f.kind, f.nullable = "object", true
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// jsonKind returns the JSON type of a raw value.
+func jsonKind(raw json.RawMessage) string {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 {
+		return "null"
+	}
+	switch raw[0] {
+	case '"':
+		return "string"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case 't', 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// EndpointSchemaReport is the report for one endpoint.
+type EndpointSchemaReport struct {
+	Endpoint string        `json:"endpoint"`
+	Checked  int           `json:"checked"`
+	Issues   []SchemaIssue `json:"issues"`
+}
+
+// Report returns what was seen on each endpoint checked so far, sorted by
+// endpoint, with each endpoint's issues sorted by field.
+func (w *SchemaWatch) Report() []EndpointSchemaReport {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var out []EndpointSchemaReport
+	for endpoint, r := range w.endpoints {
+		if r.checked == 0 {
+			continue
+		}
+		er := EndpointSchemaReport{Endpoint: endpoint, Checked: r.checked, Issues: []SchemaIssue{}}
+		for _, i := range r.issues {
+			er.Issues = append(er.Issues, *i)
+		}
+		sort.Slice(er.Issues, func(a, b int) bool {
+			if er.Issues[a].Field != er.Issues[b].Field {
+				return er.Issues[a].Field < er.Issues[b].Field
+			}
+			return er.Issues[a].Kind < er.Issues[b].Kind
+		})
+		out = append(out, er)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].Endpoint < out[b].Endpoint })
+	return out
+}
+
+// Handler returns an http.Handler that serves the report as text, or as
+// JSON with the ?json query parameter.
+func (w *SchemaWatch) Handler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		report := w.Report()
+		if _, wantJSON := r.URL.Query()["json"]; wantJSON {
+			rw.Header().Set("Content-Type", "application/json")
+			if report == nil {
+				report = []EndpointSchemaReport{}
+			}
+			if err := json.NewEncoder(rw).Encode(report); err != nil {
+				http.Error(rw, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if len(report) == 0 {
+			fmt.Fprint(rw, "no backend responses checked yet\n")
+			return
+		}
+		for _, er := range report {
+			fmt.Fprintf(rw, "%s: %d responses checked", er.Endpoint, er.Checked)
+			if len(er.Issues) == 0 {
+				fmt.Fprint(rw, ", no mismatches\n")
+				continue
+			}
+			fmt.Fprint(rw, "\n")
+			for i := range er.Issues {
+				issue := &er.Issues[i]
+				fmt.Fprintf(rw, "  %s (%d, last %s)\n", issue, issue.Count, issue.LastSeen.UTC().Format(time.RFC3339))
+			}
+		}
+	})
+}
+
+// UnmarshalLenient is json.Unmarshal, except that values of the wrong JSON
+// type don't fail the whole response: like any field the client doesn't
+// know, they are skipped and the rest is decoded. A SchemaWatch reports
+// them.
+func UnmarshalLenient(data []byte, v any) error {
+	err := json.Unmarshal(data, v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return nil
+	}
+	return err
+}
+
+// decodeLenient is UnmarshalLenient for a stream.
+func decodeLenient(r io.Reader, v any) error {
+	err := json.NewDecoder(r).Decode(v)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return nil
+	}
+	return err
+}
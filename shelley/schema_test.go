@@ -0,0 +1,99 @@
+package shelley
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSchemaWatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/conversations":
+			// archived_at is new, updated_at went missing, created_at became a number.
+			w.Write([]byte(`[{"conversation_id":"c1","slug":"one","model":null,"cwd":null,"created_at":1760000000,"working":false,"archived_at":null}]`))
+		case "/api/conversation/c1":
+			w.Write([]byte(`{"messages":[{"message_id":"m1","conversation_id":"c1","sequence_id":1,"type":"user","created_at":"2026-10-16T09:00:00Z"}],"agent_working":false}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	watch := NewSchemaWatch()
+	var logged []string
+	watch.logf = func(format string, args ...any) { logged = append(logged, fmt.Sprintf(format, args...)) }
+	client := NewClient(server.URL)
+	client.SetSchemaWatch(watch)
+
+	data, err := client.ListConversations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var convs []Conversation
+	if err := UnmarshalLenient(data, &convs); err != nil {
+		t.Fatalf("lenient parse failed: %v", err)
+	}
+	if len(convs) != 1 || convs[0].ConversationID != "c1" || derefSlug(convs[0]) != "one" {
+		t.Errorf("lenient parse lost the rest of the conversation: %+v", convs)
+	}
+	if _, err := client.GetConversation("c1"); err != nil {
+		t.Fatal(err)
+	}
+
+	report := watch.Report()
+	if len(report) != 2 {
+		t.Fatalf("expected 2 endpoints, got %+v", report)
+	}
+	list, detail := report[1], report[0]
+	if list.Endpoint != "GET /api/conversations" || list.Checked != 1 {
+		t.Errorf("list report = %+v", list)
+	}
+	var issues []string
+	for i := range list.Issues {
+		issues = append(issues, list.Issues[i].String())
+	}
+	want := []string{`unknown field "archived_at"`, `field "created_at" is number, want string`, `missing field "updated_at"`}
+	if strings.Join(issues, "; ") != strings.Join(want, "; ") {
+		t.Errorf("issues = %q, want %q", issues, want)
+	}
+	if detail.Endpoint != "GET /api/conversation/{id}" || len(detail.Issues) != 0 {
+		t.Errorf("detail report = %+v, want no issues (top-level fields aren't checked)", detail)
+	}
+	if len(logged) != 3 || !strings.HasPrefix(logged[0], "warning: backend API drift on GET /api/conversations: ") {
+		t.Errorf("logged %q", logged)
+	}
+
+	rec := httptest.NewRecorder()
+	watch.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/diag/schema", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "GET /api/conversations: 1 responses checked\n") || !strings.Contains(body, `  missing field "updated_at" (1, last `) {
+		t.Errorf("text report:\n%s", body)
+	}
+}
+
+func TestSchemaWatchSpacesOutChecks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	watch := NewSchemaWatch()
+	client := NewClient(server.URL)
+	client.SetSchemaWatch(watch)
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListConversations(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if report := watch.Report(); len(report) != 1 || report[0].Checked != 1 {
+		t.Errorf("expected one checked response within the interval, got %+v", report)
+	}
+}
+
+func derefSlug(c Conversation) string {
+	if c.Slug == nil {
+		return ""
+	}
+	return *c.Slug
+}
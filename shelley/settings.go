@@ -0,0 +1,108 @@
+package shelley
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrSettingsUnsupported is returned when the backend can't change the
+// settings of a conversation after it was created (the server responds 404).
+var ErrSettingsUnsupported = errors.New("backend does not support changing conversation settings")
+
+// ConversationSettings are the settings of a conversation the backend can
+// change while it runs. Zero fields are left as they are.
+type ConversationSettings struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+// SettingsConflictError is returned when the backend refuses a settings
+// change it would otherwise accept, e.g. a model switch while the agent is
+// working (the server responds 409). Current holds the settings in effect.
+type SettingsConflictError struct {
+	Current ConversationSettings
+}
+
+func (e *SettingsConflictError) Error() string {
+	return "conversation settings changed on the backend"
+}
+
+// SettingsUpdater is implemented by clients that can change the settings of
+// a live conversation. Like MessageEditor it is optional: callers should
+// type-assert a ShelleyClient.
+type SettingsUpdater interface {
+	// UpdateSettings applies the non-zero fields of settings and returns
+	// the settings in effect afterwards.
+	UpdateSettings(conversationID string, settings ConversationSettings) (ConversationSettings, error)
+}
+
+var _ SettingsUpdater = (*Client)(nil)
+var _ SettingsUpdater = (*CachingClient)(nil)
+
+// UpdateSettings changes conversation settings with
+// POST /api/conversation/{id}/settings.
+func (c *Client) UpdateSettings(conversationID string, settings ConversationSettings) (ConversationSettings, error) {
+	if c.disabled(FeatureSettings) {
+		return ConversationSettings{}, ErrSettingsUnsupported
+	}
+	body, err := json.Marshal(settings)
+	if err != nil {
+		return ConversationSettings{}, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.baseURL+"/api/conversation/"+conversationID+"/settings", bytes.NewBuffer(body))
+	if err != nil {
+		return ConversationSettings{}, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Shelley-Request", "1")
+	req.Header.Set("X-Exedev-Userid", "1")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return ConversationSettings{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var current ConversationSettings
+		if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+			return ConversationSettings{}, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return current, nil
+	case http.StatusNotFound:
+		return ConversationSettings{}, ErrSettingsUnsupported
+	case http.StatusConflict:
+		conflict := &SettingsConflictError{}
+		if err := json.NewDecoder(resp.Body).Decode(&conflict.Current); err != nil {
+			return ConversationSettings{}, fmt.Errorf("failed to decode conflict: %w", err)
+		}
+		return ConversationSettings{}, conflict
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return ConversationSettings{}, &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+}
+
+// UpdateSettings changes the settings on the backend and drops the cached
+// conversation and listing, which may show the old model.
+func (c *CachingClient) UpdateSettings(conversationID string, settings ConversationSettings) (ConversationSettings, error) {
+	current, err := c.client.UpdateSettings(conversationID, settings)
+	var conflict *SettingsConflictError
+	if err != nil && !errors.As(err, &conflict) {
+		return current, err
+	}
+	if c.cacheTTL > 0 {
+		c.mu.Lock()
+		c.dropLocked(c.conversationCache, "conversation", conversationID)
+		c.conversationsListCache = nil
+		c.mu.Unlock()
+	}
+	return current, err
+}
@@ -0,0 +1,60 @@
+package shelley
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUpdateSettings(t *testing.T) {
+	var path string
+	var got ConversationSettings
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.Header.Get("X-Shelley-Request") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		path = r.URL.Path
+		// The backend normalizes the model name it was given.
+		json.NewEncoder(w).Encode(ConversationSettings{Model: "model-b-id", Temperature: got.Temperature})
+	}))
+	defer server.Close()
+
+	temp := 0.5
+	current, err := NewClient(server.URL).UpdateSettings("c1", ConversationSettings{Model: "model-b", Temperature: &temp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/api/conversation/c1/settings" || got.Model != "model-b" || got.Temperature == nil || *got.Temperature != 0.5 {
+		t.Errorf("server got %s %+v", path, got)
+	}
+	if current.Model != "model-b-id" || current.Temperature == nil || *current.Temperature != 0.5 {
+		t.Errorf("current = %+v", current)
+	}
+}
+
+func TestUpdateSettingsConflictAndUnsupported(t *testing.T) {
+	conflict := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"model":"model-a"}`))
+	}))
+	defer conflict.Close()
+
+	_, err := NewClient(conflict.URL).UpdateSettings("c1", ConversationSettings{Model: "model-b"})
+	var ce *SettingsConflictError
+	if !errors.As(err, &ce) || ce.Current.Model != "model-a" {
+		t.Errorf("expected conflict with current model-a, got %v", err)
+	}
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+	if _, err := NewClient(missing.URL).UpdateSettings("c1", ConversationSettings{Model: "x"}); err != ErrSettingsUnsupported {
+		t.Errorf("expected ErrSettingsUnsupported, got %v", err)
+	}
+}
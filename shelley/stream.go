@@ -0,0 +1,128 @@
+package shelley
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrStreamingUnsupported is returned by StreamConversation when the backend
+// has no stream endpoint (the server responds 404, or negotiation ruled
+// FeatureStreaming out).
+var ErrStreamingUnsupported = errors.New("backend does not support streaming conversations")
+
+// MessageStreamer is implemented by clients that can follow a conversation
+// as messages are written. Like MessageEditor it is optional: callers
+// should type-assert a ShelleyClient and fall back to polling
+// GetConversation when it is missing or returns ErrStreamingUnsupported.
+type MessageStreamer interface {
+	// StreamConversation calls fn with the messages of each update the
+	// backend sends, starting with the messages written so far, until ctx
+	// is done (it then returns ctx.Err()) or the backend ends the stream
+	// (it returns nil).
+	StreamConversation(ctx context.Context, conversationID string, fn func([]Message)) error
+}
+
+var _ MessageStreamer = (*Client)(nil)
+var _ MessageStreamer = (*CachingClient)(nil)
+
+// StreamConversation follows a conversation with
+// GET /api/conversation/{id}/stream, a server-sent event stream whose data
+// lines each hold a StreamResponse.
+func (c *Client) StreamConversation(ctx context.Context, conversationID string, fn func([]Message)) error {
+	if c.disabled(FeatureStreaming) {
+		return ErrStreamingUnsupported
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/api/conversation/"+conversationID+"/stream", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("X-Exedev-Userid", "1")
+
+	// The stream stays open as long as the conversation is followed, so
+	// it can't share the request timeout of the other calls.
+	streamClient := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := streamClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrStreamingUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	err = readEvents(resp.Body, func(data string) error {
+		var update StreamResponse
+		if err := json.Unmarshal([]byte(data), &update); err != nil {
+			return fmt.Errorf("failed to decode stream event: %w", err)
+		}
+		if len(update.Messages) > 0 {
+			fn(update.Messages)
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// readEvents reads a server-sent event stream from r and calls fn with the
+// data of each event; events without data (comments, keep-alives) are
+// skipped.
+func readEvents(r io.Reader, fn func(data string) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				if err := fn(strings.Join(data, "\n")); err != nil {
+					return err
+				}
+				data = data[:0]
+			}
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		return fn(strings.Join(data, "\n"))
+	}
+	return nil
+}
+
+// StreamConversation follows the conversation on the backend, dropping the
+// cached copy whenever an update arrives so reads of it see the new
+// messages.
+func (c *CachingClient) StreamConversation(ctx context.Context, conversationID string, fn func([]Message)) error {
+	return c.client.StreamConversation(ctx, conversationID, func(msgs []Message) {
+		if c.cacheTTL > 0 {
+			c.mu.Lock()
+			c.dropLocked(c.conversationCache, "conversation", conversationID)
+			c.mu.Unlock()
+		}
+		fn(msgs)
+	})
+}
@@ -0,0 +1,100 @@
+package shelley
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadEvents(t *testing.T) {
+	stream := ": keep-alive\n\ndata: one\n\nevent: update\ndata: two\ndata: lines\n\n\ndata:three"
+	var got []string
+	err := readEvents(strings.NewReader(stream), func(data string) error {
+		got = append(got, data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"one", "two\nlines", "three"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestStreamConversation(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/conversation/c1/stream" || r.Header.Get("Accept") != "text/event-stream" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"messages\": [{\"message_id\": \"m1\", \"sequence_id\": 1}]}\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		fmt.Fprint(w, ": ping\n\ndata: {\"messages\": [{\"message_id\": \"m2\", \"sequence_id\": 2}]}\n\n")
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL)
+	var got []string
+	err := c.StreamConversation(context.Background(), "c1", func(msgs []Message) {
+		for _, m := range msgs {
+			got = append(got, m.MessageID)
+		}
+		if len(got) == 1 {
+			close(release)
+		}
+	})
+	if err != nil {
+		t.Fatalf("StreamConversation: %v", err)
+	}
+	if fmt.Sprint(got) != "[m1 m2]" {
+		t.Errorf("messages = %v, want [m1 m2]", got)
+	}
+
+	if err := c.StreamConversation(context.Background(), "missing", func([]Message) {}); !errors.Is(err, ErrStreamingUnsupported) {
+		t.Errorf("StreamConversation on a 404 = %v, want ErrStreamingUnsupported", err)
+	}
+}
+
+func TestStreamConversationCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := NewClient(server.URL).StreamConversation(ctx, "c1", func([]Message) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("StreamConversation = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestFormatMarkdownSince(t *testing.T) {
+	call := `{"Content": [{"Type": 5, "ID": "tu_1", "ToolName": "bash", "Input": {"command": "ls"}}]}`
+	result := `{"Content": [{"Type": 6, "ToolUseID": "tu_1", "ToolResult": [{"Text": "ok"}]}]}`
+	msgs := []Message{
+		{SequenceID: 1, Type: "shelley", LLMData: &call},
+		{SequenceID: 2, Type: "user", UserData: &result},
+	}
+	got := string(FormatMarkdownSince(msgs, 1))
+	if !strings.HasPrefix(got, "## tool result: bash\n") {
+		t.Errorf("FormatMarkdownSince = %q, want the result named after its earlier call", got)
+	}
+	if all := string(FormatMarkdown(msgs)); !strings.HasSuffix(all, got) {
+		t.Errorf("FormatMarkdownSince = %q, not the end of FormatMarkdown %q", got, all)
+	}
+	if got := FormatMarkdownSince(msgs, 2); len(got) != 0 {
+		t.Errorf("FormatMarkdownSince past the end = %q, want nothing", got)
+	}
+}
@@ -0,0 +1,49 @@
+package shelley
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenPieces splits text the way BPE tokenizers pre-split it: runs of
+// letters, runs of digits, and single other characters. Whitespace is left
+// out; tokenizers mostly fold it into the following piece.
+var tokenPieces = regexp.MustCompile(`\p{L}+|\p{N}+|[^\s\p{L}\p{N}]`)
+
+// EstimateTokens approximates how many tokens a model's tokenizer splits
+// text into, without a vocabulary: a word costs one token per six letters
+// or part thereof, a number one per three digits, and punctuation one per
+// character. Letters from scripts written without spaces, such as Chinese
+// or Japanese, cost one each. It is a guide for budgeting, not the count a
+// backend will report.
+func EstimateTokens(text string) int {
+	tokens := 0
+	for _, piece := range tokenPieces.FindAllString(text, -1) {
+		r, _ := utf8.DecodeRuneInString(piece)
+		n := utf8.RuneCountInString(piece)
+		switch {
+		case unicode.IsLetter(r) && unspacedScript(r):
+			tokens += n
+		case unicode.IsLetter(r):
+			tokens += (n + 5) / 6
+		case unicode.IsNumber(r):
+			tokens += (n + 2) / 3
+		default:
+			tokens++
+		}
+	}
+	return tokens
+}
+
+// unspacedScript reports whether r belongs to a script written without
+// spaces between words.
+func unspacedScript(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai)
+}
+
+// CountWords counts whitespace-separated words in text, as wc -w does.
+func CountWords(text string) int {
+	return len(strings.Fields(text))
+}
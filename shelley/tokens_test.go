@@ -0,0 +1,26 @@
+package shelley
+
+import "testing"
+
+func TestEstimateTokens(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"the cat sat", 3},
+		{"internationalization", 4}, // 20 letters
+		{"call f(x, 12345);", 9},    // call f ( x , 123 45 ) ;
+		{"日本語です", 5},
+	} {
+		if got := EstimateTokens(tc.text); got != tc.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestCountWords(t *testing.T) {
+	if got := CountWords("  one two\n\tthree  "); got != 3 {
+		t.Errorf("CountWords = %d, want 3", got)
+	}
+}
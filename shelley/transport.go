@@ -0,0 +1,96 @@
+package shelley
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// A backend URL is http://host:port or https://host:port as usual, or
+// unix:///path/to/shelley.sock for a server listening on a unix socket, as
+// a socket-activated shelley.socket may. Requests to a socket are sent as
+// plain HTTP with a placeholder host; the socket path has no room for a
+// URL path prefix.
+
+// unixScheme is the URL scheme of backends listening on a unix socket.
+const unixScheme = "unix://"
+
+// unixHost is the host requests to a unix socket are addressed to.
+const unixHost = "http://unix"
+
+// TLSOptions are the files that configure TLS to https backends beyond
+// the system's trusted roots. The zero value uses the system defaults.
+type TLSOptions struct {
+	CAFile   string // PEM certificates to trust in addition to the system's
+	CertFile string // PEM client certificate, with KeyFile
+	KeyFile  string // PEM private key of CertFile
+}
+
+// Config loads the files into a tls.Config, or returns nil if none are set.
+func (o TLSOptions) Config() (*tls.Config, error) {
+	if o == (TLSOptions{}) {
+		return nil, nil
+	}
+	cfg := &tls.Config{}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return nil, errors.New("a client certificate needs both a certificate and a key file")
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// UnixSocketPath returns the socket path of a unix:// backend URL, and
+// false for any other URL. The path must be absolute.
+func UnixSocketPath(baseURL string) (string, bool) {
+	path, ok := strings.CutPrefix(baseURL, unixScheme)
+	if !ok || !strings.HasPrefix(path, "/") {
+		return "", false
+	}
+	return path, true
+}
+
+// newTransport returns the base URL requests are built on and the transport
+// that reaches it: nil (http.DefaultTransport) for an http or https URL
+// without TLS options, and a transport of its own otherwise.
+func newTransport(baseURL string, tlsConfig *tls.Config) (string, http.RoundTripper) {
+	socket, isUnix := UnixSocketPath(baseURL)
+	if !isUnix && tlsConfig == nil {
+		return baseURL, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if isUnix {
+		var d net.Dialer
+		t.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, "unix", socket)
+		}
+		return unixHost, t
+	}
+	t.TLSClientConfig = tlsConfig
+	return baseURL, t
+}
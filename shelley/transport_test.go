@@ -0,0 +1,87 @@
+package shelley
+
+import (
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func modelsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/models" {
+			w.Write([]byte(`[{"id":"predictable","ready":true}]`))
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+func TestUnixSocketClient(t *testing.T) {
+	// Socket paths are limited to about 100 bytes, more than t.TempDir()
+	// may leave room for.
+	dir, err := os.MkdirTemp("", "shelley-sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "shelley.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(modelsHandler())
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	models, err := NewClient("unix://" + socket).ListModels()
+	if err != nil {
+		t.Fatalf("ListModels over %s: %v", socket, err)
+	}
+	if len(models.Models) != 1 || models.Models[0].ID != "predictable" {
+		t.Errorf("models = %+v", models)
+	}
+
+	for _, url := range []string{"unix://relative.sock", "unix://", "http://localhost"} {
+		if _, ok := UnixSocketPath(url); ok {
+			t.Errorf("UnixSocketPath(%q) accepted", url)
+		}
+	}
+}
+
+func TestTLSOptions(t *testing.T) {
+	server := httptest.NewTLSServer(modelsHandler())
+	defer server.Close()
+
+	// The test server's certificate isn't trusted by the system.
+	if _, err := NewClient(server.URL).ListModels(); err == nil {
+		t.Fatal("ListModels trusted an unknown certificate")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := TLSOptions{CAFile: caFile}.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewClientWithTLS(server.URL, cfg).ListModels(); err != nil {
+		t.Errorf("ListModels with -tls-ca: %v", err)
+	}
+
+	if cfg, err := (TLSOptions{}).Config(); cfg != nil || err != nil {
+		t.Errorf("zero TLSOptions = %v, %v; want nil, nil", cfg, err)
+	}
+	if _, err := (TLSOptions{CertFile: caFile}).Config(); err == nil {
+		t.Error("a certificate without a key was accepted")
+	}
+	if _, err := (TLSOptions{CAFile: filepath.Join(t.TempDir(), "missing.pem")}).Config(); err == nil {
+		t.Error("a missing CA file was accepted")
+	}
+}
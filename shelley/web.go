@@ -0,0 +1,60 @@
+package shelley
+
+import "net/url"
+
+// WebLinker is implemented by clients that know their backend's address, so
+// links into the backend's web UI can be derived from it. Like MappingStore
+// it is optional: callers should type-assert a ShelleyClient.
+type WebLinker interface {
+	// WebURL returns the address of the backend's web UI, or "" if it has
+	// none a browser could open, as for a backend on a unix socket.
+	WebURL() string
+
+	// ConversationWebURL returns the web UI page of a conversation, or ""
+	// if WebURL is.
+	ConversationWebURL(conversationID string) string
+
+	// ModelWebURL returns the web UI page for starting a conversation with
+	// a model, or "" if WebURL is.
+	ModelWebURL(modelID string) string
+}
+
+var _ WebLinker = (*Client)(nil)
+var _ WebLinker = (*CachingClient)(nil)
+
+// WebURL returns the URL the client was made with, or "" for a unix://
+// URL: requests to a socket go to a made-up host no browser can reach.
+func (c *Client) WebURL() string {
+	return c.webURL
+}
+
+// ConversationWebURL returns {base}/c/{conversation_id}.
+func (c *Client) ConversationWebURL(conversationID string) string {
+	if c.webURL == "" {
+		return ""
+	}
+	return c.webURL + "/c/" + url.PathEscape(conversationID)
+}
+
+// ModelWebURL returns {base}/?model={model_id}.
+func (c *Client) ModelWebURL(modelID string) string {
+	if c.webURL == "" {
+		return ""
+	}
+	return c.webURL + "/?model=" + url.QueryEscape(modelID)
+}
+
+// WebURL returns the underlying client's address.
+func (c *CachingClient) WebURL() string {
+	return c.client.WebURL()
+}
+
+// ConversationWebURL returns the underlying client's link; nothing is fetched.
+func (c *CachingClient) ConversationWebURL(conversationID string) string {
+	return c.client.ConversationWebURL(conversationID)
+}
+
+// ModelWebURL returns the underlying client's link; nothing is fetched.
+func (c *CachingClient) ModelWebURL(modelID string) string {
+	return c.client.ModelWebURL(modelID)
+}
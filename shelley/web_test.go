@@ -0,0 +1,29 @@
+package shelley
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWebURLs(t *testing.T) {
+	client := NewCachingClient(NewClient("https://shelley.example.com/"), time.Second)
+	if got, want := client.ConversationWebURL("cv-123"), "https://shelley.example.com/c/cv-123"; got != want {
+		t.Errorf("ConversationWebURL = %q, want %q", got, want)
+	}
+	if got, want := client.ModelWebURL("claude sonnet"), "https://shelley.example.com/?model=claude+sonnet"; got != want {
+		t.Errorf("ModelWebURL = %q, want %q", got, want)
+	}
+}
+
+func TestWebURLsUnixSocket(t *testing.T) {
+	client := NewClient("unix:///run/shelley.sock")
+	if got := client.WebURL(); got != "" {
+		t.Errorf("WebURL = %q, want none", got)
+	}
+	if got := client.ConversationWebURL("cv-123"); got != "" {
+		t.Errorf("ConversationWebURL = %q, want none", got)
+	}
+	if got := client.ModelWebURL("claude"); got != "" {
+		t.Errorf("ModelWebURL = %q, want none", got)
+	}
+}
@@ -0,0 +1,50 @@
+package state
+
+import "time"
+
+// EventType names a conversation lifecycle change.
+type EventType string
+
+const (
+	// EventAdopted: a server conversation got a local entry.
+	EventAdopted EventType = "adopted"
+	// EventCreated: a local clone was created on the server by its first message.
+	EventCreated EventType = "created"
+	// EventUpdated: the server reported a newer updated_at for a conversation.
+	EventUpdated EventType = "updated"
+	// EventRemoved: a conversation's local entry was deleted.
+	EventRemoved EventType = "removed"
+)
+
+// Event describes one lifecycle change of a conversation in the store.
+type Event struct {
+	Type                  EventType `json:"event"`
+	Backend               string    `json:"backend"`
+	LocalID               string    `json:"local_id"`
+	ShelleyConversationID string    `json:"conversation_id,omitempty"`
+	Time                  time.Time `json:"time"`
+}
+
+// SetEventHook makes the store call fn for every lifecycle event. fn runs
+// with the store locked, so it must be quick and must not call back into
+// the store. Nor may it log: log output may look conversations up in the
+// store (see journal.Writer). Pass nil to remove the hook.
+func (s *Store) SetEventHook(fn func(Event)) {
+	s.mu.Lock()
+	defer s.unlock()
+	s.onEvent = fn
+}
+
+// emitLocked reports an event to the hook, if any. s.mu must be held.
+func (s *Store) emitLocked(typ EventType, backend string, cs *ConversationState) {
+	if s.onEvent == nil {
+		return
+	}
+	s.onEvent(Event{
+		Type:                  typ,
+		Backend:               backend,
+		LocalID:               cs.LocalID,
+		ShelleyConversationID: cs.ShelleyConversationID,
+		Time:                  time.Now(),
+	})
+}
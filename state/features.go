@@ -0,0 +1,104 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Feature flags: what each backend supports, so one mount can serve old and
+// new Shelley servers side by side. Two sources are kept per backend:
+//
+//   - Capabilities: what the backend last reported from its capabilities
+//     endpoint. It is kept across restarts, so features are known before
+//     the next negotiation finishes, and dropped when the backend's URL
+//     changes.
+//   - Features: flags set by hand, which win over what the backend
+//     reported, e.g. to turn streaming off for a server whose stream
+//     endpoint misbehaves.
+//
+// A feature neither source rules out is assumed to be there.
+
+// BackendCapabilities is what a backend reported about itself.
+type BackendCapabilities struct {
+	Version  string   `json:"version,omitempty"`
+	Features []string `json:"features"`
+	// Negotiated is false for a backend without the capabilities
+	// endpoint: it assumes every feature.
+	Negotiated bool      `json:"negotiated"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// SetBackendCapabilities records what the named backend reported about
+// itself.
+func (s *Store) SetBackendCapabilities(name string, caps BackendCapabilities) error {
+	s.mu.Lock()
+	defer s.unlock()
+	b, exists := s.Backends[name]
+	if !exists {
+		return fmt.Errorf("backend %q not found", name)
+	}
+	caps.Features = slices.Clone(caps.Features)
+	slices.Sort(caps.Features)
+	b.Capabilities = &caps
+	return s.saveLocked()
+}
+
+// SetBackendFeature turns feature on or off for the named backend,
+// whatever the backend reports.
+func (s *Store) SetBackendFeature(name, feature string, enabled bool) error {
+	s.mu.Lock()
+	defer s.unlock()
+	b, exists := s.Backends[name]
+	if !exists {
+		return fmt.Errorf("backend %q not found", name)
+	}
+	if b.Features == nil {
+		b.Features = make(map[string]bool)
+	}
+	b.Features[feature] = enabled
+	return s.saveLocked()
+}
+
+// ClearBackendFeature removes the flag SetBackendFeature set, so the
+// backend's report decides again.
+func (s *Store) ClearBackendFeature(name, feature string) error {
+	s.mu.Lock()
+	defer s.unlock()
+	b, exists := s.Backends[name]
+	if !exists {
+		return fmt.Errorf("backend %q not found", name)
+	}
+	if _, set := b.Features[feature]; !set {
+		return nil
+	}
+	delete(b.Features, feature)
+	if len(b.Features) == 0 {
+		b.Features = nil
+	}
+	return s.saveLocked()
+}
+
+// FeatureEnabled reports whether the default backend has feature.
+func (s *Store) FeatureEnabled(feature string) bool {
+	return s.FeatureEnabledForBackend(s.GetDefaultBackend(), feature)
+}
+
+// FeatureEnabledForBackend reports whether the named backend has feature:
+// the flag set with SetBackendFeature if there is one, else whether the
+// backend reported it, else true.
+func (s *Store) FeatureEnabledForBackend(name, feature string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, exists := s.Backends[name]
+	if !exists {
+		return true
+	}
+	if enabled, set := b.Features[feature]; set {
+		return enabled
+	}
+	if b.Capabilities == nil || !b.Capabilities.Negotiated {
+		return true
+	}
+	return slices.Contains(b.Capabilities.Features, feature)
+}
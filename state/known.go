@@ -0,0 +1,28 @@
+package state
+
+// Log output may mention local conversation IDs, and the journal writer
+// tags entries with the conversation they are about (see journal.Writer).
+// Looking IDs up with Get would take s.mu from inside every log call, and
+// deadlock whenever something logs with the store locked; Known answers
+// from a snapshot instead, taken whenever the store is unlocked after a
+// change and when the state is loaded.
+
+// Known reports whether id is the local ID of a conversation on any
+// backend, as of the last change or load. It doesn't take the store's lock,
+// so it is safe to call from log output.
+func (s *Store) Known(id string) bool {
+	known := s.known.Load()
+	return known != nil && (*known)[id]
+}
+
+// refreshKnownLocked takes a new snapshot of the local IDs for Known.
+// s.mu must be held, or the store not yet shared.
+func (s *Store) refreshKnownLocked() {
+	known := make(map[string]bool)
+	for _, b := range s.Backends {
+		for id := range b.Conversations {
+			known[id] = true
+		}
+	}
+	s.known.Store(&known)
+}
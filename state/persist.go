@@ -0,0 +1,121 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// When the state file can't be written because the disk is full, over
+// quota or read-only, the store keeps working in memory instead of failing
+// every Clone and Adopt: changes are kept and served, and every later save
+// tries the disk again, so the file catches up with everything once space
+// is freed. Until then the store is memory-only, PersistStatus says so, and
+// Load leaves the in-memory state alone rather than going back to the
+// outdated file. Other write errors still fail the change.
+
+// PersistStatus describes whether the store's changes reach the disk.
+type PersistStatus struct {
+	Path       string
+	MemoryOnly bool      // the last save couldn't be written
+	Since      time.Time // when saves started failing
+	Err        error     // why the last save failed
+}
+
+// String describes the status in one line, without a trailing newline.
+func (p PersistStatus) String() string {
+	if !p.MemoryOnly {
+		return "ok: " + p.Path
+	}
+	return fmt.Sprintf("memory-only since %s: %v (changes are lost on unmount until %s can be written)",
+		p.Since.UTC().Format(time.RFC3339), p.Err, p.Path)
+}
+
+// PersistStatus reports whether the store is writing its changes to disk.
+func (s *Store) PersistStatus() PersistStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return PersistStatus{Path: s.Path, MemoryOnly: s.persistErr != nil, Since: s.persistSince, Err: s.persistErr}
+}
+
+// diskFull reports whether err means the disk can't take more data, as
+// opposed to a problem with the state file itself.
+func diskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EDQUOT) || errors.Is(err, syscall.EROFS)
+}
+
+// writeStateFile writes the state file; tests replace it to simulate a full
+// disk.
+var writeStateFile = writeFileAtomic
+
+// writeFileAtomic replaces path with data through a temporary file in the
+// same directory, so a write that fails halfway, as on a full disk, leaves
+// the old file intact.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// persistFailedLocked handles a failed write of the state file. It reports
+// whether the store carries on in memory. s.mu must be held.
+func (s *Store) persistFailedLocked(err error) bool {
+	if !diskFull(err) {
+		return false
+	}
+	if s.persistErr == nil {
+		s.persistSince = time.Now()
+		s.persistLog = fmt.Sprintf("Writing state file %s failed: %v; keeping changes in memory until it can be written", s.Path, err)
+	}
+	s.persistErr = err
+	return true
+}
+
+// persistedLocked records a successful write of the state file. s.mu must
+// be held.
+func (s *Store) persistedLocked() {
+	if s.persistErr != nil {
+		s.persistLog = fmt.Sprintf("State file %s written again after being memory-only since %s", s.Path, s.persistSince.Format(time.RFC3339))
+		s.persistErr = nil
+		s.persistSince = time.Time{}
+	}
+}
+
+// unlock refreshes the IDs Known answers from and releases s.mu, then
+// logs what the save under it had to say. Log output may look
+// conversations up in the store (see journal.Writer), so nothing is logged
+// while s.mu is held.
+func (s *Store) unlock() {
+	s.refreshKnownLocked()
+	msg := s.persistLog
+	s.persistLog = ""
+	s.mu.Unlock()
+	if msg != "" {
+		log.Print(msg)
+	}
+}
@@ -0,0 +1,136 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// CorruptError is returned by Load when the state file exists but can't be
+// parsed, e.g. because a crash or a full disk left it truncated.
+type CorruptError struct {
+	Path string
+	Err  error
+}
+
+func (e *CorruptError) Error() string {
+	return fmt.Sprintf("failed to parse state file: %v", e.Err)
+}
+
+func (e *CorruptError) Unwrap() error {
+	return e.Err
+}
+
+// Repair describes what NewStoreRepairing did with a corrupt state file.
+type Repair struct {
+	Path   string // the state file
+	Backup string // where the corrupt file was moved
+	Cause  error  // why it couldn't be read
+	// Recovered is how many conversations were salvaged from the corrupt
+	// file with their local IDs; Backends names the backends they belong to.
+	Recovered int
+	Backends  []string
+}
+
+// String is a report for the log.
+func (r *Repair) String() string {
+	return fmt.Sprintf("state file %s is corrupt (%v); moved it to %s and recovered %d conversation(s) from it. Conversations it lost will be re-adopted from the server under new local IDs.",
+		r.Path, r.Cause, r.Backup, r.Recovered)
+}
+
+// NewStoreRepairing is NewStore for a mount that should come up even when
+// its state file is damaged. If the file can't be parsed, it is moved aside
+// to {path}.corrupt-{time}, every conversation entry still intact in it is
+// carried over to a new state file, and a Repair describing this is
+// returned along with the store. The Repair is nil when nothing was wrong.
+func NewStoreRepairing(path string) (*Store, *Repair, error) {
+	s, err := NewStore(path)
+	var corrupt *CorruptError
+	if !errors.As(err, &corrupt) {
+		return s, nil, err
+	}
+	data, err := os.ReadFile(corrupt.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	repair := &Repair{
+		Path:   corrupt.Path,
+		Backup: corrupt.Path + ".corrupt-" + time.Now().UTC().Format("20060102T150405Z"),
+		Cause:  corrupt.Err,
+	}
+	if err := os.Rename(corrupt.Path, repair.Backup); err != nil {
+		return nil, nil, fmt.Errorf("failed to move corrupt state file aside: %w", err)
+	}
+
+	s = &Store{Path: corrupt.Path, Backends: make(map[string]*BackendState)}
+	backends := salvage(data)
+	for name, b := range backends {
+		if len(b.Conversations) > 0 {
+			repair.Backends = append(repair.Backends, name)
+		}
+		repair.Recovered += len(b.Conversations)
+		s.Backends[name] = b
+	}
+	s.defaultBackend()
+	if err := s.saveLocked(); err != nil {
+		return nil, nil, err
+	}
+	s.refreshKnownLocked()
+	return s, repair, nil
+}
+
+// backendStart matches the opening of a backend entry as saveLocked writes
+// it: its name, its URL if it has one, and the start of its conversations.
+var backendStart = regexp.MustCompile(`"([^"\\]+)":\s*\{\s*(?:"url":\s*("(?:[^"\\]|\\.)*"),\s*)?"conversations"`)
+
+// salvage picks the backends and the complete conversation entries out of
+// a damaged state file. A conversation belongs to the last backend that
+// starts before it, or to the main backend if none does.
+func salvage(data []byte) map[string]*BackendState {
+	backends := make(map[string]*BackendState)
+	ensure := func(name string) *BackendState {
+		if backends[name] == nil {
+			backends[name] = &BackendState{Conversations: make(map[string]*ConversationState)}
+		}
+		return backends[name]
+	}
+
+	starts := backendStart.FindAllSubmatchIndex(data, -1)
+	for _, m := range starts {
+		b := ensure(string(data[m[2]:m[3]]))
+		if m[4] >= 0 {
+			_ = json.Unmarshal(data[m[4]:m[5]], &b.URL)
+		}
+	}
+	backendAt := func(off int) string {
+		name := mainBackendName
+		for _, m := range starts {
+			if m[0] > off {
+				break
+			}
+			name = string(data[m[2]:m[3]])
+		}
+		return name
+	}
+
+	for off := 0; off < len(data); off++ {
+		if data[off] != '{' {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(data[off:]))
+		var cs ConversationState
+		if dec.Decode(&cs) != nil || cs.LocalID == "" {
+			continue
+		}
+		convs := ensure(backendAt(off)).Conversations
+		if _, dup := convs[cs.LocalID]; !dup {
+			convs[cs.LocalID] = &cs
+		}
+		off += int(dec.InputOffset()) - 1
+	}
+	return backends
+}
@@ -4,11 +4,16 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -21,16 +26,53 @@ type ConversationState struct {
 	// ModelID is the internal API model ID (e.g. "custom-f999b9b0").
 	// When set, this is sent to the API instead of Model (the display name).
 	// For built-in models where ID == display name, this may be empty.
-	ModelID   string    `json:"model_id,omitempty"`
-	Cwd       string    `json:"cwd,omitempty"`
-	Created   bool      `json:"created"`
-	CreatedAt time.Time `json:"created_at,omitempty"`
+	ModelID string `json:"model_id,omitempty"`
+	Cwd     string `json:"cwd,omitempty"`
+	// Temperature is the sampling temperature set through ctl, as written.
+	// Empty means the backend's default.
+	Temperature string `json:"temperature,omitempty"`
+	// ReadOnly freezes the conversation: send, ctl and message edits fail
+	// with EROFS until it is cleared through ctl again.
+	ReadOnly bool `json:"readonly,omitempty"`
+	// NoSendDedup turns off dropping a message written to send again
+	// within moments of the first (ctl dedup=false).
+	NoSendDedup bool `json:"no_send_dedup,omitempty"`
+	// BudgetTokens and BudgetUSD cap the tokens and cost the conversation
+	// may use (ctl budget_tokens= and budget_usd=); once it has used that
+	// much, send refuses further messages. Zero means no cap.
+	BudgetTokens int64     `json:"budget_tokens,omitempty"`
+	BudgetUSD    float64   `json:"budget_usd,omitempty"`
+	Created      bool      `json:"created"`
+	CreatedAt    time.Time `json:"created_at,omitempty"`
 	// APICreatedAt is the server's created_at timestamp (RFC3339 string).
 	// This is the original creation time from the Shelley API.
 	APICreatedAt string `json:"api_created_at,omitempty"`
 	// APIUpdatedAt is the server's updated_at timestamp (RFC3339 string).
 	// This is the last modification time from the Shelley API.
 	APIUpdatedAt string `json:"api_updated_at,omitempty"`
+	// Meta holds arbitrary user key/value pairs (exposed as conversation/{id}/meta/).
+	// Unlike ctl, metadata stays writable after the conversation is created.
+	// Access it through GetMeta/ListMeta rather than reading the map directly.
+	Meta map[string]string `json:"meta,omitempty"`
+	// MessageEdits records when messages of this conversation were last
+	// edited through the filesystem, by message ID.
+	MessageEdits map[string]time.Time `json:"message_edits,omitempty"`
+	// PinnedMessages lists the IDs of the messages pinned through the
+	// filesystem, in the order they were pinned.
+	PinnedMessages []string `json:"pinned_messages,omitempty"`
+	// Bookmarks holds the index of the last message each reader has seen
+	// (the number its messages/ directory starts with), keyed by reader.
+	Bookmarks map[string]int `json:"bookmarks,omitempty"`
+
+	// transient conversations were adopted in passthrough mode: they live
+	// only in memory and are never written to the state file.
+	transient bool
+}
+
+// Transient reports whether the conversation exists only in memory
+// (adopted in passthrough mode, see Store.SetPassthrough).
+func (cs *ConversationState) Transient() bool {
+	return cs.transient
 }
 
 // EffectiveModelID returns the model ID to use for API calls.
@@ -48,6 +90,11 @@ type BackendState struct {
 	URL string `json:"url,omitempty"`
 	// Conversations maps local IDs to conversation state for this backend.
 	Conversations map[string]*ConversationState `json:"conversations"`
+	// Capabilities is what the backend last reported about itself; nil
+	// until it has been asked (see features.go).
+	Capabilities *BackendCapabilities `json:"capabilities,omitempty"`
+	// Features holds feature flags set by hand, overriding Capabilities.
+	Features map[string]bool `json:"features,omitempty"`
 }
 
 // mainBackendName is the internal name for the auto-created default backend.
@@ -64,16 +111,41 @@ type Store struct {
 	Backends        map[string]*BackendState `json:"backends"`
 	DefaultBackend  string                  `json:"default_backend,omitempty"`
 	mu              sync.RWMutex
+	// revision is bumped on every successful save, or change kept in
+	// memory only (see PersistStatus), so callers can cheaply detect
+	// changes (see Revision).
+	revision uint64
+	// passthrough makes adoption transient (see SetPassthrough).
+	passthrough bool
+	// onEvent receives lifecycle events (see SetEventHook).
+	onEvent func(Event)
+	// maxPending caps unconversed clones (see SetMaxPendingClones).
+	maxPending int
+	// lastActivity is the latest updated_at a conversation was moved to
+	// (see LastActivity).
+	lastActivity time.Time
+	// persistErr is why the state file couldn't be written, since
+	// persistSince, while the store is memory-only (see PersistStatus).
+	persistErr   error
+	persistSince time.Time
+	// persistLog is a change of persist status to log once s.mu is
+	// released (see unlock).
+	persistLog string
+	// known is the set of local IDs Known answers from.
+	known atomic.Pointer[map[string]bool]
 }
 
+// ErrTooManyClones is returned by Clone when the unconversed clones have
+// reached the limit set with SetMaxPendingClones.
+var ErrTooManyClones = errors.New("too many unconversed clones")
+
 // NewStore creates a new Store. If path is empty, defaults to ~/.shelley-fuse/state.json.
 func NewStore(path string) (*Store, error) {
 	if path == "" {
-		home, err := os.UserHomeDir()
-		if err != nil {
-			return nil, fmt.Errorf("cannot determine home directory: %w", err)
+		var err error
+		if path, err = ProfilePath(""); err != nil {
+			return nil, err
 		}
-		path = filepath.Join(home, ".shelley-fuse", "state.json")
 	}
 	s := &Store{
 		Path:     path,
@@ -85,6 +157,63 @@ func NewStore(path string) (*Store, error) {
 	return s, nil
 }
 
+// ProfilePath returns the state file of the named profile,
+// ~/.shelley-fuse/profiles/{name}/state.json, so mounts for different
+// projects can keep separate conversation mappings. The empty name is the
+// default profile, ~/.shelley-fuse/state.json.
+func ProfilePath(name string) (string, error) {
+	if strings.ContainsAny(name, "/\x00") || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	if name == "" {
+		return filepath.Join(home, ".shelley-fuse", "state.json"), nil
+	}
+	return filepath.Join(home, ".shelley-fuse", "profiles", name, "state.json"), nil
+}
+
+// SetPassthrough switches adoption of server conversations to passthrough
+// mode: adopted conversations use their Shelley ID as the local ID and are
+// kept in memory only, so browsing the server never grows the state file.
+// Conversations created locally (Clone) are still persisted as usual.
+func (s *Store) SetPassthrough(enabled bool) {
+	s.mu.Lock()
+	defer s.unlock()
+	s.passthrough = enabled
+}
+
+// SetMaxPendingClones limits the clones that have no message yet, across
+// all backends, to n; Clone fails with ErrTooManyClones beyond it. Zero
+// means no limit.
+func (s *Store) SetMaxPendingClones(n int) {
+	s.mu.Lock()
+	defer s.unlock()
+	s.maxPending = n
+}
+
+// PendingClones returns the number of clones that have no message yet,
+// across all backends, and the limit set with SetMaxPendingClones.
+func (s *Store) PendingClones() (count, limit int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pendingLocked(), s.maxPending
+}
+
+func (s *Store) pendingLocked() int {
+	n := 0
+	for _, b := range s.Backends {
+		for _, cs := range b.Conversations {
+			if !cs.Created {
+				n++
+			}
+		}
+	}
+	return n
+}
+
 // defaultBackend returns the default backend state, creating it if needed.
 func (s *Store) defaultBackend() *BackendState {
 	b, ok := s.Backends[mainBackendName]
@@ -143,12 +272,15 @@ func (s *Store) Clone() (string, error) {
 // CloneForBackend allocates a new conversation on the specified backend.
 func (s *Store) CloneForBackend(backend string) (string, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	convs := s.conversationsForBackend(backend)
 	if convs == nil {
 		return "", fmt.Errorf("backend %q not found", backend)
 	}
+	if s.maxPending > 0 && s.pendingLocked() >= s.maxPending {
+		return "", ErrTooManyClones
+	}
 
 	id, err := s.generateIDForBackend(backend)
 	if err != nil {
@@ -165,6 +297,60 @@ func (s *Store) CloneForBackend(backend string) (string, error) {
 	return id, nil
 }
 
+// NoteMessageTime records that the conversation with Shelley ID shelleyID,
+// on whichever backend has it, holds a message created at createdAt (an API
+// timestamp). If that is later than the conversation's updated_at, which
+// is only refreshed by listings, updated_at moves up to it and an
+// EventUpdated is emitted, so the directory's mtime follows new messages.
+func (s *Store) NoteMessageTime(shelleyID, createdAt string) {
+	if shelleyID == "" || createdAt == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.unlock()
+	for name, b := range s.Backends {
+		for _, cs := range b.Conversations {
+			if cs.ShelleyConversationID != shelleyID {
+				continue
+			}
+			if cs.APIUpdatedAt != "" && !laterTimestamp(createdAt, cs.APIUpdatedAt) {
+				return
+			}
+			cs.APIUpdatedAt = createdAt
+			s.noteActivityLocked(createdAt)
+			_ = s.saveLocked() // best effort, like adoption
+			s.emitLocked(EventUpdated, name, cs)
+			return
+		}
+	}
+}
+
+// LastActivity returns the latest updated_at any conversation was moved
+// to since the store was opened, or the zero time if none was.
+func (s *Store) LastActivity() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastActivity
+}
+
+func (s *Store) noteActivityLocked(timestamp string) {
+	if t, err := time.Parse(time.RFC3339Nano, timestamp); err == nil && t.After(s.lastActivity) {
+		s.lastActivity = t
+	}
+}
+
+// laterTimestamp reports whether API timestamp a is later than b. They
+// are compared as times when both parse, since the server doesn't always
+// include fractional seconds, and as strings otherwise.
+func laterTimestamp(a, b string) bool {
+	ta, errA := time.Parse(time.RFC3339Nano, a)
+	tb, errB := time.Parse(time.RFC3339Nano, b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return ta.After(tb)
+}
+
 // Get returns the state for a conversation, or nil if not found.
 func (s *Store) Get(id string) *ConversationState {
 	return s.GetForBackend(s.GetDefaultBackend(), id)
@@ -191,7 +377,7 @@ func (s *Store) SetModel(id, displayName, internalID string) error {
 // SetModelForBackend sets the model on a conversation for the specified backend.
 func (s *Store) SetModelForBackend(backend, id, displayName, internalID string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	convs := s.conversationsForBackend(backend)
 	if convs == nil {
@@ -220,7 +406,7 @@ func (s *Store) SetCtl(id, key, value string) error {
 // SetCtlForBackend sets a key=value pair on a conversation for the specified backend.
 func (s *Store) SetCtlForBackend(backend, id, key, value string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	convs := s.conversationsForBackend(backend)
 	if convs == nil {
@@ -243,6 +429,11 @@ func (s *Store) SetCtlForBackend(backend, id, key, value string) error {
 		cs.ModelID = value
 	case "cwd":
 		cs.Cwd = value
+	case "temperature":
+		cs.Temperature = value
+	case "slug":
+		// Sent with the first message; the backend may pick another.
+		cs.Slug = value
 	default:
 		return fmt.Errorf("unknown ctl key: %s", key)
 	}
@@ -258,7 +449,7 @@ func (s *Store) MarkCreated(id, shelleyConversationID, slug string) error {
 // MarkCreatedForBackend marks a conversation as created for the specified backend.
 func (s *Store) MarkCreatedForBackend(backend, id, shelleyConversationID, slug string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	convs := s.conversationsForBackend(backend)
 	if convs == nil {
@@ -271,8 +462,500 @@ func (s *Store) MarkCreatedForBackend(backend, id, shelleyConversationID, slug s
 	}
 	cs.Created = true
 	cs.ShelleyConversationID = shelleyConversationID
-	cs.Slug = slug
-	return s.saveLocked()
+	// A backend that returns no slug keeps the one set with SetCtl.
+	if slug != "" {
+		cs.Slug = slug
+	}
+	if err := s.saveLocked(); err != nil {
+		return err
+	}
+	s.emitLocked(EventCreated, backend, cs)
+	return nil
+}
+
+// SetMeta stores a metadata key/value pair on a conversation.
+// Metadata is writable whether or not the conversation has been created.
+func (s *Store) SetMeta(id, key, value string) error {
+	return s.SetMetaForBackend(s.GetDefaultBackend(), id, key, value)
+}
+
+// SetMetaForBackend stores a metadata key/value pair on a conversation for the specified backend.
+func (s *Store) SetMetaForBackend(backend, id, key, value string) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	old, existed := cs.Meta[key]
+	if cs.Meta == nil {
+		cs.Meta = make(map[string]string)
+	}
+	cs.Meta[key] = value
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			cs.Meta[key] = old
+		} else {
+			delete(cs.Meta, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// CreateMeta adds an empty metadata key to a conversation unless it is
+// already there, and reports whether it added it. Checking and adding
+// under one lock is what makes exclusive creates (O_EXCL lockfiles in
+// meta/) safe against each other.
+func (s *Store) CreateMeta(id, key string) (bool, error) {
+	return s.CreateMetaForBackend(s.GetDefaultBackend(), id, key)
+}
+
+// CreateMetaForBackend is CreateMeta for the specified backend.
+func (s *Store) CreateMetaForBackend(backend, id, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return false, fmt.Errorf("backend %q not found", backend)
+	}
+
+	cs, ok := convs[id]
+	if !ok {
+		return false, fmt.Errorf("conversation %s not found", id)
+	}
+	if _, exists := cs.Meta[key]; exists {
+		return false, nil
+	}
+	if cs.Meta == nil {
+		cs.Meta = make(map[string]string)
+	}
+	cs.Meta[key] = ""
+	if err := s.saveLocked(); err != nil {
+		delete(cs.Meta, key)
+		return false, err
+	}
+	return true, nil
+}
+
+// GetMeta returns a metadata value and whether the key exists.
+func (s *Store) GetMeta(id, key string) (string, bool) {
+	return s.GetMetaForBackend(s.GetDefaultBackend(), id, key)
+}
+
+// GetMetaForBackend returns a metadata value for a conversation on the specified backend.
+func (s *Store) GetMetaForBackend(backend, id, key string) (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return "", false
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return "", false
+	}
+	value, ok := cs.Meta[key]
+	return value, ok
+}
+
+// ListMeta returns the metadata keys of a conversation, sorted.
+func (s *Store) ListMeta(id string) []string {
+	return s.ListMetaForBackend(s.GetDefaultBackend(), id)
+}
+
+// ListMetaForBackend returns the metadata keys of a conversation on the specified backend, sorted.
+func (s *Store) ListMetaForBackend(backend, id string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return nil
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(cs.Meta))
+	for k := range cs.Meta {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DeleteMeta removes a metadata key from a conversation.
+// Returns an error if the conversation or key doesn't exist.
+func (s *Store) DeleteMeta(id, key string) error {
+	return s.DeleteMetaForBackend(s.GetDefaultBackend(), id, key)
+}
+
+// DeleteMetaForBackend removes a metadata key from a conversation on the specified backend.
+func (s *Store) DeleteMetaForBackend(backend, id, key string) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+	old, ok := cs.Meta[key]
+	if !ok {
+		return fmt.Errorf("meta key %q not found on conversation %s", key, id)
+	}
+	delete(cs.Meta, key)
+	if err := s.saveLocked(); err != nil {
+		cs.Meta[key] = old
+		return err
+	}
+	return nil
+}
+
+// SetLiveSettings records the model and temperature the backend reports
+// for a conversation. Unlike SetModel and SetCtl it also works after the
+// conversation was created, for settings the backend changes while the
+// conversation runs. Empty arguments leave the field as it is.
+func (s *Store) SetLiveSettings(id, displayName, internalID, temperature string) error {
+	return s.SetLiveSettingsForBackend(s.GetDefaultBackend(), id, displayName, internalID, temperature)
+}
+
+// SetLiveSettingsForBackend is SetLiveSettings for a conversation on the specified backend.
+func (s *Store) SetLiveSettingsForBackend(backend, id, displayName, internalID, temperature string) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	oldModel, oldModelID, oldTemperature := cs.Model, cs.ModelID, cs.Temperature
+	if displayName != "" {
+		cs.Model = displayName
+		cs.ModelID = internalID
+	}
+	if temperature != "" {
+		cs.Temperature = temperature
+	}
+	if err := s.saveLocked(); err != nil {
+		cs.Model, cs.ModelID, cs.Temperature = oldModel, oldModelID, oldTemperature
+		return err
+	}
+	return nil
+}
+
+// SetReadOnly locks or unlocks a conversation. Unlike the other ctl
+// settings it can be changed after the conversation is created.
+func (s *Store) SetReadOnly(id string, readOnly bool) error {
+	return s.SetReadOnlyForBackend(s.GetDefaultBackend(), id, readOnly)
+}
+
+// SetReadOnlyForBackend locks or unlocks a conversation on the specified backend.
+func (s *Store) SetReadOnlyForBackend(backend, id string, readOnly bool) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	old := cs.ReadOnly
+	cs.ReadOnly = readOnly
+	if err := s.saveLocked(); err != nil {
+		cs.ReadOnly = old
+		return err
+	}
+	return nil
+}
+
+// SetSendDedup turns duplicate-send suppression on or off for a
+// conversation. Like SetReadOnly it works after creation too.
+func (s *Store) SetSendDedup(id string, enabled bool) error {
+	return s.SetSendDedupForBackend(s.GetDefaultBackend(), id, enabled)
+}
+
+// SetSendDedupForBackend is SetSendDedup for a conversation on the specified backend.
+func (s *Store) SetSendDedupForBackend(backend, id string, enabled bool) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	old := cs.NoSendDedup
+	cs.NoSendDedup = !enabled
+	if err := s.saveLocked(); err != nil {
+		cs.NoSendDedup = old
+		return err
+	}
+	return nil
+}
+
+// SetBudget sets the token and cost caps of a conversation (zero for
+// none). Like SetReadOnly it works after creation too.
+func (s *Store) SetBudget(id string, tokens int64, usd float64) error {
+	return s.SetBudgetForBackend(s.GetDefaultBackend(), id, tokens, usd)
+}
+
+// SetBudgetForBackend is SetBudget for a conversation on the specified backend.
+func (s *Store) SetBudgetForBackend(backend, id string, tokens int64, usd float64) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	oldTokens, oldUSD := cs.BudgetTokens, cs.BudgetUSD
+	cs.BudgetTokens, cs.BudgetUSD = tokens, usd
+	if err := s.saveLocked(); err != nil {
+		cs.BudgetTokens, cs.BudgetUSD = oldTokens, oldUSD
+		return err
+	}
+	return nil
+}
+
+// RecordMessageEdit notes that a message of a conversation was edited at t.
+func (s *Store) RecordMessageEdit(id, messageID string, t time.Time) error {
+	return s.RecordMessageEditForBackend(s.GetDefaultBackend(), id, messageID, t)
+}
+
+// RecordMessageEditForBackend notes that a message of a conversation on the specified backend was edited at t.
+func (s *Store) RecordMessageEditForBackend(backend, id, messageID string, t time.Time) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	old, existed := cs.MessageEdits[messageID]
+	if cs.MessageEdits == nil {
+		cs.MessageEdits = make(map[string]time.Time)
+	}
+	cs.MessageEdits[messageID] = t
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			cs.MessageEdits[messageID] = old
+		} else {
+			delete(cs.MessageEdits, messageID)
+		}
+		return err
+	}
+	return nil
+}
+
+// MessageEditedAt returns when a message was last edited through the
+// filesystem, and whether it ever was.
+func (s *Store) MessageEditedAt(id, messageID string) (time.Time, bool) {
+	return s.MessageEditedAtForBackend(s.GetDefaultBackend(), id, messageID)
+}
+
+// MessageEditedAtForBackend is MessageEditedAt for a conversation on the specified backend.
+func (s *Store) MessageEditedAtForBackend(backend, id, messageID string) (time.Time, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return time.Time{}, false
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return time.Time{}, false
+	}
+	t, ok := cs.MessageEdits[messageID]
+	return t, ok
+}
+
+// SetMessagePinned pins or unpins a message of a conversation.
+func (s *Store) SetMessagePinned(id, messageID string, pinned bool) error {
+	return s.SetMessagePinnedForBackend(s.GetDefaultBackend(), id, messageID, pinned)
+}
+
+// SetMessagePinnedForBackend is SetMessagePinned for a conversation on the specified backend.
+func (s *Store) SetMessagePinnedForBackend(backend, id, messageID string, pinned bool) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	idx := slices.Index(cs.PinnedMessages, messageID)
+	if (idx >= 0) == pinned {
+		return nil
+	}
+	old := cs.PinnedMessages
+	if pinned {
+		cs.PinnedMessages = append(slices.Clip(old), messageID)
+	} else {
+		cs.PinnedMessages = slices.Delete(slices.Clone(old), idx, idx+1)
+	}
+	if err := s.saveLocked(); err != nil {
+		cs.PinnedMessages = old
+		return err
+	}
+	return nil
+}
+
+// PinnedMessages returns the IDs of the pinned messages of a conversation,
+// in the order they were pinned.
+func (s *Store) PinnedMessages(id string) []string {
+	return s.PinnedMessagesForBackend(s.GetDefaultBackend(), id)
+}
+
+// PinnedMessagesForBackend is PinnedMessages for a conversation on the specified backend.
+func (s *Store) PinnedMessagesForBackend(backend, id string) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return nil
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return nil
+	}
+	return slices.Clone(cs.PinnedMessages)
+}
+
+// SetBookmark records the index of the last message reader has seen in a conversation.
+func (s *Store) SetBookmark(id, reader string, index int) error {
+	return s.SetBookmarkForBackend(s.GetDefaultBackend(), id, reader, index)
+}
+
+// SetBookmarkForBackend is SetBookmark for a conversation on the specified backend.
+func (s *Store) SetBookmarkForBackend(backend, id, reader string, index int) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	old, existed := cs.Bookmarks[reader]
+	if existed && old == index {
+		return nil
+	}
+	if cs.Bookmarks == nil {
+		cs.Bookmarks = make(map[string]int)
+	}
+	cs.Bookmarks[reader] = index
+	if err := s.saveLocked(); err != nil {
+		if existed {
+			cs.Bookmarks[reader] = old
+		} else {
+			delete(cs.Bookmarks, reader)
+		}
+		return err
+	}
+	return nil
+}
+
+// ClearBookmark forgets reader's bookmark in a conversation.
+func (s *Store) ClearBookmark(id, reader string) error {
+	return s.ClearBookmarkForBackend(s.GetDefaultBackend(), id, reader)
+}
+
+// ClearBookmarkForBackend is ClearBookmark for a conversation on the specified backend.
+func (s *Store) ClearBookmarkForBackend(backend, id, reader string) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return fmt.Errorf("conversation %s not found", id)
+	}
+
+	old, existed := cs.Bookmarks[reader]
+	if !existed {
+		return nil
+	}
+	delete(cs.Bookmarks, reader)
+	if err := s.saveLocked(); err != nil {
+		cs.Bookmarks[reader] = old
+		return err
+	}
+	return nil
+}
+
+// Bookmark returns the message index reader has bookmarked in a conversation
+// and whether there is one.
+func (s *Store) Bookmark(id, reader string) (int, bool) {
+	return s.BookmarkForBackend(s.GetDefaultBackend(), id, reader)
+}
+
+// BookmarkForBackend is Bookmark for a conversation on the specified backend.
+func (s *Store) BookmarkForBackend(backend, id, reader string) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return 0, false
+	}
+	cs, ok := convs[id]
+	if !ok {
+		return 0, false
+	}
+	index, ok := cs.Bookmarks[reader]
+	return index, ok
 }
 
 // List returns all known conversation IDs, sorted.
@@ -339,12 +1022,15 @@ func (s *Store) GetBySlugForBackend(backend, slug string) string {
 		return ""
 	}
 
+	// Slugs are normally unique; if not, pick the lowest local ID so the
+	// answer is stable across calls.
+	found := ""
 	for _, cs := range convs {
-		if cs.Slug == slug {
-			return cs.LocalID
+		if cs.Slug == slug && (found == "" || cs.LocalID < found) {
+			found = cs.LocalID
 		}
 	}
-	return ""
+	return found
 }
 
 // Delete removes an unconversed conversation from state.
@@ -357,7 +1043,7 @@ func (s *Store) Delete(id string) error {
 // DeleteForBackend removes an unconversed conversation from the specified backend.
 func (s *Store) DeleteForBackend(backend, id string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	convs := s.conversationsForBackend(backend)
 	if convs == nil {
@@ -373,7 +1059,11 @@ func (s *Store) DeleteForBackend(backend, id string) error {
 	}
 
 	delete(convs, id)
-	return s.saveLocked()
+	if err := s.saveLocked(); err != nil {
+		return err
+	}
+	s.emitLocked(EventRemoved, backend, cs)
+	return nil
 }
 
 // ForceDelete removes a conversation from local state regardless of its created status.
@@ -385,19 +1075,24 @@ func (s *Store) ForceDelete(id string) error {
 // ForceDeleteForBackend removes a conversation from the specified backend regardless of created status.
 func (s *Store) ForceDeleteForBackend(backend, id string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	convs := s.conversationsForBackend(backend)
 	if convs == nil {
 		return fmt.Errorf("backend %q not found", backend)
 	}
 
-	if _, ok := convs[id]; !ok {
+	cs, ok := convs[id]
+	if !ok {
 		return fmt.Errorf("conversation %s not found", id)
 	}
 
 	delete(convs, id)
-	return s.saveLocked()
+	if err := s.saveLocked(); err != nil {
+		return err
+	}
+	s.emitLocked(EventRemoved, backend, cs)
+	return nil
 }
 
 // ListMappings returns all conversations with their server IDs and slugs.
@@ -456,73 +1151,315 @@ func (s *Store) AdoptWithMetadata(shelleyConversationID, slug, apiCreatedAt, api
 // AdoptWithMetadataForBackend creates a local conversation entry with metadata on the specified backend.
 func (s *Store) AdoptWithMetadataForBackend(backend, shelleyConversationID, slug, apiCreatedAt, apiUpdatedAt, model, cwd string) (string, error) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	convs := s.conversationsForBackend(backend)
 	if convs == nil {
 		return "", fmt.Errorf("backend %q not found", backend)
 	}
+	req := AdoptRequest{
+		ShelleyConversationID: shelleyConversationID,
+		Slug:                  slug,
+		APICreatedAt:          apiCreatedAt,
+		APIUpdatedAt:          apiUpdatedAt,
+		Model:                 model,
+		Cwd:                   cwd,
+	}
 
 	// Check if already tracked
 	for _, cs := range convs {
 		if cs.ShelleyConversationID == shelleyConversationID {
-			updated := false
-			// Update slug if it was previously empty and a new slug is provided
-			if slug != "" && cs.Slug == "" {
-				cs.Slug = slug
-				updated = true
-			}
-			// Update API timestamps if not already set
-			if apiCreatedAt != "" && cs.APICreatedAt == "" {
-				cs.APICreatedAt = apiCreatedAt
-				updated = true
-			}
-			if apiUpdatedAt != "" && (cs.APIUpdatedAt == "" || apiUpdatedAt > cs.APIUpdatedAt) {
-				cs.APIUpdatedAt = apiUpdatedAt
-				updated = true
-			}
-			if model != "" && cs.Model == "" {
-				cs.Model = model
-				updated = true
-			}
-			if cwd != "" && cs.Cwd == "" {
-				cs.Cwd = cwd
-				updated = true
-			}
+			updated, bumped := s.refreshAdoptedLocked(cs, req)
 			if updated {
 				_ = s.saveLocked() // Best effort save
 			}
+			if bumped {
+				s.emitLocked(EventUpdated, backend, cs)
+			}
 			return cs.LocalID, nil
 		}
 	}
 
-	// Generate a new local ID
-	id, err := s.generateIDForBackend(backend)
+	cs, err := s.addAdoptedLocked(backend, convs, req)
 	if err != nil {
 		return "", err
 	}
+	if cs.transient {
+		s.emitLocked(EventAdopted, backend, cs)
+		return cs.LocalID, nil
+	}
+	if err := s.saveLocked(); err != nil {
+		delete(convs, cs.LocalID)
+		return "", err
+	}
+	s.emitLocked(EventAdopted, backend, cs)
+	return cs.LocalID, nil
+}
 
-	convs[id] = &ConversationState{
+// AdoptRequest is a server conversation to adopt, with its API metadata.
+type AdoptRequest struct {
+	ShelleyConversationID string
+	Slug                  string
+	APICreatedAt          string
+	APIUpdatedAt          string
+	Model                 string
+	Cwd                   string
+}
+
+// AdoptBatch adopts several server conversations, or updates the metadata
+// of those already tracked, like AdoptWithMetadata does for one, but under
+// a single lock and with a single write of the state file.
+func (s *Store) AdoptBatch(reqs []AdoptRequest) error {
+	return s.AdoptBatchForBackend(s.GetDefaultBackend(), reqs)
+}
+
+// AdoptBatchForBackend adopts several server conversations on the specified
+// backend. If the state can't be saved, none of them is adopted.
+func (s *Store) AdoptBatchForBackend(backend string, reqs []AdoptRequest) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return fmt.Errorf("backend %q not found", backend)
+	}
+	byShelleyID := make(map[string]*ConversationState, len(convs))
+	for _, cs := range convs {
+		if cs.ShelleyConversationID != "" {
+			byShelleyID[cs.ShelleyConversationID] = cs
+		}
+	}
+
+	var added, bumped []*ConversationState
+	dirty := false
+	for _, req := range reqs {
+		if cs := byShelleyID[req.ShelleyConversationID]; cs != nil {
+			updated, wasBumped := s.refreshAdoptedLocked(cs, req)
+			dirty = dirty || updated
+			if wasBumped {
+				bumped = append(bumped, cs)
+			}
+			continue
+		}
+		cs, err := s.addAdoptedLocked(backend, convs, req)
+		if err != nil {
+			for _, a := range added {
+				delete(convs, a.LocalID)
+			}
+			return err
+		}
+		byShelleyID[req.ShelleyConversationID] = cs
+		added = append(added, cs)
+		dirty = dirty || !cs.transient
+	}
+
+	if dirty {
+		if err := s.saveLocked(); err != nil {
+			for _, a := range added {
+				delete(convs, a.LocalID)
+			}
+			return err
+		}
+	}
+	for _, cs := range added {
+		s.emitLocked(EventAdopted, backend, cs)
+	}
+	for _, cs := range bumped {
+		s.emitLocked(EventUpdated, backend, cs)
+	}
+	return nil
+}
+
+// refreshAdoptedLocked fills in metadata of a tracked conversation that is
+// missing locally and moves its API updated_at forward. It reports whether
+// anything changed and whether updated_at moved from an earlier value.
+// s.mu must be held.
+func (s *Store) refreshAdoptedLocked(cs *ConversationState, req AdoptRequest) (updated, bumped bool) {
+	// Update slug if it was previously empty and a new slug is provided
+	if req.Slug != "" && cs.Slug == "" {
+		cs.Slug = req.Slug
+		updated = true
+	}
+	// Update API timestamps if not already set
+	if req.APICreatedAt != "" && cs.APICreatedAt == "" {
+		cs.APICreatedAt = req.APICreatedAt
+		updated = true
+	}
+	if req.APIUpdatedAt != "" && (cs.APIUpdatedAt == "" || laterTimestamp(req.APIUpdatedAt, cs.APIUpdatedAt)) {
+		bumped = cs.APIUpdatedAt != ""
+		cs.APIUpdatedAt = req.APIUpdatedAt
+		s.noteActivityLocked(req.APIUpdatedAt)
+		updated = true
+	}
+	if req.Model != "" && cs.Model == "" {
+		cs.Model = req.Model
+		updated = true
+	}
+	if req.Cwd != "" && cs.Cwd == "" {
+		cs.Cwd = req.Cwd
+		updated = true
+	}
+	return updated, bumped
+}
+
+// addAdoptedLocked adds an entry for an untracked server conversation to
+// convs, without saving. s.mu must be held.
+func (s *Store) addAdoptedLocked(backend string, convs map[string]*ConversationState, req AdoptRequest) (*ConversationState, error) {
+	// In passthrough mode the server ID doubles as the local ID, so the
+	// directory name is stable without recording anything on disk.
+	var id string
+	if _, taken := convs[req.ShelleyConversationID]; s.passthrough && !taken {
+		id = req.ShelleyConversationID
+	} else {
+		var err error
+		if id, err = s.generateIDForBackend(backend); err != nil {
+			return nil, err
+		}
+	}
+
+	cs := &ConversationState{
+		LocalID:               id,
+		ShelleyConversationID: req.ShelleyConversationID,
+		Slug:                  req.Slug,
+		Model:                 req.Model,
+		Cwd:                   req.Cwd,
+		Created:               true, // Already exists on server
+		CreatedAt:             time.Now(),
+		APICreatedAt:          req.APICreatedAt,
+		APIUpdatedAt:          req.APIUpdatedAt,
+		transient:             s.passthrough,
+	}
+	convs[id] = cs
+	return cs, nil
+}
+
+// ImportMapping restores a conversation mapping recorded elsewhere (e.g. on
+// the backend by another mount). If the Shelley ID is already tracked, the
+// existing local ID is kept; an empty slug is filled in and metadata keys
+// that are not set locally are merged. Otherwise the conversation is adopted
+// under localID when that ID is free, or under a fresh ID if it is taken.
+// Records come from elsewhere, so a local ID that isn't shaped like the ones
+// the store generates is replaced by a fresh ID too, and meta keys that
+// can't name a file are dropped; both are logged. Returns the local ID the
+// conversation ends up with.
+func (s *Store) ImportMapping(localID, shelleyConversationID, slug string, meta map[string]string) (string, error) {
+	return s.ImportMappingForBackend(s.GetDefaultBackend(), localID, shelleyConversationID, slug, meta)
+}
+
+// ImportMappingForBackend restores a conversation mapping on the specified backend.
+func (s *Store) ImportMappingForBackend(backend, localID, shelleyConversationID, slug string, meta map[string]string) (string, error) {
+	if shelleyConversationID == "" {
+		return "", fmt.Errorf("mapping for %q has no Shelley conversation ID", localID)
+	}
+	var dropped []string
+	for k := range meta {
+		if !validMetaKey(k) {
+			dropped = append(dropped, k)
+		}
+	}
+	if len(dropped) > 0 {
+		sort.Strings(dropped)
+		log.Printf("Mapping %s -> %s: skipping invalid meta keys %q", localID, shelleyConversationID, dropped)
+	}
+	if localID != "" && !validLocalID(localID) {
+		log.Printf("Mapping %q -> %s: invalid local ID; not using it", localID, shelleyConversationID)
+		localID = ""
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	convs := s.conversationsForBackend(backend)
+	if convs == nil {
+		return "", fmt.Errorf("backend %q not found", backend)
+	}
+
+	for _, cs := range convs {
+		if cs.ShelleyConversationID != shelleyConversationID {
+			continue
+		}
+		updated := false
+		if slug != "" && cs.Slug == "" {
+			cs.Slug = slug
+			updated = true
+		}
+		for k, v := range meta {
+			if _, ok := cs.Meta[k]; ok || !validMetaKey(k) {
+				continue
+			}
+			if cs.Meta == nil {
+				cs.Meta = make(map[string]string)
+			}
+			cs.Meta[k] = v
+			updated = true
+		}
+		if updated {
+			_ = s.saveLocked() // Best effort save
+		}
+		return cs.LocalID, nil
+	}
+
+	id := localID
+	if _, taken := convs[id]; taken || id == "" {
+		var err error
+		if id, err = s.generateIDForBackend(backend); err != nil {
+			return "", err
+		}
+	}
+
+	cs := &ConversationState{
 		LocalID:               id,
 		ShelleyConversationID: shelleyConversationID,
 		Slug:                  slug,
-		Model:                 model,
-		Cwd:                   cwd,
 		Created:               true, // Already exists on server
 		CreatedAt:             time.Now(),
-		APICreatedAt:          apiCreatedAt,
-		APIUpdatedAt:          apiUpdatedAt,
 	}
+	for k, v := range meta {
+		if !validMetaKey(k) {
+			continue
+		}
+		if cs.Meta == nil {
+			cs.Meta = make(map[string]string, len(meta))
+		}
+		cs.Meta[k] = v
+	}
+	convs[id] = cs
 
 	if err := s.saveLocked(); err != nil {
 		delete(convs, id)
 		return "", err
 	}
+	s.emitLocked(EventAdopted, backend, cs)
 	return id, nil
 }
 
-// Load reads state from disk. Returns os.ErrNotExist if file doesn't exist.
+// validLocalID reports whether id has the shape of the local IDs the store
+// generates: eight lowercase hex digits.
+func validLocalID(id string) bool {
+	if len(id) != 8 {
+		return false
+	}
+	for _, c := range id {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validMetaKey reports whether key can name a file in a conversation's
+// meta directory.
+func validMetaKey(key string) bool {
+	return key != "" && key != "." && key != ".." && !strings.ContainsAny(key, "/\x00")
+}
+
+// Load reads state from disk. Returns os.ErrNotExist if file doesn't exist,
+// and a *CorruptError if it can't be parsed.
 func (s *Store) Load() error {
+	if s.PersistStatus().MemoryOnly {
+		// The file is older than what is in memory.
+		return nil
+	}
 	data, err := os.ReadFile(s.Path)
 	if err != nil {
 		return err
@@ -539,6 +1476,7 @@ func (s *Store) Load() error {
 			s.DefaultBackend = newFormat.DefaultBackend
 			// Ensure default backend exists
 			s.defaultBackend()
+			s.refreshKnownLocked()
 			return nil
 		}
 	}
@@ -546,7 +1484,7 @@ func (s *Store) Load() error {
 	// If new format failed, try old format (flat conversations map) and migrate
 	var v1 V1State
 	if err := json.Unmarshal(data, &v1); err != nil {
-		return fmt.Errorf("failed to parse state file: %w", err)
+		return &CorruptError{Path: s.Path, Err: err}
 	}
 
 	// Migrate from old format
@@ -566,16 +1504,55 @@ func (s *Store) Load() error {
 func (s *Store) saveLocked() error {
 	dir := filepath.Dir(s.Path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
+		if s.persistFailedLocked(err) {
+			s.revision++
+			return nil
+		}
 		return fmt.Errorf("failed to create state directory: %w", err)
 	}
 	data, err := json.MarshalIndent(struct {
 		Backends       map[string]*BackendState `json:"backends"`
 		DefaultBackend string                  `json:"default_backend,omitempty"`
-	}{Backends: s.Backends, DefaultBackend: s.DefaultBackend}, "", "  ")
+	}{Backends: s.persistentBackendsLocked(), DefaultBackend: s.DefaultBackend}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
-	return os.WriteFile(s.Path, data, 0644)
+	if err := writeStateFile(s.Path, data); err != nil {
+		if !s.persistFailedLocked(err) {
+			return err
+		}
+	} else {
+		s.persistedLocked()
+	}
+	s.revision++
+	return nil
+}
+
+// persistentBackendsLocked returns the backends with transient conversations
+// left out, for writing to disk.
+func (s *Store) persistentBackendsLocked() map[string]*BackendState {
+	if !s.passthrough {
+		return s.Backends
+	}
+	out := make(map[string]*BackendState, len(s.Backends))
+	for name, b := range s.Backends {
+		convs := make(map[string]*ConversationState, len(b.Conversations))
+		for id, cs := range b.Conversations {
+			if !cs.transient {
+				convs[id] = cs
+			}
+		}
+		out[name] = &BackendState{URL: b.URL, Conversations: convs, Capabilities: b.Capabilities, Features: b.Features}
+	}
+	return out
+}
+
+// Revision returns a counter that increases every time the state is saved.
+// It is not persisted; it only orders changes within one process.
+func (s *Store) Revision() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revision
 }
 
 func (s *Store) generateID() (string, error) {
@@ -611,7 +1588,7 @@ var reservedBackendNames = map[string]bool{
 // Returns an error if the name is reserved or already exists.
 func (s *Store) CreateBackend(name, url string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	if reservedBackendNames[name] {
 		return fmt.Errorf("backend name %q is reserved", name)
@@ -639,7 +1616,7 @@ func (s *Store) GetBackend(name string) *BackendState {
 // Returns an error if the backend doesn't exist or is the default backend.
 func (s *Store) DeleteBackend(name string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	if name == s.getDefaultBackend() {
 		return fmt.Errorf("cannot delete default backend %q", name)
@@ -658,7 +1635,7 @@ func (s *Store) DeleteBackend(name string) error {
 // If the renamed backend is the default, updates the default backend reference.
 func (s *Store) RenameBackend(oldName, newName string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	if _, exists := s.Backends[oldName]; !exists {
 		return fmt.Errorf("backend %q not found", oldName)
@@ -688,7 +1665,7 @@ func (s *Store) RenameBackend(oldName, newName string) error {
 // Returns an error if the backend doesn't exist.
 func (s *Store) SetDefaultBackend(name string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	if _, exists := s.Backends[name]; !exists {
 		return fmt.Errorf("backend %q not found", name)
@@ -718,7 +1695,7 @@ func (s *Store) getDefaultBackend() string {
 // Ensures the default backend exists before listing.
 func (s *Store) ListBackends() []string {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	// Ensure the default backend exists
 	if _, ok := s.Backends[mainBackendName]; !ok {
@@ -740,13 +1717,16 @@ func (s *Store) ListBackends() []string {
 // Returns an error if the backend doesn't exist.
 func (s *Store) SetBackendURL(name, url string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	b, exists := s.Backends[name]
 	if !exists {
 		return fmt.Errorf("backend %q not found", name)
 	}
 
+	if b.URL != url {
+		b.Capabilities = nil // a different server
+	}
 	b.URL = url
 	return s.saveLocked()
 }
@@ -755,7 +1735,7 @@ func (s *Store) SetBackendURL(name, url string) error {
 // This is useful for initializing the default backend URL on startup.
 func (s *Store) EnsureBackendURL(name, url string) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	if _, exists := s.Backends[name]; !exists {
 		s.Backends[name] = &BackendState{
@@ -763,6 +1743,9 @@ func (s *Store) EnsureBackendURL(name, url string) error {
 		}
 	}
 
+	if s.Backends[name].URL != url {
+		s.Backends[name].Capabilities = nil // a different server
+	}
 	s.Backends[name].URL = url
 	return s.saveLocked()
 }
@@ -1,10 +1,18 @@
 package state
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func tempStatePath(t *testing.T) string {
@@ -81,6 +89,39 @@ func TestSetCtl(t *testing.T) {
 	}
 }
 
+func TestSetCtlSlug(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := s.Clone()
+	if err := s.SetCtl(id, "slug", "my-slug"); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.GetBySlug("my-slug"); got != id {
+		t.Errorf("GetBySlug(my-slug) = %q, want %q", got, id)
+	}
+
+	// A backend that returns no slug keeps the requested one.
+	if err := s.MarkCreated(id, "server-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if cs := s.Get(id); cs.Slug != "my-slug" {
+		t.Errorf("slug after creation without one = %q, want my-slug", cs.Slug)
+	}
+
+	// One that picks its own wins.
+	id2, _ := s.Clone()
+	s.SetCtl(id2, "slug", "wanted")
+	if err := s.MarkCreated(id2, "server-2", "given"); err != nil {
+		t.Fatal(err)
+	}
+	if cs := s.Get(id2); cs.Slug != "given" {
+		t.Errorf("slug after creation = %q, want the backend's", cs.Slug)
+	}
+}
+
 func TestSetCtlUnknownKey(t *testing.T) {
 	s, err := NewStore(tempStatePath(t))
 	if err != nil {
@@ -232,6 +273,64 @@ func TestNewStoreCorruptFile(t *testing.T) {
 	}
 }
 
+func TestNewStoreRepairing(t *testing.T) {
+	path := tempStatePath(t)
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept1, _ := s.AdoptWithSlug("conv-1", "first")
+	kept2, _ := s.Adopt("conv-2")
+	if err := s.EnsureBackendURL("work", "http://work.example"); err != nil {
+		t.Fatal(err)
+	}
+	keptWork, _ := s.AdoptForBackend("work", "conv-w1")
+	lost, _ := s.AdoptForBackend("work", "conv-w2")
+
+	// Cut the file off in the middle of the last conversation written
+	// (maps are written in key order).
+	if keptWork > lost {
+		keptWork, lost = lost, keptWork
+	}
+	data, _ := os.ReadFile(path)
+	cut := data[:bytes.LastIndex(data, []byte(`"local_id": "`+lost))+5]
+	os.WriteFile(path, cut, 0644)
+
+	if _, err := NewStore(path); !errors.As(err, new(*CorruptError)) {
+		t.Fatalf("NewStore on a truncated file: got %v, want a CorruptError", err)
+	}
+	repaired, repair, err := NewStoreRepairing(path)
+	if err != nil || repair == nil {
+		t.Fatalf("NewStoreRepairing = %v, %v", repair, err)
+	}
+	if repair.Recovered != 3 {
+		t.Errorf("recovered %d conversations, want 3", repair.Recovered)
+	}
+	if backup, err := os.ReadFile(repair.Backup); err != nil || !bytes.Equal(backup, cut) {
+		t.Errorf("backup %s: %v, want the corrupt file", repair.Backup, err)
+	}
+	if cs := repaired.Get(kept1); cs == nil || cs.ShelleyConversationID != "conv-1" || cs.Slug != "first" {
+		t.Errorf("conv-1 after repair = %+v", cs)
+	}
+	if repaired.GetByShelleyID("conv-2") != kept2 {
+		t.Errorf("conv-2 lost its local ID %s", kept2)
+	}
+	if cs := repaired.GetForBackend("work", keptWork); cs == nil {
+		t.Errorf("conversation %s of backend work not recovered", keptWork)
+	}
+	if repaired.GetForBackend("work", lost) != nil {
+		t.Errorf("truncated conversation %s recovered", lost)
+	}
+	if b := repaired.GetBackend("work"); b == nil || b.URL != "http://work.example" {
+		t.Errorf("backend work after repair = %+v", b)
+	}
+
+	// The repaired file loads cleanly.
+	if _, repair, err := NewStoreRepairing(path); err != nil || repair != nil {
+		t.Errorf("second NewStoreRepairing = %v, %v; want no repair", repair, err)
+	}
+}
+
 func TestGetByShelleyID(t *testing.T) {
 	s, err := NewStore(tempStatePath(t))
 	if err != nil {
@@ -771,6 +870,73 @@ func TestAdoptWithMetadata(t *testing.T) {
 	}
 }
 
+func TestAdoptBatch(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := s.AdoptWithMetadata("server-batch-1", "", "", "2024-01-01T00:00:00Z", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	s.SetEventHook(func(e Event) { events = append(events, e) })
+
+	rev := s.Revision()
+	err = s.AdoptBatch([]AdoptRequest{
+		{ShelleyConversationID: "server-batch-1", Slug: "first", APIUpdatedAt: "2024-02-01T00:00:00Z"},
+		{ShelleyConversationID: "server-batch-2", Slug: "second", Model: "claude"},
+		{ShelleyConversationID: "server-batch-3"},
+		{ShelleyConversationID: "server-batch-2"}, // listed twice
+	})
+	if err != nil {
+		t.Fatalf("AdoptBatch failed: %v", err)
+	}
+	if got := s.Revision() - rev; got != 1 {
+		t.Errorf("AdoptBatch saved %d times, want once", got)
+	}
+	if cs := s.Get(existing); cs.Slug != "first" || cs.APIUpdatedAt != "2024-02-01T00:00:00Z" {
+		t.Errorf("existing conversation not refreshed: %+v", cs)
+	}
+	second := s.GetByShelleyID("server-batch-2")
+	if second == "" || s.Get(second).Model != "claude" || s.GetByShelleyID("server-batch-3") == "" {
+		t.Fatalf("new conversations not adopted: %v", s.ListMappings())
+	}
+	if n := len(s.ListMappings()); n != 3 {
+		t.Errorf("%d conversations tracked, want 3", n)
+	}
+	var adopted, updated int
+	for _, e := range events {
+		switch e.Type {
+		case EventAdopted:
+			adopted++
+		case EventUpdated:
+			updated++
+		}
+	}
+	if adopted != 2 || updated != 1 {
+		t.Errorf("got %d adopted and %d updated events, want 2 and 1", adopted, updated)
+	}
+
+	// Nothing new: nothing written.
+	rev = s.Revision()
+	if err := s.AdoptBatch([]AdoptRequest{{ShelleyConversationID: "server-batch-3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if s.Revision() != rev {
+		t.Error("AdoptBatch saved without changes")
+	}
+
+	// Reloading finds the batch on disk.
+	s2, err := NewStore(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.GetByShelleyID("server-batch-2") != second {
+		t.Error("batch-adopted conversation not persisted")
+	}
+}
+
 func TestAdoptWithMetadataUpdatesTimestamps(t *testing.T) {
 	s, err := NewStore(tempStatePath(t))
 	if err != nil {
@@ -2052,6 +2218,39 @@ func TestCloneForBackendNonexistentBackend(t *testing.T) {
 	}
 }
 
+func TestMaxPendingClones(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateBackend("other", "http://localhost:9999"); err != nil {
+		t.Fatal(err)
+	}
+	s.SetMaxPendingClones(2)
+
+	first, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CloneForBackend("other"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Clone(); !errors.Is(err, ErrTooManyClones) {
+		t.Fatalf("third clone: got %v, want ErrTooManyClones", err)
+	}
+	if count, limit := s.PendingClones(); count != 2 || limit != 2 {
+		t.Errorf("PendingClones() = %d, %d; want 2, 2", count, limit)
+	}
+
+	// A conversation that got its first message no longer counts.
+	if err := s.MarkCreated(first, "server-1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Clone(); err != nil {
+		t.Errorf("clone after one was created: %v", err)
+	}
+}
+
 func TestGetForBackendNonexistentBackend(t *testing.T) {
 	s, err := NewStore(tempStatePath(t))
 	if err != nil {
@@ -2593,4 +2792,804 @@ func TestSameIDDifferentBackends(t *testing.T) {
 			t.Errorf("wrong server ID for backend-x %s: %s", id, cs.ShelleyConversationID)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestMeta(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, _ := s.Clone()
+	_ = s.MarkCreated(id, "shelley-meta", "")
+
+	// Metadata remains writable after creation, unlike ctl
+	if err := s.SetMeta(id, "checkpoint", "step-3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetMeta(id, "correlation-id", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.GetMeta(id, "checkpoint"); !ok || v != "step-3" {
+		t.Errorf("GetMeta(checkpoint) = %q, %v; want step-3, true", v, ok)
+	}
+	if _, ok := s.GetMeta(id, "missing"); ok {
+		t.Error("expected missing key to be absent")
+	}
+
+	keys := s.ListMeta(id)
+	if len(keys) != 2 || keys[0] != "checkpoint" || keys[1] != "correlation-id" {
+		t.Errorf("ListMeta = %v, want [checkpoint correlation-id]", keys)
+	}
+
+	if err := s.DeleteMeta(id, "checkpoint"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.GetMeta(id, "checkpoint"); ok {
+		t.Error("expected checkpoint to be deleted")
+	}
+	if err := s.DeleteMeta(id, "checkpoint"); err == nil {
+		t.Error("expected error deleting missing key")
+	}
+}
+
+func TestCreateMeta(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s.Clone()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	winners := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			created, err := s.CreateMeta(id, "lock")
+			if err != nil {
+				t.Error(err)
+			}
+			if created {
+				mu.Lock()
+				winners++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if winners != 1 {
+		t.Errorf("%d concurrent CreateMeta calls created the key, want 1", winners)
+	}
+
+	// An existing value is left alone.
+	if err := s.SetMeta(id, "lock", "pid 42"); err != nil {
+		t.Fatal(err)
+	}
+	if created, err := s.CreateMeta(id, "lock"); created || err != nil {
+		t.Errorf("CreateMeta of an existing key = %v, %v", created, err)
+	}
+	if v, _ := s.GetMeta(id, "lock"); v != "pid 42" {
+		t.Errorf("value after CreateMeta = %q, want it unchanged", v)
+	}
+	if _, err := s.CreateMeta("nonexistent", "lock"); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+}
+
+func TestMetaNotFound(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetMeta("nonexistent", "k", "v"); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+	if keys := s.ListMeta("nonexistent"); keys != nil {
+		t.Errorf("expected nil keys, got %v", keys)
+	}
+}
+
+func TestMetaPersistence(t *testing.T) {
+	path := tempStatePath(t)
+	s1, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s1.Clone()
+	if err := s1.SetMeta(id, "ticket", "PROJ-42"); err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s2.GetMeta(id, "ticket"); !ok || v != "PROJ-42" {
+		t.Errorf("after reload GetMeta(ticket) = %q, %v; want PROJ-42, true", v, ok)
+	}
+}
+
+func TestRecordMessageEdit(t *testing.T) {
+	path := tempStatePath(t)
+	s1, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s1.Clone()
+	_ = s1.MarkCreated(id, "shelley-edit", "")
+	if _, ok := s1.MessageEditedAt(id, "m1"); ok {
+		t.Error("expected no edit recorded before the first one")
+	}
+	at := time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)
+	if err := s1.RecordMessageEdit(id, "m1", at); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.RecordMessageEdit("nonexistent", "m1", at); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := s2.MessageEditedAt(id, "m1"); !ok || !got.Equal(at) {
+		t.Errorf("after reload MessageEditedAt(m1) = %v, %v; want %v, true", got, ok, at)
+	}
+}
+
+func TestSetMessagePinned(t *testing.T) {
+	path := tempStatePath(t)
+	s1, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s1.Clone()
+	for _, msg := range []string{"m2", "m1", "m3", "m1"} {
+		if err := s1.SetMessagePinned(id, msg, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s1.SetMessagePinned(id, "m3", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.SetMessagePinned(id, "m9", false); err != nil {
+		t.Errorf("unpinning a message that isn't pinned: %v", err)
+	}
+	if err := s1.SetMessagePinned("nonexistent", "m1", true); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s2.PinnedMessages(id); !slices.Equal(got, []string{"m2", "m1"}) {
+		t.Errorf("after reload PinnedMessages = %v, want [m2 m1]", got)
+	}
+}
+
+func TestBookmarks(t *testing.T) {
+	path := tempStatePath(t)
+	s1, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s1.Clone()
+	if _, ok := s1.Bookmark(id, "1000"); ok {
+		t.Error("new conversation has a bookmark")
+	}
+	if err := s1.SetBookmark(id, "1000", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.SetBookmark(id, "1001", 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.ClearBookmark(id, "1001"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.ClearBookmark(id, "1002"); err != nil {
+		t.Errorf("clearing a bookmark that isn't set: %v", err)
+	}
+	if err := s1.SetBookmark("nonexistent", "1000", 1); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq, ok := s2.Bookmark(id, "1000"); !ok || seq != 4 {
+		t.Errorf("after reload Bookmark(1000) = %d, %v; want 4, true", seq, ok)
+	}
+	if _, ok := s2.Bookmark(id, "1001"); ok {
+		t.Error("cleared bookmark came back after reload")
+	}
+}
+
+func TestSetReadOnly(t *testing.T) {
+	path := tempStatePath(t)
+	s1, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s1.Clone()
+	_ = s1.MarkCreated(id, "shelley-frozen", "")
+	if err := s1.SetReadOnly(id, true); err != nil {
+		t.Fatalf("SetReadOnly after creation: %v", err)
+	}
+	if err := s1.SetReadOnly("nonexistent", true); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s2.Get(id).ReadOnly {
+		t.Error("ReadOnly not persisted")
+	}
+}
+
+func TestSetSendDedup(t *testing.T) {
+	path := tempStatePath(t)
+	s1, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s1.Clone()
+	_ = s1.MarkCreated(id, "shelley-repeats", "")
+	if err := s1.SetSendDedup(id, false); err != nil {
+		t.Fatalf("SetSendDedup after creation: %v", err)
+	}
+	if err := s1.SetSendDedup("nonexistent", false); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s2.Get(id).NoSendDedup {
+		t.Error("NoSendDedup not persisted")
+	}
+}
+
+func TestSetLiveSettings(t *testing.T) {
+	path := tempStatePath(t)
+	s1, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s1.Clone()
+	if err := s1.SetCtl(id, "temperature", "0.7"); err != nil {
+		t.Fatal(err)
+	}
+	_ = s1.SetModel(id, "model-a", "model-a")
+	_ = s1.MarkCreated(id, "shelley-live", "")
+	if err := s1.SetCtl(id, "temperature", "0.2"); err == nil {
+		t.Error("expected SetCtl to fail after creation")
+	}
+	// Empty arguments keep the temperature; the model changes.
+	if err := s1.SetLiveSettings(id, "model-b", "custom-b1", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s1.SetLiveSettings("nonexistent", "model-b", "", ""); err == nil {
+		t.Error("expected error for nonexistent conversation")
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs := s2.Get(id)
+	if cs.Model != "model-b" || cs.ModelID != "custom-b1" || cs.Temperature != "0.7" {
+		t.Errorf("after reload got model=%q model_id=%q temperature=%q", cs.Model, cs.ModelID, cs.Temperature)
+	}
+}
+
+func TestImportMapping(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := s.ImportMapping("abcd1234", "conv-1", "slug-1", map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "abcd1234" {
+		t.Errorf("expected recorded local ID to be kept, got %q", id)
+	}
+	cs := s.Get(id)
+	if cs == nil || !cs.Created || cs.ShelleyConversationID != "conv-1" || cs.Slug != "slug-1" {
+		t.Fatalf("unexpected state: %+v", cs)
+	}
+	if v, _ := s.GetMeta(id, "k"); v != "v" {
+		t.Errorf("meta k = %q, want v", v)
+	}
+
+	// Re-importing keeps the ID and merges only missing metadata.
+	if err := s.SetMeta(id, "k", "local"); err != nil {
+		t.Fatal(err)
+	}
+	again, err := s.ImportMapping("ffff0000", "conv-1", "other", map[string]string{"k": "remote", "n": "new"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != id {
+		t.Errorf("re-import returned %q, want %q", again, id)
+	}
+	if v, _ := s.GetMeta(id, "k"); v != "local" {
+		t.Errorf("local meta overwritten: %q", v)
+	}
+	if v, _ := s.GetMeta(id, "n"); v != "new" {
+		t.Errorf("missing meta not merged: %q", v)
+	}
+	if s.Get(id).Slug != "slug-1" {
+		t.Errorf("existing slug overwritten: %q", s.Get(id).Slug)
+	}
+}
+
+func TestImportMappingLocalIDTaken(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := s.ImportMapping(taken, "conv-2", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == taken {
+		t.Error("import reused a local ID that belongs to another conversation")
+	}
+	if s.GetByShelleyID("conv-2") != id {
+		t.Errorf("conv-2 not mapped to %q", id)
+	}
+
+	if _, err := s.ImportMapping("abcd0000", "", "", nil); err == nil {
+		t.Error("expected error for mapping without a Shelley ID")
+	}
+}
+
+func TestImportMappingInvalidRecord(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"../../etc", "ABCD1234", "abcd12345", "a/b"} {
+		id, err := s.ImportMapping(bad, "conv-"+bad, "", nil)
+		if err != nil {
+			t.Fatalf("ImportMapping(%q): %v", bad, err)
+		}
+		if id == bad || !validLocalID(id) {
+			t.Errorf("ImportMapping(%q) = %q, want a fresh ID", bad, id)
+		}
+	}
+
+	id, err := s.ImportMapping("abcd1234", "conv-meta", "", map[string]string{"ok": "1", "../x": "2", "": "3", "..": "4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta := s.Get(id).Meta; len(meta) != 1 || meta["ok"] != "1" {
+		t.Errorf("meta = %v, want only the valid key", meta)
+	}
+	if _, err := s.ImportMapping("ffff0000", "conv-meta", "", map[string]string{"a/b": "5"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Get(id).Meta["a/b"]; ok {
+		t.Error("invalid meta key merged into a tracked conversation")
+	}
+}
+
+func TestRevision(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := s.Revision()
+	if _, err := s.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Revision() <= before {
+		t.Errorf("revision did not advance after save: %d -> %d", before, s.Revision())
+	}
+}
+
+func TestPassthroughAdoptIsNotPersisted(t *testing.T) {
+	path := tempStatePath(t)
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPassthrough(true)
+
+	id, err := s.AdoptWithSlug("server-conv-1", "my-slug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "server-conv-1" {
+		t.Errorf("passthrough adoption should use the server ID as local ID, got %q", id)
+	}
+	cs := s.Get(id)
+	if cs == nil || !cs.Transient() {
+		t.Fatalf("expected transient conversation, got %+v", cs)
+	}
+	if s.GetBySlug("my-slug") != id {
+		t.Errorf("slug lookup should find the transient conversation")
+	}
+
+	// Locally created conversations are still persisted.
+	local, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s2.Get(id) != nil {
+		t.Error("transient conversation was written to the state file")
+	}
+	if s2.Get(local) == nil {
+		t.Error("cloned conversation was not persisted")
+	}
+}
+
+func TestEventHook(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	s.SetEventHook(func(e Event) { events = append(events, e) })
+
+	clone, _ := s.Clone()
+	s.MarkCreated(clone, "server-new", "")
+	adopted, _ := s.AdoptWithMetadata("server-old", "", "", "2024-01-01T00:00:00Z", "", "")
+	s.AdoptWithMetadata("server-old", "", "", "2024-01-01T00:00:00Z", "", "") // unchanged
+	s.AdoptWithMetadata("server-old", "", "", "2024-01-02T00:00:00Z", "", "")
+	s.ForceDelete(adopted)
+	stale, _ := s.Clone()
+	s.Delete(stale)
+
+	want := []struct {
+		typ     EventType
+		localID string
+	}{
+		{EventCreated, clone},
+		{EventAdopted, adopted},
+		{EventUpdated, adopted},
+		{EventRemoved, adopted},
+		{EventRemoved, stale},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != w.typ || e.LocalID != w.localID || e.Backend != DefaultBackendName || e.Time.IsZero() {
+			t.Errorf("event %d = %+v, want %s for %s", i, e, w.typ, w.localID)
+		}
+	}
+	if events[0].ShelleyConversationID != "server-new" {
+		t.Errorf("created event has conversation ID %q", events[0].ShelleyConversationID)
+	}
+}
+
+func TestNoteMessageTime(t *testing.T) {
+	s, err := NewStore(tempStatePath(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	s.SetEventHook(func(e Event) { events = append(events, e) })
+	id, _ := s.AdoptWithMetadata("server-1", "", "2024-01-01T00:00:00Z", "2024-01-01T10:00:00Z", "", "")
+	events = nil
+
+	s.NoteMessageTime("server-1", "2024-01-01T09:00:00Z") // older than updated_at
+	s.NoteMessageTime("server-1", "2024-01-01T10:00:00.000Z")
+	s.NoteMessageTime("unknown", "2024-01-03T00:00:00Z")
+	if got := s.Get(id).APIUpdatedAt; got != "2024-01-01T10:00:00Z" {
+		t.Errorf("updated_at moved to %s for an older message", got)
+	}
+	if len(events) != 0 || !s.LastActivity().IsZero() {
+		t.Errorf("unexpected activity: %+v, %v", events, s.LastActivity())
+	}
+
+	s.NoteMessageTime("server-1", "2024-01-02T08:30:00.5Z")
+	if got := s.Get(id).APIUpdatedAt; got != "2024-01-02T08:30:00.5Z" {
+		t.Errorf("updated_at = %s after a new message", got)
+	}
+	if len(events) != 1 || events[0].Type != EventUpdated || events[0].LocalID != id {
+		t.Errorf("events = %+v, want one update", events)
+	}
+	if want := time.Date(2024, 1, 2, 8, 30, 0, 5e8, time.UTC); !s.LastActivity().Equal(want) {
+		t.Errorf("LastActivity() = %v, want %v", s.LastActivity(), want)
+	}
+
+	// A listing with the server's own, coarser timestamp doesn't go back.
+	s.AdoptWithMetadata("server-1", "", "", "2024-01-02T08:30:00Z", "", "")
+	if got := s.Get(id).APIUpdatedAt; got != "2024-01-02T08:30:00.5Z" {
+		t.Errorf("updated_at went back to %s", got)
+	}
+
+	s2, err := NewStore(s.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s2.Get(id).APIUpdatedAt; got != "2024-01-02T08:30:00.5Z" {
+		t.Errorf("updated_at not persisted: %s", got)
+	}
+}
+
+func TestProfilePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	if got, err := ProfilePath(""); err != nil || got != filepath.Join(home, ".shelley-fuse", "state.json") {
+		t.Errorf("default profile = %q, %v", got, err)
+	}
+	work, err := ProfilePath("work")
+	if err != nil || work != filepath.Join(home, ".shelley-fuse", "profiles", "work", "state.json") {
+		t.Fatalf("work profile = %q, %v", work, err)
+	}
+	for _, bad := range []string{"..", ".hidden", "a/b"} {
+		if _, err := ProfilePath(bad); err == nil {
+			t.Errorf("ProfilePath(%q) should fail", bad)
+		}
+	}
+
+	// Profiles don't see each other's conversations.
+	s, err := NewStore(work)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := s.Clone()
+	other, err := NewStore(filepath.Join(home, ".shelley-fuse", "profiles", "home", "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Get(id) != nil {
+		t.Error("conversation leaked into another profile")
+	}
+	if _, err := os.Stat(work); err != nil {
+		t.Errorf("profile state file not written: %v", err)
+	}
+}
+
+func TestBackendFeatures(t *testing.T) {
+	path := tempStatePath(t)
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateBackend("old", "http://old:9999"); err != nil {
+		t.Fatal(err)
+	}
+	if !s.FeatureEnabledForBackend("old", "streaming") {
+		t.Error("features should be assumed before the backend reports any")
+	}
+
+	err = s.SetBackendCapabilities("old", BackendCapabilities{Version: "1.0", Features: []string{"edit", "delete"}, Negotiated: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.FeatureEnabledForBackend("old", "delete") || s.FeatureEnabledForBackend("old", "streaming") {
+		t.Error("FeatureEnabledForBackend doesn't follow the reported features")
+	}
+	if s.FeatureEnabled("streaming") != true {
+		t.Error("the default backend picked up another backend's features")
+	}
+
+	// Flags set by hand win over the report, in both directions.
+	if err := s.SetBackendFeature("old", "streaming", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetBackendFeature("old", "delete", false); err != nil {
+		t.Fatal(err)
+	}
+	if !s.FeatureEnabledForBackend("old", "streaming") || s.FeatureEnabledForBackend("old", "delete") {
+		t.Error("flags set by hand don't override the reported features")
+	}
+	if err := s.SetBackendFeature("missing", "delete", false); err == nil {
+		t.Error("SetBackendFeature on a missing backend succeeded")
+	}
+
+	// Both survive a restart.
+	s2, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s2.FeatureEnabledForBackend("old", "streaming") || s2.FeatureEnabledForBackend("old", "delete") {
+		t.Error("feature flags lost on reload")
+	}
+	if caps := s2.Backends["old"].Capabilities; caps == nil || caps.Version != "1.0" || !slices.Equal(caps.Features, []string{"delete", "edit"}) {
+		t.Errorf("capabilities after reload = %+v", caps)
+	}
+
+	// Clearing a flag hands the decision back to the report.
+	if err := s2.ClearBackendFeature("old", "streaming"); err != nil {
+		t.Fatal(err)
+	}
+	if s2.FeatureEnabledForBackend("old", "streaming") {
+		t.Error("cleared flag still in effect")
+	}
+
+	// A new URL is a different server: its report no longer applies, the
+	// flags set by hand still do.
+	if err := s2.SetBackendURL("old", "http://new:9999"); err != nil {
+		t.Fatal(err)
+	}
+	if s2.Backends["old"].Capabilities != nil {
+		t.Error("capabilities kept across a URL change")
+	}
+	if !s2.FeatureEnabledForBackend("old", "streaming") || s2.FeatureEnabledForBackend("old", "delete") {
+		t.Error("feature flags after a URL change don't fall back to the defaults")
+	}
+}
+
+func TestMemoryOnlyWhenDiskFull(t *testing.T) {
+	path := tempStatePath(t)
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := s.PersistStatus(); status.MemoryOnly {
+		t.Fatalf("status before the disk fills = %v", status)
+	}
+
+	writeStateFile = func(string, []byte) error {
+		return &os.PathError{Op: "write", Path: path, Err: syscall.ENOSPC}
+	}
+	defer func() { writeStateFile = writeFileAtomic }()
+
+	id, err := s.Clone()
+	if err != nil {
+		t.Fatalf("Clone on a full disk: %v", err)
+	}
+	if _, err := s.Adopt("conv-full"); err != nil {
+		t.Fatalf("Adopt on a full disk: %v", err)
+	}
+	status := s.PersistStatus()
+	if !status.MemoryOnly || !errors.Is(status.Err, syscall.ENOSPC) || status.Since.IsZero() {
+		t.Fatalf("status on a full disk = %+v", status)
+	}
+	if !strings.HasPrefix(status.String(), "memory-only since ") {
+		t.Errorf("String() = %q", status.String())
+	}
+	// Load must not go back to the file, which lacks the new clone.
+	if err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Get(id) == nil {
+		t.Fatal("clone made on a full disk was dropped")
+	}
+
+	// Other errors still fail the change.
+	writeStateFile = func(string, []byte) error {
+		return &os.PathError{Op: "write", Path: path, Err: syscall.EACCES}
+	}
+	if _, err := s.Clone(); err == nil {
+		t.Error("Clone with an unwritable state file succeeded")
+	}
+
+	// Once a save gets through, everything kept in memory is on disk.
+	writeStateFile = writeFileAtomic
+	if _, err := s.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if status := s.PersistStatus(); status.MemoryOnly {
+		t.Errorf("status after the disk has room again = %v", status)
+	}
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Get(saved) == nil || reloaded.Get(id) == nil {
+		t.Error("state file is missing conversations cloned before or during the outage")
+	}
+}
+
+// storeLogWriter notes whether the store is locked whenever something is
+// logged: the journal writer looks conversations up in the store then.
+type storeLogWriter struct {
+	s              *Store
+	writes, locked int
+}
+
+func (w *storeLogWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.s.mu.TryRLock() {
+		w.s.mu.RUnlock()
+	} else {
+		w.locked++
+	}
+	return len(p), nil
+}
+
+func TestPersistStatusLoggedOutsideLock(t *testing.T) {
+	path := tempStatePath(t)
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &storeLogWriter{s: s}
+	log.SetOutput(w)
+	defer log.SetOutput(os.Stderr)
+	writeStateFile = func(string, []byte) error {
+		return &os.PathError{Op: "write", Path: path, Err: syscall.ENOSPC}
+	}
+	defer func() { writeStateFile = writeFileAtomic }()
+
+	if _, err := s.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	writeStateFile = writeFileAtomic
+	if _, err := s.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes != 2 {
+		t.Errorf("logged %d lines, want one as the disk fills and one as it has room again", w.writes)
+	}
+	if w.locked != 0 {
+		t.Errorf("logged %d lines with the store locked", w.locked)
+	}
+}
+
+func TestKnown(t *testing.T) {
+	path := tempStatePath(t)
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cloned, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := s.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetPassthrough(true)
+	adopted, err := s.Adopt("conv-passing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Known(cloned) || !s.Known(adopted) || s.Known("00000000") {
+		t.Errorf("Known(%s, %s, 00000000) = %v, %v, %v; want true, true, false",
+			cloned, adopted, s.Known(cloned), s.Known(adopted), s.Known("00000000"))
+	}
+	if err := s.Delete(cloned); err != nil {
+		t.Fatal(err)
+	}
+	if s.Known(cloned) {
+		t.Error("deleted conversation still known")
+	}
+
+	// Known doesn't wait for the lock.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.Known(adopted) {
+		t.Error("Known with the store locked = false")
+	}
+	reloaded, err := NewStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reloaded.Known(kept) || reloaded.Known(adopted) {
+		t.Errorf("loaded store: Known(%s, %s) = %v, %v; want the saved clone only",
+			kept, adopted, reloaded.Known(kept), reloaded.Known(adopted))
+	}
+}